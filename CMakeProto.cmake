@@ -67,6 +67,7 @@ protoc_go("github.com/google/gapid/gapis/service/path" "gapis/service/path" "pat
 protoc_java("gapis/service/path" "path.proto" "com/google/gapid/proto/service/path/Path")
 protoc_go("github.com/google/gapid/gapis/service" "gapis/service" "service.proto")
 protoc_java("gapis/service" "service.proto" "com/google/gapid/proto/service/Service;com/google/gapid/proto/service/GapidGrpc")
+protoc_go("github.com/google/gapid/gapis/service/scripting" "gapis/service/scripting" "scripting.proto")
 protoc_go("github.com/google/gapid/gapis/stringtable" "gapis/stringtable" "stringtable.proto")
 protoc_java("gapis/stringtable" "stringtable.proto" "com/google/gapid/proto/stringtable/Stringtable")
 protoc_go("github.com/google/gapid/gapis/vertex" "gapis/vertex" "vertex.proto")
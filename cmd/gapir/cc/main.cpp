@@ -31,9 +31,11 @@
 #include <memory>
 #include <stdlib.h>
 #include <string.h>
+#include <string>
 
 #if TARGET_OS == GAPID_OS_ANDROID
 #include <android_native_app_glue.h>
+#include <sys/system_properties.h>
 #endif  // TARGET_OS == GAPID_OS_ANDROID
 
 using namespace core;
@@ -55,7 +57,6 @@ std::vector<uint32_t> memorySizes{
 std::unique_ptr<ResourceInMemoryCache> createResourceProvider(
         const char* cachePath, MemoryManager* memoryManager) {
     if (cachePath != nullptr) {
-        GAPID_FATAL("Disk cache is currently out of service. Got %s", cachePath);
         return std::unique_ptr<ResourceInMemoryCache>(
             ResourceInMemoryCache::create(
                 ResourceDiskCache::create(ResourceRequester::create(), cachePath),
@@ -72,14 +73,14 @@ void listenConnections(std::unique_ptr<Connection> conn,
                        const char* cachePath,
                        int idleTimeoutMs,
                        MemoryManager* memoryManager) {
-    ServerListener listener(std::move(conn), memoryManager->getSize());
+    ServerListener listener(std::move(conn), memoryManager->getSize(), cachePath != nullptr);
 
     std::unique_ptr<ResourceInMemoryCache> resourceProvider(
             createResourceProvider(cachePath, memoryManager));
 
     while (true) {
         std::unique_ptr<ServerConnection> acceptedConn(
-                listener.acceptConnection(idleTimeoutMs, authToken));
+                listener.acceptConnection(idleTimeoutMs, authToken, resourceProvider.get()));
         if (!acceptedConn) {
             GAPID_INFO("Shutting down");
             break;
@@ -118,6 +119,10 @@ const char* pipeName() {
 #endif
 }
 
+// cacheProperty is the system property set to 1 by GAPIS to enable the disk
+// cache. The cache is never evicted, so it is only used when asked for.
+const char* cacheProperty = "debug.gapid.gapir.cache";
+
 // Main function for android
 void android_main(struct android_app* app) {
     app_dummy();
@@ -135,9 +140,17 @@ void android_main(struct android_app* app) {
             "Supported ABIs: %s\n",
             pipe, core::supportedABIs());
 
-    // Note if you want to create a disk cache create it under:
-    // app->activity->internalDataPath
-    listenConnections(std::move(conn), nullptr, nullptr, Connection::NO_TIMEOUT, &memoryManager);
+    // Keep the resources in the app's internal storage so they persist across
+    // replays, if enabled.
+    std::string cachePath;
+    char enabled[PROP_VALUE_MAX] = {};
+    if (__system_property_get(cacheProperty, enabled) > 0 && strcmp(enabled, "1") == 0) {
+        cachePath = std::string(app->activity->internalDataPath) + "/cache";
+        GAPID_INFO("Using the resource cache at '%s'", cachePath.c_str());
+    }
+    listenConnections(std::move(conn), nullptr,
+                      cachePath.empty() ? nullptr : cachePath.c_str(),
+                      Connection::NO_TIMEOUT, &memoryManager);
 }
 
 #else  // TARGET_OS == GAPID_OS_ANDROID
@@ -148,6 +161,7 @@ int main(int argc, const char* argv[]) {
 
     const char* cachePath = nullptr;
     const char* portStr = "0";
+    const char* listenAddress = "127.0.0.1";
     const char* authToken = nullptr;
     int idleTimeoutMs = Connection::NO_TIMEOUT;
 
@@ -167,6 +181,11 @@ int main(int argc, const char* argv[]) {
                 GAPID_FATAL("Usage: --port <port_num>");
             }
             portStr = argv[++i];
+        } else if (strcmp(argv[i], "--listen") == 0) {
+            if (i + 1 >= argc) {
+                GAPID_FATAL("Usage: --listen <address>");
+            }
+            listenAddress = argv[++i];
         } else if (strcmp(argv[i], "--log-level") == 0) {
             if (i + 1 >= argc) {
                 GAPID_FATAL("Usage: --log-level <F|E|W|I|D|V>");
@@ -199,8 +218,11 @@ int main(int argc, const char* argv[]) {
     GAPID_LOGGER_INIT(logLevel, "gapir", logPath);
 
     MemoryManager memoryManager(memorySizes);
-    GAPID_INFO("gapir listening on port %s", portStr);
-    auto conn = SocketConnection::createSocket("127.0.0.1", portStr);
+    if (strcmp(listenAddress, "127.0.0.1") != 0 && authToken == nullptr) {
+        GAPID_WARNING("Listening on %s without an auth-token", listenAddress);
+    }
+    GAPID_INFO("gapir listening on %s port %s", listenAddress, portStr);
+    auto conn = SocketConnection::createSocket(listenAddress, portStr);
     if (conn == nullptr) {
         GAPID_FATAL("Failed to create listening socket on port: %s", portStr);
     }
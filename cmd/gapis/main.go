@@ -16,8 +16,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/gapid/core/app"
@@ -27,6 +32,8 @@ import (
 	"github.com/google/gapid/core/os/android/adb"
 	"github.com/google/gapid/core/os/device/bind"
 	"github.com/google/gapid/core/os/device/host"
+	gapir "github.com/google/gapid/gapir/client"
+	"github.com/google/gapid/gapis/config"
 	"github.com/google/gapid/gapis/database"
 	"github.com/google/gapid/gapis/replay"
 	"github.com/google/gapid/gapis/server"
@@ -43,6 +50,26 @@ var (
 	gapirArgStr     = flag.String("gapir-args", "", `"<The arguments to be passed to gapir>"`)
 	scanAndroidDevs = flag.Bool("monitor-android-devices", true, "Server will scan for locally connected Android devices")
 	addLocalDevice  = flag.Bool("add-local-device", true, "Server will create a new local replay device")
+	addSwiftShader  = flag.Bool("add-swiftshader-device", true, "Server will create a local replay device using the bundled SwiftShader Vulkan driver")
+	experimentsDir  = flag.String("experiments", "", "Directory used to persist the results of experiments")
+	replayIsolation = flag.String("replay-isolation", "shared", "Which replays share a host gapir instance. One of: 'shared', 'capture' or 'request'")
+	crashReports    = flag.String("gapir-crash-reports", "", "Directory used to write reports of gapir instances terminating unexpectedly")
+	compressReplay  = flag.Bool("gapir-compression", false, "Compress the resources sent to gapir instances on Android and remote devices")
+	prewarmReplay   = flag.Bool("gapir-prewarm", false, "Start the gapir instances of the replay devices as soon as they are added and keep them running between replays")
+	resourceCache   = flag.String("gapir-resource-cache", "", "Directory used by the host gapir instances to keep replay resources between runs. Requires 'shared' replay isolation")
+	deviceCache     = flag.Bool("gapir-device-resource-cache", false, "Keep replay resources in the gapir app storage on Android devices between runs. The cache is never evicted")
+	databaseBudget  = flag.Int("database-budget", 0, "Maximum memory in megabytes used to cache resolved results. 0 is unlimited")
+	remoteGapir     = flag.String("remote-gapir", "", "Comma-separated host:port list of gapir instances on other hosts to add as replay devices")
+	remoteAuthToken = flag.String("remote-gapir-auth-token", "", "The connection authorization token for the remote gapir instances")
+	remoteTLS       = flag.Bool("remote-gapir-tls", false, "Connect to the remote gapir instances using TLS. gapir does not speak TLS, so each instance needs a TLS terminating proxy in front of it")
+	remoteCA        = flag.String("remote-gapir-ca", "", "PEM file of the certificate authorities trusted for remote gapir TLS connections")
+	databaseSpill   = flag.String("database-spill", "", "Directory used to hold large resolved results evicted by the database budget. Empty drops them")
+	enableScripting = flag.Bool("enable-scripting-api", false, "Server will also serve the versioned scripting API for third-party automation")
+	tlsCert         = flag.String("tls-cert", "", "PEM file of the certificate used to serve the RPCs over TLS")
+	tlsKey          = flag.String("tls-key", "", "PEM file of the private key of the TLS certificate")
+	prefetch        = flag.Bool("prefetch", false, "Server will resolve the command tree, dependency graph, first frame state and thumbnails in the background after loading a capture")
+	allowOrigins    = flag.String("allow-origins", "", "Comma-separated IP addresses or CIDR networks of the remote clients to accept. Requires an auth token")
+	bindlessDCE     = flag.Bool("dce-per-descriptor-bindless", config.PerDescriptorBindlessDCE, "Dead code elimination tracks the descriptors of update-after-bind descriptor sets individually")
 )
 
 func main() {
@@ -72,11 +99,37 @@ func run(ctx context.Context) error {
 	addFallbackLogHandler(logBroadcaster, log.GetHandler(ctx))
 	ctx = log.PutHandler(ctx, logBroadcaster)
 
+	config.PerDescriptorBindlessDCE = *bindlessDCE
+
 	r := bind.NewRegistry()
 	ctx = bind.PutRegistry(ctx, r)
-	m := replay.New(ctx)
+	isolation, err := gapir.ParseIsolation(*replayIsolation)
+	if err != nil {
+		return err
+	}
+	m := replay.New(ctx, gapir.Options{
+		Isolation:           isolation,
+		CrashReports:        *crashReports,
+		ResourceCache:       *resourceCache,
+		DeviceResourceCache: *deviceCache,
+		Compression:         *compressReplay,
+		Prewarm:             *prewarmReplay,
+	})
 	ctx = replay.PutManager(ctx, m)
-	ctx = database.Put(ctx, database.NewInMemory(ctx))
+	if *databaseBudget < 0 {
+		return fmt.Errorf("Invalid database budget: %d", *databaseBudget)
+	}
+	budget := uint64(*databaseBudget) << 20
+	if *databaseSpill != "" && budget > 0 {
+		dir, err := ioutil.TempDir(*databaseSpill, "gapis-database")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		ctx = database.Put(ctx, database.NewTiered(ctx, budget, dir))
+	} else {
+		ctx = database.Put(ctx, database.NewInMemoryWithBudget(ctx, budget))
+	}
 
 	deviceScanDone, onDeviceScanDone := task.NewSignal()
 	if *scanAndroidDevs {
@@ -89,6 +142,36 @@ func run(ctx context.Context) error {
 		r.AddDevice(ctx, bind.Host(ctx))
 	}
 
+	if *addSwiftShader {
+		if d, err := gapir.NewSwiftShader(ctx); err == nil {
+			r.AddDevice(ctx, d)
+		} else {
+			log.I(ctx, "SwiftShader replay device unavailable: %v", err)
+		}
+	}
+
+	if err := addRemoteDevices(ctx, r); err != nil {
+		return err
+	}
+
+	if *enableScripting {
+		features = append(features, "scripting-api-v1")
+	}
+
+	origins, err := server.ParseOrigins(*allowOrigins)
+	if err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if *tlsCert != "" || *tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			return log.Err(ctx, err, "Could not load the TLS certificate")
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
 	return server.Listen(ctx, *rpc, server.Config{
 		Info: &service.ServerInfo{
 			Name:         host.Instance(ctx).Name,
@@ -96,13 +179,41 @@ func run(ctx context.Context) error {
 			VersionMinor: uint32(version.Minor),
 			Features:     features,
 		},
-		StringTables:   loadStrings(ctx),
-		AuthToken:      auth.Token(*gapisAuthToken),
-		DeviceScanDone: deviceScanDone,
-		LogBroadcaster: logBroadcaster,
+		StringTables:    loadStrings(ctx),
+		AuthToken:       auth.Token(*gapisAuthToken),
+		DeviceScanDone:  deviceScanDone,
+		LogBroadcaster:  logBroadcaster,
+		ExperimentsDir:  *experimentsDir,
+		EnableScripting: *enableScripting,
+		TLS:             tlsConfig,
+		AllowedOrigins:  origins,
+		Prefetch:        *prefetch,
 	})
 }
 
+// addRemoteDevices adds the devices of the gapir instances listed by the
+// remote-gapir flag to the registry r.
+func addRemoteDevices(ctx context.Context, r *bind.Registry) error {
+	if *remoteGapir == "" {
+		return nil
+	}
+	var tlsConfig *tls.Config
+	if *remoteTLS {
+		var err error
+		if tlsConfig, err = gapir.LoadTLSConfig(*remoteCA); err != nil {
+			return err
+		}
+	}
+	for _, address := range strings.Split(*remoteGapir, ",") {
+		d, err := gapir.ConnectRemote(ctx, strings.TrimSpace(address), auth.Token(*remoteAuthToken), tlsConfig)
+		if err != nil {
+			return err
+		}
+		r.AddDevice(ctx, d)
+	}
+	return nil
+}
+
 func monitorAndroidDevices(ctx context.Context, r *bind.Registry, onDeviceScanDone task.Task) {
 	// Populate the registry with all the existing devices.
 	func() {
@@ -18,18 +18,43 @@
 # build and the file will be recreated, check in the new version.
 
 set(files
+    benchmark.go
+    commands.go
     common.go
+    crash.go
+    deltas.go
     devices.go
+    diff.go
     dump.go
     dump_shaders.go
+    experiments.go
+    export_commands.go
+    export_cpp.go
     flags.go
+    framegraph.go
+    indices.go
     info.go
     inputs.go
+    leaks.go
+    lint.go
     main.go
+    memory.go
     packages.go
+    perfetto.go
+    profile_frame.go
+    progress.go
+    redundant.go
+    renderdoc.go
     report.go
+    repro.go
+    sanitize.go
+    screenshot.go
+    state.go
+    statediff.go
     sxs_video.go
     trace.go
+    trace_test.go
+    trim.go
     video.go
 )
 set(dirs
@@ -0,0 +1,174 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+type benchmarkVerb struct{ BenchmarkFlags }
+
+func init() {
+	verb := &benchmarkVerb{
+		BenchmarkFlags{
+			At: allTheWay,
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "benchmark",
+		ShortHelp: "Measures the time and memory GAPIS takes to perform common operations on a capture",
+		Auto:      verb,
+	})
+}
+
+// benchmarkStep is the measurement of a single benchmarked operation.
+type benchmarkStep struct {
+	Name      string        `json:"name"`
+	Wall      time.Duration `json:"wall_ns"`
+	HeapInuse uint64        `json:"heap_inuse"` // GAPIS heap in use after the step.
+}
+
+func (verb *benchmarkVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	// Results are cached by GAPIS, so the measurements are only meaningful
+	// with a new server instance.
+	if verb.Gapis.Port != 0 {
+		log.W(ctx, "Benchmarking an existing GAPIS instance, cached results may skew the measurements")
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	var (
+		capturePath *path.Capture
+		device      *path.Device
+		at          *path.Command
+	)
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"load-capture", func() (err error) {
+			capturePath, err = client.LoadCapture(ctx, capture)
+			return err
+		}},
+		{"decode-commands", func() error {
+			boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+			if err != nil {
+				return err
+			}
+			if verb.At == allTheWay {
+				verb.At = len(boxedAtoms.(*atom.List).Atoms) - 1
+			}
+			at = capturePath.Commands().Index(uint64(verb.At))
+			return nil
+		}},
+		{"command-tree", func() error {
+			_, err := client.Get(ctx, capturePath.Hierarchies().Path())
+			return err
+		}},
+		// Extracting the minimal reproduction of a command is dominated by
+		// building the dependency graph of the capture.
+		{"dependency-graph", func() error {
+			_, err := client.ExtractMinimalRepro(ctx, at)
+			return err
+		}},
+		{"select-device", func() (err error) {
+			device, err = getDevice(ctx, client, capturePath, verb.Gapir)
+			return err
+		}},
+		{"replay-frame", func() error {
+			_, err := renderFrame(ctx, &service.RenderSettings{}, at, device, client)
+			return err
+		}},
+		{"fetch-framebuffer", func() error {
+			// The replay is cached, this measures the resolve and transfer of
+			// the framebuffer data.
+			_, err := renderFrame(ctx, &service.RenderSettings{}, at, device, client)
+			return err
+		}},
+	}
+
+	results := []benchmarkStep{}
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.run(); err != nil {
+			return log.Errf(ctx, err, "Benchmark step %v failed", step.name)
+		}
+		wall := time.Since(start)
+		heap, err := serverHeapInuse(ctx, client)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to get the GAPIS memory usage")
+		}
+		results = append(results, benchmarkStep{Name: step.name, Wall: wall, HeapInuse: heap})
+		fmt.Printf("%-20s %12v %10.1f MB\n", step.name, wall, float64(heap)/(1024*1024))
+	}
+
+	if verb.Out != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return log.Err(ctx, err, "Couldn't marshal the measurements to JSON")
+		}
+		if err := ioutil.WriteFile(verb.Out, data, 0666); err != nil {
+			return log.Errf(ctx, err, "Could not write the measurements to: %v", verb.Out)
+		}
+	}
+	return nil
+}
+
+// serverHeapInuse returns the heap memory in use by GAPIS, as reported by the
+// memory statistics trailing its heap profile.
+func serverHeapInuse(ctx context.Context, client service.Service) (uint64, error) {
+	profile, err := client.GetProfile(ctx, "heap", 1)
+	if err != nil {
+		return 0, err
+	}
+	const prefix = "# HeapInuse = "
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, prefix) {
+			return strconv.ParseUint(strings.TrimPrefix(line, prefix), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("No HeapInuse in the heap profile")
+}
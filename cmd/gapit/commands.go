@@ -0,0 +1,156 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+)
+
+type commandsVerb struct{ CommandsFlags }
+
+func init() {
+	verb := &commandsVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "commands",
+		ShortHelp: "Prints the commands of a capture grouped by frame and submit",
+		Auto:      verb,
+	})
+}
+
+func (verb *commandsVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	var filter *regexp.Regexp
+	if verb.Filter != "" {
+		re, err := regexp.Compile("(?i)" + verb.Filter)
+		if err != nil {
+			return log.Errf(ctx, err, "Invalid filter: %v", verb.Filter)
+		}
+		filter = re
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	schemaMsg, err := client.GetSchema(ctx)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the schema")
+	}
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+	if err != nil {
+		return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+	}
+	atoms := boxedAtoms.(*atom.List).Atoms
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open the commands output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// The frame and submit headers are only printed once a command of their
+	// group is, so that filtered out groups do not clutter the output.
+	frame, submit := 1, 1
+	frameShown, submitShown := false, false
+	matched := 0
+	for i, a := range atoms {
+		var s string
+		if dyn, ok := a.(*atom.Dynamic); ok && !verb.Raw {
+			s = dyn.StringWithConstants(schemaMsg.Constants)
+		} else {
+			s = fmt.Sprint(a)
+		}
+
+		if (verb.Frame == 0 || verb.Frame == frame) && (filter == nil || filter.MatchString(s)) {
+			if !frameShown {
+				fmt.Fprintf(w, "Frame %d:\n", frame)
+				frameShown = true
+			}
+			indent := "    "
+			if verb.Submits {
+				if !submitShown {
+					fmt.Fprintf(w, "    Submit %d:\n", submit)
+					submitShown = true
+				}
+				indent += "    "
+			}
+			fmt.Fprintf(w, "%s%.6d %s\n", indent, i, s)
+			matched++
+		}
+
+		if verb.Submits && isSubmit(a) {
+			submit++
+			submitShown = false
+		}
+		if a.AtomFlags().IsEndOfFrame() {
+			frame++
+			submit = 1
+			frameShown, submitShown = false, false
+			if verb.Frame != 0 && frame > verb.Frame {
+				break
+			}
+		}
+	}
+
+	if filter != nil {
+		fmt.Fprintf(w, "%d of %d commands matched\n", matched, len(atoms))
+	}
+	return nil
+}
+
+// isSubmit returns true if a hands its recorded work over to a queue, ending
+// the current submit group.
+func isSubmit(a atom.Atom) bool {
+	dyn, ok := a.(*atom.Dynamic)
+	if !ok {
+		return false
+	}
+	switch dyn.Class().Schema().Name() {
+	case "vkQueueSubmit", "glFlush", "glFinish":
+		return true
+	}
+	return false
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"regexp"
@@ -24,6 +25,7 @@ import (
 	"github.com/google/gapid/core/app/auth"
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/os/android/adb"
+	gapir "github.com/google/gapid/gapir/client"
 	"github.com/google/gapid/gapis/client"
 	"github.com/google/gapid/gapis/service/path"
 )
@@ -45,10 +47,19 @@ func getGapis(ctx context.Context, gapisFlags GapisFlags, gapirFlags GapirFlags)
 	} else {
 		token = auth.Token(gapisFlags.Token)
 	}
+	var tlsConfig *tls.Config
+	if gapisFlags.TLS {
+		var err error
+		if tlsConfig, err = gapir.LoadTLSConfig(gapisFlags.CA); err != nil {
+			return nil, log.Err(ctx, err, "Failed to load the TLS certificate authorities")
+		}
+	}
 	client, _, err := client.Connect(ctx, client.Config{
 		Port:  gapisFlags.Port,
 		Args:  args,
 		Token: token,
+		Host:  gapisFlags.Host,
+		TLS:   tlsConfig,
 	})
 	if err != nil {
 		return nil, log.Err(ctx, err, "Failed to connect to the GAPIS server")
@@ -58,6 +69,10 @@ func getGapis(ctx context.Context, gapisFlags GapisFlags, gapirFlags GapirFlags)
 		go client.GetLogStream(ctx, h)
 	}
 
+	if gapirFlags.Progress {
+		go client.GetReplayStatusStream(ctx, replayProgress{os.Stderr}.update)
+	}
+
 	return client, nil
 }
 
@@ -0,0 +1,100 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+)
+
+type crashVerb struct{ CrashFlags }
+
+func init() {
+	verb := &crashVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "crash",
+		ShortHelp: "Locates a GPU crash in a capture using a vendor crash dump",
+		Auto:      verb,
+	})
+}
+
+func (verb *crashVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if verb.Dump == "" {
+		app.Usage(ctx, "The crash dump must be specified with -dump")
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	dump, err := ioutil.ReadFile(verb.Dump)
+	if err != nil {
+		return log.Errf(ctx, err, "Could not read crash dump: %v", verb.Dump)
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	crash, err := client.ImportCrashDump(ctx, capturePath, dump)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to correlate the crash dump")
+	}
+
+	atomsObj, err := client.Get(ctx, capturePath.Commands().Path())
+	if err != nil {
+		return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+	}
+	atoms := atomsObj.(*atom.List).Atoms
+
+	if crash.FaultAddress != 0 {
+		fmt.Printf("Fault address: 0x%x\n", crash.FaultAddress)
+	}
+	if len(crash.Markers) > 0 {
+		fmt.Printf("Markers: %s\n", strings.Join(crash.Markers, " / "))
+	}
+	fmt.Printf("Commands:\n")
+	for i := crash.Range.First; i < crash.Range.First+crash.Range.Count; i++ {
+		fmt.Printf("  (%d) %v\n", i, atoms[i])
+	}
+	if len(crash.Resources) > 0 {
+		fmt.Printf("Resources:\n")
+		for _, r := range crash.Resources {
+			fmt.Printf("  %s %s (last used by command %d)\n", r.Handle, r.Label, r.Accesses[len(r.Accesses)-1])
+		}
+	}
+	return nil
+}
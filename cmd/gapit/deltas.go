@@ -0,0 +1,104 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+)
+
+type deltasVerb struct{ DeltasFlags }
+
+func init() {
+	verb := &deltasVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "deltas",
+		ShortHelp: "Summarizes the resources changed by each frame of a .gfxtrace",
+		Auto:      verb,
+	})
+}
+
+func (verb *deltasVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	deltas, err := client.GetFrameDeltas(ctx, capturePath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to get the frame deltas")
+	}
+
+	for _, f := range deltas.Frames {
+		fmt.Printf("Frame %d (commands %d-%d): %v\n", f.Frame, f.FirstCommand, f.LastCommand, summarizeFrameDelta(f))
+		if verb.Verbose {
+			for _, t := range f.Types {
+				if len(t.Changed) > 0 {
+					fmt.Printf("    %v: %v\n", resourceTypeName(t.Type), strings.Join(t.Changed, ", "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// summarizeFrameDelta returns a single line describing what changed in f, for
+// example "2 of 14 Texture2D changed (1 created), no Shader changed".
+func summarizeFrameDelta(f *service.FrameDelta) string {
+	if len(f.Types) == 0 {
+		return "no resources used"
+	}
+	parts := make([]string, len(f.Types))
+	for i, t := range f.Types {
+		name := resourceTypeName(t.Type)
+		switch {
+		case len(t.Changed) == 0:
+			parts[i] = fmt.Sprintf("no %v changed", name)
+		case t.Created > 0:
+			parts[i] = fmt.Sprintf("%d of %d %v changed (%d created)", len(t.Changed), t.Used, name, t.Created)
+		default:
+			parts[i] = fmt.Sprintf("%d of %d %v changed", len(t.Changed), t.Used, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func resourceTypeName(ty gfxapi.ResourceType) string {
+	return strings.TrimSuffix(ty.String(), "Resource")
+}
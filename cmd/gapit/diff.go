@@ -0,0 +1,106 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+type diffVerb struct{ DiffFlags }
+
+func init() {
+	verb := &diffVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "diff",
+		ShortHelp: "Compares two .gfxtrace files frame by frame",
+		Auto:      verb,
+	})
+}
+
+func (verb *diffVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 2 {
+		app.Usage(ctx, "Exactly two gfx trace files expected, got %d", flags.NArg())
+		return nil
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	captures := make([]*path.Capture, 2)
+	for i := range captures {
+		capture, err := filepath.Abs(flags.Arg(i))
+		if err != nil {
+			return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(i))
+		}
+		if captures[i], err = client.LoadCapture(ctx, capture); err != nil {
+			return log.Errf(ctx, err, "Failed to load the capture file: %v", flags.Arg(i))
+		}
+	}
+
+	diff, err := client.DiffCaptures(ctx, captures[0], captures[1])
+	if err != nil {
+		return log.Err(ctx, err, "Failed to compare the captures")
+	}
+
+	if diff.FramesA != diff.FramesB {
+		fmt.Printf("Frame count: %d -> %d\n", diff.FramesA, diff.FramesB)
+	}
+	if len(diff.Frames) == 0 {
+		fmt.Println("No differences found")
+		return nil
+	}
+	for i, f := range diff.Frames {
+		if verb.Max > 0 && i >= verb.Max {
+			fmt.Printf("... %d more differing frames\n", len(diff.Frames)-i)
+			break
+		}
+		printFrameDiff(f)
+	}
+	return nil
+}
+
+func printFrameDiff(f *service.FrameDiff) {
+	fmt.Printf("Frame %d: %d -> %d commands\n", f.Frame, f.CommandsA, f.CommandsB)
+	if f.CommandsTruncated {
+		fmt.Println("    commands differ too much to be aligned")
+	}
+	for _, c := range f.Removed {
+		fmt.Printf("  - %d: %v\n", c.Index, c.Name)
+	}
+	for _, c := range f.Added {
+		fmt.Printf("  + %d: %v\n", c.Index, c.Name)
+	}
+	printList := func(label string, l []string) {
+		if len(l) > 0 {
+			fmt.Printf("    %v: %v\n", label, strings.Join(l, ", "))
+		}
+	}
+	printList("state changed", f.ChangedState)
+	printList("resources changed", f.ChangedResources)
+	printList("resources only in A", f.RemovedResources)
+	printList("resources only in B", f.AddedResources)
+}
@@ -18,8 +18,9 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"os"
+	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/gapid/core/app"
 	"github.com/google/gapid/gapis/atom"
@@ -32,12 +33,13 @@ type dumpShadersVerb struct{ DumpShadersFlags }
 func init() {
 	verb := &dumpShadersVerb{
 		DumpShadersFlags{
-			Atom: -1,
+			Atom:      -1,
+			Container: "ktx2",
 		},
 	}
 	app.AddVerb(&app.Verb{
 		Name:      "dump_resources",
-		ShortHelp: "Dump all shaders at a particular atom from a .gfxtrace",
+		ShortHelp: "Dump all shaders and textures at a particular atom from a .gfxtrace",
 		Auto:      verb,
 	})
 }
@@ -53,6 +55,12 @@ func (verb *dumpShadersVerb) Run(ctx context.Context, flags flag.FlagSet) error
 		return fmt.Errorf("Could not find capture file '%s': %v", flags.Arg(0), err)
 	}
 
+	container, ok := service.TextureContainer_value[strings.ToUpper(verb.Container)]
+	if !ok {
+		app.Usage(ctx, "Unknown texture container '%s'", verb.Container)
+		return nil
+	}
+
 	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
 	if err != nil {
 		return fmt.Errorf("Failed to connect to the GAPIS server: %v", err)
@@ -91,13 +99,25 @@ func (verb *dumpShadersVerb) Run(ctx context.Context, flags flag.FlagSet) error
 				}
 				shaderSource := shaderData.(*gfxapi.Shader).GetSource()
 
-				f, err := os.Create(v.GetHandle())
+				if err := ioutil.WriteFile(v.GetHandle(), []byte(shaderSource), 0666); err != nil {
+					return fmt.Errorf("Could not write shader %s: %v", v.GetHandle(), err)
+				}
+			}
+		}
+		if types.Type == gfxapi.ResourceType_Texture2DResource ||
+			types.Type == gfxapi.ResourceType_Texture3DResource ||
+			types.Type == gfxapi.ResourceType_CubemapResource {
+			for _, v := range types.GetResources() {
+				resourcePath := capture.Commands().Index(uint64(verb.Atom)).ResourceAfter(v.GetId())
+				data, err := client.ExportTexture(ctx, resourcePath, service.TextureContainer(container))
 				if err != nil {
-					fmt.Printf("Could open file to write %s %v", v.GetHandle(), err)
+					fmt.Printf("Could not export texture: %v %v\n", v, err)
 					continue
 				}
-				defer f.Close()
-				f.WriteString(shaderSource)
+				name := v.GetHandle() + "." + strings.ToLower(verb.Container)
+				if err := ioutil.WriteFile(name, data, 0666); err != nil {
+					return fmt.Errorf("Could not write texture %s: %v", name, err)
+				}
 			}
 		}
 	}
@@ -0,0 +1,152 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+)
+
+type experimentsVerb struct{ ExperimentsFlags }
+
+func init() {
+	verb := &experimentsVerb{
+		ExperimentsFlags{
+			At: -1,
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "experiments",
+		ShortHelp: "Lists, runs or shows the results of experiments on a capture",
+		Auto:      verb,
+	})
+}
+
+func (verb *experimentsVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() > 1 {
+		app.Usage(ctx, "At most one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	if flags.NArg() == 0 {
+		list, err := client.GetExperiments(ctx)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to get the list of experiments")
+		}
+		for _, e := range list {
+			fmt.Printf("%s: %s\n", e.Name, e.Description)
+			if len(e.Parameters) > 0 {
+				fmt.Printf("  Parameters: %s\n", strings.Join(e.Parameters, ", "))
+			}
+		}
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	var out interface{}
+	if verb.Name == "" {
+		results, err := client.GetExperimentResults(ctx, capturePath)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to get the experiment results")
+		}
+		out = results
+	} else {
+		params, err := parseExperimentParams(verb.Params)
+		if err != nil {
+			app.Usage(ctx, "%v", err)
+			return nil
+		}
+
+		if verb.At == -1 {
+			boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+			if err != nil {
+				return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+			}
+			verb.At = len(boxedAtoms.(*atom.List).Atoms) - 1
+		}
+
+		device, err := getDevice(ctx, client, capturePath, verb.Gapir)
+		if err != nil {
+			return err
+		}
+
+		result, err := client.RunExperiment(ctx, &service.ExperimentRequest{
+			Name:       verb.Name,
+			Parameters: params,
+			After:      capturePath.Commands().Index(uint64(verb.At)),
+			Device:     device,
+		})
+		if err != nil {
+			return log.Err(ctx, err, "Failed to run the experiment")
+		}
+		out = result
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return log.Err(ctx, err, "Couldn't marshal the results to JSON")
+	}
+
+	if verb.Out == "" {
+		_, err := os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	if err := ioutil.WriteFile(verb.Out, data, 0666); err != nil {
+		return log.Errf(ctx, err, "Could not write the results to: %v", verb.Out)
+	}
+	return nil
+}
+
+// parseExperimentParams parses a list of 'name=value' pairs separated by ';'.
+func parseExperimentParams(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid experiment parameter '%v', expected 'name=value'", pair)
+		}
+		out[strings.TrimSpace(parts[0])] = parts[1]
+	}
+	return out, nil
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+type exportCommandsVerb struct{ ExportCommandsFlags }
+
+func init() {
+	verb := &exportCommandsVerb{
+		ExportCommandsFlags{
+			Format: "json",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "export-commands",
+		ShortHelp: "Exports the commands of a capture as JSON or protobuf text",
+		Auto:      verb,
+	})
+}
+
+// Run writes the commands as an ExportedCommands message, whose schema is
+// documented in gapis/service/service.proto.
+func (verb *exportCommandsVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if verb.Format != "json" && verb.Format != "text" {
+		app.Usage(ctx, "Unknown format %q, expected 'json' or 'text'", verb.Format)
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, GapirFlags{})
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	out := io.Writer(os.Stdout)
+	if verb.Out != "" {
+		f, err := os.Create(verb.Out)
+		if err != nil {
+			return log.Errf(ctx, err, "Could not create the output file: %v", verb.Out)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+
+	// The batches are written as they arrive so that the whole capture is
+	// never held in memory. The concatenation of the batches in text format
+	// is itself an ExportedCommands message.
+	marshaler := &jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+	first := true
+	if verb.Format == "json" {
+		w.WriteString(`{"commands":[`)
+	}
+	err = client.ExportCommands(ctx, capturePath, verb.Data, func(batch *service.ExportedCommands) error {
+		if verb.Format == "text" {
+			_, err := w.WriteString(proto.MarshalTextString(batch))
+			return err
+		}
+		for _, cmd := range batch.Commands {
+			if !first {
+				w.WriteString(",")
+			}
+			first = false
+			w.WriteString("\n")
+			if err := marshaler.Marshal(w, cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return log.Err(ctx, err, "Failed to export the commands")
+	}
+	if verb.Format == "json" {
+		w.WriteString("\n]}\n")
+	}
+	if err := w.Flush(); err != nil {
+		return log.Errf(ctx, err, "Could not write the commands to: %v", verb.Out)
+	}
+	return nil
+}
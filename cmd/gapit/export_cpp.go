@@ -0,0 +1,83 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+)
+
+type exportCppVerb struct{ ExportCppFlags }
+
+func init() {
+	verb := &exportCppVerb{
+		ExportCppFlags{
+			Frame: 1,
+			Out:   "capture.cpp",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "export-cpp",
+		ShortHelp: "Exports a capture as a standalone C++ program repeating a frame",
+		Auto:      verb,
+	})
+}
+
+func (verb *exportCppVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if verb.Frame < 1 {
+		app.Usage(ctx, "The frame must be at least 1, got %d", verb.Frame)
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	device, err := getDevice(ctx, client, capturePath, verb.Gapir)
+	if err != nil {
+		return err
+	}
+
+	source, err := client.ExportCpp(ctx, device, capturePath, uint32(verb.Frame))
+	if err != nil {
+		return log.Errf(ctx, err, "Failed to export frame %d", verb.Frame)
+	}
+
+	if err := ioutil.WriteFile(verb.Out, source, 0666); err != nil {
+		return log.Errf(ctx, err, "Could not write the program to: %v", verb.Out)
+	}
+	return nil
+}
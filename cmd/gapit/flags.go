@@ -50,6 +50,27 @@ func (v VideoType) String() string {
 	return videoTypeNames[v]
 }
 
+type VideoCodec uint8
+
+const (
+	AutoCodec VideoCodec = iota
+	H264Codec
+	VP9Codec
+)
+
+var videoCodecNames = map[VideoCodec]string{
+	AutoCodec: "auto",
+	H264Codec: "h264",
+	VP9Codec:  "vp9",
+}
+
+func (v *VideoCodec) Choose(c interface{}) {
+	*v = c.(VideoCodec)
+}
+func (v VideoCodec) String() string {
+	return videoCodecNames[v]
+}
+
 type PackagesOutput uint8
 
 var packagesOutputNames = map[PackagesOutput]string{
@@ -67,32 +88,176 @@ func (v PackagesOutput) String() string {
 }
 
 type (
+	BenchmarkFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		At    int    `help:"command to replay the frame up to: -1 for last command"`
+		Out   string `help:"output JSON path for the measurements, none if empty"`
+	}
+	CommandsFlags struct {
+		Gapis   GapisFlags
+		Gapir   GapirFlags
+		Filter  string `help:"only print the commands matching this case-insensitive regular expression"`
+		Frame   int    `help:"only print the commands of this frame, starting at 1. 0 for all frames"`
+		Submits bool   `help:"group the commands of each frame by queue submit or flush"`
+		Raw     bool   `help:"print the commands without resolving their constant names"`
+		Out     string `help:"output path, standard output if none"`
+	}
+	CrashFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Dump  string `help:"the vendor GPU crash dump captured alongside the trace"`
+	}
+	DeltasFlags struct {
+		Gapis   GapisFlags
+		Gapir   GapirFlags
+		Verbose bool `help:"list the handles of the changed resources"`
+	}
+	DiffFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Max   int `help:"the maximum number of differing frames to print, 0 for all"`
+	}
 	DeviceFlags struct {
 		Device string `help:"Device to spawn on. One of: 'host', 'android' or <device-serial>"`
 	}
 	DevicesFlags struct {
 		Gapis GapisFlags
 	}
+	ExperimentsFlags struct {
+		Gapis  GapisFlags
+		Gapir  GapirFlags
+		Name   string `help:"the experiment to run. Lists the experiments if no capture is given"`
+		Params string `help:"the experiment parameters as 'name=value' pairs separated by ';'"`
+		At     int    `help:"command to inspect the framebuffer after: -1 for last command"`
+		Out    string `help:"output JSON path, standard output if none"`
+	}
+	ExportCppFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Frame int    `help:"the frame repeated by the program, starting at 1"`
+		Out   string `help:"output C++ source path"`
+	}
+	ExportCommandsFlags struct {
+		Gapis  GapisFlags
+		Format string `help:"output format: 'json' or 'text' for protobuf text"`
+		Data   bool   `help:"include the observed memory in the observations"`
+		Out    string `help:"output path, standard output if none"`
+	}
+	FrameGraphFlags struct {
+		Gapis      GapisFlags
+		Gapir      GapirFlags
+		Dot        bool   `help:"output the graph in the Graphviz dot format"`
+		Thumbnails string `help:"directory to save the attachment thumbnails of the render passes to, shown by the dot output"`
+		Out        string `help:"output path, standard output if none"`
+	}
 	GapisFlags struct {
 		Profile string `help:"produce a pprof file from gapis"`
 		Port    int    `help:"gapis tcp port to connect to, 0 means start new instance."`
 		Args    string `help:"The arguments to be passed to gapis"`
 		Token   string `help:"The auth token to use when connecting to an existing server."`
+		Host    string `help:"the host of the existing server to connect to, localhost if empty"`
+		TLS     bool   `help:"connect to the existing server using TLS"`
+		CA      string `help:"PEM file of the certificate authorities trusted for the TLS connection"`
 	}
 	GapirFlags struct {
 		DeviceFlags
-		Args string `help:"The arguments to be passed to gapir"`
+		Args     string `help:"The arguments to be passed to gapir"`
+		Progress bool   `help:"show the progress of the replays on standard error"`
 	}
 	GapiiFlags struct {
 		DeviceFlags
 	}
+	IndicesFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Cache struct {
+			Size int `help:"the number of entries of the simulated vertex cache"`
+		}
+	}
 	InfoFlags struct {
 	}
+	LeaksFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Out   string `help:"output report path, standard output if none"`
+	}
+	LintFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Out   string `help:"output report path, standard output if none"`
+	}
+	MemoryFlags struct {
+		Gapis    GapisFlags
+		At       int    `help:"the command to dump the memory after, the last command of the capture if negative"`
+		Pool     int    `help:"the memory pool to dump, 0 for the application pool"`
+		Range    string `help:"the memory range to dump as <base>+<size>, such as 0x1000+256"`
+		Format   string `help:"dump format: 'hex' or the little-endian values u8, u16, u32, u64, s8, s16, s32, s64, f32 or f64"`
+		Accesses bool   `help:"also list the ranges read and written by the command"`
+		Out      string `help:"output path, standard output if none"`
+	}
+	PerfettoFlags struct {
+		Gapis  GapisFlags
+		Gapir  GapirFlags
+		Replay bool   `help:"replay the capture to add the GPU timings of the commands"`
+		Out    string `help:"output Perfetto trace path"`
+	}
+	ProfileFrameFlags struct {
+		Gapis       GapisFlags
+		Gapir       GapirFlags
+		Frame       int    `help:"the frame to profile, starting at 1"`
+		Iterations  int    `help:"the number of times the frame is replayed"`
+		Experiments string `help:"comma-separated experiments, such as texture-1x1, simple-shader or resolution-scale, to compare the frame with"`
+		Params      string `help:"the experiment parameters as 'name=value' pairs separated by ';'"`
+	}
+	RedundantFlags struct {
+		Gapis    GapisFlags
+		Gapir    GapirFlags
+		Commands bool   `help:"list each redundant command after the frame summaries"`
+		Out      string `help:"output report path, standard output if none"`
+	}
+	RenderDocFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Out   string `help:"output JSON path, standard output if none"`
+	}
 	ReportFlags struct {
 		Gapis GapisFlags
 		Gapir GapirFlags
 		Out   string `help:"output report path"`
 	}
+	ReproFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		At    int    `help:"the draw call to extract the commands of"`
+		Out   string `help:"output gfx trace path"`
+	}
+	StateFlags struct {
+		Gapis  GapisFlags
+		At     int    `help:"the command to print the state after, the last command of the capture if negative"`
+		Path   string `help:"the part of the state to print, such as 'Images[0x3].Info'. All of it if empty"`
+		Format string `help:"output format: 'json' or 'text'"`
+		Out    string `help:"output path, standard output if none"`
+	}
+	StateDiffFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		From  int `help:"the command to diff the state after"`
+		To    int `help:"the last command whose changes are included, the last command of the capture if negative"`
+	}
+	SanitizeFlags struct {
+		Gapis    GapisFlags
+		Shaders  bool   `help:"replace the shader source code (GLES shaders no longer compile)"`
+		Names    bool   `help:"replace the debug names, labels, application names and file paths"`
+		Textures bool   `help:"replace the texture contents by checkerboards"`
+		Out      string `help:"output gfx trace path"`
+	}
+	TrimFlags struct {
+		Gapis GapisFlags
+		Gapir GapirFlags
+		Frame string `help:"the frames to keep, as N or N..M (frames are numbered from 1)"`
+		Out   string `help:"output gfx trace path"`
+	}
 	VideoFlags struct {
 		Gapis GapisFlags
 		Gapir GapirFlags
@@ -102,17 +267,34 @@ type (
 			Width  int `help:"maximum video width"`
 			Height int `help:"maximum video height"`
 		}
-		Type   VideoType `help:"type of output to produce"`
-		Text   string    `help:"summary prefix (use '║' for aligned columns, '¶' for new line)"`
+		Type   VideoType  `help:"type of output to produce"`
+		Codec  VideoCodec `help:"video codec: auto picks VP9 for .webm output and H.264 otherwise"`
+		Text   string     `help:"summary prefix (use '║' for aligned columns, '¶' for new line)"`
 		Frames struct {
 			Start int `help:"frame to start capture from"`
 			End   int `help:"frame to end capture on: -1 for last frame"`
 		}
 	}
+	ScreenshotFlags struct {
+		Gapis      GapisFlags
+		Gapir      GapirFlags
+		At         int    `help:"command to take the screenshot after: -1 for the last command"`
+		Atoms      string `help:"comma-separated commands or N..M command ranges to take screenshots after"`
+		Frames     string `help:"comma-separated frames or N..M frame ranges, numbered from 1, to take screenshots at the end of"`
+		Attachment string `help:"attachment to save: color0 to color3, or depth"`
+		Out        string `help:"output image path, expanding {atom}, {frame} and {attachment}"`
+		Overdraw   bool   `help:"render a heatmap of the per-pixel overdraw instead of the color"`
+		Skip       string `help:"comma-separated commands or N..M command ranges whose draws, clears and submissions are skipped"`
+		Max        struct {
+			Width  int `help:"maximum screenshot width"`
+			Height int `help:"maximum screenshot height"`
+		}
+	}
 	DumpShadersFlags struct {
-		Gapis GapisFlags
-		Gapir GapirFlags
-		Atom  int `help:"atom to dump the resources after"`
+		Gapis     GapisFlags
+		Gapir     GapirFlags
+		Atom      int    `help:"atom to dump the resources after"`
+		Container string `help:"container to save textures in: ktx2 or dds"`
 	}
 	DumpFlags struct {
 		Gapis          GapisFlags
@@ -147,6 +329,7 @@ type (
 		Record struct {
 			Errors bool `help:"record device error state"`
 			Inputs bool `help:"record the inputs to file"`
+			Values bool `help:"record query results and timestamps for deterministic replay"`
 		}
 		Clear struct {
 			Cache bool `help:"clear package data before running it"`
@@ -164,8 +347,12 @@ type (
 			}
 		}
 		Capture struct {
-			Frames int `help:"only capture the given number of frames. 0 for all"`
+			Frames int `help:"only capture the given number of frames, then stop the trace. 0 for all"`
+		}
+		Chunk struct {
+			Size int `help:"stream the trace to disk in chunks of n megabytes, with an index written to out. 0 to disable"`
 		}
+		Compress string `help:"compress the trace with the given codec (none, deflate, lz4, zstd)"`
 	}
 	PackagesFlags struct {
 		DeviceFlags
@@ -0,0 +1,170 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+)
+
+type frameGraphVerb struct{ FrameGraphFlags }
+
+func init() {
+	verb := &frameGraphVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "framegraph",
+		ShortHelp: "Prints the graph of the passes of a capture and the resources they share",
+		Auto:      verb,
+	})
+}
+
+func (verb *frameGraphVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	graph, err := client.GetFrameGraph(ctx, capturePath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to get the frame graph of the capture")
+	}
+
+	thumbnails := map[int]string{}
+	if verb.Thumbnails != "" {
+		device, err := getDevice(ctx, client, capturePath, verb.Gapir)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(verb.Thumbnails, 0755); err != nil {
+			return log.Err(ctx, err, "Failed to create the thumbnails directory")
+		}
+		settings := &service.RenderSettings{MaxWidth: 128, MaxHeight: 128}
+		for i, pass := range graph.Passes {
+			if len(pass.Attachments) == 0 {
+				continue
+			}
+			cmd := capturePath.Commands().Index(pass.Last)
+			frame, err := renderAttachment(ctx, settings, cmd, device, client, pass.Attachments[0])
+			if err != nil {
+				log.W(ctx, "Failed to get the attachment of pass %d: %v", i, err)
+				continue
+			}
+			out := filepath.Join(verb.Thumbnails, fmt.Sprintf("pass_%d.png", i))
+			if err := writePNG(out, frame); err != nil {
+				return log.Err(ctx, err, "Failed to write the thumbnail")
+			}
+			thumbnails[i] = out
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open frame graph output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if verb.Dot {
+		writeFrameGraphDot(w, graph, thumbnails)
+		return nil
+	}
+	for i, pass := range graph.Passes {
+		fmt.Fprintf(w, "%d: %s [%d..%d] %s\n", i, framePassKind(pass.Kind), pass.First, pass.Last, pass.Name)
+		for _, e := range graph.Edges {
+			if e.To == uint32(i) {
+				fmt.Fprintf(w, "    <- %d: %s\n", e.From, frameResources(e.Resources))
+			}
+		}
+	}
+	return nil
+}
+
+// writeFrameGraphDot writes the graph in the Graphviz dot format, showing the
+// thumbnails of the passes that have one.
+func writeFrameGraphDot(w io.Writer, graph *service.FrameGraph, thumbnails map[int]string) {
+	fmt.Fprintln(w, "digraph framegraph {")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for i, pass := range graph.Passes {
+		label := fmt.Sprintf("%s\\n%s [%d..%d]", framePassKind(pass.Kind), pass.Name, pass.First, pass.Last)
+		if len(pass.Attachments) > 0 {
+			label += "\\n" + frameAttachments(pass.Attachments)
+		}
+		if thumbnail, ok := thumbnails[i]; ok {
+			fmt.Fprintf(w, "  pass%d [label=%q, image=%q, labelloc=b];\n", i, label, thumbnail)
+		} else {
+			fmt.Fprintf(w, "  pass%d [label=%q];\n", i, label)
+		}
+	}
+	for _, e := range graph.Edges {
+		fmt.Fprintf(w, "  pass%d -> pass%d [label=%q];\n", e.From, e.To, frameResources(e.Resources))
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func framePassKind(k service.FramePassKind) string {
+	switch k {
+	case service.FramePassKind_ComputePass:
+		return "compute"
+	case service.FramePassKind_TransferPass:
+		return "transfer"
+	default:
+		return "render"
+	}
+}
+
+func frameAttachments(l []gfxapi.FramebufferAttachment) string {
+	names := make([]string, len(l))
+	for i, a := range l {
+		names[i] = strings.ToLower(a.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+func frameResources(l []*service.FrameResource) string {
+	names := make([]string, len(l))
+	for i, r := range l {
+		names[i] = fmt.Sprintf("%s 0x%x", r.Kind, r.Handle)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+type indicesVerb struct{ IndicesFlags }
+
+func init() {
+	verb := &indicesVerb{}
+	verb.Cache.Size = gfxapi.DefaultVertexCacheSize
+	app.AddVerb(&app.Verb{
+		Name:      "indices",
+		ShortHelp: "Analyzes the index buffers of the draw calls of a .gfxtrace",
+		Auto:      verb,
+	})
+}
+
+func (verb *indicesVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+	if err != nil {
+		return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+	}
+	atoms := boxedAtoms.(*atom.List).Atoms
+
+	w := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', tabwriter.AlignRight)
+	defer w.Flush()
+	fmt.Fprintln(w, "Command\tIndices\tTriangles\tDegenerate\tRestarts\tACMR\tATVR\t")
+
+	total := gfxapi.IndexStats{}
+	for i, a := range atoms {
+		if !a.AtomFlags().IsDrawCall() {
+			continue
+		}
+		boxedMesh, err := client.Get(ctx, capturePath.Commands().Index(uint64(i)).Mesh(false).Path())
+		if err != nil {
+			log.W(ctx, "Could not get the mesh of command %d: %v", i, err)
+			continue
+		}
+		stats := boxedMesh.(*gfxapi.Mesh).IndexStats(verb.Cache.Size)
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%.3f\t%.3f\t\n", i, stats.Indices, stats.Triangles,
+			stats.Degenerate, stats.Restarts, stats.ACMR(), stats.ATVR())
+		total.Indices += stats.Indices
+		total.Triangles += stats.Triangles
+		total.Degenerate += stats.Degenerate
+		total.Restarts += stats.Restarts
+		total.Vertices += stats.Vertices
+		total.CacheMisses += stats.CacheMisses
+	}
+	fmt.Fprintf(w, "Total\t%d\t%d\t%d\t%d\t%.3f\t%.3f\t\n", total.Indices, total.Triangles,
+		total.Degenerate, total.Restarts, total.ACMR(), total.ATVR())
+	return nil
+}
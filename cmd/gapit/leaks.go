@@ -0,0 +1,103 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+type leaksVerb struct{ LeaksFlags }
+
+func init() {
+	verb := &leaksVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "leaks",
+		ShortHelp: "Lists the objects a capture creates and never releases",
+		Auto:      verb,
+	})
+}
+
+func (verb *leaksVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	report, err := client.GetLeakReport(ctx, capturePath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to find the leaks of the capture")
+	}
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open leak report output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count := 0
+	for _, group := range report.Groups {
+		fmt.Fprintf(w, "%d %s:\n", group.Command, group.CommandName)
+		for _, leak := range group.Leaks {
+			fmt.Fprintf(w, "    %s 0x%x", leak.Kind, leak.Handle)
+			if leak.Size > 0 {
+				fmt.Fprintf(w, " (%d bytes)", leak.Size)
+			}
+			fmt.Fprintf(w, ": %s\n", leakReason(leak.Reason))
+			count++
+		}
+	}
+	fmt.Fprintf(w, "%d leaks, %d bytes\n", count, report.Size)
+	return nil
+}
+
+func leakReason(r service.LeakReason) string {
+	switch r {
+	case service.LeakReason_NeverFreed:
+		return "never freed"
+	case service.LeakReason_NeverReset:
+		return "never reset"
+	default:
+		return "never destroyed"
+	}
+}
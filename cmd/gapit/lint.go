@@ -0,0 +1,86 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+)
+
+type lintVerb struct{ LintFlags }
+
+func init() {
+	verb := &lintVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "lint",
+		ShortHelp: "Checks the commands of a capture against correctness rules",
+		Auto:      verb,
+	})
+}
+
+func (verb *lintVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	report, err := client.GetLintReport(ctx, capturePath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to check the capture")
+	}
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open lint report output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "%d: [%s] %s", issue.Command, issue.Rule, issue.Message)
+		if len(issue.Related) > 0 {
+			fmt.Fprintf(w, " (see %v)", issue.Related)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d issues\n", len(report.Issues))
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+)
+
+type memoryVerb struct{ MemoryFlags }
+
+func init() {
+	verb := &memoryVerb{
+		MemoryFlags{
+			At:     -1,
+			Format: "hex",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "memory",
+		ShortHelp: "Dumps a range of the tracked memory after a command of a .gfxtrace file",
+		Auto:      verb,
+	})
+}
+
+// memoryFormats maps the typed dump formats to their element size.
+var memoryFormats = map[string]int{
+	"hex": 1,
+	"u8":  1, "u16": 2, "u32": 4, "u64": 8,
+	"s8": 1, "s16": 2, "s32": 4, "s64": 8,
+	"f32": 4, "f64": 8,
+}
+
+func (verb *memoryVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if _, ok := memoryFormats[verb.Format]; !ok {
+		app.Usage(ctx, "Unknown format %q, expected one of hex, u8, u16, u32, u64, s8, s16, s32, s64, f32 or f64", verb.Format)
+		return nil
+	}
+	base, size, err := parseMemoryRange(verb.Range)
+	if err != nil {
+		app.Usage(ctx, "Invalid memory range %q: %v", verb.Range, err)
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, GapirFlags{})
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	at := uint64(verb.At)
+	if verb.At < 0 {
+		boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+		if err != nil {
+			return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+		}
+		at = uint64(len(boxedAtoms.(*atom.List).Atoms) - 1)
+	}
+
+	memoryPath := capturePath.Commands().Index(at).MemoryAfter(uint32(verb.Pool), base, size)
+	boxedMemory, err := client.Get(ctx, memoryPath.Path())
+	if err != nil {
+		return log.Err(ctx, err, "Failed to acquire the capture's memory")
+	}
+	memory := boxedMemory.(*service.MemoryInfo)
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open the memory output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if verb.Format == "hex" {
+		dumpHex(w, base, memory)
+	} else {
+		dumpTyped(w, base, memory, verb.Format)
+	}
+	if verb.Accesses {
+		printMemoryRanges(w, "Read", base, memory.Reads)
+		printMemoryRanges(w, "Written", base, memory.Writes)
+	}
+	return nil
+}
+
+// parseMemoryRange parses ranges of the form base+size, such as 0x1000+256.
+func parseMemoryRange(s string) (base, size uint64, err error) {
+	parts := strings.Split(s, "+")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected <base>+<size>")
+	}
+	if base, err = strconv.ParseUint(parts[0], 0, 64); err != nil {
+		return 0, 0, err
+	}
+	if size, err = strconv.ParseUint(parts[1], 0, 64); err != nil {
+		return 0, 0, err
+	}
+	if size == 0 {
+		return 0, 0, fmt.Errorf("the size must be greater than 0")
+	}
+	return base, size, nil
+}
+
+// observed returns a per-byte mask of the data that was observed, as the rest
+// of the data holds no meaningful value.
+func observed(memory *service.MemoryInfo) []bool {
+	out := make([]bool, len(memory.Data))
+	for _, r := range memory.Observed {
+		for i := r.Base; i < r.Base+r.Size && i < uint64(len(out)); i++ {
+			out[i] = true
+		}
+	}
+	return out
+}
+
+// dumpHex writes the memory as 16 bytes per line, followed by their ASCII
+// representation. Bytes that were never observed are shown as '--'.
+func dumpHex(w io.Writer, base uint64, memory *service.MemoryInfo) {
+	known := observed(memory)
+	for line := 0; line < len(memory.Data); line += 16 {
+		hex, ascii := &bytes.Buffer{}, &bytes.Buffer{}
+		for i := line; i < line+16; i++ {
+			switch {
+			case i >= len(memory.Data):
+				hex.WriteString("   ")
+			case !known[i]:
+				hex.WriteString(" --")
+				ascii.WriteByte(' ')
+			default:
+				b := memory.Data[i]
+				fmt.Fprintf(hex, " %.2x", b)
+				if b >= 0x20 && b < 0x7f {
+					ascii.WriteByte(b)
+				} else {
+					ascii.WriteByte('.')
+				}
+			}
+		}
+		fmt.Fprintf(w, "%.16x:%s  |%s|\n", base+uint64(line), hex, ascii)
+	}
+}
+
+// dumpTyped writes the memory as little-endian values of the given format,
+// four per line. Values with any byte never observed are shown as '?'.
+func dumpTyped(w io.Writer, base uint64, memory *service.MemoryInfo, format string) {
+	known := observed(memory)
+	size := memoryFormats[format]
+	data := memory.Data
+	count := len(data) / size
+	for line := 0; line < count; line += 4 {
+		fmt.Fprintf(w, "%.16x:", base+uint64(line*size))
+		for i := line; i < line+4 && i < count; i++ {
+			offset := i * size
+			complete := true
+			for _, k := range known[offset : offset+size] {
+				complete = complete && k
+			}
+			if !complete {
+				fmt.Fprintf(w, " %24s", "?")
+				continue
+			}
+			fmt.Fprintf(w, " %24s", formatValue(data[offset:offset+size], format))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func formatValue(b []byte, format string) string {
+	le := binary.LittleEndian
+	switch format {
+	case "u8":
+		return fmt.Sprint(b[0])
+	case "u16":
+		return fmt.Sprint(le.Uint16(b))
+	case "u32":
+		return fmt.Sprint(le.Uint32(b))
+	case "u64":
+		return fmt.Sprint(le.Uint64(b))
+	case "s8":
+		return fmt.Sprint(int8(b[0]))
+	case "s16":
+		return fmt.Sprint(int16(le.Uint16(b)))
+	case "s32":
+		return fmt.Sprint(int32(le.Uint32(b)))
+	case "s64":
+		return fmt.Sprint(int64(le.Uint64(b)))
+	case "f32":
+		return fmt.Sprint(math.Float32frombits(le.Uint32(b)))
+	case "f64":
+		return fmt.Sprint(math.Float64frombits(le.Uint64(b)))
+	default:
+		panic(fmt.Errorf("Unknown memory format %v", format))
+	}
+}
+
+func printMemoryRanges(w io.Writer, kind string, base uint64, ranges []*service.MemoryRange) {
+	if len(ranges) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s by the command:\n", kind)
+	for _, r := range ranges {
+		fmt.Fprintf(w, "    0x%x+%d\n", base+r.Base, r.Size)
+	}
+}
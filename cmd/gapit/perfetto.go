@@ -0,0 +1,81 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+type perfettoVerb struct{ PerfettoFlags }
+
+func init() {
+	verb := &perfettoVerb{
+		PerfettoFlags{
+			Out: "capture.perfetto-trace",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "export-perfetto",
+		ShortHelp: "Exports the timeline of a capture as a Perfetto trace",
+		Auto:      verb,
+	})
+}
+
+func (verb *perfettoVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	var device *path.Device
+	if verb.Replay {
+		if device, err = getDevice(ctx, client, capturePath, verb.Gapir); err != nil {
+			return err
+		}
+	}
+
+	data, err := client.ExportPerfettoTrace(ctx, capturePath, device)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to export the Perfetto trace")
+	}
+
+	if err := ioutil.WriteFile(verb.Out, data, 0666); err != nil {
+		return log.Errf(ctx, err, "Could not write the trace to: %v", verb.Out)
+	}
+	return nil
+}
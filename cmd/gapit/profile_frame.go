@@ -0,0 +1,130 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+type profileFrameVerb struct{ ProfileFrameFlags }
+
+func init() {
+	verb := &profileFrameVerb{
+		ProfileFrameFlags{
+			Frame:      1,
+			Iterations: 100,
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "profile-frame",
+		ShortHelp: "Replays a frame of a capture in a loop and prints its GPU time",
+		Auto:      verb,
+	})
+}
+
+func (verb *profileFrameVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if verb.Frame < 1 {
+		app.Usage(ctx, "The frame must be at least 1, got %d", verb.Frame)
+		return nil
+	}
+	if verb.Iterations < 1 {
+		app.Usage(ctx, "The number of iterations must be at least 1, got %d", verb.Iterations)
+		return nil
+	}
+
+	experiments := []string{}
+	for _, name := range strings.Split(verb.Experiments, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			experiments = append(experiments, name)
+		}
+	}
+	params, err := parseExperimentParams(verb.Params)
+	if err != nil {
+		app.Usage(ctx, "%v", err)
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	device, err := getDevice(ctx, client, capturePath, verb.Gapir)
+	if err != nil {
+		return err
+	}
+
+	profile, err := client.GetFrameLoopProfile(ctx, device, capturePath, uint32(verb.Frame), uint32(verb.Iterations), nil, nil)
+	if err != nil {
+		return log.Errf(ctx, err, "Failed to profile frame %d", verb.Frame)
+	}
+
+	fmt.Printf("Frame %d, %d iterations\n", profile.Frame, len(profile.Durations))
+	printFrameLoopProfile(profile)
+
+	if len(experiments) == 0 {
+		return nil
+	}
+	modified, err := client.GetFrameLoopProfile(ctx, device, capturePath, uint32(verb.Frame), uint32(verb.Iterations), experiments, params)
+	if err != nil {
+		return log.Errf(ctx, err, "Failed to profile frame %d with the experiments %v", verb.Frame, verb.Experiments)
+	}
+
+	fmt.Printf("With %s", strings.Join(modified.Experiments, ", "))
+	names := make([]string, 0, len(modified.Parameters))
+	for name := range modified.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf(" %s=%s", name, modified.Parameters[name])
+	}
+	fmt.Println()
+	printFrameLoopProfile(modified)
+	saved := time.Duration(profile.Median) - time.Duration(modified.Median)
+	fmt.Printf("  saved:  %v (%.1f%% of the median)\n", saved, 100*float64(saved)/float64(profile.Median))
+	return nil
+}
+
+func printFrameLoopProfile(profile *service.FrameLoopProfile) {
+	fmt.Printf("  min:    %v\n", time.Duration(profile.Min))
+	fmt.Printf("  median: %v\n", time.Duration(profile.Median))
+	fmt.Printf("  max:    %v\n", time.Duration(profile.Max))
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/gapid/gapis/service"
+)
+
+const progressBarWidth = 30
+
+// replayProgress draws the status of the replays of the server as a progress
+// bar, rewriting the same line until the replay ends.
+type replayProgress struct {
+	w io.Writer
+}
+
+func (p replayProgress) update(s *service.ReplayStatus) {
+	var task string
+	var done, total uint64
+	switch s.Stage {
+	case service.ReplayStage_ReplayBuilding:
+		task, done, total = "building", s.CommandsBuilt, s.CommandsTotal
+	case service.ReplayStage_ReplayExecuting:
+		// Resources already cached on the device are not sent.
+		if toSend := s.ResourceBytesTotal - s.ResourceBytesCached; s.ResourceBytesSent < toSend {
+			task, done, total = "sending resources", s.ResourceBytesSent, toSend
+		} else {
+			task, done, total = "executing", s.CommandsExecuted, s.CommandsTotal
+		}
+	case service.ReplayStage_ReplayDone:
+		task, done, total = "done", 1, 1
+	default:
+		task, done, total = "failed", 0, 1
+	}
+
+	percent := uint64(100)
+	if total > 0 {
+		percent = done * 100 / total
+	}
+	filled := int(percent * progressBarWidth / 100)
+	bar := strings.Repeat("#", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	line := fmt.Sprintf("Replay %d: [%s] %3d%% %s, frame %d", s.Replay, bar, percent, task, s.Frame)
+	// Pad the line to overwrite any longer previous line.
+	fmt.Fprintf(p.w, "\r%-80s", line)
+
+	switch s.Stage {
+	case service.ReplayStage_ReplayDone, service.ReplayStage_ReplayFailed:
+		fmt.Fprintln(p.w)
+	}
+}
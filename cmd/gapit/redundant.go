@@ -0,0 +1,101 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+)
+
+type redundantVerb struct{ RedundantFlags }
+
+func init() {
+	verb := &redundantVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "redundant",
+		ShortHelp: "Reports the commands of a capture that set state to its current value",
+		Auto:      verb,
+	})
+}
+
+func (verb *redundantVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	report, err := client.GetRedundancyReport(ctx, capturePath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to find the redundant commands of the capture")
+	}
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open redundancy report output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	commands, redundant := uint64(0), uint64(0)
+	for _, frame := range report.Frames {
+		commands += frame.Commands
+		redundant += frame.Redundant
+		if frame.Redundant == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "Frame %d: %d of %d commands redundant\n", frame.Frame, frame.Redundant, frame.Commands)
+		for _, c := range frame.Counts {
+			fmt.Fprintf(w, "    %6d %s\n", c.Count, c.Name)
+		}
+	}
+	fmt.Fprintf(w, "Total: %d of %d commands redundant\n", redundant, commands)
+	for _, c := range report.Totals {
+		fmt.Fprintf(w, "    %6d %s\n", c.Count, c.Name)
+	}
+
+	if verb.Commands {
+		fmt.Fprintln(w)
+		for _, c := range report.Commands {
+			fmt.Fprintf(w, "%d %s: same as %d\n", c.Command, c.Name, c.Previous)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+)
+
+type renderDocVerb struct{ RenderDocFlags }
+
+func init() {
+	verb := &renderDocVerb{}
+	app.AddVerb(&app.Verb{
+		Name:      "renderdoc",
+		ShortHelp: "Exports the commands of a capture as RenderDoc event IDs",
+		Auto:      verb,
+	})
+}
+
+func (verb *renderDocVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	events, err := client.ExportRenderDocEvents(ctx, capturePath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to export the RenderDoc events")
+	}
+
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return log.Err(ctx, err, "Couldn't marshal the events to JSON")
+	}
+
+	if verb.Out == "" {
+		_, err := os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	if err := ioutil.WriteFile(verb.Out, data, 0666); err != nil {
+		return log.Errf(ctx, err, "Could not write the events to: %v", verb.Out)
+	}
+	return nil
+}
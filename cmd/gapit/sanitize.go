@@ -0,0 +1,84 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+type sanitizeVerb struct{ SanitizeFlags }
+
+func init() {
+	verb := &sanitizeVerb{
+		SanitizeFlags{
+			Shaders: true,
+			Names:   true,
+			Out:     "sanitized.gfxtrace",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "sanitize",
+		ShortHelp: "Removes the potentially sensitive data from a capture to share it",
+		Auto:      verb,
+	})
+}
+
+func (verb *sanitizeVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, GapirFlags{})
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	sanitized, err := client.SanitizeCapture(ctx, capturePath, &service.SanitizeOptions{
+		Shaders:  verb.Shaders,
+		Names:    verb.Names,
+		Textures: verb.Textures,
+	})
+	if err != nil {
+		return log.Err(ctx, err, "Failed to sanitize the capture")
+	}
+
+	data, err := client.ExportCapture(ctx, sanitized)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to export the sanitized capture")
+	}
+	if err := ioutil.WriteFile(verb.Out, data, 0666); err != nil {
+		return log.Errf(ctx, err, "Could not write the capture to: %v", verb.Out)
+	}
+	return nil
+}
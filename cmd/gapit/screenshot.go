@@ -0,0 +1,264 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+)
+
+type screenshotVerb struct{ ScreenshotFlags }
+
+func init() {
+	verb := &screenshotVerb{
+		ScreenshotFlags{
+			At:         allTheWay,
+			Attachment: "color0",
+			Out:        "screenshot.png",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "screenshot",
+		ShortHelp: "Saves the framebuffer after commands of a .gfxtrace as PNGs",
+		Auto:      verb,
+	})
+}
+
+var attachmentNames = map[string]gfxapi.FramebufferAttachment{
+	"color0": gfxapi.FramebufferAttachment_Color0,
+	"color1": gfxapi.FramebufferAttachment_Color1,
+	"color2": gfxapi.FramebufferAttachment_Color2,
+	"color3": gfxapi.FramebufferAttachment_Color3,
+	"depth":  gfxapi.FramebufferAttachment_Depth,
+}
+
+// screenshot is a single image to save.
+type screenshot struct {
+	atom  int // The command to take the screenshot after.
+	frame int // The frame holding the command, numbered from 1.
+}
+
+func (verb *screenshotVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	attachment, ok := attachmentNames[strings.ToLower(verb.Attachment)]
+	if !ok {
+		app.Usage(ctx, "Unknown attachment %q", verb.Attachment)
+		return nil
+	}
+
+	filepath, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capture, err := client.LoadCapture(ctx, filepath)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	device, err := getDevice(ctx, client, capture, verb.Gapir)
+	if err != nil {
+		return err
+	}
+
+	boxedAtoms, err := client.Get(ctx, capture.Commands().Path())
+	if err != nil {
+		return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+	}
+	shots, err := verb.screenshots(boxedAtoms.(*atom.List).Atoms)
+	if err != nil {
+		return err
+	}
+
+	settings := &service.RenderSettings{
+		MaxWidth:  uint32(verb.Max.Width),
+		MaxHeight: uint32(verb.Max.Height),
+	}
+	if verb.Overdraw {
+		settings.WireframeMode = service.WireframeMode_Overdraw
+	}
+	if verb.Skip != "" {
+		if settings.Skip, err = parseCommandRanges(verb.Skip); err != nil {
+			app.Usage(ctx, "Invalid command list %q: %v", verb.Skip, err)
+			return nil
+		}
+	}
+
+	// All the screenshots are rendered by the same GAPIS session, which
+	// batches their replays.
+	failed := 0
+	for _, shot := range shots {
+		out := verb.outPath(shot, len(shots) > 1)
+		ctx := log.V{"out": out}.Bind(ctx)
+		frame, err := renderAttachment(ctx, settings, capture.Commands().Index(uint64(shot.atom)), device, client, attachment)
+		if err != nil {
+			log.E(ctx, "Failed to get the framebuffer: %v", err)
+			failed++
+			continue
+		}
+		if err := writePNG(out, frame); err != nil {
+			log.E(ctx, "Failed to write the screenshot: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d screenshots failed", failed, len(shots))
+	}
+	return nil
+}
+
+// screenshots returns the screenshots selected by the flags, in command order.
+func (verb *screenshotVerb) screenshots(atoms []atom.Atom) ([]screenshot, error) {
+	// frames holds the frame of each command, and ends the index of the
+	// last command of each frame.
+	frames, ends := make([]int, len(atoms)), []int{}
+	for i, a := range atoms {
+		frames[i] = len(ends) + 1
+		if a.AtomFlags().IsEndOfFrame() {
+			ends = append(ends, i)
+		}
+	}
+
+	selected := map[int]bool{}
+	if verb.Atoms != "" {
+		ids, err := parseIndexList(verb.Atoms)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid command list %q: %v", verb.Atoms, err)
+		}
+		for _, id := range ids {
+			if id >= uint64(len(atoms)) {
+				return nil, fmt.Errorf("Command %d is out of range, the capture has %d commands", id, len(atoms))
+			}
+			selected[int(id)] = true
+		}
+	}
+	if verb.Frames != "" {
+		for _, r := range strings.Split(verb.Frames, ",") {
+			first, last, err := parseFrameRange(r)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid frame range %q: %v", r, err)
+			}
+			if last > uint64(len(ends)) {
+				return nil, fmt.Errorf("Frame %d is out of range, the capture has %d frames", last, len(ends))
+			}
+			for f := first; f <= last; f++ {
+				selected[ends[f-1]] = true
+			}
+		}
+	}
+	if len(selected) == 0 {
+		at := verb.At
+		if at == allTheWay {
+			at = len(atoms) - 1
+		}
+		selected[at] = true
+	}
+
+	shots := []screenshot{}
+	for i := range atoms {
+		if selected[i] {
+			shots = append(shots, screenshot{atom: i, frame: frames[i]})
+		}
+	}
+	return shots, nil
+}
+
+// outPath returns the path of the image for the screenshot shot. If the
+// output path is shared by multiple screenshots but does not distinguish them,
+// the command index is added to it.
+func (verb *screenshotVerb) outPath(shot screenshot, multiple bool) string {
+	out := verb.Out
+	if multiple && !strings.Contains(out, "{atom}") && !strings.Contains(out, "{frame}") {
+		ext := filepath.Ext(out)
+		out = strings.TrimSuffix(out, ext) + "-{atom}" + ext
+	}
+	return strings.NewReplacer(
+		"{atom}", strconv.Itoa(shot.atom),
+		"{frame}", strconv.Itoa(shot.frame),
+		"{attachment}", strings.ToLower(verb.Attachment),
+	).Replace(out)
+}
+
+// parseIndexList parses a comma-separated list of indices of the form N or
+// N..M, returning all the indices of the list.
+func parseIndexList(s string) ([]uint64, error) {
+	out := []uint64{}
+	for _, r := range strings.Split(s, ",") {
+		parts := strings.SplitN(r, "..", 2)
+		first, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(parts) == 2 {
+			if last, err = strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		if first > last {
+			return nil, fmt.Errorf("the range %v must not be empty", r)
+		}
+		for i := first; i <= last; i++ {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// parseCommandRanges parses a comma-separated list of commands or N..M
+// command ranges.
+func parseCommandRanges(s string) ([]*service.CommandRange, error) {
+	out := []*service.CommandRange{}
+	for _, r := range strings.Split(s, ",") {
+		ids, err := parseIndexList(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &service.CommandRange{First: ids[0], Count: uint64(len(ids))})
+	}
+	return out, nil
+}
+
+func writePNG(path string, frame image.Image) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return png.Encode(out, frame)
+}
@@ -0,0 +1,230 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+type stateVerb struct{ StateFlags }
+
+func init() {
+	verb := &stateVerb{
+		StateFlags{
+			At:     -1,
+			Format: "json",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "state",
+		ShortHelp: "Prints the API state, or a part of it, after a command of a .gfxtrace file",
+		Auto:      verb,
+	})
+}
+
+func (verb *stateVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if verb.Format != "json" && verb.Format != "text" {
+		app.Usage(ctx, "Unknown format %q, expected 'json' or 'text'", verb.Format)
+		return nil
+	}
+	segments, err := parseStatePath(verb.Path)
+	if err != nil {
+		app.Usage(ctx, "Invalid state path %q: %v", verb.Path, err)
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, GapirFlags{})
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	at := uint64(verb.At)
+	if verb.At < 0 {
+		boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+		if err != nil {
+			return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+		}
+		at = uint64(len(boxedAtoms.(*atom.List).Atoms) - 1)
+	}
+	root := capturePath.Commands().Index(at).StateAfter()
+
+	// Bracketed keys are first tried as map keys, as most of the API state is
+	// held in maps. An integer key the server reports as invalid is retried
+	// as an array index.
+	var value interface{}
+	for {
+		nodes := segments.build(root)
+		value, err = client.Get(ctx, nodes[len(nodes)-1].Path())
+		if err == nil {
+			break
+		}
+		if !segments.retryAsArrayIndex(nodes, err) {
+			return log.Errf(ctx, err, "Failed to get the state at command %d", at)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if verb.Out != "" {
+		f, err := os.OpenFile(verb.Out, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return log.Err(ctx, err, "Failed to open the state output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if verb.Format == "text" {
+		fmt.Fprintf(w, "%+v\n", value)
+		return nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return log.Err(ctx, err, "Failed to encode the state as JSON")
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// stateSegment is a single step of a state path: either a field name or a
+// bracketed key.
+type stateSegment struct {
+	field string
+	key   interface{}
+	array bool
+}
+
+type statePath []stateSegment
+
+// parseStatePath parses paths of the form Images[0x3].Info into a list of
+// field and key segments. Keys are parsed as integers or booleans where
+// possible, and as strings otherwise.
+func parseStatePath(s string) (statePath, error) {
+	out := statePath{}
+	if s == "" {
+		return out, nil
+	}
+	for _, part := range strings.Split(s, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name, part = part[:i], part[i:]
+		} else {
+			part = ""
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty field name")
+		}
+		out = append(out, stateSegment{field: name})
+		for part != "" {
+			end := strings.IndexByte(part, ']')
+			if part[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("malformed key in %q", part)
+			}
+			out = append(out, stateSegment{key: parseStateKey(part[1:end])})
+			part = part[end+1:]
+		}
+	}
+	return out, nil
+}
+
+func parseStateKey(s string) interface{} {
+	if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseBool(s); err == nil {
+		return v
+	}
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return s
+}
+
+// build returns the path nodes of each segment, rooted at root. The last node
+// is root itself if the path is empty.
+func (p statePath) build(root *path.State) []path.Node {
+	nodes := []path.Node{root}
+	var parent path.Node = root
+	for _, s := range p {
+		switch {
+		case s.field != "":
+			n := &path.Field{Name: s.field}
+			n.SetParent(parent)
+			parent = n
+		case s.array:
+			n := &path.ArrayIndex{Index: s.key.(uint64)}
+			n.SetParent(parent)
+			parent = n
+		default:
+			n := &path.MapIndex{Key: &path.MapIndex_Pod{pod.NewValue(s.key)}}
+			n.SetParent(parent)
+			parent = n
+		}
+		nodes = append(nodes, parent)
+	}
+	return nodes
+}
+
+// retryAsArrayIndex switches the integer map key that err reports as invalid
+// to an array index, returning false if there is no such key.
+func (p statePath) retryAsArrayIndex(nodes []path.Node, err error) bool {
+	invalid, ok := err.(*service.ErrInvalidPath)
+	if !ok || invalid.Path == nil {
+		return false
+	}
+	text := invalid.Path.Text()
+	for i, s := range p {
+		if _, isIndex := s.key.(uint64); !isIndex || s.array {
+			continue
+		}
+		if nodes[i+1].Text() == text {
+			p[i].array = true
+			return true
+		}
+	}
+	return false
+}
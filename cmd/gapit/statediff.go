@@ -0,0 +1,99 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+)
+
+type stateDiffVerb struct{ StateDiffFlags }
+
+func init() {
+	verb := &stateDiffVerb{
+		StateDiffFlags{
+			To: allTheWay,
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "statediff",
+		ShortHelp: "Prints the changes to the API state between two commands of a .gfxtrace file",
+		Auto:      verb,
+	})
+}
+
+func (verb *stateDiffVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	to := uint64(verb.To)
+	if verb.To < 0 {
+		boxedAtoms, err := client.Get(ctx, capturePath.Commands().Path())
+		if err != nil {
+			return log.Err(ctx, err, "Failed to acquire the capture's atoms")
+		}
+		to = uint64(len(boxedAtoms.(*atom.List).Atoms) - 1)
+	}
+
+	diff, err := client.GetStateDiff(ctx,
+		capturePath.Commands().Index(uint64(verb.From)),
+		capturePath.Commands().Index(to))
+	if err != nil {
+		return log.Err(ctx, err, "Failed to diff the state")
+	}
+
+	if len(diff.Changes) == 0 {
+		fmt.Println("No state changes")
+		return nil
+	}
+	for _, c := range diff.Changes {
+		switch c.Kind {
+		case service.StateChangeKind_StateCreated:
+			fmt.Printf("+ %v\n", c.Path)
+		case service.StateChangeKind_StateDestroyed:
+			fmt.Printf("- %v\n", c.Path)
+		case service.StateChangeKind_StateRebound:
+			fmt.Printf("  %v: -> %v (was -> %v)\n", c.Path, c.NewValue, c.OldValue)
+		default:
+			fmt.Printf("  %v: %v (was %v)\n", c.Path, c.NewValue, c.OldValue)
+		}
+	}
+	return nil
+}
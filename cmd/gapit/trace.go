@@ -19,6 +19,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -36,6 +37,7 @@ import (
 	"github.com/google/gapid/core/os/shell"
 	"github.com/google/gapid/core/vulkan/loader"
 	"github.com/google/gapid/gapii/client"
+	"github.com/google/gapid/gapis/capture"
 )
 
 type traceVerb struct{ TraceFlags }
@@ -49,13 +51,30 @@ func init() {
 	})
 }
 
+// validate returns an error if the flags of the verb are inconsistent.
+func (verb *traceVerb) validate() error {
+	if verb.Start.Defer && verb.Start.At.Frame != 0 {
+		return fmt.Errorf("start-defer and start-at-frame cannot be used together")
+	}
+	if verb.Start.At.Frame < 0 {
+		return fmt.Errorf("start-at-frame must not be negative")
+	}
+	_, err := parseCodec(verb.Compress)
+	return err
+}
+
 func (verb *traceVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if err := verb.validate(); err != nil {
+		return err
+	}
+
 	options := client.Options{
 		ObserveFrameFrequency: uint32(verb.Observe.Frames),
 		ObserveDrawFrequency:  uint32(verb.Observe.Draws),
 		StartFrame:            uint32(verb.Start.At.Frame),
 		FramesToCapture:       uint32(verb.Capture.Frames),
 		APK:                   verb.APK,
+		ChunkSize:             uint64(verb.Chunk.Size) * 1024 * 1024,
 	}
 
 	if verb.Disable.PCS {
@@ -67,6 +86,9 @@ func (verb *traceVerb) Run(ctx context.Context, flags flag.FlagSet) error {
 	if verb.Start.Defer {
 		options.Flags |= client.DeferStart
 	}
+	if verb.Record.Values {
+		options.Flags |= client.RecordNondeterministicValues
+	}
 
 	if !verb.Local.App.IsEmpty() {
 		cleanup, err := verb.startLocalApp(ctx)
@@ -115,7 +137,7 @@ func (verb *traceVerb) captureLocal(ctx context.Context, flags flag.FlagSet, por
 	if output == "" {
 		output = "capture.gfxtrace"
 	}
-	return doCapture(ctx, options, port, output, verb.For)
+	return doCapture(ctx, options, port, output, verb.Compress, verb.For)
 }
 
 func (verb *traceVerb) captureADB(ctx context.Context, flags flag.FlagSet, options client.Options) error {
@@ -243,17 +265,43 @@ func (verb *traceVerb) captureADB(ctx context.Context, flags flag.FlagSet, optio
 		}
 	}
 
-	return doCapture(ctx, options, int(port), output, verb.For)
+	return doCapture(ctx, options, int(port), output, verb.Compress, verb.For)
+}
+
+// parseCodec returns the capture codec with the given name, or
+// capture.Uncompressed if name is empty.
+func parseCodec(name string) (capture.Codec, error) {
+	if name == "" {
+		return capture.Uncompressed, nil
+	}
+	return capture.ParseCodec(name)
 }
 
-func doCapture(ctx context.Context, options client.Options, port int, out string, duration time.Duration) error {
+func doCapture(ctx context.Context, options client.Options, port int, out string, compress string, duration time.Duration) error {
+	codec, err := parseCodec(compress)
+	if err != nil {
+		return err
+	}
+
 	log.I(ctx, "Creating file '%v'", out)
 	os.MkdirAll(filepath.Dir(out), 0755)
-	file, err := os.Create(out)
+	var file io.WriteCloser
+	if options.ChunkSize != 0 {
+		file, err = capture.NewChunkWriter(out, options.ChunkSize)
+	} else {
+		file, err = os.Create(out)
+	}
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+
+	w := io.WriteCloser(file)
+	if codec != capture.Uncompressed {
+		if w, err = capture.NewCompressor(file, codec); err != nil {
+			file.Close()
+			return err
+		}
+	}
 
 	signal, fireSignal := task.NewSignal()
 	if duration == 0 {
@@ -266,18 +314,28 @@ func doCapture(ctx context.Context, options client.Options, port int, out string
 				_, _ = reader.ReadString('\n')
 				fireSignal(ctx)
 			}
-			println("Press enter to stop capturing...")
+			if options.FramesToCapture != 0 {
+				println(fmt.Sprintf("Capturing %d frames. Press enter to stop early...", options.FramesToCapture))
+			} else {
+				println("Press enter to stop capturing...")
+			}
 			_, _ = reader.ReadString('\n')
 			cancel()
 		}()
 	} else {
 		ctx, _ = task.WithTimeout(ctx, duration)
 	}
-	_, err = client.Capture(ctx, port, signal, file, options)
-	if err != nil {
-		return err
+	_, err = client.Capture(ctx, port, signal, w, options)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if w != file {
+		// The compressors do not close the file they write to.
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
 	}
-	return nil
+	return err
 }
 
 func getAction(ctx context.Context, d adb.Device, pattern string) (*android.ActivityAction, error) {
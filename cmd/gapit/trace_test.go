@@ -0,0 +1,45 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+)
+
+func TestTraceValidate(t *testing.T) {
+	ctx := assert.Context(t)
+	for _, test := range []struct {
+		name     string
+		deferred bool
+		frame    int
+		compress string
+		valid    bool
+	}{
+		{"defaults", false, 0, "", true},
+		{"deferred", true, 0, "", true},
+		{"at frame", false, 10, "", true},
+		{"deferred at frame", true, 10, "", false},
+		{"negative frame", false, -1, "", false},
+		{"unknown codec", false, 0, "unknown", false},
+	} {
+		verb := &traceVerb{}
+		verb.Start.Defer = test.deferred
+		verb.Start.At.Frame = test.frame
+		verb.Compress = test.compress
+		assert.For(ctx, test.name).That(verb.validate() == nil).Equals(test.valid)
+	}
+}
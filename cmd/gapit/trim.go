@@ -0,0 +1,109 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/log"
+)
+
+type trimVerb struct{ TrimFlags }
+
+func init() {
+	verb := &trimVerb{
+		TrimFlags{
+			Out: "trim.gfxtrace",
+		},
+	}
+	app.AddVerb(&app.Verb{
+		Name:      "trim",
+		ShortHelp: "Extracts the commands required to replay a range of frames",
+		Auto:      verb,
+	})
+}
+
+func (verb *trimVerb) Run(ctx context.Context, flags flag.FlagSet) error {
+	if flags.NArg() != 1 {
+		app.Usage(ctx, "Exactly one gfx trace file expected, got %d", flags.NArg())
+		return nil
+	}
+	if verb.Frame == "" {
+		app.Usage(ctx, "The frames to keep must be given with -frame")
+		return nil
+	}
+
+	first, last, err := parseFrameRange(verb.Frame)
+	if err != nil {
+		app.Usage(ctx, "Invalid frame range '%v': %v", verb.Frame, err)
+		return nil
+	}
+
+	capture, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return log.Errf(ctx, err, "Could not find capture file: %v", flags.Arg(0))
+	}
+
+	client, err := getGapis(ctx, verb.Gapis, verb.Gapir)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to the GAPIS server")
+	}
+	defer client.Close()
+
+	capturePath, err := client.LoadCapture(ctx, capture)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to load the capture file")
+	}
+
+	trimmed, err := client.TrimCapture(ctx, capturePath, first, last)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to trim the capture")
+	}
+
+	data, err := client.ExportCapture(ctx, trimmed)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to export the trimmed capture")
+	}
+
+	if err := ioutil.WriteFile(verb.Out, data, 0666); err != nil {
+		return log.Errf(ctx, err, "Could not write the capture to: %v", verb.Out)
+	}
+	return nil
+}
+
+// parseFrameRange parses a frame range of the form N or N..M.
+func parseFrameRange(s string) (first, last uint64, err error) {
+	parts := strings.SplitN(s, "..", 2)
+	if first, err = strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64); err != nil {
+		return 0, 0, err
+	}
+	last = first
+	if len(parts) == 2 {
+		if last, err = strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	if first == 0 || first > last {
+		return 0, 0, fmt.Errorf("frames are numbered from 1 and the range must not be empty")
+	}
+	return first, last, nil
+}
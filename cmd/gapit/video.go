@@ -24,15 +24,18 @@ import (
 	"image/draw"
 	"image/png"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/data/endian"
 	"github.com/google/gapid/core/event/task"
 	"github.com/google/gapid/core/image/font"
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/math/sint"
+	"github.com/google/gapid/core/os/device"
 	"github.com/google/gapid/core/os/file"
 	"github.com/google/gapid/core/text/reflow"
 	"github.com/google/gapid/core/video"
@@ -314,9 +317,24 @@ func (verb *videoVerb) writeSingleFrame(frame image.Image, fn string) error {
 	return png.Encode(out, frame)
 }
 
+// codec returns the codec to encode the video with.
+func (verb *videoVerb) codec() video.Codec {
+	switch verb.Codec {
+	case H264Codec:
+		return video.H264
+	case VP9Codec:
+		return video.VP9
+	}
+	if verb.Out != "" && strings.EqualFold(file.Abs(verb.Out).Ext(), ".webm") {
+		return video.VP9
+	}
+	return video.H264
+}
+
 func (verb *videoVerb) encodeVideo(ctx context.Context, filepath string, vidFun videoFrameWriter) error {
 	// Start an encoder
-	frames, video, err := video.Encode(ctx, video.Settings{FPS: verb.FPS})
+	codec := verb.codec()
+	frames, video, err := video.Encode(ctx, video.Settings{FPS: verb.FPS, Codec: codec})
 	if err != nil {
 		return err
 	}
@@ -328,7 +346,7 @@ func (verb *videoVerb) encodeVideo(ctx context.Context, filepath string, vidFun
 
 	out := verb.Out
 	if out == "" {
-		out = file.Abs(filepath).ChangeExt(".mp4").System()
+		out = file.Abs(filepath).ChangeExt(codec.Ext()).System()
 	}
 	mpg, err := os.Create(out)
 	if err != nil {
@@ -347,9 +365,21 @@ func (verb *videoVerb) encodeVideo(ctx context.Context, filepath string, vidFun
 }
 
 func getFrame(ctx context.Context, flags VideoFlags, cmd *path.Command, device *path.Device, client service.Service) (*image.NRGBA, error) {
-	ctx = log.V{"cmd": int(cmd.Index)}.Bind(ctx)
 	settings := &service.RenderSettings{MaxWidth: uint32(flags.Max.Width), MaxHeight: uint32(flags.Max.Height)}
-	iip, err := client.GetFramebufferAttachment(ctx, device, cmd, gfxapi.FramebufferAttachment_Color0, settings, nil)
+	return renderFrame(ctx, settings, cmd, device, client)
+}
+
+// renderFrame returns the color attachment after cmd, rendered with settings.
+func renderFrame(ctx context.Context, settings *service.RenderSettings, cmd *path.Command, device *path.Device, client service.Service) (*image.NRGBA, error) {
+	return renderAttachment(ctx, settings, cmd, device, client, gfxapi.FramebufferAttachment_Color0)
+}
+
+// renderAttachment returns the attachment after cmd, rendered with settings.
+// Depth attachments are returned as grayscale images, stretched to the range
+// of depths of the image.
+func renderAttachment(ctx context.Context, settings *service.RenderSettings, cmd *path.Command, device *path.Device, client service.Service, attachment gfxapi.FramebufferAttachment) (*image.NRGBA, error) {
+	ctx = log.V{"cmd": int(cmd.Index), "attachment": attachment}.Bind(ctx)
+	iip, err := client.GetFramebufferAttachment(ctx, device, cmd, attachment, settings, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -372,6 +402,9 @@ func getFrame(ctx context.Context, flags VideoFlags, cmd *path.Command, device *
 	if ii.Width == 0 || ii.Height == 0 {
 		return nil, log.Err(ctx, nil, "Framebuffer has zero dimensions")
 	}
+	if attachment == gfxapi.FramebufferAttachment_Depth {
+		return depthToGray(ctx, data, w, h, ii.Format)
+	}
 	data, err = img.Convert(data, w, h, ii.Format, img.RGBA_U8_NORM)
 	if err != nil {
 		return nil, log.Err(ctx, err, "Failed to convert frame to RGBA")
@@ -383,3 +416,35 @@ func getFrame(ctx context.Context, flags VideoFlags, cmd *path.Command, device *
 		Pix:    data,
 	}, nil
 }
+
+// depthToGray returns the depth image data as a grayscale image, with the
+// nearest depth black and the farthest white.
+func depthToGray(ctx context.Context, data []byte, w, h int, format *img.Format) (*image.NRGBA, error) {
+	data, err := img.Convert(data, w, h, format, img.D_F32)
+	if err != nil {
+		return nil, log.Err(ctx, err, "Failed to convert depth")
+	}
+	depths := make([]float32, w*h)
+	r := endian.Reader(bytes.NewReader(data), device.LittleEndian)
+	min, max := float32(math.Inf(1)), float32(math.Inf(-1))
+	for i := range depths {
+		d := r.Float32()
+		depths[i] = d
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	scale := float32(0)
+	if max > min {
+		scale = 255 / (max - min)
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i, d := range depths {
+		v := uint8((d - min) * scale)
+		out.Pix[i*4+0], out.Pix[i*4+1], out.Pix[i*4+2], out.Pix[i*4+3] = v, v, v, 255
+	}
+	return out, nil
+}
@@ -34,6 +34,7 @@ import (
 var (
 	packFile   file.Path
 	legacyFile file.Path
+	blocksFile file.Path
 	timing     bool
 )
 
@@ -42,6 +43,7 @@ func main() {
 	app.Version = app.VersionSpec{Major: 0, Minor: 1}
 	flag.Var(&packFile, "pack", "the pack file to generate")
 	flag.Var(&legacyFile, "legacy", "the legacy file to generate")
+	flag.Var(&blocksFile, "blocks", "the block format file to generate")
 	flag.BoolVar(&timing, "time", false, "time the encode and decode performanc3")
 	app.Run(run)
 }
@@ -79,6 +81,13 @@ func run(ctx context.Context) error {
 		}
 		log.I(ctx, "Wrote %v atoms to pack file in %v", len(atoms.Atoms), d)
 	}
+	if !blocksFile.IsEmpty() {
+		d := delta(func() { err = writeAtoms(ctx, blocksFile.System(), atoms, capture.WriteBlocks) })
+		if err != nil {
+			return log.Err(ctx, err, "Unable write blocks")
+		}
+		log.I(ctx, "Wrote %v atoms to block file in %v", len(atoms.Atoms), d)
+	}
 
 	if !timing {
 		return nil
@@ -20,6 +20,9 @@ import (
 
 	"github.com/google/gapid/core/app/auth"
 	"github.com/google/gapid/core/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestWrite(t *testing.T) {
@@ -61,3 +64,53 @@ func (r *readCloser) Close() error {
 	r.closed = true
 	return nil
 }
+
+// serverStream is a grpc.ServerStream of the given context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	assert := assert.To(t)
+	interceptor := auth.StreamServerInterceptor(auth.Token("abc"))
+	for _, test := range []struct {
+		name     string
+		metadata metadata.MD
+		expected error
+	}{
+		{
+			name:     "no-metadata",
+			expected: auth.ErrInvalidToken,
+		},
+		{
+			name:     "no-token",
+			metadata: metadata.Pairs("other", "abc"),
+			expected: auth.ErrInvalidToken,
+		},
+		{
+			name:     "wrong-token",
+			metadata: metadata.Pairs("auth_token", "abd"),
+			expected: auth.ErrInvalidToken,
+		},
+		{
+			name:     "token",
+			metadata: metadata.Pairs("auth_token", "abc"),
+		},
+	} {
+		ctx := context.Background()
+		if test.metadata != nil {
+			ctx = metadata.NewContext(ctx, test.metadata)
+		}
+		handled := false
+		handler := func(srv interface{}, ss grpc.ServerStream) error {
+			handled = true
+			return nil
+		}
+		err := interceptor(nil, serverStream{ctx: ctx}, &grpc.StreamServerInfo{}, handler)
+		assert.For("%s error", test.name).ThatError(err).Equals(test.expected)
+		assert.For("%s handled", test.name).That(handled).Equals(test.expected == nil)
+	}
+}
@@ -36,6 +36,7 @@ type LibraryType int
 const (
 	LibGraphicsSpy LibraryType = iota
 	LibVirtualSwapChain
+	LibSwiftShader
 )
 
 // FileLayout provides a unified way of accessing various Gapid binaries.
@@ -52,18 +53,21 @@ type FileLayout interface {
 	GapidApk(ctx context.Context, abi *device.ABI) (file.Path, error)
 	// Library returns the path to the requested library.
 	Library(ctx context.Context, lib LibraryType) (file.Path, error)
-	// Json returns the path to the Vulkan layer JSON definition for the given library.
+	// Json returns the path to the Vulkan layer or driver JSON definition for
+	// the given library.
 	Json(ctx context.Context, lib LibraryType) (file.Path, error)
 }
 
 var libTypeToName = map[LibraryType]string{
 	LibGraphicsSpy:      withPlatformSuffix("libgapii"),
 	LibVirtualSwapChain: withPlatformSuffix("libVkLayer_VirtualSwapchain"),
+	LibSwiftShader:      withPlatformSuffix("libvk_swiftshader"),
 }
 
 var libTypeToJson = map[LibraryType]string{
 	LibGraphicsSpy:      "GraphicsSpyLayer.json",
 	LibVirtualSwapChain: "VirtualSwapchainLayer.json",
+	LibSwiftShader:      "vk_swiftshader_icd.json",
 }
 
 func withPlatformSuffix(lib string) string {
@@ -106,6 +106,10 @@ bool Archive::write(const std::string& id, const void* buffer, uint32_t size) {
         return false;
     }
 
+    // Flush the files so the record survives the process being killed.
+    fflush(mDataFile);
+    fflush(mIndexFile);
+
     // Update the memory index.
     mRecords.emplace(id, ArchiveRecord{ dataOffset, size });
     return true;
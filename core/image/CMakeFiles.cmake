@@ -19,8 +19,14 @@
 
 set(files
     astc.go
+    astc_decode.go
     atc.go
+    bptc.go
+    compressed_test.go
+    container.go
+    container_test.go
     convert.go
+    dds.go
     decompress_test.go
     doc.go
     etc1.go
@@ -31,10 +37,12 @@ set(files
     image.pb.go
     image.proto
     image_test.go
+    ktx2.go
     png.go
     resizer.go
     rgba_f32.go
     rgba_f32_test.go
+    rgtc.go
     s3.go
     s3_dxt1_rgb.go
     s3_dxt1_rgba.go
@@ -0,0 +1,1077 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/math/f16"
+	"github.com/google/gapid/core/os/device"
+)
+
+// The ASTC decoder follows the Khronos Data Format Specification, decoding
+// both the LDR and HDR profiles for 2D blocks.
+
+// astcTexel is a decoded ASTC texel. Each channel is either an UNORM16 value
+// or, for HDR channels, a half float.
+type astcTexel struct {
+	v   [4]uint16
+	hdr [4]bool
+}
+
+// astcErrorTexel is the texel returned for illegal blocks.
+var astcErrorTexel = astcTexel{v: [4]uint16{0xffff, 0, 0xffff, 0xffff}}
+
+func (t astcTexel) float32s() [4]float32 {
+	out := [4]float32{}
+	for c := range t.v {
+		if t.hdr[c] {
+			out[c] = f16.Number(t.v[c]).Float32()
+		} else {
+			out[c] = float32(t.v[c]) / 0xffff
+		}
+	}
+	return out
+}
+
+func (t astcTexel) unorm8s() [4]byte {
+	out := [4]byte{}
+	for c := range t.v {
+		if t.hdr[c] {
+			f := f16.Number(t.v[c]).Float32()
+			out[c] = byte(math.Min(math.Max(float64(f), 0), 1)*0xff + 0.5)
+		} else {
+			out[c] = byte(t.v[c] >> 8)
+		}
+	}
+	return out
+}
+
+// decodeASTC decodes the ASTC image into texels.
+func decodeASTC(src []byte, width, height int, f *FmtASTC) ([]astcTexel, error) {
+	bw, bh := int(f.BlockWidth), int(f.BlockHeight)
+	texels := make([]astcTexel, width*height)
+	block := make([]astcTexel, bw*bh)
+	r := endian.Reader(bytes.NewReader(src), device.LittleEndian)
+	for y := 0; y < height; y += bh {
+		for x := 0; x < width; x += bw {
+			b := astcBlock{r.Uint64(), r.Uint64()}
+			b.decode(bw, bh, f.Srgb, block)
+			for dy := 0; dy < bh && y+dy < height; dy++ {
+				for dx := 0; dx < bw && x+dx < width; dx++ {
+					texels[(y+dy)*width+x+dx] = block[dy*bw+dx]
+				}
+			}
+		}
+	}
+	return texels, r.Error()
+}
+
+func decodeASTCToRGBA_U8_NORM(src []byte, width, height int, f *FmtASTC) ([]byte, error) {
+	texels, err := decodeASTC(src, width, height, f)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(texels)*4)
+	for _, t := range texels {
+		v := t.unorm8s()
+		out = append(out, v[:]...)
+	}
+	return out, nil
+}
+
+func decodeASTCToRGBA_F32(src []byte, width, height int, f *FmtASTC) ([]byte, error) {
+	texels, err := decodeASTC(src, width, height, f)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	for _, t := range texels {
+		for _, v := range t.float32s() {
+			w.Float32(v)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// astcBlock is a 128 bit ASTC block.
+type astcBlock struct {
+	lo, hi uint64
+}
+
+// bits returns the n bits of the block starting at bit start. Bits past the
+// end of the block are read as zero.
+func (b astcBlock) bits(start, n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		p := uint(start + i)
+		switch {
+		case p < 64:
+			v |= int(b.lo>>p&1) << uint(i)
+		case p < 128:
+			v |= int(b.hi>>(p-64)&1) << uint(i)
+		}
+	}
+	return v
+}
+
+// reversed returns the block with the order of its bits reversed.
+func (b astcBlock) reversed() astcBlock {
+	reverse := func(v uint64) uint64 {
+		out := uint64(0)
+		for i := 0; i < 64; i++ {
+			out = out<<1 | v&1
+			v >>= 1
+		}
+		return out
+	}
+	return astcBlock{reverse(b.hi), reverse(b.lo)}
+}
+
+// astcRange describes an integer sequence encoding range as a number of bits
+// and an optional trit or quint.
+type astcRange struct {
+	bits          int
+	trits, quints bool
+}
+
+func (r astcRange) levels() int {
+	switch {
+	case r.trits:
+		return 3 << uint(r.bits)
+	case r.quints:
+		return 5 << uint(r.bits)
+	default:
+		return 1 << uint(r.bits)
+	}
+}
+
+// sequenceBits returns the number of bits used to encode count values.
+func (r astcRange) sequenceBits(count int) int {
+	n := count * r.bits
+	switch {
+	case r.trits:
+		n += (count*8 + 4) / 5
+	case r.quints:
+		n += (count*7 + 2) / 3
+	}
+	return n
+}
+
+// astcWeightRanges holds the weight ranges indexed by the range bits of the
+// block mode and the high precision bit.
+var astcWeightRanges = [2][8]astcRange{
+	{{}, {}, {1, false, false}, {0, true, false}, {2, false, false}, {0, false, true}, {1, true, false}, {3, false, false}},
+	{{}, {}, {1, false, true}, {2, true, false}, {4, false, false}, {2, false, true}, {3, true, false}, {5, false, false}},
+}
+
+// astcColorRanges holds the color endpoint ranges, from the largest to the
+// smallest.
+var astcColorRanges = []astcRange{
+	{8, false, false}, {6, true, false}, {5, false, true}, {7, false, false},
+	{5, true, false}, {4, false, true}, {6, false, false}, {4, true, false},
+	{3, false, true}, {5, false, false}, {3, true, false}, {2, false, true},
+	{4, false, false}, {2, true, false}, {1, false, true}, {3, false, false},
+	{1, true, false},
+}
+
+// decodeISE decodes count integers of the range r from the bits of b starting
+// at start.
+func decodeISE(b astcBlock, start, count int, r astcRange) []int {
+	out := make([]int, 0, count+4)
+	pos, end := start, start+r.sequenceBits(count)
+	read := func(n int) int {
+		// Bits past the end of the sequence are read as zero.
+		if pos+n > end {
+			n = end - pos
+		}
+		if n <= 0 {
+			return 0
+		}
+		v := b.bits(pos, n)
+		pos += n
+		return v
+	}
+	switch {
+	case r.trits:
+		for len(out) < count {
+			var m [5]int
+			m[0] = read(r.bits)
+			t := read(2)
+			m[1] = read(r.bits)
+			t |= read(2) << 2
+			m[2] = read(r.bits)
+			t |= read(1) << 4
+			m[3] = read(r.bits)
+			t |= read(2) << 5
+			m[4] = read(r.bits)
+			t |= read(1) << 7
+			trits := decodeTrits(t)
+			for i := range m {
+				out = append(out, trits[i]<<uint(r.bits)|m[i])
+			}
+		}
+	case r.quints:
+		for len(out) < count {
+			var m [3]int
+			m[0] = read(r.bits)
+			q := read(3)
+			m[1] = read(r.bits)
+			q |= read(2) << 3
+			m[2] = read(r.bits)
+			q |= read(2) << 5
+			quints := decodeQuints(q)
+			for i := range m {
+				out = append(out, quints[i]<<uint(r.bits)|m[i])
+			}
+		}
+	default:
+		for len(out) < count {
+			out = append(out, read(r.bits))
+		}
+	}
+	return out[:count]
+}
+
+func bit(v int, i uint) int {
+	return (v >> i) & 1
+}
+
+// decodeTrits decodes the 5 trits packed into the 8 bits t.
+func decodeTrits(t int) [5]int {
+	var c, t0, t1, t2, t3, t4 int
+	if (t>>2)&7 == 7 {
+		c = (t>>5&7)<<2 | t&3
+		t4, t3 = 2, 2
+	} else {
+		c = t & 0x1f
+		if (t>>5)&3 == 3 {
+			t4, t3 = 2, bit(t, 7)
+		} else {
+			t4, t3 = bit(t, 7), (t>>5)&3
+		}
+	}
+	switch {
+	case c&3 == 3:
+		t2, t1 = 2, bit(c, 4)
+		t0 = bit(c, 3)<<1 | (bit(c, 2) &^ bit(c, 3))
+	case (c>>2)&3 == 3:
+		t2, t1, t0 = 2, 2, c&3
+	default:
+		t2, t1 = bit(c, 4), (c>>2)&3
+		t0 = bit(c, 1)<<1 | (bit(c, 0) &^ bit(c, 1))
+	}
+	return [5]int{t0, t1, t2, t3, t4}
+}
+
+// decodeQuints decodes the 3 quints packed into the 7 bits q.
+func decodeQuints(q int) [3]int {
+	var q0, q1, q2 int
+	if (q>>1)&3 == 3 && (q>>5)&3 == 0 {
+		q2 = bit(q, 0)<<2 | (bit(q, 4)&^bit(q, 0))<<1 | (bit(q, 3) &^ bit(q, 0))
+		q1, q0 = 4, 4
+	} else {
+		var c int
+		if (q>>1)&3 == 3 {
+			q2 = 4
+			c = (q>>3&3)<<3 | (^(q>>5)&3)<<1 | q&1
+		} else {
+			q2 = (q >> 5) & 3
+			c = q & 0x1f
+		}
+		if c&7 == 5 {
+			q1, q0 = 4, (c>>3)&3
+		} else {
+			q1, q0 = (c>>3)&3, c&7
+		}
+	}
+	return [3]int{q0, q1, q2}
+}
+
+// unquantizeColor returns the integer sequence value v of the range r
+// unquantized to the range [0, 255].
+func unquantizeColor(v int, r astcRange) int {
+	if !r.trits && !r.quints {
+		return replicateBits(v, uint(r.bits), 8)
+	}
+	a := bit(v, 0) * 0x1ff
+	b, c, d := 0, 0, v>>uint(r.bits)
+	bits := v & (1<<uint(r.bits) - 1)
+	x := func(i uint) int { return bit(bits, i) }
+	if r.trits {
+		switch r.bits {
+		case 1:
+			c = 204
+		case 2:
+			c = 93
+			b = x(1)<<8 | x(1)<<4 | x(1)<<2 | x(1)<<1
+		case 3:
+			c = 44
+			b = x(2)<<8 | x(1)<<7 | x(2)<<3 | x(1)<<2 | x(2)<<1 | x(1)
+		case 4:
+			c = 22
+			b = x(3)<<8 | x(2)<<7 | x(1)<<6 | x(3)<<2 | x(2)<<1 | x(1)
+		case 5:
+			c = 11
+			b = x(4)<<8 | x(3)<<7 | x(2)<<6 | x(1)<<5 | x(4)<<1 | x(3)
+		case 6:
+			c = 5
+			b = x(5)<<8 | x(4)<<7 | x(3)<<6 | x(2)<<5 | x(1)<<4 | x(5)
+		}
+	} else {
+		switch r.bits {
+		case 1:
+			c = 113
+		case 2:
+			c = 54
+			b = x(1)<<8 | x(1)<<3 | x(1)<<2
+		case 3:
+			c = 26
+			b = x(2)<<8 | x(1)<<7 | x(2)<<2 | x(1)<<1 | x(2)
+		case 4:
+			c = 13
+			b = x(3)<<8 | x(2)<<7 | x(1)<<6 | x(3)<<1 | x(2)
+		case 5:
+			c = 6
+			b = x(4)<<8 | x(3)<<7 | x(2)<<6 | x(1)<<5 | x(4)
+		}
+	}
+	t := (d*c + b) ^ a
+	return (a & 0x80) | (t >> 2)
+}
+
+// unquantizeWeight returns the integer sequence value v of the range r
+// unquantized to the range [0, 64].
+func unquantizeWeight(v int, r astcRange) int {
+	var t int
+	switch {
+	case r.trits && r.bits == 0:
+		return []int{0, 32, 64}[v]
+	case r.quints && r.bits == 0:
+		return []int{0, 16, 32, 48, 64}[v]
+	case r.trits || r.quints:
+		a := bit(v, 0) * 0x7f
+		b, c, d := 0, 0, v>>uint(r.bits)
+		bits := v & (1<<uint(r.bits) - 1)
+		x := func(i uint) int { return bit(bits, i) }
+		switch {
+		case r.trits && r.bits == 1:
+			c = 50
+		case r.trits && r.bits == 2:
+			c = 23
+			b = x(1)<<6 | x(1)<<2 | x(1)
+		case r.trits && r.bits == 3:
+			c = 11
+			b = x(2)<<6 | x(1)<<5 | x(2)<<1 | x(1)
+		case r.quints && r.bits == 1:
+			c = 28
+		case r.quints && r.bits == 2:
+			c = 13
+			b = x(1)<<6 | x(1)<<1
+		}
+		t = (d*c + b) ^ a
+		t = (a & 0x20) | (t >> 2)
+	default:
+		t = replicateBits(v, uint(r.bits), 6)
+	}
+	if t > 32 {
+		t++
+	}
+	return t
+}
+
+// replicateBits returns the n bit value v expanded to m bits by replicating
+// its bits.
+func replicateBits(v int, n, m uint) int {
+	if n == 0 {
+		return 0
+	}
+	out := 0
+	for shift := int(m) - int(n); shift > -int(n); shift -= int(n) {
+		if shift >= 0 {
+			out |= v << uint(shift)
+		} else {
+			out |= v >> uint(-shift)
+		}
+	}
+	return out
+}
+
+// astcBlockMode is the decoded block mode of an ASTC block.
+type astcBlockMode struct {
+	gridWidth, gridHeight int
+	dualPlane             bool
+	weights               astcRange
+}
+
+// decodeBlockMode decodes the 11 bit block mode, returning false if the mode
+// is reserved.
+func decodeBlockMode(mode int) (astcBlockMode, bool) {
+	out := astcBlockMode{}
+	high := bit(mode, 9)
+	out.dualPlane = bit(mode, 10) == 1
+	var r int
+	if mode&3 != 0 {
+		r = bit(mode, 4) | (mode&3)<<1
+		a, b := (mode>>5)&3, (mode>>7)&3
+		switch (mode >> 2) & 3 {
+		case 0:
+			out.gridWidth, out.gridHeight = b+4, a+2
+		case 1:
+			out.gridWidth, out.gridHeight = b+8, a+2
+		case 2:
+			out.gridWidth, out.gridHeight = a+2, b+8
+		case 3:
+			if bit(mode, 8) == 0 {
+				out.gridWidth, out.gridHeight = a+2, b&1+6
+			} else {
+				out.gridWidth, out.gridHeight = b&1+2, a+2
+			}
+		}
+	} else {
+		r = bit(mode, 4) | (mode>>1)&6
+		if mode&0xf == 0 {
+			return out, false
+		}
+		a, b := (mode>>5)&3, (mode>>9)&3
+		switch (mode >> 7) & 3 {
+		case 0:
+			out.gridWidth, out.gridHeight = 12, a+2
+		case 1:
+			out.gridWidth, out.gridHeight = a+2, 12
+		case 2:
+			out.gridWidth, out.gridHeight = a+6, b+6
+			high, out.dualPlane = 0, false
+		case 3:
+			switch a {
+			case 0:
+				out.gridWidth, out.gridHeight = 6, 10
+			case 1:
+				out.gridWidth, out.gridHeight = 10, 6
+			default:
+				return out, false
+			}
+		}
+	}
+	out.weights = astcWeightRanges[high][r]
+	return out, r >= 2
+}
+
+// decode decodes the w x h block into out.
+func (b astcBlock) decode(w, h int, srgb bool, out []astcTexel) {
+	fail := func() {
+		for i := range out {
+			out[i] = astcErrorTexel
+		}
+	}
+
+	mode := b.bits(0, 11)
+	if mode&0x1ff == 0x1fc {
+		b.decodeVoidExtent(srgb, out)
+		return
+	}
+
+	bm, ok := decodeBlockMode(mode)
+	if !ok || bm.gridWidth > w || bm.gridHeight > h {
+		fail()
+		return
+	}
+	planes := 1
+	if bm.dualPlane {
+		planes = 2
+	}
+	weightCount := bm.gridWidth * bm.gridHeight * planes
+	weightBits := bm.weights.sequenceBits(weightCount)
+	partitions := b.bits(11, 2) + 1
+	if weightCount > 64 || weightBits < 24 || weightBits > 96 || (bm.dualPlane && partitions == 4) {
+		fail()
+		return
+	}
+
+	belowWeights := 128 - weightBits
+
+	cems := make([]int, partitions)
+	partitionIndex, colorStart := 0, 17
+	if partitions == 1 {
+		cems[0] = b.bits(13, 4)
+	} else {
+		partitionIndex, colorStart = b.bits(13, 10), 29
+		cem := b.bits(23, 6)
+		if sel := cem & 3; sel == 0 {
+			for i := range cems {
+				cems[i] = cem >> 2
+			}
+		} else {
+			extraBits := 3*partitions - 4
+			belowWeights -= extraBits
+			cem = cem>>2 | b.bits(belowWeights, extraBits)<<4
+			for i := range cems {
+				c := bit(cem, uint(i))
+				m := (cem >> uint(partitions+2*i)) & 3
+				cems[i] = (sel-1+c)<<2 | m
+			}
+		}
+	}
+
+	// The color component selector sits below any extra CEM bits.
+	ccs := 0
+	if bm.dualPlane {
+		belowWeights -= 2
+		ccs = b.bits(belowWeights, 2)
+	}
+
+	colorCount := 0
+	for _, cem := range cems {
+		colorCount += (cem>>2 + 1) * 2
+	}
+	if colorCount > 18 {
+		fail()
+		return
+	}
+	colorBits := belowWeights - colorStart
+	var colorRange astcRange
+	found := false
+	for _, r := range astcColorRanges {
+		if r.sequenceBits(colorCount) <= colorBits {
+			colorRange, found = r, true
+			break
+		}
+	}
+	if !found {
+		fail()
+		return
+	}
+	colors := decodeISE(b, colorStart, colorCount, colorRange)
+	for i := range colors {
+		colors[i] = unquantizeColor(colors[i], colorRange)
+	}
+
+	endpoints := make([][2]astcEndpoint, partitions)
+	for i, cem := range cems {
+		n := (cem>>2 + 1) * 2
+		e0, e1 := decodeEndpoints(cem, colors[:n])
+		colors = colors[n:]
+		if srgb && (e0.hdr[0] || e0.hdr[3]) {
+			fail()
+			return
+		}
+		endpoints[i] = [2]astcEndpoint{e0, e1}
+	}
+
+	weights := decodeISE(b.reversed(), 0, weightCount, bm.weights)
+	for i := range weights {
+		weights[i] = unquantizeWeight(weights[i], bm.weights)
+	}
+	texelWeights := infillWeights(weights, w, h, bm.gridWidth, bm.gridHeight, planes)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			p := 0
+			if partitions > 1 {
+				p = astcPartition(partitionIndex, x, y, partitions, w*h < 31)
+			}
+			e := endpoints[p]
+			t := astcTexel{}
+			for c := 0; c < 4; c++ {
+				weight := texelWeights[0][i]
+				if bm.dualPlane && c == ccs {
+					weight = texelWeights[1][i]
+				}
+				t.v[c], t.hdr[c] = interpolateEndpoints(e[0], e[1], c, weight, srgb)
+			}
+			out[i] = t
+		}
+	}
+}
+
+func (b astcBlock) decodeVoidExtent(srgb bool, out []astcTexel) {
+	hdr := bit(b.bits(0, 10), 9) == 1
+	t := astcTexel{}
+	for c := 0; c < 4; c++ {
+		t.v[c] = uint16(b.bits(64+c*16, 16))
+		t.hdr[c] = hdr
+	}
+	if hdr && srgb {
+		t = astcErrorTexel
+	}
+	for i := range out {
+		out[i] = t
+	}
+}
+
+// astcEndpoint is a decoded color endpoint. Each LDR channel holds an 8 bit
+// value and each HDR channel a 12 bit value.
+type astcEndpoint struct {
+	v   [4]int
+	hdr [4]bool
+}
+
+func ldrEndpoint(r, g, b, a int) astcEndpoint {
+	return astcEndpoint{v: [4]int{clamp255(r), clamp255(g), clamp255(b), clamp255(a)}}
+}
+
+func hdrEndpoint(r, g, b int) astcEndpoint {
+	// Alpha defaults to 1.0, which is stored as a LDR value.
+	return astcEndpoint{v: [4]int{clampFFF(r), clampFFF(g), clampFFF(b), 0xff}, hdr: [4]bool{true, true, true, false}}
+}
+
+func clamp255(v int) int {
+	switch {
+	case v < 0:
+		return 0
+	case v > 0xff:
+		return 0xff
+	default:
+		return v
+	}
+}
+
+func clampFFF(v int) int {
+	switch {
+	case v < 0:
+		return 0
+	case v > 0xfff:
+		return 0xfff
+	default:
+		return v
+	}
+}
+
+// bitTransferSigned transfers the top bit of b to a, returning the signed 6
+// bit offset a and the base b.
+func bitTransferSigned(a, b int) (int, int) {
+	b = b>>1 | a&0x80
+	a = (a >> 1) & 0x3f
+	if a&0x20 != 0 {
+		a -= 0x40
+	}
+	return a, b
+}
+
+func blueContract(r, g, b, a int) astcEndpoint {
+	return ldrEndpoint((r+b)>>1, (g+b)>>1, b, a)
+}
+
+// decodeEndpoints returns the two endpoints of the color endpoint mode cem
+// using the unquantized values v.
+func decodeEndpoints(cem int, v []int) (astcEndpoint, astcEndpoint) {
+	switch cem {
+	case 0: // LDR luminance, direct.
+		return ldrEndpoint(v[0], v[0], v[0], 0xff), ldrEndpoint(v[1], v[1], v[1], 0xff)
+	case 1: // LDR luminance, base+offset.
+		l0 := v[0]>>2 | v[1]&0xc0
+		l1 := l0 + v[1]&0x3f
+		return ldrEndpoint(l0, l0, l0, 0xff), ldrEndpoint(l1, l1, l1, 0xff)
+	case 2: // HDR luminance, large range.
+		var y0, y1 int
+		if v[1] >= v[0] {
+			y0, y1 = v[0]<<4, v[1]<<4
+		} else {
+			y0, y1 = v[1]<<4+8, v[0]<<4-8
+		}
+		return hdrEndpoint(y0, y0, y0), hdrEndpoint(y1, y1, y1)
+	case 3: // HDR luminance, small range.
+		var y0, d int
+		if v[0]&0x80 != 0 {
+			y0 = (v[1]&0xe0)<<4 | (v[0]&0x7f)<<2
+			d = (v[1] & 0x1f) << 2
+		} else {
+			y0 = (v[1]&0xf0)<<4 | (v[0]&0x7f)<<1
+			d = (v[1] & 0x0f) << 1
+		}
+		y1 := clampFFF(y0 + d)
+		return hdrEndpoint(y0, y0, y0), hdrEndpoint(y1, y1, y1)
+	case 4: // LDR luminance+alpha, direct.
+		return ldrEndpoint(v[0], v[0], v[0], v[2]), ldrEndpoint(v[1], v[1], v[1], v[3])
+	case 5: // LDR luminance+alpha, base+offset.
+		l1, l0 := bitTransferSigned(v[1], v[0])
+		a1, a0 := bitTransferSigned(v[3], v[2])
+		return ldrEndpoint(l0, l0, l0, a0), ldrEndpoint(l0+l1, l0+l1, l0+l1, a0+a1)
+	case 6: // LDR RGB, base+scale.
+		return ldrEndpoint(v[0]*v[3]>>8, v[1]*v[3]>>8, v[2]*v[3]>>8, 0xff), ldrEndpoint(v[0], v[1], v[2], 0xff)
+	case 7: // HDR RGB, base+scale.
+		return decodeHDRRGBScale(v)
+	case 8: // LDR RGB, direct.
+		if v[1]+v[3]+v[5] >= v[0]+v[2]+v[4] {
+			return ldrEndpoint(v[0], v[2], v[4], 0xff), ldrEndpoint(v[1], v[3], v[5], 0xff)
+		}
+		return blueContract(v[1], v[3], v[5], 0xff), blueContract(v[0], v[2], v[4], 0xff)
+	case 9: // LDR RGB, base+offset.
+		r1, r0 := bitTransferSigned(v[1], v[0])
+		g1, g0 := bitTransferSigned(v[3], v[2])
+		b1, b0 := bitTransferSigned(v[5], v[4])
+		if r1+g1+b1 >= 0 {
+			return ldrEndpoint(r0, g0, b0, 0xff), ldrEndpoint(r0+r1, g0+g1, b0+b1, 0xff)
+		}
+		return blueContract(r0+r1, g0+g1, b0+b1, 0xff), blueContract(r0, g0, b0, 0xff)
+	case 10: // LDR RGB, base+scale plus two alpha.
+		return ldrEndpoint(v[0]*v[3]>>8, v[1]*v[3]>>8, v[2]*v[3]>>8, v[4]), ldrEndpoint(v[0], v[1], v[2], v[5])
+	case 11: // HDR RGB, direct.
+		return decodeHDRRGB(v)
+	case 12: // LDR RGBA, direct.
+		if v[1]+v[3]+v[5] >= v[0]+v[2]+v[4] {
+			return ldrEndpoint(v[0], v[2], v[4], v[6]), ldrEndpoint(v[1], v[3], v[5], v[7])
+		}
+		return blueContract(v[1], v[3], v[5], v[7]), blueContract(v[0], v[2], v[4], v[6])
+	case 13: // LDR RGBA, base+offset.
+		r1, r0 := bitTransferSigned(v[1], v[0])
+		g1, g0 := bitTransferSigned(v[3], v[2])
+		b1, b0 := bitTransferSigned(v[5], v[4])
+		a1, a0 := bitTransferSigned(v[7], v[6])
+		if r1+g1+b1 >= 0 {
+			return ldrEndpoint(r0, g0, b0, a0), ldrEndpoint(r0+r1, g0+g1, b0+b1, a0+a1)
+		}
+		return blueContract(r0+r1, g0+g1, b0+b1, a0+a1), blueContract(r0, g0, b0, a0)
+	case 14: // HDR RGB, direct plus LDR alpha.
+		e0, e1 := decodeHDRRGB(v)
+		e0.v[3], e1.v[3] = v[6], v[7]
+		return e0, e1
+	default: // HDR RGB, direct plus HDR alpha.
+		e0, e1 := decodeHDRRGB(v)
+		e0.v[3], e1.v[3] = decodeHDRAlpha(v[6], v[7])
+		e0.hdr[3], e1.hdr[3] = true, true
+		return e0, e1
+	}
+}
+
+// decodeHDRRGBScale decodes the endpoints of the HDR RGB base+scale mode.
+func decodeHDRRGBScale(v []int) (astcEndpoint, astcEndpoint) {
+	modeValue := (v[0]&0xc0)>>6 | (v[1]&0x80)>>5 | (v[2]&0x80)>>4
+	var majComp, mode int
+	switch {
+	case modeValue&0xc != 0xc:
+		majComp, mode = modeValue>>2, modeValue&3
+	case modeValue != 0xf:
+		majComp, mode = modeValue&3, 4
+	default:
+		majComp, mode = 0, 5
+	}
+
+	red, green, blue, scale := v[0]&0x3f, v[1]&0x1f, v[2]&0x1f, v[3]&0x1f
+	x0, x1 := bit(v[1], 6), bit(v[1], 5)
+	x2, x3 := bit(v[2], 6), bit(v[2], 5)
+	x4, x5, x6 := bit(v[3], 7), bit(v[3], 6), bit(v[3], 5)
+
+	ohm := 1 << uint(mode)
+	if ohm&0x30 != 0 {
+		green |= x0 << 6
+	}
+	if ohm&0x3a != 0 {
+		green |= x1 << 5
+	}
+	if ohm&0x30 != 0 {
+		blue |= x2 << 6
+	}
+	if ohm&0x3a != 0 {
+		blue |= x3 << 5
+	}
+	if ohm&0x3d != 0 {
+		scale |= x6 << 5
+	}
+	if ohm&0x2d != 0 {
+		scale |= x5 << 6
+	}
+	if ohm&0x04 != 0 {
+		scale |= x4 << 7
+	}
+	if ohm&0x3b != 0 {
+		red |= x4 << 6
+	}
+	if ohm&0x04 != 0 {
+		red |= x3 << 6
+	}
+	if ohm&0x10 != 0 {
+		red |= x5 << 7
+	}
+	if ohm&0x0f != 0 {
+		red |= x2 << 7
+	}
+	if ohm&0x05 != 0 {
+		red |= x1 << 8
+	}
+	if ohm&0x0a != 0 {
+		red |= x0 << 8
+	}
+	if ohm&0x05 != 0 {
+		red |= x0 << 9
+	}
+	if ohm&0x02 != 0 {
+		red |= x6 << 9
+	}
+	if ohm&0x01 != 0 {
+		red |= x3 << 10
+	}
+	if ohm&0x02 != 0 {
+		red |= x5 << 10
+	}
+
+	shift := uint([]int{1, 1, 2, 3, 4, 5}[mode])
+	red, green, blue, scale = red<<shift, green<<shift, blue<<shift, scale<<shift
+	if mode != 5 {
+		green, blue = red-green, red-blue
+	}
+	switch majComp {
+	case 1:
+		red, green = green, red
+	case 2:
+		red, blue = blue, red
+	}
+	return hdrEndpoint(red-scale, green-scale, blue-scale), hdrEndpoint(red, green, blue)
+}
+
+// decodeHDRRGB decodes the endpoints of the HDR RGB direct mode.
+func decodeHDRRGB(v []int) (astcEndpoint, astcEndpoint) {
+	majComp := (v[4]&0x80)>>7 | (v[5]&0x80)>>6
+	if majComp == 3 {
+		return hdrEndpoint(v[0]<<4, v[2]<<4, (v[4]&0x7f)<<5), hdrEndpoint(v[1]<<4, v[3]<<4, (v[5]&0x7f)<<5)
+	}
+
+	mode := (v[1]&0x80)>>7 | (v[2]&0x80)>>6 | (v[3]&0x80)>>5
+	va := v[0] | (v[1]&0x40)<<2
+	vb0, vb1 := v[2]&0x3f, v[3]&0x3f
+	vc := v[1] & 0x3f
+	vd0, vd1 := v[4]&0x1f, v[5]&0x1f
+
+	x0, x1 := bit(v[2], 6), bit(v[3], 6)
+	x2, x3 := bit(v[4], 6), bit(v[5], 6)
+	x4, x5 := bit(v[4], 5), bit(v[5], 5)
+
+	ohm := 1 << uint(mode)
+	if ohm&0xa4 != 0 {
+		va |= x0 << 9
+	}
+	if ohm&0x08 != 0 {
+		va |= x2 << 9
+	}
+	if ohm&0x50 != 0 {
+		va |= x4 << 9
+	}
+	if ohm&0x50 != 0 {
+		va |= x5 << 10
+	}
+	if ohm&0xa0 != 0 {
+		va |= x1 << 10
+	}
+	if ohm&0xc0 != 0 {
+		va |= x2 << 11
+	}
+	if ohm&0x04 != 0 {
+		vc |= x1 << 6
+	}
+	if ohm&0xe8 != 0 {
+		vc |= x3 << 6
+	}
+	if ohm&0x20 != 0 {
+		vc |= x2 << 7
+	}
+	if ohm&0x5b != 0 {
+		vb0 |= x0 << 6
+		vb1 |= x1 << 6
+	}
+	if ohm&0x12 != 0 {
+		vb0 |= x2 << 7
+		vb1 |= x3 << 7
+	}
+	if ohm&0xaf != 0 {
+		vd0 |= x4 << 5
+		vd1 |= x5 << 5
+	}
+	if ohm&0x05 != 0 {
+		vd0 |= x2 << 6
+		vd1 |= x3 << 6
+	}
+
+	dBits := uint([]int{7, 6, 7, 6, 5, 6, 5, 6}[mode])
+	vd0, vd1 = signExtend(vd0, dBits), signExtend(vd1, dBits)
+
+	shift := uint((mode >> 1) ^ 3)
+	va, vb0, vb1, vc = va<<shift, vb0<<shift, vb1<<shift, vc<<shift
+	vd0, vd1 = vd0*(1<<shift), vd1*(1<<shift)
+
+	e0 := [3]int{va - vc, va - vb0 - vc - vd0, va - vb1 - vc - vd1}
+	e1 := [3]int{va, va - vb0, va - vb1}
+	switch majComp {
+	case 1:
+		e0[0], e0[1] = e0[1], e0[0]
+		e1[0], e1[1] = e1[1], e1[0]
+	case 2:
+		e0[0], e0[2] = e0[2], e0[0]
+		e1[0], e1[2] = e1[2], e1[0]
+	}
+	return hdrEndpoint(e0[0], e0[1], e0[2]), hdrEndpoint(e1[0], e1[1], e1[2])
+}
+
+// decodeHDRAlpha decodes the alpha endpoints of the HDR RGBA mode.
+func decodeHDRAlpha(v6, v7 int) (int, int) {
+	mode := (v6>>7)&1 | (v7>>6)&2
+	v6, v7 = v6&0x7f, v7&0x7f
+	if mode == 3 {
+		return v6 << 5, v7 << 5
+	}
+	m := uint(mode)
+	v6 |= (v7 << (m + 1)) & 0x780
+	v7 &= 0x3f >> m
+	v7 ^= 0x20 >> m
+	v7 -= 0x20 >> m
+	v6 <<= 4 - m
+	v7 *= 1 << (4 - m)
+	return v6, clampFFF(v7 + v6)
+}
+
+// interpolateEndpoints returns channel c interpolated between the endpoints
+// e0 and e1 using the weight in [0, 64].
+func interpolateEndpoints(e0, e1 astcEndpoint, c, weight int, srgb bool) (uint16, bool) {
+	var c0, c1 int
+	hdr := e0.hdr[c] || e1.hdr[c]
+	switch {
+	case hdr:
+		c0, c1 = e0.v[c]<<4, e1.v[c]<<4
+	case srgb && c < 3:
+		c0, c1 = e0.v[c]<<8|0x80, e1.v[c]<<8|0x80
+	default:
+		c0, c1 = e0.v[c]*0x101, e1.v[c]*0x101
+	}
+	v := (c0*(64-weight) + c1*weight + 32) >> 6
+	if !hdr {
+		return uint16(v), false
+	}
+	// Convert the logarithmic value to a half float.
+	e, m := v>>11, v&0x7ff
+	switch {
+	case m < 512:
+		m *= 3
+	case m < 1536:
+		m = 4*m - 512
+	default:
+		m = 5*m - 2048
+	}
+	h := e<<10 | m>>3
+	if h >= 0x7c00 {
+		h = 0x7bff
+	}
+	return uint16(h), true
+}
+
+// infillWeights returns the weights of each texel of each plane, bilinearly
+// interpolated from the gw x gh grid of weights.
+func infillWeights(weights []int, w, h, gw, gh, planes int) [2][]int {
+	out := [2][]int{make([]int, w*h), make([]int, w*h)}
+	ds := (1024 + w/2) / (w - 1)
+	dt := (1024 + h/2) / (h - 1)
+	weight := func(plane, x, y int) int {
+		if x >= gw || y >= gh {
+			return 0
+		}
+		return weights[(y*gw+x)*planes+plane]
+	}
+	for t := 0; t < h; t++ {
+		for s := 0; s < w; s++ {
+			gs := (ds*s*(gw-1) + 32) >> 6
+			gt := (dt*t*(gh-1) + 32) >> 6
+			js, fs := gs>>4, gs&0xf
+			jt, ft := gt>>4, gt&0xf
+			w11 := (fs*ft + 8) >> 4
+			w10 := ft - w11
+			w01 := fs - w11
+			w00 := 16 - fs - ft + w11
+			for p := 0; p < planes; p++ {
+				out[p][t*w+s] = (weight(p, js, jt)*w00 +
+					weight(p, js+1, jt)*w01 +
+					weight(p, js, jt+1)*w10 +
+					weight(p, js+1, jt+1)*w11 + 8) >> 4
+			}
+		}
+	}
+	return out
+}
+
+func hash52(p uint32) uint32 {
+	p ^= p >> 15
+	p -= p << 17
+	p += p << 7
+	p += p << 4
+	p ^= p >> 5
+	p += p << 16
+	p ^= p >> 7
+	p ^= p >> 3
+	p ^= p << 6
+	p ^= p >> 17
+	return p
+}
+
+// astcPartition returns the partition of the texel at x, y.
+func astcPartition(seed, x, y, partitions int, small bool) int {
+	if small {
+		x, y = x<<1, y<<1
+	}
+	seed += (partitions - 1) * 1024
+	rnum := hash52(uint32(seed))
+	var seeds [8]uint32
+	for i := range seeds {
+		s := (rnum >> uint(i*4)) & 0xf
+		seeds[i] = s * s
+	}
+
+	var sh1, sh2 uint
+	if seed&1 != 0 {
+		sh1, sh2 = 4, 5
+		if seed&2 == 0 {
+			sh1 = 5
+		}
+		if partitions == 3 {
+			sh2 = 6
+		}
+	} else {
+		sh1, sh2 = 5, 4
+		if partitions == 3 {
+			sh1 = 6
+		}
+		if seed&2 == 0 {
+			sh2 = 5
+		}
+	}
+	for i := range seeds {
+		if i%2 == 0 {
+			seeds[i] >>= sh1
+		} else {
+			seeds[i] >>= sh2
+		}
+	}
+
+	a := (int(seeds[0])*x + int(seeds[1])*y + int(rnum>>14)) & 0x3f
+	b := (int(seeds[2])*x + int(seeds[3])*y + int(rnum>>10)) & 0x3f
+	c := (int(seeds[4])*x + int(seeds[5])*y + int(rnum>>6)) & 0x3f
+	d := (int(seeds[6])*x + int(seeds[7])*y + int(rnum>>2)) & 0x3f
+	if partitions < 4 {
+		d = 0
+	}
+	if partitions < 3 {
+		c = 0
+	}
+	switch {
+	case a >= b && a >= c && a >= d:
+		return 0
+	case b >= c && b >= d:
+		return 1
+	case c >= d:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,640 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/math/f16"
+	"github.com/google/gapid/core/math/sint"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/stream"
+)
+
+var (
+	BC6H_RGB_UF16     = NewBC6H_RGB_UF16("BC6H_RGB_UF16")
+	BC6H_RGB_SF16     = NewBC6H_RGB_SF16("BC6H_RGB_SF16")
+	BC7_RGBA_U8_NORM  = NewBC7_RGBA_U8_NORM("BC7_RGBA_U8_NORM")
+	BC7_SRGBA_U8_NORM = NewBC7_SRGBA_U8_NORM("BC7_SRGBA_U8_NORM")
+)
+
+// NewBC6H_RGB_UF16 returns a format representing the unsigned BC6H (BPTC
+// float) block texture compression format.
+func NewBC6H_RGB_UF16(name string) *Format {
+	return &Format{name, &Format_Bc6HRgbF16{&FmtBC6H_RGB_F16{}}}
+}
+
+// NewBC6H_RGB_SF16 returns a format representing the signed BC6H (BPTC
+// float) block texture compression format.
+func NewBC6H_RGB_SF16(name string) *Format {
+	return &Format{name, &Format_Bc6HRgbF16{&FmtBC6H_RGB_F16{Signed: true}}}
+}
+
+func (f *FmtBC6H_RGB_F16) key() interface{} {
+	return *f
+}
+func (*FmtBC6H_RGB_F16) size(w, h int) int {
+	return sint.Max(sint.AlignUp(w, 4), 4) * sint.Max(sint.AlignUp(h, 4), 4)
+}
+func (f *FmtBC6H_RGB_F16) check(d []byte, w, h int) error {
+	return checkSize(d, f, w, h)
+}
+func (*FmtBC6H_RGB_F16) channels() []stream.Channel {
+	return []stream.Channel{stream.Channel_Red, stream.Channel_Green, stream.Channel_Blue}
+}
+
+// NewBC7_RGBA_U8_NORM returns a format representing the BC7 (BPTC) block
+// texture compression format.
+func NewBC7_RGBA_U8_NORM(name string) *Format {
+	return &Format{name, &Format_Bc7RgbaU8Norm{&FmtBC7_RGBA_U8_NORM{}}}
+}
+
+// NewBC7_SRGBA_U8_NORM returns a format representing the sRGB BC7 (BPTC)
+// block texture compression format.
+func NewBC7_SRGBA_U8_NORM(name string) *Format {
+	return &Format{name, &Format_Bc7RgbaU8Norm{&FmtBC7_RGBA_U8_NORM{Srgb: true}}}
+}
+
+func (f *FmtBC7_RGBA_U8_NORM) key() interface{} {
+	return *f
+}
+func (*FmtBC7_RGBA_U8_NORM) size(w, h int) int {
+	return sint.Max(sint.AlignUp(w, 4), 4) * sint.Max(sint.AlignUp(h, 4), 4)
+}
+func (f *FmtBC7_RGBA_U8_NORM) check(d []byte, w, h int) error {
+	return checkSize(d, f, w, h)
+}
+func (*FmtBC7_RGBA_U8_NORM) channels() []stream.Channel {
+	return []stream.Channel{stream.Channel_Red, stream.Channel_Green, stream.Channel_Blue, stream.Channel_Alpha}
+}
+
+func init() {
+	for _, f := range []*Format{BC6H_RGB_UF16, BC6H_RGB_SF16} {
+		signed := f.GetBc6HRgbF16().Signed
+		RegisterConverter(f, RGBA_F32, func(src []byte, width, height int) ([]byte, error) {
+			return decodeBC6H(src, width, height, signed)
+		})
+	}
+	for _, f := range []*Format{BC7_RGBA_U8_NORM, BC7_SRGBA_U8_NORM} {
+		RegisterConverter(f, RGBA_U8_NORM, func(src []byte, width, height int) ([]byte, error) {
+			return decodeBC7(src, width, height)
+		})
+	}
+}
+
+// bptcBlock is a 128 bit BPTC block, read from the least significant bit.
+type bptcBlock struct {
+	lo, hi uint64
+	pos    uint
+}
+
+func readBPTCBlock(r pod.Reader) bptcBlock {
+	lo := r.Uint64()
+	hi := r.Uint64()
+	return bptcBlock{lo: lo, hi: hi}
+}
+
+// bit returns the next bit of the block.
+func (b *bptcBlock) bit() int {
+	var v uint64
+	if b.pos < 64 {
+		v = b.lo >> b.pos
+	} else {
+		v = b.hi >> (b.pos - 64)
+	}
+	b.pos++
+	return int(v & 1)
+}
+
+// bits returns the next n bits of the block.
+func (b *bptcBlock) bits(n uint) int {
+	v := 0
+	for i := uint(0); i < n; i++ {
+		v |= b.bit() << i
+	}
+	return v
+}
+
+// bptcPartitions2 holds the subset of each texel for the two subset
+// partitions, as a bit mask of the texels in the second subset.
+var bptcPartitions2 = [64]uint16{
+	0xcccc, 0x8888, 0xeeee, 0xecc8, 0xc880, 0xfeec, 0xfec8, 0xec80,
+	0xc800, 0xffec, 0xfe80, 0xe800, 0xffe8, 0xff00, 0xfff0, 0xf000,
+	0xf710, 0x008e, 0x7100, 0x08ce, 0x008c, 0x7310, 0x3100, 0x8cce,
+	0x088c, 0x3110, 0x6666, 0x366c, 0x17e8, 0x0ff0, 0x718e, 0x399c,
+	0xaaaa, 0xf0f0, 0x5a5a, 0x33cc, 0x3c3c, 0x55aa, 0x9696, 0xa55a,
+	0x73ce, 0x13c8, 0x324c, 0x3bdc, 0x6996, 0xc33c, 0x9966, 0x0660,
+	0x0272, 0x04e4, 0x4e40, 0x2720, 0xc936, 0x936c, 0x39c6, 0x639c,
+	0x9336, 0x9cc6, 0x817e, 0xe718, 0xccf0, 0x0fcc, 0x7744, 0xee22,
+}
+
+// bptcPartitions3 holds the subset of each texel for the three subset
+// partitions, as 2 bits per texel.
+var bptcPartitions3 = [64]uint32{
+	0xaa685050, 0x6a5a5040, 0x5a5a4200, 0x5450a0a8, 0xa5a50000, 0xa0a05050, 0x5555a0a0, 0x5a5a5050,
+	0xaa550000, 0xaa555500, 0xaaaa5500, 0x90909090, 0x94949494, 0xa4a4a4a4, 0xa9a59450, 0x2a0a4250,
+	0xa5945040, 0x0a425054, 0xa5a5a500, 0x55a0a0a0, 0xa8a85454, 0x6a6a4040, 0xa4a45000, 0x1a1a0500,
+	0x0050a4a4, 0xaaa59090, 0x14696914, 0x69691400, 0xa08585a0, 0xaa821414, 0x50a4a450, 0x6a5a0200,
+	0xa9a58000, 0x5090a0a8, 0xa8a09050, 0x24242424, 0x00aa5500, 0x24924924, 0x24499224, 0x50a50a50,
+	0x500aa550, 0xaaaa4444, 0x66660000, 0xa5a0a5a0, 0x50a050a0, 0x69286928, 0x44aaaa44, 0x66666600,
+	0xaa444444, 0x54a854a8, 0x95809580, 0x96969600, 0xa85454a8, 0x80959580, 0xaa141414, 0x96960000,
+	0xaaaa1414, 0xa05050a0, 0xa0a5a5a0, 0x96000000, 0x40804080, 0xa9a8a9a8, 0xaaaaaa44, 0x2a4a5254,
+}
+
+// bptcAnchors2 holds the anchor texel of the second subset of the two subset
+// partitions.
+var bptcAnchors2 = [64]int{
+	15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15,
+	15, 2, 8, 2, 2, 8, 8, 15, 2, 8, 2, 2, 8, 8, 2, 2,
+	15, 15, 6, 8, 2, 8, 15, 15, 2, 8, 2, 2, 2, 15, 15, 6,
+	6, 2, 6, 8, 15, 15, 2, 2, 15, 15, 15, 15, 15, 2, 2, 15,
+}
+
+// bptcAnchors3 holds the anchor texels of the second and third subsets of the
+// three subset partitions.
+var bptcAnchors3 = [2][64]int{
+	{
+		3, 3, 15, 15, 8, 3, 15, 15, 8, 8, 6, 6, 6, 5, 3, 3,
+		3, 3, 8, 15, 3, 3, 6, 10, 5, 8, 8, 6, 8, 5, 15, 15,
+		8, 15, 3, 5, 6, 10, 8, 15, 15, 3, 15, 5, 15, 15, 15, 15,
+		3, 15, 5, 5, 5, 8, 5, 10, 5, 10, 8, 13, 15, 12, 3, 3,
+	}, {
+		15, 8, 8, 3, 15, 15, 3, 8, 15, 15, 15, 15, 15, 15, 15, 8,
+		15, 8, 15, 3, 15, 8, 15, 8, 3, 15, 6, 10, 15, 15, 10, 8,
+		15, 3, 15, 10, 10, 8, 9, 10, 6, 15, 8, 15, 3, 6, 6, 8,
+		15, 3, 15, 15, 15, 15, 15, 15, 15, 15, 15, 15, 3, 15, 15, 8,
+	},
+}
+
+// bptcSubset returns the subset of the texel i for the given partition.
+func bptcSubset(subsets, partition, i int) int {
+	switch subsets {
+	case 2:
+		return int(bptcPartitions2[partition]>>uint(i)) & 1
+	case 3:
+		return int(bptcPartitions3[partition]>>uint(i*2)) & 3
+	default:
+		return 0
+	}
+}
+
+// bptcIsAnchor returns true if the texel i is the anchor of its subset, and
+// so has its index encoded with one bit less.
+func bptcIsAnchor(subsets, partition, i int) bool {
+	switch {
+	case i == 0:
+		return true
+	case subsets == 2:
+		return i == bptcAnchors2[partition]
+	case subsets == 3:
+		return i == bptcAnchors3[0][partition] || i == bptcAnchors3[1][partition]
+	default:
+		return false
+	}
+}
+
+var bptcWeights = [][]int{
+	2: {0, 21, 43, 64},
+	3: {0, 9, 18, 27, 37, 46, 55, 64},
+	4: {0, 4, 9, 13, 17, 21, 26, 30, 34, 38, 43, 47, 51, 55, 60, 64},
+}
+
+func bptcInterpolate(e0, e1, index int, indexBits uint) int {
+	w := bptcWeights[indexBits][index]
+	return ((64-w)*e0 + w*e1 + 32) >> 6
+}
+
+// bc7Mode describes the layout of a BC7 block mode.
+type bc7Mode struct {
+	subsets         int
+	partitionBits   uint
+	rotationBits    uint
+	indexSelBits    uint
+	colorBits       uint
+	alphaBits       uint
+	endpointPBits   bool
+	sharedPBits     bool
+	indexBits       uint
+	secondIndexBits uint
+}
+
+var bc7Modes = []bc7Mode{
+	{3, 4, 0, 0, 4, 0, true, false, 3, 0},
+	{2, 6, 0, 0, 6, 0, false, true, 3, 0},
+	{3, 6, 0, 0, 5, 0, false, false, 2, 0},
+	{2, 6, 0, 0, 7, 0, true, false, 2, 0},
+	{1, 0, 2, 1, 5, 6, false, false, 2, 3},
+	{1, 0, 2, 0, 7, 8, false, false, 2, 2},
+	{1, 0, 0, 0, 7, 7, true, false, 4, 0},
+	{2, 6, 0, 0, 5, 5, true, false, 2, 0},
+}
+
+func decodeBC7(src []byte, width, height int) ([]byte, error) {
+	dst := make([]byte, width*height*4)
+	block := make([]pixel, 16)
+	r := endian.Reader(bytes.NewReader(src), device.LittleEndian)
+	for y := 0; y < height; y += 4 {
+		for x := 0; x < width; x += 4 {
+			b := readBPTCBlock(r)
+			decodeBC7Block(&b, block)
+			copyToDest(block, dst, x, y, width, height)
+		}
+	}
+	return dst, r.Error()
+}
+
+func decodeBC7Block(b *bptcBlock, dst []pixel) {
+	mode := 0
+	for mode < 8 && b.bit() == 0 {
+		mode++
+	}
+	if mode == 8 {
+		// Reserved mode, decodes to transparent black.
+		for i := range dst {
+			dst[i] = pixel{}
+		}
+		return
+	}
+	m := bc7Modes[mode]
+	partition := b.bits(m.partitionBits)
+	rotation := b.bits(m.rotationBits)
+	indexSel := b.bits(m.indexSelBits)
+
+	// endpoints[i][c] holds channel c of endpoint i, the two endpoints of
+	// subset s being 2s and 2s+1.
+	endpoints := make([][4]int, m.subsets*2)
+	for c := 0; c < 3; c++ {
+		for i := range endpoints {
+			endpoints[i][c] = b.bits(m.colorBits)
+		}
+	}
+	for i := range endpoints {
+		endpoints[i][3] = b.bits(m.alphaBits)
+	}
+
+	colorBits, alphaBits := m.colorBits, m.alphaBits
+	switch {
+	case m.endpointPBits:
+		for i := range endpoints {
+			p := b.bit()
+			for c := range endpoints[i] {
+				endpoints[i][c] = endpoints[i][c]<<1 | p
+			}
+		}
+		colorBits++
+		if alphaBits > 0 {
+			alphaBits++
+		}
+	case m.sharedPBits:
+		for s := 0; s < m.subsets; s++ {
+			p := b.bit()
+			for i := s * 2; i < s*2+2; i++ {
+				for c := range endpoints[i] {
+					endpoints[i][c] = endpoints[i][c]<<1 | p
+				}
+			}
+		}
+		colorBits++
+	}
+	for i := range endpoints {
+		for c := 0; c < 3; c++ {
+			endpoints[i][c] = bptcExpand(endpoints[i][c], colorBits)
+		}
+		if alphaBits > 0 {
+			endpoints[i][3] = bptcExpand(endpoints[i][3], alphaBits)
+		} else {
+			endpoints[i][3] = 255
+		}
+	}
+
+	var indices, secondIndices [16]int
+	for i := range indices {
+		n := m.indexBits
+		if bptcIsAnchor(m.subsets, partition, i) {
+			n--
+		}
+		indices[i] = b.bits(n)
+	}
+	if m.secondIndexBits > 0 {
+		for i := range secondIndices {
+			n := m.secondIndexBits
+			if i == 0 {
+				n--
+			}
+			secondIndices[i] = b.bits(n)
+		}
+	}
+
+	for i := range dst {
+		s := bptcSubset(m.subsets, partition, i)
+		e0, e1 := endpoints[s*2], endpoints[s*2+1]
+		colorIndex, colorBits := indices[i], m.indexBits
+		alphaIndex, alphaBits := indices[i], m.indexBits
+		if m.secondIndexBits > 0 {
+			alphaIndex, alphaBits = secondIndices[i], m.secondIndexBits
+			if indexSel == 1 {
+				colorIndex, colorBits, alphaIndex, alphaBits = alphaIndex, alphaBits, colorIndex, colorBits
+			}
+		}
+		p := pixel{
+			bptcInterpolate(e0[0], e1[0], colorIndex, colorBits),
+			bptcInterpolate(e0[1], e1[1], colorIndex, colorBits),
+			bptcInterpolate(e0[2], e1[2], colorIndex, colorBits),
+			bptcInterpolate(e0[3], e1[3], alphaIndex, alphaBits),
+		}
+		switch rotation {
+		case 1:
+			p.r, p.a = p.a, p.r
+		case 2:
+			p.g, p.a = p.a, p.g
+		case 3:
+			p.b, p.a = p.a, p.b
+		}
+		dst[i] = p
+	}
+}
+
+// bptcExpand expands the n bit value v to 8 bits.
+func bptcExpand(v int, n uint) int {
+	v <<= 8 - n
+	return v | v>>n
+}
+
+// bc6hField is a sequence of bits of a BC6H endpoint value or partition,
+// stored from the bit first to the bit last.
+type bc6hField struct {
+	value       int // endpoint*3 + channel, or bc6hPartition.
+	last, first uint
+}
+
+const bc6hPartition = 12
+
+// The BC6H fields, named as in the specification.
+const (
+	bc6hRW = iota
+	bc6hGW
+	bc6hBW
+	bc6hRX
+	bc6hGX
+	bc6hBX
+	bc6hRY
+	bc6hGY
+	bc6hBY
+	bc6hRZ
+	bc6hGZ
+	bc6hBZ
+	bc6hD = bc6hPartition
+)
+
+// bc6hMode describes the layout of a BC6H block mode.
+type bc6hMode struct {
+	transformed  bool
+	regions      int
+	endpointBits uint
+	deltaBits    [3]uint
+	layout       []bc6hField
+}
+
+// bc6hModes holds the BC6H modes, indexed by mode value.
+var bc6hModes = map[int]bc6hMode{
+	0x00: {true, 2, 10, [3]uint{5, 5, 5}, []bc6hField{
+		{bc6hGY, 4, 4}, {bc6hBY, 4, 4}, {bc6hBZ, 4, 4}, {bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0},
+		{bc6hRX, 4, 0}, {bc6hGZ, 4, 4}, {bc6hGY, 3, 0}, {bc6hGX, 4, 0}, {bc6hBZ, 0, 0}, {bc6hGZ, 3, 0},
+		{bc6hBX, 4, 0}, {bc6hBZ, 1, 1}, {bc6hBY, 3, 0}, {bc6hRY, 4, 0}, {bc6hBZ, 2, 2}, {bc6hRZ, 4, 0},
+		{bc6hBZ, 3, 3}, {bc6hD, 4, 0},
+	}},
+	0x01: {true, 2, 7, [3]uint{6, 6, 6}, []bc6hField{
+		{bc6hGY, 5, 5}, {bc6hGZ, 4, 4}, {bc6hGZ, 5, 5}, {bc6hRW, 6, 0}, {bc6hBZ, 0, 0}, {bc6hBZ, 1, 1},
+		{bc6hBY, 4, 4}, {bc6hGW, 6, 0}, {bc6hBY, 5, 5}, {bc6hBZ, 2, 2}, {bc6hGY, 4, 4}, {bc6hBW, 6, 0},
+		{bc6hBZ, 3, 3}, {bc6hBZ, 5, 5}, {bc6hBZ, 4, 4}, {bc6hRX, 5, 0}, {bc6hGY, 3, 0}, {bc6hGX, 5, 0},
+		{bc6hGZ, 3, 0}, {bc6hBX, 5, 0}, {bc6hBY, 3, 0}, {bc6hRY, 5, 0}, {bc6hRZ, 5, 0}, {bc6hD, 4, 0},
+	}},
+	0x02: {true, 2, 11, [3]uint{5, 4, 4}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 4, 0}, {bc6hRW, 10, 10}, {bc6hGY, 3, 0},
+		{bc6hGX, 3, 0}, {bc6hGW, 10, 10}, {bc6hBZ, 0, 0}, {bc6hGZ, 3, 0}, {bc6hBX, 3, 0}, {bc6hBW, 10, 10},
+		{bc6hBZ, 1, 1}, {bc6hBY, 3, 0}, {bc6hRY, 4, 0}, {bc6hBZ, 2, 2}, {bc6hRZ, 4, 0}, {bc6hBZ, 3, 3},
+		{bc6hD, 4, 0},
+	}},
+	0x06: {true, 2, 11, [3]uint{4, 5, 4}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 3, 0}, {bc6hRW, 10, 10}, {bc6hGZ, 4, 4},
+		{bc6hGY, 3, 0}, {bc6hGX, 4, 0}, {bc6hGW, 10, 10}, {bc6hGZ, 3, 0}, {bc6hBX, 3, 0}, {bc6hBW, 10, 10},
+		{bc6hBZ, 1, 1}, {bc6hBY, 3, 0}, {bc6hRY, 3, 0}, {bc6hBZ, 0, 0}, {bc6hBZ, 2, 2}, {bc6hRZ, 3, 0},
+		{bc6hGY, 4, 4}, {bc6hBZ, 3, 3}, {bc6hD, 4, 0},
+	}},
+	0x0a: {true, 2, 11, [3]uint{4, 4, 5}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 3, 0}, {bc6hRW, 10, 10}, {bc6hBY, 4, 4},
+		{bc6hGY, 3, 0}, {bc6hGX, 3, 0}, {bc6hGW, 10, 10}, {bc6hBZ, 0, 0}, {bc6hGZ, 3, 0}, {bc6hBX, 4, 0},
+		{bc6hBW, 10, 10}, {bc6hBY, 3, 0}, {bc6hRY, 3, 0}, {bc6hBZ, 1, 1}, {bc6hBZ, 2, 2}, {bc6hRZ, 3, 0},
+		{bc6hBZ, 4, 4}, {bc6hBZ, 3, 3}, {bc6hD, 4, 0},
+	}},
+	0x0e: {true, 2, 9, [3]uint{5, 5, 5}, []bc6hField{
+		{bc6hRW, 8, 0}, {bc6hBY, 4, 4}, {bc6hGW, 8, 0}, {bc6hGY, 4, 4}, {bc6hBW, 8, 0}, {bc6hBZ, 4, 4},
+		{bc6hRX, 4, 0}, {bc6hGZ, 4, 4}, {bc6hGY, 3, 0}, {bc6hGX, 4, 0}, {bc6hBZ, 0, 0}, {bc6hGZ, 3, 0},
+		{bc6hBX, 4, 0}, {bc6hBZ, 1, 1}, {bc6hBY, 3, 0}, {bc6hRY, 4, 0}, {bc6hBZ, 2, 2}, {bc6hRZ, 4, 0},
+		{bc6hBZ, 3, 3}, {bc6hD, 4, 0},
+	}},
+	0x12: {true, 2, 8, [3]uint{6, 5, 5}, []bc6hField{
+		{bc6hRW, 7, 0}, {bc6hGZ, 4, 4}, {bc6hBY, 4, 4}, {bc6hGW, 7, 0}, {bc6hBZ, 2, 2}, {bc6hGY, 4, 4},
+		{bc6hBW, 7, 0}, {bc6hBZ, 3, 3}, {bc6hBZ, 4, 4}, {bc6hRX, 5, 0}, {bc6hGY, 3, 0}, {bc6hGX, 4, 0},
+		{bc6hBZ, 0, 0}, {bc6hGZ, 3, 0}, {bc6hBX, 4, 0}, {bc6hBZ, 1, 1}, {bc6hBY, 3, 0}, {bc6hRY, 5, 0},
+		{bc6hRZ, 5, 0}, {bc6hD, 4, 0},
+	}},
+	0x16: {true, 2, 8, [3]uint{5, 6, 5}, []bc6hField{
+		{bc6hRW, 7, 0}, {bc6hBZ, 0, 0}, {bc6hBY, 4, 4}, {bc6hGW, 7, 0}, {bc6hGY, 5, 5}, {bc6hGY, 4, 4},
+		{bc6hBW, 7, 0}, {bc6hGZ, 5, 5}, {bc6hBZ, 4, 4}, {bc6hRX, 4, 0}, {bc6hGZ, 4, 4}, {bc6hGY, 3, 0},
+		{bc6hGX, 5, 0}, {bc6hGZ, 3, 0}, {bc6hBX, 4, 0}, {bc6hBZ, 1, 1}, {bc6hBY, 3, 0}, {bc6hRY, 4, 0},
+		{bc6hBZ, 2, 2}, {bc6hRZ, 4, 0}, {bc6hBZ, 3, 3}, {bc6hD, 4, 0},
+	}},
+	0x1a: {true, 2, 8, [3]uint{5, 5, 6}, []bc6hField{
+		{bc6hRW, 7, 0}, {bc6hBZ, 1, 1}, {bc6hBY, 4, 4}, {bc6hGW, 7, 0}, {bc6hBY, 5, 5}, {bc6hGY, 4, 4},
+		{bc6hBW, 7, 0}, {bc6hBZ, 5, 5}, {bc6hBZ, 4, 4}, {bc6hRX, 4, 0}, {bc6hGZ, 4, 4}, {bc6hGY, 3, 0},
+		{bc6hGX, 4, 0}, {bc6hBZ, 0, 0}, {bc6hGZ, 3, 0}, {bc6hBX, 5, 0}, {bc6hBY, 3, 0}, {bc6hRY, 4, 0},
+		{bc6hBZ, 2, 2}, {bc6hRZ, 4, 0}, {bc6hBZ, 3, 3}, {bc6hD, 4, 0},
+	}},
+	0x1e: {false, 2, 6, [3]uint{6, 6, 6}, []bc6hField{
+		{bc6hRW, 5, 0}, {bc6hGZ, 4, 4}, {bc6hBZ, 0, 0}, {bc6hBZ, 1, 1}, {bc6hBY, 4, 4}, {bc6hGW, 5, 0},
+		{bc6hGY, 5, 5}, {bc6hBY, 5, 5}, {bc6hBZ, 2, 2}, {bc6hGY, 4, 4}, {bc6hBW, 5, 0}, {bc6hGZ, 5, 5},
+		{bc6hBZ, 3, 3}, {bc6hBZ, 5, 5}, {bc6hBZ, 4, 4}, {bc6hRX, 5, 0}, {bc6hGY, 3, 0}, {bc6hGX, 5, 0},
+		{bc6hGZ, 3, 0}, {bc6hBX, 5, 0}, {bc6hBY, 3, 0}, {bc6hRY, 5, 0}, {bc6hRZ, 5, 0}, {bc6hD, 4, 0},
+	}},
+	0x03: {false, 1, 10, [3]uint{10, 10, 10}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 9, 0}, {bc6hGX, 9, 0}, {bc6hBX, 9, 0},
+	}},
+	0x07: {true, 1, 11, [3]uint{9, 9, 9}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 8, 0}, {bc6hRW, 10, 10}, {bc6hGX, 8, 0},
+		{bc6hGW, 10, 10}, {bc6hBX, 8, 0}, {bc6hBW, 10, 10},
+	}},
+	0x0b: {true, 1, 12, [3]uint{8, 8, 8}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 7, 0}, {bc6hRW, 10, 11}, {bc6hGX, 7, 0},
+		{bc6hGW, 10, 11}, {bc6hBX, 7, 0}, {bc6hBW, 10, 11},
+	}},
+	0x0f: {true, 1, 16, [3]uint{4, 4, 4}, []bc6hField{
+		{bc6hRW, 9, 0}, {bc6hGW, 9, 0}, {bc6hBW, 9, 0}, {bc6hRX, 3, 0}, {bc6hRW, 10, 15}, {bc6hGX, 3, 0},
+		{bc6hGW, 10, 15}, {bc6hBX, 3, 0}, {bc6hBW, 10, 15},
+	}},
+}
+
+func decodeBC6H(src []byte, width, height int, signed bool) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	block := make([][3]f16.Number, 16)
+	texels := make([][3]f16.Number, width*height)
+	r := endian.Reader(bytes.NewReader(src), device.LittleEndian)
+	for y := 0; y < height; y += 4 {
+		for x := 0; x < width; x += 4 {
+			b := readBPTCBlock(r)
+			decodeBC6HBlock(&b, block, signed)
+			for dy := 0; dy < 4 && y+dy < height; dy++ {
+				copy(texels[(y+dy)*width+x:(y+dy+1)*width], block[dy*4:dy*4+4])
+			}
+		}
+	}
+	for _, t := range texels {
+		w.Float32(t[0].Float32())
+		w.Float32(t[1].Float32())
+		w.Float32(t[2].Float32())
+		w.Float32(1)
+	}
+	if err := r.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeBC6HBlock(b *bptcBlock, dst [][3]f16.Number, signed bool) {
+	modeValue := b.bits(2)
+	if modeValue >= 2 {
+		modeValue |= b.bits(3) << 2
+	}
+	m, ok := bc6hModes[modeValue]
+	if !ok {
+		// Reserved mode, decodes to black.
+		for i := range dst {
+			dst[i] = [3]f16.Number{}
+		}
+		return
+	}
+
+	var fields [13]int
+	for _, f := range m.layout {
+		if f.last >= f.first {
+			for i := f.first; i <= f.last; i++ {
+				fields[f.value] |= b.bit() << i
+			}
+		} else {
+			for i := f.first; i >= f.last; i-- {
+				fields[f.value] |= b.bit() << i
+			}
+		}
+	}
+	partition := fields[bc6hPartition]
+
+	var endpoints [4][3]int
+	for c := 0; c < 3; c++ {
+		base := fields[c]
+		if signed {
+			base = signExtend(base, m.endpointBits)
+		}
+		endpoints[0][c] = base
+		for i := 1; i < m.regions*2; i++ {
+			v := fields[i*3+c]
+			if m.transformed {
+				v = signExtend(v, m.deltaBits[c])
+				v = (base + v) & (1<<m.endpointBits - 1)
+			}
+			if signed {
+				v = signExtend(v, m.endpointBits)
+			}
+			endpoints[i][c] = v
+		}
+	}
+	for i := range endpoints {
+		for c := range endpoints[i] {
+			endpoints[i][c] = bc6hUnquantize(endpoints[i][c], m.endpointBits, signed)
+		}
+	}
+
+	indexBits := uint(4)
+	if m.regions == 2 {
+		indexBits = 3
+	}
+	for i := range dst {
+		n := indexBits
+		if bptcIsAnchor(m.regions, partition, i) {
+			n--
+		}
+		index := b.bits(n)
+		s := bptcSubset(m.regions, partition, i)
+		for c := range dst[i] {
+			v := bptcInterpolate(endpoints[s*2][c], endpoints[s*2+1][c], index, indexBits)
+			dst[i][c] = bc6hFinish(v, signed)
+		}
+	}
+}
+
+// signExtend returns the n bit value v sign extended.
+func signExtend(v int, n uint) int {
+	if v&(1<<(n-1)) != 0 {
+		return v | -1<<n
+	}
+	return v & (1<<n - 1)
+}
+
+// bc6hUnquantize returns the n bit endpoint value v expanded to 16 bits.
+func bc6hUnquantize(v int, n uint, signed bool) int {
+	if !signed {
+		switch {
+		case n >= 15:
+			return v
+		case v == 0:
+			return 0
+		case v == 1<<n-1:
+			return 0xffff
+		default:
+			return (v<<16 + 0x8000) >> n
+		}
+	}
+	if n >= 16 {
+		return v
+	}
+	negative := v < 0
+	if negative {
+		v = -v
+	}
+	switch {
+	case v == 0:
+	case v >= 1<<(n-1)-1:
+		v = 0x7fff
+	default:
+		v = (v<<15 + 0x4000) >> (n - 1)
+	}
+	if negative {
+		return -v
+	}
+	return v
+}
+
+// bc6hFinish returns the interpolated value v as a half float.
+func bc6hFinish(v int, signed bool) f16.Number {
+	if !signed {
+		return f16.Number(v * 31 >> 6)
+	}
+	if v < 0 {
+		return f16.Number(0x8000 | (-v * 31 >> 5))
+	}
+	return f16.Number(v * 31 >> 5)
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+)
+
+// block is a 128 bit compressed block, built up a bit field at a time.
+type block [16]byte
+
+// set sets the n bits starting at bit start to v.
+func (b *block) set(start, n int, v uint64) *block {
+	for i := 0; i < n; i++ {
+		if p := start + i; (v>>uint(i))&1 != 0 {
+			b[p/8] |= 1 << uint(p%8)
+		}
+	}
+	return b
+}
+
+// setReversed sets the n bits starting at bit 127 - start, counting down, to
+// v.
+func (b *block) setReversed(start, n int, v uint64) *block {
+	for i := 0; i < n; i++ {
+		if p := 127 - (start + i); (v>>uint(i))&1 != 0 {
+			b[p/8] |= 1 << uint(p%8)
+		}
+	}
+	return b
+}
+
+func toF32(data []byte) []float32 {
+	out := make([]float32, len(data)/4)
+	r := endian.Reader(bytes.NewReader(data), device.LittleEndian)
+	for i := range out {
+		out[i] = r.Float32()
+	}
+	return out
+}
+
+func TestBC4(t *testing.T) {
+	ctx := log.Testing(t)
+	b := &block{}
+	b.set(0, 8, 200).set(8, 8, 100)
+	for i := 0; i < 16; i++ {
+		b.set(16+i*3, 3, uint64(i%8))
+	}
+	got, err := image.Convert(b[:8], 4, 4, image.BC4_R_U8_NORM, image.R_U8_NORM)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "texels").That(got[:8]).DeepEquals([]byte{200, 100, 186, 171, 157, 143, 129, 114})
+}
+
+func TestBC7(t *testing.T) {
+	ctx := log.Testing(t)
+	// Mode 6: a single subset with 7 bit endpoints, a p-bit per endpoint and
+	// 4 bit indices.
+	b := &block{}
+	b.set(0, 7, 1<<6)
+	for c := 0; c < 4; c++ {
+		b.set(7+c*14, 7, 0).set(14+c*14, 7, 127)
+	}
+	b.set(63, 1, 0).set(64, 1, 1)
+	b.set(65, 3, 0)
+	for i := 1; i < 16; i++ {
+		b.set(64+i*4, 4, uint64(i))
+	}
+	got, err := image.Convert(b[:], 4, 4, image.BC7_RGBA_U8_NORM, image.RGBA_U8_NORM)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "texel 0").That(got[0:4]).DeepEquals([]byte{0, 0, 0, 0})
+	assert.For(ctx, "texel 8").That(got[32:36]).DeepEquals([]byte{135, 135, 135, 135})
+	assert.For(ctx, "texel 15").That(got[60:64]).DeepEquals([]byte{255, 255, 255, 255})
+}
+
+func TestBC6H(t *testing.T) {
+	ctx := log.Testing(t)
+	// Mode 11: a single region with untransformed 10 bit endpoints and 4 bit
+	// indices.
+	b := &block{}
+	b.set(0, 5, 0x03)
+	for c := 0; c < 3; c++ {
+		b.set(5+c*10, 10, 0).set(35+c*10, 10, 0x3ff)
+	}
+	b.set(65, 3, 0)
+	for i := 1; i < 16; i++ {
+		b.set(64+i*4, 4, uint64(i))
+	}
+	data, err := image.Convert(b[:], 4, 4, image.BC6H_RGB_UF16, image.RGBA_F32)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	got := toF32(data)
+	assert.For(ctx, "texel 0").That(got[0:4]).DeepEquals([]float32{0, 0, 0, 1})
+	assert.For(ctx, "texel 15").That(got[60:64]).DeepEquals([]float32{65504, 65504, 65504, 1})
+}
+
+func TestASTCVoidExtent(t *testing.T) {
+	ctx := log.Testing(t)
+	b := &block{}
+	b.set(0, 12, 0xdfc).set(12, 52, 1<<52-1)
+	b.set(64, 16, 0xffff).set(80, 16, 0x8080).set(96, 16, 0).set(112, 16, 0xffff)
+	got, err := image.Convert(b[:], 5, 5, image.NewASTC_RGBA_5x5(""), image.RGBA_U8_NORM)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	for i := 0; i < 25; i++ {
+		assert.For(ctx, "texel %d", i).That(got[i*4 : i*4+4]).DeepEquals([]byte{255, 128, 0, 255})
+	}
+}
+
+func TestASTC(t *testing.T) {
+	ctx := log.Testing(t)
+	// A single partition block with a 4x4 grid of 2 bit weights and LDR RGB
+	// direct endpoints.
+	b := &block{}
+	b.set(0, 11, 0x42).set(11, 2, 0).set(13, 4, 8)
+	for i, v := range []uint64{0, 255, 0, 255, 0, 255} {
+		b.set(17+i*8, 8, v)
+	}
+	for i := 0; i < 16; i++ {
+		b.setReversed(i*2, 2, uint64(i%4))
+	}
+	got, err := image.Convert(b[:], 4, 4, image.NewASTC_RGBA_4x4(""), image.RGBA_U8_NORM)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "row").That(got[:16]).DeepEquals([]byte{
+		0, 0, 0, 255, 84, 84, 84, 255, 171, 171, 171, 255, 255, 255, 255, 255,
+	})
+}
@@ -0,0 +1,325 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"fmt"
+
+	"github.com/google/gapid/core/data/protoutil"
+)
+
+// TextureShape is the shape of the texture held by a texture container.
+type TextureShape int
+
+const (
+	// Texture2DShape is a two-dimensional texture, or an array of them when
+	// the levels have several layers.
+	Texture2DShape = TextureShape(iota)
+	// CubemapShape is a cube-map, or an array of them, where the layers of
+	// each level are the six faces of each cube in the order
+	// +X, -X, +Y, -Y, +Z, -Z.
+	CubemapShape
+	// Texture3DShape is a three-dimensional texture, where the layers of each
+	// level are its depth slices from front to back. The number of slices
+	// halves with each level, down to 1.
+	Texture3DShape
+)
+
+// layers returns the number of layers level l of a texture of the shape has,
+// given the number of layers of the first level.
+func (s TextureShape) layers(first, l int) int {
+	if s != Texture3DShape {
+		return first
+	}
+	if n := first >> uint(l); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// containerFormat describes how images of a format are stored in the KTX2 and
+// DDS texture containers.
+type containerFormat struct {
+	vkFormat   uint32 // The VkFormat, or 0 if KTX2 cannot hold the format.
+	dxgiFormat uint32 // The DXGI_FORMAT, or 0 if DDS cannot hold the format.
+	fourCC     string // The legacy DDS FourCC, or empty if there is none.
+	typeSize   uint32 // The size of the data type, 1 for block compressed.
+	dfd        dfdBlock
+}
+
+// dfdBlock is a Khronos basic data format descriptor block.
+type dfdBlock struct {
+	model, transfer uint8
+	blockW, blockH  uint8
+	bytes           uint8
+	samples         []dfdSample
+}
+
+// dfdSample is a single sample of a basic data format descriptor block.
+type dfdSample struct {
+	bitOffset, bitLength uint16
+	channel, qualifiers  uint8
+	lower, upper         uint32
+}
+
+const (
+	dfdModelRGBSDA = 1
+	dfdModelBC1A   = 128
+	dfdModelBC2    = 129
+	dfdModelBC3    = 130
+	dfdModelBC4    = 131
+	dfdModelBC5    = 132
+	dfdModelBC6H   = 133
+	dfdModelBC7    = 134
+	dfdModelETC2   = 161
+	dfdModelASTC   = 162
+
+	dfdTransferLinear = 1
+	dfdTransferSRGB   = 2
+
+	dfdLinear = 0x1
+	dfdSigned = 0x4
+	dfdFloat  = 0x8
+
+	dfdChannelAlpha = 15
+)
+
+func dfdTransfer(srgb bool) uint8 {
+	if srgb {
+		return dfdTransferSRGB
+	}
+	return dfdTransferLinear
+}
+
+// blockSample returns a sample of the compressed block of the given channel,
+// covering bits [offset, offset+length).
+func blockSample(channel uint8, offset, length uint16, qualifiers uint8) dfdSample {
+	s := dfdSample{bitOffset: offset, bitLength: length, channel: channel, qualifiers: qualifiers}
+	switch {
+	case qualifiers&dfdFloat != 0:
+		s.lower, s.upper = 0xbf800000, 0x3f800000 // -1.0, 1.0
+	case qualifiers&dfdSigned != 0:
+		s.lower, s.upper = 0x80000000, 0x7fffffff
+	default:
+		s.lower, s.upper = 0, 0xffffffff
+	}
+	return s
+}
+
+func blockFormat(vk, dxgi uint32, fourCC string, model, transfer, w, h, bytes uint8, samples ...dfdSample) containerFormat {
+	return containerFormat{
+		vkFormat:   vk,
+		dxgiFormat: dxgi,
+		fourCC:     fourCC,
+		typeSize:   1,
+		dfd:        dfdBlock{model, transfer, w, h, bytes, samples},
+	}
+}
+
+// rgba8ContainerFormat returns the container format for RGBA_U8_NORM images.
+func rgba8ContainerFormat(srgb bool) containerFormat {
+	alpha := uint8(0)
+	vk, dxgi := uint32(37), uint32(28) // R8G8B8A8_UNORM
+	if srgb {
+		vk, dxgi = 43, 29 // R8G8B8A8_SRGB
+		alpha = dfdLinear
+	}
+	return containerFormat{
+		vkFormat:   vk,
+		dxgiFormat: dxgi,
+		typeSize:   1,
+		dfd: dfdBlock{dfdModelRGBSDA, dfdTransfer(srgb), 1, 1, 4, []dfdSample{
+			{0, 8, 0, 0, 0, 255},
+			{8, 8, 1, 0, 0, 255},
+			{16, 8, 2, 0, 0, 255},
+			{24, 8, dfdChannelAlpha, alpha, 0, 255},
+		}},
+	}
+}
+
+// rgbaF32ContainerFormat is the container format for RGBA_F32 images.
+var rgbaF32ContainerFormat = containerFormat{
+	vkFormat:   109, // R32G32B32A32_SFLOAT
+	dxgiFormat: 2,   // R32G32B32A32_FLOAT
+	typeSize:   4,
+	dfd: dfdBlock{dfdModelRGBSDA, dfdTransferLinear, 1, 1, 16, []dfdSample{
+		{0, 32, 0, dfdFloat | dfdSigned, 0xbf800000, 0x3f800000},
+		{32, 32, 1, dfdFloat | dfdSigned, 0xbf800000, 0x3f800000},
+		{64, 32, 2, dfdFloat | dfdSigned, 0xbf800000, 0x3f800000},
+		{96, 32, dfdChannelAlpha, dfdFloat | dfdSigned, 0xbf800000, 0x3f800000},
+	}},
+}
+
+// astcBlockSizes lists the ASTC block sizes in VkFormat order.
+var astcBlockSizes = [][2]uint32{
+	{4, 4}, {5, 4}, {5, 5}, {6, 5}, {6, 6}, {8, 5}, {8, 6}, {8, 8},
+	{10, 5}, {10, 6}, {10, 8}, {10, 10}, {12, 10}, {12, 12},
+}
+
+// containerFormatOf returns the container format that holds images of the
+// format f without conversion. The returned format's vkFormat and dxgiFormat
+// are 0 for formats the containers cannot hold.
+func containerFormatOf(f *Format) containerFormat {
+	bc := func(vk, dxgi uint32, fourCC string, model, bytes uint8, samples ...dfdSample) containerFormat {
+		return blockFormat(vk, dxgi, fourCC, model, dfdTransferLinear, 4, 4, bytes, samples...)
+	}
+	etc := func(vk uint32, srgb bool, bytes uint8, samples ...dfdSample) containerFormat {
+		if srgb {
+			vk++
+		}
+		return blockFormat(vk, 0, "", dfdModelETC2, dfdTransfer(srgb), 4, 4, bytes, samples...)
+	}
+	switch f := protoutil.OneOf(f.Format).(type) {
+	case *FmtUncompressed:
+		switch f.key() {
+		case RGBA_U8_NORM.Key():
+			return rgba8ContainerFormat(false)
+		case RGBA_F32.Key():
+			return rgbaF32ContainerFormat
+		}
+	case *FmtS3_DXT1_RGB:
+		return bc(131, 71, "DXT1", dfdModelBC1A, 8, blockSample(0, 0, 64, 0))
+	case *FmtS3_DXT1_RGBA:
+		return bc(133, 71, "DXT1", dfdModelBC1A, 8, blockSample(1, 0, 64, 0))
+	case *FmtS3_DXT3_RGBA:
+		return bc(135, 74, "DXT3", dfdModelBC2, 16, blockSample(dfdChannelAlpha, 0, 64, 0), blockSample(0, 64, 64, 0))
+	case *FmtS3_DXT5_RGBA:
+		return bc(137, 77, "DXT5", dfdModelBC3, 16, blockSample(dfdChannelAlpha, 0, 64, 0), blockSample(0, 64, 64, 0))
+	case *FmtBC4_R_U8_NORM:
+		return bc(139, 80, "", dfdModelBC4, 8, blockSample(0, 0, 64, 0))
+	case *FmtBC4_R_S8_NORM:
+		return bc(140, 81, "", dfdModelBC4, 8, blockSample(0, 0, 64, dfdSigned))
+	case *FmtBC5_RG_U8_NORM:
+		return bc(141, 83, "", dfdModelBC5, 16, blockSample(0, 0, 64, 0), blockSample(1, 64, 64, 0))
+	case *FmtBC5_RG_S8_NORM:
+		return bc(142, 84, "", dfdModelBC5, 16, blockSample(0, 0, 64, dfdSigned), blockSample(1, 64, 64, dfdSigned))
+	case *FmtBC6H_RGB_F16:
+		if f.Signed {
+			return bc(144, 96, "", dfdModelBC6H, 16, blockSample(0, 0, 128, dfdFloat|dfdSigned))
+		}
+		return bc(143, 95, "", dfdModelBC6H, 16, blockSample(0, 0, 128, dfdFloat))
+	case *FmtBC7_RGBA_U8_NORM:
+		vk, dxgi := uint32(145), uint32(98)
+		if f.Srgb {
+			vk, dxgi = 146, 99
+		}
+		return blockFormat(vk, dxgi, "", dfdModelBC7, dfdTransfer(f.Srgb), 4, 4, 16, blockSample(0, 0, 128, 0))
+	case *FmtETC1_RGB_U8_NORM:
+		// ETC1 is a subset of ETC2.
+		return etc(147, false, 8, blockSample(2, 0, 64, 0))
+	case *FmtETC2_RGB_U8_NORM:
+		return etc(147, f.Srgb, 8, blockSample(2, 0, 64, 0))
+	case *FmtETC2_RGBA_U8U8U8U1_NORM:
+		return etc(149, f.Srgb, 8, blockSample(2, 0, 64, 0))
+	case *FmtETC2_RGBA_U8_NORM:
+		return etc(151, f.Srgb, 16, blockSample(dfdChannelAlpha, 0, 64, 0), blockSample(2, 64, 64, 0))
+	case *FmtETC2_R_U11_NORM:
+		return etc(153, false, 8, blockSample(0, 0, 64, 0))
+	case *FmtETC2_R_S11_NORM:
+		return etc(154, false, 8, blockSample(0, 0, 64, dfdSigned))
+	case *FmtETC2_RG_U11_NORM:
+		return etc(155, false, 16, blockSample(0, 0, 64, 0), blockSample(1, 64, 64, 0))
+	case *FmtETC2_RG_S11_NORM:
+		return etc(156, false, 16, blockSample(0, 0, 64, dfdSigned), blockSample(1, 64, 64, dfdSigned))
+	case *FmtASTC:
+		for i, s := range astcBlockSizes {
+			if s[0] == f.BlockWidth && s[1] == f.BlockHeight {
+				vk := 157 + uint32(i)*2
+				if f.Srgb {
+					vk++
+				}
+				return blockFormat(vk, 0, "", dfdModelASTC, dfdTransfer(f.Srgb),
+					uint8(s[0]), uint8(s[1]), 16, blockSample(0, 0, 128, 0))
+			}
+		}
+	}
+	return containerFormat{}
+}
+
+// isSRGB returns true if the format f holds sRGB encoded colors.
+func isSRGB(f *Format) bool {
+	switch f := protoutil.OneOf(f.Format).(type) {
+	case *FmtASTC:
+		return f.Srgb
+	case *FmtBC7_RGBA_U8_NORM:
+		return f.Srgb
+	case *FmtETC2_RGB_U8_NORM:
+		return f.Srgb
+	case *FmtETC2_RGBA_U8U8U8U1_NORM:
+		return f.Srgb
+	case *FmtETC2_RGBA_U8_NORM:
+		return f.Srgb
+	}
+	return false
+}
+
+// isHighPrecision returns true if the format f has channels that cannot be
+// represented with 8 bits without loss.
+func isHighPrecision(f *Format) bool {
+	switch f := protoutil.OneOf(f.Format).(type) {
+	case *FmtUncompressed:
+		for _, c := range f.Format.Components {
+			if c.DataType.IsFloat() || c.DataType.Bits() > 8 {
+				return true
+			}
+		}
+	case *FmtBC6H_RGB_F16:
+		return true
+	}
+	return false
+}
+
+// containerImages returns the images of a texture of the given shape, indexed
+// by level then layer, in a format that the container can hold, along with the
+// container format. has returns true if the container can hold the given
+// container format without conversion.
+func containerImages(images [][]*Image2D, shape TextureShape, has func(containerFormat) bool) ([][]*Image2D, containerFormat, error) {
+	if len(images) == 0 || len(images[0]) == 0 {
+		return nil, containerFormat{}, fmt.Errorf("Texture has no images")
+	}
+	format := images[0][0].Format
+	for l, level := range images {
+		if expected := shape.layers(len(images[0]), l); len(level) != expected {
+			return nil, containerFormat{}, fmt.Errorf("Level %d has %d layers, expected %d", l, len(level), expected)
+		}
+		for _, img := range level {
+			if img.Format.Key() != format.Key() {
+				return nil, containerFormat{}, fmt.Errorf("Level %d has format %v, expected %v", l, img.Format, format)
+			}
+		}
+	}
+
+	if cf := containerFormatOf(format); has(cf) {
+		return images, cf, nil
+	}
+
+	// The container cannot hold the format. Convert to a format it can.
+	to, cf := RGBA_U8_NORM, rgba8ContainerFormat(isSRGB(format))
+	if isHighPrecision(format) {
+		to, cf = RGBA_F32, rgbaF32ContainerFormat
+	}
+	out := make([][]*Image2D, len(images))
+	for l, level := range images {
+		out[l] = make([]*Image2D, len(level))
+		for i, img := range level {
+			converted, err := img.Convert(to)
+			if err != nil {
+				return nil, containerFormat{}, err
+			}
+			out[l][i] = converted
+		}
+	}
+	return out, cf, nil
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+)
+
+// mipChain returns a chain of 8x8, 4x4 and 2x2 images of the format f, each
+// with the given number of layers.
+func mipChain(f *image.Format, layers int) [][]*image.Image2D {
+	out := [][]*image.Image2D{}
+	for size := uint32(8); size >= 2; size /= 2 {
+		level := []*image.Image2D{}
+		for i := 0; i < layers; i++ {
+			level = append(level, &image.Image2D{
+				Format: f,
+				Width:  size,
+				Height: size,
+				Data:   make([]byte, f.Size(int(size), int(size))),
+			})
+		}
+		out = append(out, level)
+	}
+	return out
+}
+
+// volume returns the 8x8x4, 4x4x2 and 2x2x1 levels of a 3D texture of the
+// format f.
+func volume(f *image.Format) [][]*image.Image2D {
+	out := mipChain(f, 4)
+	out[1], out[2] = out[1][:2], out[2][:1]
+	return out
+}
+
+func TestKTX2(t *testing.T) {
+	ctx := log.Testing(t)
+	data, err := image.KTX2(mipChain(image.S3_DXT1_RGB, 6), image.CubemapShape)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+
+	r := endian.Reader(bytes.NewReader(data), device.LittleEndian)
+	identifier := make([]byte, 12)
+	r.Data(identifier)
+	assert.For(ctx, "identifier").That(identifier).DeepEquals([]byte{
+		0xAB, 0x4B, 0x54, 0x58, 0x20, 0x32, 0x30, 0xBB, 0x0D, 0x0A, 0x1A, 0x0A})
+	header := make([]uint32, 9)
+	for i := range header {
+		header[i] = r.Uint32()
+	}
+	// vkFormat, typeSize, width, height, depth, layers, faces, levels, supercompression
+	assert.For(ctx, "header").That(header).DeepEquals([]uint32{131, 1, 8, 8, 0, 0, 6, 3, 0})
+
+	r.Data(make([]byte, 32)) // index
+	lengths := []uint64{}
+	for i := 0; i < 3; i++ {
+		r.Uint64()
+		lengths = append(lengths, r.Uint64())
+		r.Uint64()
+	}
+	assert.For(ctx, "level lengths").That(lengths).DeepEquals([]uint64{6 * 32, 6 * 8, 6 * 8})
+}
+
+func TestKTX2Shapes(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, test := range []struct {
+		name   string
+		images [][]*image.Image2D
+		shape  image.TextureShape
+		header []uint32 // width, height, depth, layers, faces, levels
+	}{
+		{"2D", mipChain(image.RGBA_U8_NORM, 1), image.Texture2DShape, []uint32{8, 8, 0, 0, 1, 3}},
+		{"array", mipChain(image.RGBA_U8_NORM, 3), image.Texture2DShape, []uint32{8, 8, 0, 3, 1, 3}},
+		{"cube array", mipChain(image.RGBA_U8_NORM, 12), image.CubemapShape, []uint32{8, 8, 0, 2, 6, 3}},
+		{"3D", volume(image.RGBA_U8_NORM), image.Texture3DShape, []uint32{8, 8, 4, 0, 1, 3}},
+	} {
+		data, err := image.KTX2(test.images, test.shape)
+		if !assert.For(ctx, "%s: err", test.name).ThatError(err).Succeeded() {
+			continue
+		}
+		r := endian.Reader(bytes.NewReader(data[20:]), device.LittleEndian)
+		header := make([]uint32, 6)
+		for i := range header {
+			header[i] = r.Uint32()
+		}
+		assert.For(ctx, "%s: header", test.name).That(header).DeepEquals(test.header)
+	}
+
+	// 3D textures need the depth slices to halve with each level.
+	_, err := image.KTX2(mipChain(image.RGBA_U8_NORM, 4), image.Texture3DShape)
+	assert.For(ctx, "3D with constant depth").ThatError(err).Failed()
+}
+
+func TestDDS(t *testing.T) {
+	ctx := log.Testing(t)
+	// DDS cannot hold ETC2, so the images are decompressed.
+	data, err := image.DDS(mipChain(image.ETC2_RGB_U8_NORM, 1), image.Texture2DShape)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+
+	r := endian.Reader(bytes.NewReader(data), device.LittleEndian)
+	magic := make([]byte, 4)
+	r.Data(magic)
+	assert.For(ctx, "magic").That(string(magic)).Equals("DDS ")
+	size, _, height, width, pitch, _, levels := r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32()
+	assert.For(ctx, "size").That(size).Equals(uint32(124))
+	assert.For(ctx, "width").That(width).Equals(uint32(8))
+	assert.For(ctx, "height").That(height).Equals(uint32(8))
+	assert.For(ctx, "pitch").That(pitch).Equals(uint32(8 * 4))
+	assert.For(ctx, "levels").That(levels).Equals(uint32(3))
+	assert.For(ctx, "data size").That(len(data)).Equals(128 + (8*8+4*4+2*2)*4)
+}
+
+func TestDDSVolume(t *testing.T) {
+	ctx := log.Testing(t)
+	data, err := image.DDS(volume(image.RGBA_U8_NORM), image.Texture3DShape)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+
+	r := endian.Reader(bytes.NewReader(data[4:]), device.LittleEndian)
+	_, flags, _, _, _, depth, levels := r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32()
+	assert.For(ctx, "depth flag").That(flags & 0x800000).Equals(uint32(0x800000))
+	assert.For(ctx, "depth").That(depth).Equals(uint32(4))
+	assert.For(ctx, "levels").That(levels).Equals(uint32(3))
+	r.Data(make([]byte, 11*4+32+4)) // dwReserved1, ddspf, dwCaps
+	assert.For(ctx, "caps2").That(r.Uint32()).Equals(uint32(0x200000))
+	assert.For(ctx, "data size").That(len(data)).Equals(128 + (8*8*4+4*4*2+2*2)*4)
+}
@@ -31,6 +31,10 @@ type srcDstFmt struct{ src, dst interface{} }
 
 var registeredConverters = make(map[srcDstFmt]Converter)
 
+// registeredTargets holds the formats each source format can be converted to,
+// in registration order.
+var registeredTargets = make(map[interface{}][]*Format)
+
 // RegisterConverter registers the Converter for converting from src to dst
 // formats. If a converter already exists for converting from src to dst, then
 // this function panics.
@@ -40,6 +44,7 @@ func RegisterConverter(src, dst *Format, c Converter) {
 		panic(fmt.Errorf("Converter from %s to %s already registered", src, dst))
 	}
 	registeredConverters[key] = c
+	registeredTargets[key.src] = append(registeredTargets[key.src], dst)
 }
 
 func registered(src, dst *Format) bool {
@@ -70,26 +75,21 @@ func Convert(data []byte, width, height int, srcFmt, dstFmt *Format) ([]byte, er
 		return nil, fmt.Errorf("Source data of format %s is invalid: %s", srcFmt, err)
 	}
 
-	// Look for a registered converter.
-	if conv, found := registeredConverters[srcDstFmt{srcKey, dstKey}]; found {
-		return conv(data, width, height)
+	if data, err := convertDirect(data, width, height, srcFmt, dstFmt); data != nil || err != nil {
+		return data, err
 	}
 
-	// Check if the source format supports the converter interface.
-	if c, ok := protoutil.OneOf(srcFmt.Format).(converter); ok {
-		data, err := c.convert(data, width, height, dstFmt)
-		if data != nil || err != nil {
-			return data, err
+	// No direct conversion found. Try going via each of the formats the source
+	// format can be converted to, such as the decoded form of a compressed
+	// format.
+	for _, via := range registeredTargets[srcKey] {
+		conv := registeredConverters[srcDstFmt{srcKey, via.Key()}]
+		viaData, err := conv(data, width, height)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	// No direct conversion found. Try going via RGBA_U8_NORM.
-	rgbaU8Key := RGBA_U8_NORM.Key()
-	if convA, found := registeredConverters[srcDstFmt{srcKey, rgbaU8Key}]; found {
-		if convB, found := registeredConverters[srcDstFmt{rgbaU8Key, dstKey}]; found {
-			if data, err := convA(data, width, height); err != nil {
-				return convB(data, width, height)
-			}
+		if data, err := convertDirect(viaData, width, height, via, dstFmt); data != nil || err != nil {
+			return data, err
 		}
 	}
 
@@ -97,6 +97,24 @@ func Convert(data []byte, width, height int, srcFmt, dstFmt *Format) ([]byte, er
 		srcFmt, dstFmt)
 }
 
+// convertDirect converts the image formed from data, width and height from
+// srcFmt to dstFmt using either a registered Converter or the source format's
+// converter interface. If there is no direct conversion then nil, nil is
+// returned.
+func convertDirect(data []byte, width, height int, srcFmt, dstFmt *Format) ([]byte, error) {
+	// Look for a registered converter.
+	if conv, found := registeredConverters[srcDstFmt{srcFmt.Key(), dstFmt.Key()}]; found {
+		return conv(data, width, height)
+	}
+
+	// Check if the source format supports the converter interface.
+	if c, ok := protoutil.OneOf(srcFmt.Format).(converter); ok {
+		return c.convert(data, width, height, dstFmt)
+	}
+
+	return nil, nil
+}
+
 // Resolve returns the byte array holding the converted image for the resolve
 // request.
 // TODO: Can this be moved to the resolve package?
@@ -0,0 +1,172 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/os/device"
+)
+
+// See: https://docs.microsoft.com/en-us/windows/desktop/direct3ddds/dx-graphics-dds-pguide
+
+const (
+	ddsdCaps        = 0x1
+	ddsdHeight      = 0x2
+	ddsdWidth       = 0x4
+	ddsdPitch       = 0x8
+	ddsdPixelFormat = 0x1000
+	ddsdMipMapCount = 0x20000
+	ddsdLinearSize  = 0x80000
+	ddsdDepth       = 0x800000
+
+	ddpfAlphaPixels = 0x1
+	ddpfFourCC      = 0x4
+	ddpfRGB         = 0x40
+
+	ddsCapsComplex = 0x8
+	ddsCapsTexture = 0x1000
+	ddsCapsMipMap  = 0x400000
+
+	ddsCaps2Cubemap         = 0x200
+	ddsCaps2CubemapAllFaces = 0xfc00
+	ddsCaps2Volume          = 0x200000
+
+	ddsDimensionTexture2D = 3
+	ddsDimensionTexture3D = 4
+	ddsMiscTextureCube    = 0x4
+)
+
+// DDS returns the images of a texture of the given shape encoded as a DDS
+// texture container. images is indexed by mip-map level then layer.
+// Compressed images are stored without conversion where DDS supports the
+// format, otherwise all the images are decompressed.
+func DDS(images [][]*Image2D, shape TextureShape) ([]byte, error) {
+	images, cf, err := containerImages(images, shape, func(cf containerFormat) bool { return cf.dxgiFormat != 0 })
+	if err != nil {
+		return nil, err
+	}
+	layers, depth := uint32(len(images[0])), uint32(0)
+	switch shape {
+	case CubemapShape:
+		if layers%6 != 0 {
+			return nil, fmt.Errorf("Cube-map has %d faces, expected a multiple of 6", layers)
+		}
+		layers /= 6
+	case Texture3DShape:
+		layers, depth = 1, layers
+	}
+	base := images[0][0]
+	compressed := cf.dfd.blockW > 1 || cf.dfd.blockH > 1
+
+	// Use the legacy header where possible as it is more widely supported.
+	legacyRGBA := cf.dxgiFormat == rgba8ContainerFormat(false).dxgiFormat
+	dx10 := layers > 1 || (cf.fourCC == "" && !legacyRGBA)
+
+	flags := uint32(ddsdCaps | ddsdHeight | ddsdWidth | ddsdPixelFormat | ddsdMipMapCount)
+	pitch := uint32(len(base.Data))
+	if compressed {
+		flags |= ddsdLinearSize
+	} else {
+		flags |= ddsdPitch
+		pitch = base.Width * uint32(cf.dfd.bytes)
+	}
+	caps, caps2 := uint32(ddsCapsTexture), uint32(0)
+	if len(images) > 1 {
+		caps |= ddsCapsComplex | ddsCapsMipMap
+	}
+	dimension := uint32(ddsDimensionTexture2D)
+	switch shape {
+	case CubemapShape:
+		caps |= ddsCapsComplex
+		caps2 |= ddsCaps2Cubemap | ddsCaps2CubemapAllFaces
+	case Texture3DShape:
+		flags |= ddsdDepth
+		caps |= ddsCapsComplex
+		caps2 |= ddsCaps2Volume
+		dimension = ddsDimensionTexture3D
+	}
+
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	w.Data([]byte("DDS "))
+	w.Uint32(124) // dwSize
+	w.Uint32(flags)
+	w.Uint32(base.Height)
+	w.Uint32(base.Width)
+	w.Uint32(pitch)
+	w.Uint32(depth)
+	w.Uint32(uint32(len(images)))
+	w.Data(make([]byte, 11*4)) // dwReserved1
+
+	// DDS_PIXELFORMAT
+	w.Uint32(32) // dwSize
+	switch {
+	case dx10:
+		w.Uint32(ddpfFourCC)
+		w.Data([]byte("DX10"))
+		w.Data(make([]byte, 5*4))
+	case cf.fourCC != "":
+		w.Uint32(ddpfFourCC)
+		w.Data([]byte(cf.fourCC))
+		w.Data(make([]byte, 5*4))
+	default:
+		w.Uint32(ddpfRGB | ddpfAlphaPixels)
+		w.Uint32(0)  // dwFourCC
+		w.Uint32(32) // dwRGBBitCount
+		w.Uint32(0x000000ff)
+		w.Uint32(0x0000ff00)
+		w.Uint32(0x00ff0000)
+		w.Uint32(0xff000000)
+	}
+
+	w.Uint32(caps)
+	w.Uint32(caps2)
+	w.Data(make([]byte, 3*4)) // dwCaps3, dwCaps4, dwReserved2
+
+	if dx10 {
+		misc := uint32(0)
+		if shape == CubemapShape {
+			misc = ddsMiscTextureCube
+		}
+		w.Uint32(cf.dxgiFormat)
+		w.Uint32(dimension)
+		w.Uint32(misc)
+		w.Uint32(layers)
+		w.Uint32(0) // miscFlags2
+	}
+
+	if shape == Texture3DShape {
+		// Each level holds all of its depth slices.
+		for _, level := range images {
+			for _, img := range level {
+				w.Data(img.Data)
+			}
+		}
+	} else {
+		// Each layer holds its complete chain of mip-map levels.
+		for i := range images[0] {
+			for _, level := range images {
+				w.Data(level[i].Data)
+			}
+		}
+	}
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -68,6 +68,12 @@ var _ = []format{
 	&FmtS3_DXT3_RGBA{},
 	&FmtS3_DXT5_RGBA{},
 	&FmtASTC{},
+	&FmtBC4_R_U8_NORM{},
+	&FmtBC4_R_S8_NORM{},
+	&FmtBC5_RG_U8_NORM{},
+	&FmtBC5_RG_S8_NORM{},
+	&FmtBC6H_RGB_F16{},
+	&FmtBC7_RGBA_U8_NORM{},
 }
 
 // Check returns an error if the combination of data, image width and image
@@ -0,0 +1,154 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/os/device"
+)
+
+// See: https://registry.khronos.org/KTX/specs/2.0/ktxspec.v2.html
+
+var ktx2Identifier = []byte{0xAB, 0x4B, 0x54, 0x58, 0x20, 0x32, 0x30, 0xBB, 0x0D, 0x0A, 0x1A, 0x0A}
+
+const (
+	ktx2HeaderSize     = 80 // Identifier, header and index.
+	ktx2LevelIndexSize = 24
+)
+
+// KTX2 returns the images of a texture of the given shape encoded as a KTX2
+// texture container. images is indexed by mip-map level then layer.
+// Compressed images are stored without conversion where KTX2 supports the
+// format, otherwise all the images are decompressed.
+func KTX2(images [][]*Image2D, shape TextureShape) ([]byte, error) {
+	images, cf, err := containerImages(images, shape, func(cf containerFormat) bool { return cf.vkFormat != 0 })
+	if err != nil {
+		return nil, err
+	}
+	layers, faces, depth := uint32(len(images[0])), uint32(1), uint32(0)
+	switch shape {
+	case CubemapShape:
+		if layers%6 != 0 {
+			return nil, fmt.Errorf("Cube-map has %d faces, expected a multiple of 6", layers)
+		}
+		layers, faces = layers/6, 6
+	case Texture3DShape:
+		layers, depth = 1, layers
+	}
+	if layers == 1 {
+		layers = 0 // Not an array texture.
+	}
+
+	dfd := ktx2DFD(cf.dfd)
+	kvd := ktx2KeyValue("KTXwriter", "GAPID")
+	dfdOffset := ktx2HeaderSize + ktx2LevelIndexSize*len(images)
+	kvdOffset := dfdOffset + len(dfd)
+
+	// The levels are stored from the smallest to the largest, each aligned to
+	// the texel block size.
+	align := int(cf.dfd.bytes)
+	if align%4 != 0 {
+		align *= 4 / gcd(align, 4)
+	}
+	offsets := make([]int, len(images))
+	lengths := make([]int, len(images))
+	end := kvdOffset + len(kvd)
+	for l := len(images) - 1; l >= 0; l-- {
+		end = (end + align - 1) / align * align
+		offsets[l] = end
+		for _, img := range images[l] {
+			lengths[l] += len(img.Data)
+		}
+		end += lengths[l]
+	}
+
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	w.Data(ktx2Identifier)
+	w.Uint32(cf.vkFormat)
+	w.Uint32(cf.typeSize)
+	w.Uint32(images[0][0].Width)
+	w.Uint32(images[0][0].Height)
+	w.Uint32(depth)
+	w.Uint32(layers)
+	w.Uint32(faces)
+	w.Uint32(uint32(len(images)))
+	w.Uint32(0) // supercompressionScheme
+	w.Uint32(uint32(dfdOffset))
+	w.Uint32(uint32(len(dfd)))
+	w.Uint32(uint32(kvdOffset))
+	w.Uint32(uint32(len(kvd)))
+	w.Uint64(0) // sgdByteOffset
+	w.Uint64(0) // sgdByteLength
+	for l := range images {
+		w.Uint64(uint64(offsets[l]))
+		w.Uint64(uint64(lengths[l]))
+		w.Uint64(uint64(lengths[l]))
+	}
+	w.Data(dfd)
+	w.Data(kvd)
+	for l := len(images) - 1; l >= 0; l-- {
+		w.Data(make([]byte, offsets[l]-buf.Len()))
+		for _, img := range images[l] {
+			w.Data(img.Data)
+		}
+	}
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ktx2DFD returns the data format descriptor holding the single basic block b.
+func ktx2DFD(b dfdBlock) []byte {
+	blockSize := 24 + 16*len(b.samples)
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	w.Uint32(uint32(4 + blockSize)) // dfdTotalSize
+	w.Uint32(0)                     // vendorId, descriptorType
+	w.Uint32(2 | uint32(blockSize)<<16)
+	w.Uint32(uint32(b.model) | 1<<8 | uint32(b.transfer)<<16) // BT709 primaries
+	w.Uint32(uint32(b.blockW-1) | uint32(b.blockH-1)<<8)
+	w.Uint32(uint32(b.bytes))
+	w.Uint32(0)
+	for _, s := range b.samples {
+		w.Uint32(uint32(s.bitOffset) | uint32(s.bitLength-1)<<16 | uint32(s.channel)<<24 | uint32(s.qualifiers)<<28)
+		w.Uint32(0) // samplePosition
+		w.Uint32(s.lower)
+		w.Uint32(s.upper)
+	}
+	return buf.Bytes()
+}
+
+// ktx2KeyValue returns the key-value pair encoded with its padding.
+func ktx2KeyValue(key, value string) []byte {
+	kv := append(append([]byte(key), 0), append([]byte(value), 0)...)
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	w.Uint32(uint32(len(kv)))
+	w.Data(kv)
+	w.Data(make([]byte, (4-len(kv)%4)%4))
+	return buf.Bytes()
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,183 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/math/sint"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/stream"
+)
+
+var (
+	BC4_R_U8_NORM  = NewBC4_R_U8_NORM("BC4_R_U8_NORM")
+	BC4_R_S8_NORM  = NewBC4_R_S8_NORM("BC4_R_S8_NORM")
+	BC5_RG_U8_NORM = NewBC5_RG_U8_NORM("BC5_RG_U8_NORM")
+	BC5_RG_S8_NORM = NewBC5_RG_S8_NORM("BC5_RG_S8_NORM")
+)
+
+// NewBC4_R_U8_NORM returns a format representing the unsigned BC4 (RGTC1)
+// block texture compression format.
+func NewBC4_R_U8_NORM(name string) *Format {
+	return &Format{name, &Format_Bc4RU8Norm{&FmtBC4_R_U8_NORM{}}}
+}
+
+func (f *FmtBC4_R_U8_NORM) key() interface{} {
+	return *f
+}
+func (*FmtBC4_R_U8_NORM) size(w, h int) int {
+	return (sint.Max(sint.AlignUp(w, 4), 4) * sint.Max(sint.AlignUp(h, 4), 4)) / 2
+}
+func (f *FmtBC4_R_U8_NORM) check(d []byte, w, h int) error {
+	return checkSize(d, f, w, h)
+}
+func (*FmtBC4_R_U8_NORM) channels() []stream.Channel {
+	return []stream.Channel{stream.Channel_Red}
+}
+
+// NewBC4_R_S8_NORM returns a format representing the signed BC4 (RGTC1)
+// block texture compression format.
+func NewBC4_R_S8_NORM(name string) *Format {
+	return &Format{name, &Format_Bc4RS8Norm{&FmtBC4_R_S8_NORM{}}}
+}
+
+func (f *FmtBC4_R_S8_NORM) key() interface{} {
+	return *f
+}
+func (*FmtBC4_R_S8_NORM) size(w, h int) int {
+	return (sint.Max(sint.AlignUp(w, 4), 4) * sint.Max(sint.AlignUp(h, 4), 4)) / 2
+}
+func (f *FmtBC4_R_S8_NORM) check(d []byte, w, h int) error {
+	return checkSize(d, f, w, h)
+}
+func (*FmtBC4_R_S8_NORM) channels() []stream.Channel {
+	return []stream.Channel{stream.Channel_Red}
+}
+
+// NewBC5_RG_U8_NORM returns a format representing the unsigned BC5 (RGTC2)
+// block texture compression format.
+func NewBC5_RG_U8_NORM(name string) *Format {
+	return &Format{name, &Format_Bc5RgU8Norm{&FmtBC5_RG_U8_NORM{}}}
+}
+
+func (f *FmtBC5_RG_U8_NORM) key() interface{} {
+	return *f
+}
+func (*FmtBC5_RG_U8_NORM) size(w, h int) int {
+	return sint.Max(sint.AlignUp(w, 4), 4) * sint.Max(sint.AlignUp(h, 4), 4)
+}
+func (f *FmtBC5_RG_U8_NORM) check(d []byte, w, h int) error {
+	return checkSize(d, f, w, h)
+}
+func (*FmtBC5_RG_U8_NORM) channels() []stream.Channel {
+	return []stream.Channel{stream.Channel_Red, stream.Channel_Green}
+}
+
+// NewBC5_RG_S8_NORM returns a format representing the signed BC5 (RGTC2)
+// block texture compression format.
+func NewBC5_RG_S8_NORM(name string) *Format {
+	return &Format{name, &Format_Bc5RgS8Norm{&FmtBC5_RG_S8_NORM{}}}
+}
+
+func (f *FmtBC5_RG_S8_NORM) key() interface{} {
+	return *f
+}
+func (*FmtBC5_RG_S8_NORM) size(w, h int) int {
+	return sint.Max(sint.AlignUp(w, 4), 4) * sint.Max(sint.AlignUp(h, 4), 4)
+}
+func (f *FmtBC5_RG_S8_NORM) check(d []byte, w, h int) error {
+	return checkSize(d, f, w, h)
+}
+func (*FmtBC5_RG_S8_NORM) channels() []stream.Channel {
+	return []stream.Channel{stream.Channel_Red, stream.Channel_Green}
+}
+
+func init() {
+	RegisterConverter(BC4_R_U8_NORM, R_U8_NORM, func(src []byte, width, height int) ([]byte, error) {
+		return decodeRGTC(src, width, height, 1, false)
+	})
+	RegisterConverter(BC4_R_S8_NORM, R_S8_NORM, func(src []byte, width, height int) ([]byte, error) {
+		return decodeRGTC(src, width, height, 1, true)
+	})
+	RegisterConverter(BC5_RG_U8_NORM, RG_U8_NORM, func(src []byte, width, height int) ([]byte, error) {
+		return decodeRGTC(src, width, height, 2, false)
+	})
+	RegisterConverter(BC5_RG_S8_NORM, RG_S8_NORM, func(src []byte, width, height int) ([]byte, error) {
+		return decodeRGTC(src, width, height, 2, true)
+	})
+}
+
+// decodeRGTC decodes the BC4 (channels = 1) or BC5 (channels = 2) image to
+// 8 bit normalized values.
+func decodeRGTC(src []byte, width, height int, channels int, signed bool) ([]byte, error) {
+	dst := make([]byte, width*height*channels)
+	block := make([]int, 16)
+	r := endian.Reader(bytes.NewReader(src), device.LittleEndian)
+	for y := 0; y < height; y += 4 {
+		for x := 0; x < width; x += 4 {
+			for c := 0; c < channels; c++ {
+				decodeRGTCBlock(r, block, signed)
+				for dy := 0; dy < 4 && y+dy < height; dy++ {
+					for dx := 0; dx < 4 && x+dx < width; dx++ {
+						dst[((y+dy)*width+x+dx)*channels+c] = byte(block[dy*4+dx])
+					}
+				}
+			}
+		}
+	}
+	return dst, r.Error()
+}
+
+// decodeRGTCBlock decodes a single channel BC4 block of 16 values.
+func decodeRGTCBlock(r pod.Reader, dst []int, signed bool) {
+	var e0, e1, min, max int
+	if signed {
+		e0, e1, min, max = int(r.Int8()), int(r.Int8()), -127, 127
+		// -128 and -127 both represent -1.0.
+		e0, e1 = sint.Max(e0, -127), sint.Max(e1, -127)
+	} else {
+		e0, e1, min, max = int(r.Uint8()), int(r.Uint8()), 0, 255
+	}
+	codes := uint64(r.Uint16()) | uint64(r.Uint32())<<16
+
+	var palette [8]int
+	palette[0], palette[1] = e0, e1
+	if e0 > e1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = divRound((7-i)*e0+i*e1, 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = divRound((5-i)*e0+i*e1, 5)
+		}
+		palette[6], palette[7] = min, max
+	}
+	for i := range dst {
+		dst[i] = palette[codes&0x7]
+		codes >>= 3
+	}
+}
+
+// divRound returns n / d rounded to the nearest integer, with halves rounded
+// away from zero.
+func divRound(n, d int) int {
+	if n < 0 {
+		return -((-n + d/2) / d)
+	}
+	return (n + d/2) / d
+}
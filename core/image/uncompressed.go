@@ -26,11 +26,16 @@ var (
 	RGBA_F32     = newUncompressed(fmts.RGBA_F32)
 	RGB_U8_NORM  = newUncompressed(fmts.RGB_U8_NORM)
 	RGBA_U8_NORM = newUncompressed(fmts.RGBA_U8_NORM)
+	R_U8_NORM    = newUncompressed(fmts.R_U8_NORM)
+	RG_U8_NORM   = newUncompressed(fmts.RG_U8_NORM)
+	R_S8_NORM    = newUncompressed(fmts.R_S8_NORM)
+	RG_S8_NORM   = newUncompressed(fmts.RG_S8_NORM)
 	R_U16_NORM   = newUncompressed(fmts.R_U16_NORM)
 	RG_U16_NORM  = newUncompressed(fmts.RG_U16_NORM)
 	R_S16_NORM   = newUncompressed(fmts.R_S16_NORM)
 	RG_S16_NORM  = newUncompressed(fmts.RG_S16_NORM)
 	D_U16_NORM   = newUncompressed(fmts.D_U16_NORM)
+	D_F32        = newUncompressed(fmts.D_F32)
 )
 
 // newUncompressed returns a new uncompressed format containing with the default
@@ -55,10 +55,16 @@ if(NOT DISABLED_CXX)
 
     target_include_directories(deviceinfo PUBLIC "${PROTO_CC_OUT}")
     target_include_directories(deviceinfo PUBLIC "${CMAKE_SOURCE_DIR}/external/protobuf/src")
+    target_include_directories(deviceinfo PRIVATE "${CMAKE_SOURCE_DIR}/core/vulkan/cc/include")
 
     find_package(GL REQUIRED)
     target_link_libraries(deviceinfo protobuf cityhash GL::Lib)
 
+    if(LINUX)
+        find_package(DLOpen REQUIRED)
+        target_link_libraries(deviceinfo DLOpen::Lib)
+    endif()
+
     if(ANDROID)
         find_package(EGL REQUIRED)
         find_package(NDK REQUIRED)
@@ -23,6 +23,7 @@ set(files
     instance.cpp
     instance.h
     query.h
+    vk.cpp
 )
 set(dirs
     android
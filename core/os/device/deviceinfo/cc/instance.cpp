@@ -44,6 +44,7 @@ device_instance get_device_instance(void* platform_data) {
 
     // Instance.Configuration.Drivers.VulkanDriver
     auto vulkan_driver = new VulkanDriver();
+    query::vkDriver(vulkan_driver);
 
     // Instance.Configuration.Drivers
     auto drivers = new Drivers();
@@ -44,6 +44,7 @@ const char* instanceSerial();
 const char* instanceName();
 
 void glDriver(device::OpenGLDriver*);
+void vkDriver(device::VulkanDriver*);
 
 device::OSKind osKind();
 const char* osName();
@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+#include "query.h"
+
+#include "core/cc/target.h"
+
+#include <vulkan/vulkan.h>
+
+#include <vector>
+
+#if TARGET_OS == GAPID_OS_WINDOWS
+#   include <windows.h>
+#else
+#   include <dlfcn.h>
+#endif
+
+namespace {
+
+// The last format defined by the core Vulkan 1.0 specification.
+const VkFormat kLastCoreFormat = VK_FORMAT_ASTC_12x12_SRGB_BLOCK;
+
+void* openVulkan() {
+#if TARGET_OS == GAPID_OS_WINDOWS
+    return reinterpret_cast<void*>(LoadLibraryA("vulkan-1.dll"));
+#elif TARGET_OS == GAPID_OS_OSX
+    return nullptr;
+#else
+    if (void* lib = dlopen("libvulkan.so.1", RTLD_NOW | RTLD_LOCAL)) {
+        return lib;
+    }
+    return dlopen("libvulkan.so", RTLD_NOW | RTLD_LOCAL);
+#endif
+}
+
+void* lookup(void* lib, const char* name) {
+#if TARGET_OS == GAPID_OS_WINDOWS
+    return reinterpret_cast<void*>(GetProcAddress(reinterpret_cast<HMODULE>(lib), name));
+#else
+    return dlsym(lib, name);
+#endif
+}
+
+void closeVulkan(void* lib) {
+#if TARGET_OS == GAPID_OS_WINDOWS
+    FreeLibrary(reinterpret_cast<HMODULE>(lib));
+#else
+    dlclose(lib);
+#endif
+}
+
+}  // anonymous namespace
+
+namespace query {
+
+void vkDriver(device::VulkanDriver* driver) {
+    void* lib = openVulkan();
+    if (lib == nullptr) {
+        return;  // No Vulkan loader.
+    }
+
+    auto getInstanceProcAddr = reinterpret_cast<PFN_vkGetInstanceProcAddr>(
+            lookup(lib, "vkGetInstanceProcAddr"));
+    auto createInstance = getInstanceProcAddr == nullptr ? nullptr :
+            reinterpret_cast<PFN_vkCreateInstance>(
+                    getInstanceProcAddr(VK_NULL_HANDLE, "vkCreateInstance"));
+    if (createInstance == nullptr) {
+        closeVulkan(lib);
+        return;
+    }
+
+    VkApplicationInfo appInfo{VK_STRUCTURE_TYPE_APPLICATION_INFO};
+    appInfo.pApplicationName = "deviceinfo";
+    appInfo.apiVersion = VK_MAKE_VERSION(1, 0, 0);
+    VkInstanceCreateInfo createInfo{VK_STRUCTURE_TYPE_INSTANCE_CREATE_INFO};
+    createInfo.pApplicationInfo = &appInfo;
+
+    VkInstance instance;
+    if (createInstance(&createInfo, nullptr, &instance) != VK_SUCCESS) {
+        closeVulkan(lib);
+        return;
+    }
+
+#define GET_PROC(name) \
+    auto name = reinterpret_cast<PFN_##name>(getInstanceProcAddr(instance, #name))
+
+    GET_PROC(vkDestroyInstance);
+    GET_PROC(vkEnumeratePhysicalDevices);
+    GET_PROC(vkGetPhysicalDeviceProperties);
+    GET_PROC(vkGetPhysicalDeviceMemoryProperties);
+    GET_PROC(vkGetPhysicalDeviceQueueFamilyProperties);
+    GET_PROC(vkGetPhysicalDeviceFormatProperties);
+    GET_PROC(vkCreateDevice);
+    GET_PROC(vkDestroyDevice);
+    GET_PROC(vkCreateBuffer);
+    GET_PROC(vkDestroyBuffer);
+    GET_PROC(vkGetBufferMemoryRequirements);
+    GET_PROC(vkCreateImage);
+    GET_PROC(vkDestroyImage);
+    GET_PROC(vkGetImageMemoryRequirements);
+
+#undef GET_PROC
+
+    uint32_t count = 0;
+    vkEnumeratePhysicalDevices(instance, &count, nullptr);
+    std::vector<VkPhysicalDevice> physicalDevices(count);
+    vkEnumeratePhysicalDevices(instance, &count, physicalDevices.data());
+
+    for (uint32_t i = 0; i < count; i++) {
+        VkPhysicalDevice pd = physicalDevices[i];
+        auto out = driver->add_physicaldevices();
+
+        VkPhysicalDeviceProperties properties;
+        vkGetPhysicalDeviceProperties(pd, &properties);
+        out->set_devicename(properties.deviceName);
+        out->set_vendorid(properties.vendorID);
+        out->set_deviceid(properties.deviceID);
+
+        VkPhysicalDeviceMemoryProperties memory;
+        vkGetPhysicalDeviceMemoryProperties(pd, &memory);
+        for (uint32_t j = 0; j < memory.memoryTypeCount; j++) {
+            auto type = out->add_memorytypes();
+            type->set_propertyflags(memory.memoryTypes[j].propertyFlags);
+            type->set_heapindex(memory.memoryTypes[j].heapIndex);
+        }
+
+        uint32_t familyCount = 0;
+        vkGetPhysicalDeviceQueueFamilyProperties(pd, &familyCount, nullptr);
+        std::vector<VkQueueFamilyProperties> families(familyCount);
+        vkGetPhysicalDeviceQueueFamilyProperties(pd, &familyCount, families.data());
+        for (auto& f : families) {
+            auto family = out->add_queuefamilies();
+            family->set_queueflags(f.queueFlags);
+            family->set_queuecount(f.queueCount);
+        }
+
+        for (int f = VK_FORMAT_UNDEFINED + 1; f <= kLastCoreFormat; f++) {
+            VkFormatProperties props;
+            vkGetPhysicalDeviceFormatProperties(pd, VkFormat(f), &props);
+            if (props.linearTilingFeatures == 0 && props.optimalTilingFeatures == 0 &&
+                props.bufferFeatures == 0) {
+                continue;
+            }
+            auto format = out->add_formats();
+            format->set_format(f);
+            format->set_lineartilingfeatures(props.linearTilingFeatures);
+            format->set_optimaltilingfeatures(props.optimalTilingFeatures);
+            format->set_bufferfeatures(props.bufferFeatures);
+        }
+
+        // The memory types a resource can be bound to are only known by
+        // creating the resource, which needs a logical device.
+        float priority = 1.0f;
+        VkDeviceQueueCreateInfo queueInfo{VK_STRUCTURE_TYPE_DEVICE_QUEUE_CREATE_INFO};
+        queueInfo.queueFamilyIndex = 0;
+        queueInfo.queueCount = 1;
+        queueInfo.pQueuePriorities = &priority;
+        VkDeviceCreateInfo deviceInfo{VK_STRUCTURE_TYPE_DEVICE_CREATE_INFO};
+        deviceInfo.queueCreateInfoCount = 1;
+        deviceInfo.pQueueCreateInfos = &queueInfo;
+        VkDevice device;
+        if (familyCount == 0 || vkCreateDevice(pd, &deviceInfo, nullptr, &device) != VK_SUCCESS) {
+            continue;
+        }
+
+        VkBufferCreateInfo bufferInfo{VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO};
+        bufferInfo.size = 256;
+        bufferInfo.usage = VK_BUFFER_USAGE_TRANSFER_SRC_BIT | VK_BUFFER_USAGE_TRANSFER_DST_BIT |
+                VK_BUFFER_USAGE_UNIFORM_BUFFER_BIT | VK_BUFFER_USAGE_STORAGE_BUFFER_BIT |
+                VK_BUFFER_USAGE_INDEX_BUFFER_BIT | VK_BUFFER_USAGE_VERTEX_BUFFER_BIT |
+                VK_BUFFER_USAGE_INDIRECT_BUFFER_BIT;
+        bufferInfo.sharingMode = VK_SHARING_MODE_EXCLUSIVE;
+        VkBuffer buffer;
+        if (vkCreateBuffer(device, &bufferInfo, nullptr, &buffer) == VK_SUCCESS) {
+            VkMemoryRequirements requirements;
+            vkGetBufferMemoryRequirements(device, buffer, &requirements);
+            out->set_buffermemorytypebits(requirements.memoryTypeBits);
+            vkDestroyBuffer(device, buffer, nullptr);
+        }
+
+        VkImageCreateInfo imageInfo{VK_STRUCTURE_TYPE_IMAGE_CREATE_INFO};
+        imageInfo.imageType = VK_IMAGE_TYPE_2D;
+        imageInfo.format = VK_FORMAT_R8G8B8A8_UNORM;
+        imageInfo.extent = {16, 16, 1};
+        imageInfo.mipLevels = 1;
+        imageInfo.arrayLayers = 1;
+        imageInfo.samples = VK_SAMPLE_COUNT_1_BIT;
+        imageInfo.tiling = VK_IMAGE_TILING_OPTIMAL;
+        imageInfo.usage = VK_IMAGE_USAGE_TRANSFER_SRC_BIT | VK_IMAGE_USAGE_TRANSFER_DST_BIT |
+                VK_IMAGE_USAGE_SAMPLED_BIT | VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT;
+        imageInfo.sharingMode = VK_SHARING_MODE_EXCLUSIVE;
+        imageInfo.initialLayout = VK_IMAGE_LAYOUT_UNDEFINED;
+        VkImage image;
+        if (vkCreateImage(device, &imageInfo, nullptr, &image) == VK_SUCCESS) {
+            VkMemoryRequirements requirements;
+            vkGetImageMemoryRequirements(device, image, &requirements);
+            out->set_imagememorytypebits(requirements.memoryTypeBits);
+            vkDestroyImage(device, image, nullptr);
+        }
+
+        vkDestroyDevice(device, nullptr);
+    }
+
+    vkDestroyInstance(instance, nullptr);
+    closeVulkan(lib);
+}
+
+}  // namespace query
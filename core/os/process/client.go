@@ -77,6 +77,10 @@ type StartOptions struct {
 
 	// Should all stderr and and stdout also be logged to the logger?
 	Verbose bool
+
+	// OnExit, if not nil, is called with the error returned by the process
+	// once it has terminated.
+	OnExit func(err error)
 }
 
 // Start runs the application with the given path and options, waits for
@@ -99,7 +103,11 @@ func Start(ctx context.Context, name string, opts StartOptions) (int, error) {
 		if opts.Verbose {
 			cmd = cmd.Verbose()
 		}
-		errChan <- cmd.Run(ctx)
+		err := cmd.Run(ctx)
+		if opts.OnExit != nil {
+			opts.OnExit(err)
+		}
+		errChan <- err
 	}()
 
 	select {
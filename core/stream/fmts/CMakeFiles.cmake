@@ -34,6 +34,7 @@ set(files
     rg.go
     rgb.go
     rgba.go
+    s.go
     sd.go
     xy.go
     xyz.go
@@ -25,10 +25,57 @@ import (
 	"github.com/google/gapid/core/os/shell"
 )
 
+// Codec is a video compression format.
+type Codec int
+
+const (
+	// H264 encodes the video with H.264 in a fragmented MP4 container.
+	H264 Codec = iota
+	// VP9 encodes the video with VP9 in a WebM container.
+	VP9
+)
+
+func (c Codec) String() string {
+	switch c {
+	case H264:
+		return "H.264"
+	case VP9:
+		return "VP9"
+	default:
+		return fmt.Sprintf("Codec<%d>", int(c))
+	}
+}
+
+// Ext returns the file extension of the container the codec is encoded in.
+func (c Codec) Ext() string {
+	if c == VP9 {
+		return ".webm"
+	}
+	return ".mp4"
+}
+
+// args returns the encoder arguments selecting the codec and its container.
+func (c Codec) args() []string {
+	switch c {
+	case VP9:
+		return []string{
+			"-c:v", "libvpx-vp9",
+			"-f", "webm",
+		}
+	default:
+		return []string{
+			"-c:v", "libx264",
+			"-f", "mp4", // output should be a mp4
+			"-movflags", "frag_keyframe+empty_moov", // fragmented mp4, required for streaming.
+		}
+	}
+}
+
 // Settings for encoding a video with Encode.
 type Settings struct {
-	FPS      int // Frames per second. Default: 30
-	DataRate int // Target bits-per-second. Default: 5000000
+	FPS      int   // Frames per second. Default: 30
+	DataRate int   // Target bits-per-second. Default: 5000000
+	Codec    Codec // Compression format. Default: H264
 }
 
 var encoder string
@@ -84,19 +131,26 @@ func Encode(ctx context.Context, settings Settings) (chan<- image.Image, io.Read
 		stdin, pixels := io.Pipe()
 		defer pixels.Close() // Stops the encoder
 
+		args := []string{
+			"-v", "verbose",
+			"-r", fmt.Sprint(settings.FPS),
+			"-pix_fmt", pixfmt,
+			"-f", "rawvideo",
+			"-s", fmt.Sprintf("%dx%d", frame.Bounds().Dx(), frame.Bounds().Dy()),
+			"-i", "pipe:0", // stdin
+			"-b:v", fmt.Sprint(settings.DataRate),
+			// Every input frame is one output frame at a constant rate, so
+			// the video plays back at the requested frame rate.
+			"-r", fmt.Sprint(settings.FPS),
+			"-vsync", "cfr",
+			// 4:2:0 chroma subsampling is the only one most players support.
+			"-pix_fmt", "yuv420p",
+		}
+		args = append(args, settings.Codec.args()...)
+		args = append(args, "pipe:1") // stdout
+
 		go func() {
-			err := shell.Command(encoder,
-				"-v", "verbose",
-				"-r", fmt.Sprint(settings.FPS),
-				"-pix_fmt", pixfmt,
-				"-f", "rawvideo",
-				"-s", fmt.Sprintf("%dx%d", frame.Bounds().Dx(), frame.Bounds().Dy()),
-				"-i", "pipe:0", // stdin
-				"-b:v", fmt.Sprint(settings.DataRate),
-				"-f", "mp4", // output should be a mp4
-				"-movflags", "frag_keyframe+empty_moov", // fragmented mp4, required for streaming.
-				"pipe:1", // stdout
-			).Read(stdin).Capture(mpg, debugWriter).Run(ctx)
+			err := shell.Command(encoder, args...).Read(stdin).Capture(mpg, debugWriter).Run(ctx)
 
 			if err != nil {
 				log.E(ctx, "%v returned error: %v", encoder, err)
@@ -17,6 +17,7 @@ package loader
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"runtime"
@@ -61,6 +62,21 @@ func SetupReplay(ctx context.Context, env *shell.Env) (func(), error) {
 	return setupJSON(lib, json, env)
 }
 
+// SetupSwiftShader sets up the environment for local replay to use the
+// SwiftShader Vulkan driver bundled with GAPID instead of the system drivers.
+func SetupSwiftShader(ctx context.Context, env *shell.Env) error {
+	_, json, err := findLibraryAndJSON(ctx, layout.LibSwiftShader)
+	if err != nil {
+		return err
+	}
+	if !json.Exists() {
+		return fmt.Errorf("SwiftShader is not bundled: %v not found", json)
+	}
+	// The JSON references the library relative to its own location.
+	env.Set("VK_ICD_FILENAMES", json.System())
+	return nil
+}
+
 func findLibraryAndJSON(ctx context.Context, libType layout.LibraryType) (file.Path, file.Path, error) {
 	lib, err := layout.Library(ctx, libType)
 	if err != nil {
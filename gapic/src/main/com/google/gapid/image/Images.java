@@ -134,19 +134,25 @@ public class Images {
     switch (format.getFormatCase()) {
       case UNCOMPRESSED:
         return getChannelCount(format.getUncompressed().getFormat(), interestedChannels);
+      case BC4_R_U8_NORM:
+      case BC4_R_S8_NORM:
       case ETC2_R_U11_NORM:
       case ETC2_R_S11_NORM:
         return 1;
+      case BC5_RG_U8_NORM:
+      case BC5_RG_S8_NORM:
       case ETC2_RG_U11_NORM:
       case ETC2_RG_S11_NORM:
         return 2;
       case ATC_RGB_AMD:
       case ETC1_RGB_U8_NORM:
+      case BC6H_RGB_F16:
       case ETC2_RGB_U8_NORM:
       case S3_DXT1_RGB:
         return 3;
       case ASTC:
       case ATC_RGBA_EXPLICIT_ALPHA_AMD:
+      case BC7_RGBA_U8_NORM:
       case ATC_RGBA_INTERPOLATED_ALPHA_AMD:
       case ETC2_RGBA_U8_NORM:
       case ETC2_RGBA_U8U8U8U1_NORM:
@@ -175,8 +181,10 @@ public class Images {
     switch (format.getFormatCase()) {
       case UNCOMPRESSED:
         return are8BitsEnough(format.getUncompressed().getFormat(), interestedChannels);
+      case BC6H_RGB_F16:
+        return false;
       default:
-        // All Compressed formats can fully be represented as 8 bits (at this time).
+        // All other compressed formats can fully be represented as 8 bits.
         return true;
     }
   }
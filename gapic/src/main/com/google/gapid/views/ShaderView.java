@@ -22,6 +22,7 @@ import static com.google.gapid.util.Ranges.last;
 import static com.google.gapid.widgets.Widgets.createComposite;
 import static com.google.gapid.widgets.Widgets.createDropDownViewer;
 import static com.google.gapid.widgets.Widgets.createGroup;
+import static com.google.gapid.widgets.Widgets.createLabel;
 import static com.google.gapid.widgets.Widgets.createStandardTabFolder;
 import static com.google.gapid.widgets.Widgets.createStandardTabItem;
 import static com.google.gapid.widgets.Widgets.createTableViewer;
@@ -43,6 +44,7 @@ import com.google.gapid.proto.service.Service.CommandRange;
 import com.google.gapid.proto.service.gfxapi.GfxAPI.Program;
 import com.google.gapid.proto.service.gfxapi.GfxAPI.ResourceType;
 import com.google.gapid.proto.service.gfxapi.GfxAPI.Shader;
+import com.google.gapid.proto.service.gfxapi.GfxAPI.ShaderIssue;
 import com.google.gapid.proto.service.gfxapi.GfxAPI.Uniform;
 import com.google.gapid.proto.service.path.Path;
 import com.google.gapid.rpclib.futures.FutureController;
@@ -73,6 +75,7 @@ import org.eclipse.swt.widgets.Composite;
 import org.eclipse.swt.widgets.Control;
 import org.eclipse.swt.widgets.Event;
 import org.eclipse.swt.widgets.Group;
+import org.eclipse.swt.widgets.Label;
 import org.eclipse.swt.widgets.TabFolder;
 
 import java.util.Collections;
@@ -346,12 +349,18 @@ public class ShaderView extends Composite
 
     private SourceViewer createSourcePanel(Composite parent, Source source) {
       Group group = createGroup(parent, source.label);
+      group.setLayout(new GridLayout(1, false));
       SourceViewer viewer =
           new SourceViewer(group, null, SWT.MULTI | SWT.H_SCROLL | SWT.V_SCROLL | SWT.BORDER);
       viewer.setEditable(type.isEditable());
       viewer.getTextWidget().setFont(theme.getMonoSpaceFont());
       viewer.configure(new GlslSourceConfiguration(theme));
       viewer.setDocument(GlslSourceConfiguration.createDocument(source.source));
+      viewer.getControl().setLayoutData(new GridData(SWT.FILL, SWT.FILL, true, true));
+      if (!source.issues.isEmpty()) {
+        Label issues = createLabel(group, source.issues);
+        issues.setLayoutData(new GridData(SWT.FILL, SWT.TOP, true, false));
+      }
       return viewer;
     }
 
@@ -462,15 +471,52 @@ public class ShaderView extends Composite
 
       public final String label;
       public final String source;
+      public final String issues;
 
       public Source(String label, String source) {
+        this(label, source, "");
+      }
+
+      public Source(String label, String source, String issues) {
         this.label = label;
         this.source = source;
+        this.issues = issues;
       }
 
       public static Source of(Shader shader) {
         return new Source(shader.getType() + " Shader",
-            shader.getSource().isEmpty() ? EMPTY_SHADER : shader.getSource());
+            shader.getSource().isEmpty() ? EMPTY_SHADER : shader.getSource(), issues(shader));
+      }
+
+      /**
+       * Returns the issues found by the static analysis of the shader, one per line.
+       */
+      private static String issues(Shader shader) {
+        StringBuilder result = new StringBuilder();
+        for (ShaderIssue issue : shader.getIssuesList()) {
+          if (result.length() > 0) {
+            result.append('\n');
+          }
+          switch (issue.getKind()) {
+            case UnusedBinding:
+              result.append("Unused uniform ");
+              break;
+            case UnusedVertexInput:
+              result.append("Unused vertex input ");
+              break;
+            default:
+              result.append("Medium precision candidate ");
+              break;
+          }
+          result.append('\'').append(issue.getName()).append('\'');
+          if (issue.getHasBinding()) {
+            result.append(" (set ").append(issue.getSet())
+                .append(", binding ").append(issue.getBinding()).append(')');
+          } else if (issue.getHasLocation()) {
+            result.append(" (location ").append(issue.getLocation()).append(')');
+          }
+        }
+        return result.toString();
       }
 
       public static Source[] of(Program program) {
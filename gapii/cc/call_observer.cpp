@@ -27,6 +27,12 @@ namespace {
 // Minimum byte gap between memory observations before globbing together.
 const size_t MEMORY_MERGE_THRESHOLD = 256;
 
+// Observations larger than this are split into chunks of this size, aligned
+// to multiples of this size in the address space. Each chunk is hashed
+// separately so that only the modified chunks of a huge buffer need to be
+// re-encoded each time it is observed.
+const size_t OBSERVATION_CHUNK_SIZE = 1024*1024;
+
 // Size of the temporary heap buffer to use when the scratch stack buffer is
 // filled.
 const size_t SCRATCH_BUFFER_SIZE = 512*1024;
@@ -83,22 +89,58 @@ void CallObserver::write(const void* base, uint64_t size) {
     }
 }
 
+// Encodes a resource holding data unless a resource with the same identifier
+// has already been encoded.
+core::Id CallObserver::encodeResource(const void* data, size_t size) {
+    core::Id id = core::Id::Hash(data, size);
+    if (mSpyPtr->getResources().count(id) == 0) {
+        atom_pb::Resource resource;
+        resource.set_id(reinterpret_cast<const char*>(id.data), sizeof(id.data));
+        resource.set_data(data, size);
+        mSpyPtr->getEncoder()->message(&resource);
+        mSpyPtr->getResources().emplace(id);
+    }
+    return id;
+}
+
+// Encodes the memory range [start, end) as a chunked resource, where each
+// chunk is encoded as its own resource.
+core::Id CallObserver::encodeChunkedResource(uint64_t start, uint64_t end) {
+    atom_pb::Resource resource;
+    core::Vector<uint8_t> ids = mScratch.vector<uint8_t>(
+        ((end - start) / OBSERVATION_CHUNK_SIZE + 2) * sizeof(core::Id::data));
+    for (uint64_t base = start; base < end;) {
+        uint64_t next = (base / OBSERVATION_CHUNK_SIZE + 1) * OBSERVATION_CHUNK_SIZE;
+        if (next > end) {
+            next = end;
+        }
+        core::Id chunk = encodeResource(reinterpret_cast<uint8_t*>(base), next - base);
+        resource.add_chunks(reinterpret_cast<const char*>(chunk.data), sizeof(chunk.data));
+        for (auto b : chunk.data) {
+            ids.append(b);
+        }
+        base = next;
+    }
+    // The chunked resource is identified by the hash of its chunk identifiers.
+    core::Id id = core::Id::Hash(ids.data(), ids.count());
+    if (mSpyPtr->getResources().count(id) == 0) {
+        resource.set_id(reinterpret_cast<const char*>(id.data), sizeof(id.data));
+        mSpyPtr->getEncoder()->message(&resource);
+        mSpyPtr->getResources().emplace(id);
+    }
+    return id;
+}
+
 void CallObserver::observePending() {
     if (mSpyPtr->is_suspended()) return;
     for (auto p : mPendingObservations) {
-        core::Vector<uint8_t> data(reinterpret_cast<uint8_t*>(p.start()),
-                                    p.end() - p.start());
-        core::Id id = core::Id::Hash(data.data(), data.count());
-        if (mSpyPtr->getResources().count(id) == 0) {
-            atom_pb::Resource resource;
-            resource.set_id(reinterpret_cast<const char*>(id.data), sizeof(id.data));
-            resource.set_data(data.data(), data.count());
-            mSpyPtr->getEncoder()->message(&resource);
-            mSpyPtr->getResources().emplace(id);
-        }
+        size_t size = p.end() - p.start();
+        core::Id id = size > OBSERVATION_CHUNK_SIZE
+            ? encodeChunkedResource(p.start(), p.end())
+            : encodeResource(reinterpret_cast<uint8_t*>(p.start()), size);
         auto observation = new memory_pb::Observation();
         observation->set_base(p.start());
-        observation->set_size(data.count());
+        observation->set_size(size);
         observation->set_id(reinterpret_cast<const char*>(id.data), sizeof(id.data));
         addExtra(observation);
     }
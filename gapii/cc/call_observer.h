@@ -23,6 +23,7 @@
 
 #include "gapis/memory/memory_pb/memory.pb.h"
 
+#include "core/cc/id.h"
 #include "core/cc/interval_list.h"
 #include "core/cc/scratch_allocator.h"
 #include "core/cc/vector.h"
@@ -138,6 +139,16 @@ private:
     // The list of pending memory observations is cleared on returning.
     void observePending();
 
+    // encodeResource encodes the size bytes at data as a resource, if it has
+    // not already been encoded, returning the identifier of the resource.
+    core::Id encodeResource(const void* data, size_t size);
+
+    // encodeChunkedResource encodes the memory range [start, end) as a list of
+    // fixed-size chunk resources, followed by a resource that references the
+    // chunks in order. Only chunks that have not already been encoded are
+    // written. Returns the identifier of the chunked resource.
+    core::Id encodeChunkedResource(uint64_t start, uint64_t end);
+
     // shouldObserve returns true if the given slice is located in application
     // pool and we are supposed to observe application pool.
     template <class T>
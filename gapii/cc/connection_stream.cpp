@@ -40,11 +40,18 @@ ConnectionStream::ConnectionStream(std::unique_ptr<core::Connection> connection)
     : mConnection(std::move(connection)) {}
 
 uint64_t ConnectionStream::read(void* data, uint64_t max_size) {
-    return mConnection->recv(data, max_size);
+    std::lock_guard<std::mutex> lock(mMutex);
+    return mConnection ? mConnection->recv(data, max_size) : 0;
 }
 
 uint64_t ConnectionStream::write(const void* data, uint64_t size) {
-    return mConnection->send(data, size);
+    std::lock_guard<std::mutex> lock(mMutex);
+    return mConnection ? mConnection->send(data, size) : 0;
+}
+
+void ConnectionStream::close() {
+    std::lock_guard<std::mutex> lock(mMutex);
+    mConnection.reset();
 }
 
 } // namespace gapii
@@ -21,6 +21,7 @@
 #include "core/cc/stream_writer.h"
 
 #include <memory>
+#include <mutex>
 
 namespace core {
 
@@ -49,9 +50,14 @@ public:
     // core::StreamWriter compliance
     virtual uint64_t write(const void* data, uint64_t size) override;
 
+    // close closes the connection, signalling the end of the stream to the
+    // remote. Subsequent reads and writes return 0.
+    void close();
+
 private:
     ConnectionStream(std::unique_ptr<core::Connection>);
 
+    std::mutex mMutex;
     std::unique_ptr<core::Connection> mConnection;
 };
 
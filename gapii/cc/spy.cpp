@@ -178,7 +178,8 @@ Spy::Spy()
   , mObserveFrameFrequency(0)
   , mObserveDrawFrequency(0)
   , mDisablePrecompiledShaders(false)
-  , mRecordGLErrorState(false) {
+  , mRecordGLErrorState(false)
+  , mRecordNondeterministicValues(false) {
 
 #if TARGET_OS == GAPID_OS_ANDROID
     // Use a "localabstract" pipe on Android to prevent depending on the traced application
@@ -187,6 +188,7 @@ Spy::Spy()
 #else // TARGET_OS
     auto conn = ConnectionStream::listenSocket("127.0.0.1", "9286");
 #endif // TARGET_OS
+    mConnection = conn;
 
     GAPID_INFO("Connection made");
 
@@ -198,6 +200,8 @@ Spy::Spy()
                 (header.mFlags & ConnectionHeader::FLAG_DISABLE_PRECOMPILED_SHADERS) != 0;
         mRecordGLErrorState =
                 (header.mFlags & ConnectionHeader::FLAG_RECORD_ERROR_STATE) != 0;
+        mRecordNondeterministicValues =
+                (header.mFlags & ConnectionHeader::FLAG_RECORD_NONDETERMINISTIC_VALUES) != 0;
         // This will be over-written if we also set the header flags
         mSuspendCaptureFrames = header.mStartFrame;
         mCaptureFrames = header.mNumFrames;
@@ -211,6 +215,7 @@ Spy::Spy()
     GAPID_INFO("Observe framebuffer every %d frames", mObserveFrameFrequency);
     GAPID_INFO("Observe framebuffer every %d draws", mObserveDrawFrequency);
     GAPID_INFO("Disable precompiled shaders: %s", mDisablePrecompiledShaders ? "true" : "false");
+    GAPID_INFO("Record nondeterministic values: %s", mRecordNondeterministicValues ? "true" : "false");
 
     CallObserver observer(this);
 
@@ -229,6 +234,12 @@ Spy::Spy()
     alignments->set_pointeralignment(GetAlignment<void*>());
     observer.addExtra(alignments);
 
+    if (mRecordNondeterministicValues) {
+        atom_pb::NondeterministicValues* values = new atom_pb::NondeterministicValues();
+        values->set_recorded(true);
+        observer.addExtra(values);
+    }
+
 #if TARGET_OS == GAPID_OS_ANDROID
     auto props = getDeviceProperties();
 
@@ -412,7 +423,11 @@ void Spy::onPostEndOfFrame(CallObserver* observer) {
     if (!is_suspended() && mCaptureFrames >= 1) {
         mCaptureFrames -= 1;
         if (mCaptureFrames == 0) {
+            // All the requested frames have been captured. Close the
+            // connection so the capture ends without user interaction.
+            GAPID_INFO("Captured the requested frames. Ending the capture.");
             set_suspended(true);
+            mConnection->close();
         }
     }
     if (mSuspendCaptureFrames.load() > 0) {
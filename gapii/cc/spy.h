@@ -19,6 +19,7 @@
 #include "gapii/cc/core_spy.h"
 #include "gapii/cc/vulkan_spy.h"
 #include "gapii/cc/gles_spy.h"
+#include "gapii/cc/connection_stream.h"
 #include "core/cc/thread.h"
 
 #include <memory>
@@ -85,6 +86,7 @@ private:
     // returns a map of properties to values.
     std::unordered_map<std::string, std::string> getDeviceProperties();
 
+    std::shared_ptr<ConnectionStream> mConnection;
     std::shared_ptr<gapii::PackEncoder> mEncoder;
     std::unordered_map<std::string, void*> mSymbols;
 
@@ -100,6 +102,7 @@ private:
     int mObserveDrawFrequency;
     bool mDisablePrecompiledShaders;
     bool mRecordGLErrorState;
+    bool mRecordNondeterministicValues;
 
     std::unordered_map<ContextID, GLenum_Error> mFakeGlError;
     std::unique_ptr<core::AsyncJob> mDeferStartJob;
@@ -622,9 +622,28 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
           }
       }
     }
+    {
+        VkSamplerYcbcrConversionCreateInfoKHR create_info = {};
+        create_info.msType = VkStructureType::VK_STRUCTURE_TYPE_SAMPLER_YCBCR_CONVERSION_CREATE_INFO_KHR;
+        for (auto& conversion: SamplerYcbcrConversions) {
+            auto& conversionObject = *conversion.second;
+            create_info.mformat = conversionObject.mFormat;
+            create_info.mycbcrModel = conversionObject.mYcbcrModel;
+            create_info.mycbcrRange = conversionObject.mYcbcrRange;
+            create_info.mcomponents = conversionObject.mComponents;
+            create_info.mxChromaOffset = conversionObject.mXChromaOffset;
+            create_info.myChromaOffset = conversionObject.mYChromaOffset;
+            create_info.mchromaFilter = conversionObject.mChromaFilter;
+            create_info.mforceExplicitReconstruction = conversionObject.mForceExplicitReconstruction;
+
+            RecreateSamplerYcbcrConversion(observer, conversionObject.mDevice, &create_info, &conversionObject.mVulkanHandle);
+        }
+    }
     {
         VkSamplerCreateInfo sampler_create_info = {};
         sampler_create_info.msType = VkStructureType::VK_STRUCTURE_TYPE_SAMPLER_CREATE_INFO;
+        VkSamplerYcbcrConversionInfoKHR conversion_info = {};
+        conversion_info.msType = VkStructureType::VK_STRUCTURE_TYPE_SAMPLER_YCBCR_CONVERSION_INFO_KHR;
         for (auto& sampler: Samplers) {
             auto& samplerObject = *sampler.second;
             sampler_create_info.mmagFilter = samplerObject.mMagFilter;
@@ -642,6 +661,11 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
             sampler_create_info.mmaxLod = samplerObject.mMaxLod;
             sampler_create_info.mborderColor = samplerObject.mBorderColor;
             sampler_create_info.munnormalizedCoordinates = samplerObject.mUnnormalizedCoordinates;
+            sampler_create_info.mpNext = nullptr;
+            if (samplerObject.mYcbcrConversion) {
+                conversion_info.mconversion = samplerObject.mYcbcrConversion->mVulkanHandle;
+                sampler_create_info.mpNext = &conversion_info;
+            }
 
             RecreateSampler(observer, samplerObject.mDevice, &sampler_create_info, &samplerObject.mVulkanHandle);
         }
@@ -696,15 +720,29 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
                 //                  Figure out how we are supposed to get the data BACK into a MS image (shader?)
                 // TODO(awoloszyn): Handle depth stencil images
 
-                auto block_info = subGetElementAndTexelBlockSize(nullptr, nullptr, info.mFormat);
+                // Multi-planar images are copied plane by plane, each plane
+                // in its own format and with its own subsampled extent.
+                std::vector<uint32_t> aspects;
+                const uint32_t plane_count = subGetPlaneCount(nullptr, nullptr, info.mFormat);
+                if (plane_count == 0) {
+                    aspects.push_back(image.second->mImageAspect);
+                } else {
+                    for (uint32_t p = 0; p < plane_count; ++p) {
+                        aspects.push_back(VkImageAspectFlagBits::VK_IMAGE_ASPECT_PLANE_0_BIT_KHR << p);
+                    }
+                }
 
-                for (size_t i = 0; i < info.mMipLevels; ++i) {
-                    const size_t width = subGetMipSize(nullptr, nullptr, info.mExtent.mWidth, i);
-                    const size_t height = subGetMipSize(nullptr, nullptr, info.mExtent.mHeight, i);
-                    const size_t depth = subGetMipSize(nullptr, nullptr, info.mExtent.mDepth, i);
-                    const size_t width_in_blocks = subRoundUpTo(nullptr, nullptr, width, block_info.mTexelBlockSize.mWidth);
-                    const size_t height_in_blocks = subRoundUpTo(nullptr, nullptr, height, block_info.mTexelBlockSize.mHeight);
-                    data_size += width_in_blocks * height_in_blocks * depth * block_info.mElementSize * info.mArrayLayers;
+                for (size_t p = 0; p < aspects.size(); ++p) {
+                    auto plane_info = subGetPlaneInfo(nullptr, nullptr, info.mFormat, p);
+                    auto block_info = subGetElementAndTexelBlockSize(nullptr, nullptr, plane_info.mFormat);
+                    for (size_t i = 0; i < info.mMipLevels; ++i) {
+                        const size_t width = subRoundUpTo(nullptr, nullptr, subGetMipSize(nullptr, nullptr, info.mExtent.mWidth, i), plane_info.mWidthDivisor);
+                        const size_t height = subRoundUpTo(nullptr, nullptr, subGetMipSize(nullptr, nullptr, info.mExtent.mHeight, i), plane_info.mHeightDivisor);
+                        const size_t depth = subGetMipSize(nullptr, nullptr, info.mExtent.mDepth, i);
+                        const size_t width_in_blocks = subRoundUpTo(nullptr, nullptr, width, block_info.mTexelBlockSize.mWidth);
+                        const size_t height_in_blocks = subRoundUpTo(nullptr, nullptr, height, block_info.mTexelBlockSize.mHeight);
+                        data_size += width_in_blocks * height_in_blocks * depth * block_info.mElementSize * info.mArrayLayers;
+                    }
                 }
 
                 need_to_clean_up_temps = true;
@@ -793,31 +831,35 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
 
                 device_functions.vkBeginCommandBuffer(copy_commands, &begin_info);
 
-                std::vector<VkBufferImageCopy> image_copies(info.mMipLevels);
+                std::vector<VkBufferImageCopy> image_copies;
                 size_t buffer_offset = 0;
-                for (size_t i = 0; i < info.mMipLevels; ++i) {
-                    const size_t width = subGetMipSize(nullptr, nullptr, info.mExtent.mWidth, i);
-                    const size_t height = subGetMipSize(nullptr, nullptr, info.mExtent.mHeight, i);
-                    const size_t depth = subGetMipSize(nullptr, nullptr, info.mExtent.mDepth, i);
-                    const size_t width_in_blocks = subRoundUpTo(nullptr, nullptr, width, block_info.mTexelBlockSize.mWidth);
-                    const size_t height_in_blocks = subRoundUpTo(nullptr, nullptr, height, block_info.mTexelBlockSize.mHeight);
-                    image_copies[i] = {
-                        static_cast<uint64_t>(buffer_offset),
-                        0, // bufferRowLength << tightly packed
-                        0, // bufferImageHeight << tightly packed
-                        {
-                            image.second->mImageAspect,
-                            static_cast<uint32_t>(i),
-                            0,
-                            info.mArrayLayers,
-                        }, /// subresource
-                        { 0, 0, 0 },
-                        { static_cast<uint32_t>(width),
-                          static_cast<uint32_t>(height),
-                          static_cast<uint32_t>(depth) }
-                    };
-
-                    buffer_offset += width_in_blocks * height_in_blocks * depth * block_info.mElementSize * info.mArrayLayers;
+                for (size_t p = 0; p < aspects.size(); ++p) {
+                    auto plane_info = subGetPlaneInfo(nullptr, nullptr, info.mFormat, p);
+                    auto block_info = subGetElementAndTexelBlockSize(nullptr, nullptr, plane_info.mFormat);
+                    for (size_t i = 0; i < info.mMipLevels; ++i) {
+                        const size_t width = subRoundUpTo(nullptr, nullptr, subGetMipSize(nullptr, nullptr, info.mExtent.mWidth, i), plane_info.mWidthDivisor);
+                        const size_t height = subRoundUpTo(nullptr, nullptr, subGetMipSize(nullptr, nullptr, info.mExtent.mHeight, i), plane_info.mHeightDivisor);
+                        const size_t depth = subGetMipSize(nullptr, nullptr, info.mExtent.mDepth, i);
+                        const size_t width_in_blocks = subRoundUpTo(nullptr, nullptr, width, block_info.mTexelBlockSize.mWidth);
+                        const size_t height_in_blocks = subRoundUpTo(nullptr, nullptr, height, block_info.mTexelBlockSize.mHeight);
+                        image_copies.push_back({
+                            static_cast<uint64_t>(buffer_offset),
+                            0, // bufferRowLength << tightly packed
+                            0, // bufferImageHeight << tightly packed
+                            {
+                                aspects[p],
+                                static_cast<uint32_t>(i),
+                                0,
+                                info.mArrayLayers,
+                            }, /// subresource
+                            { 0, 0, 0 },
+                            { static_cast<uint32_t>(width),
+                              static_cast<uint32_t>(height),
+                              static_cast<uint32_t>(depth) }
+                        });
+
+                        buffer_offset += width_in_blocks * height_in_blocks * depth * block_info.mElementSize * info.mArrayLayers;
+                    }
                 }
 
 
@@ -1028,6 +1070,8 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
         for (auto& descriptorSetLayout: DescriptorSetLayouts) {
             std::vector<VkDescriptorSetLayoutBinding> bindings;
             std::vector<std::vector<VkSampler>> immutableSamplers;
+            std::vector<VkDescriptorBindingFlagsEXT> bindingFlags;
+            bool hasBindingFlags = false;
             for (auto& binding: descriptorSetLayout.second->mBindings) {
                 bindings.push_back({
                     binding.first,
@@ -1036,6 +1080,8 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
                     binding.second.mStages,
                     nullptr
                 });
+                bindingFlags.push_back(binding.second.mFlags);
+                hasBindingFlags = hasBindingFlags || binding.second.mFlags != 0;
                 immutableSamplers.push_back({});
                 if (binding.second.mImmutableSamplers.size()) {
                     for(size_t i = 0; i < binding.second.mImmutableSamplers.size(); ++i) {
@@ -1050,6 +1096,14 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
                 }
             }
 
+            VkDescriptorSetLayoutBindingFlagsCreateInfoEXT flags_info = {
+                VkStructureType::VK_STRUCTURE_TYPE_DESCRIPTOR_SET_LAYOUT_BINDING_FLAGS_CREATE_INFO_EXT,
+                nullptr,
+                static_cast<uint32_t>(bindingFlags.size()),
+                bindingFlags.data()
+            };
+            create_info.mpNext = hasBindingFlags ? &flags_info : nullptr;
+            create_info.mflags = descriptorSetLayout.second->mFlags;
             create_info.mbindingCount = bindings.size();
             create_info.mpBindings = bindings.data();
             RecreateDescriptorSetLayout(observer, descriptorSetLayout.second->mDevice,
@@ -1476,12 +1530,19 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
     {
         VkImageViewCreateInfo create_info = {};
         create_info.msType = VkStructureType::VK_STRUCTURE_TYPE_IMAGE_VIEW_CREATE_INFO;
+        VkSamplerYcbcrConversionInfoKHR conversion_info = {};
+        conversion_info.msType = VkStructureType::VK_STRUCTURE_TYPE_SAMPLER_YCBCR_CONVERSION_INFO_KHR;
         for (auto& image_view: ImageViews) {
             create_info.mimage = image_view.second->mImage->mVulkanHandle;
             create_info.mviewType = image_view.second->mType;
             create_info.mformat = image_view.second->mFormat;
             create_info.mcomponents = image_view.second->mComponents;
             create_info.msubresourceRange = image_view.second->mSubresourceRange;
+            create_info.mpNext = nullptr;
+            if (image_view.second->mYcbcrConversion) {
+                conversion_info.mconversion = image_view.second->mYcbcrConversion->mVulkanHandle;
+                create_info.mpNext = &conversion_info;
+            }
 
             RecreateImageView(observer, image_view.second->mDevice, &create_info, &image_view.second->mVulkanHandle);
         }
@@ -1529,6 +1590,28 @@ void VulkanSpy::EnumerateVulkanResources(CallObserver* observer) {
             allocate_info.mdescriptorPool = descriptorSet.second->mDescriptorPool->mVulkanHandle;
             allocate_info.mpSetLayouts = &descriptorSet.second->mLayout->mVulkanHandle;
 
+            // Bindings with a variable descriptor count need to be
+            // reallocated with the count they were originally allocated with.
+            uint32_t variable_count = 0;
+            bool has_variable_count = false;
+            for (auto& binding: descriptorSet.second->mLayout->mBindings) {
+                if (binding.second.mFlags &
+                    VkDescriptorBindingFlagBitsEXT::VK_DESCRIPTOR_BINDING_VARIABLE_DESCRIPTOR_COUNT_BIT_EXT) {
+                    auto& set_binding = descriptorSet.second->mBindings[binding.first];
+                    variable_count = set_binding.mImageBinding.size() +
+                        set_binding.mBufferBinding.size() +
+                        set_binding.mBufferViewBindings.size();
+                    has_variable_count = true;
+                }
+            }
+            VkDescriptorSetVariableDescriptorCountAllocateInfoEXT variable_count_info = {
+                VkStructureType::VK_STRUCTURE_TYPE_DESCRIPTOR_SET_VARIABLE_DESCRIPTOR_COUNT_ALLOCATE_INFO_EXT,
+                nullptr,
+                1,
+                &variable_count
+            };
+            allocate_info.mpNext = has_variable_count ? &variable_count_info : nullptr;
+
             std::deque<VkDescriptorImageInfo> image_infos;
             std::deque<VkDescriptorBufferInfo> buffer_infos;
             std::deque<VkBufferView> buffer_views;
@@ -41,8 +41,14 @@ const (
 	// errors as extras.
 	RecordErrorState Flags = 0x10000000
 	// DeferStart does not start tracing right away but waits for a signal
-	// from gapit
+	// from gapit. When the signal is received, the interceptor serializes the
+	// current Vulkan state as Recreate* atoms before tracing the following
+	// frames.
 	DeferStart Flags = 0x00000010
+	// RecordNondeterministicValues marks the capture so that the recorded
+	// query results and timestamps are fed back to the application on replay,
+	// instead of being re-queried from the replay device.
+	RecordNondeterministicValues Flags = 0x00000020
 )
 
 // Options to use when creating a capture.
@@ -51,14 +57,20 @@ type Options struct {
 	ObserveFrameFrequency uint32
 	// If non-zero, then a framebuffer-observation will be made after every n draw calls.
 	ObserveDrawFrequency uint32
-	// If non-zero, then the capture will only start at frame n.
+	// If non-zero, then the capture will only start at frame n, beginning with
+	// the Recreate* atoms for the Vulkan state at that point. Not compatible
+	// with DeferStart.
 	StartFrame uint32
-	// If non-zero, then only n frames will be captured.
+	// If non-zero, then only n frames will be captured, after which the
+	// interceptor ends the capture by closing the connection.
 	FramesToCapture uint32
 	// Combination of FlagXX bits.
 	Flags Flags
 	// APK is an apk to install before tracing
 	APK file.Path
+	// If non-zero, then the capture is streamed to disk in chunks of at most
+	// n bytes, with an index, instead of a single file.
+	ChunkSize uint64
 }
 
 const sizeGap = 1024 * 1024 * 5
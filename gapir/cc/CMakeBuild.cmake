@@ -58,7 +58,7 @@ if(NOT DISABLED_CXX)
         target_link_libraries(gapir_static EGL::Lib)
     else()
         find_package(GL REQUIRED)
-        target_link_libraries(gapir_static GL::Lib)
+        target_link_libraries(gapir_static GL::Lib deviceinfo)
 
         add_executable(gapir-tests ${test_sources})
         use_gtest(gapir-tests)
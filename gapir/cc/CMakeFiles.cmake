@@ -31,6 +31,9 @@ set(files
     interpreter.cpp
     interpreter.h
     interpreter_test.cpp
+    lz4.cpp
+    lz4.h
+    lz4_test.cpp
     memory_manager.cpp
     memory_manager.h
     memory_manager_test.cpp
@@ -47,6 +50,7 @@ set(files
     resource_cache.h
     resource_disk_cache.cpp
     resource_disk_cache.h
+    resource_disk_cache_test.cpp
     resource_in_memory_cache.cpp
     resource_in_memory_cache.h
     resource_in_memory_cache_test.cpp
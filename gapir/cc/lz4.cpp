@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+
+#include "lz4.h"
+
+#include <string.h>
+
+namespace gapir {
+namespace {
+
+const size_t kMinMatch = 4;
+
+// readLength adds the extra bytes of a length field with the initial value of 15 to length.
+bool readLength(const uint8_t** src, const uint8_t* srcEnd, size_t* length) {
+    if (*length != 15) {
+        return true;
+    }
+    uint8_t b;
+    do {
+        if (*src == srcEnd) {
+            return false;
+        }
+        b = *(*src)++;
+        *length += b;
+    } while (b == 255);
+    return true;
+}
+
+}  // anonymous namespace
+
+bool decompressLZ4(const uint8_t* src, size_t srcSize, uint8_t* dst, size_t dstSize) {
+    const uint8_t* srcEnd = src + srcSize;
+    uint8_t* out = dst;
+    uint8_t* outEnd = dst + dstSize;
+    while (src < srcEnd) {
+        uint8_t token = *src++;
+
+        size_t literals = token >> 4;
+        if (!readLength(&src, srcEnd, &literals) ||
+            literals > size_t(srcEnd - src) || literals > size_t(outEnd - out)) {
+            return false;
+        }
+        memcpy(out, src, literals);
+        out += literals;
+        src += literals;
+        if (src == srcEnd) {
+            break;  // The last sequence has no match.
+        }
+
+        if (srcEnd - src < 2) {
+            return false;
+        }
+        size_t offset = size_t(src[0]) | (size_t(src[1]) << 8);
+        src += 2;
+        size_t match = token & 15;
+        if (!readLength(&src, srcEnd, &match)) {
+            return false;
+        }
+        match += kMinMatch;
+        if (offset == 0 || offset > size_t(out - dst) || match > size_t(outEnd - out)) {
+            return false;
+        }
+        // The match may overlap the bytes it produces, so copy byte by byte.
+        const uint8_t* ref = out - offset;
+        for (size_t i = 0; i < match; i++) {
+            out[i] = ref[i];
+        }
+        out += match;
+    }
+    return out == outEnd;
+}
+
+}  // namespace gapir
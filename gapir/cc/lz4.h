@@ -0,0 +1,32 @@
+/*
+ * Copyright (C) 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+
+#ifndef GAPIR_LZ4_H
+#define GAPIR_LZ4_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+namespace gapir {
+
+// Decompresses the LZ4 block of srcSize bytes at src to the dstSize bytes at dst. Returns false if
+// the block is malformed or does not decompress to exactly dstSize bytes.
+bool decompressLZ4(const uint8_t* src, size_t srcSize, uint8_t* dst, size_t dstSize);
+
+}  // namespace gapir
+
+#endif  // GAPIR_LZ4_H
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+
+#include "lz4.h"
+
+#include <gtest/gtest.h>
+
+#include <string>
+#include <vector>
+
+namespace gapir {
+namespace test {
+namespace {
+
+std::string decompress(const std::vector<uint8_t>& block, size_t size, bool* ok) {
+    std::vector<uint8_t> out(size + 1);
+    *ok = decompressLZ4(block.data(), block.size(), out.data(), size);
+    return std::string(out.begin(), out.begin() + size);
+}
+
+}  // anonymous namespace
+
+TEST(LZ4Test, Literals) {
+    bool ok;
+    EXPECT_EQ("hello", decompress({0x50, 'h', 'e', 'l', 'l', 'o'}, 5, &ok));
+    EXPECT_TRUE(ok);
+}
+
+TEST(LZ4Test, LongLiterals) {
+    std::vector<uint8_t> block = {0xf0, 255, 30};
+    block.insert(block.end(), 300, 'x');
+    bool ok;
+    EXPECT_EQ(std::string(300, 'x'), decompress(block, 300, &ok));
+    EXPECT_TRUE(ok);
+}
+
+TEST(LZ4Test, OverlappingMatch) {
+    // 'a', then a match of 8 bytes at offset 1, then the last literals.
+    std::vector<uint8_t> block = {0x14, 'a', 0x01, 0x00, 0x50, 'b', 'c', 'd', 'e', 'f'};
+    bool ok;
+    EXPECT_EQ("aaaaaaaaabcdef", decompress(block, 14, &ok));
+    EXPECT_TRUE(ok);
+}
+
+TEST(LZ4Test, LongMatch) {
+    // 'ab', then a match of 4 + 15 + 21 bytes at offset 2, then the last literal.
+    std::vector<uint8_t> block = {0x2f, 'a', 'b', 0x02, 0x00, 21, 0x10, 'c'};
+    std::string expected;
+    for (int i = 0; i < 21; i++) {
+        expected += "ab";
+    }
+    expected += "c";
+    bool ok;
+    EXPECT_EQ(expected, decompress(block, expected.size(), &ok));
+    EXPECT_TRUE(ok);
+}
+
+TEST(LZ4Test, Invalid) {
+    bool ok;
+    // Size mismatch.
+    decompress({0x50, 'h', 'e', 'l', 'l', 'o'}, 4, &ok);
+    EXPECT_FALSE(ok);
+    decompress({0x50, 'h', 'e', 'l', 'l', 'o'}, 6, &ok);
+    EXPECT_FALSE(ok);
+    // Truncated literals.
+    decompress({0x50, 'h', 'e'}, 5, &ok);
+    EXPECT_FALSE(ok);
+    // Truncated offset.
+    decompress({0x14, 'a', 0x01}, 9, &ok);
+    EXPECT_FALSE(ok);
+    // Offset of zero.
+    decompress({0x14, 'a', 0x00, 0x00}, 9, &ok);
+    EXPECT_FALSE(ok);
+    // Offset before the start of the output.
+    decompress({0x14, 'a', 0x02, 0x00}, 9, &ok);
+    EXPECT_FALSE(ok);
+    // Truncated length.
+    decompress({0xf0, 255}, 270, &ok);
+    EXPECT_FALSE(ok);
+}
+
+}  // namespace test
+}  // namespace gapir
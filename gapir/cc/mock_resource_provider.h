@@ -34,6 +34,8 @@ public:
 
     MOCK_METHOD5(prefetch, void(const Resource* resources, size_t count,
                                 const ServerConnection& server, void* temp, size_t tempSize));
+
+    MOCK_METHOD1(has, bool(const ResourceId& id));
 };
 
 // PatternedResourceProvider is a ResourceProvider that writes a pattern to the pointer handed to
@@ -57,6 +59,10 @@ public:
         mInner->prefetch(resources, count, server, temp, tempSize);
     }
 
+    inline bool has(const ResourceId& id) override {
+        return mInner->has(id);
+    }
+
     // patternFor returns the memory pattern that will be written to the target pointer when
     // calling get.
     static std::vector<uint8_t> patternFor(const std::vector<Resource>& resources) {
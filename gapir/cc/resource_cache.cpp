@@ -41,7 +41,7 @@ bool ResourceCache::get(const Resource*         resources,
             if (!batch.flush(*this, server)) {
                 return false; // Failed to get resources from fallback provider.
             }
-            batch = Batch(dst, size);
+            batch = Batch(dst + resource.size, size - resource.size);
         } else {
             // Not in cache.
             // Add this to the batch we need to request from the fallback provider.
@@ -53,6 +53,10 @@ bool ResourceCache::get(const Resource*         resources,
     return batch.flush(*this, server);
 }
 
+bool ResourceCache::has(const ResourceId& id) {
+    return hasCache(id) || mFallbackProvider->has(id);
+}
+
 ResourceCache::Batch::Batch(void* target, size_t size)
     : mTarget(reinterpret_cast<uint8_t*>(target))
     , mSize(0)
@@ -34,9 +34,13 @@ public:
     bool get(const Resource* resources, size_t count, const ServerConnection& server,
              void* target, size_t size) override;
 
+    // Returns true if the resource is held by this cache or by the fallback provider.
+    bool has(const ResourceId& id) override;
+
 protected:
     virtual void putCache(const Resource& resource, const void* data) = 0;
     virtual bool getCache(const Resource& resource, void* data) = 0;
+    virtual bool hasCache(const ResourceId& id) = 0;
 
     // Fall back resource provider for the cases when the requested resource is not in the cache.
     std::unique_ptr<ResourceProvider> mFallbackProvider;
@@ -90,9 +90,13 @@ void ResourceDiskCache::prefetch(const Resource*         resources,
                                  size_t                  tempSize) {
     Batch batch(temp, tempSize);
     for (size_t i = 0; i < count; i++) {
+        if (mArchive.contains(resources[i].id)) {
+            continue;  // Already on disk, possibly from a previous replay.
+        }
         if (!batch.append(resources[i])) {
             batch.flush(*this, server);
             batch = Batch(temp, tempSize);
+            batch.append(resources[i]);
         }
     }
     batch.flush(*this, server);
@@ -106,4 +110,8 @@ bool ResourceDiskCache::getCache(const Resource& resource, void* data) {
     return mArchive.read(resource.id, data, resource.size);
 }
 
+bool ResourceDiskCache::hasCache(const ResourceId& id) {
+    return mArchive.contains(id);
+}
+
 }  // namespace gapir
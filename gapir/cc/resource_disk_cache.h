@@ -41,6 +41,7 @@ public:
 protected:
     void putCache(const Resource& resource, const void* data) override;
     bool getCache(const Resource& resource, void* data) override;
+    bool hasCache(const ResourceId& id) override;
 
 private:
     ResourceDiskCache(std::unique_ptr<ResourceProvider> fallbackProvider, const std::string& path);
@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+#include "mock_resource_provider.h"
+#include "resource_disk_cache.h"
+#include "resource_provider.h"
+#include "server_connection.h"
+#include "test_utilities.h"
+
+#include "core/cc/target.h"
+
+#include <gmock/gmock.h>
+#include <gtest/gtest.h>
+
+#include <stdio.h>
+#include <stdlib.h>
+
+#include <memory>
+#include <string>
+#include <vector>
+
+#if TARGET_OS == GAPID_OS_WINDOWS
+#include <direct.h>
+#define rmdir(path) _rmdir(path)
+#else
+#include <unistd.h>
+#endif
+
+using namespace ::testing;
+
+namespace gapir {
+namespace test {
+namespace {
+
+const Resource A("A", 64);
+const Resource B("B", 256);
+const Resource C("C", 512);
+const Resource D("D", 1024);
+
+// tempPath returns the path of a new directory for the cache, which the cache creates.
+std::string tempPath() {
+#if TARGET_OS == GAPID_OS_WINDOWS
+    char* name = _tempnam(nullptr, "gapir");
+    std::string path(name);
+    free(name);
+    return path;
+#else
+    char path[] = "/tmp/gapir-disk-cache-XXXXXX";
+    return mkdtemp(path) != nullptr ? path : "";
+#endif
+}
+
+class ResourceDiskCacheTest : public Test {
+protected:
+    virtual void SetUp() {
+        mPath = tempPath();
+        ASSERT_NE(mPath, "");
+        createCache();
+        mServer = createServerConnection("", 0);
+    }
+
+    virtual void TearDown() {
+        mResourceDiskCache.reset();
+        std::string archive = mPath + PATH_DELIMITER + "resources";
+        remove((archive + ".data").c_str());
+        remove((archive + ".index").c_str());
+        rmdir(mPath.c_str());
+    }
+
+    // createCache (re)creates the cache at mPath, with a new fallback provider.
+    void createCache() {
+        mResourceDiskCache.reset();
+
+        // ResourceDiskCache -> PatternedResourceProvider -> MockResourceProvider
+        mFallbackProvider = new StrictMock<MockResourceProvider>();
+
+        auto patternedResourceProvider = new PatternedResourceProvider(
+                std::unique_ptr<StrictMock<MockResourceProvider>>(mFallbackProvider));
+
+        mResourceDiskCache = ResourceDiskCache::create(
+                std::unique_ptr<PatternedResourceProvider>(patternedResourceProvider), mPath);
+    }
+
+    inline void expectCacheHit(std::vector<Resource> resources) {
+        SCOPED_TRACE("expectCacheHit");
+
+        auto pattern = PatternedResourceProvider::patternFor(resources);
+        std::vector<uint8_t> got(pattern.size());
+        EXPECT_TRUE(mResourceDiskCache->get(
+            resources.data(), resources.size(), *mServer, got.data(), got.size()));
+        EXPECT_EQ(got, pattern);
+    }
+
+    inline void expectFetch(std::vector<Resource> resources) {
+        size_t size = 0;
+        for (auto resource : resources) {
+            size += resource.size;
+        }
+        EXPECT_CALL(*mFallbackProvider, get(_, _, _, mTemp, size))
+            .With(Args<0, 1>(ElementsAreArray(resources)))
+            .WillOnce(Return(true))
+            .RetiresOnSaturation();
+    }
+
+    static const size_t TEMP_SIZE = 1024;
+
+    StrictMock<MockResourceProvider>* mFallbackProvider;
+
+    std::string mPath;
+    std::unique_ptr<ResourceProvider> mResourceDiskCache;
+    std::unique_ptr<ServerConnection> mServer;
+    uint8_t mTemp[TEMP_SIZE];
+};
+
+}  // anonymous namespace
+
+// Test that prefetch() fetches the resources in batches fitting the temporary buffer.
+TEST_F(ResourceDiskCacheTest, PrefetchAcrossBatches) {
+    {
+        InSequence x;
+        expectFetch({A, B, C});
+        expectFetch({D});
+    }
+
+    Resource resources[] = {A, B, C, D};
+    mResourceDiskCache->prefetch(resources, 4, *mServer, mTemp, TEMP_SIZE);
+
+    // All the resources of both batches should be on disk.
+    expectCacheHit({A, B, C, D});
+    expectCacheHit({D, B});
+}
+
+// Test that prefetch() only fetches the resources not already on disk.
+TEST_F(ResourceDiskCacheTest, PrefetchSkipsCached) {
+    expectFetch({B});
+    mResourceDiskCache->prefetch(&B, 1, *mServer, mTemp, TEMP_SIZE);
+
+    expectFetch({A, C});
+    Resource resources[] = {A, B, C};
+    mResourceDiskCache->prefetch(resources, 3, *mServer, mTemp, TEMP_SIZE);
+
+    expectCacheHit({A, B, C});
+}
+
+// Test that the resources are kept on disk for the caches later created at the same path.
+TEST_F(ResourceDiskCacheTest, PrefetchAfterReopen) {
+    Resource resources[] = {A, B};
+    expectFetch({A, B});
+    mResourceDiskCache->prefetch(resources, 2, *mServer, mTemp, TEMP_SIZE);
+
+    createCache();
+    mResourceDiskCache->prefetch(resources, 2, *mServer, mTemp, TEMP_SIZE);
+    expectCacheHit({A, B});
+}
+
+// Test that has() reports the resources on disk, consulting the fallback provider for the others.
+TEST_F(ResourceDiskCacheTest, Has) {
+    InSequence x;
+
+    expectFetch({A});
+    mResourceDiskCache->prefetch(&A, 1, *mServer, mTemp, TEMP_SIZE);
+    EXPECT_TRUE(mResourceDiskCache->has(A.id));
+
+    EXPECT_CALL(*mFallbackProvider, has(B.id)).WillOnce(Return(false));
+    EXPECT_FALSE(mResourceDiskCache->has(B.id));
+
+    EXPECT_CALL(*mFallbackProvider, has(C.id)).WillOnce(Return(true));
+    EXPECT_TRUE(mResourceDiskCache->has(C.id));
+}
+
+}  // namespace test
+}  // namespace gapir
@@ -205,4 +205,8 @@ bool ResourceInMemoryCache::getCache(const Resource& resource, void* data) {
     return true;
 }
 
+bool ResourceInMemoryCache::hasCache(const ResourceId& id) {
+    return mCache.find(id) != mCache.end();
+}
+
 }  // namespace gapir
@@ -77,6 +77,7 @@ protected:
 
     void putCache(const Resource& resource, const void* data) override;
     bool getCache(const Resource& resource, void* data) override;
+    bool hasCache(const ResourceId& id) override;
 
     // free evicts the cache entry for block, transforming it into a free block.
     void free(Block* block);
@@ -141,6 +141,32 @@ TEST_F(ResourceInMemoryCacheTest, CacheHit) {
     expectCacheHit({A, B});
 }
 
+// Test that resources missing from the cache are fetched after the cached ones preceding them.
+TEST_F(ResourceInMemoryCacheTest, CacheHitThenMiss) {
+    InSequence x;
+
+    expectCacheMiss({A});
+
+    auto pattern = PatternedResourceProvider::patternFor({A, B});
+    std::vector<uint8_t> got(pattern.size());
+    EXPECT_CALL(*mFallbackProvider, get(Pointee(Eq(B)), 1, _, &got[A.size], B.size))
+        .WillOnce(Return(true));
+    Resource resources[] = {A, B};
+    EXPECT_TRUE(mResourceInMemoryCache->get(resources, 2, *mServer, got.data(), got.size()));
+    EXPECT_EQ(got, pattern);
+}
+
+// Test that has() reports cached resources, consulting the fallback provider for the others.
+TEST_F(ResourceInMemoryCacheTest, Has) {
+    InSequence x;
+
+    expectCacheMiss({A});
+    EXPECT_TRUE(mResourceInMemoryCache->has(A.id));
+
+    EXPECT_CALL(*mFallbackProvider, has(B.id)).WillOnce(Return(false));
+    EXPECT_FALSE(mResourceInMemoryCache->has(B.id));
+}
+
 TEST_F(ResourceInMemoryCacheTest, Prefetch) {
     InSequence x;
 
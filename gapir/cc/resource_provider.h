@@ -38,6 +38,10 @@ public:
     // temp is a temporary buffer of size tempSize that can be used by prefetch.
     virtual void prefetch(const Resource* resources, size_t count, const ServerConnection& server,
     											void* temp, size_t tempSize) = 0;
+
+    // Returns true if the resource with the given identifier can be loaded without requesting it
+    // from the server.
+    virtual bool has(const ResourceId& id) = 0;
 };
 
 }  // namespace gapir
@@ -51,4 +51,8 @@ void ResourceRequester::prefetch(const Resource*         resources,
                                  void*                   temp,
                                  size_t                  tempSize) {}
 
+bool ResourceRequester::has(const ResourceId& id) {
+    return false;
+}
+
 }  // namespace gapir
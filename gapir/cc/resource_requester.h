@@ -38,6 +38,9 @@ public:
     void prefetch(const Resource* resources, size_t count, const ServerConnection& server,
                   void* temp, size_t tempSize) override;
 
+    // Always returns false as every resource has to be requested from the server.
+    bool has(const ResourceId& id) override;
+
 private:
     ResourceRequester() = default;
 };
@@ -14,6 +14,7 @@
  * limitations under the License.
  */
 
+#include "lz4.h"
 #include "server_connection.h"
 
 #include "core/cc/connection.h"
@@ -26,7 +27,7 @@
 namespace gapir {
 
 std::unique_ptr<ServerConnection> ServerConnection::create(
-        std::unique_ptr<core::Connection> conn) {
+        std::unique_ptr<core::Connection> conn, Compression compression) {
     std::string replayId;
     if (!conn->readString(&replayId)) {
         GAPID_WARNING("Failed to read replay id. Error: %s", conn->error());
@@ -40,14 +41,15 @@ std::unique_ptr<ServerConnection> ServerConnection::create(
     }
 
     return std::unique_ptr<ServerConnection>(
-            new ServerConnection(std::move(conn), replayId, replayLen));
+            new ServerConnection(std::move(conn), replayId, replayLen, compression));
 }
 
 ServerConnection::ServerConnection(std::unique_ptr<core::Connection> conn,
-        const std::string& replayId, uint32_t replayLen) :
+        const std::string& replayId, uint32_t replayLen, Compression compression) :
         mConn(std::move(conn)),
         mReplayLen(replayLen),
-        mReplayId(replayId) {
+        mReplayId(replayId),
+        mCompression(compression) {
 }
 
 ServerConnection::~ServerConnection() {
@@ -93,6 +95,10 @@ bool ServerConnection::getResources(const ResourceId* resourceIds, size_t count,
         }
     }
 
+    if (mCompression == COMPRESSION_LZ4) {
+        return recvCompressed(target, size);
+    }
+
     size_t received = mConn->recv(target, size);
     if (received != size) {
         GAPID_WARNING("GET %lu resources returned unexpected size. "
@@ -104,6 +110,44 @@ bool ServerConnection::getResources(const ResourceId* resourceIds, size_t count,
     return true;
 }
 
+bool ServerConnection::recvCompressed(void* target, size_t size) const {
+    uint8_t* out = reinterpret_cast<uint8_t*>(target);
+    std::vector<uint8_t> packed;
+    while (size > 0) {
+        uint32_t chunkSize, packedSize;
+        if (mConn->recv(&chunkSize, sizeof(chunkSize)) != sizeof(chunkSize) ||
+            mConn->recv(&packedSize, sizeof(packedSize)) != sizeof(packedSize)) {
+            GAPID_WARNING("Failed to read GET chunk header. Error: %s", mConn->error());
+            return false;
+        }
+        if (chunkSize == 0 || chunkSize > size || packedSize > chunkSize) {
+            GAPID_WARNING("Invalid GET chunk. Size: 0x%x, packed size: 0x%x, expected at most: 0x%x",
+                chunkSize, packedSize, int(size));
+            return false;
+        }
+        if (packedSize == chunkSize) {
+            // Stored as is.
+            if (mConn->recv(out, chunkSize) != chunkSize) {
+                GAPID_WARNING("Failed to read GET chunk. Error: %s", mConn->error());
+                return false;
+            }
+        } else {
+            packed.resize(packedSize);
+            if (mConn->recv(packed.data(), packedSize) != packedSize) {
+                GAPID_WARNING("Failed to read GET chunk. Error: %s", mConn->error());
+                return false;
+            }
+            if (!decompressLZ4(packed.data(), packedSize, out, chunkSize)) {
+                GAPID_WARNING("Failed to decompress GET chunk");
+                return false;
+            }
+        }
+        out += chunkSize;
+        size -= chunkSize;
+    }
+    return true;
+}
+
 bool ServerConnection::post(const void* postData, uint32_t postSize) const {
     GAPID_DEBUG("POST: %p (%d)", postData, postSize);
 
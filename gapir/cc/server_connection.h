@@ -35,8 +35,17 @@ namespace gapir {
 // Class for managing the communication between the replay daemon and the server (gazer)
 class ServerConnection {
 public:
-    // Creates a gazer connection using the given connection
-    static std::unique_ptr<ServerConnection> create(std::unique_ptr<core::Connection> conn);
+    // Compression of the resource data sent by the server. It have to be consistent with the
+    // values used by the server.
+    enum Compression : uint8_t {
+        COMPRESSION_NONE = 0,
+        COMPRESSION_LZ4  = 1,
+    };
+
+    // Creates a gazer connection using the given connection. The resource data is expected to be
+    // sent with the given compression.
+    static std::unique_ptr<ServerConnection> create(std::unique_ptr<core::Connection> conn,
+                                                    Compression compression = COMPRESSION_NONE);
 
     ~ServerConnection();
 
@@ -66,7 +75,12 @@ public:
 private:
     // Initialize the member variables of the ServerConnection object
     ServerConnection(std::unique_ptr<core::Connection> conn, const std::string& replayId,
-                    uint32_t replayLen);
+                    uint32_t replayLen, Compression compression);
+
+    // Receives size bytes of resource data sent as compressed chunks to target. Each chunk is
+    // sent as its size, the size of its data, and its data which is stored as is if both sizes
+    // are equal. Returns true if all the data was received and decompressed, false otherwise.
+    bool recvCompressed(void* target, size_t size) const;
 
     // The connection used for sending and receiving data to and from the server.
     std::unique_ptr<core::Connection> mConn;
@@ -76,6 +90,9 @@ private:
 
     // The resource id of the replay this request belongs to.
     std::string mReplayId;
+
+    // The compression of the resource data sent by the server.
+    Compression mCompression;
 };
 
 }  // namespace gapir
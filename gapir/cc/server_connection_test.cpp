@@ -114,6 +114,50 @@ TEST_F(ServerConnectionTest, GetErrorContent) {
     EXPECT_FALSE(mServerConnection->getResources(AB, 2, mBuffer.data(), mBuffer.size()));
 }
 
+TEST(ServerConnectionTestStatic, GetCompressed) {
+    auto connection = new core::test::MockConnection();
+    pushString(&connection->in, replayId);
+    pushUint32(&connection->in, 0);
+    // A chunk stored as is.
+    pushUint32(&connection->in, 3);
+    pushUint32(&connection->in, 3);
+    pushBytes(&connection->in, {1, 2, 3});
+    // An LZ4 compressed chunk of 'a' repeated 9 times and "bcdef".
+    std::vector<uint8_t> packed{0x14, 'a', 0x01, 0x00, 0x50, 'b', 'c', 'd', 'e', 'f'};
+    pushUint32(&connection->in, 14);
+    pushUint32(&connection->in, packed.size());
+    pushBytes(&connection->in, packed);
+
+    auto svrConnection = ServerConnection::create(
+            std::unique_ptr<core::Connection>(connection), ServerConnection::COMPRESSION_LZ4);
+    ASSERT_THAT(svrConnection, NotNull());
+
+    std::vector<uint8_t> buffer(17);
+    EXPECT_TRUE(svrConnection->getResources(AB, 2, buffer.data(), buffer.size()));
+    std::vector<uint8_t> expected{1, 2, 3};
+    for (char c : std::string("aaaaaaaaabcdef")) {
+        expected.push_back(c);
+    }
+    EXPECT_EQ(expected, buffer);
+}
+
+TEST(ServerConnectionTestStatic, GetCompressedErrorChunkSize) {
+    auto connection = new core::test::MockConnection();
+    pushString(&connection->in, replayId);
+    pushUint32(&connection->in, 0);
+    // A chunk larger than the requested resources.
+    pushUint32(&connection->in, 4);
+    pushUint32(&connection->in, 4);
+    pushBytes(&connection->in, {1, 2, 3, 4});
+
+    auto svrConnection = ServerConnection::create(
+            std::unique_ptr<core::Connection>(connection), ServerConnection::COMPRESSION_LZ4);
+    ASSERT_THAT(svrConnection, NotNull());
+
+    std::vector<uint8_t> buffer(3);
+    EXPECT_FALSE(svrConnection->getResources(AB, 2, buffer.data(), buffer.size()));
+}
+
 TEST_F(ServerConnectionTest, Post) {
     std::vector<uint8_t> postData{1, 2, 3};
 
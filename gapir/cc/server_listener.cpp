@@ -17,34 +17,103 @@
 #include "gapir/cc/gles_gfx_api.h"
 #include "gles_renderer.h"
 
+#include "resource_provider.h"
 #include "server_connection.h"
 #include "server_listener.h"
 
 #include "core/cc/connection.h"
 #include "core/cc/log.h"
 #include "core/cc/supported_abis.h"
+#include "core/cc/target.h"
+
+#if TARGET_OS != GAPID_OS_ANDROID
+#include "core/os/device/deviceinfo/cc/instance.h"
+#endif  // TARGET_OS != GAPID_OS_ANDROID
 
 #include <string.h>
 
 #include <memory>
 #include <sstream>
 #include <string>
+#include <vector>
 
 namespace {
 
 const uint32_t kProtocolVersion = 1;
 const char kAuthTokenHeader[] = { 'A', 'U', 'T', 'H' };
 
+// sendDeviceInfo writes the size of the serialized device::Instance proto of
+// this device followed by its data, and a byte: 1 if the resources of the
+// replays are kept for the later replays, otherwise 0. Only a size of zero is
+// written if the device information cannot be queried.
+void sendDeviceInfo(core::Connection* client, bool persistentResources) {
+#if TARGET_OS == GAPID_OS_ANDROID
+    // Querying the device information on Android requires the JVM.
+    client->send(uint32_t(0));
+#else  // TARGET_OS == GAPID_OS_ANDROID
+    device_instance instance = get_device_instance(nullptr);
+    if (instance.data == nullptr) {
+        GAPID_WARNING("Failed to get device information: %s", get_device_instance_error());
+        client->send(uint32_t(0));
+        return;
+    }
+    uint32_t size = static_cast<uint32_t>(instance.size);
+    if (client->send(size) && client->send(instance.data, instance.size) == instance.size) {
+        client->send(uint8_t(persistentResources ? 1 : 0));
+    }
+    free_device_instance(instance);
+#endif  // TARGET_OS == GAPID_OS_ANDROID
+}
+
+// sendCachedResources reads a count followed by that many resource identifiers
+// and replies with a byte per identifier: 1 if the resource can be loaded
+// without requesting it from the server, otherwise 0. Returns false if the
+// query could not be read or the reply could not be sent.
+bool sendCachedResources(core::Connection* client, gapir::ResourceProvider* resources) {
+    uint32_t count;
+    if (client->recv(&count, sizeof(count)) != sizeof(count)) {
+        return false;
+    }
+    std::vector<uint8_t> cached(count);
+    for (uint32_t i = 0; i < count; i++) {
+        std::string id;
+        if (!client->readString(&id)) {
+            return false;
+        }
+        cached[i] = (resources != nullptr && resources->has(id)) ? 1 : 0;
+    }
+    return count == 0 || client->send(cached.data(), count) == count;
+}
+
+// acceptCompression reads the compression requested for the resource data and
+// replies with a byte: 1 if it is supported, otherwise 0 in which case the
+// data is sent uncompressed. Returns false if the request could not be read or
+// the reply could not be sent.
+bool acceptCompression(core::Connection* client, gapir::ServerConnection::Compression* compression) {
+    uint8_t requested;
+    if (client->recv(&requested, sizeof(requested)) != sizeof(requested)) {
+        return false;
+    }
+    uint8_t accepted = requested == gapir::ServerConnection::COMPRESSION_LZ4 ? 1 : 0;
+    if (accepted) {
+        *compression = gapir::ServerConnection::COMPRESSION_LZ4;
+    }
+    return client->send(accepted);
+}
+
 }  // anonymous namespace
 
 namespace gapir {
 
-ServerListener::ServerListener(std::unique_ptr<core::Connection> conn, uint64_t maxMemorySize) :
+ServerListener::ServerListener(std::unique_ptr<core::Connection> conn, uint64_t maxMemorySize,
+                               bool persistentResources) :
         mConn(std::move(conn)),
-        mMaxMemorySize(maxMemorySize) {
+        mMaxMemorySize(maxMemorySize),
+        mPersistentResources(persistentResources) {
 }
 
-std::unique_ptr<ServerConnection> ServerListener::acceptConnection(int idleTimeoutMs, const char* authToken) {
+std::unique_ptr<ServerConnection> ServerListener::acceptConnection(int idleTimeoutMs, const char* authToken,
+                                                                   ResourceProvider* resources) {
     while (true) {
         GAPID_DEBUG("Waiting for new connection...");
         std::unique_ptr<core::Connection> client = mConn->accept(idleTimeoutMs);
@@ -76,10 +145,29 @@ std::unique_ptr<ServerConnection> ServerListener::acceptConnection(int idleTimeo
             continue;
         }
 
+        // Queries and options are followed by another request on the same connection.
+        ServerConnection::Compression compression = ServerConnection::COMPRESSION_NONE;
+        bool ok = true;
+        while (ok && (connectionType == CACHED_RESOURCES || connectionType == COMPRESSION)) {
+            if (connectionType == CACHED_RESOURCES) {
+                GAPID_DEBUG("Cached resources queried");
+                ok = sendCachedResources(client.get(), resources);
+            } else {
+                GAPID_DEBUG("Compression requested");
+                ok = acceptCompression(client.get(), &compression);
+            }
+            ok = ok && client->recv(&connectionType, sizeof(connectionType)) == sizeof(connectionType);
+        }
+        if (!ok) {
+            GAPID_WARNING("Failed to handle the requests preceding the connection type");
+            continue;
+        }
+
         switch (connectionType) {
             case REPLAY_REQUEST: {
                 GAPID_DEBUG("Replay requested");
-                std::unique_ptr<ServerConnection> conn = ServerConnection::create(std::move(client));
+                std::unique_ptr<ServerConnection> conn =
+                        ServerConnection::create(std::move(client), compression);
                 if (conn != nullptr) {
                     return conn;
                 } else {
@@ -95,6 +183,11 @@ std::unique_ptr<ServerConnection> ServerListener::acceptConnection(int idleTimeo
                 client->sendString("PONG");
                 break;
             }
+            case DEVICE_INFO: {
+                GAPID_DEBUG("Device information requested");
+                sendDeviceInfo(client.get(), mPersistentResources);
+                break;
+            }
             default: {
                 GAPID_WARNING("Unknown connection type %d ignored", connectionType);
             }
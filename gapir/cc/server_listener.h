@@ -28,6 +28,7 @@ class Connection;
 
 namespace gapir {
 
+class ResourceProvider;
 class ServerConnection;
 
 // Class for listening to incoming connections from the server.
@@ -35,19 +36,27 @@ class ServerListener {
 public:
     // Construct a ServerListener using the specified connection.
     // maxMemorySize is the maximum memory size that can be reported as
-    // supported by this device.
-    explicit ServerListener(std::unique_ptr<core::Connection> conn, uint64_t maxMemorySize);
+    // supported by this device. persistentResources is reported to the server
+    // with the device information, and is true if the resources of the replays
+    // are kept for the later replays.
+    ServerListener(std::unique_ptr<core::Connection> conn, uint64_t maxMemorySize,
+                   bool persistentResources = false);
 
     // Accept a new incoming connection on the underlying socket and create a ServerConnection over
     // the newly created socket object. idleTimeoutMs is the timeout in milliseconds to wait for
     // activity before returning a null pointer. Pass core::Connection::NO_TIMEOUT to disable the
-    // timeout.
-    std::unique_ptr<ServerConnection> acceptConnection(int idleTimeoutMs, const char* authToken);
+    // timeout. resources, if not null, is used to answer the server's queries for the resources
+    // already held by this device, so that they are not sent again.
+    std::unique_ptr<ServerConnection> acceptConnection(int idleTimeoutMs, const char* authToken,
+                                                       ResourceProvider* resources = nullptr);
 
     enum ConnectionType {
         REPLAY_REQUEST   = 0,
         SHUTDOWN_REQUEST = 1,
         PING             = 2,
+        DEVICE_INFO      = 3,
+        CACHED_RESOURCES = 4,
+        COMPRESSION      = 5,
     };
 
 private:
@@ -55,6 +64,8 @@ private:
     std::unique_ptr<core::Connection> mConn;
     // The maximum memory size that can be reported as supported by this device.
     uint64_t mMaxMemorySize;
+    // Whether the resources of the replays are kept for the later replays.
+    bool mPersistentResources;
 };
 
 }  // namespace gapir
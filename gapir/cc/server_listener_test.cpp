@@ -14,6 +14,7 @@
  * limitations under the License.
  */
 
+#include "mock_resource_provider.h"
 #include "server_connection.h"
 #include "server_listener.h"
 #include "test_utilities.h"
@@ -27,6 +28,7 @@
 
 using ::testing::_;
 using ::testing::DoAll;
+using ::testing::ElementsAre;
 using ::testing::IsNull;
 using ::testing::NotNull;
 using ::testing::Return;
@@ -127,6 +129,45 @@ TEST_F(ServerListenerTest, AcceptConnectionCorrectAuthToken) {
     EXPECT_THAT(mServerListener->acceptConnection(core::Connection::NO_TIMEOUT, "secrets"), NotNull());
 }
 
+TEST_F(ServerListenerTest, AcceptConnectionCachedResources) {
+    StrictMock<MockResourceProvider> resourceProvider;
+    EXPECT_CALL(resourceProvider, has(ResourceId("A"))).WillOnce(Return(true));
+    EXPECT_CALL(resourceProvider, has(ResourceId("B"))).WillOnce(Return(false));
+    auto clientConnection = new core::test::MockConnection();
+    mConnection->connections.push(clientConnection);
+    pushUint8(&clientConnection->in, ServerListener::CACHED_RESOURCES);
+    pushUint32(&clientConnection->in, 2);
+    pushString(&clientConnection->in, "A");
+    pushString(&clientConnection->in, "B");
+    pushValidReplayRequest(&clientConnection->in);
+    auto conn = mServerListener->acceptConnection(
+            core::Connection::NO_TIMEOUT, nullptr, &resourceProvider);
+    EXPECT_THAT(conn, NotNull());
+    EXPECT_THAT(clientConnection->out, ElementsAre(1, 0));
+}
+
+TEST_F(ServerListenerTest, AcceptConnectionCompression) {
+    auto clientConnection = new core::test::MockConnection();
+    mConnection->connections.push(clientConnection);
+    pushUint8(&clientConnection->in, ServerListener::COMPRESSION);
+    pushUint8(&clientConnection->in, ServerConnection::COMPRESSION_LZ4);
+    pushValidReplayRequest(&clientConnection->in);
+    auto conn = mServerListener->acceptConnection(core::Connection::NO_TIMEOUT, nullptr);
+    EXPECT_THAT(conn, NotNull());
+    EXPECT_THAT(clientConnection->out, ElementsAre(1));
+}
+
+TEST_F(ServerListenerTest, AcceptConnectionUnknownCompression) {
+    auto clientConnection = new core::test::MockConnection();
+    mConnection->connections.push(clientConnection);
+    pushUint8(&clientConnection->in, ServerListener::COMPRESSION);
+    pushUint8(&clientConnection->in, 0x7f);
+    pushValidReplayRequest(&clientConnection->in);
+    auto conn = mServerListener->acceptConnection(core::Connection::NO_TIMEOUT, nullptr);
+    EXPECT_THAT(conn, NotNull());
+    EXPECT_THAT(clientConnection->out, ElementsAre(0));
+}
+
 
 }  // namespace test
 }  // namespace gapir
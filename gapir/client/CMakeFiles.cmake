@@ -19,9 +19,15 @@
 
 set(files
     client.go
+    crash_report.go
+    crash_report_test.go
     doc.go
     host_log_parser.go
+    isolation.go
+    remote.go
+    remote_test.go
     session.go
+    swiftshader.go
 )
 set(dirs
     
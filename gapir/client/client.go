@@ -20,32 +20,61 @@ import (
 	"sync"
 
 	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/data/id"
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/os/device"
 	"github.com/google/gapid/core/os/device/bind"
+	"github.com/google/gapid/core/os/device/host"
 )
 
 // Client is interface used to connect to GAPIR instances on devices.
 type Client struct {
 	mutex    sync.Mutex
-	sessions map[deviceArch]*session
+	options  Options
+	sessions map[sessionKey]*session
 }
 
 // New returns a newly construct Client.
-func New(ctx context.Context) *Client {
-	c := &Client{sessions: map[deviceArch]*session{}}
+func New(ctx context.Context, options Options) *Client {
+	c := &Client{options: options, sessions: map[sessionKey]*session{}}
 	app.AddCleanup(ctx, c.shutdown)
 	return c
 }
 
-type deviceArch struct {
-	d bind.Device
-	a device.Architecture
+type sessionKey struct {
+	d       bind.Device
+	a       device.Architecture
+	capture id.ID
 }
 
-// Connect opens a connection to the replay device.
-func (c *Client) Connect(ctx context.Context, d bind.Device, abi *device.ABI) (io.ReadWriteCloser, error) {
-	s, isNew, err := c.getOrCreateSession(ctx, d, abi)
+// Connect opens a connection to the replay device, for replaying the capture
+// with the given identifier.
+func (c *Client) Connect(ctx context.Context, d bind.Device, abi *device.ABI, capture id.ID) (io.ReadWriteCloser, error) {
+	isolation := c.options.Isolation
+	if !host.Instance(ctx).SameAs(d.Instance()) {
+		isolation = Shared
+	}
+
+	if isolation == PerRequest {
+		// The session is not shared, and lives as long as the connection.
+		s := newSession(d, capture, c.options)
+		if err := s.init(ctx, d, abi); err != nil {
+			return nil, err
+		}
+		conn, err := s.connect(ctx)
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		return sessionConnection{conn, s}, nil
+	}
+
+	key := sessionKey{d: d, a: abi.Architecture}
+	if isolation == PerCapture {
+		key.capture = capture
+	}
+
+	s, isNew, err := c.getOrCreateSession(ctx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +88,64 @@ func (c *Client) Connect(ctx context.Context, d bind.Device, abi *device.ABI) (i
 	return s.connect(ctx)
 }
 
-func (c *Client) getOrCreateSession(ctx context.Context, d bind.Device, abi *device.ABI) (*session, bool, error) {
+// Warm starts the GAPIR instance shared by the replays on the device d with the
+// given ABI if it is not running, so that neither its start nor the loading of
+// its libraries delay the next replay. A running instance that does not reply
+// to a ping is restarted. The heartbeat of the session keeps the instance
+// running until it stops replying, at which point the returned channel is
+// closed. Warm does nothing and returns a nil channel for host devices whose
+// replays do not share an instance.
+func (c *Client) Warm(ctx context.Context, d bind.Device, abi *device.ABI) (<-chan struct{}, error) {
+	if c.options.Isolation != Shared && host.Instance(ctx).SameAs(d.Instance()) {
+		return nil, nil
+	}
+
+	key := sessionKey{d: d, a: abi.Architecture}
+	s, isNew, err := c.getOrCreateSession(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !isNew {
+		<-s.inited
+		if _, err := s.ping(ctx); err == nil {
+			return s.closed, nil
+		}
+		log.W(ctx, "GAPIR did not reply to a ping, restarting it")
+		s.close()
+		if s, isNew, err = c.getOrCreateSession(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	if isNew {
+		if err := s.init(ctx, d, abi); err != nil {
+			return nil, err
+		}
+	}
+	<-s.inited
+	return s.closed, nil
+}
+
+// Compress returns true if the resources sent to the GAPIR instances on the
+// device should be compressed.
+func (c *Client) Compress(ctx context.Context, d bind.Device) bool {
+	if _, ok := d.(*SwiftShader); ok || host.Instance(ctx).SameAs(d.Instance()) {
+		return false
+	}
+	return c.options.Compression
+}
+
+// sessionConnection is a connection that closes its session once closed.
+type sessionConnection struct {
+	io.ReadWriteCloser
+	s *session
+}
+
+func (c sessionConnection) Close() error {
+	defer c.s.close()
+	return c.ReadWriteCloser.Close()
+}
+
+func (c *Client) getOrCreateSession(ctx context.Context, key sessionKey) (*session, bool, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -67,13 +153,12 @@ func (c *Client) getOrCreateSession(ctx context.Context, d bind.Device, abi *dev
 		return nil, false, log.Err(ctx, nil, "Client has been shutdown")
 	}
 
-	key := deviceArch{d, abi.Architecture}
 	s, existing := c.sessions[key]
 	if existing {
 		return s, false, nil
 	}
 
-	s = newSession(d)
+	s = newSession(key.d, key.capture, c.options)
 	c.sessions[key] = s
 	s.onClose(func() {
 		c.mutex.Lock()
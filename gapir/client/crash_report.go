@@ -0,0 +1,84 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxCrashReportLines is the number of output lines of a GAPIR instance that
+// are kept for the crash report.
+const maxCrashReportLines = 200
+
+// crashReport collects the last lines of output of a GAPIR instance, so they
+// can be written to a report if the instance terminates unexpectedly.
+type crashReport struct {
+	mutex sync.Mutex
+	lines []string
+	next  int
+}
+
+// add appends line to the collected output, dropping the oldest line if the
+// report is full.
+func (r *crashReport) add(line string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.lines) < maxCrashReportLines {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % maxCrashReportLines
+}
+
+// output returns the collected output, oldest line first.
+func (r *crashReport) output() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	out := make([]string, 0, len(r.lines))
+	out = append(out, r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
+
+// write writes the report for the instance that terminated with the error
+// cause into the directory dir, and returns the path to the report.
+func (r *crashReport) write(dir string, s *session, cause error) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	sb := &bytes.Buffer{}
+	fmt.Fprintf(sb, "GAPIR terminated unexpectedly: %v\n", cause)
+	fmt.Fprintf(sb, "Time:    %v\n", now.Format(time.RFC3339))
+	fmt.Fprintf(sb, "Device:  %v\n", s.device.Instance().GetName())
+	if s.capture.IsValid() {
+		fmt.Fprintf(sb, "Capture: %v\n", s.capture)
+	}
+	fmt.Fprintf(sb, "\nLast %d lines of output:\n", maxCrashReportLines)
+	for _, line := range r.output() {
+		fmt.Fprintln(sb, line)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("gapir-crash-%v.txt", now.Format("20060102-150405.000")))
+	if err := ioutil.WriteFile(path, sb.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+)
+
+func TestCrashReportKeepsLastLines(t *testing.T) {
+	ctx := log.Testing(t)
+	r := &crashReport{}
+	for i := 0; i < maxCrashReportLines+5; i++ {
+		r.add(fmt.Sprint(i))
+	}
+	lines := r.output()
+	assert.With(ctx).ThatSlice(lines).IsLength(maxCrashReportLines)
+	assert.With(ctx).ThatString(lines[0]).Equals("5")
+	assert.With(ctx).ThatString(lines[len(lines)-1]).Equals(fmt.Sprint(maxCrashReportLines + 4))
+}
+
+func TestParseIsolation(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, i := range []Isolation{Shared, PerCapture, PerRequest} {
+		got, err := ParseIsolation(i.String())
+		assert.With(ctx).ThatError(err).Succeeded()
+		assert.With(ctx).That(got).Equals(i)
+	}
+	_, err := ParseIsolation("process")
+	assert.With(ctx).ThatError(err).Failed()
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import "fmt"
+
+// Isolation controls which replays share a GAPIR instance on the host, so that
+// a driver crash during one replay does not fail the replays of other
+// requests.
+type Isolation int
+
+const (
+	// Shared runs all the replays on a device in a single GAPIR instance.
+	Shared Isolation = iota
+	// PerCapture runs the replays of each capture in their own GAPIR instance.
+	PerCapture
+	// PerRequest runs each replay in a new GAPIR instance.
+	PerRequest
+)
+
+var isolationNames = map[Isolation]string{
+	Shared:     "shared",
+	PerCapture: "capture",
+	PerRequest: "request",
+}
+
+func (i Isolation) String() string {
+	if name, ok := isolationNames[i]; ok {
+		return name
+	}
+	return fmt.Sprintf("Isolation<%d>", int(i))
+}
+
+// ParseIsolation returns the Isolation with the given name, as returned by
+// Isolation.String.
+func ParseIsolation(name string) (Isolation, error) {
+	for i, n := range isolationNames {
+		if n == name {
+			return i, nil
+		}
+	}
+	return Shared, fmt.Errorf("Unknown replay isolation '%v'", name)
+}
+
+// Options holds the options used to create a Client.
+type Options struct {
+	// Isolation controls which replays share a GAPIR instance on the host.
+	// Replays on Android and remote devices always share a single GAPIR
+	// instance.
+	Isolation Isolation
+
+	// CrashReports is the directory in which a report is written whenever a
+	// GAPIR instance on the host terminates unexpectedly. No reports are
+	// written if empty.
+	CrashReports string
+
+	// ResourceCache is the directory in which GAPIR instances on the host keep
+	// the resources of the replays, so that later replays do not need to send
+	// them again. Only used when Isolation is Shared, as concurrent instances
+	// cannot share a cache. No resources are kept between instances if empty.
+	ResourceCache string
+
+	// DeviceResourceCache keeps the resources of the replays in the internal
+	// storage of the GAPID app on Android devices, so that later replays do
+	// not need to send them again. The cache is never evicted and grows with
+	// each new capture replayed, so it is disabled by default.
+	DeviceResourceCache bool
+
+	// Compression compresses the resources sent to GAPIR instances on Android
+	// and remote devices. Instances on the host are always sent uncompressed
+	// resources as the compression would only cost time.
+	Compression bool
+
+	// Prewarm starts the GAPIR instances of the replay devices as soon as the
+	// devices are added, instead of on their first replay, and keeps them
+	// running between replays.
+	Prewarm bool
+}
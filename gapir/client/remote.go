@@ -0,0 +1,149 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/core/app/auth"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/os/device/bind"
+	"github.com/google/gapid/gapis/replay/protocol"
+)
+
+// remoteDialTimeout is the maximum time spent establishing a connection to a
+// remote GAPIR instance.
+const remoteDialTimeout = time.Second * 10
+
+// Remote is a replay device on another host, reached over the network.
+// The GAPIR instance serving the device is not managed by the client, and has to
+// be started on the remote host with the --listen and --auth-token flags.
+//
+// The replays use the same protocol as local ones: GAPIR requests the payload
+// and the resources over the connection as the replay needs them, after asking
+// which of them it already holds. Starting GAPIR with the --cache flag keeps
+// the resources on the remote host, so later replays do not send them again.
+type Remote struct {
+	bind.Simple
+	// Address is the host:port of the remote GAPIR instance.
+	Address string
+	// AuthToken is the token used to authenticate to the remote GAPIR instance.
+	AuthToken auth.Token
+	// TLS is the configuration used to encrypt the connections to the remote
+	// GAPIR instance, or nil to use plain TCP. GAPIR does not speak TLS, so
+	// the connections have to be terminated by a proxy in front of it.
+	TLS *tls.Config
+	// ResourceCache is true if the remote GAPIR instance keeps the resources
+	// of the replays for the later replays.
+	ResourceCache bool
+}
+
+// ConnectRemote connects to the GAPIR instance listening at address, and
+// returns the replay device it runs on. If tlsConfig is not nil the connections
+// are encrypted, which requires a TLS terminating proxy in front of GAPIR.
+func ConnectRemote(ctx context.Context, address string, token auth.Token, tlsConfig *tls.Config) (*Remote, error) {
+	ctx = log.V{"address": address}.Bind(ctx)
+	r := &Remote{Address: address, AuthToken: token, TLS: tlsConfig}
+
+	conn, err := r.dial()
+	if err != nil {
+		return nil, log.Err(ctx, err, "Connecting to remote GAPIR")
+	}
+	defer conn.Close()
+
+	w := endian.Writer(conn, device.LittleEndian)
+	rd := endian.Reader(conn, device.LittleEndian)
+	if w.Uint8(uint8(protocol.ConnectionType_DeviceInfo)); w.Error() != nil {
+		return nil, log.Err(ctx, w.Error(), "Requesting remote device information")
+	}
+	size := rd.Uint32()
+	if rd.Error() == nil && size == 0 {
+		return nil, log.Err(ctx, nil, "Remote GAPIR could not query its device information")
+	}
+	data := make([]byte, size)
+	if rd.Data(data); rd.Error() != nil {
+		return nil, log.Err(ctx, rd.Error(), "Reading remote device information")
+	}
+
+	cache := rd.Uint8()
+	if rd.Error() != nil {
+		return nil, log.Err(ctx, rd.Error(), "Reading remote device information")
+	}
+
+	instance := &device.Instance{}
+	if err := proto.Unmarshal(data, instance); err != nil {
+		return nil, log.Err(ctx, err, "Unmarshalling remote device information")
+	}
+	if r.ResourceCache = cache != 0; !r.ResourceCache {
+		log.W(ctx, "Remote GAPIR has no resource cache, every replay sends all its resources. Start it with --cache to keep them")
+	}
+	if instance.Serial == "" {
+		instance.Serial = address
+	}
+	r.To, r.LastStatus = instance, bind.Status_Online
+	return r, nil
+}
+
+// LoadTLSConfig returns the TLS configuration for connecting to remote GAPIR
+// instances whose certificates are signed by the certificate authorities in
+// the PEM file caFile. If caFile is empty, the host's root certificate
+// authorities are used.
+func LoadTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return &tls.Config{}, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No certificates found in '%v'", caFile)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+func (r *Remote) String() string {
+	return fmt.Sprintf("%v (%v)", r.Simple.String(), r.Address)
+}
+
+// dial opens a new authenticated connection to the remote GAPIR instance.
+func (r *Remote) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: remoteDialTimeout}
+	var conn net.Conn
+	var err error
+	if r.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.Address, r.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", r.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.Write(conn, r.AuthToken); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
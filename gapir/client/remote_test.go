@@ -0,0 +1,98 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/core/app/auth"
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/os/device/bind"
+	"github.com/google/gapid/gapis/replay/protocol"
+)
+
+// fakeRemote is a GAPIR instance listening for the connections of remote
+// clients, answering their device information and ping requests.
+type fakeRemote struct {
+	listener net.Listener
+	token    auth.Token
+	instance *device.Instance
+	cache    bool
+}
+
+func (f *fakeRemote) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRemote) handle(conn net.Conn) {
+	defer conn.Close()
+	r := endian.Reader(conn, device.LittleEndian)
+	w := endian.Writer(conn, device.LittleEndian)
+	header := make([]byte, 4)
+	if r.Data(header); string(header) != "AUTH" || r.String() != string(f.token) {
+		return
+	}
+	switch protocol.ConnectionType(r.Uint8()) {
+	case protocol.ConnectionType_DeviceInfo:
+		data, err := proto.Marshal(f.instance)
+		if err != nil {
+			panic(err)
+		}
+		w.Uint32(uint32(len(data)))
+		w.Data(data)
+		w.Bool(f.cache)
+	case protocol.ConnectionType_Ping:
+		w.String("PONG")
+	}
+}
+
+func TestRemote(t *testing.T) {
+	ctx := log.Testing(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.For(ctx, "Listen").ThatError(err).Succeeded()
+	defer listener.Close()
+	f := &fakeRemote{listener, auth.Token("secret"), &device.Instance{Name: "remote"}, true}
+	go f.serve()
+	address := listener.Addr().String()
+
+	r, err := ConnectRemote(ctx, address, f.token, nil)
+	assert.For(ctx, "ConnectRemote").ThatError(err).Succeeded()
+	assert.For(ctx, "Name").ThatString(r.Instance().Name).Equals("remote")
+	assert.For(ctx, "Serial").ThatString(r.Instance().Serial).Equals(address)
+	assert.For(ctx, "Status").That(r.Status()).Equals(bind.Status_Online)
+	assert.For(ctx, "ResourceCache").That(r.ResourceCache).Equals(true)
+
+	s := newSession(r, id.ID{}, Options{})
+	assert.For(ctx, "newRemote").ThatError(s.newRemote(ctx, r)).Succeeded()
+	assert.For(ctx, "remote").That(s.remote).Equals(r)
+
+	_, err = ConnectRemote(ctx, address, auth.Token("wrong"), nil)
+	assert.For(ctx, "ConnectRemote with a wrong token").ThatError(err).Failed()
+	bad := &Remote{Simple: r.Simple, Address: address, AuthToken: auth.Token("wrong")}
+	s = newSession(bad, id.ID{}, Options{})
+	assert.For(ctx, "newRemote with a wrong token").ThatError(s.newRemote(ctx, bad)).Failed()
+}
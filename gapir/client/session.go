@@ -18,12 +18,16 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/gapid/core/app/auth"
 	"github.com/google/gapid/core/app/layout"
 	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/id"
 	"github.com/google/gapid/core/event/task"
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/os/android/adb"
@@ -47,24 +51,37 @@ const sessionTimeout = time.Second * 10
 
 type session struct {
 	device   bind.Device
+	capture  id.ID // The capture the session is isolated to, if any.
+	options  Options
 	port     int
 	auth     auth.Token
+	remote   *Remote // The remote device, if the GAPIR instance is on another host.
 	closeCBs []func()
+	mutex    sync.Mutex // guards closeCBs and closed
 	inited   chan struct{}
+	closed   chan struct{} // Closed once the session is closed.
 }
 
-func newSession(d bind.Device) *session {
-	return &session{device: d, inited: make(chan struct{})}
+func newSession(d bind.Device, capture id.ID, options Options) *session {
+	return &session{
+		device:  d,
+		capture: capture,
+		options: options,
+		inited:  make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
 }
 
 func (s *session) init(ctx context.Context, d bind.Device, abi *device.ABI) error {
 	defer close(s.inited)
 
 	var err error
-	if host.Instance(ctx).SameAs(d.Instance()) {
+	if _, ok := d.(*SwiftShader); ok || host.Instance(ctx).SameAs(d.Instance()) {
 		err = s.newHost(ctx, d)
 	} else if d, ok := d.(adb.Device); ok {
 		err = s.newADB(ctx, d, abi)
+	} else if d, ok := d.(*Remote); ok {
+		err = s.newRemote(ctx, d)
 	} else {
 		err = log.Errf(ctx, nil, "Cannot connect to device type %v", d)
 	}
@@ -73,6 +90,9 @@ func (s *session) init(ctx context.Context, d bind.Device, abi *device.ABI) erro
 		return err
 	}
 
+	// Stop the heartbeat once the session is closed.
+	ctx, stop := task.WithCancel(ctx)
+	s.onClose(stop)
 	go s.heartbeat(ctx, sessionTimeout/2)
 	return nil
 }
@@ -85,10 +105,21 @@ func (s *session) newHost(ctx context.Context, d bind.Device, gapirArgs ...strin
 		"--auth-token", string(authToken),
 	}
 	args = append(args, gapirArgs...)
+	if s.options.ResourceCache != "" && s.options.Isolation == Shared {
+		// Each device gets its own cache as the instances cannot share one.
+		cache := filepath.Join(s.options.ResourceCache, d.Instance().Id.ID().String())
+		args = append(args, "--cache", cache)
+	}
 	if LogPath != "" {
 		args = append(args, "--log", LogPath)
 	}
 
+	// The instance is killed when the session is closed.
+	ctx, stop := task.WithCancel(ctx)
+	s.onClose(stop)
+
+	report := &crashReport{}
+
 	gapir, err := layout.Gapir(ctx)
 	if err != nil {
 		log.F(ctx, "Couldn't locate gapir executable: %v", err)
@@ -99,6 +130,11 @@ func (s *session) newHost(ctx context.Context, d bind.Device, gapirArgs ...strin
 	if _, err := loader.SetupReplay(ctx, env); err != nil {
 		return err
 	}
+	if _, ok := d.(*SwiftShader); ok {
+		if err := loader.SetupSwiftShader(ctx, env); err != nil {
+			return err
+		}
+	}
 
 	parser := func(severity log.Severity) io.WriteCloser {
 		h := log.GetHandler(ctx)
@@ -107,6 +143,7 @@ func (s *session) newHost(ctx context.Context, d bind.Device, gapirArgs ...strin
 		}
 		ctx := log.PutProcess(ctx, "gapir")
 		return text.Writer(func(line string) error {
+			report.add(line)
 			if m := parseHostLogMsg(line); m != nil {
 				h.Handle(m)
 				return nil
@@ -131,6 +168,7 @@ func (s *session) newHost(ctx context.Context, d bind.Device, gapirArgs ...strin
 		Args:   args,
 		Stdout: stdout,
 		Stderr: stderr,
+		OnExit: func(err error) { s.onExit(ctx, report, err) },
 	})
 	if err != nil {
 		log.E(ctx, "Starting gapir. Error: %v", err)
@@ -174,6 +212,16 @@ func (s *session) newADB(ctx context.Context, d adb.Device, abi *device.ABI) err
 	}
 	s.onClose(func() { d.RemoveForward(ctx, localPort) })
 
+	// GAPIR reads the property on start, so it is always set to replace the
+	// value of a previous session.
+	cache := "0"
+	if s.options.DeviceResourceCache {
+		cache = "1"
+	}
+	if err := d.Shell("setprop", "debug.gapid.gapir.cache", cache).Run(ctx); err != nil {
+		return log.Err(ctx, err, "Setting the resource cache property")
+	}
+
 	log.I(ctx, "Launching GAPIR...")
 	if err := d.StartActivity(ctx, *apk.ActivityActions[0]); err != nil {
 		return err
@@ -191,24 +239,69 @@ func (s *session) newADB(ctx context.Context, d adb.Device, abi *device.ABI) err
 	return log.Err(ctx, nil, "Timeout waiting for connection")
 }
 
+// newRemote uses the GAPIR instance already running on the remote device d.
+func (s *session) newRemote(ctx context.Context, d *Remote) error {
+	s.remote = d
+	if _, err := s.ping(ctx); err != nil {
+		return log.Errf(ctx, err, "Connecting to remote GAPIR at %v", d.Address)
+	}
+	log.I(ctx, "Connected to remote GAPIR at %v", d.Address)
+	return nil
+}
+
 func (s *session) connect(ctx context.Context) (io.ReadWriteCloser, error) {
 	<-s.inited
+	return s.dial()
+}
+
+// dial opens a new connection to the GAPIR instance of the session.
+func (s *session) dial() (net.Conn, error) {
+	if s.remote != nil {
+		return s.remote.dial()
+	}
 	return process.Connect(s.port, s.auth)
 }
 
+// onExit is called when the GAPIR instance on the host terminates. The
+// session is closed so that the next connection starts a new instance, and
+// a crash report is written if the instance did not terminate normally.
+func (s *session) onExit(ctx context.Context, report *crashReport, err error) {
+	if err != nil && !task.Stopped(ctx) {
+		log.E(ctx, "GAPIR terminated unexpectedly: %v", err)
+		if dir := s.options.CrashReports; dir != "" {
+			if path, err := report.write(dir, s, err); err != nil {
+				log.E(ctx, "Failed to write the GAPIR crash report: %v", err)
+			} else {
+				log.I(ctx, "GAPIR crash report written to: %v", path)
+			}
+		}
+	}
+	s.close()
+}
+
 func (s *session) onClose(f func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.closeCBs = append(s.closeCBs, f)
 }
 
 func (s *session) close() {
-	for _, f := range s.closeCBs {
+	s.mutex.Lock()
+	cbs := s.closeCBs
+	s.closeCBs = nil
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	s.mutex.Unlock()
+	for _, f := range cbs {
 		f()
 	}
-	s.closeCBs = nil
 }
 
 func (s *session) ping(ctx context.Context) (time.Duration, error) {
-	connection, err := process.Connect(s.port, s.auth)
+	connection, err := s.dial()
 	if err != nil {
 		return 0, err
 	}
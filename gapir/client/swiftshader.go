@@ -0,0 +1,58 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/core/app/layout"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/os/device/bind"
+	"github.com/google/gapid/core/os/device/host"
+)
+
+// SwiftShader is a replay device running GAPIR on the host machine with the
+// SwiftShader software Vulkan driver bundled with GAPID. Its rendering does not
+// depend on the host's GPU, so replays on it are deterministic and available
+// even when the host has no compatible hardware.
+type SwiftShader struct {
+	bind.Simple
+}
+
+// NewSwiftShader returns the SwiftShader replay device of the host machine, or
+// an error if SwiftShader is not bundled with GAPID.
+func NewSwiftShader(ctx context.Context) (*SwiftShader, error) {
+	json, err := layout.Json(ctx, layout.LibSwiftShader)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Exists() {
+		return nil, log.Errf(ctx, nil, "SwiftShader not found at %v", json)
+	}
+
+	instance := proto.Clone(host.Instance(ctx)).(*device.Instance)
+	instance.Serial = "swiftshader:" + instance.Serial
+	instance.Name = "SwiftShader"
+	instance.Configuration.Drivers = &device.Drivers{
+		Vulkan: &device.VulkanDriver{
+			Renderer: "SwiftShader Device",
+			Vendor:   "Google",
+		},
+	}
+	instance.GenID()
+	return &SwiftShader{bind.Simple{To: instance, LastStatus: bind.Status_Online}}, nil
+}
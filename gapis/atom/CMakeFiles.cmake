@@ -21,27 +21,41 @@ set(files
     abort.go
     atom.go
     atom_binary.go
+    batching.go
     cast.go
     convert.go
     data.go
+    dependencies.go
     doc.go
+    execution.go
     extras.go
     field_alignments.go
     flags.go
+    frame_graph.go
     framebuffer_observation.go
     group.go
     group_list.go
     group_test.go
+    grouper.go
     id_set.go
     labeled.go
+    leaks.go
+    lint.go
     list.go
     list_test.go
+    nondeterministic_values.go
     observations.go
+    passes.go
     range.go
     range_list.go
+    redundancy.go
+    redundancy_test.go
     resource.go
+    sanitize.go
     schema.go
+    shader_issues.go
     snippet.go
+    submissions.go
     writer.go
 )
 set(dirs
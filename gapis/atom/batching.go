@@ -0,0 +1,40 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import "context"
+
+// DrawBatch is a run of draw atoms using the same pipeline and bound
+// resources, with only per-draw data changed between them, that could be
+// merged into a single draw.
+type DrawBatch struct {
+	Draws []ID // The draw atoms of the batch, in order.
+	// Commands is the number of atoms of the batch, from the first draw to the
+	// last one included, ignoring the atoms of other command buffers or
+	// contexts.
+	Commands uint64
+	// Instanceable is true if the draws all draw the same vertices, so that the
+	// batch could be a single instanced draw with the per-draw data moved to
+	// instance attributes.
+	Instanceable bool
+}
+
+// DrawBatcher is the interface implemented by APIs that can find the draw
+// atoms of a capture that could be merged into fewer draws.
+type DrawBatcher interface {
+	// DrawBatches returns the batches of two draws or more of the capture held
+	// by ctx, in the order of their first draw.
+	DrawBatches(ctx context.Context) ([]DrawBatch, error)
+}
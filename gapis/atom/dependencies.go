@@ -0,0 +1,28 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import "context"
+
+// DependencyTracker is the interface implemented by APIs that can use their
+// dependency graph to find the atoms needed to reproduce the output of other
+// atoms.
+type DependencyTracker interface {
+	// Dependencies returns the IDs of the atoms of the capture held by ctx
+	// that need to be executed for each atom in requests to produce the same
+	// output as in the capture. The returned IDs include the requests, and
+	// are in increasing order.
+	Dependencies(ctx context.Context, requests []ID) ([]ID, error)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import "context"
+
+// ExecutionKind describes the kind of work performed by an atom once it is
+// executed by the device.
+type ExecutionKind int
+
+const (
+	// Other is the kind of atoms that do not perform any work on the device
+	// by themselves, such as state changes.
+	Other ExecutionKind = iota
+	// Action is the kind of atoms that perform work on the device, such as
+	// draws, dispatches, copies and clears.
+	Action
+	// BeginPass is the kind of atoms that begin a render pass.
+	BeginPass
+	// EndPass is the kind of atoms that end a render pass.
+	EndPass
+	// Submit is the kind of atoms that submit recorded atoms to a queue for
+	// execution.
+	Submit
+)
+
+// DeferredExecutor is the interface implemented by APIs that record atoms
+// for deferred execution, such as into command buffers which are executed
+// when they are submitted to a queue.
+type DeferredExecutor interface {
+	// ExecutionOrder returns order with the recorded atoms of the API moved
+	// to follow the atoms that submit them for execution. Recorded atoms
+	// that are never submitted are removed, and atoms that are submitted more
+	// than once are repeated. atoms is the full list of atoms of the capture,
+	// which order indexes into.
+	ExecutionOrder(ctx context.Context, atoms []Atom, order []ID) ([]ID, error)
+
+	// ExecutionKind returns the kind of work performed by the atom a of the
+	// API once it is executed.
+	ExecutionKind(a Atom) ExecutionKind
+}
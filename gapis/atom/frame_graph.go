@@ -0,0 +1,59 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+// FramePassKind is the kind of a pass of a frame graph.
+type FramePassKind int
+
+const (
+	// RenderPass is the kind of the render passes.
+	RenderPass FramePassKind = iota
+	// ComputePass is the kind of the dispatches.
+	ComputePass
+	// TransferPass is the kind of the copies, blits, clears and other
+	// transfers outside render passes.
+	TransferPass
+)
+
+// FrameResource is a resource read or written by the passes of a frame graph.
+type FrameResource struct {
+	Kind   string // The type of the resource, such as VkImage.
+	Handle uint64 // The handle of the resource.
+}
+
+// FramePass is a render pass, dispatch or transfer executed by a capture,
+// along with the resources it reads and writes.
+type FramePass struct {
+	Kind        FramePassKind
+	First       ID                             // The first command of the pass.
+	Last        ID                             // The last command of the pass.
+	Attachments []gfxapi.FramebufferAttachment // The attachments rendered to.
+	Reads       []FrameResource                // The resources read, in first access order.
+	Writes      []FrameResource                // The resources written, in first access order.
+}
+
+// FrameGrapher is the interface implemented by APIs that can list the passes
+// executed by a capture and the resources they access.
+type FrameGrapher interface {
+	// FramePasses returns the passes executed by the capture held by ctx, in
+	// execution order.
+	FramePasses(ctx context.Context) ([]FramePass, error)
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+// Grouper is the interface implemented by APIs that group their atoms into a
+// command hierarchy of their own, in addition to the frames, user markers and
+// draw calls.
+type Grouper interface {
+	// NewGroupBuilder returns a new GroupBuilder for the atoms of a single
+	// context of the API.
+	NewGroupBuilder() GroupBuilder
+}
+
+// GroupBuilder adds the API specific groups of a single context to the
+// context's command hierarchy.
+type GroupBuilder interface {
+	// Process is called with each atom a of the context, and its index i,
+	// after a has been mutated on s. Groups are added to root.
+	Process(ctx context.Context, a Atom, i uint64, s *gfxapi.State, root *Group)
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import "context"
+
+// LeakReason is the reason an object is reported as leaked.
+type LeakReason int
+
+const (
+	// NeverDestroyed is the reason of the objects never destroyed.
+	NeverDestroyed LeakReason = iota
+	// NeverFreed is the reason of the memory allocations never freed.
+	NeverFreed
+	// NeverReset is the reason of the pools never reset.
+	NeverReset
+)
+
+// Leak is an object created by an atom of a capture and not released by the
+// end of the capture.
+type Leak struct {
+	Created ID         // The atom that created the object.
+	Kind    string     // The type of the object, such as VkImage.
+	Handle  uint64     // The handle or name of the object.
+	Size    uint64     // The size in bytes of the memory held, 0 if unknown.
+	Reason  LeakReason // Why the object is reported.
+}
+
+// LeakTracker is the interface implemented by APIs that can find the objects
+// that the atoms of a capture create and never release.
+type LeakTracker interface {
+	// Leaks returns the objects created by the atoms of the capture held by
+	// ctx that are still alive at the end of the capture, in creation order.
+	Leaks(ctx context.Context) ([]Leak, error)
+}
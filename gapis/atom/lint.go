@@ -0,0 +1,32 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import "context"
+
+// LintIssue is a violation of a correctness rule by an atom of a capture.
+type LintIssue struct {
+	Atom    ID     // The offending atom.
+	Related ID     // Another atom involved in the violation, or NoID.
+	Rule    string // The identifier of the violated rule.
+	Message string // The description of the violation.
+}
+
+// Linter is the interface implemented by APIs that can check the atoms of a
+// capture against correctness rules, without replaying them.
+type Linter interface {
+	// Lint returns the issues found in the atoms of the capture held by ctx.
+	Lint(ctx context.Context) ([]LintIssue, error)
+}
@@ -16,6 +16,7 @@ package atom
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/gapid/framework/binary"
 )
@@ -58,3 +59,16 @@ func (l *List) AddAt(a Atom, id ID) {
 	copy(l.Atoms[id+1:], l.Atoms[id:])
 	l.Atoms[id] = a
 }
+
+// Splice returns a new list holding the atoms of l, with the atoms in rng
+// replaced by atoms. The list l is not modified.
+func (l *List) Splice(rng Range, atoms ...Atom) (*List, error) {
+	if rng.Start > rng.End || rng.End > uint64(len(l.Atoms)) {
+		return nil, fmt.Errorf("Range %v is out of bounds of the list of %d atoms", rng, len(l.Atoms))
+	}
+	out := &List{Atoms: make([]Atom, 0, uint64(len(l.Atoms))-rng.Length()+uint64(len(atoms)))}
+	out.Atoms = append(out.Atoms, l.Atoms[:rng.Start]...)
+	out.Atoms = append(out.Atoms, atoms...)
+	out.Atoms = append(out.Atoms, l.Atoms[rng.End:]...)
+	return out, nil
+}
@@ -86,3 +86,34 @@ func TestAtomListWriteTo(t *testing.T) {
 		}
 	}
 }
+
+func TestAtomListSplice(t *testing.T) {
+	for _, splice := range []struct {
+		name     string
+		rng      atom.Range
+		atoms    []atom.Atom
+		expected []atom.Atom
+	}{
+		{"replace", atom.Range{Start: 1, End: 2}, []atom.Atom{&test.AtomC{String: "Pasta"}},
+			[]atom.Atom{&test.AtomA{}, &test.AtomC{String: "Pasta"}, &test.AtomC{String: "Pizza"}}},
+		{"insert", atom.Range{Start: 0, End: 0}, []atom.Atom{&test.AtomB{}},
+			[]atom.Atom{&test.AtomB{}, &test.AtomA{}, &test.AtomB{Bool: true}, &test.AtomC{String: "Pizza"}}},
+		{"remove", atom.Range{Start: 0, End: 2}, nil,
+			[]atom.Atom{&test.AtomC{String: "Pizza"}}},
+	} {
+		got, err := testList.Splice(splice.rng, splice.atoms...)
+		if err != nil {
+			t.Errorf("%v: Splice returned error: %v", splice.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Atoms, splice.expected) {
+			t.Errorf("%v: Expected: %#v Got: %#v", splice.name, splice.expected, got.Atoms)
+		}
+	}
+	if len(testList.Atoms) != 3 {
+		t.Errorf("Splice modified the source list")
+	}
+	if _, err := testList.Splice(atom.Range{Start: 2, End: 4}); err == nil {
+		t.Errorf("Splice with an out of bounds range should fail")
+	}
+}
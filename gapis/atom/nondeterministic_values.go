@@ -0,0 +1,60 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+
+	"github.com/google/gapid/framework/binary"
+	"github.com/google/gapid/gapis/atom/atom_pb"
+)
+
+// NondeterministicValues is an extra attached to the first atoms of a capture
+// that was taken with the record-values option. It signals that the query
+// results and timestamps held in the capture's observations should be fed
+// back during replay instead of being re-queried from the replay device.
+type NondeterministicValues struct {
+	binary.Generate `java:"disable"`
+	Recorded        bool // True if the values were recorded for feeding back.
+}
+
+func (v *NondeterministicValues) Convert(ctx context.Context, out atom_pb.Handler) error {
+	return out(ctx, &atom_pb.NondeterministicValues{
+		Recorded: v.Recorded,
+	})
+}
+
+func NondeterministicValuesFrom(from *atom_pb.NondeterministicValues) NondeterministicValues {
+	return NondeterministicValues{
+		Recorded: from.Recorded,
+	}
+}
+
+// HasRecordedValues returns true if the atom list was captured with the
+// nondeterministic values recorded for feeding back on replay.
+func HasRecordedValues(l *List) bool {
+	for _, a := range l.Atoms {
+		if api := a.API(); api != nil && api.Index() != 0 {
+			// The extra is only attached to the leading core atoms.
+			return false
+		}
+		for _, e := range a.Extras().All() {
+			if v, ok := e.(*NondeterministicValues); ok && v.Recorded {
+				return true
+			}
+		}
+	}
+	return false
+}
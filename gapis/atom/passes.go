@@ -0,0 +1,40 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+// DrawInfo describes the state used by a draw call, as needed to group the
+// draw calls of a capture without debug markers into passes.
+type DrawInfo struct {
+	Target     uint64 // Identifies the render target drawn to.
+	Program    uint64 // Identifies the program or pipeline used.
+	Vertices   uint64 // The number of vertices drawn, or 0 if not known.
+	ColorWrite bool   // True if any color attachment can be written.
+	DepthWrite bool   // True if the depth attachment can be written.
+	Blend      bool   // True if blending is enabled for any color attachment.
+}
+
+// DrawDescriber is the interface implemented by APIs that can describe their
+// draw calls for grouping into passes.
+type DrawDescriber interface {
+	// DescribeDraw returns the DrawInfo of the draw call a given the state s
+	// before a is mutated, or false if a is not a draw call of the API.
+	DescribeDraw(ctx context.Context, s *gfxapi.State, a Atom) (DrawInfo, bool)
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+	"reflect"
+)
+
+// Redundancy is an atom of a capture that sets state to the value it already
+// holds.
+type Redundancy struct {
+	Atom     ID // The redundant atom.
+	Previous ID // The atom that last set the state to the same value.
+}
+
+// RedundancyChecker is the interface implemented by APIs that can find the
+// atoms setting state to its current value.
+type RedundancyChecker interface {
+	// Redundancies returns the redundant atoms of the capture held by ctx, in
+	// atom order.
+	Redundancies(ctx context.Context) ([]Redundancy, error)
+}
+
+// SlotValue is a value set to a piece of state, identified by a comparable
+// slot.
+type SlotValue struct {
+	Slot  interface{}
+	Value interface{}
+}
+
+// StateSlots holds the values last set to pieces of state, and the atoms that
+// set them. The values are compared with reflect.DeepEqual.
+type StateSlots map[interface{}]slotState
+
+type slotState struct {
+	atom  ID
+	value interface{}
+}
+
+// Set records that the atom id sets the given values. If all of the slots
+// already hold equal values, the last atom that set them is returned along
+// with true, and the slots are left unchanged.
+func (s StateSlots) Set(id ID, values ...SlotValue) (ID, bool) {
+	previous, redundant := NoID, len(values) > 0
+	for _, v := range values {
+		old, ok := s[v.Slot]
+		if !ok || !reflect.DeepEqual(old.value, v.Value) {
+			redundant = false
+			break
+		}
+		if previous == NoID || old.atom > previous {
+			previous = old.atom
+		}
+	}
+	if redundant {
+		return previous, true
+	}
+	for _, v := range values {
+		s[v.Slot] = slotState{id, v.Value}
+	}
+	return NoID, false
+}
+
+// Forget forgets the values of the slots for which f returns true, as their
+// state was changed or invalidated by other means.
+func (s StateSlots) Forget(f func(slot interface{}) bool) {
+	for slot := range s {
+		if f(slot) {
+			delete(s, slot)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+)
+
+func TestStateSlots(t *testing.T) {
+	ctx := log.Testing(t)
+	type slot struct{ state, key string }
+	viewport := slot{"viewport", ""}
+	front, back := slot{"stencil", "front"}, slot{"stencil", "back"}
+	slots := atom.StateSlots{}
+
+	set := func(id atom.ID, values ...atom.SlotValue) atom.ID {
+		previous, redundant := slots.Set(id, values...)
+		if !redundant {
+			return atom.NoID
+		}
+		return previous
+	}
+	assert.For(ctx, "first set").That(set(0, atom.SlotValue{viewport, []int{0, 0, 64, 64}})).Equals(atom.NoID)
+	assert.For(ctx, "same value").That(set(1, atom.SlotValue{viewport, []int{0, 0, 64, 64}})).Equals(atom.ID(0))
+	assert.For(ctx, "new value").That(set(2, atom.SlotValue{viewport, []int{0, 0, 32, 32}})).Equals(atom.NoID)
+	assert.For(ctx, "old value").That(set(3, atom.SlotValue{viewport, []int{0, 0, 64, 64}})).Equals(atom.NoID)
+
+	assert.For(ctx, "both faces").That(set(4, atom.SlotValue{front, 1}, atom.SlotValue{back, 1})).Equals(atom.NoID)
+	assert.For(ctx, "front face").That(set(5, atom.SlotValue{front, 2})).Equals(atom.NoID)
+	assert.For(ctx, "partly redundant").That(set(6, atom.SlotValue{front, 1}, atom.SlotValue{back, 1})).Equals(atom.NoID)
+	assert.For(ctx, "fully redundant").That(set(7, atom.SlotValue{front, 1}, atom.SlotValue{back, 1})).Equals(atom.ID(6))
+
+	slots.Forget(func(s interface{}) bool { return s.(slot).state == "stencil" })
+	assert.For(ctx, "forgotten").That(set(8, atom.SlotValue{front, 1})).Equals(atom.NoID)
+	assert.For(ctx, "kept").That(set(9, atom.SlotValue{viewport, []int{0, 0, 64, 64}})).Equals(atom.ID(3))
+}
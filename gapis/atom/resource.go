@@ -30,11 +30,15 @@ import (
 // the stream on import and their resources are placed into the database.
 type Resource struct {
 	binary.Generate
-	ID   id.ID  // The resource identifier holding the memory that was observed.
-	Data []byte // The resource data
+	ID     id.ID   // The resource identifier holding the memory that was observed.
+	Data   []byte  // The resource data
+	Chunks []id.ID // The resources that form the data when concatenated, if chunked.
 }
 
 func (a *Resource) String() string {
+	if len(a.Chunks) > 0 {
+		return fmt.Sprintf("ID: %s - %d chunks", a.ID, len(a.Chunks))
+	}
 	return fmt.Sprintf("ID: %s - 0x%x bytes", a.ID, len(a.Data))
 }
 
@@ -45,14 +49,25 @@ func (a *Resource) Mutate(ctx context.Context, s *gfxapi.State, b *builder.Build
 	return nil
 }
 func (a *Resource) Convert(ctx context.Context, out atom_pb.Handler) error {
+	chunks := make([]string, len(a.Chunks))
+	for i, c := range a.Chunks {
+		chunks[i] = c.String()
+	}
 	return out(ctx, &atom_pb.Resource{
-		Id:   a.ID.String(),
-		Data: a.Data,
+		Id:     a.ID.String(),
+		Data:   a.Data,
+		Chunks: chunks,
 	})
 }
 func ResourceFrom(from *atom_pb.Resource) Resource {
 	r := Resource{}
 	r.ID.Parse(from.Id)
 	r.Data = from.Data
+	if len(from.Chunks) > 0 {
+		r.Chunks = make([]id.ID, len(from.Chunks))
+		for i, c := range from.Chunks {
+			r.Chunks[i].Parse(c)
+		}
+	}
 	return r
 }
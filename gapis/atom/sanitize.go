@@ -0,0 +1,53 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/memory"
+)
+
+// SensitiveKind is the kind of the potentially sensitive data of a capture.
+type SensitiveKind int
+
+const (
+	// SensitiveShader is shader source code.
+	SensitiveShader SensitiveKind = iota
+	// SensitiveName is a debug name, label or marker, an application name or
+	// a file path.
+	SensitiveName
+	// SensitiveTexture is texture content.
+	SensitiveTexture
+)
+
+// SensitiveData is a range of the application memory read by an atom that
+// holds potentially sensitive data.
+type SensitiveData struct {
+	Atom  ID            // The atom reading the data.
+	Kind  SensitiveKind // The kind of the data.
+	Range memory.Range  // The memory holding the data.
+	// The size in bytes of a texel and of a row of texels of the
+	// SensitiveTexture data.
+	TexelSize, RowSize uint64
+}
+
+// Sanitizer is the interface implemented by APIs that can find the
+// potentially sensitive data read by the atoms of a capture.
+type Sanitizer interface {
+	// SensitiveData returns the potentially sensitive data read by the atoms
+	// of the capture held by ctx.
+	SensitiveData(ctx context.Context) ([]SensitiveData, error)
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+// ShaderIssues are the issues found by the static analysis of a shader
+// created or compiled by an atom.
+type ShaderIssues struct {
+	Atom   ID                    // The atom that created or compiled the shader.
+	Handle uint64                // The handle or name of the shader.
+	Type   gfxapi.ShaderType     // The type of the shader.
+	Issues []*gfxapi.ShaderIssue // The issues found, possibly none.
+}
+
+// ShaderAnalyzer is the interface implemented by APIs that can statically
+// analyze the shaders of a capture.
+type ShaderAnalyzer interface {
+	// ShaderIssues returns the issues of each shader created or compiled by
+	// the atoms of the capture held by ctx, in atom order.
+	ShaderIssues(ctx context.Context) ([]ShaderIssues, error)
+}
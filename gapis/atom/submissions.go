@@ -0,0 +1,52 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atom
+
+import "context"
+
+// SubmissionKind is the kind of a queue submission or host synchronization
+// atom.
+type SubmissionKind int
+
+const (
+	// QueueSubmit is the kind of the submissions of work to a queue.
+	QueueSubmit SubmissionKind = iota
+	// QueuePresent is the kind of the presentations of images by a queue.
+	QueuePresent
+	// ImageAcquire is the kind of the acquisitions of presentable images.
+	ImageAcquire
+	// FenceWait is the kind of the host waits on fences.
+	FenceWait
+)
+
+// Submission is an atom of a capture that submits work to a queue, or that
+// synchronizes with the work submitted to queues.
+type Submission struct {
+	Atom    ID             // The submitting atom.
+	Kind    SubmissionKind // The kind of the submission.
+	Queue   uint64         // The queue handle, 0 if not submitted to a queue.
+	Waits   []uint64       // The semaphores waited on.
+	Signals []uint64       // The semaphores signaled.
+	Fences  []uint64       // The fences signaled, or waited on for FenceWait.
+}
+
+// SubmissionTracker is the interface implemented by APIs that can list the
+// queue submissions of a capture along with their synchronization
+// primitives.
+type SubmissionTracker interface {
+	// Submissions returns the submissions of the capture held by ctx, in atom
+	// order.
+	Submissions(ctx context.Context) ([]Submission, error)
+}
@@ -18,9 +18,15 @@
 # build and the file will be recreated, check in the new version.
 
 set(files
+    blocks.go
+    blocks_test.go
     capture.go
     capture.pb.go
     capture.proto
+    chunks.go
+    chunks_test.go
+    compression.go
+    compression_test.go
     context.go
     doc.go
     id.go
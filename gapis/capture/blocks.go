@@ -0,0 +1,390 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/core/app/benchmark"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+var (
+	decodeCounter       = benchmark.GlobalCounters.Duration("capture.decode")
+	decodeBlocksCounter = benchmark.GlobalCounters.Integer("capture.decode.blocks")
+	decodeBytesCounter  = benchmark.GlobalCounters.Integer("capture.decode.bytes")
+)
+
+// BlocksTag is the header tag of a capture file in the block format.
+//
+// A capture in the block format is a sequence of blocks, each holding a
+// complete pack stream that can be decoded independently of the others. Each
+// block is preceded by its length prefixed Block header, so a file that was
+// not closed can still be read block by block. Closing the file appends the
+// BlockIndex, followed by its offset as a little-endian uint64 and the
+// BlockIndexTag, so readers can seek straight to the blocks they need.
+const BlocksTag = "GapidBlocks_V2"
+
+// BlockIndexTag is the tag that ends a capture file in the block format
+// holding a block index.
+const BlockIndexTag = "GapidBlockIndex"
+
+// BlockKind is the kind of data held by a block.
+type BlockKind uint32
+
+const (
+	// AtomBlock holds commands along with their memory observations.
+	AtomBlock BlockKind = 1
+	// ResourceBlock holds the resources observed by the commands of the
+	// following atom blocks.
+	ResourceBlock BlockKind = 2
+)
+
+func (k BlockKind) String() string {
+	switch k {
+	case AtomBlock:
+		return "atoms"
+	case ResourceBlock:
+		return "resources"
+	default:
+		return fmt.Sprintf("BlockKind(%d)", uint32(k))
+	}
+}
+
+// DefaultBlockSize is the default size of the blocks written by a BlockWriter.
+const DefaultBlockSize = 1 << 20
+
+// BlockWriter writes atoms to a capture file in the block format. The blocks
+// are appended as they are filled, so the capture can be streamed.
+// Resources are written to resource blocks that precede the atom block
+// holding the next atoms, so they are decoded before the atoms using them.
+type BlockWriter struct {
+	out       io.Writer
+	size      int
+	offset    uint64
+	atoms     uint64
+	index     BlockIndex
+	pending   [2]pendingBlock
+	lastError error
+}
+
+type pendingBlock struct {
+	kind   BlockKind
+	buf    bytes.Buffer
+	writer atomWriter
+	first  uint64
+	count  uint64
+}
+
+// NewBlockWriter returns a BlockWriter writing to out blocks of about size
+// bytes. The BlockWriter must be closed to write the last blocks and the
+// index.
+func NewBlockWriter(out io.Writer, size int) (*BlockWriter, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("Block size must be greater than zero")
+	}
+	if _, err := out.Write([]byte(BlocksTag)); err != nil {
+		return nil, err
+	}
+	w := &BlockWriter{out: out, size: size, offset: uint64(len(BlocksTag))}
+	w.pending[0].kind = ResourceBlock
+	w.pending[1].kind = AtomBlock
+	return w, nil
+}
+
+// Write appends the atom a to the capture.
+func (w *BlockWriter) Write(ctx context.Context, a atom.Atom) error {
+	if w.lastError != nil {
+		return w.lastError
+	}
+	b := &w.pending[1]
+	if _, ok := a.(*atom.Resource); ok {
+		b = &w.pending[0]
+	}
+	if b.writer == nil {
+		writer, err := packWriter(&b.buf)
+		if err != nil {
+			return w.fail(err)
+		}
+		b.writer, b.first = writer, w.atoms
+	}
+	if err := b.writer(ctx, a); err != nil {
+		return w.fail(err)
+	}
+	b.count++
+	if b.kind == AtomBlock {
+		w.atoms++
+	}
+	if b.buf.Len() >= w.size {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close writes the pending blocks and the block index. It does not close the
+// underlying writer.
+func (w *BlockWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	data, err := proto.Marshal(&w.index)
+	if err != nil {
+		return err
+	}
+	trailer := make([]byte, 8)
+	binary.LittleEndian.PutUint64(trailer, w.offset)
+	for _, d := range [][]byte{data, trailer, []byte(BlockIndexTag)} {
+		if _, err := w.out.Write(d); err != nil {
+			return w.fail(err)
+		}
+	}
+	return nil
+}
+
+// flush writes the pending resource block, then the pending atom block.
+func (w *BlockWriter) flush() error {
+	for i := range w.pending {
+		b := &w.pending[i]
+		if b.count == 0 {
+			continue
+		}
+		block := &Block{
+			Kind:  uint32(b.kind),
+			Size:  uint64(b.buf.Len()),
+			First: b.first,
+			Count: b.count,
+		}
+		header, err := proto.Marshal(block)
+		if err != nil {
+			return w.fail(err)
+		}
+		prefix := make([]byte, binary.MaxVarintLen64)
+		prefix = prefix[:binary.PutUvarint(prefix, uint64(len(header)))]
+		for _, d := range [][]byte{prefix, header, b.buf.Bytes()} {
+			if _, err := w.out.Write(d); err != nil {
+				return w.fail(err)
+			}
+		}
+		block.Offset = w.offset + uint64(len(prefix)+len(header))
+		w.offset = block.Offset + block.Size
+		w.index.Blocks = append(w.index.Blocks, block)
+		b.buf.Reset()
+		b.writer, b.count = nil, 0
+	}
+	return nil
+}
+
+func (w *BlockWriter) fail(err error) error {
+	w.lastError = err
+	return err
+}
+
+// IsBlocks returns true if in starts with the block format header. The
+// position of in is restored.
+func IsBlocks(in io.ReadSeeker) bool {
+	header := make([]byte, len(BlocksTag))
+	_, err := io.ReadFull(in, header)
+	in.Seek(0, io.SeekStart)
+	return err == nil && string(header) == BlocksTag
+}
+
+// ReadBlockIndex returns the index of the blocks of the capture in the block
+// format in. If the capture was not closed, and so has no index, the blocks
+// are found by reading their headers.
+func ReadBlockIndex(in io.ReadSeeker) (*BlockIndex, error) {
+	if !IsBlocks(in) {
+		return nil, fmt.Errorf("The capture is not in the block format")
+	}
+	end, err := in.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	trailerSize := int64(8 + len(BlockIndexTag))
+	if end >= int64(len(BlocksTag))+trailerSize {
+		trailer := make([]byte, trailerSize)
+		if _, err := in.Seek(end-trailerSize, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(in, trailer); err != nil {
+			return nil, err
+		}
+		if string(trailer[8:]) == BlockIndexTag {
+			offset := int64(binary.LittleEndian.Uint64(trailer))
+			if offset > end-trailerSize {
+				return nil, fmt.Errorf("Invalid block index offset: %d", offset)
+			}
+			data := make([]byte, end-trailerSize-offset)
+			if _, err := in.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			if _, err := io.ReadFull(in, data); err != nil {
+				return nil, err
+			}
+			index := &BlockIndex{}
+			if err := proto.Unmarshal(data, index); err != nil {
+				return nil, err
+			}
+			return index, nil
+		}
+	}
+	return scanBlocks(in, end)
+}
+
+// scanBlocks returns the index of the complete blocks of in, read from their
+// headers.
+func scanBlocks(in io.ReadSeeker, end int64) (*BlockIndex, error) {
+	offset := int64(len(BlocksTag))
+	if _, err := in.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(in)
+	index := &BlockIndex{}
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			break // End of the file, or truncated header.
+		}
+		header := make([]byte, size)
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		block := &Block{}
+		if err := proto.Unmarshal(header, block); err != nil {
+			break
+		}
+		block.Offset = uint64(offset) + uint64(uvarintSize(size)) + size
+		if int64(block.Offset+block.Size) > end {
+			break // Truncated payload.
+		}
+		if _, err := r.Discard(int(block.Size)); err != nil {
+			break
+		}
+		offset = int64(block.Offset + block.Size)
+		index.Blocks = append(index.Blocks, block)
+	}
+	return index, nil
+}
+
+func uvarintSize(v uint64) int {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return binary.PutUvarint(buf, v)
+}
+
+// ReadBlock decodes the atoms, or resources, of the block b of in.
+func ReadBlock(ctx context.Context, in io.ReadSeeker, b *Block) (*atom.List, error) {
+	if _, err := in.Seek(int64(b.Offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ReadPack(ctx, io.LimitReader(in, int64(b.Size)))
+}
+
+// ReadBlocks converts the contents of a capture in the block format to an
+// atom list. The blocks are read in order, and decoded concurrently by one
+// goroutine per CPU. The decoded atoms are in the order of the blocks.
+func ReadBlocks(ctx context.Context, in io.ReadSeeker) (*atom.List, error) {
+	t0 := decodeCounter.Start()
+	defer decodeCounter.Stop(t0)
+
+	index, err := ReadBlockIndex(in)
+	if err != nil {
+		return nil, err
+	}
+
+	type job struct {
+		index int
+		data  []byte
+	}
+	results := make([]*atom.List, len(index.Blocks))
+	errs := make([]error, len(index.Blocks))
+	workers := runtime.NumCPU()
+	jobs := make(chan job, workers) // Bounds the payloads held in memory.
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j.index], errs[j.index] = ReadPack(ctx, bytes.NewReader(j.data))
+			}
+		}()
+	}
+
+	for i, b := range index.Blocks {
+		data := make([]byte, b.Size)
+		if _, err = in.Seek(int64(b.Offset), io.SeekStart); err == nil {
+			_, err = io.ReadFull(in, data)
+		}
+		if err != nil {
+			break
+		}
+		decodeBlocksCounter.Increment()
+		decodeBytesCounter.AddInt64(int64(b.Size))
+		jobs <- job{i, data}
+	}
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for i, l := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		count += len(l.Atoms)
+	}
+	list := atom.NewList(make([]atom.Atom, 0, count)...)
+	for _, l := range results {
+		list.Atoms = append(list.Atoms, l.Atoms...)
+	}
+	return list, nil
+}
+
+// WriteBlocks writes the supplied atoms directly to the writer in the block
+// format.
+func WriteBlocks(ctx context.Context, atoms *atom.List, w io.Writer) error {
+	writer, err := NewBlockWriter(w, DefaultBlockSize)
+	if err != nil {
+		return err
+	}
+	if err := writeAll(ctx, atoms, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// ExportBlocks encodes the given capture and associated resources and writes
+// it to the supplied io.Writer in the block format, producing output suitable
+// for use with Import.
+func ExportBlocks(ctx context.Context, p *path.Capture, w io.Writer) error {
+	writer, err := NewBlockWriter(w, DefaultBlockSize)
+	if err != nil {
+		return err
+	}
+	if err := export(ctx, p, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+)
+
+func TestBlocksRoundTrip(t *testing.T) {
+	ctx := log.Testing(t)
+	list := atom.NewList()
+	for i := 0; i < 5; i++ {
+		data := bytes.Repeat([]byte{byte(i)}, 16)
+		list.Add(&atom.Resource{ID: id.OfBytes(data), Data: data})
+	}
+
+	buf := &bytes.Buffer{}
+	w, err := capture.NewBlockWriter(buf, 32)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	for _, a := range list.Atoms {
+		assert.With(ctx).ThatError(w.Write(ctx, a)).Succeeded()
+	}
+	// Each resource fills a block, so all the blocks are written before the
+	// index and can be read without it.
+	unclosed := append([]byte{}, buf.Bytes()...)
+	assert.With(ctx).ThatError(w.Close()).Succeeded()
+
+	in := bytes.NewReader(buf.Bytes())
+	assert.With(ctx).That(capture.IsBlocks(in)).Equals(true)
+	index, err := capture.ReadBlockIndex(in)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).ThatSlice(index.Blocks).IsLength(len(list.Atoms))
+	for _, b := range index.Blocks {
+		assert.With(ctx).That(capture.BlockKind(b.Kind)).Equals(capture.ResourceBlock)
+		assert.With(ctx).That(b.Count).Equals(uint64(1))
+	}
+
+	got, err := capture.ReadBlocks(ctx, in)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).ThatSlice(got.Atoms).IsLength(len(list.Atoms))
+	for i, a := range got.Atoms {
+		r := a.(*atom.Resource)
+		assert.For(ctx, "resource %d", i).ThatSlice(r.Data).Equals(list.Atoms[i].(*atom.Resource).Data)
+	}
+
+	scanned, err := capture.ReadBlockIndex(bytes.NewReader(unclosed))
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).ThatSlice(scanned.Blocks).IsLength(len(index.Blocks))
+	for i, b := range scanned.Blocks {
+		assert.For(ctx, "block %d", i).That(b.Offset).Equals(index.Blocks[i].Offset)
+	}
+}
+
+func BenchmarkReadBlocks(b *testing.B) {
+	ctx := log.Testing(b)
+	buf := &bytes.Buffer{}
+	w, err := capture.NewBlockWriter(buf, 64<<10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 4096; i++ {
+		data := bytes.Repeat([]byte{byte(i), byte(i >> 8)}, 512)
+		w.Write(ctx, &atom.Resource{ID: id.OfBytes(data), Data: data})
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := capture.ReadBlocks(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -111,6 +111,20 @@ func Captures() []*path.Capture {
 	return out
 }
 
+// register adds the capture with the identifier id to the list of captures.
+// Loading the same capture data twice yields the same identifier, which is
+// only listed once.
+func register(id id.ID) {
+	capturesLock.Lock()
+	defer capturesLock.Unlock()
+	for _, c := range captures {
+		if c == id {
+			return
+		}
+	}
+	captures = append(captures, id)
+}
+
 // ResolveFromID resolves a single capture with the ID id.
 func ResolveFromID(ctx context.Context, id id.ID) (*Capture, error) {
 	obj, err := database.Resolve(ctx, id)
@@ -185,15 +199,47 @@ func ImportAtomList(ctx context.Context, name string, a *atom.List) (*path.Captu
 		return nil, err
 	}
 
-	capturesLock.Lock()
-	captures = append(captures, captureID)
-	capturesLock.Unlock()
+	register(captureID)
+
+	return &path.Capture{Id: path.NewID(captureID)}, nil
+}
+
+// Derive builds a new capture with the device, APIs and observed memory of c,
+// holding the atom list identified by commands, stores it into d and returns
+// the new capture path. commands may identify a database.Resolvable building
+// the atom list.
+func (c *Capture) Derive(ctx context.Context, name string, commands id.ID) (*path.Capture, error) {
+	derived := &Capture{
+		Name:     name,
+		Device:   c.Device,
+		Apis:     c.Apis,
+		Commands: NewID(commands),
+		Observed: c.Observed,
+	}
+
+	captureID, err := database.Store(ctx, derived)
+	if err != nil {
+		return nil, err
+	}
+
+	register(captureID)
 
 	return &path.Capture{Id: path.NewID(captureID)}, nil
 }
 
 // ReadAny attempts to auto detect the capture stream type and read it.
+// Compressed captures are decompressed as they are read.
 func ReadAny(ctx context.Context, in io.ReadSeeker) (*atom.List, error) {
+	if IsBlocks(in) {
+		return ReadBlocks(ctx, in)
+	}
+	if IsCompressed(in) {
+		r, err := Decompress(in)
+		if err != nil {
+			return nil, err
+		}
+		return ReadPack(ctx, r)
+	}
 	atoms, err := ReadPack(ctx, in)
 	switch err {
 	case nil:
@@ -414,7 +460,20 @@ func process(ctx context.Context, a *atom.List) (*atom.List, []*MemoryRange, err
 
 		switch a := a.(type) {
 		case *atom.Resource:
-			id, err := database.Store(ctx, a.Data)
+			var data interface{} = a.Data
+			if len(a.Chunks) > 0 {
+				// Chunked resources are reassembled from their chunks on demand.
+				chunks := make([]id.ID, len(a.Chunks))
+				for i, c := range a.Chunks {
+					chunk, found := idmap[c]
+					if !found {
+						return nil, nil, log.Errf(ctx, nil, "Resource %v references unknown chunk: %v", a.ID, c)
+					}
+					chunks[i] = chunk
+				}
+				data = memory.NewChunkedResolvable(chunks)
+			}
+			id, err := database.Store(ctx, data)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -0,0 +1,235 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// ChunkIndexTag is the header tag of a chunked capture index file.
+const ChunkIndexTag = "GapidChunkIndex_V1"
+
+// ChunkWriter is an io.WriteCloser that streams a capture to disk as a
+// sequence of chunk files of bounded size, described by an index file.
+// The index is rewritten each time a chunk is completed, so a capture that is
+// interrupted can still be loaded up to its last complete chunk.
+type ChunkWriter struct {
+	path    string
+	size    uint64
+	index   ChunkIndex
+	current *os.File
+}
+
+// NewChunkWriter returns a ChunkWriter that writes the index to path, and
+// the chunks of at most size bytes to files next to it.
+func NewChunkWriter(path string, size uint64) (*ChunkWriter, error) {
+	if size == 0 {
+		return nil, fmt.Errorf("Chunk size must be greater than zero")
+	}
+	w := &ChunkWriter{path: path, size: size}
+	if err := w.writeIndex(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, splitting data across chunks.
+func (w *ChunkWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		if w.current == nil {
+			if err := w.nextChunk(); err != nil {
+				return written, err
+			}
+		}
+		chunk := w.index.Chunks[len(w.index.Chunks)-1]
+		count := uint64(len(data))
+		if space := w.size - chunk.Size; count > space {
+			count = space
+		}
+		n, err := w.current.Write(data[:count])
+		chunk.Size += uint64(n)
+		written += n
+		data = data[n:]
+		if err != nil {
+			return written, err
+		}
+		if chunk.Size >= w.size {
+			if err := w.finishChunk(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close completes the current chunk and writes the final index.
+func (w *ChunkWriter) Close() error {
+	if w.current != nil {
+		return w.finishChunk()
+	}
+	return w.writeIndex()
+}
+
+func (w *ChunkWriter) nextChunk() error {
+	name := fmt.Sprintf("%s.%04d", filepath.Base(w.path), len(w.index.Chunks))
+	f, err := os.Create(filepath.Join(filepath.Dir(w.path), name))
+	if err != nil {
+		return err
+	}
+	w.current = f
+	w.index.Chunks = append(w.index.Chunks, &Chunk{Path: name})
+	return nil
+}
+
+func (w *ChunkWriter) finishChunk() error {
+	err := w.current.Close()
+	w.current = nil
+	if err != nil {
+		return err
+	}
+	return w.writeIndex()
+}
+
+func (w *ChunkWriter) writeIndex() error {
+	data, err := proto.Marshal(&w.index)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBufferString(ChunkIndexTag)
+	buf.Write(data)
+	// Write to a temporary file first so the index is never left truncated.
+	tmp := w.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
+
+// IsChunkIndex returns true if the file at path is the index of a capture
+// streamed to disk in chunks.
+func IsChunkIndex(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	tag := make([]byte, len(ChunkIndexTag))
+	if _, err := io.ReadFull(f, tag); err != nil {
+		return false
+	}
+	return string(tag) == ChunkIndexTag
+}
+
+// ReadChunkIndex reads the chunk index file at path.
+func ReadChunkIndex(path string) (*ChunkIndex, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(ChunkIndexTag)) {
+		return nil, fmt.Errorf("%v is not a chunk index", path)
+	}
+	index := &ChunkIndex{}
+	if err := proto.Unmarshal(data[len(ChunkIndexTag):], index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// OpenChunks returns a reader of the capture stream described by the chunk
+// index at path. Only one chunk file is open at any time.
+func OpenChunks(path string) (io.ReadCloser, error) {
+	index, err := ReadChunkIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkReader{dir: filepath.Dir(path), chunks: index.Chunks}, nil
+}
+
+type chunkReader struct {
+	dir     string
+	chunks  []*Chunk
+	current io.Reader
+	file    *os.File
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.file == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			chunk := r.chunks[0]
+			r.chunks = r.chunks[1:]
+			f, err := os.Open(filepath.Join(r.dir, chunk.Path))
+			if err != nil {
+				return 0, err
+			}
+			// Ignore any data written after the chunk was indexed.
+			r.file, r.current = f, io.LimitReader(f, int64(chunk.Size))
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.Close()
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file, r.current = nil, nil
+	return err
+}
+
+// ImportChunks imports the capture streamed to disk in chunks, described by
+// the chunk index file. The chunks are decoded as they are read, so the raw
+// capture data is never held in memory as a whole.
+func ImportChunks(ctx context.Context, name string, index string) (*path.Capture, error) {
+	in, err := OpenChunks(index)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	r, err := Decompress(in)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReadPack(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Atoms) == 0 {
+		return nil, nil
+	}
+	return ImportAtomList(ctx, name, list)
+}
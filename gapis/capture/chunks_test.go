@@ -0,0 +1,60 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/capture"
+)
+
+func TestChunksRoundTrip(t *testing.T) {
+	ctx := log.Testing(t)
+	dir, err := ioutil.TempDir("", "chunks")
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	index := filepath.Join(dir, "trace.gfxtrace")
+	w, err := capture.NewChunkWriter(index, 4)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	w.Write([]byte("0123456"))
+	w.Write([]byte("789ab"))
+	assert.With(ctx).ThatError(w.Close()).Succeeded()
+
+	assert.With(ctx).That(capture.IsChunkIndex(index)).Equals(true)
+	i, err := capture.ReadChunkIndex(index)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).ThatSlice(i.Chunks).IsLength(3)
+
+	r, err := capture.OpenChunks(index)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).ThatString(string(data)).Equals("0123456789ab")
+}
@@ -0,0 +1,148 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"bufio"
+	"compress/flate"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CompressedTag is the header tag of a compressed capture file. The tag is
+// followed by a single byte holding the Codec of the compressed stream.
+const CompressedTag = "GapidCompressed_V1"
+
+// Codec identifies the compression algorithm of a compressed capture file.
+type Codec byte
+
+const (
+	// Uncompressed captures do not have the compressed header.
+	Uncompressed Codec = 0
+	// Deflate is the DEFLATE algorithm, as implemented by compress/flate.
+	Deflate Codec = 1
+	// LZ4 is the LZ4 frame format. It requires a registered implementation.
+	LZ4 Codec = 2
+	// Zstd is the Zstandard format. It requires a registered implementation.
+	Zstd Codec = 3
+)
+
+// CodecImpl is the implementation of a compression codec.
+type CodecImpl struct {
+	// Name is the name used to select the codec.
+	Name string
+	// NewWriter returns a writer compressing to w.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing from r.
+	NewReader func(r io.Reader) (io.Reader, error)
+}
+
+var codecNames = map[Codec]string{
+	Uncompressed: "none",
+	Deflate:      "deflate",
+	LZ4:          "lz4",
+	Zstd:         "zstd",
+}
+
+var codecs = map[Codec]*CodecImpl{
+	Deflate: {
+		Name: "deflate",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			// Favour speed as captures are compressed while the application runs.
+			return flate.NewWriter(w, flate.BestSpeed)
+		},
+		NewReader: func(r io.Reader) (io.Reader, error) {
+			return flate.NewReader(r), nil
+		},
+	},
+}
+
+// RegisterCodec registers the implementation of the codec c.
+func RegisterCodec(c Codec, impl *CodecImpl) {
+	codecs[c] = impl
+	codecNames[c] = impl.Name
+}
+
+func (c Codec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Codec(%d)", byte(c))
+}
+
+// ParseCodec returns the codec with the given name.
+func ParseCodec(name string) (Codec, error) {
+	names := []string{}
+	for c, n := range codecNames {
+		if n == name {
+			return c, nil
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return Uncompressed, fmt.Errorf("Unknown codec '%v'. Valid codecs: %v", name, strings.Join(names, ", "))
+}
+
+func (c Codec) impl() (*CodecImpl, error) {
+	impl, ok := codecs[c]
+	if !ok {
+		return nil, fmt.Errorf("The %v codec is not supported by this build", c)
+	}
+	return impl, nil
+}
+
+// NewCompressor returns a writer that writes the compressed header for the
+// codec c to w, followed by the data written to it compressed with c.
+// The returned writer must be closed to flush the compressed stream, this
+// does not close w.
+func NewCompressor(w io.Writer, c Codec) (io.WriteCloser, error) {
+	impl, err := c.impl()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append([]byte(CompressedTag), byte(c))); err != nil {
+		return nil, err
+	}
+	return impl.NewWriter(w)
+}
+
+// Decompress returns a reader of the decompressed data of in if in starts
+// with the compressed header. Otherwise Decompress returns a reader of the
+// unmodified data of in.
+func Decompress(in io.Reader) (io.Reader, error) {
+	r := bufio.NewReader(in)
+	header, err := r.Peek(len(CompressedTag) + 1)
+	if err != nil || string(header[:len(CompressedTag)]) != CompressedTag {
+		// Too short or uncompressed, let the format reader report any error.
+		return r, nil
+	}
+	impl, err := Codec(header[len(CompressedTag)]).impl()
+	if err != nil {
+		return nil, err
+	}
+	r.Discard(len(header))
+	return impl.NewReader(r)
+}
+
+// IsCompressed returns true if in starts with the compressed header. The
+// position of in is restored.
+func IsCompressed(in io.ReadSeeker) bool {
+	header := make([]byte, len(CompressedTag))
+	_, err := io.ReadFull(in, header)
+	in.Seek(0, io.SeekStart)
+	return err == nil && string(header) == CompressedTag
+}
@@ -0,0 +1,69 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/capture"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	ctx := log.Testing(t)
+	data := bytes.Repeat([]byte("protopack"), 100)
+
+	buf := &bytes.Buffer{}
+	w, err := capture.NewCompressor(buf, capture.Deflate)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	w.Write(data)
+	assert.With(ctx).ThatError(w.Close()).Succeeded()
+	assert.With(ctx).That(buf.Len() < len(data)).Equals(true)
+	assert.With(ctx).That(capture.IsCompressed(bytes.NewReader(buf.Bytes()))).Equals(true)
+
+	r, err := capture.Decompress(buf)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	got, err := ioutil.ReadAll(r)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).ThatSlice(got).Equals(data)
+}
+
+func TestDecompressUncompressed(t *testing.T) {
+	ctx := log.Testing(t)
+	r, err := capture.Decompress(bytes.NewReader([]byte("protopack")))
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	got, err := ioutil.ReadAll(r)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).ThatString(string(got)).Equals("protopack")
+}
+
+func TestUnsupportedCodec(t *testing.T) {
+	ctx := log.Testing(t)
+	_, err := capture.NewCompressor(&bytes.Buffer{}, capture.Zstd)
+	assert.With(ctx).ThatError(err).Failed()
+
+	codec, err := capture.ParseCodec("lz4")
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).That(codec).Equals(capture.LZ4)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/google/gapid/core/log/log_pb"
 	"github.com/google/gapid/core/net/grpcutil"
 	"github.com/google/gapid/framework/binary/schema"
+	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/gfxapi"
 	"github.com/google/gapid/gapis/service"
 	"github.com/google/gapid/gapis/service/path"
@@ -131,6 +132,17 @@ func (c *client) GetPerformanceCounters(ctx context.Context) ([]byte, error) {
 	return res.GetData(), nil
 }
 
+func (c *client) CancelRequests(ctx context.Context, ids []string) (uint32, error) {
+	res, err := c.client.CancelRequests(ctx, &service.CancelRequestsRequest{Ids: ids})
+	if err != nil {
+		return 0, err
+	}
+	if err := res.GetError(); err != nil {
+		return 0, err.Get()
+	}
+	return res.GetCancelled(), nil
+}
+
 func (c *client) GetProfile(ctx context.Context, name string, debug int32) ([]byte, error) {
 	res, err := c.client.GetProfile(ctx, &service.GetProfileRequest{
 		Name:  name,
@@ -221,6 +233,17 @@ func (c *client) LoadCapture(ctx context.Context, path string) (*path.Capture, e
 	return res.GetCapture(), nil
 }
 
+func (c *client) GetCaptures(ctx context.Context) (*service.LoadedCaptures, error) {
+	res, err := c.client.GetCaptures(ctx, &service.GetCapturesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCaptures(), nil
+}
+
 func (c *client) GetDevices(ctx context.Context) ([]*path.Device, error) {
 	res, err := c.client.GetDevices(ctx, &service.GetDevicesRequest{})
 	if err != nil {
@@ -269,6 +292,489 @@ func (c *client) GetFramebufferAttachment(
 	return res.GetImage(), nil
 }
 
+func (c *client) ImportCrashDump(ctx context.Context, p *path.Capture, data []byte) (*service.CrashDumpCorrelation, error) {
+	res, err := c.client.ImportCrashDump(ctx, &service.ImportCrashDumpRequest{
+		Capture: p,
+		Data:    data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCorrelation(), nil
+}
+
+func (c *client) ExportRenderDocEvents(ctx context.Context, p *path.Capture) (*service.RenderDocEvents, error) {
+	res, err := c.client.ExportRenderDocEvents(ctx, &service.ExportRenderDocEventsRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetEvents(), nil
+}
+
+func (c *client) ExportPerfettoTrace(ctx context.Context, p *path.Capture, d *path.Device) ([]byte, error) {
+	res, err := c.client.ExportPerfettoTrace(ctx, &service.ExportPerfettoTraceRequest{
+		Capture: p,
+		Device:  d,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetData(), nil
+}
+
+func (c *client) ExportTexture(ctx context.Context, r *path.ResourceData, t service.TextureContainer) ([]byte, error) {
+	res, err := c.client.ExportTexture(ctx, &service.ExportTextureRequest{
+		Resource:  r,
+		Container: t,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetData(), nil
+}
+
+func (c *client) GetExperiments(ctx context.Context) ([]*service.ExperimentInfo, error) {
+	res, err := c.client.GetExperiments(ctx, &service.GetExperimentsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetExperiments().List, nil
+}
+
+func (c *client) RunExperiment(ctx context.Context, r *service.ExperimentRequest) (*service.ExperimentResult, error) {
+	res, err := c.client.RunExperiment(ctx, &service.RunExperimentRequest{
+		Request: r,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetResult(), nil
+}
+
+func (c *client) GetExperimentResults(ctx context.Context, p *path.Capture) ([]*service.ExperimentResult, error) {
+	res, err := c.client.GetExperimentResults(ctx, &service.GetExperimentResultsRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetResults().Results, nil
+}
+
+func (c *client) ExtractMinimalRepro(ctx context.Context, p *path.Command) (*path.Capture, error) {
+	res, err := c.client.ExtractMinimalRepro(ctx, &service.ExtractMinimalReproRequest{
+		Command: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCapture(), nil
+}
+
+func (c *client) TrimCapture(ctx context.Context, p *path.Capture, firstFrame, lastFrame uint64) (*path.Capture, error) {
+	res, err := c.client.TrimCapture(ctx, &service.TrimCaptureRequest{
+		Capture:    p,
+		FirstFrame: firstFrame,
+		LastFrame:  lastFrame,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCapture(), nil
+}
+
+func (c *client) GetFrameDeltas(ctx context.Context, p *path.Capture) (*service.FrameDeltas, error) {
+	res, err := c.client.GetFrameDeltas(ctx, &service.GetFrameDeltasRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetDeltas(), nil
+}
+
+func (c *client) DiffCaptures(ctx context.Context, a, b *path.Capture) (*service.CaptureDiff, error) {
+	res, err := c.client.DiffCaptures(ctx, &service.DiffCapturesRequest{
+		A: a,
+		B: b,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetDiff(), nil
+}
+
+func (c *client) GetStateDiff(ctx context.Context, from, to *path.Command) (*service.StateDiff, error) {
+	res, err := c.client.GetStateDiff(ctx, &service.GetStateDiffRequest{
+		From: from,
+		To:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetDiff(), nil
+}
+
+func (c *client) SpliceCommands(ctx context.Context, p *path.Capture, r *service.CommandRange, commands *atom.List) (*path.Capture, error) {
+	res, err := c.client.SpliceCommands(ctx, &service.SpliceCommandsRequest{
+		Capture:  p,
+		Range:    r,
+		Commands: service.NewValue(commands),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCapture(), nil
+}
+
+func (c *client) GetMemoryProvenance(ctx context.Context, p *path.Memory) (*service.MemoryProvenance, error) {
+	res, err := c.client.GetMemoryProvenance(ctx, &service.GetMemoryProvenanceRequest{
+		Memory: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetProvenance(), nil
+}
+
+func (c *client) GetBufferView(ctx context.Context, p *path.Memory, l *service.BufferLayout) (*service.BufferView, error) {
+	res, err := c.client.GetBufferView(ctx, &service.GetBufferViewRequest{
+		Memory: p,
+		Layout: l,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetView(), nil
+}
+
+func (c *client) SearchCommands(ctx context.Context, p *path.Capture, query string, handler func([]uint64) error) error {
+	stream, err := c.client.SearchCommands(ctx, &service.SearchCommandsRequest{
+		Capture: p,
+		Query:   query,
+	})
+	if err != nil {
+		return err
+	}
+	h := func(ctx context.Context, res *service.SearchCommandsResponse) error {
+		if err := res.GetError(); err != nil {
+			return err.Get()
+		}
+		return handler(res.GetResults().Commands)
+	}
+	return event.Feed(ctx, event.AsHandler(ctx, h), grpcutil.ToProducer(stream))
+}
+
+func (c *client) GetPixelHistory(
+	ctx context.Context,
+	dev *path.Device,
+	p *path.Capture,
+	r *service.CommandRange,
+	att gfxapi.FramebufferAttachment,
+	x, y uint32) (*service.PixelHistory, error) {
+
+	res, err := c.client.GetPixelHistory(ctx, &service.GetPixelHistoryRequest{
+		Device:     dev,
+		Capture:    p,
+		Range:      r,
+		Attachment: att,
+		X:          x,
+		Y:          y,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetHistory(), nil
+}
+
+func (c *client) GetResourceTimeline(ctx context.Context, p *path.Capture, id *path.ID) (*service.ResourceTimeline, error) {
+	res, err := c.client.GetResourceTimeline(ctx, &service.GetResourceTimelineRequest{
+		Capture: p,
+		Id:      id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetTimeline(), nil
+}
+
+func (c *client) GetDispatchSnapshot(ctx context.Context, d *path.Device, p *path.Command) (*service.DispatchSnapshot, error) {
+	res, err := c.client.GetDispatchSnapshot(ctx, &service.GetDispatchSnapshotRequest{
+		Device:  d,
+		Command: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetSnapshot(), nil
+}
+
+func (c *client) GetTimingProfile(ctx context.Context, d *path.Device, p *path.Capture) (*service.TimingProfile, error) {
+	res, err := c.client.GetTimingProfile(ctx, &service.GetTimingProfileRequest{
+		Device:  d,
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetProfile(), nil
+}
+
+func (c *client) GetPipelineStatistics(ctx context.Context, d *path.Device, p *path.Capture) (*service.PipelineStatistics, error) {
+	res, err := c.client.GetPipelineStatistics(ctx, &service.GetPipelineStatisticsRequest{
+		Device:  d,
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetStatistics(), nil
+}
+
+func (c *client) GetFrameLoopProfile(ctx context.Context, d *path.Device, p *path.Capture, frame, iterations uint32, experiments []string, parameters map[string]string) (*service.FrameLoopProfile, error) {
+	res, err := c.client.GetFrameLoopProfile(ctx, &service.GetFrameLoopProfileRequest{
+		Device:      d,
+		Capture:     p,
+		Frame:       frame,
+		Iterations:  iterations,
+		Experiments: experiments,
+		Parameters:  parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetProfile(), nil
+}
+
+func (c *client) GetLeakReport(ctx context.Context, p *path.Capture) (*service.LeakReport, error) {
+	res, err := c.client.GetLeakReport(ctx, &service.GetLeakReportRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetReport(), nil
+}
+
+func (c *client) GetLintReport(ctx context.Context, p *path.Capture) (*service.LintReport, error) {
+	res, err := c.client.GetLintReport(ctx, &service.GetLintReportRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetReport(), nil
+}
+
+func (c *client) ExportCpp(ctx context.Context, d *path.Device, p *path.Capture, frame uint32) ([]byte, error) {
+	res, err := c.client.ExportCpp(ctx, &service.ExportCppRequest{
+		Device:  d,
+		Capture: p,
+		Frame:   frame,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetSource(), nil
+}
+
+func (c *client) ExportCommands(ctx context.Context, p *path.Capture, data bool, handler func(*service.ExportedCommands) error) error {
+	stream, err := c.client.ExportCommands(ctx, &service.ExportCommandsRequest{
+		Capture: p,
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+	h := func(ctx context.Context, res *service.ExportCommandsResponse) error {
+		if err := res.GetError(); err != nil {
+			return err.Get()
+		}
+		return handler(res.GetCommands())
+	}
+	return event.Feed(ctx, event.AsHandler(ctx, h), grpcutil.ToProducer(stream))
+}
+
+func (c *client) SanitizeCapture(ctx context.Context, p *path.Capture, opts *service.SanitizeOptions) (*path.Capture, error) {
+	res, err := c.client.SanitizeCapture(ctx, &service.SanitizeCaptureRequest{
+		Capture: p,
+		Options: opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCapture(), nil
+}
+
+func (c *client) EditCommands(ctx context.Context, p *path.Capture, edits []*service.CommandEdit) (*path.Capture, error) {
+	res, err := c.client.EditCommands(ctx, &service.EditCommandsRequest{
+		Capture: p,
+		Edits:   edits,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetCapture(), nil
+}
+
+func (c *client) GetCommandTracks(ctx context.Context, p *path.Capture) (*service.CommandTracks, error) {
+	res, err := c.client.GetCommandTracks(ctx, &service.GetCommandTracksRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetTracks(), nil
+}
+
+func (c *client) GetSubmissionTimeline(ctx context.Context, p *path.Capture) (*service.SubmissionTimeline, error) {
+	res, err := c.client.GetSubmissionTimeline(ctx, &service.GetSubmissionTimelineRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetTimeline(), nil
+}
+
+func (c *client) GetFrameGraph(ctx context.Context, p *path.Capture) (*service.FrameGraph, error) {
+	res, err := c.client.GetFrameGraph(ctx, &service.GetFrameGraphRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetGraph(), nil
+}
+
+func (c *client) GetRedundancyReport(ctx context.Context, p *path.Capture) (*service.RedundancyReport, error) {
+	res, err := c.client.GetRedundancyReport(ctx, &service.GetRedundancyReportRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetReport(), nil
+}
+
+func (c *client) GetShaderAnalysis(ctx context.Context, p *path.Capture) (*service.ShaderAnalysis, error) {
+	res, err := c.client.GetShaderAnalysis(ctx, &service.GetShaderAnalysisRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetAnalysis(), nil
+}
+
+func (c *client) GetMemoryProfile(ctx context.Context, p *path.Capture) (*service.MemoryProfile, error) {
+	res, err := c.client.GetMemoryProfile(ctx, &service.GetMemoryProfileRequest{
+		Capture: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.GetError(); err != nil {
+		return nil, err.Get()
+	}
+	return res.GetProfile(), nil
+}
+
 func (c *client) GetLogStream(ctx context.Context, handler log.Handler) error {
 	stream, err := c.client.GetLogStream(ctx, &service.GetLogStreamRequest{})
 	if err != nil {
@@ -280,3 +786,15 @@ func (c *client) GetLogStream(ctx context.Context, handler log.Handler) error {
 	}
 	return event.Feed(ctx, event.AsHandler(ctx, h), grpcutil.ToProducer(stream))
 }
+
+func (c *client) GetReplayStatusStream(ctx context.Context, handler func(*service.ReplayStatus)) error {
+	stream, err := c.client.GetReplayStatusStream(ctx, &service.GetReplayStatusStreamRequest{})
+	if err != nil {
+		return err
+	}
+	h := func(ctx context.Context, status *service.ReplayStatus) error {
+		handler(status)
+		return nil
+	}
+	return event.Feed(ctx, event.AsHandler(ctx, h), grpcutil.ToProducer(stream))
+}
@@ -16,6 +16,7 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 
 	"github.com/google/gapid/core/app/auth"
@@ -28,6 +29,7 @@ import (
 	"github.com/google/gapid/framework/binary/registry"
 	"github.com/google/gapid/framework/binary/schema"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -72,6 +74,11 @@ type Config struct {
 	Port  int
 	Args  []string
 	Token auth.Token
+	// Host is the host of the GAPIS process to connect to when Port is not
+	// zero. If empty, the process is on the local host.
+	Host string
+	// TLS, if not nil, is the configuration used to connect over TLS.
+	TLS *tls.Config
 }
 
 // Connect attempts to connect to a GAPIS process.
@@ -100,11 +107,20 @@ func Connect(ctx context.Context, cfg Config) (Client, *schema.Message, error) {
 		}
 	}
 
-	target := fmt.Sprintf("localhost:%d", cfg.Port)
+	host := cfg.Host
+	if host == "" {
+		host = "localhost"
+	}
+	target := fmt.Sprintf("%v:%d", host, cfg.Port)
 
+	security := grpc.WithInsecure()
+	if cfg.TLS != nil {
+		security = grpc.WithTransportCredentials(credentials.NewTLS(cfg.TLS))
+	}
 	conn, err := grpcutil.Dial(ctx, target,
-		grpc.WithInsecure(),
-		grpc.WithUnaryInterceptor(auth.ClientInterceptor(cfg.Token)))
+		security,
+		grpc.WithUnaryInterceptor(auth.ClientInterceptor(cfg.Token)),
+		grpc.WithStreamInterceptor(auth.StreamClientInterceptor(cfg.Token)))
 	if err != nil {
 		return nil, nil, log.Err(ctx, err, "Dialing GAPIS")
 	}
@@ -20,10 +20,17 @@ const (
 	DebugReplay                = false
 	DebugReplayBuilder         = false
 	DisableDeadCodeElimination = false
+	DisableStatePriming        = false // Replays all the commands before the frame of the requests
 	DebugDeadCodeElimination   = false
+	PerSubresourceImageDCE     = true  // Tracks image mip levels and array layers individually in the dependency graph
 	LogExtrasInTransforms      = false // Logs all atoms' extras together with transforms
 	LogMemoryInExtras          = false // Logs all atoms' read/write memory observation together with extras
 	LogTransformsToFile        = false
 	UseGlslang                 = false
 	SeparateMutateStates       = false
 )
+
+// Options that can be changed at startup, before any capture is loaded.
+var (
+	PerDescriptorBindlessDCE = true // Tracks update-after-bind descriptors individually in the dependency graph
+)
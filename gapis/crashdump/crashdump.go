@@ -0,0 +1,79 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crashdump
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Dump holds the information extracted from a vendor GPU crash dump.
+type Dump struct {
+	// Vendor is the name of the tool or driver that produced the dump.
+	Vendor string `json:"vendor"`
+	// Device is the name of the GPU that crashed.
+	Device string `json:"device"`
+	// FaultAddress is the GPU virtual address that faulted, or 0 if the crash
+	// was not caused by a page fault.
+	FaultAddress uint64 `json:"fault_address"`
+	// Markers is the stack of debug markers that were active when the GPU
+	// crashed, outermost first.
+	Markers []string `json:"markers"`
+	// Resources is the list of resources the dump reports as in-flight at the
+	// time of the crash.
+	Resources []Resource `json:"resources"`
+}
+
+// Resource describes a single resource reported by the crash dump.
+type Resource struct {
+	// Type is the vendor's name for the resource type. e.g. "Image".
+	Type string `json:"type"`
+	// Handle is the API handle of the resource.
+	Handle uint64 `json:"handle"`
+	// Address is the GPU virtual address the resource was bound to.
+	Address uint64 `json:"address"`
+	// Size is the size in bytes of the resource's memory binding.
+	Size uint64 `json:"size"`
+}
+
+// Contains returns true if addr lies within the memory bound to r.
+func (r Resource) Contains(addr uint64) bool {
+	return r.Size > 0 && addr >= r.Address && addr-r.Address < r.Size
+}
+
+// Parse decodes the crash dump from data.
+func Parse(data []byte) (*Dump, error) {
+	d := &Dump{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("Failed to decode crash dump: %v", err)
+	}
+	return d, nil
+}
+
+// Faulting returns the resources whose memory contains the fault address.
+// If the dump has no fault address, then all the reported resources are
+// returned.
+func (d *Dump) Faulting() []Resource {
+	if d.FaultAddress == 0 {
+		return d.Resources
+	}
+	out := []Resource{}
+	for _, r := range d.Resources {
+		if r.Contains(d.FaultAddress) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crashdump_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/crashdump"
+)
+
+const testDump = `{
+	"vendor": "test",
+	"fault_address": 4128,
+	"markers": ["Frame 2", "Shadows"],
+	"resources": [
+		{"type": "Image", "handle": 10, "address": 4096, "size": 64},
+		{"type": "Buffer", "handle": 11, "address": 8192, "size": 64}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	ctx := log.Testing(t)
+	d, err := crashdump.Parse([]byte(testDump))
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).That(d.Vendor).Equals("test")
+	assert.With(ctx).ThatSlice(d.Markers).Equals([]string{"Frame 2", "Shadows"})
+	assert.With(ctx).ThatSlice(d.Faulting()).Equals([]crashdump.Resource{
+		{Type: "Image", Handle: 10, Address: 4096, Size: 64},
+	})
+}
+
+func TestParseInvalid(t *testing.T) {
+	ctx := log.Testing(t)
+	_, err := crashdump.Parse([]byte("not a dump"))
+	assert.With(ctx).ThatError(err).Failed()
+}
@@ -19,9 +19,16 @@
 
 set(files
     database.go
+    disk.go
     hash.go
     memory.go
+    memory_test.go
+    mmap_unix.go
+    mmap_windows.go
+    priority.go
+    priority_test.go
     resolvable.go
+    size.go
 )
 set(dirs
     
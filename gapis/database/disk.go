@@ -0,0 +1,111 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/google/gapid/core/data/id"
+)
+
+// spillThreshold is the minimum size in bytes of a resolved value for it to
+// be written to disk on eviction. Smaller values are dropped and recomputed.
+const spillThreshold = 64 << 10
+
+// diskStore writes evicted resolved values to files in a directory.
+type diskStore struct {
+	dir string
+}
+
+// spillFile is a resolved value that has been written to disk.
+type spillFile struct {
+	path string
+	size int64
+	ty   reflect.Type // Type of the proto message, or nil for byte slices.
+}
+
+// spillable returns true if v can be written to disk.
+func spillable(v interface{}) bool {
+	switch v.(type) {
+	case []byte, proto.Message:
+		return true
+	default:
+		return false
+	}
+}
+
+// write stores v to a file named after id.
+func (s *diskStore) write(id id.ID, v interface{}) (*spillFile, error) {
+	var data []byte
+	var ty reflect.Type
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case proto.Message:
+		var err error
+		if data, err = proto.Marshal(v); err != nil {
+			return nil, err
+		}
+		ty = reflect.TypeOf(v)
+	default:
+		return nil, fmt.Errorf("Cannot spill %T to disk", v)
+	}
+	path := filepath.Join(s.dir, id.String())
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return &spillFile{path: path, size: int64(len(data)), ty: ty}, nil
+}
+
+// read maps the file back into memory and decodes the value.
+func (f *spillFile) read() (interface{}, error) {
+	if f.size == 0 {
+		return f.decode([]byte{})
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, unmap, err := mmap(file, f.size)
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+	return f.decode(data)
+}
+
+// decode builds the value from data, copying it out of the mapping.
+func (f *spillFile) decode(data []byte) (interface{}, error) {
+	if f.ty == nil {
+		return append([]byte{}, data...), nil
+	}
+	msg := reflect.New(f.ty.Elem()).Interface().(proto.Message)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// remove deletes the file.
+func (f *spillFile) remove() {
+	os.Remove(f.path)
+}
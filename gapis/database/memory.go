@@ -15,20 +15,59 @@
 package database
 
 import (
+	"container/list"
 	"context"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sync"
 
+	"github.com/google/gapid/core/app/benchmark"
+	"github.com/google/gapid/core/context/keys"
 	"github.com/google/gapid/core/data/id"
 	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/gapis/config"
 )
 
+var (
+	hitCounter      = benchmark.GlobalCounters.Integer("database.hits")
+	missCounter     = benchmark.GlobalCounters.Integer("database.misses")
+	evictionCounter = benchmark.GlobalCounters.Integer("database.evictions")
+	bytesCounter    = benchmark.GlobalCounters.Integer("database.bytes")
+	spillCounter    = benchmark.GlobalCounters.Integer("database.spills")
+	loadCounter     = benchmark.GlobalCounters.Integer("database.loads")
+)
+
 // NewInMemory builds a new in memory database.
 func NewInMemory(ctx context.Context) Database {
-	m := &memory{}
+	return NewInMemoryWithBudget(ctx, 0)
+}
+
+// NewInMemoryWithBudget builds a new in memory database that holds at most
+// budget bytes of resolved values. When the budget is exceeded the least
+// recently used resolved values are evicted, and are recomputed the next time
+// they are resolved. Stored values and resolvables are never evicted.
+// A budget of 0 means the database grows without bound.
+func NewInMemoryWithBudget(ctx context.Context, budget uint64) Database {
+	return newMemory(ctx, budget, nil)
+}
+
+// NewTiered builds a new database that holds at most budget bytes of resolved
+// values in memory, like NewInMemoryWithBudget. Large evicted values that can
+// be serialized are written to files in dir instead of being dropped, and are
+// mapped back into memory the next time they are resolved.
+// The caller is responsible for removing dir once the database is no longer
+// used.
+func NewTiered(ctx context.Context, budget uint64, dir string) Database {
+	return newMemory(ctx, budget, &diskStore{dir: dir})
+}
+
+func newMemory(ctx context.Context, budget uint64, disk *diskStore) *memory {
+	m := &memory{budget: budget, disk: disk}
+	m.scheduler = newScheduler(runtime.NumCPU())
 	m.records = map[id.ID]*record{}
+	m.lru = list.New()
 	m.resolveCtx = Put(ctx, m)
 	return m
 }
@@ -36,6 +75,11 @@ func NewInMemory(ctx context.Context) Database {
 type record struct {
 	value        interface{}
 	resolveState *resolveState
+	owner        *record       // The resolvable record that produced this value.
+	size         uint64        // Approximate size of value in bytes.
+	element      *list.Element // Position in the LRU list, nil if not evictable.
+	spill        *spillFile    // The value on disk, or nil if never spilled.
+	spilling     bool          // True while the value is being written to disk.
 }
 
 type resolveState struct {
@@ -45,12 +89,22 @@ type resolveState struct {
 	finished chan struct{}   // Signal that resolve has finished. Set to nil when done.
 	waiting  uint32          // Number of go-routines waiting for the resolve
 	cancel   func()          // Cancels ctx
+	ticket   *ticket         // Place in the scheduler queue, nil if unscheduled
+	slot     bool            // True while the resolve holds a scheduler slot
+	blocked  uint32          // Number of go-routines of the resolve waiting on others
+	regrant  bool            // True while waiting to get a slot back
 }
 
 type memory struct {
 	mutex      sync.Mutex
 	records    map[id.ID]*record
 	resolveCtx context.Context
+	budget     uint64     // Maximum bytes of resolved values. 0 is unbounded.
+	used       uint64     // Bytes of resolved values currently held.
+	lru        *list.List // Resolved value identifiers, most recent first.
+	disk       *diskStore // Store for evicted values, or nil to drop them.
+	scheduler  *scheduler // Limits the number of concurrent resolves.
+	spills     []id.ID    // Evicted values waiting to be written to disk.
 }
 
 // Implements Database
@@ -76,11 +130,131 @@ func (d *memory) store(ctx context.Context, id id.ID, v interface{}) error {
 	return nil
 }
 
+// storeResolved stores the value resolved by the resolvable record owner,
+// making it a candidate for eviction. storeResolved must be called with a
+// locked mutex.
+func (d *memory) storeResolved(ctx context.Context, owner *record, id id.ID, v interface{}) error {
+	if err := d.store(ctx, id, v); err != nil {
+		return err
+	}
+	r := d.records[id]
+	if r.element != nil || d.budget == 0 {
+		return nil
+	}
+	r.owner, r.size = owner, sizeOf(v)
+	r.element = d.lru.PushFront(id)
+	d.used += r.size
+	bytesCounter.AddInt64(int64(r.size))
+	d.evict(ctx, r)
+	return nil
+}
+
+// load reads the spilled value of the record r back from disk, returning
+// false if the value could not be read, in which case the record is removed
+// so the value is recomputed. load must be called with a locked mutex.
+func (d *memory) load(ctx context.Context, id id.ID, r *record) bool {
+	v, err := r.spill.read()
+	if err != nil {
+		log.W(ctx, "Failed to read %v back from disk: %v", id, err)
+		r.spill.remove()
+		d.forget(id, r)
+		return false
+	}
+	loadCounter.Increment()
+	r.value = v
+	r.element = d.lru.PushFront(id)
+	d.used += r.size
+	bytesCounter.AddInt64(int64(r.size))
+	d.evict(ctx, r)
+	return true
+}
+
+// forget removes the resolved value record r, and the resolve state of its
+// owner so the next request recomputes the value. forget must be called with
+// a locked mutex.
+func (d *memory) forget(id id.ID, r *record) {
+	delete(d.records, id)
+	if rs := r.owner.resolveState; rs != nil && rs.valID == id && rs.finished == nil {
+		r.owner.resolveState = nil
+	}
+}
+
+// evict removes the least recently used resolved values until the database
+// is within budget. keep is never evicted. Large values are queued to be
+// written to disk by spill. evict must be called with a locked mutex.
+func (d *memory) evict(ctx context.Context, keep *record) {
+	for e := d.lru.Back(); e != nil && d.used > d.budget; {
+		prev := e.Prev()
+		id := e.Value.(id.ID)
+		if r := d.records[id]; r != keep {
+			d.lru.Remove(e)
+			r.element = nil
+			d.used -= r.size
+			bytesCounter.AddInt64(-int64(r.size))
+			evictionCounter.Increment()
+			switch {
+			case r.spill != nil:
+				// Keep the record, the value is read back from disk.
+				r.value = nil
+			case r.spilling:
+				// The value is dropped once the pending spill has written it.
+			case d.disk != nil && r.size >= spillThreshold && spillable(r.value):
+				r.spilling = true
+				d.spills = append(d.spills, id)
+			default:
+				d.forget(id, r)
+			}
+		}
+		e = prev
+	}
+}
+
+// spill writes the values queued by evict to disk, unlocking the mutex while
+// writing. Values used again while being written are kept in memory.
+// spill must be called with a locked mutex, and returns with a locked mutex.
+func (d *memory) spill(ctx context.Context) {
+	for len(d.spills) > 0 {
+		ids := d.spills
+		d.spills = nil
+		values := make([]interface{}, len(ids))
+		for i, id := range ids {
+			values[i] = d.records[id].value
+		}
+
+		d.mutex.Unlock()
+		files, errs := make([]*spillFile, len(ids)), make([]error, len(ids))
+		for i, id := range ids {
+			files[i], errs[i] = d.disk.write(id, values[i])
+		}
+		d.mutex.Lock()
+
+		for i, id := range ids {
+			r := d.records[id]
+			r.spilling = false
+			switch {
+			case errs[i] != nil:
+				log.W(ctx, "Failed to spill %v to disk: %v", id, errs[i])
+				if r.element == nil {
+					d.forget(id, r)
+				}
+			case r.element != nil:
+				// Stored again while being written.
+				files[i].remove()
+			default:
+				spillCounter.Increment()
+				r.spill, r.value = files[i], nil
+			}
+		}
+	}
+}
+
 // Implements Database
 func (d *memory) Resolve(ctx context.Context, id id.ID) (interface{}, error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
-	return d.resolve(ctx, id)
+	v, err := d.resolve(ctx, id)
+	d.spill(ctx)
+	return v, err
 }
 
 // resolve function must be called with a locked mutex and returns with a locked
@@ -97,12 +271,22 @@ func (d *memory) resolve(ctx context.Context, id id.ID) (interface{}, error) {
 	resolvable, isResolvable := r.value.(Resolvable)
 	if !isResolvable {
 		// Non-resolvable object. Just return the value.
+		if r.element != nil {
+			d.lru.MoveToFront(r.element)
+		}
 		return r.value, nil
 	}
 
 	rs := r.resolveState
-	if rs == nil {
-		// First request for this resolvable.
+	if rs != nil {
+		hitCounter.Increment()
+		if rs.ticket != nil {
+			// Still queued. Let the most urgent waiter decide the priority.
+			d.scheduler.raise(rs.ticket, GetPriority(ctx))
+		}
+	} else {
+		// First request for this resolvable, or its value was evicted.
+		missCounter.Increment()
 
 		// Mutate the resolvable identifier to get the result value identifier.
 		valID := resolvedID(id)
@@ -111,24 +295,30 @@ func (d *memory) resolve(ctx context.Context, id id.ID) (interface{}, error) {
 		resolveCtx, cancel := task.WithCancel(d.resolveCtx)
 
 		rs = &resolveState{
-			ctx:      resolveCtx,
 			valID:    valID,
 			finished: make(chan struct{}),
 			cancel:   cancel,
 		}
+		rs.ctx = keys.WithValue(resolveCtx, resolvingKey, rs)
+		if ctx.Value(resolvingKey) == nil {
+			// Top-level resolve. Wait for a free slot before starting.
+			// Dependencies of a running resolve use their parent's slot.
+			rs.ticket = d.scheduler.enqueue(GetPriority(ctx))
+		}
 		r.resolveState = rs
 
 		// Build the resolvable on a separate go-routine.
 		go func() {
-			val, err := resolvable.Resolve(rs.ctx)
+			val, err := d.run(rs, resolvable)
+			d.mutex.Lock()
 			if err == nil {
 				// Resolved without error. Store the resulting values.
-				err = d.Store(ctx, rs.valID, val)
+				err = d.storeResolved(ctx, r, rs.valID, val)
 			}
 			// Signal that the resolvable has finished.
-			d.mutex.Lock()
 			close(rs.finished)
 			rs.err, rs.finished = err, nil
+			d.spill(ctx)
 			d.mutex.Unlock()
 		}()
 	}
@@ -139,12 +329,21 @@ func (d *memory) resolve(ctx context.Context, id id.ID) (interface{}, error) {
 		rs.waiting++
 
 		// Wait for either the resolve to finish or ctx to be cancelled.
+		// A resolve waiting on another gives up its slot while it waits, as
+		// the other may be queued behind it.
+		holder, _ := ctx.Value(resolvingKey).(*resolveState)
+		if holder != nil {
+			d.block(holder)
+		}
 		d.mutex.Unlock()
 		select {
 		case <-finished:
 		case <-task.ShouldStop(ctx):
 		}
 		d.mutex.Lock()
+		if holder != nil {
+			d.unblock(holder)
+		}
 
 		// Decrement the waiting go-routine counter.
 		rs.waiting--
@@ -165,10 +364,80 @@ func (d *memory) resolve(ctx context.Context, id id.ID) (interface{}, error) {
 		return nil, rs.err // Resolve errored.
 	}
 	// Resolve was successful.
+	val, got := d.records[rs.valID]
+	if got && val.value == nil && !d.load(ctx, rs.valID, val) {
+		got = false
+	}
+	if !got {
+		// The value was evicted before this go-routine woke. Recompute it.
+		if r.resolveState == rs {
+			r.resolveState = nil
+		}
+		return d.resolve(ctx, id)
+	}
 	// Resolve the value identifier to get the goods.
 	return d.resolve(ctx, rs.valID)
 }
 
+// run calls Resolve on resolvable once the scheduler has granted rs a slot.
+func (d *memory) run(rs *resolveState, resolvable Resolvable) (interface{}, error) {
+	if t := rs.ticket; t != nil {
+		if err := d.scheduler.wait(rs.ctx, t); err != nil {
+			return nil, err // Cancelled before starting.
+		}
+		d.mutex.Lock()
+		rs.slot = true
+		d.mutex.Unlock()
+		defer func() {
+			d.mutex.Lock()
+			if rs.slot {
+				d.scheduler.release()
+			}
+			rs.ticket, rs.slot = nil, false
+			d.mutex.Unlock()
+		}()
+	}
+	return resolvable.Resolve(rs.ctx)
+}
+
+// block releases the slot of the running resolve rs, as one of its
+// go-routines is about to wait on another resolve. block must be called with
+// a locked mutex.
+func (d *memory) block(rs *resolveState) {
+	rs.blocked++
+	if rs.slot {
+		rs.slot = false
+		d.scheduler.release()
+	}
+}
+
+// unblock gets a slot back for the running resolve rs once none of its
+// go-routines are waiting on other resolves. Other go-routines of rs carry on
+// while the slot is being granted. unblock must be called with a locked
+// mutex, and returns with a locked mutex.
+func (d *memory) unblock(rs *resolveState) {
+	rs.blocked--
+	if rs.blocked > 0 || rs.ticket == nil || rs.slot || rs.regrant {
+		return
+	}
+	rs.regrant = true
+	t := d.scheduler.requeue(rs.ticket)
+	rs.ticket = t
+	d.mutex.Unlock()
+	err := d.scheduler.wait(rs.ctx, t)
+	d.mutex.Lock()
+	rs.regrant = false
+	if err != nil {
+		return // The resolve was cancelled.
+	}
+	if rs.blocked > 0 || rs.ticket != t {
+		// Blocked again, or finished, while waiting for the slot.
+		d.scheduler.release()
+		return
+	}
+	rs.slot = true
+}
+
 // Implements Database
 func (d *memory) Contains(ctx context.Context, id id.ID) (res bool) {
 	d.mutex.Lock()
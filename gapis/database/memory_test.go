@@ -0,0 +1,107 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/database"
+)
+
+type countingResolvable struct {
+	size  int
+	count *int
+}
+
+func (r countingResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	*r.count++
+	return make([]byte, r.size), nil
+}
+
+func TestInMemoryBudget(t *testing.T) {
+	ctx := log.Testing(t)
+	d := database.NewInMemoryWithBudget(ctx, 10)
+
+	a, b := id.OfString("a"), id.OfString("b")
+	countA, countB := 0, 0
+	assert.With(ctx).ThatError(d.Store(ctx, a, countingResolvable{8, &countA})).Succeeded()
+	assert.With(ctx).ThatError(d.Store(ctx, b, countingResolvable{8, &countB})).Succeeded()
+
+	for i := 0; i < 3; i++ {
+		_, err := d.Resolve(ctx, a)
+		assert.With(ctx).ThatError(err).Succeeded()
+	}
+	assert.For(ctx, "a resolves").That(countA).Equals(1)
+
+	// Resolving b exceeds the budget, evicting a's value.
+	_, err := d.Resolve(ctx, b)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.For(ctx, "resolvable a kept").That(d.Contains(ctx, a)).Equals(true)
+
+	// a is recomputed on the next resolve, evicting b's value.
+	v, err := d.Resolve(ctx, a)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.For(ctx, "value").That(len(v.([]byte))).Equals(8)
+	assert.For(ctx, "a resolves").That(countA).Equals(2)
+	assert.For(ctx, "b resolves").That(countB).Equals(1)
+}
+
+func TestTieredSpill(t *testing.T) {
+	ctx := log.Testing(t)
+	dir, err := ioutil.TempDir("", "database")
+	assert.With(ctx).ThatError(err).Succeeded()
+	defer os.RemoveAll(dir)
+	d := database.NewTiered(ctx, 100<<10, dir)
+
+	a, b := id.OfString("a"), id.OfString("b")
+	countA, countB := 0, 0
+	assert.With(ctx).ThatError(d.Store(ctx, a, countingResolvable{80 << 10, &countA})).Succeeded()
+	assert.With(ctx).ThatError(d.Store(ctx, b, countingResolvable{80 << 10, &countB})).Succeeded()
+
+	_, err = d.Resolve(ctx, a)
+	assert.With(ctx).ThatError(err).Succeeded()
+	// Resolving b exceeds the budget, spilling a's value to disk.
+	_, err = d.Resolve(ctx, b)
+	assert.With(ctx).ThatError(err).Succeeded()
+	files, err := ioutil.ReadDir(dir)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.For(ctx, "spilled files").That(len(files)).Equals(1)
+
+	// a is read back from disk rather than recomputed.
+	v, err := d.Resolve(ctx, a)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.For(ctx, "value").That(len(v.([]byte))).Equals(80 << 10)
+	assert.For(ctx, "a resolves").That(countA).Equals(1)
+	assert.For(ctx, "b resolves").That(countB).Equals(1)
+}
+
+func TestInMemoryUnbounded(t *testing.T) {
+	ctx := log.Testing(t)
+	d := database.NewInMemory(ctx)
+
+	a, count := id.OfString("a"), 0
+	assert.With(ctx).ThatError(d.Store(ctx, a, countingResolvable{1 << 20, &count})).Succeeded()
+	for i := 0; i < 3; i++ {
+		_, err := d.Resolve(ctx, a)
+		assert.With(ctx).ThatError(err).Succeeded()
+	}
+	assert.For(ctx, "resolves").That(count).Equals(1)
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/google/gapid/core/context/keys"
+	"github.com/google/gapid/core/event/task"
+)
+
+// Priority is the scheduling priority of a resolve. When more resolves are
+// requested than can run at once, those of higher priority are started first.
+type Priority int32
+
+const (
+	// LowPriority is used for speculative resolves that nobody is waiting on.
+	LowPriority = Priority(-1)
+	// NormalPriority is the priority of resolves with no explicit priority.
+	NormalPriority = Priority(0)
+	// HighPriority is used for resolves the user is actively waiting on.
+	HighPriority = Priority(1)
+)
+
+type priorityKeyTy string
+
+const priorityKey = priorityKeyTy("priority")
+
+// PutPriority amends a Context by attaching the priority used to schedule the
+// resolves it requests.
+func PutPriority(ctx context.Context, p Priority) context.Context {
+	return keys.WithValue(ctx, priorityKey, p)
+}
+
+// GetPriority returns the resolve priority attached to the given context, or
+// NormalPriority if there is none.
+func GetPriority(ctx context.Context) Priority {
+	if val := ctx.Value(priorityKey); val != nil {
+		return val.(Priority)
+	}
+	return NormalPriority
+}
+
+type resolvingKeyTy string
+
+// resolvingKey is attached to the context passed to Resolvable.Resolve, with
+// the *resolveState of the resolve as value. Resolves requested with this
+// context are dependencies of a running resolve, and so bypass the scheduler
+// to avoid deadlocking on its own slot.
+const resolvingKey = resolvingKeyTy("resolving")
+
+// PutDependent amends a Context for work done on behalf of running resolves
+// that wait on it, such as replays. The resolves requested with the context
+// start without waiting for a free slot, as the slots may all be held by the
+// resolves waiting on the work.
+func PutDependent(ctx context.Context) context.Context {
+	return keys.WithValue(ctx, resolvingKey, (*resolveState)(nil))
+}
+
+// scheduler limits the number of resolves running at once, starting queued
+// resolves in priority order.
+type scheduler struct {
+	mutex sync.Mutex
+	free  int     // Number of resolves that can be started right away.
+	queue tickets // Resolves waiting for a free slot.
+	next  uint64  // Sequence number of the next ticket.
+}
+
+// ticket is a place in the scheduler's queue.
+type ticket struct {
+	priority Priority
+	seq      uint64        // Orders tickets of equal priority.
+	index    int           // Index in the queue, or -1 once granted.
+	ready    chan struct{} // Closed once the ticket has been granted a slot.
+}
+
+func newScheduler(slots int) *scheduler {
+	return &scheduler{free: slots}
+}
+
+// enqueue returns a new ticket for a resolve of priority p.
+func (s *scheduler) enqueue(p Priority) *ticket {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t := &ticket{priority: p, seq: s.next, ready: make(chan struct{})}
+	s.next++
+	if s.free > 0 && len(s.queue) == 0 {
+		s.free--
+		t.index = -1
+		close(t.ready)
+	} else {
+		heap.Push(&s.queue, t)
+	}
+	return t
+}
+
+// requeue returns a new ticket with the priority of t, for a resolve that gave
+// up the slot granted to t.
+func (s *scheduler) requeue(t *ticket) *ticket {
+	s.mutex.Lock()
+	p := t.priority
+	s.mutex.Unlock()
+	return s.enqueue(p)
+}
+
+// raise increases the priority of the queued ticket t to p.
+func (s *scheduler) raise(t *ticket, p Priority) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if t.index >= 0 && p > t.priority {
+		t.priority = p
+		heap.Fix(&s.queue, t.index)
+	}
+}
+
+// wait blocks until t is granted a slot or ctx is cancelled. If wait returns
+// nil then release must be called once the resolve has finished.
+func (s *scheduler) wait(ctx context.Context, t *ticket) error {
+	select {
+	case <-t.ready:
+		return nil
+	case <-task.ShouldStop(ctx):
+	}
+	s.mutex.Lock()
+	if t.index >= 0 {
+		heap.Remove(&s.queue, t.index)
+		s.mutex.Unlock()
+	} else {
+		// Granted while being cancelled. Pass the slot on.
+		s.mutex.Unlock()
+		s.release()
+	}
+	return task.StopReason(ctx)
+}
+
+// release frees a slot, starting the highest priority queued resolve.
+func (s *scheduler) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.queue) > 0 {
+		close(heap.Pop(&s.queue).(*ticket).ready)
+	} else {
+		s.free++
+	}
+}
+
+// tickets is a heap of tickets, highest priority first.
+type tickets []*ticket
+
+func (l tickets) Len() int { return len(l) }
+func (l tickets) Less(i, j int) bool {
+	if l[i].priority != l[j].priority {
+		return l[i].priority > l[j].priority
+	}
+	return l[i].seq < l[j].seq
+}
+func (l tickets) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+	l[i].index, l[j].index = i, j
+}
+func (l *tickets) Push(x interface{}) {
+	t := x.(*ticket)
+	t.index = len(*l)
+	*l = append(*l, t)
+}
+func (l *tickets) Pop() interface{} {
+	old := *l
+	t := old[len(old)-1]
+	t.index = -1
+	*l = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+)
+
+func granted(t *ticket) bool {
+	select {
+	case <-t.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	ctx := log.Testing(t)
+	s := newScheduler(1)
+
+	first := s.enqueue(NormalPriority)
+	low := s.enqueue(LowPriority)
+	normal := s.enqueue(NormalPriority)
+	raised := s.enqueue(LowPriority)
+	s.raise(raised, HighPriority)
+	assert.For(ctx, "first granted").That(granted(first)).Equals(true)
+
+	for _, test := range []struct {
+		name string
+		next *ticket
+	}{
+		{"raised", raised},
+		{"normal", normal},
+		{"low", low},
+	} {
+		assert.For(ctx, "%s granted early", test.name).That(granted(test.next)).Equals(false)
+		s.release()
+		assert.For(ctx, "%s granted", test.name).That(granted(test.next)).Equals(true)
+	}
+	s.release()
+	assert.For(ctx, "free slots").That(s.free).Equals(1)
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	ctx := log.Testing(t)
+	s := newScheduler(1)
+
+	first := s.enqueue(NormalPriority)
+	queued := s.enqueue(HighPriority)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err := s.wait(cancelled, queued)
+	assert.For(ctx, "err").That(err).Equals(context.Canceled)
+	assert.For(ctx, "queue length").That(len(s.queue)).Equals(0)
+
+	assert.With(ctx).ThatError(s.wait(ctx, first)).Succeeded()
+	s.release()
+	assert.For(ctx, "free slots").That(s.free).Equals(1)
+}
+
+type funcResolvable func(ctx context.Context) (interface{}, error)
+
+func (f funcResolvable) Resolve(ctx context.Context) (interface{}, error) { return f(ctx) }
+
+// queued returns the number of tickets waiting in the queue of s.
+func queued(s *scheduler) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.queue)
+}
+
+// resolveAll resolves the ids concurrently with ctx, failing the test if the
+// resolves do not all finish in time.
+func resolveAll(ctx context.Context, d Database, ids ...id.ID) {
+	done := make(chan error, len(ids))
+	for _, r := range ids {
+		r := r
+		go func() {
+			_, err := d.Resolve(ctx, r)
+			done <- err
+		}()
+	}
+	for range ids {
+		select {
+		case err := <-done:
+			assert.With(ctx).ThatError(err).Succeeded()
+		case <-time.After(5 * time.Second):
+			assert.For(ctx, "resolves").Error("Deadlocked")
+			return
+		}
+	}
+}
+
+func TestSchedulerSharedResolve(t *testing.T) {
+	ctx := log.Testing(t)
+	const slots = 2
+	d := newMemory(ctx, 0, nil)
+	d.scheduler = newScheduler(slots)
+
+	shared := id.ID{1}
+	d.Store(ctx, shared, funcResolvable(func(ctx context.Context) (interface{}, error) {
+		return "shared", nil
+	}))
+
+	// Each holder takes a slot, then waits on the shared resolvable queued by
+	// another top-level resolve while all the slots were taken.
+	running, gate := make(chan struct{}, slots), make(chan struct{})
+	holders := []id.ID{}
+	for i := 0; i < slots; i++ {
+		holder := id.ID{2, byte(i)}
+		d.Store(ctx, holder, funcResolvable(func(ctx context.Context) (interface{}, error) {
+			running <- struct{}{}
+			<-gate
+			return d.Resolve(ctx, shared)
+		}))
+		holders = append(holders, holder)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		resolveAll(ctx, d, holders...)
+		close(done)
+	}()
+	for i := 0; i < slots; i++ {
+		<-running
+	}
+	go func() {
+		resolveAll(ctx, d, shared)
+	}()
+	for queued(d.scheduler) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(gate)
+	<-done
+}
+
+func TestSchedulerDependentWork(t *testing.T) {
+	ctx := log.Testing(t)
+	const slots = 2
+	d := newMemory(ctx, 0, nil)
+	d.scheduler = newScheduler(slots)
+
+	// Like a replay, the work of the holders is done on another context,
+	// requesting a resolve while the holders take all the slots.
+	work := id.ID{4}
+	d.Store(ctx, work, funcResolvable(func(ctx context.Context) (interface{}, error) {
+		return "work", nil
+	}))
+	result := make(chan error)
+	requests := make(chan struct{}, slots+1)
+	go func() {
+		ctx := PutDependent(ctx)
+		for i := 0; i < slots; i++ {
+			<-requests
+		}
+		_, err := d.Resolve(ctx, work)
+		for i := 0; i < slots; i++ {
+			result <- err
+		}
+	}()
+	holders := []id.ID{}
+	for i := 0; i < slots; i++ {
+		holder := id.ID{5, byte(i)}
+		d.Store(ctx, holder, funcResolvable(func(ctx context.Context) (interface{}, error) {
+			requests <- struct{}{}
+			if err := <-result; err != nil {
+				return nil, err
+			}
+			return "done", nil
+		}))
+		holders = append(holders, holder)
+	}
+	resolveAll(ctx, d, holders...)
+}
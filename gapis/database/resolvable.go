@@ -25,7 +25,8 @@ var resolvablePrefix = []byte("resolvable:")
 // Resolvable is the interface for types that redirects database resolves to an
 // object lazily built using Resolve(). The Resolve() method will be called
 // the first time the object is resolved, and all subsequent resolves will
-// return the same pre-built object.
+// return the same pre-built object, unless the object has been evicted from a
+// database with a memory budget, in which case Resolve() is called again.
 // Resolvable is commonly implemented by objects that generate data that is
 // expensive to calculate but can be deterministically produced using the
 // information stored in the Resolvable.
@@ -0,0 +1,118 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Sizer can be implemented by resolved values that can cheaply report the
+// number of bytes they occupy in memory. Values that do not implement Sizer
+// have their size estimated by walking the value with reflection.
+type Sizer interface {
+	// DatabaseSize returns the approximate size of the value in bytes.
+	DatabaseSize() uint64
+}
+
+// sizeOf returns the approximate number of bytes held by v.
+func sizeOf(v interface{}) uint64 {
+	switch v := v.(type) {
+	case Sizer:
+		return v.DatabaseSize()
+	case []byte:
+		return uint64(len(v))
+	case proto.Message:
+		return uint64(proto.Size(v))
+	}
+	s := sizer{visited: map[uintptr]bool{}}
+	return s.value(reflect.ValueOf(v))
+}
+
+// sizer walks a value graph, counting each pointed-to object once.
+type sizer struct {
+	visited map[uintptr]bool
+}
+
+func (s *sizer) value(v reflect.Value) uint64 {
+	if !v.IsValid() {
+		return 0
+	}
+	return uint64(v.Type().Size()) + s.indirect(v)
+}
+
+// indirect returns the number of bytes held by v outside of v itself.
+func (s *sizer) indirect(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !s.visit(v.Pointer()) {
+			return 0
+		}
+		return s.value(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		if e.Kind() == reflect.Ptr {
+			return s.indirect(e)
+		}
+		return s.value(e)
+	case reflect.String:
+		return uint64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || !s.visit(v.Pointer()) {
+			return 0
+		}
+		size := uint64(v.Cap()) * uint64(v.Type().Elem().Size())
+		for i, c := 0, v.Len(); i < c; i++ {
+			size += s.indirect(v.Index(i))
+		}
+		return size
+	case reflect.Array:
+		size := uint64(0)
+		for i, c := 0, v.Len(); i < c; i++ {
+			size += s.indirect(v.Index(i))
+		}
+		return size
+	case reflect.Struct:
+		size := uint64(0)
+		for i, c := 0, v.NumField(); i < c; i++ {
+			size += s.indirect(v.Field(i))
+		}
+		return size
+	case reflect.Map:
+		if v.IsNil() || !s.visit(v.Pointer()) {
+			return 0
+		}
+		size := uint64(0)
+		for _, k := range v.MapKeys() {
+			size += s.value(k) + s.value(v.MapIndex(k))
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
+// visit returns true if p has not been visited before.
+func (s *sizer) visit(p uintptr) bool {
+	if s.visited[p] {
+		return false
+	}
+	s.visited[p] = true
+	return true
+}
@@ -0,0 +1,22 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package experiments provides a registry of experiments that modify the
+// commands of a capture, such as disabling draw calls or overriding shaders,
+// so that the effect of the modification on the rendering can be observed.
+//
+// Each experiment is registered once by name, and is implemented separately
+// for each graphics API that supports it. The results of running experiments
+// are persisted per capture so that they can be revisited later.
+package experiments
@@ -0,0 +1,242 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+// Parameters holds the parameters of an experiment run, keyed by name.
+type Parameters map[string]string
+
+// Implementation returns the transform that applies an experiment, with the
+// parameters p, to the commands of a single graphics API.
+type Implementation func(ctx context.Context, p Parameters) (transform.Transformer, error)
+
+// Experiment is a named modification that can be applied to the commands of a
+// capture.
+type Experiment struct {
+	// Name is the unique name of the experiment.
+	Name string
+	// Description is a human readable description of the experiment.
+	Description string
+	// Parameters lists the names of the parameters accepted by the experiment.
+	Parameters []string
+
+	impls map[gfxapi.ID]Implementation
+}
+
+var (
+	mutex    sync.RWMutex
+	registry = map[string]*Experiment{}
+)
+
+// The built-in experiments. The graphics APIs register their implementations
+// of these from their init functions.
+var (
+	DisableDraws = Register("disable-draws",
+		"Removes draw calls. If commands is set, only the listed draw calls are removed.",
+		"commands")
+	Scissor1x1 = Register("scissor-1x1",
+		"Restricts all draw calls to a 1x1 scissor rectangle, to remove the cost of fragment shading.")
+	ShaderOverride = Register("shader-override",
+		"Replaces the source of the shader with the given identifier.",
+		"shader", "source")
+	ResolutionScale = Register("resolution-scale",
+		"Scales the viewport and scissor of all draw calls by the given factor.",
+		"scale")
+	PresentMode = Register("present-mode",
+		"Changes the present mode of the swapchains to the given mode.",
+		"mode")
+	Texture1x1 = Register("texture-1x1",
+		"Replaces all sampled textures with 1x1 textures, to remove the cost of texture fetches.")
+	SimpleShader = Register("simple-shader",
+		"Replaces all fragment shaders with shaders writing a constant color, to remove the cost of fragment shading.")
+)
+
+// Register adds a new experiment with the given name, description and
+// parameter names to the registry. Register panics if an experiment with the
+// same name has already been registered.
+func Register(name, description string, parameters ...string) *Experiment {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, found := registry[name]; found {
+		panic("Experiment " + name + " already registered")
+	}
+	e := &Experiment{
+		Name:        name,
+		Description: description,
+		Parameters:  parameters,
+		impls:       map[gfxapi.ID]Implementation{},
+	}
+	registry[name] = e
+	return e
+}
+
+// Find returns the experiment with the given name, or nil if there is no such
+// experiment.
+func Find(name string) *Experiment {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return registry[name]
+}
+
+// All returns all the registered experiments, sorted by name.
+func All() []*Experiment {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	out := make([]*Experiment, 0, len(registry))
+	for _, e := range registry {
+		out = append(out, e)
+	}
+	sort.Sort(byName(out))
+	return out
+}
+
+// Implement registers impl as the implementation of the experiment for the
+// graphics API api.
+func (e *Experiment) Implement(api gfxapi.API, impl Implementation) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	e.impls[api.ID()] = impl
+}
+
+// Transformer returns the transform that applies the experiment, with the
+// parameters p, to the commands of the graphics API api.
+func (e *Experiment) Transformer(ctx context.Context, api gfxapi.API, p Parameters) (transform.Transformer, error) {
+	for name := range p {
+		if !e.accepts(name) {
+			return nil, Invalid(name, "unknown parameter")
+		}
+	}
+	mutex.RLock()
+	impl, ok := e.impls[api.ID()]
+	mutex.RUnlock()
+	if !ok {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrExperimentNotSupported(e.Name)}
+	}
+	return impl(ctx, p)
+}
+
+// Service returns the service description of the experiment.
+func (e *Experiment) Service() *service.ExperimentInfo {
+	return &service.ExperimentInfo{
+		Name:        e.Name,
+		Description: e.Description,
+		Parameters:  e.Parameters,
+	}
+}
+
+// Modified holds the commands of a capture with an experiment applied.
+type Modified struct {
+	// Atoms is the modified list of commands.
+	Atoms *atom.List
+	// IDs holds, for each command in Atoms, the identifier of the original
+	// command it was produced for.
+	IDs []atom.ID
+}
+
+// Index returns the index of the last command in m that was produced for the
+// original command id, or for any command before it.
+func (m *Modified) Index(id atom.ID) (uint64, bool) {
+	for i := len(m.IDs) - 1; i >= 0; i-- {
+		if m.IDs[i] <= id {
+			return uint64(i), true
+		}
+	}
+	return 0, false
+}
+
+// Apply returns the commands of the capture c with the experiment applied,
+// using the parameters p.
+func (e *Experiment) Apply(ctx context.Context, c *capture.Capture, p Parameters) (*Modified, error) {
+	for name := range p {
+		if !e.accepts(name) {
+			return nil, Invalid(name, "unknown parameter")
+		}
+	}
+
+	transforms := transform.Transforms{}
+	mutex.RLock()
+	for _, api := range c.Apis {
+		if impl, ok := e.impls[gfxapi.ID(api.ID())]; ok {
+			t, err := impl(ctx, p)
+			if err != nil {
+				mutex.RUnlock()
+				return nil, err
+			}
+			transforms.Add(t)
+		}
+	}
+	mutex.RUnlock()
+
+	if len(transforms) == 0 {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrExperimentNotSupported(e.Name)}
+	}
+
+	atoms, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &listWriter{state: c.NewState()}
+	transforms.Transform(ctx, *atoms, out)
+	return &Modified{Atoms: atom.NewList(out.atoms...), IDs: out.ids}, nil
+}
+
+func (e *Experiment) accepts(parameter string) bool {
+	for _, p := range e.Parameters {
+		if p == parameter {
+			return true
+		}
+	}
+	return false
+}
+
+type byName []*Experiment
+
+func (l byName) Len() int           { return len(l) }
+func (l byName) Less(i, j int) bool { return l[i].Name < l[j].Name }
+func (l byName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// listWriter is a transform.Writer that collects the atoms written to it.
+// Atoms generated by the transforms are attributed to the last original atom
+// written before them.
+type listWriter struct {
+	state *gfxapi.State
+	atoms []atom.Atom
+	ids   []atom.ID
+	last  atom.ID
+}
+
+func (w *listWriter) State() *gfxapi.State { return w.state }
+
+func (w *listWriter) MutateAndWrite(ctx context.Context, id atom.ID, a atom.Atom) {
+	a.Mutate(ctx, w.state, nil /* no builder, just mutate */)
+	if id.Derived() != id {
+		w.last = id
+	}
+	w.atoms = append(w.atoms, a)
+	w.ids = append(w.ids, w.last)
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/experiments"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := log.Testing(t)
+	assert.With(ctx).That(experiments.Find("disable-draws")).Equals(experiments.DisableDraws)
+	assert.With(ctx).That(experiments.Find("not-an-experiment")).IsNil()
+
+	all := experiments.All()
+	for i := 1; i < len(all); i++ {
+		assert.With(ctx).That(all[i-1].Name < all[i].Name).Equals(true)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	ctx := log.Testing(t)
+	p := experiments.Parameters{"commands": "1, 5,0x10", "scale": "0.25", "bad": "x"}
+
+	ids, err := p.IDs("commands")
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).That(ids).DeepEquals(map[atom.ID]bool{1: true, 5: true, 16: true})
+
+	ids, err = p.IDs("missing")
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).That(ids).IsNil()
+
+	f, err := p.Float("scale", 1)
+	assert.With(ctx).ThatError(err).Succeeded()
+	assert.With(ctx).That(f).Equals(0.25)
+
+	_, err = p.Uint("bad", 0)
+	assert.With(ctx).ThatError(err).Failed()
+}
+
+func TestParametersSelect(t *testing.T) {
+	ctx := log.Testing(t)
+	p := experiments.Parameters{"scale": "0.5", "mode": "fifo"}
+	assert.With(ctx).That(p.Select(experiments.ResolutionScale)).DeepEquals(experiments.Parameters{"scale": "0.5"})
+	assert.With(ctx).That(p.Select(experiments.Texture1x1)).DeepEquals(experiments.Parameters{})
+}
+
+func TestStorePersists(t *testing.T) {
+	ctx := log.Testing(t)
+	dir, err := ioutil.TempDir("", "experiments")
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	c := id.OfString("capture")
+	result := &service.ExperimentResult{
+		Request:   &service.ExperimentRequest{Name: "disable-draws"},
+		Timestamp: 1234,
+	}
+	err = experiments.NewStore(dir).Add(ctx, c, result)
+	assert.With(ctx).ThatError(err).Succeeded()
+
+	results, err := experiments.NewStore(dir).Results(ctx, c)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).ThatSlice(results.Results).IsLength(1)
+	assert.With(ctx).That(results.Results[0].Request.Name).Equals("disable-draws")
+	assert.With(ctx).That(results.Results[0].Timestamp).Equals(int64(1234))
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+// String returns the value of the parameter name, or def if the parameter was
+// not set.
+func (p Parameters) String(name, def string) string {
+	if v, ok := p[name]; ok {
+		return v
+	}
+	return def
+}
+
+// Uint returns the value of the parameter name parsed as an unsigned
+// integer, or def if the parameter was not set.
+func (p Parameters) Uint(name string, def uint64) (uint64, error) {
+	v, ok := p[name]
+	if !ok {
+		return def, nil
+	}
+	u, err := strconv.ParseUint(strings.TrimSpace(v), 0, 64)
+	if err != nil {
+		return 0, invalid(name, err)
+	}
+	return u, nil
+}
+
+// Float returns the value of the parameter name parsed as a floating point
+// number, or def if the parameter was not set.
+func (p Parameters) Float(name string, def float64) (float64, error) {
+	v, ok := p[name]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0, invalid(name, err)
+	}
+	return f, nil
+}
+
+// IDs returns the value of the parameter name parsed as a comma-separated
+// list of command indices. If the parameter was not set then nil is returned.
+func (p Parameters) IDs(name string) (map[atom.ID]bool, error) {
+	v, ok := p[name]
+	if !ok || strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	out := map[atom.ID]bool{}
+	for _, s := range strings.Split(v, ",") {
+		u, err := strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+		if err != nil {
+			return nil, invalid(name, err)
+		}
+		out[atom.ID(u)] = true
+	}
+	return out, nil
+}
+
+// Select returns the parameters of p accepted by the experiment e.
+func (p Parameters) Select(e *Experiment) Parameters {
+	out := Parameters{}
+	for name, v := range p {
+		if e.accepts(name) {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+// Invalid returns an error reporting that the value of the parameter name is
+// invalid for the given reason.
+func Invalid(name, reason string) error {
+	return &service.ErrInvalidArgument{Reason: messages.ErrInvalidExperimentParameter(name, reason)}
+}
+
+func invalid(name string, err error) error {
+	return Invalid(name, err.Error())
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+// Store holds the results of the experiments run on each capture.
+// If the store has a directory, the results are persisted to a file per
+// capture in that directory, so they survive server restarts.
+type Store struct {
+	dir     string
+	mutex   sync.Mutex
+	results map[id.ID]*service.ExperimentResults
+}
+
+// NewStore returns a new Store that persists results to the directory dir.
+// If dir is empty then results are only held in memory.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, results: map[id.ID]*service.ExperimentResults{}}
+}
+
+// Add appends the result r to the results of the capture c.
+func (s *Store) Add(ctx context.Context, c id.ID, r *service.ExperimentResult) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	results, err := s.load(ctx, c)
+	if err != nil {
+		return err
+	}
+	results.Results = append(results.Results, r)
+	if s.dir == "" {
+		return nil
+	}
+	data, err := proto.Marshal(results)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return log.Errf(ctx, err, "Could not create experiments directory %v", s.dir)
+	}
+	if err := ioutil.WriteFile(s.path(c), data, 0644); err != nil {
+		return log.Errf(ctx, err, "Could not write experiment results for %v", c)
+	}
+	return nil
+}
+
+// Results returns the results of all the experiments run on the capture c,
+// oldest first.
+func (s *Store) Results(ctx context.Context, c id.ID) (*service.ExperimentResults, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	results, err := s.load(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Clone(results).(*service.ExperimentResults), nil
+}
+
+// load returns the cached results for c, reading them from disk on first use.
+// s.mutex must be held when calling load.
+func (s *Store) load(ctx context.Context, c id.ID) (*service.ExperimentResults, error) {
+	if r, ok := s.results[c]; ok {
+		return r, nil
+	}
+	r := &service.ExperimentResults{}
+	if s.dir != "" {
+		data, err := ioutil.ReadFile(s.path(c))
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, log.Errf(ctx, err, "Could not read experiment results for %v", c)
+		default:
+			if err := proto.Unmarshal(data, r); err != nil {
+				return nil, log.Errf(ctx, err, "Could not decode experiment results for %v", c)
+			}
+		}
+	}
+	s.results[c] = r
+	return r, nil
+}
+
+func (s *Store) path(c id.ID) string {
+	return filepath.Join(s.dir, c.String()+".experiments")
+}
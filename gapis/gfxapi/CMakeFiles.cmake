@@ -19,10 +19,14 @@
 
 set(files
     api.go
+    constants.go
     context.go
     doc.go
     gfxapi.pb.go
     gfxapi.proto
+    index_stats.go
+    index_stats_test.go
+    memory_usage.go
     mesh.go
     resource.go
     snippet.go
@@ -35,6 +39,7 @@ set(dirs
     all
     core
     gles
+    interop
     templates
     test
     vulkan
@@ -0,0 +1,29 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gfxapi
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// ConstantsProvider is the interface implemented by types that provide the
+// shader constants used by draw and dispatch calls.
+type ConstantsProvider interface {
+	// Constants returns the constants used by the command o.
+	// If nil, nil then the command does not use any shader constants.
+	Constants(ctx context.Context, o interface{}, p *path.Constants) (*ConstantTable, error)
+}
@@ -20,6 +20,7 @@
 set(files
     api.go
     backwards_compat.go
+    batching.go
     compat.go
     compat_test.go
     context.go
@@ -32,6 +33,7 @@ set(files
     draw_call.go
     draw_call_mesh.go
     enum.go
+    experiments.go
     externs.go
     extras.go
     find_issues.go
@@ -42,24 +44,36 @@ set(files
     guess_semantics.go
     helpers.go
     image.go
+    interop.go
     issue_whitelist.go
+    leaks.go
     links.go
     markers.go
     markers_test.go
     metadata.go
     mutate.go
+    overdraw.go
+    passes.go
     read_framebuffer.go
+    recorded_values.go
+    redundancy.go
     replay.go
     resolvables.pb.go
     resolvables.proto
     resources.go
     resources_test.go
+    sanitize.go
+    shader_analysis.go
+    shader_analysis_test.go
+    skip.go
     snippets_embed.go
     state.go
+    state_override.go
     string.go
     stub_program.go
     stub_program_test.go
     texture_compat.go
+    texture_override.go
     tweaker.go
     undefined_framebuffer.go
     version.go
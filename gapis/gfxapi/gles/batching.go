@@ -0,0 +1,121 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+)
+
+var _ = atom.DrawBatcher(api{})
+
+// batchRecorder is the state of a context that decides which of its draws can
+// be batched together.
+type batchRecorder struct {
+	// version is incremented each time the vertex attributes or the buffers
+	// they use may change.
+	version int
+	batch   *atom.DrawBatch // The batch of the last draw, nil if broken.
+	draw    interface{}     // The parameters of the last draw.
+	since   uint64          // The atoms issued since the last draw.
+}
+
+// DrawBatches returns the runs of glDrawArrays and glDrawElements atoms of the
+// capture held by ctx issued on the same context with only uniform, vertex
+// attribute and buffer binding changes between them.
+func (api) DrawBatches(ctx context.Context) ([]atom.DrawBatch, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.DrawBatch{}
+	recorders := map[*Context]*batchRecorder{}
+	flush := func(r *batchRecorder) {
+		if r.batch != nil && len(r.batch.Draws) > 1 {
+			out = append(out, *r.batch)
+		}
+		r.batch = nil
+	}
+
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if context := GetState(s).getContext(); context != nil {
+			r, ok := recorders[context]
+			if !ok {
+				r = &batchRecorder{}
+				recorders[context] = r
+			}
+			r.since++
+			switch a := a.(type) {
+			case *GlDrawArrays:
+				r.add(id, [4]interface{}{a.DrawMode, a.FirstIndex, a.IndicesCount, r.version})
+			case *GlDrawElements:
+				r.add(id, [5]interface{}{a.DrawMode, a.IndicesCount, a.IndicesType, a.Indices, r.version})
+			default:
+				name := a.Class().Schema().Name()
+				switch {
+				case strings.HasPrefix(name, "glUniform") && name != "glUniformBlockBinding":
+					// Per-draw data, which merged draws would index by draw or
+					// instance.
+				case strings.HasPrefix(name, "glVertexAttrib"),
+					strings.HasSuffix(name, "ableVertexAttribArray"),
+					name == "glBindBuffer":
+					r.version++
+				default:
+					flush(r)
+				}
+			}
+		}
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+		}
+	}
+	for _, r := range recorders {
+		flush(r)
+	}
+	sort.Sort(batchesByFirstDraw(out))
+	return out, nil
+}
+
+// add adds the draw atom id with the given parameters to the batch of the
+// last draw, or starts a new batch if there is none.
+func (r *batchRecorder) add(id atom.ID, draw interface{}) {
+	if r.batch == nil {
+		r.batch = &atom.DrawBatch{Draws: []atom.ID{id}, Commands: 1, Instanceable: true}
+	} else {
+		r.batch.Draws = append(r.batch.Draws, id)
+		r.batch.Commands += r.since
+		r.batch.Instanceable = r.batch.Instanceable && draw == r.draw
+	}
+	r.draw, r.since = draw, 0
+}
+
+// batchesByFirstDraw sorts the batches by first draw.
+type batchesByFirstDraw []atom.DrawBatch
+
+func (l batchesByFirstDraw) Len() int           { return len(l) }
+func (l batchesByFirstDraw) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l batchesByFirstDraw) Less(i, j int) bool { return l[i].Draws[0] < l[j].Draws[0] }
@@ -22,7 +22,9 @@ import (
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/capture"
 	"github.com/google/gapid/gapis/config"
+	"github.com/google/gapid/gapis/gfxapi/interop"
 )
 
 var (
@@ -35,6 +37,15 @@ var (
 	deadCodeEliminationDataLiveCounter = benchmark.GlobalCounters.Integer("deadCodeElimination.data.live")
 )
 
+// deadCodeEliminator is the interface implemented by the dead code
+// elimination transforms, for GLES only and interop captures.
+type deadCodeEliminator interface {
+	transform.Transformer
+	// Request ensures that we keep alive all atoms needed to render
+	// framebuffer at the given point.
+	Request(id atom.ID)
+}
+
 // DeadCodeElimination is an implementation of Transformer that outputs live atoms.
 // That is, all atoms which to not affect the requested output are omitted.
 // The transform generates atoms from the given AtomsID, it does not take inputs.
@@ -53,6 +64,36 @@ func newDeadCodeElimination(ctx context.Context, dependencyGraph *DependencyGrap
 	}
 }
 
+var _ = atom.DependencyTracker(api{})
+
+// Dependencies implements the atom.DependencyTracker interface.
+func (api) Dependencies(ctx context.Context, requests []atom.ID) ([]atom.ID, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if interop.IsInterop(c) {
+		g, err := interop.GetDependencyGraph(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t := interop.NewDeadCodeElimination(ctx, g)
+		for _, id := range requests {
+			t.Request(id)
+		}
+		return t.LiveAtoms(ctx), nil
+	}
+	g, err := GetDependencyGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := newDeadCodeElimination(ctx, g)
+	for _, id := range requests {
+		t.Request(id)
+	}
+	return t.liveAtoms(ctx), nil
+}
+
 // Request ensures that we keep alive all atoms needed to render framebuffer at the given point.
 func (t *DeadCodeElimination) Request(id atom.ID) {
 	t.requests.Add(id)
@@ -76,6 +117,18 @@ func (t *DeadCodeElimination) Flush(ctx context.Context, out transform.Writer) {
 	}
 }
 
+// liveAtoms returns the IDs of the atoms kept alive by the requests, in
+// increasing order.
+func (t *DeadCodeElimination) liveAtoms(ctx context.Context) []atom.ID {
+	ids := []atom.ID{}
+	for i, live := range t.propagateLiveness(ctx) {
+		if live {
+			ids = append(ids, atom.ID(i))
+		}
+	}
+	return ids
+}
+
 // See https://en.wikipedia.org/wiki/Live_variable_analysis
 func (t *DeadCodeElimination) propagateLiveness(ctx context.Context) []bool {
 	isLive := make([]bool, t.lastRequest+1)
@@ -19,6 +19,7 @@ import (
 
 	"github.com/google/gapid/core/assert"
 	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/atom/test"
 	"github.com/google/gapid/gapis/capture"
@@ -119,6 +120,18 @@ func TestDeadAtomRemoval(t *testing.T) {
 		},
 	}
 
+	programInfoWithAttributes := &ProgramInfo{
+		LinkStatus: GLboolean_GL_TRUE,
+		ActiveAttributes: AttributeIndexːActiveAttributeᵐ{
+			0: {
+				Name:      "position",
+				Type:      GLenum_GL_FLOAT_VEC4,
+				Location:  0,
+				ArraySize: 1,
+			},
+		},
+	}
+
 	ctxHandle1 := memory.Pointer{Pool: memory.ApplicationPool, Address: 1}
 	ctxHandle2 := memory.Pointer{Pool: memory.ApplicationPool, Address: 2}
 	prologue := []atom.Atom{
@@ -130,8 +143,12 @@ func TestDeadAtomRemoval(t *testing.T) {
 		NewGlCreateProgram(2),
 		atom.WithExtras(NewGlLinkProgram(1), programInfo),
 		atom.WithExtras(NewGlLinkProgram(2), programInfo),
+		NewGlCreateProgram(3),
+		atom.WithExtras(NewGlLinkProgram(3), programInfoWithAttributes),
 		NewGlUseProgram(1),
 	}
+	invalidatePtr := memory.Pointer{Pool: memory.ApplicationPool, Address: 0x1000}
+	invalidateAll := []GLenum{GLenum_GL_COLOR, GLenum_GL_DEPTH, GLenum_GL_STENCIL}
 	allBuffers := GLbitfield_GL_COLOR_BUFFER_BIT | GLbitfield_GL_DEPTH_BUFFER_BIT | GLbitfield_GL_STENCIL_BUFFER_BIT
 	tests := map[string][]atom.Atom{
 		"Draw calls up to the requested point are preserved": {
@@ -197,6 +214,35 @@ func TestDeadAtomRemoval(t *testing.T) {
 			NewGlUniform4fv(0, 1, memory.Nullptr),
 			live(NewGlDrawArrays(GLenum_GL_TRIANGLES, 0, 0)),
 		},
+		"Unused vertex attributes are removed": {
+			NewGlUseProgram(3),
+			NewGlVertexAttribPointer(0, 4, GLenum_GL_FLOAT, GLboolean_GL_FALSE, 0, memory.Nullptr),
+			dead(NewGlVertexAttribPointer(1, 4, GLenum_GL_FLOAT, GLboolean_GL_FALSE, 0, memory.Nullptr)),
+			live(NewGlDrawArrays(GLenum_GL_TRIANGLES, 0, 0)),
+		},
+		"Buffer overwrites": {
+			NewGlBindBuffer(GLenum_GL_ARRAY_BUFFER, 1),
+			dead(NewGlBufferData(GLenum_GL_ARRAY_BUFFER, 16, memory.Nullptr, GLenum_GL_STATIC_DRAW)),
+			NewGlBufferData(GLenum_GL_ARRAY_BUFFER, 16, memory.Nullptr, GLenum_GL_STATIC_DRAW),
+			dead(NewGlBufferSubData(GLenum_GL_ARRAY_BUFFER, 0, 8, memory.Nullptr)),
+			NewGlBufferSubData(GLenum_GL_ARRAY_BUFFER, 8, 8, memory.Nullptr), // Unaffected
+			NewGlBufferSubData(GLenum_GL_ARRAY_BUFFER, 0, 8, memory.Nullptr),
+			NewGlEnableVertexAttribArray(0),
+			NewGlVertexAttribPointer(0, 4, GLenum_GL_FLOAT, GLboolean_GL_FALSE, 0, memory.Nullptr),
+			live(NewGlDrawArrays(GLenum_GL_TRIANGLES, 0, 0)),
+		},
+		"Buffers not used by draw calls are removed": {
+			NewGlBindBuffer(GLenum_GL_ARRAY_BUFFER, 1),
+			dead(NewGlBufferData(GLenum_GL_ARRAY_BUFFER, 16, memory.Nullptr, GLenum_GL_STATIC_DRAW)),
+			dead(NewGlBufferSubData(GLenum_GL_ARRAY_BUFFER, 0, 8, memory.Nullptr)),
+			live(NewGlDrawArrays(GLenum_GL_TRIANGLES, 0, 0)),
+		},
+		"Invalidated framebuffer kills draw calls": {
+			dead(NewGlDrawArrays(GLenum_GL_TRIANGLES, 0, 0)),
+			NewGlInvalidateFramebuffer(GLenum_GL_FRAMEBUFFER, 3, invalidatePtr).
+				AddRead(atom.Data(ctx, device.Little32, invalidatePtr, invalidateAll)),
+			live(NewGlDrawArrays(GLenum_GL_TRIANGLES, 0, 0)),
+		},
 		"Multiple contexts": {
 			// Draw in context 1
 			dead(NewGlUniform4fv(0, 1, memory.Nullptr)),
@@ -58,6 +58,15 @@ type DependencyGraph struct {
 	behaviours []AtomBehaviour       // State reads/writes for each atom (graph edges).
 	roots      map[StateAddress]bool // State to mark live at requested atoms.
 	addressMap addressMapping        // Remap state keys to integers for performance.
+
+	// texelBuffers is the set of buffers that have been attached to a buffer
+	// texture. Shaders may read these from any draw or dispatch.
+	texelBuffers map[*Buffer]bool
+
+	// nativeBuffers maps the address of each EGLImage created from an Android
+	// native buffer to the address of that buffer. These are the images whose
+	// content can be shared with other graphics APIs.
+	nativeBuffers map[uint64]uint64
 }
 
 type AtomBehaviour struct {
@@ -131,6 +140,8 @@ func (r *DependencyGraphResolvable) Resolve(ctx context.Context) (interface{}, e
 			key:     map[StateAddress]stateKey{nullStateAddress: nil},
 			parent:  map[StateAddress]StateAddress{nullStateAddress: nullStateAddress},
 		},
+		texelBuffers:  map[*Buffer]bool{},
+		nativeBuffers: map[uint64]uint64{},
 	}
 
 	s := c.NewState()
@@ -229,6 +240,20 @@ type textureSizeKey struct {
 
 func (k textureSizeKey) Parent() stateKey { return nil }
 
+type bufferDataKey struct {
+	buffer *Buffer
+}
+
+func (k bufferDataKey) Parent() stateKey { return nil }
+
+type bufferSubDataKey struct {
+	buffer *Buffer
+	offset GLintptr
+	size   GLsizeiptr
+}
+
+func (k bufferSubDataKey) Parent() stateKey { return bufferDataKey{k.buffer} }
+
 type eglImageDataKey struct {
 	address GLeglImageOES
 }
@@ -255,6 +280,9 @@ func (k eglImageSizeKey) Parent() stateKey { return nil }
 //
 func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id atom.ID, a atom.Atom) AtomBehaviour {
 	b := AtomBehaviour{}
+	if a, ok := a.(*EglCreateImageKHR); ok && a.Target == EGLenum_EGL_NATIVE_BUFFER_ANDROID {
+		g.nativeBuffers[a.Result.Address] = a.Buffer.Address
+	}
 	c := GetContext(s)
 	if c != nil && c.Info.Initialized {
 		_, isEglSwapBuffers := a.(*EglSwapBuffers)
@@ -272,17 +300,30 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			b.write(g, renderbufferDataKey{stencil})
 		} else if a.AtomFlags().IsDrawCall() {
 			b.read(g, uniformGroupKey{c, c.BoundProgram})
-			b.read(g, vertexAttribGroupKey{c, c.BoundVertexArray})
+			for _, stateKey := range getAllUsedVertexAttribs(c) {
+				b.read(g, stateKey)
+			}
 			for _, stateKey := range getAllUsedTextureData(ctx, a, s, c) {
 				b.read(g, stateKey)
 			}
+			b.read(g, getBufferData(c, c.BoundBuffers.DrawIndirectBuffer))
+			for _, stateKey := range g.getAllUsedShaderBufferData(c) {
+				b.read(g, stateKey)
+			}
+			for _, stateKey := range getAllWrittenShaderBufferData(c) {
+				b.modify(g, stateKey)
+			}
+			if tf := c.Objects.TransformFeedbacks[c.BoundTransformFeedback]; tf != nil && tf.Active == GLboolean_GL_TRUE {
+				for _, binding := range tf.Buffers {
+					b.modify(g, getBufferData(c, binding.Binding))
+				}
+			}
 			fb := c.Objects.Framebuffers[c.BoundDrawFramebuffer]
 			for _, att := range fb.ColorAttachments {
 				b.modify(g, getAttachmentData(g, c, att))
 			}
 			b.modify(g, getAttachmentData(g, c, fb.DepthAttachment))
 			b.modify(g, getAttachmentData(g, c, fb.StencilAttachment))
-			// TODO: Write transform feedback buffers.
 		} else {
 			switch a := a.(type) {
 			case *GlClear:
@@ -314,16 +355,96 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 				texData, texSize := getTextureDataAndSize(ctx, a, s, c, c.ActiveTextureUnit, a.Target)
 				b.modify(g, texData)
 				b.write(g, texSize)
+				b.read(g, getBufferData(c, c.BoundBuffers.PixelUnpackBuffer))
 			case *GlCompressedTexSubImage2D:
 				texData, _ := getTextureDataAndSize(ctx, a, s, c, c.ActiveTextureUnit, a.Target)
 				b.modify(g, texData)
+				b.read(g, getBufferData(c, c.BoundBuffers.PixelUnpackBuffer))
 			case *GlTexImage2D:
 				texData, texSize := getTextureDataAndSize(ctx, a, s, c, c.ActiveTextureUnit, a.Target)
 				b.modify(g, texData)
 				b.write(g, texSize)
+				b.read(g, getBufferData(c, c.BoundBuffers.PixelUnpackBuffer))
 			case *GlTexSubImage2D:
 				texData, _ := getTextureDataAndSize(ctx, a, s, c, c.ActiveTextureUnit, a.Target)
 				b.modify(g, texData)
+				b.read(g, getBufferData(c, c.BoundBuffers.PixelUnpackBuffer))
+			case *GlBufferData:
+				b.write(g, getBoundBufferData(ctx, a, s, a.Target))
+			case *GlBufferSubData:
+				if data, ok := getBoundBufferData(ctx, a, s, a.Target).(bufferDataKey); ok {
+					b.write(g, bufferSubDataKey{data.buffer, a.Offset, a.Size})
+				}
+			case *GlCopyBufferSubData:
+				b.read(g, getBoundBufferData(ctx, a, s, a.ReadTarget))
+				if data, ok := getBoundBufferData(ctx, a, s, a.WriteTarget).(bufferDataKey); ok {
+					b.write(g, bufferSubDataKey{data.buffer, a.WriteOffset, a.Size})
+				}
+			case *GlCopyBufferSubDataNV:
+				b.read(g, getBoundBufferData(ctx, a, s, a.ReadTarget))
+				if data, ok := getBoundBufferData(ctx, a, s, a.WriteTarget).(bufferDataKey); ok {
+					b.write(g, bufferSubDataKey{data.buffer, a.WriteOffset, a.Size})
+				}
+			case *GlMapBuffer:
+				// The application may read and write the mapped memory.
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlMapBufferOES:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlMapBufferRange:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlMapBufferRangeEXT:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlFlushMappedBufferRange:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlFlushMappedBufferRangeEXT:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlUnmapBuffer:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlUnmapBufferOES:
+				b.modify(g, getBoundBufferData(ctx, a, s, a.Target))
+				b.KeepAlive = true
+			case *GlTexBuffer:
+				g.addTexelBuffer(c, a.Buffer)
+				b.KeepAlive = true // Changes untracked state
+			case *GlTexBufferEXT:
+				g.addTexelBuffer(c, a.Buffer)
+				b.KeepAlive = true // Changes untracked state
+			case *GlTexBufferOES:
+				g.addTexelBuffer(c, a.Buffer)
+				b.KeepAlive = true // Changes untracked state
+			case *GlTexBufferRange:
+				g.addTexelBuffer(c, a.Buffer)
+				b.KeepAlive = true // Changes untracked state
+			case *GlTexBufferRangeEXT:
+				g.addTexelBuffer(c, a.Buffer)
+				b.KeepAlive = true // Changes untracked state
+			case *GlTexBufferRangeOES:
+				g.addTexelBuffer(c, a.Buffer)
+				b.KeepAlive = true // Changes untracked state
+			case *GlDispatchCompute:
+				for _, stateKey := range g.getAllUsedShaderBufferData(c) {
+					b.read(g, stateKey)
+				}
+				for _, stateKey := range getAllWrittenShaderBufferData(c) {
+					b.modify(g, stateKey)
+				}
+				b.KeepAlive = true // Image stores are not tracked
+			case *GlDispatchComputeIndirect:
+				b.read(g, getBufferData(c, c.BoundBuffers.DispatchIndirectBuffer))
+				for _, stateKey := range g.getAllUsedShaderBufferData(c) {
+					b.read(g, stateKey)
+				}
+				for _, stateKey := range getAllWrittenShaderBufferData(c) {
+					b.modify(g, stateKey)
+				}
+				b.KeepAlive = true // Image stores are not tracked
 			case *GlUniform1fv:
 				b.write(g, uniformKey{c, c.BoundProgram, a.Location, a.Count})
 			case *GlUniform2fv:
@@ -336,9 +457,40 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 				b.write(g, uniformKey{c, c.BoundProgram, a.Location, a.Count})
 			case *GlVertexAttribPointer:
 				b.write(g, vertexAttribKey{c, c.BoundVertexArray, a.Location})
+			case *GlInvalidateFramebuffer:
+				a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+				attachments := a.Attachments.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil)
+				for _, stateKey := range getInvalidatedAttachmentData(c, a.Target, attachments, nil) {
+					b.write(g, stateKey)
+				}
+			case *GlInvalidateSubFramebuffer:
+				a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+				attachments := a.Attachments.Slice(0, uint64(a.NumAttachments), s).Read(ctx, a, s, nil)
+				region := &Rect{X: a.X, Y: a.Y, Width: a.Width, Height: a.Height}
+				for _, stateKey := range getInvalidatedAttachmentData(c, a.Target, attachments, region) {
+					b.write(g, stateKey)
+				}
+			case *GlDiscardFramebufferEXT:
+				a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+				attachments := a.Attachments.Slice(0, uint64(a.NumAttachments), s).Read(ctx, a, s, nil)
+				for _, stateKey := range getInvalidatedAttachmentData(c, a.Target, attachments, nil) {
+					b.write(g, stateKey)
+				}
 			default:
 				// Force all unhandled atoms to be kept alive.
 				b.KeepAlive = true
+				// Any unhandled blit, copy or pixel read may use the content of
+				// the read framebuffer.
+				fb := c.Objects.Framebuffers[c.BoundReadFramebuffer]
+				for _, att := range fb.ColorAttachments {
+					b.read(g, getAttachmentFullData(att))
+				}
+				b.read(g, getAttachmentFullData(fb.DepthAttachment))
+				b.read(g, getAttachmentFullData(fb.StencilAttachment))
+				// Any unhandled texture upload or pixel read may use the
+				// bound pixel buffers.
+				b.read(g, getBufferData(c, c.BoundBuffers.PixelUnpackBuffer))
+				b.modify(g, getBufferData(c, c.BoundBuffers.PixelPackBuffer))
 			}
 		}
 	} else /* c == nil */ {
@@ -379,6 +531,120 @@ func getAllUsedTextureData(ctx context.Context, a atom.Atom, s *gfxapi.State, c
 	return
 }
 
+// getAllUsedVertexAttribs returns the vertex attribute state and the vertex
+// and index buffer data read by a draw call with the current bindings.
+func getAllUsedVertexAttribs(c *Context) (stateKeys []stateKey) {
+	vao := c.Objects.VertexArrays[c.BoundVertexArray]
+	if vao == nil {
+		return []stateKey{vertexAttribGroupKey{c, c.BoundVertexArray}}
+	}
+	locations := []AttributeLocation{}
+	if prog := c.SharedObjects.Programs[c.BoundProgram]; prog != nil && len(prog.ActiveAttributes) > 0 {
+		for _, attr := range prog.ActiveAttributes {
+			if attr.Location == unusedAttributeLocation {
+				continue
+			}
+			count := attributeLocationCount(attr.Type)
+			if attr.ArraySize > 1 {
+				count *= int(attr.ArraySize)
+			}
+			for i := 0; i < count; i++ {
+				location := attr.Location + AttributeLocation(i)
+				stateKeys = append(stateKeys, vertexAttribKey{c, c.BoundVertexArray, location})
+				locations = append(locations, location)
+			}
+		}
+	} else {
+		// We know nothing about the program's inputs, so assume they are all used.
+		stateKeys = append(stateKeys, vertexAttribGroupKey{c, c.BoundVertexArray})
+		for location := range vao.VertexAttributeArrays {
+			locations = append(locations, location)
+		}
+	}
+	for _, location := range locations {
+		vaa := vao.VertexAttributeArrays[location]
+		if vaa == nil || vaa.Enabled == GLboolean_GL_FALSE {
+			continue
+		}
+		if vbb := vao.VertexBufferBindings[vaa.Binding]; vbb != nil {
+			stateKeys = append(stateKeys, getBufferData(c, vbb.Buffer))
+		}
+	}
+	stateKeys = append(stateKeys, getBufferData(c, vao.ElementArrayBuffer))
+	return
+}
+
+// unusedAttributeLocation is the location of an active attribute that is not
+// used by the program.
+const unusedAttributeLocation = AttributeLocation(0xFFFFFFFF)
+
+// attributeLocationCount returns the number of consecutive attribute locations
+// occupied by a single vertex attribute of the given type.
+func attributeLocationCount(ty GLenum) int {
+	switch ty {
+	case GLenum_GL_FLOAT_MAT2, GLenum_GL_FLOAT_MAT2x3, GLenum_GL_FLOAT_MAT2x4:
+		return 2
+	case GLenum_GL_FLOAT_MAT3, GLenum_GL_FLOAT_MAT3x2, GLenum_GL_FLOAT_MAT3x4:
+		return 3
+	case GLenum_GL_FLOAT_MAT4, GLenum_GL_FLOAT_MAT4x2, GLenum_GL_FLOAT_MAT4x3:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// getAllUsedShaderBufferData returns the buffer data that shaders may read
+// through uniform blocks and buffer textures.
+func (g *DependencyGraph) getAllUsedShaderBufferData(c *Context) (stateKeys []stateKey) {
+	for _, binding := range c.BoundBuffers.UniformBuffers {
+		stateKeys = append(stateKeys, getBufferData(c, binding.Binding))
+	}
+	for buffer := range g.texelBuffers {
+		stateKeys = append(stateKeys, bufferDataKey{buffer})
+	}
+	return
+}
+
+// getAllWrittenShaderBufferData returns the buffer data that shaders may read
+// and write through shader storage blocks and atomic counters.
+func getAllWrittenShaderBufferData(c *Context) (stateKeys []stateKey) {
+	for _, binding := range c.BoundBuffers.ShaderStorageBuffers {
+		stateKeys = append(stateKeys, getBufferData(c, binding.Binding))
+	}
+	for _, binding := range c.BoundBuffers.AtomicCounterBuffers {
+		stateKeys = append(stateKeys, getBufferData(c, binding.Binding))
+	}
+	return
+}
+
+func (g *DependencyGraph) addTexelBuffer(c *Context, id BufferId) {
+	if buffer := c.SharedObjects.Buffers[id]; buffer != nil {
+		g.texelBuffers[buffer] = true
+	}
+}
+
+// getBufferData returns the state key for the data of the buffer with the
+// given identifier, or nil if there is no such buffer.
+func getBufferData(c *Context, id BufferId) stateKey {
+	if id == 0 {
+		return nil
+	}
+	if buffer := c.SharedObjects.Buffers[id]; buffer != nil {
+		return bufferDataKey{buffer}
+	}
+	return nil
+}
+
+// getBoundBufferData returns the state key for the data of the buffer bound to
+// target, or nil if no buffer is bound.
+func getBoundBufferData(ctx context.Context, a atom.Atom, s *gfxapi.State, target GLenum) stateKey {
+	buffer, err := subGetBoundBufferOrError(ctx, a, nil, s, GetState(s), nil, target)
+	if buffer == nil || err != nil {
+		return nil
+	}
+	return bufferDataKey{buffer}
+}
+
 func getTextureDataAndSize(ctx context.Context, a atom.Atom, s *gfxapi.State, c *Context, unit, target GLenum) (stateKey, stateKey) {
 	tex, err := subGetBoundTextureForUnit(ctx, a, nil, s, GetState(s), nil, c, unit, target)
 	if tex == nil || err != nil {
@@ -422,6 +688,88 @@ func getAttachmentData(g *DependencyGraph, c *Context, att FramebufferAttachment
 	return
 }
 
+// getAttachmentFullData returns the state key for the entire content of the
+// attached image, regardless of the scissor.
+func getAttachmentFullData(att FramebufferAttachment) stateKey {
+	switch att.Type {
+	case GLenum_GL_RENDERBUFFER:
+		if rb := att.Renderbuffer; rb != nil && rb.InternalFormat != GLenum_GL_NONE {
+			return renderbufferDataKey{rb}
+		}
+	case GLenum_GL_TEXTURE:
+		if tex := att.Texture; tex != nil {
+			if tex.EGLImage != GLeglImageOES(memory.Nullptr) {
+				return eglImageDataKey{tex.EGLImage}
+			}
+			return textureDataKey{tex, tex.ID}
+		}
+	}
+	return nil
+}
+
+// getInvalidatedAttachmentData returns the state keys for the content of the
+// attachments that are discarded by an invalidate of the given framebuffer
+// target. If region is not nil, then only that area is invalidated.
+// Attachments which only make up part of a state key (such as one level of a
+// mip-mapped texture) are not returned, as the rest of the state is preserved.
+func getInvalidatedAttachmentData(c *Context, target GLenum, attachments []GLenum, region *Rect) (stateKeys []stateKey) {
+	id := c.BoundDrawFramebuffer
+	if target == GLenum_GL_READ_FRAMEBUFFER {
+		id = c.BoundReadFramebuffer
+	}
+	fb := c.Objects.Framebuffers[id]
+	if fb == nil {
+		return nil
+	}
+	invalidate := func(att FramebufferAttachment) {
+		var width, height GLsizei
+		switch att.Type {
+		case GLenum_GL_RENDERBUFFER:
+			if rb := att.Renderbuffer; rb != nil {
+				width, height = rb.Width, rb.Height
+			}
+		case GLenum_GL_TEXTURE:
+			tex := att.Texture
+			if tex == nil {
+				return
+			}
+			if tex.EGLImage == GLeglImageOES(memory.Nullptr) {
+				if tex.Kind != GLenum_GL_TEXTURE_2D || len(tex.Texture2D) != 1 || att.TextureLevel != 0 {
+					return
+				}
+			}
+			if img, ok := tex.Texture2D[0]; ok {
+				width, height = img.Width, img.Height
+			}
+		}
+		if region != nil && *region != (Rect{Width: width, Height: height}) {
+			if att.Type == GLenum_GL_RENDERBUFFER && att.Renderbuffer != nil {
+				stateKeys = append(stateKeys, renderbufferSubDataKey{att.Renderbuffer, *region})
+			}
+			return
+		}
+		stateKeys = append(stateKeys, getAttachmentFullData(att))
+	}
+	for _, attachment := range attachments {
+		switch attachment {
+		case GLenum_GL_COLOR:
+			invalidate(fb.ColorAttachments[0])
+		case GLenum_GL_DEPTH, GLenum_GL_DEPTH_ATTACHMENT:
+			invalidate(fb.DepthAttachment)
+		case GLenum_GL_STENCIL, GLenum_GL_STENCIL_ATTACHMENT:
+			invalidate(fb.StencilAttachment)
+		case GLenum_GL_DEPTH_STENCIL_ATTACHMENT:
+			invalidate(fb.DepthAttachment)
+			invalidate(fb.StencilAttachment)
+		default:
+			if attachment >= GLenum_GL_COLOR_ATTACHMENT0 && attachment <= GLenum_GL_COLOR_ATTACHMENT15 {
+				invalidate(fb.ColorAttachments[GLint(attachment-GLenum_GL_COLOR_ATTACHMENT0)])
+			}
+		}
+	}
+	return
+}
+
 func getAttachmentSize(g *DependencyGraph, c *Context, att FramebufferAttachment) (key stateKey) {
 	if att.Type == GLenum_GL_TEXTURE {
 		tex := att.Texture
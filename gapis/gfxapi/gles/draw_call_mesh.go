@@ -60,9 +60,26 @@ func drawCallMesh(ctx context.Context, dc drawCall, p *path.Mesh) (*gfxapi.Mesh,
 		return nil, nil
 	}
 
+	// GL_PRIMITIVE_RESTART_FIXED_INDEX uses the maximum value of the index type.
+	restart, restartIndex := false, uint32(0)
+	if de, ok := dc.(*GlDrawElements); ok && c.Miscellaneous.PrimitiveRestartFixedIndex == GLboolean_GL_TRUE {
+		restart = true
+		switch de.IndicesType {
+		case GLenum_GL_UNSIGNED_BYTE:
+			restartIndex = 0xff
+		case GLenum_GL_UNSIGNED_SHORT:
+			restartIndex = 0xffff
+		default:
+			restartIndex = 0xffffffff
+		}
+	}
+
 	// Look at the indices to find the number of vertices we're dealing with.
 	count := 0
 	for _, i := range indices {
+		if restart && i == restartIndex {
+			continue
+		}
 		if count <= int(i) {
 			count = int(i) + 1
 		}
@@ -116,7 +133,9 @@ func drawCallMesh(ctx context.Context, dc drawCall, p *path.Mesh) (*gfxapi.Mesh,
 	guessSemantics(vb)
 
 	ib := &gfxapi.IndexBuffer{
-		Indices: []uint32(indices),
+		Indices:          []uint32(indices),
+		PrimitiveRestart: restart,
+		RestartIndex:     restartIndex,
 	}
 
 	mesh := &gfxapi.Mesh{
@@ -0,0 +1,129 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/experiments"
+	"github.com/google/gapid/gapis/memory"
+)
+
+func init() {
+	experiments.DisableDraws.Implement(api{}, disableDrawsExperiment)
+	experiments.Scissor1x1.Implement(api{}, scissor1x1Experiment)
+	experiments.ShaderOverride.Implement(api{}, shaderOverrideExperiment)
+	experiments.ResolutionScale.Implement(api{}, resolutionScaleExperiment)
+}
+
+// disableDrawsExperiment drops the draw calls listed by the commands
+// parameter, or all draw calls if the parameter is not set.
+func disableDrawsExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	ids, err := p.IDs("commands")
+	if err != nil {
+		return nil, err
+	}
+	return transform.Transform("DisableDraws", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if a.AtomFlags().IsDrawCall() && (ids == nil || ids[i]) {
+			return
+		}
+		out.MutateAndWrite(ctx, i, a)
+	}), nil
+}
+
+// scissor1x1Experiment restricts every draw call to the bottom-left pixel of
+// the framebuffer, so that only the cost of the vertex processing remains.
+func scissor1x1Experiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	return transform.Transform("Scissor1x1", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if !a.AtomFlags().IsDrawCall() || GetContext(out.State()) == nil {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		t := newTweaker(ctx, out, i)
+		t.glEnable(GLenum_GL_SCISSOR_TEST)
+		t.glScissor(0, 0, 1, 1)
+		out.MutateAndWrite(ctx, i, a)
+		t.revert()
+	}), nil
+}
+
+// shaderOverrideExperiment replaces the source of the shader identified by
+// the shader parameter with the source parameter.
+func shaderOverrideExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	id, err := p.Uint("shader", 0)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		return nil, experiments.Invalid("shader", "a shader identifier is required")
+	}
+	src := p.String("source", "")
+	if strings.TrimSpace(src) == "" {
+		return nil, experiments.Invalid("source", "the shader source is required")
+	}
+	shader := ShaderId(id)
+	return transform.Transform("ShaderOverride", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		ss, ok := a.(*GlShaderSource)
+		if !ok || ss.Shader != shader {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		s := out.State()
+		tmpSrc := atom.Must(atom.AllocData(ctx, s, src))
+		tmpPtrToSrc := atom.Must(atom.AllocData(ctx, s, tmpSrc.Ptr()))
+		out.MutateAndWrite(ctx, i, NewGlShaderSource(shader, 1, tmpPtrToSrc.Ptr(), memory.Nullptr).
+			AddRead(tmpSrc.Data()).
+			AddRead(tmpPtrToSrc.Data()))
+		tmpPtrToSrc.Free()
+		tmpSrc.Free()
+	}), nil
+}
+
+// resolutionScaleExperiment scales the viewport and scissor rectangle of every
+// draw call by the scale parameter.
+func resolutionScaleExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	scale, err := p.Float("scale", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if scale <= 0 || scale > 1 {
+		return nil, experiments.Invalid("scale", "the scale must be in the range (0, 1]")
+	}
+	scaleRect := func(r Rect) (GLint, GLint, GLsizei, GLsizei) {
+		return GLint(float64(r.X) * scale), GLint(float64(r.Y) * scale),
+			GLsizei(float64(r.Width) * scale), GLsizei(float64(r.Height) * scale)
+	}
+	return transform.Transform("ResolutionScale", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		c := GetContext(out.State())
+		if !a.AtomFlags().IsDrawCall() || c == nil {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		t := newTweaker(ctx, out, i)
+		// The initial viewport is not tracked by the state, so only viewports
+		// explicitly set by the application can be scaled.
+		if v := c.Rasterization.Viewport; v.Width > 0 && v.Height > 0 {
+			t.glViewport(scaleRect(v))
+		}
+		if s := c.FragmentOperations.Scissor.Box; s.Width > 0 && s.Height > 0 {
+			t.glScissor(scaleRect(s))
+		}
+		out.MutateAndWrite(ctx, i, a)
+		t.revert()
+	}), nil
+}
@@ -305,6 +305,26 @@ func getCompressedImageFormat(format GLenum) (*image.Format, error) {
 		return image.NewS3_DXT3_RGBA("GL_COMPRESSED_RGBA_S3TC_DXT3_EXT"), nil
 	case GLenum_GL_COMPRESSED_RGBA_S3TC_DXT5_EXT:
 		return image.NewS3_DXT5_RGBA("GL_COMPRESSED_RGBA_S3TC_DXT5_EXT"), nil
+
+	// RGTC
+	case GLenum_GL_COMPRESSED_RED_RGTC1:
+		return image.NewBC4_R_U8_NORM("GL_COMPRESSED_RED_RGTC1"), nil
+	case GLenum_GL_COMPRESSED_SIGNED_RED_RGTC1:
+		return image.NewBC4_R_S8_NORM("GL_COMPRESSED_SIGNED_RED_RGTC1"), nil
+	case GLenum_GL_COMPRESSED_RG_RGTC2:
+		return image.NewBC5_RG_U8_NORM("GL_COMPRESSED_RG_RGTC2"), nil
+	case GLenum_GL_COMPRESSED_SIGNED_RG_RGTC2:
+		return image.NewBC5_RG_S8_NORM("GL_COMPRESSED_SIGNED_RG_RGTC2"), nil
+
+	// BPTC
+	case GLenum_GL_COMPRESSED_RGBA_BPTC_UNORM:
+		return image.NewBC7_RGBA_U8_NORM("GL_COMPRESSED_RGBA_BPTC_UNORM"), nil
+	case GLenum_GL_COMPRESSED_SRGB_ALPHA_BPTC_UNORM:
+		return image.NewBC7_SRGBA_U8_NORM("GL_COMPRESSED_SRGB_ALPHA_BPTC_UNORM"), nil
+	case GLenum_GL_COMPRESSED_RGB_BPTC_SIGNED_FLOAT:
+		return image.NewBC6H_RGB_SF16("GL_COMPRESSED_RGB_BPTC_SIGNED_FLOAT"), nil
+	case GLenum_GL_COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT:
+		return image.NewBC6H_RGB_UF16("GL_COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT"), nil
 	}
 
 	return nil, fmt.Errorf("Unsupported compressed format: %s", format)
@@ -0,0 +1,94 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/interop"
+)
+
+// Interface compliance tests
+var (
+	_ = interop.GraphProvider(api{})
+	_ = interop.Graph(&DependencyGraph{})
+)
+
+// InteropDependencyGraph implements the interop.GraphProvider interface.
+func (api) InteropDependencyGraph(ctx context.Context) (interop.Graph, error) {
+	return GetDependencyGraph(ctx)
+}
+
+// API implements the interop.Graph interface.
+func (g *DependencyGraph) API() gfxapi.API { return api{} }
+
+// NumStates implements the interop.Graph interface.
+func (g *DependencyGraph) NumStates() int { return len(g.addressMap.parent) }
+
+// Parent implements the interop.Graph interface.
+func (g *DependencyGraph) Parent(address interop.StateAddress) interop.StateAddress {
+	return interop.StateAddress(g.addressMap.parent[StateAddress(address)])
+}
+
+// Behaviour implements the interop.Graph interface.
+func (g *DependencyGraph) Behaviour(i int) interop.Behaviour {
+	b := g.behaviours[i]
+	return interop.Behaviour{
+		Read:      toInteropAddresses(b.Read),
+		Modify:    toInteropAddresses(b.Modify),
+		Write:     toInteropAddresses(b.Write),
+		KeepAlive: b.KeepAlive,
+		Aborted:   b.Aborted,
+	}
+}
+
+// Roots implements the interop.Graph interface.
+func (g *DependencyGraph) Roots() []interop.StateAddress {
+	out := make([]interop.StateAddress, 0, len(g.roots))
+	for root := range g.roots {
+		out = append(out, interop.StateAddress(root))
+	}
+	return out
+}
+
+// SharedResources implements the interop.Graph interface.
+// The content of EGLImages created from Android native buffers is shared with
+// any other API that imports the same buffer.
+func (g *DependencyGraph) SharedResources() map[interop.StateAddress]interop.SharedResource {
+	out := map[interop.StateAddress]interop.SharedResource{}
+	for address, key := range g.addressMap.key {
+		if key, ok := key.(eglImageDataKey); ok {
+			if buffer, ok := g.nativeBuffers[key.address.Address]; ok {
+				out[interop.StateAddress(address)] = interop.SharedResource{
+					Kind:   interop.NativeBuffer,
+					Handle: buffer,
+				}
+			}
+		}
+	}
+	return out
+}
+
+func toInteropAddresses(addresses []StateAddress) []interop.StateAddress {
+	if len(addresses) == 0 {
+		return nil
+	}
+	out := make([]interop.StateAddress, len(addresses))
+	for i, a := range addresses {
+		out[i] = interop.StateAddress(a)
+	}
+	return out
+}
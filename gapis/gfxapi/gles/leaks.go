@@ -0,0 +1,124 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+)
+
+var _ = atom.LeakTracker(api{})
+
+// createdObject is a GLES object generated by an atom of the capture.
+type createdObject struct {
+	created atom.ID
+	kind    string
+	name    uint32
+	owner   *Context
+}
+
+// Leaks returns the GLES objects generated by the capture held by ctx and
+// never deleted.
+func (api) Leaks(ctx context.Context) ([]atom.Leak, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []createdObject{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		owner := GetState(s).getContext()
+		if owner == nil {
+			continue
+		}
+		add := func(kind string, name uint32) {
+			objects = append(objects, createdObject{id, kind, name, owner})
+		}
+		switch a := a.(type) {
+		case *GlGenTextures:
+			for _, t := range a.Textures.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil) {
+				add("Texture", uint32(t))
+			}
+		case *GlGenBuffers:
+			for _, b := range a.Buffers.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil) {
+				add("Buffer", uint32(b))
+			}
+		case *GlGenRenderbuffers:
+			for _, r := range a.Renderbuffers.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil) {
+				add("Renderbuffer", uint32(r))
+			}
+		case *GlGenSamplers:
+			for _, r := range a.Samplers.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil) {
+				add("Sampler", uint32(r))
+			}
+		case *GlGenFramebuffers:
+			for _, f := range a.Framebuffers.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil) {
+				add("Framebuffer", uint32(f))
+			}
+		case *GlGenVertexArrays:
+			for _, v := range a.Arrays.Slice(0, uint64(a.Count), s).Read(ctx, a, s, nil) {
+				add("VertexArray", uint32(v))
+			}
+		case *GlCreateShader:
+			add("Shader", uint32(a.Result))
+		case *GlCreateProgram:
+			add("Program", uint32(a.Result))
+		}
+	}
+
+	leaks := []atom.Leak{}
+	for _, o := range objects {
+		shared, objs := o.owner.SharedObjects, o.owner.Objects
+		leak := atom.Leak{Created: o.created, Kind: o.kind, Handle: uint64(o.name)}
+		alive := false
+		switch o.kind {
+		case "Texture":
+			alive = shared.Textures.Contains(TextureId(o.name))
+		case "Buffer":
+			if b, ok := shared.Buffers[BufferId(o.name)]; ok {
+				alive, leak.Size = true, uint64(b.Size)
+			}
+		case "Renderbuffer":
+			alive = shared.Renderbuffers.Contains(RenderbufferId(o.name))
+		case "Sampler":
+			alive = shared.Samplers.Contains(SamplerId(o.name))
+		case "Shader":
+			alive = shared.Shaders.Contains(ShaderId(o.name))
+		case "Program":
+			alive = shared.Programs.Contains(ProgramId(o.name))
+		case "Framebuffer":
+			alive = objs.Framebuffers.Contains(FramebufferId(o.name))
+		case "VertexArray":
+			alive = objs.VertexArrays.Contains(VertexArrayId(o.name))
+		}
+		if alive {
+			leaks = append(leaks, leak)
+		}
+	}
+	return leaks, nil
+}
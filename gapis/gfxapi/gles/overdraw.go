@@ -0,0 +1,194 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+)
+
+// overdraw is an atom transform that counts the number of times each pixel is
+// drawn by the draw calls of the frame holding the atom after, using the
+// stencil buffer, and replaces the color buffer with a heatmap of the counts
+// after the atom.
+// Backbuffers captured without a stencil buffer are given one for the replay,
+// while framebuffer objects must have a stencil attachment for the counts to
+// be recorded, otherwise the result reports an error.
+type overdraw struct {
+	after        atom.ID
+	clearStencil bool
+	done         bool
+	// The reason the counts could not be recorded, or nil.
+	err error
+}
+
+func newOverdraw(ctx context.Context, after atom.ID) *overdraw {
+	return &overdraw{after: after, clearStencil: true}
+}
+
+// result returns a replay.Result forwarding the framebuffer read to res,
+// unless the counts could not be recorded, in which case it reports why.
+func (t *overdraw) result(res replay.Result) replay.Result {
+	return func(val interface{}, err error) {
+		if err == nil && t.err != nil {
+			res(nil, t.err)
+			return
+		}
+		res(val, err)
+	}
+}
+
+func (t *overdraw) Transform(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+	if t.done {
+		out.MutateAndWrite(ctx, i, a)
+		return
+	}
+	if i == t.after && a.AtomFlags().IsEndOfFrame() {
+		// The color buffer is undefined after the frame is presented.
+		t.drawOverdraw(ctx, i, out)
+		out.MutateAndWrite(ctx, i, a)
+		t.done = true
+		return
+	}
+	switch a := a.(type) {
+	case *EglMakeCurrent:
+		if cs := FindDynamicContextState(a.Extras()); cs != nil && cs.BackbufferStencilFmt == GLenum_GL_NONE {
+			a = cloneAtom(a).(*EglMakeCurrent)
+			FindDynamicContextState(a.Extras()).BackbufferStencilFmt = GLenum_GL_STENCIL_INDEX8
+		}
+		out.MutateAndWrite(ctx, i, a)
+	case drawCall:
+		if GetContext(out.State()) == nil || !hasStencil(out.State()) {
+			// Draws to framebuffers without a stencil buffer are not counted.
+			out.MutateAndWrite(ctx, i, a)
+			break
+		}
+		tw := newTweaker(ctx, out, i)
+		tw.glEnable(GLenum_GL_STENCIL_TEST)
+		tw.glStencilMask(0xff)
+		if t.clearStencil {
+			tw.glClearStencil(0)
+			out.MutateAndWrite(ctx, i.Derived(), NewGlClear(GLbitfield_GL_STENCIL_BUFFER_BIT))
+			t.clearStencil = false
+		}
+		tw.glStencilFunc(GLenum_GL_ALWAYS, 0, 0xff)
+		tw.glStencilOp(GLenum_GL_KEEP, GLenum_GL_KEEP, GLenum_GL_INCR)
+		out.MutateAndWrite(ctx, i, a)
+		tw.revert()
+	default:
+		out.MutateAndWrite(ctx, i, a)
+	}
+	if a.AtomFlags().IsEndOfFrame() {
+		t.clearStencil = true
+	}
+	if i == t.after {
+		t.drawOverdraw(ctx, i, out)
+		t.done = true
+	}
+}
+
+func (t *overdraw) Flush(ctx context.Context, out transform.Writer) {}
+
+// hasStencil returns true if the bound draw framebuffer has a stencil buffer.
+func hasStencil(s *gfxapi.State) bool {
+	_, _, format, err := GetState(s).getFramebufferAttachmentInfo(gfxapi.FramebufferAttachment_Stencil)
+	return err == nil && format != GLenum_GL_NONE
+}
+
+// drawOverdraw draws the heatmap of the overdraw counts held by the stencil
+// buffer over the whole color buffer.
+func (t *overdraw) drawOverdraw(ctx context.Context, id atom.ID, out transform.Writer) {
+	const (
+		aScreenCoordsLocation AttributeLocation = 0
+
+		vertexShaderSource string = `
+					precision highp float;
+					attribute vec2 aScreenCoords;
+
+					void main() {
+						gl_Position = vec4(aScreenCoords.xy, 0., 1.);
+					}`
+		fragmentShaderSource string = `
+					precision highp float;
+
+					void main() {
+						gl_FragColor = vec4(%f, %f, %f, 1.0);
+					}`
+	)
+
+	s := out.State()
+	c := GetContext(s)
+	if c == nil {
+		return
+	}
+	width, height, _, err := GetState(s).getFramebufferAttachmentInfo(gfxapi.FramebufferAttachment_Color0)
+	if err != nil {
+		log.E(ctx, "Could not draw the overdraw heatmap: %v", err)
+		return
+	}
+	if !hasStencil(s) {
+		t.err = &service.ErrDataUnavailable{
+			Reason: messages.ErrMessage("Overdraw needs the framebuffer to have a stencil buffer"),
+		}
+		return
+	}
+
+	// 2D vertices positions for a full screen 2D triangle strip.
+	positions := []float32{-1., -1., 1., -1., -1., 1., 1., 1.}
+
+	dID := id.Derived()
+	tw := newTweaker(ctx, out, id)
+
+	tw.glDisable(GLenum_GL_BLEND)
+	tw.glDisable(GLenum_GL_CULL_FACE)
+	tw.glDisable(GLenum_GL_DEPTH_TEST)
+	tw.glDisable(GLenum_GL_SCISSOR_TEST)
+	tw.glEnable(GLenum_GL_STENCIL_TEST)
+	tw.glStencilMask(0)
+	tw.glStencilOp(GLenum_GL_KEEP, GLenum_GL_KEEP, GLenum_GL_KEEP)
+	tw.glViewport(0, 0, GLsizei(width), GLsizei(height))
+	tw.makeVertexArray(aScreenCoordsLocation)
+
+	bufferID := tw.glGenBuffer()
+	tw.GlBindBuffer_ArrayBuffer(bufferID)
+
+	tmp := tw.AllocData(positions)
+	out.MutateAndWrite(ctx, dID, NewGlBufferData(GLenum_GL_ARRAY_BUFFER, GLsizeiptr(4*len(positions)), tmp.Ptr(), GLenum_GL_STATIC_DRAW).
+		AddRead(tmp.Data()))
+	out.MutateAndWrite(ctx, dID, NewGlVertexAttribPointer(aScreenCoordsLocation, 2, GLenum_GL_FLOAT, GLboolean(0), 0, memory.Nullptr))
+
+	// Each level is drawn over the pixels drawn at least that many times, so
+	// that the pixels end up with the color of their count.
+	for level, color := range replay.OverdrawColors {
+		fs := fmt.Sprintf(fragmentShaderSource, color[0], color[1], color[2])
+		programID := tw.makeProgram(vertexShaderSource, fs)
+		out.MutateAndWrite(ctx, dID, NewGlBindAttribLocation(programID, aScreenCoordsLocation, "aScreenCoords"))
+		out.MutateAndWrite(ctx, dID, NewGlLinkProgram(programID))
+		tw.glUseProgram(programID)
+		tw.glStencilFunc(GLenum_GL_LEQUAL, GLint(level), 0xff)
+		out.MutateAndWrite(ctx, dID, NewGlDrawArrays(GLenum_GL_TRIANGLE_STRIP, 0, 4))
+	}
+
+	tw.revert()
+}
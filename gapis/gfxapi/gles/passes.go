@@ -0,0 +1,66 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.DrawDescriber(api{})
+
+// DescribeDraw implements the atom.DrawDescriber interface.
+func (api) DescribeDraw(ctx context.Context, s *gfxapi.State, a atom.Atom) (atom.DrawInfo, bool) {
+	if _, ok := a.(drawCall); !ok {
+		return atom.DrawInfo{}, false
+	}
+	c := GetContext(s)
+	if c == nil {
+		return atom.DrawInfo{}, false
+	}
+
+	info := atom.DrawInfo{
+		Target:  uint64(c.BoundDrawFramebuffer),
+		Program: uint64(c.BoundProgram),
+		DepthWrite: c.FragmentOperations.Depth.Test == GLboolean_GL_TRUE &&
+			c.Framebuffer.DepthWritemask == GLboolean_GL_TRUE,
+	}
+
+	switch a := a.(type) {
+	case *GlDrawArrays:
+		info.Vertices = uint64(a.IndicesCount)
+	case *GlDrawElements:
+		info.Vertices = uint64(a.IndicesCount)
+	}
+
+	// The color write mask defaults to all channels for draw buffers that
+	// were never masked.
+	info.ColorWrite = len(c.Framebuffer.ColorWritemask) == 0
+	for _, m := range c.Framebuffer.ColorWritemask {
+		for _, e := range m.Elements {
+			if e == GLboolean_GL_TRUE {
+				info.ColorWrite = true
+			}
+		}
+	}
+	for _, b := range c.FragmentOperations.Blend {
+		if b.Enabled == GLboolean_GL_TRUE {
+			info.Blend = true
+		}
+	}
+	return info, true
+}
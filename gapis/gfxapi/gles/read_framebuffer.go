@@ -57,13 +57,28 @@ func (t *readFramebuffer) Flush(ctx context.Context, out transform.Writer) {}
 func (t *readFramebuffer) Depth(id atom.ID, res replay.Result) {
 	t.injections[id] = append(t.injections[id], func(ctx context.Context, out transform.Writer) {
 		s := out.State()
+		c := GetContext(s)
 		width, height, format, err := GetState(s).getFramebufferAttachmentInfo(gfxapi.FramebufferAttachment_Depth)
 		if err != nil {
 			res(nil, &service.ErrDataUnavailable{Reason: messages.ErrFramebufferUnavailable()})
 			return
 		}
 
+		if GetState(s).getFramebufferAttachmentSamples(gfxapi.FramebufferAttachment_Depth) == 0 {
+			postColorData(ctx, s, int32(width), int32(height), format, out, id, res)
+			return
+		}
+
+		// Depth can only be read from a single-sampled framebuffer.
+		dID := id.Derived()
+		t := newTweaker(ctx, out, dID)
+		t.glBindFramebuffer_Read(c.BoundDrawFramebuffer)
+		resolveMultisample(ctx, t, out, dID, format, int32(width), int32(height),
+			GLenum_GL_DEPTH_ATTACHMENT, GLbitfield_GL_DEPTH_BUFFER_BIT)
+
 		postColorData(ctx, s, int32(width), int32(height), format, out, id, res)
+
+		t.revert()
 	})
 }
 
@@ -112,6 +127,12 @@ func (t *readFramebuffer) Color(id atom.ID, width, height, bufferIdx uint32, res
 			t.glReadBuffer(GLenum_GL_COLOR_ATTACHMENT0 + GLenum(bufferIdx))
 		}
 
+		if GetState(s).getFramebufferAttachmentSamples(attachment) > 0 {
+			// Multisampled framebuffers cannot be read or scaled directly.
+			resolveMultisample(ctx, t, out, dID, fmt, inW, inH,
+				GLenum_GL_COLOR_ATTACHMENT0, GLbitfield_GL_COLOR_BUFFER_BIT)
+		}
+
 		if inW == outW && inH == outH {
 			postColorData(ctx, s, outW, outH, fmt, out, id, res)
 		} else {
@@ -135,6 +156,33 @@ func (t *readFramebuffer) Color(id atom.ID, width, height, bufferIdx uint32, res
 	})
 }
 
+// resolveMultisample resolves the buffer selected by mask of the currently
+// bound read framebuffer into a new single-sampled renderbuffer of the given
+// format and size. The framebuffer holding the resolved renderbuffer is then
+// bound as the read framebuffer.
+func resolveMultisample(ctx context.Context,
+	t *tweaker,
+	out transform.Writer,
+	id atom.ID,
+	sizedFormat GLenum,
+	width, height int32,
+	attachment GLenum,
+	mask GLbitfield) {
+
+	t.glScissor(0, 0, GLsizei(width), GLsizei(height))
+	framebufferID := t.glGenFramebuffer()
+	t.glBindFramebuffer_Draw(framebufferID)
+	renderbufferID := t.glGenRenderbuffer()
+	t.glBindRenderbuffer(renderbufferID)
+
+	mutateAndWriteEach(ctx, out, id,
+		NewGlRenderbufferStorage(GLenum_GL_RENDERBUFFER, sizedFormat, GLsizei(width), GLsizei(height)),
+		NewGlFramebufferRenderbuffer(GLenum_GL_DRAW_FRAMEBUFFER, attachment, GLenum_GL_RENDERBUFFER, renderbufferID),
+		NewGlBlitFramebuffer(0, 0, GLint(width), GLint(height), 0, 0, GLint(width), GLint(height), mask, GLenum_GL_NEAREST),
+	)
+	t.glBindFramebuffer_Read(framebufferID)
+}
+
 func postColorData(ctx context.Context,
 	s *gfxapi.State,
 	width, height int32,
@@ -0,0 +1,51 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+)
+
+// recordedValues returns a transform that drops the queries for query results
+// and timestamps from the replay, so that the values recorded in the capture
+// are used instead of those of the replay device.
+func recordedValues(ctx context.Context) transform.Transformer {
+	return transform.Transform("RecordedValues", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if !isNondeterministicQuery(a) {
+			out.MutateAndWrite(ctx, i, a)
+		}
+	})
+}
+
+// isNondeterministicQuery returns true if a returns a value to the application
+// that depends on the timing or results of GPU work.
+func isNondeterministicQuery(a atom.Atom) bool {
+	switch a := a.(type) {
+	case *GlGetQueryObjectuiv,
+		*GlGetQueryObjectivEXT,
+		*GlGetQueryObjectuivEXT,
+		*GlGetQueryObjecti64vEXT,
+		*GlGetQueryObjectui64vEXT:
+		return true
+	case *GlGetInteger64v:
+		return a.Param == GLenum_GL_TIMESTAMP_EXT || a.Param == GLenum_GL_GPU_DISJOINT_EXT
+	case *GlGetIntegerv:
+		return a.Param == GLenum_GL_TIMESTAMP_EXT || a.Param == GLenum_GL_GPU_DISJOINT_EXT
+	}
+	return false
+}
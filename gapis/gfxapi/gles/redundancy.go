@@ -0,0 +1,173 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+)
+
+var _ = atom.RedundancyChecker(api{})
+
+// glSlot identifies a piece of the state of a context.
+type glSlot struct {
+	context *Context
+	state   string
+	key     interface{}
+}
+
+// bindingStates are the pieces of state holding object names, which are reset
+// when the objects are deleted.
+var bindingStates = map[string]bool{
+	"program":          true,
+	"texture":          true,
+	"buffer":           true,
+	"draw-framebuffer": true,
+	"read-framebuffer": true,
+	"renderbuffer":     true,
+	"vertex-array":     true,
+}
+
+// Redundancies returns the atoms of the capture held by ctx that enable or
+// disable capabilities, set the viewport, scissor, depth, culling or clear
+// color state, or bind programs, textures, buffers, framebuffers,
+// renderbuffers and vertex arrays, to the values held by the current context.
+// The state is only known once set by the capture, so the atoms setting the
+// state inherited from before the start of the capture are never reported.
+func (api) Redundancies(ctx context.Context) ([]atom.Redundancy, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.Redundancy{}
+	slots := atom.StateSlots{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if context := GetState(s).getContext(); context != nil {
+			values := glStateValues(context, a)
+			if len(values) > 0 {
+				if previous, ok := slots.Set(id, values...); ok {
+					out = append(out, atom.Redundancy{Atom: id, Previous: previous})
+				}
+			}
+			forgetGLState(slots, context, a, len(values) > 0)
+		}
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+		}
+	}
+	return out, nil
+}
+
+// glStateValues returns the values that the atom a sets to the state of the
+// context c.
+func glStateValues(c *Context, a atom.Atom) []atom.SlotValue {
+	value := func(state string, key, v interface{}) []atom.SlotValue {
+		return []atom.SlotValue{{Slot: glSlot{c, state, key}, Value: v}}
+	}
+	switch a := a.(type) {
+	case *GlEnable:
+		return value("capability", a.Capability, true)
+	case *GlDisable:
+		return value("capability", a.Capability, false)
+	case *GlViewport:
+		return value("viewport", nil, [4]interface{}{a.X, a.Y, a.Width, a.Height})
+	case *GlScissor:
+		return value("scissor", nil, [4]interface{}{a.X, a.Y, a.Width, a.Height})
+	case *GlDepthFunc:
+		return value("depth-func", nil, a.Function)
+	case *GlDepthMask:
+		return value("depth-mask", nil, a.Enabled)
+	case *GlCullFace:
+		return value("cull-face", nil, a.Mode)
+	case *GlFrontFace:
+		return value("front-face", nil, a.Orientation)
+	case *GlLineWidth:
+		return value("line-width", nil, a.Width)
+	case *GlClearColor:
+		return value("clear-color", nil, [4]interface{}{a.R, a.G, a.B, a.A})
+	case *GlActiveTexture:
+		return value("active-texture", nil, a.Unit)
+	case *GlUseProgram:
+		return value("program", nil, a.Program)
+	case *GlBindTexture:
+		return value("texture", [2]interface{}{c.ActiveTextureUnit, a.Target}, a.Texture)
+	case *GlBindBuffer:
+		return value("buffer", a.Target, a.Buffer)
+	case *GlBindRenderbuffer:
+		return value("renderbuffer", a.Target, a.Renderbuffer)
+	case *GlBindVertexArray:
+		return value("vertex-array", nil, a.Array)
+	case *GlBindVertexArrayOES:
+		return value("vertex-array", nil, a.Array)
+	case *GlBindFramebuffer:
+		switch a.Target {
+		case GLenum_GL_DRAW_FRAMEBUFFER:
+			return value("draw-framebuffer", nil, a.Framebuffer)
+		case GLenum_GL_READ_FRAMEBUFFER:
+			return value("read-framebuffer", nil, a.Framebuffer)
+		default:
+			return append(value("draw-framebuffer", nil, a.Framebuffer), value("read-framebuffer", nil, a.Framebuffer)...)
+		}
+	}
+	return nil
+}
+
+// forgetGLState forgets the values of the state of the context c that the
+// atom a changes as a side effect, or without being handled by glStateValues.
+func forgetGLState(slots atom.StateSlots, c *Context, a atom.Atom, handled bool) {
+	forget := func(f func(s glSlot) bool) {
+		slots.Forget(func(slot interface{}) bool {
+			s, ok := slot.(glSlot)
+			return ok && f(s)
+		})
+	}
+	switch a.(type) {
+	case *GlBindVertexArray, *GlBindVertexArrayOES:
+		// The element array buffer binding is part of the vertex array state.
+		forget(func(s glSlot) bool {
+			return s.context == c && s.state == "buffer" && s.key == GLenum_GL_ELEMENT_ARRAY_BUFFER
+		})
+		return
+	}
+	if handled {
+		return
+	}
+	name := a.Class().Schema().Name()
+	switch {
+	case strings.HasPrefix(name, "glDelete"):
+		// Deleted objects are unbound from all the contexts sharing them.
+		forget(func(s glSlot) bool { return bindingStates[s.state] })
+	case strings.HasPrefix(name, "glEnablei"), strings.HasPrefix(name, "glDisablei"):
+		forget(func(s glSlot) bool { return s.context == c && s.state == "capability" })
+	case strings.HasPrefix(name, "glBindBuffer"):
+		// glBindBufferBase and glBindBufferRange also set the generic binding.
+		forget(func(s glSlot) bool { return s.context == c && s.state == "buffer" })
+	case strings.HasPrefix(name, "glViewport"):
+		forget(func(s glSlot) bool { return s.context == c && s.state == "viewport" })
+	case strings.HasPrefix(name, "glScissor"):
+		forget(func(s glSlot) bool { return s.context == c && s.state == "scissor" })
+	}
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/google/gapid/gapis/capture"
 	"github.com/google/gapid/gapis/config"
 	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/interop"
+	"github.com/google/gapid/gapis/messages"
 	"github.com/google/gapid/gapis/replay"
 	"github.com/google/gapid/gapis/service"
 )
@@ -35,6 +37,9 @@ var (
 	// Interface compliance tests
 	_ = replay.QueryIssues(api{})
 	_ = replay.QueryFramebufferAttachment(api{})
+	_ = replay.QuerySkippedCommands(api{})
+	_ = replay.QueryTextureOverride(api{})
+	_ = replay.QueryStateOverride(api{})
 	_ = replay.Support(api{})
 )
 
@@ -45,7 +50,16 @@ type issuesConfig struct{}
 // depthBufferRequests.
 type drawConfig struct {
 	wireframeMode      replay.WireframeMode
-	wireframeOverlayID atom.ID // used when wireframeMode == WireframeMode_Overlay
+	wireframeOverlayID atom.ID // used when wireframeMode is WireframeMode_Overlay or WireframeMode_Overdraw
+	// The commands whose rendering is skipped, or nil. As the ranges are held
+	// by pointer, configs skipping commands are never batched together.
+	skip *atom.RangeList
+	// The texture whose data is replaced, or nil. As for skip, configs
+	// overriding textures are never batched together.
+	override *textureOverride
+	// The fixed-function state forced on the draw calls, or nil. As for skip,
+	// configs overriding state are never batched together.
+	state *replay.StateOverride
 }
 
 // uniqueConfig returns a replay.Config that is guaranteed to be unique.
@@ -93,20 +107,29 @@ func (a api) Replay(
 	if err != nil {
 		return log.Err(ctx, err, "Failed to load atom stream")
 	}
+	recorded := atom.HasRecordedValues(atoms)
 
 	transforms := transform.Transforms{}
 
 	// Gathers and reports any issues found.
 	var issues *findIssues
 
-	// Prepare data for dead-code-elimination.
-	dependencyGraph, err := GetDependencyGraph(ctx)
-	if err != nil {
-		return err
-	}
-
 	// Skip unnecessary atoms.
-	deadCodeElimination := newDeadCodeElimination(ctx, dependencyGraph)
+	var deadCodeElimination deadCodeEliminator
+	if interop.IsInterop(capture) {
+		// The capture also uses other APIs, trim the atoms of all of them.
+		dependencyGraph, err := interop.GetDependencyGraph(ctx)
+		if err != nil {
+			return err
+		}
+		deadCodeElimination = interop.NewDeadCodeElimination(ctx, dependencyGraph)
+	} else {
+		dependencyGraph, err := GetDependencyGraph(ctx)
+		if err != nil {
+			return err
+		}
+		deadCodeElimination = newDeadCodeElimination(ctx, dependencyGraph)
+	}
 
 	// Transform for all framebuffer reads.
 	readFramebuffer := newReadFramebuffer(ctx)
@@ -129,22 +152,27 @@ func (a api) Replay(
 			// TODO: Remove this and handle swap-buffers better.
 			deadCodeElimination.Request(req.after - 1)
 
-			switch req.attachment {
-			case gfxapi.FramebufferAttachment_Depth:
-				readFramebuffer.Depth(req.after, rr.Result)
-			case gfxapi.FramebufferAttachment_Stencil:
-				return fmt.Errorf("Stencil buffer attachments are not currently supported")
-			default:
-				idx := uint32(req.attachment - gfxapi.FramebufferAttachment_Color0)
-				readFramebuffer.Color(req.after, req.width, req.height, idx, rr.Result)
-			}
-
+			res := rr.Result
 			cfg := cfg.(drawConfig)
 			switch cfg.wireframeMode {
 			case replay.WireframeMode_All:
 				wire = true
 			case replay.WireframeMode_Overlay:
 				transforms.Add(wireframeOverlay(ctx, req.after))
+			case replay.WireframeMode_Overdraw:
+				o := newOverdraw(ctx, req.after)
+				transforms.Add(o)
+				res = o.result(res)
+			}
+
+			switch req.attachment {
+			case gfxapi.FramebufferAttachment_Depth:
+				readFramebuffer.Depth(req.after, res)
+			case gfxapi.FramebufferAttachment_Stencil:
+				return fmt.Errorf("Stencil buffer attachments are not currently supported")
+			default:
+				idx := uint32(req.attachment - gfxapi.FramebufferAttachment_Color0)
+				readFramebuffer.Color(req.after, req.width, req.height, idx, res)
 			}
 		}
 	}
@@ -154,6 +182,16 @@ func (a api) Replay(
 		transforms.Prepend(deadCodeElimination)
 	}
 
+	if cfg, ok := cfg.(drawConfig); ok && cfg.skip != nil {
+		transforms.Add(skipCommands(ctx, *cfg.skip))
+	}
+	if cfg, ok := cfg.(drawConfig); ok && cfg.override != nil {
+		transforms.Add(overrideTexture(ctx, *cfg.override))
+	}
+	if cfg, ok := cfg.(drawConfig); ok && cfg.state != nil {
+		transforms.Add(overrideState(ctx, *cfg.state))
+	}
+
 	if wire {
 		transforms.Add(wireframe(ctx))
 	}
@@ -168,6 +206,11 @@ func (a api) Replay(
 
 	transforms.Add(readFramebuffer)
 
+	// Feed back the query results and timestamps held by the capture.
+	if recorded {
+		transforms.Add(recordedValues(ctx))
+	}
+
 	// Device-dependent transforms.
 	if c, err := compat(ctx, device); err == nil {
 		transforms.Add(c)
@@ -229,7 +272,7 @@ func (a api) QueryFramebufferAttachment(
 	hints *service.UsageHints) (*image.Image2D, error) {
 
 	c := drawConfig{wireframeMode: wireframeMode}
-	if wireframeMode == replay.WireframeMode_Overlay {
+	if wireframeMode == replay.WireframeMode_Overlay || wireframeMode == replay.WireframeMode_Overdraw {
 		c.wireframeOverlayID = after
 	}
 	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
@@ -240,6 +283,70 @@ func (a api) QueryFramebufferAttachment(
 	return res.(*image.Image2D), nil
 }
 
+func (a api) QuerySkippedCommands(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	after atom.ID,
+	skip atom.RangeList,
+	width, height uint32,
+	attachment gfxapi.FramebufferAttachment,
+	hints *service.UsageHints) (*image.Image2D, error) {
+
+	c := drawConfig{skip: &skip}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*image.Image2D), nil
+}
+
+func (a api) QueryTextureOverride(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	after atom.ID,
+	texture gfxapi.Resource,
+	pattern replay.TexturePattern,
+	width, height uint32,
+	attachment gfxapi.FramebufferAttachment,
+	hints *service.UsageHints) (*image.Image2D, error) {
+
+	t, ok := texture.(*Texture)
+	if !ok || t.Kind != GLenum_GL_TEXTURE_2D {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrMessage("Only the data of 2D textures can be overridden"),
+		}
+	}
+	c := drawConfig{override: &textureOverride{texture: t.ID, pattern: pattern}}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*image.Image2D), nil
+}
+
+func (a api) QueryStateOverride(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	after atom.ID,
+	override replay.StateOverride,
+	width, height uint32,
+	attachment gfxapi.FramebufferAttachment,
+	hints *service.UsageHints) (*image.Image2D, error) {
+
+	c := drawConfig{state: &override}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*image.Image2D), nil
+}
+
 // destroyResourcesAtEOS is a transform that destroys all textures,
 // framebuffers, buffers, shaders, programs and vertex-arrays that were not
 // destroyed by EOS.
@@ -45,6 +45,13 @@ func (t *Texture) ResourceLabel() string {
 	return t.Label
 }
 
+// IsDeleted returns true if the texture is no longer an object of the current
+// context.
+func (t *Texture) IsDeleted(s *gfxapi.State) bool {
+	c := GetContext(s)
+	return c != nil && c.SharedObjects.Textures.Get(t.ID) != t
+}
+
 // Order returns an integer used to sort the resources for presentation.
 func (t *Texture) Order() uint64 {
 	return uint64(t.ID)
@@ -143,6 +150,13 @@ func (s *Shader) ResourceLabel() string {
 	return s.Label
 }
 
+// IsDeleted returns true if the shader is no longer an object of the current
+// context.
+func (s *Shader) IsDeleted(t *gfxapi.State) bool {
+	c := GetContext(t)
+	return c != nil && c.SharedObjects.Shaders.Get(s.ID) != s
+}
+
 // Order returns an integer used to sort the resources for presentation.
 func (s *Shader) Order() uint64 {
 	return uint64(s.ID)
@@ -156,22 +170,29 @@ func (s *Shader) ResourceType(ctx context.Context) gfxapi.ResourceType {
 // ResourceData returns the resource data given the current state.
 func (s *Shader) ResourceData(ctx context.Context, t *gfxapi.State) (interface{}, error) {
 	ctx = log.Enter(ctx, "Shader.Resource()")
-	var ty gfxapi.ShaderType
-	switch s.ShaderType {
-	case GLenum_GL_VERTEX_SHADER:
-		ty = gfxapi.ShaderType_Vertex
+	return &gfxapi.Shader{
+		Type:   shaderType(s.ShaderType),
+		Source: s.Source,
+		Issues: analyzeGLSL(s.Source, s.ShaderType),
+	}, nil
+}
+
+// shaderType returns the resource shader type of the GL shader type t.
+func shaderType(t GLenum) gfxapi.ShaderType {
+	switch t {
 	case GLenum_GL_GEOMETRY_SHADER:
-		ty = gfxapi.ShaderType_Geometry
+		return gfxapi.ShaderType_Geometry
 	case GLenum_GL_TESS_CONTROL_SHADER:
-		ty = gfxapi.ShaderType_TessControl
+		return gfxapi.ShaderType_TessControl
 	case GLenum_GL_TESS_EVALUATION_SHADER:
-		ty = gfxapi.ShaderType_TessEvaluation
+		return gfxapi.ShaderType_TessEvaluation
 	case GLenum_GL_FRAGMENT_SHADER:
-		ty = gfxapi.ShaderType_Fragment
+		return gfxapi.ShaderType_Fragment
 	case GLenum_GL_COMPUTE_SHADER:
-		ty = gfxapi.ShaderType_Compute
+		return gfxapi.ShaderType_Compute
+	default:
+		return gfxapi.ShaderType_Vertex
 	}
-	return &gfxapi.Shader{Type: ty, Source: s.Source}, nil
 }
 
 func (shader *Shader) SetResourceData(ctx context.Context, at *path.Command,
@@ -241,6 +262,13 @@ func (p *Program) ResourceLabel() string {
 	return p.Label
 }
 
+// IsDeleted returns true if the program is no longer an object of the current
+// context.
+func (p *Program) IsDeleted(s *gfxapi.State) bool {
+	c := GetContext(s)
+	return c != nil && c.SharedObjects.Programs.Get(p.ID) != p
+}
+
 // Order returns an integer used to sort the resources for presentation.
 func (p *Program) Order() uint64 {
 	return uint64(p.ID)
@@ -258,29 +286,15 @@ func (p *Program) ResourceData(ctx context.Context, s *gfxapi.State) (interface{
 	context := state.Contexts.Get(state.CurrentThread)
 
 	shaders := []*gfxapi.Shader{}
-	for shaderType, shaderID := range p.Shaders {
+	for ty, shaderID := range p.Shaders {
 		shader := context.SharedObjects.Shaders.Get(shaderID)
 		if shader == nil {
 			continue
 		}
-		var ty gfxapi.ShaderType
-		switch shaderType {
-		case GLenum_GL_VERTEX_SHADER:
-			ty = gfxapi.ShaderType_Vertex
-		case GLenum_GL_GEOMETRY_SHADER:
-			ty = gfxapi.ShaderType_Geometry
-		case GLenum_GL_TESS_CONTROL_SHADER:
-			ty = gfxapi.ShaderType_TessControl
-		case GLenum_GL_TESS_EVALUATION_SHADER:
-			ty = gfxapi.ShaderType_TessEvaluation
-		case GLenum_GL_FRAGMENT_SHADER:
-			ty = gfxapi.ShaderType_Fragment
-		case GLenum_GL_COMPUTE_SHADER:
-			ty = gfxapi.ShaderType_Compute
-		}
 		shaders = append(shaders, &gfxapi.Shader{
-			Type:   ty,
+			Type:   shaderType(ty),
 			Source: shader.Source,
+			Issues: analyzeGLSL(shader.Source, ty),
 		})
 	}
 
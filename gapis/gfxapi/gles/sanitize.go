@@ -0,0 +1,155 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+var _ = atom.Sanitizer(api{})
+
+// SensitiveData returns the shader sources, the debug labels and markers, and
+// the contents of the textures read from the application memory by the
+// capture held by ctx. The compressed textures are not reported.
+func (api) SensitiveData(ctx context.Context) ([]atom.SensitiveData, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.SensitiveData{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if o := a.Extras().Observations(); o != nil {
+			o.ApplyReads(s.Memory[memory.ApplicationPool])
+		}
+		out = append(out, sensitiveData(ctx, id, a, s)...)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+		}
+	}
+	return out, nil
+}
+
+// sensitiveData returns the sensitive data read by the atom a, before it is
+// applied to the state s.
+func sensitiveData(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) []atom.SensitiveData {
+	out := []atom.SensitiveData{}
+	// str adds the string at p, of the given length if terminated is false
+	// and null-terminated otherwise.
+	str := func(kind atom.SensitiveKind, p GLcharᶜᵖ, length GLsizei, terminated bool) {
+		if p.Address == 0 {
+			return
+		}
+		rng := p.StringSlice(ctx, s).Range(s)
+		if !terminated {
+			rng = p.Slice(0, uint64(length), s).Range(s)
+		}
+		out = append(out, atom.SensitiveData{Atom: id, Kind: kind, Range: rng})
+	}
+	// sources adds the count shader sources at p, of the lengths at lengths
+	// if not null.
+	sources := func(p GLcharᶜᵖᶜᵖ, count GLsizei, lengths GLintᶜᵖ) {
+		if p.Address == 0 || count <= 0 {
+			return
+		}
+		var l []GLint
+		if lengths.Address != 0 {
+			l = lengths.Slice(0, uint64(count), s).Read(ctx, a, s, nil)
+		}
+		for i, src := range p.Slice(0, uint64(count), s).Read(ctx, a, s, nil) {
+			if l == nil || l[i] < 0 {
+				str(atom.SensitiveShader, src, 0, true)
+			} else {
+				str(atom.SensitiveShader, src, GLsizei(l[i]), false)
+			}
+		}
+	}
+	// texture adds the texture data of the given format read at p.
+	texture := func(p TexturePointer, format, ty GLenum, width, height, depth GLsizei) {
+		c := GetState(s).getContext()
+		if p.Address == 0 || c == nil || c.BoundBuffers.PixelUnpackBuffer != 0 {
+			return
+		}
+		f, err := getImageFormat(format, ty)
+		if err != nil {
+			return
+		}
+		texel := uint64(f.Size(1, 1))
+		row := texel * uint64(width)
+		if c.PixelStorage.UnpackRowLength > 0 {
+			row = texel * uint64(c.PixelStorage.UnpackRowLength)
+		}
+		if align := uint64(c.PixelStorage.UnpackAlignment); align > 1 {
+			row = (row + align - 1) / align * align
+		}
+		out = append(out, atom.SensitiveData{
+			Atom:      id,
+			Kind:      atom.SensitiveTexture,
+			Range:     memory.Range{Base: p.Address, Size: row * uint64(height) * uint64(depth)},
+			TexelSize: texel,
+			RowSize:   row,
+		})
+	}
+
+	switch a := a.(type) {
+	case *GlShaderSource:
+		sources(a.Source, a.Count, a.Length)
+	case *GlCreateShaderProgramv:
+		sources(a.Strings, a.Count, GLintᶜᵖ{})
+	case *GlObjectLabel:
+		str(atom.SensitiveName, a.Label, a.Length, a.Length < 0)
+	case *GlObjectLabelKHR:
+		str(atom.SensitiveName, a.Label, a.Length, a.Length < 0)
+	case *GlObjectPtrLabel:
+		str(atom.SensitiveName, a.Label, a.Length, a.Length < 0)
+	case *GlObjectPtrLabelKHR:
+		str(atom.SensitiveName, a.Label, a.Length, a.Length < 0)
+	case *GlPushDebugGroup:
+		str(atom.SensitiveName, a.Message, a.Length, a.Length < 0)
+	case *GlPushDebugGroupKHR:
+		str(atom.SensitiveName, a.Message, a.Length, a.Length < 0)
+	case *GlDebugMessageInsert:
+		str(atom.SensitiveName, a.Message, a.Length, a.Length < 0)
+	case *GlDebugMessageInsertKHR:
+		str(atom.SensitiveName, a.Message, a.Length, a.Length < 0)
+	case *GlLabelObjectEXT:
+		str(atom.SensitiveName, a.Label, a.Length, a.Length <= 0)
+	case *GlInsertEventMarkerEXT:
+		str(atom.SensitiveName, a.Marker, a.Length, a.Length <= 0)
+	case *GlPushGroupMarkerEXT:
+		str(atom.SensitiveName, a.Marker, a.Length, a.Length <= 0)
+	case *GlTexImage2D:
+		texture(a.Data, a.Format, a.Type, a.Width, a.Height, 1)
+	case *GlTexSubImage2D:
+		texture(a.Data, a.Format, a.Type, a.Width, a.Height, 1)
+	case *GlTexImage3D:
+		texture(a.Data, a.Format, a.Type, a.Width, a.Height, a.Depth)
+	case *GlTexSubImage3D:
+		texture(a.Data, a.Format, a.Type, a.Width, a.Height, a.Depth)
+	}
+	return out
+}
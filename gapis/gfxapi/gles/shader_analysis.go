@@ -0,0 +1,145 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/gles/glsl"
+	"github.com/google/gapid/gapis/gfxapi/gles/glsl/ast"
+)
+
+var _ = atom.ShaderAnalyzer(api{})
+
+// ShaderIssues returns the issues of the shaders compiled by the
+// glCompileShader atoms of the capture held by ctx.
+func (api) ShaderIssues(ctx context.Context) ([]atom.ShaderIssues, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.ShaderIssues{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		compile, ok := a.(*GlCompileShader)
+		if !ok {
+			continue
+		}
+		context := GetState(s).getContext()
+		if context == nil {
+			continue
+		}
+		shader := context.SharedObjects.Shaders.Get(compile.Shader)
+		if shader == nil {
+			continue
+		}
+		out = append(out, atom.ShaderIssues{
+			Atom:   id,
+			Handle: uint64(compile.Shader),
+			Type:   shaderType(shader.ShaderType),
+			Issues: analyzeGLSL(shader.Source, shader.ShaderType),
+		})
+	}
+	return out, nil
+}
+
+// analyzeGLSL returns the unused uniforms of the GLSL source of a vertex or
+// fragment shader of type ty, the unused attributes of a vertex shader, and
+// the high precision float outputs and default precision of a fragment
+// shader. Color attachments rarely hold more than 16 bits per component, so
+// these are medium precision candidates. Sources that cannot be parsed have no
+// issues.
+func analyzeGLSL(source string, ty GLenum) []*gfxapi.ShaderIssue {
+	out := []*gfxapi.ShaderIssue{}
+	var lang ast.Language
+	switch ty {
+	case GLenum_GL_VERTEX_SHADER:
+		lang = ast.LangVertexShader
+	case GLenum_GL_FRAGMENT_SHADER:
+		lang = ast.LangFragmentShader
+	default:
+		return out
+	}
+	program, _, _, errs := glsl.Parse(source, lang)
+	tree, ok := program.(*ast.Ast)
+	if len(errs) > 0 || !ok {
+		return out
+	}
+
+	used := map[*ast.VariableSym]bool{}
+	var visit func(n interface{})
+	visit = func(n interface{}) {
+		switch n := n.(type) {
+		case *ast.InvariantDecl:
+			return // Qualifying a variable does not use it.
+		case *ast.VarRefExpr:
+			if v, ok := n.Sym.(*ast.VariableSym); ok {
+				used[v] = true
+			}
+		}
+		ast.VisitChildren(n, visit)
+	}
+	visit(tree)
+
+	isHighpFloat := func(t ast.Type) bool {
+		b, ok := t.(*ast.BuiltinType)
+		return ok && b.Precision == ast.HighP && ast.GetFundamentalType(b.Type) == ast.TFloat
+	}
+	for _, d := range tree.Decls {
+		switch d := d.(type) {
+		case *ast.PrecisionDecl:
+			if lang == ast.LangFragmentShader && isHighpFloat(d.Type) {
+				out = append(out, &gfxapi.ShaderIssue{
+					Kind: gfxapi.ShaderIssueKind_MediumPrecisionCandidate,
+					Name: "precision highp float",
+				})
+			}
+		case *ast.MultiVarDecl:
+			if d.Quals == nil {
+				continue
+			}
+			storage := d.Quals.Storage
+			for _, v := range d.Vars {
+				issue := &gfxapi.ShaderIssue{Name: v.SymName}
+				switch {
+				case storage == ast.StorUniform && !used[v]:
+					issue.Kind = gfxapi.ShaderIssueKind_UnusedBinding
+				case lang == ast.LangVertexShader && (storage == ast.StorAttribute || storage == ast.StorIn) && !used[v]:
+					issue.Kind = gfxapi.ShaderIssueKind_UnusedVertexInput
+				case lang == ast.LangFragmentShader && storage == ast.StorOut && isHighpFloat(d.Type):
+					issue.Kind = gfxapi.ShaderIssueKind_MediumPrecisionCandidate
+				default:
+					continue
+				}
+				out = append(out, issue)
+			}
+		}
+	}
+	return out
+}
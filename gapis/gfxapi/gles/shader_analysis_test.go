@@ -0,0 +1,69 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+)
+
+func TestAnalyzeGLSL(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, test := range []struct {
+		name     string
+		ty       GLenum
+		source   string
+		expected []string
+	}{
+		{
+			name: "Vertex",
+			ty:   GLenum_GL_VERTEX_SHADER,
+			source: `
+attribute vec4 position;
+attribute vec2 normal;
+uniform mat4 mvp;
+uniform vec4 tint;
+void main() { gl_Position = mvp * position; }`,
+			expected: []string{"UnusedVertexInput normal", "UnusedBinding tint"},
+		},
+		{
+			name: "Fragment",
+			ty:   GLenum_GL_FRAGMENT_SHADER,
+			source: `#version 300 es
+precision highp float;
+uniform sampler2D tex;
+in vec2 uv;
+out highp vec4 color;
+void main() { color = texture(tex, uv); }`,
+			expected: []string{"MediumPrecisionCandidate precision highp float", "MediumPrecisionCandidate color"},
+		},
+		{
+			name:     "Invalid",
+			ty:       GLenum_GL_FRAGMENT_SHADER,
+			source:   `void main() {`,
+			expected: []string{},
+		},
+	} {
+		ctx := log.Enter(ctx, test.name)
+		got := []string{}
+		for _, issue := range analyzeGLSL(test.source, test.ty) {
+			got = append(got, fmt.Sprintf("%v %s", issue.Kind, issue.Name))
+		}
+		assert.With(ctx).ThatSlice(got).Equals(test.expected)
+	}
+}
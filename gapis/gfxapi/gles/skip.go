@@ -0,0 +1,37 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/math/interval"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+)
+
+// skipCommands returns an atom transform that drops the draw calls and clears
+// within the ranges skip. The other commands are kept, as the state they set
+// may be used by the commands following the ranges.
+func skipCommands(ctx context.Context, skip atom.RangeList) transform.Transformer {
+	return transform.Transform("SkipCommands", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if interval.Contains(&skip, uint64(i)) {
+			if _, ok := a.(*GlClear); ok || a.AtomFlags().IsDrawCall() {
+				return
+			}
+		}
+		out.MutateAndWrite(ctx, i, a)
+	})
+}
@@ -26,36 +26,55 @@ func (s *State) getContext() *Context {
 
 // TODO: When gfx api macros produce functions instead of inlining, move this logic
 // to the gles.api file.
-func (s *State) getFramebufferAttachmentInfo(att gfxapi.FramebufferAttachment) (width, height uint32, sizedFormat GLenum, err error) {
+// getFramebufferAttachment returns the attachment att of the currently bound
+// draw framebuffer.
+func (s *State) getFramebufferAttachment(att gfxapi.FramebufferAttachment) (FramebufferAttachment, error) {
 	c := s.getContext()
 	if c == nil {
-		return 0, 0, 0, fmt.Errorf("No context bound")
+		return FramebufferAttachment{}, fmt.Errorf("No context bound")
 	}
 	if !c.Info.Initialized {
-		return 0, 0, 0, fmt.Errorf("Context not initialized")
+		return FramebufferAttachment{}, fmt.Errorf("Context not initialized")
 	}
 
 	framebuffer, ok := c.Objects.Framebuffers[c.BoundDrawFramebuffer]
 	if !ok {
-		return 0, 0, 0, fmt.Errorf("No GL_FRAMEBUFFER bound")
+		return FramebufferAttachment{}, fmt.Errorf("No GL_FRAMEBUFFER bound")
 	}
 
-	var a FramebufferAttachment
 	switch att {
 	case gfxapi.FramebufferAttachment_Color0:
-		a = framebuffer.ColorAttachments[0]
+		return framebuffer.ColorAttachments[0], nil
 	case gfxapi.FramebufferAttachment_Color1:
-		a = framebuffer.ColorAttachments[1]
+		return framebuffer.ColorAttachments[1], nil
 	case gfxapi.FramebufferAttachment_Color2:
-		a = framebuffer.ColorAttachments[2]
+		return framebuffer.ColorAttachments[2], nil
 	case gfxapi.FramebufferAttachment_Color3:
-		a = framebuffer.ColorAttachments[3]
+		return framebuffer.ColorAttachments[3], nil
 	case gfxapi.FramebufferAttachment_Depth:
-		a = framebuffer.DepthAttachment
+		return framebuffer.DepthAttachment, nil
 	case gfxapi.FramebufferAttachment_Stencil:
-		a = framebuffer.StencilAttachment
+		return framebuffer.StencilAttachment, nil
 	default:
-		return 0, 0, 0, fmt.Errorf("Framebuffer attachment %v unsupported by gles", att)
+		return FramebufferAttachment{}, fmt.Errorf("Framebuffer attachment %v unsupported by gles", att)
+	}
+}
+
+// getFramebufferAttachmentSamples returns the number of samples of the
+// attachment att of the currently bound draw framebuffer, or 0 if the
+// attachment is not multisampled.
+func (s *State) getFramebufferAttachmentSamples(att gfxapi.FramebufferAttachment) GLsizei {
+	a, err := s.getFramebufferAttachment(att)
+	if err != nil || a.Type != GLenum_GL_RENDERBUFFER || a.Renderbuffer == nil {
+		return 0
+	}
+	return a.Renderbuffer.Samples
+}
+
+func (s *State) getFramebufferAttachmentInfo(att gfxapi.FramebufferAttachment) (width, height uint32, sizedFormat GLenum, err error) {
+	a, err := s.getFramebufferAttachment(att)
+	if err != nil {
+		return 0, 0, 0, err
 	}
 
 	switch a.Type {
@@ -0,0 +1,57 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+)
+
+// overrideState returns an atom transform that forces the fixed-function
+// state of o around the overridden draw calls.
+func overrideState(ctx context.Context, o replay.StateOverride) transform.Transformer {
+	return transform.Transform("OverrideState", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if !a.AtomFlags().IsDrawCall() || (o.Draw != atom.NoID && o.Draw != i) || GetContext(out.State()) == nil {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		t := newTweaker(ctx, out, i)
+		if o.DisableDepthTest {
+			t.glDisable(GLenum_GL_DEPTH_TEST)
+		}
+		switch o.CullMode {
+		case service.CullModeOverride_CullNone:
+			t.glDisable(GLenum_GL_CULL_FACE)
+		case service.CullModeOverride_CullFront:
+			t.glEnable(GLenum_GL_CULL_FACE)
+			t.glCullFace(GLenum_GL_FRONT)
+		case service.CullModeOverride_CullBack:
+			t.glEnable(GLenum_GL_CULL_FACE)
+			t.glCullFace(GLenum_GL_BACK)
+		}
+		if o.ForceCCW {
+			t.glFrontFace(GLenum_GL_CCW)
+		}
+		if o.DisableBlending {
+			t.glDisable(GLenum_GL_BLEND)
+		}
+		out.MutateAndWrite(ctx, i, a)
+		t.revert()
+	})
+}
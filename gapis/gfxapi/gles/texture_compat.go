@@ -367,6 +367,20 @@ func getExtensionTextureFormats(extension string) []GLenum {
 			GLenum_GL_COMPRESSED_SRGB8_ALPHA8_ASTC_12x10_KHR,
 			GLenum_GL_COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR,
 		}
+	case "GL_EXT_texture_compression_rgtc", "GL_ARB_texture_compression_rgtc":
+		return []GLenum{
+			GLenum_GL_COMPRESSED_RED_RGTC1,
+			GLenum_GL_COMPRESSED_SIGNED_RED_RGTC1,
+			GLenum_GL_COMPRESSED_RG_RGTC2,
+			GLenum_GL_COMPRESSED_SIGNED_RG_RGTC2,
+		}
+	case "GL_EXT_texture_compression_bptc", "GL_ARB_texture_compression_bptc":
+		return []GLenum{
+			GLenum_GL_COMPRESSED_RGBA_BPTC_UNORM,
+			GLenum_GL_COMPRESSED_SRGB_ALPHA_BPTC_UNORM,
+			GLenum_GL_COMPRESSED_RGB_BPTC_SIGNED_FLOAT,
+			GLenum_GL_COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT,
+		}
 	case "GL_EXT_texture_compression_latc", "GL_NV_texture_compression_latc":
 		return []GLenum{
 			GLenum_GL_COMPRESSED_LUMINANCE_LATC1_EXT,
@@ -387,6 +401,7 @@ func isCompressedFormat(internalformat GLenum) bool {
 		GLenum_GL_ATC_RGB_AMD,
 		GLenum_GL_COMPRESSED_LUMINANCE_ALPHA_LATC2_EXT,
 		GLenum_GL_COMPRESSED_LUMINANCE_LATC1_EXT,
+		GLenum_GL_COMPRESSED_RED_RGTC1,
 		GLenum_GL_COMPRESSED_RG11_EAC,
 		GLenum_GL_COMPRESSED_RGB8_ETC2,
 		GLenum_GL_COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2,
@@ -405,14 +420,20 @@ func isCompressedFormat(internalformat GLenum) bool {
 		GLenum_GL_COMPRESSED_RGBA_ASTC_8x5,
 		GLenum_GL_COMPRESSED_RGBA_ASTC_8x6,
 		GLenum_GL_COMPRESSED_RGBA_ASTC_8x8,
+		GLenum_GL_COMPRESSED_RGBA_BPTC_UNORM,
 		GLenum_GL_COMPRESSED_RGBA_S3TC_DXT1_EXT,
 		GLenum_GL_COMPRESSED_RGBA_S3TC_DXT3_EXT,
 		GLenum_GL_COMPRESSED_RGBA_S3TC_DXT5_EXT,
+		GLenum_GL_COMPRESSED_RGB_BPTC_SIGNED_FLOAT,
+		GLenum_GL_COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT,
 		GLenum_GL_COMPRESSED_RGB_S3TC_DXT1_EXT,
+		GLenum_GL_COMPRESSED_RG_RGTC2,
 		GLenum_GL_COMPRESSED_SIGNED_LUMINANCE_ALPHA_LATC2_EXT,
 		GLenum_GL_COMPRESSED_SIGNED_LUMINANCE_LATC1_EXT,
 		GLenum_GL_COMPRESSED_SIGNED_R11_EAC,
+		GLenum_GL_COMPRESSED_SIGNED_RED_RGTC1,
 		GLenum_GL_COMPRESSED_SIGNED_RG11_EAC,
+		GLenum_GL_COMPRESSED_SIGNED_RG_RGTC2,
 		GLenum_GL_COMPRESSED_SRGB8_ALPHA8_ASTC_10x10,
 		GLenum_GL_COMPRESSED_SRGB8_ALPHA8_ASTC_10x5,
 		GLenum_GL_COMPRESSED_SRGB8_ALPHA8_ASTC_10x6,
@@ -430,6 +451,7 @@ func isCompressedFormat(internalformat GLenum) bool {
 		GLenum_GL_COMPRESSED_SRGB8_ALPHA8_ETC2_EAC,
 		GLenum_GL_COMPRESSED_SRGB8_ETC2,
 		GLenum_GL_COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2,
+		GLenum_GL_COMPRESSED_SRGB_ALPHA_BPTC_UNORM,
 		GLenum_GL_ETC1_RGB8_OES:
 		return true
 	}
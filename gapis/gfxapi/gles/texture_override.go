@@ -0,0 +1,102 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gles
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/replay"
+)
+
+// textureOverride replaces the data of a 2D texture during a replay.
+type textureOverride struct {
+	texture TextureId
+	pattern replay.TexturePattern
+}
+
+// overrideTexture returns an atom transform that uploads the pattern of o to
+// the uncompressed levels of the texture before each draw call with the
+// texture bound to a texture unit. The pattern is uploaded again for each of
+// these draw calls, so that it also replaces the data uploaded or rendered to
+// the texture between them.
+func overrideTexture(ctx context.Context, o textureOverride) transform.Transformer {
+	return transform.Transform("TextureOverride", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if a.AtomFlags().IsDrawCall() {
+			o.upload(ctx, i, out)
+		}
+		out.MutateAndWrite(ctx, i, a)
+	})
+}
+
+// upload writes the atoms uploading the pattern to the texture, if the
+// texture is bound to a texture unit of the current context.
+func (o textureOverride) upload(ctx context.Context, i atom.ID, out transform.Writer) {
+	c := GetContext(out.State())
+	if c == nil {
+		return
+	}
+	bound := false
+	for _, unit := range c.TextureUnits {
+		if unit.Binding2d == o.texture {
+			bound = true
+			break
+		}
+	}
+	texture := c.SharedObjects.Textures.Get(o.texture)
+	if !bound || texture == nil {
+		return
+	}
+
+	t := newTweaker(ctx, out, i)
+	defer t.revert()
+	t.setPixelStorage(PixelStorageState{UnpackAlignment: 1, PackAlignment: 1}, 0, 0)
+	t.glBindTexture_2D(o.texture)
+	for level, img := range texture.Texture2D {
+		if img.DataType == GLenum_GL_NONE {
+			// Compressed or uninitialized level.
+			continue
+		}
+		f, err := getImageFormat(img.DataFormat, img.DataType)
+		if err != nil {
+			log.W(ctx, "Couldn't override level %v of texture %v: %v", level, o.texture, err)
+			continue
+		}
+		width, height := int(img.Width), int(img.Height)
+		data, err := o.pattern(width, height)
+		if err == nil {
+			data, err = image.Convert(data, width, height, image.RGBA_U8_NORM, f)
+		}
+		if err != nil {
+			log.W(ctx, "Couldn't override level %v of texture %v: %v", level, o.texture, err)
+			continue
+		}
+		tmp := t.AllocData(data)
+		out.MutateAndWrite(ctx, i.Derived(), NewGlTexSubImage2D(
+			GLenum_GL_TEXTURE_2D,
+			level,
+			0,
+			0,
+			img.Width,
+			img.Height,
+			img.DataFormat,
+			img.DataType,
+			tmp.Ptr(),
+		).AddRead(tmp.Data()))
+	}
+}
@@ -109,6 +109,22 @@ func (t *tweaker) glDepthFunc(v GLenum) {
 	}
 }
 
+func (t *tweaker) glCullFace(mode GLenum) {
+	if o := t.c.Rasterization.CullFaceMode; o != mode {
+		t.doAndUndo(
+			NewGlCullFace(mode),
+			NewGlCullFace(o))
+	}
+}
+
+func (t *tweaker) glFrontFace(orientation GLenum) {
+	if o := t.c.Rasterization.FrontFace; o != orientation {
+		t.doAndUndo(
+			NewGlFrontFace(orientation),
+			NewGlFrontFace(o))
+	}
+}
+
 func (t *tweaker) glBlendColor(r, g, b, a GLfloat) {
 	n := Color{Red: r, Green: g, Blue: b, Alpha: a}
 	if o := t.c.FragmentOperations.BlendColor; o != n {
@@ -151,6 +167,52 @@ func (t *tweaker) glLineWidth(width GLfloat) {
 	}
 }
 
+func (t *tweaker) glStencilFunc(f GLenum, ref GLint, mask GLuint) {
+	o := t.c.FragmentOperations.Stencil
+	if o.Func == f && o.Ref == ref && o.ValueMask == mask &&
+		o.BackFunc == f && o.BackRef == ref && o.BackValueMask == mask {
+		return
+	}
+	t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilFunc(f, ref, mask))
+	t.undo = append(t.undo, func() {
+		t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilFuncSeparate(GLenum_GL_FRONT, o.Func, o.Ref, o.ValueMask))
+		t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilFuncSeparate(GLenum_GL_BACK, o.BackFunc, o.BackRef, o.BackValueMask))
+	})
+}
+
+func (t *tweaker) glStencilOp(fail, zfail, zpass GLenum) {
+	o := t.c.FragmentOperations.Stencil
+	if o.Fail == fail && o.PassDepthFail == zfail && o.PassDepthPass == zpass &&
+		o.BackFail == fail && o.BackPassDepthFail == zfail && o.BackPassDepthPass == zpass {
+		return
+	}
+	t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilOp(fail, zfail, zpass))
+	t.undo = append(t.undo, func() {
+		t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilOpSeparate(GLenum_GL_FRONT, o.Fail, o.PassDepthFail, o.PassDepthPass))
+		t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilOpSeparate(GLenum_GL_BACK, o.BackFail, o.BackPassDepthFail, o.BackPassDepthPass))
+	})
+}
+
+func (t *tweaker) glStencilMask(mask GLuint) {
+	front, back := t.c.Framebuffer.StencilWritemask, t.c.Framebuffer.StencilBackWritemask
+	if front == mask && back == mask {
+		return
+	}
+	t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilMask(mask))
+	t.undo = append(t.undo, func() {
+		t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilMaskSeparate(GLenum_GL_FRONT, front))
+		t.out.MutateAndWrite(t.ctx, t.dID, NewGlStencilMaskSeparate(GLenum_GL_BACK, back))
+	})
+}
+
+func (t *tweaker) glClearStencil(v GLint) {
+	if o := t.c.Framebuffer.StencilClearValue; o != v {
+		t.doAndUndo(
+			NewGlClearStencil(v),
+			NewGlClearStencil(o))
+	}
+}
+
 // This will either bind new VAO (GLES 3.x) or save state of the default one (GLES 2.0).
 func (t *tweaker) makeVertexArray(enabledLocations ...AttributeLocation) {
 	ctx := t.ctx
@@ -290,6 +352,15 @@ func (t *tweaker) glScissor(x, y GLint, w, h GLsizei) {
 	}
 }
 
+func (t *tweaker) glViewport(x, y GLint, w, h GLsizei) {
+	v := Rect{X: x, Y: y, Width: w, Height: h}
+	if o := t.c.Rasterization.Viewport; o != v {
+		t.doAndUndo(
+			NewGlViewport(x, y, w, h),
+			NewGlViewport(o.X, o.Y, o.Width, o.Height))
+	}
+}
+
 func (t *tweaker) GlBindBuffer_ArrayBuffer(id BufferId) {
 	if o := t.c.BoundBuffers.ArrayBuffer; o != id {
 		t.doAndUndo(
@@ -0,0 +1,110 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gfxapi
+
+// DefaultVertexCacheSize is the size of the post-transform vertex cache used
+// for the IndexStats of a mesh when none is specified.
+const DefaultVertexCacheSize = 32
+
+// IndexStats holds statistics on the index buffer of a mesh, describing how
+// well the mesh is optimized for rendering.
+type IndexStats struct {
+	Indices     int // Number of indices, excluding restart indices.
+	Restarts    int // Number of primitive restart indices.
+	Vertices    int // Number of unique vertices referenced.
+	Triangles   int // Number of triangles, including degenerate triangles.
+	Degenerate  int // Number of triangles with less than 3 unique vertices.
+	CacheMisses int // Number of vertex shader invocations with a FIFO cache.
+}
+
+// ACMR returns the average cache miss ratio: the number of vertex shader
+// invocations per triangle. Lower is better, 0.5 being optimal for regular
+// grids. ACMR returns 0 if the mesh has no triangles.
+func (s IndexStats) ACMR() float64 {
+	if s.Triangles == 0 {
+		return 0
+	}
+	return float64(s.CacheMisses) / float64(s.Triangles)
+}
+
+// ATVR returns the average transform to vertex ratio: the number of vertex
+// shader invocations per unique vertex. Lower is better, 1 being optimal.
+// ATVR returns 0 if the mesh has no vertices.
+func (s IndexStats) ATVR() float64 {
+	if s.Vertices == 0 {
+		return 0
+	}
+	return float64(s.CacheMisses) / float64(s.Vertices)
+}
+
+// IndexStats decodes the index buffer of the mesh, simulating a FIFO
+// post-transform vertex cache of cacheSize entries.
+func (m *Mesh) IndexStats(cacheSize int) IndexStats {
+	if cacheSize <= 0 {
+		cacheSize = DefaultVertexCacheSize
+	}
+	stats := IndexStats{}
+	if m.IndexBuffer == nil {
+		return stats
+	}
+
+	cache := make([]uint32, 0, cacheSize)
+	cached := make(map[uint32]bool, cacheSize)
+	unique := map[uint32]bool{}
+	transform := func(i uint32) {
+		unique[i] = true
+		if cached[i] {
+			return
+		}
+		stats.CacheMisses++
+		if len(cache) == cacheSize {
+			delete(cached, cache[0])
+			cache = cache[1:]
+		}
+		cache = append(cache, i)
+		cached[i] = true
+	}
+
+	ib := m.IndexBuffer
+	primitive := []uint32{}
+	flush := func() {
+		for _, i := range primitive {
+			transform(i)
+		}
+		stats.Indices += len(primitive)
+		sub := Mesh{DrawPrimitive: m.DrawPrimitive, IndexBuffer: &IndexBuffer{Indices: primitive}}
+		// Strips and fans of less than 3 vertices have a negative count.
+		if count := sub.TriangleCount(); count > 0 {
+			for t := 0; t < count; t++ {
+				if a, b, c := sub.Triangle(t); a == b || b == c || a == c {
+					stats.Degenerate++
+				}
+			}
+			stats.Triangles += count
+		}
+		primitive = primitive[:0]
+	}
+	for _, i := range ib.Indices {
+		if ib.PrimitiveRestart && i == ib.RestartIndex {
+			stats.Restarts++
+			flush()
+			continue
+		}
+		primitive = append(primitive, i)
+	}
+	flush()
+	stats.Vertices = len(unique)
+	return stats
+}
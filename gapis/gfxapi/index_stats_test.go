@@ -0,0 +1,66 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gfxapi_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+func TestIndexStats(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, test := range []struct {
+		name      string
+		mesh      *gfxapi.Mesh
+		cacheSize int
+		expected  gfxapi.IndexStats
+	}{
+		{
+			"triangles",
+			&gfxapi.Mesh{
+				DrawPrimitive: gfxapi.DrawPrimitive_Triangles,
+				IndexBuffer:   &gfxapi.IndexBuffer{Indices: []uint32{0, 1, 2, 2, 1, 3, 0, 0, 1}},
+			},
+			0,
+			gfxapi.IndexStats{Indices: 9, Vertices: 4, Triangles: 3, Degenerate: 1, CacheMisses: 4},
+		}, {
+			"evictions",
+			&gfxapi.Mesh{
+				DrawPrimitive: gfxapi.DrawPrimitive_Triangles,
+				IndexBuffer:   &gfxapi.IndexBuffer{Indices: []uint32{0, 1, 2, 0, 1, 2}},
+			},
+			2,
+			gfxapi.IndexStats{Indices: 6, Vertices: 3, Triangles: 2, Degenerate: 0, CacheMisses: 6},
+		}, {
+			"restart",
+			&gfxapi.Mesh{
+				DrawPrimitive: gfxapi.DrawPrimitive_TriangleStrip,
+				IndexBuffer: &gfxapi.IndexBuffer{
+					Indices:          []uint32{0, 1, 2, 3, 0xffff, 4, 5, 6, 0xffff, 7, 8},
+					PrimitiveRestart: true,
+					RestartIndex:     0xffff,
+				},
+			},
+			0,
+			gfxapi.IndexStats{Indices: 9, Restarts: 2, Vertices: 9, Triangles: 3, CacheMisses: 9},
+		},
+	} {
+		ctx := log.Enter(ctx, test.name)
+		assert.With(ctx).That(test.mesh.IndexStats(test.cacheSize)).Equals(test.expected)
+	}
+}
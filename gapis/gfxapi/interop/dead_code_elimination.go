@@ -0,0 +1,201 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+)
+
+// DeadCodeElimination is an implementation of Transformer that outputs the
+// live atoms of all the graphics APIs of a capture, using the combined
+// dependency graph. The transform generates atoms from the dependency graph,
+// it does not take inputs.
+type DeadCodeElimination struct {
+	dependencyGraph *DependencyGraph
+	requests        atom.IDSet
+	lastRequest     atom.ID
+}
+
+// NewDeadCodeElimination returns a new DeadCodeElimination transform for the
+// combined dependency graph g.
+func NewDeadCodeElimination(ctx context.Context, g *DependencyGraph) *DeadCodeElimination {
+	return &DeadCodeElimination{
+		dependencyGraph: g,
+		requests:        make(atom.IDSet),
+	}
+}
+
+// Request ensures that we keep alive all atoms needed to render framebuffer at the given point.
+func (t *DeadCodeElimination) Request(id atom.ID) {
+	t.requests.Add(id)
+	if id > t.lastRequest {
+		t.lastRequest = id
+	}
+}
+
+func (t *DeadCodeElimination) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	panic(fmt.Errorf("This transform does not accept input atoms"))
+}
+
+func (t *DeadCodeElimination) Flush(ctx context.Context, out transform.Writer) {
+	isLive := t.propagateLiveness(ctx)
+	for i, live := range isLive {
+		if live {
+			out.MutateAndWrite(ctx, atom.ID(i), t.dependencyGraph.atoms[i])
+		}
+	}
+}
+
+// LiveAtoms returns the IDs of the atoms kept alive by the requests, in
+// increasing order.
+func (t *DeadCodeElimination) LiveAtoms(ctx context.Context) []atom.ID {
+	ids := []atom.ID{}
+	for i, live := range t.propagateLiveness(ctx) {
+		if live {
+			ids = append(ids, atom.ID(i))
+		}
+	}
+	return ids
+}
+
+// See https://en.wikipedia.org/wiki/Live_variable_analysis
+func (t *DeadCodeElimination) propagateLiveness(ctx context.Context) []bool {
+	g := t.dependencyGraph
+	isLive := make([]bool, t.lastRequest+1)
+	state := newLivenessTree(g.parents)
+	for i := int(t.lastRequest); i >= 0; i-- {
+		b := g.behaviours[i]
+		isLive[i] = b.KeepAlive
+		// Always ignore commands that abort.
+		if b.Aborted {
+			continue
+		}
+		// If this is requested ID, mark all root state as live.
+		if t.requests.Contains(atom.ID(i)) {
+			isLive[i] = true
+			for root := range g.roots {
+				state.MarkLive(root)
+			}
+		}
+		// If any output state is live then this atom is live as well.
+		for _, write := range b.Write {
+			if state.IsLive(write) {
+				isLive[i] = true
+				state.MarkDead(write) // KILL
+			}
+		}
+		for _, modify := range b.Modify {
+			if state.IsLive(modify) {
+				isLive[i] = true
+			}
+		}
+		// Mark input state as live so that we get all dependencies.
+		if isLive[i] {
+			for _, modify := range b.Modify {
+				state.MarkLive(modify) // GEN
+			}
+			for _, read := range b.Read {
+				state.MarkLive(read) // GEN
+			}
+		}
+	}
+
+	numLive := 0
+	for _, live := range isLive {
+		if live {
+			numLive++
+		}
+	}
+	log.D(ctx, "Interop DCE: %v of %v cmds live, %v shared resources",
+		numLive, len(isLive), len(g.shared))
+	return isLive
+}
+
+// livenessTree assigns boolean value to each state (live or dead).
+// Think of each node as memory range, with children being sub-ranges.
+// See the GLES dead code elimination for details of the optimizations.
+type livenessTree struct {
+	nodes []livenessNode // indexed by StateAddress
+	time  int            // current time used for time-stamps
+}
+
+type livenessNode struct {
+	live      bool          // Liveness value for this node.
+	anyLive   bool          // Union of liveness of this node and all its descendants.
+	timestamp int           // Time of the last write to the 'live' field.
+	parent    *livenessNode // Link to the parent node, or nil if there is none.
+}
+
+func newLivenessTree(parents []StateAddress) livenessTree {
+	nodes := make([]livenessNode, len(parents))
+	for address, parent := range parents {
+		if parent != nullStateAddress {
+			nodes[address].parent = &nodes[parent]
+		}
+	}
+	return livenessTree{nodes: nodes, time: 1}
+}
+
+// IsLive returns true if the state, or any of its descendants, are live.
+func (l *livenessTree) IsLive(address StateAddress) bool {
+	node := &l.nodes[address]
+	live := node.anyLive // Check descendants as well.
+	for p := node.parent; p != nil; p = p.parent {
+		if p.timestamp > node.timestamp {
+			node = p
+			live = p.live // Ignore other descendants.
+		}
+	}
+	return live
+}
+
+// MarkDead makes the given state, and all of its descendants, dead.
+func (l *livenessTree) MarkDead(address StateAddress) {
+	node := &l.nodes[address]
+	node.live = false
+	node.anyLive = false
+	node.timestamp = l.time
+	l.time++
+}
+
+// MarkLive makes the given state, and all of its descendants, live.
+func (l *livenessTree) MarkLive(address StateAddress) {
+	node := &l.nodes[address]
+	node.live = true
+	node.anyLive = true
+	node.timestamp = l.time
+	l.time++
+	if p := node.parent; p != nil {
+		p.setAnyLive()
+	}
+}
+
+// setAnyLive is helper to recursively set 'anyLive' flag on ancestors.
+func (node *livenessNode) setAnyLive() {
+	if p := node.parent; p != nil {
+		p.setAnyLive()
+		if node.timestamp < p.timestamp {
+			// This node is effectively deleted so we need to create it.
+			node.live = p.live
+			node.timestamp = p.timestamp
+		}
+	}
+	node.anyLive = true
+}
@@ -0,0 +1,23 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interop builds dependency graphs for captures that use more than
+// one graphics API, such as applications rendering their UI with GLES and
+// their game with Vulkan.
+//
+// Each API builds the dependency graph of its own atoms. The graphs are then
+// combined into a single graph, where the state of resources shared between
+// the APIs, like Android native buffers bound as EGLImages, is linked so that
+// dead code elimination can trim the atoms of all the APIs together.
+package interop
@@ -0,0 +1,243 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+// StateAddress is the address of a piece of state in a dependency graph.
+type StateAddress uint32
+
+// nullStateAddress is the parent of all the top-level state.
+const nullStateAddress = StateAddress(0)
+
+// Behaviour describes the state accessed by a single atom.
+type Behaviour struct {
+	Read      []StateAddress // State read by the atom.
+	Modify    []StateAddress // State read and written by the atom.
+	Write     []StateAddress // State written by the atom.
+	KeepAlive bool           // Force the atom to be live.
+	Aborted   bool           // Mutation of the atom aborts.
+}
+
+// SharedResourceKind is the kind of handle identifying a SharedResource.
+type SharedResourceKind int
+
+const (
+	// NativeBuffer is an Android native buffer, identified by the address of
+	// its AHardwareBuffer. This is also the address of the buffer's
+	// ANativeWindowBuffer, as passed to eglCreateImageKHR.
+	NativeBuffer SharedResourceKind = iota
+)
+
+// SharedResource identifies a resource whose content is shared between
+// graphics APIs.
+type SharedResource struct {
+	Kind   SharedResourceKind
+	Handle uint64
+}
+
+func (r SharedResource) String() string {
+	switch r.Kind {
+	case NativeBuffer:
+		return fmt.Sprintf("NativeBuffer<0x%x>", r.Handle)
+	default:
+		return fmt.Sprintf("SharedResource<%d, 0x%x>", r.Kind, r.Handle)
+	}
+}
+
+// Graph is the dependency graph built by a single graphics API for all the
+// atoms of a capture.
+type Graph interface {
+	// API returns the graphics API that built the graph.
+	API() gfxapi.API
+	// NumStates returns the number of state addresses used by the graph,
+	// including the null address.
+	NumStates() int
+	// Parent returns the address of the state enclosing the state at address,
+	// or 0 if the state is not enclosed by any other state.
+	Parent(address StateAddress) StateAddress
+	// Behaviour returns the state accessed by the atom at index i. Only the
+	// behaviours of atoms belonging to the graph's API are used.
+	Behaviour(i int) Behaviour
+	// Roots returns the state to mark live at requested atoms.
+	Roots() []StateAddress
+	// SharedResources returns the state holding the content of resources that
+	// can be shared with other graphics APIs.
+	SharedResources() map[StateAddress]SharedResource
+}
+
+// GraphProvider is the interface implemented by graphics APIs that can build
+// a Graph for the capture held by the context.
+type GraphProvider interface {
+	InteropDependencyGraph(ctx context.Context) (Graph, error)
+}
+
+// DependencyGraph is the combination of the dependency graphs of all the
+// graphics APIs used by a capture.
+type DependencyGraph struct {
+	atoms      []atom.Atom           // Atom list which this graph was build for.
+	behaviours []Behaviour           // State reads/writes for each atom.
+	roots      map[StateAddress]bool // State to mark live at requested atoms.
+	parents    []StateAddress        // Parent of each state, indexed by address.
+	shared     map[SharedResource]StateAddress
+}
+
+// IsInterop returns true if the capture c uses more than one graphics API
+// able to build a Graph, in which case GetDependencyGraph should be used for
+// dead code elimination.
+func IsInterop(c *capture.Capture) bool {
+	count := 0
+	for _, id := range c.Apis {
+		if _, ok := gfxapi.Find(gfxapi.ID(id.ID())).(GraphProvider); ok {
+			count++
+		}
+	}
+	return count > 1
+}
+
+// GetDependencyGraph returns the combined dependency graph for the capture
+// held by the context.
+func GetDependencyGraph(ctx context.Context) (*DependencyGraph, error) {
+	r, err := database.Build(ctx, &DependencyGraphResolvable{Capture: capture.Get(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("Could not calculate interop dependency graph: %v", err)
+	}
+	return r.(*DependencyGraph), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *DependencyGraphResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+	c, err := capture.ResolveFromPath(ctx, r.Capture)
+	if err != nil {
+		return nil, err
+	}
+	atoms, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	graphs := []Graph{}
+	indices := map[gfxapi.ID]int{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if p, ok := api.(GraphProvider); ok {
+			g, err := p.InteropDependencyGraph(ctx)
+			if err != nil {
+				return nil, err
+			}
+			indices[api.ID()] = len(graphs)
+			graphs = append(graphs, g)
+		}
+	}
+
+	owners := make([]int, len(atoms.Atoms))
+	for i, a := range atoms.Atoms {
+		owners[i] = -1
+		if api := a.API(); api != nil {
+			if g, ok := indices[api.ID()]; ok {
+				owners[i] = g
+			}
+		}
+	}
+
+	return merge(atoms.Atoms, graphs, owners), nil
+}
+
+// merge combines the graphs into a single dependency graph. owners holds the
+// index of the graph owning each atom, or -1 if the atom belongs to no graph.
+// The state of the shared resources reported by multiple graphs is merged
+// into a single node.
+func merge(atoms []atom.Atom, graphs []Graph, owners []int) *DependencyGraph {
+	out := &DependencyGraph{
+		atoms:      atoms,
+		behaviours: make([]Behaviour, len(owners)),
+		roots:      map[StateAddress]bool{},
+		parents:    []StateAddress{nullStateAddress},
+		shared:     map[SharedResource]StateAddress{},
+	}
+
+	// Assign each state of each graph an address in the combined graph.
+	// Parents can have higher addresses than their children, so the parents
+	// are remapped once all the addresses are known.
+	remaps := make([][]StateAddress, len(graphs))
+	for i, g := range graphs {
+		remap := make([]StateAddress, g.NumStates())
+		shared := g.SharedResources()
+		for a := 1; a < len(remap); a++ {
+			if r, ok := shared[StateAddress(a)]; ok {
+				if address, found := out.shared[r]; found {
+					remap[a] = address
+					continue
+				}
+				out.shared[r] = StateAddress(len(out.parents))
+			}
+			remap[a] = StateAddress(len(out.parents))
+			out.parents = append(out.parents, nullStateAddress)
+		}
+		remaps[i] = remap
+	}
+
+	assigned := make([]bool, len(out.parents))
+	for i, g := range graphs {
+		remap := remaps[i]
+		for a := 1; a < len(remap); a++ {
+			// Shared state takes the parent from the first graph reporting it.
+			if address := remap[a]; !assigned[address] {
+				out.parents[address] = remap[g.Parent(StateAddress(a))]
+				assigned[address] = true
+			}
+		}
+		for _, root := range g.Roots() {
+			out.roots[remap[root]] = true
+		}
+	}
+
+	translate := func(remap, addresses []StateAddress) []StateAddress {
+		if len(addresses) == 0 {
+			return nil
+		}
+		out := make([]StateAddress, len(addresses))
+		for i, a := range addresses {
+			out[i] = remap[a]
+		}
+		return out
+	}
+
+	for i, owner := range owners {
+		if owner < 0 {
+			// Atoms that no graph understands must be kept.
+			out.behaviours[i] = Behaviour{KeepAlive: true}
+			continue
+		}
+		b, remap := graphs[owner].Behaviour(i), remaps[owner]
+		out.behaviours[i] = Behaviour{
+			Read:      translate(remap, b.Read),
+			Modify:    translate(remap, b.Modify),
+			Write:     translate(remap, b.Write),
+			KeepAlive: b.KeepAlive,
+			Aborted:   b.Aborted,
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interop
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+type fakeGraph struct {
+	parents    []StateAddress
+	behaviours map[int]Behaviour
+	roots      []StateAddress
+	shared     map[StateAddress]SharedResource
+}
+
+func (g *fakeGraph) API() gfxapi.API                                  { return nil }
+func (g *fakeGraph) NumStates() int                                   { return len(g.parents) }
+func (g *fakeGraph) Parent(a StateAddress) StateAddress               { return g.parents[a] }
+func (g *fakeGraph) Behaviour(i int) Behaviour                        { return g.behaviours[i] }
+func (g *fakeGraph) Roots() []StateAddress                            { return g.roots }
+func (g *fakeGraph) SharedResources() map[StateAddress]SharedResource { return g.shared }
+
+func TestMergeSharedResources(t *testing.T) {
+	ctx := log.Testing(t)
+	buffer := SharedResource{NativeBuffer, 0x1000}
+
+	// 1: EGLImage backed by the native buffer, 2: framebuffer, 3: image data.
+	gles := &fakeGraph{
+		parents: []StateAddress{0, 0, 0, 1},
+		behaviours: map[int]Behaviour{
+			3: {Read: []StateAddress{3}, Write: []StateAddress{2}},
+		},
+		roots:  []StateAddress{2},
+		shared: map[StateAddress]SharedResource{3: buffer},
+	}
+	// 1: unrelated image, 2: memory imported from the native buffer.
+	vulkan := &fakeGraph{
+		parents: []StateAddress{0, 0, 0},
+		behaviours: map[int]Behaviour{
+			0: {Write: []StateAddress{1}},
+			1: {Write: []StateAddress{2}},
+		},
+		shared: map[StateAddress]SharedResource{2: buffer},
+	}
+	// Atom 2 belongs to neither graph.
+	g := merge(make([]atom.Atom, 4), []Graph{gles, vulkan}, []int{1, 1, -1, 0})
+
+	assert.With(ctx).ThatSlice(g.parents).IsLength(5)
+	assert.With(ctx).That(g.shared[buffer]).Equals(StateAddress(3))
+	assert.With(ctx).That(g.parents[3]).Equals(StateAddress(1))
+	assert.With(ctx).ThatSlice(g.behaviours[1].Write).Equals([]StateAddress{3})
+	assert.With(ctx).That(g.behaviours[2].KeepAlive).Equals(true)
+
+	dce := NewDeadCodeElimination(ctx, g)
+	dce.Request(3)
+	assert.With(ctx).ThatSlice(dce.propagateLiveness(ctx)).Equals([]bool{false, true, true, true})
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gfxapi
+
+import "context"
+
+// MemoryUsage is the device memory used by the objects of an API state.
+type MemoryUsage struct {
+	Allocated uint64   // The total size of the memory allocations, in bytes.
+	Heaps     []uint64 // The size of the memory allocations of each heap.
+	Bound     uint64   // The size of the images and buffers bound to memory.
+	Images    uint64   // The memory size required by the images.
+	Buffers   uint64   // The memory size required by the buffers.
+}
+
+// Add adds the memory usage o to u.
+func (u *MemoryUsage) Add(o MemoryUsage) {
+	u.Allocated += o.Allocated
+	u.Bound += o.Bound
+	u.Images += o.Images
+	u.Buffers += o.Buffers
+	for i, size := range o.Heaps {
+		if i >= len(u.Heaps) {
+			u.Heaps = append(u.Heaps, make([]uint64, i+1-len(u.Heaps))...)
+		}
+		u.Heaps[i] += size
+	}
+}
+
+// MemoryUsageProvider is the interface implemented by APIs that can measure
+// the device memory used by their state.
+type MemoryUsageProvider interface {
+	// MemoryUsage returns the device memory used by the objects of the state.
+	MemoryUsage(ctx context.Context, s *State) MemoryUsage
+}
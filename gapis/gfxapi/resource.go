@@ -46,6 +46,13 @@ type Resource interface {
 	SetResourceData(ctx context.Context, at *path.Command, data interface{}, resources ResourceMap, edits ReplaceCallback) error
 }
 
+// DeletableResource is the optional interface implemented by resources that
+// can report their deletion.
+type DeletableResource interface {
+	// IsDeleted returns true if the resource has been deleted in the state s.
+	IsDeleted(s *State) bool
+}
+
 // ResourceMeta represents resource with a state information obtained during building.
 type ResourceMeta struct {
 	Resource Resource    // Resolved resource.
@@ -126,3 +126,59 @@ func (t *Cubemap) ConvertTo(ctx context.Context, f *image.Format) (interface{},
 	}
 	return out, nil
 }
+
+// Thumbnail returns the image that most closely matches the desired size.
+func (t *Texture2DArray) Thumbnail(ctx context.Context, w, h uint32) (*image.Info2D, error) {
+	if len(t.Layers) == 0 {
+		return nil, nil
+	}
+	return t.Layers[0].Thumbnail(ctx, w, h)
+}
+
+// ConvertTo returns this Texture2DArray with each mip-level of each layer
+// converted to the requested format.
+func (t *Texture2DArray) ConvertTo(ctx context.Context, f *image.Format) (interface{}, error) {
+	out := &Texture2DArray{
+		Layers: make([]*Texture2D, len(t.Layers)),
+	}
+	for i, l := range t.Layers {
+		obj, err := l.ConvertTo(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		out.Layers[i] = obj.(*Texture2D)
+	}
+	return out, nil
+}
+
+// Thumbnail returns the front slice that most closely matches the desired
+// size.
+func (t *Texture3D) Thumbnail(ctx context.Context, w, h uint32) (*image.Info2D, error) {
+	m := imageMatcher{width: w, height: h}
+	for _, l := range t.Levels {
+		if len(l.Slices) > 0 {
+			m.consider(l.Slices[0])
+		}
+	}
+
+	return m.best, nil
+}
+
+// ConvertTo returns this Texture3D with each slice of each mip-level converted
+// to the requested format.
+func (t *Texture3D) ConvertTo(ctx context.Context, f *image.Format) (interface{}, error) {
+	out := &Texture3D{
+		Levels: make([]*Texture3DLevel, len(t.Levels)),
+	}
+	for i, l := range t.Levels {
+		out.Levels[i] = &Texture3DLevel{Slices: make([]*image.Info2D, len(l.Slices))}
+		for j, s := range l.Slices {
+			obj, err := s.ConvertTo(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			out.Levels[i].Slices[j] = obj
+		}
+	}
+	return out, nil
+}
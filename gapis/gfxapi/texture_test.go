@@ -29,5 +29,7 @@ var (
 	_ = []image.Thumbnailer{
 		(*gfxapi.Texture2D)(nil),
 		(*gfxapi.Cubemap)(nil),
+		(*gfxapi.Texture2DArray)(nil),
+		(*gfxapi.Texture3D)(nil),
 	}
 )
@@ -19,25 +19,63 @@
 
 set(files
     api.go
+    batching.go
     buffer_command.go
+    constants.go
     convert.go
     custom_replay.go
     dead_code_elimination.go
+    dead_code_elimination_test.go
     dependency_graph.go
+    depth_resolve.go
+    dispatch_snapshot.go
     doc.go
     enum.go
+    execution.go
+    experiments.go
+    export_cpp.go
+    export_test.go
     externs.go
     find_issues.go
+    frame_graph.go
+    frame_loop.go
+    hierarchy.go
+    highlight.go
+    image_planes_test.go
+    interop.go
+    layout_compatibility.go
+    leaks.go
+    lint.go
+    markers.go
+    memory_usage.go
     mutate.go
+    overdraw.go
+    overdraw_test.go
+    pipeline_statistics.go
+    portability.go
+    portability_test.go
+    query_pools.go
     read_framebuffer.go
+    read_framebuffer_test.go
+    recorded_values.go
+    redundancy.go
     replay.go
     resolvables.proto
     resources.go
+    sanitize.go
+    shader_analysis.go
+    skip.go
     snippets_embed.go
     state.go
+    state_override.go
+    state_priming.go
+    state_priming_test.go
+    submissions.go
+    timing.go
     vulkan.go
     vulkan_binary.go
     vulkan_binary_metatadata.go
+    wireframe.go
 )
 set(dirs
     android
@@ -0,0 +1,192 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+)
+
+var _ = atom.DrawBatcher(api{})
+
+// batchRecorder is the state of a command buffer being recorded that decides
+// which of its draws can be batched together.
+type batchRecorder struct {
+	pipeline VkPipeline
+	sets     map[uint32]interface{} // The bound descriptor sets by number.
+	buffers  map[uint32]interface{} // The bound vertex buffers by binding.
+	index    interface{}            // The bound index buffer.
+	// version is incremented each time the vertex or index buffers change.
+	version int
+	batch   *atom.DrawBatch // The batch of the last draw, nil if broken.
+	draw    interface{}     // The parameters of the last draw.
+	since   uint64          // The atoms recorded since the last draw.
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{
+		sets:    map[uint32]interface{}{},
+		buffers: map[uint32]interface{}{},
+	}
+}
+
+// DrawBatches returns the runs of vkCmdDraw and vkCmdDrawIndexed atoms of the
+// capture held by ctx recorded to the same command buffer with the same
+// graphics pipeline and descriptor sets, and with only vertex buffer, index
+// buffer and push constant changes between them.
+func (api) DrawBatches(ctx context.Context) ([]atom.DrawBatch, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.DrawBatch{}
+	recorders := map[VkCommandBuffer]*batchRecorder{}
+	flush := func(r *batchRecorder) {
+		if r.batch != nil && len(r.batch.Draws) > 1 {
+			out = append(out, *r.batch)
+		}
+		r.batch = nil
+	}
+	get := func(cb VkCommandBuffer) *batchRecorder {
+		r, ok := recorders[cb]
+		if !ok {
+			r = newBatchRecorder()
+			recorders[cb] = r
+		}
+		return r
+	}
+	reset := func(cb VkCommandBuffer) {
+		if r, ok := recorders[cb]; ok {
+			flush(r)
+			delete(recorders, cb)
+		}
+	}
+
+	graphics := VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		switch a := a.(type) {
+		case *VkBeginCommandBuffer:
+			reset(a.CommandBuffer)
+			continue
+		case *RecreateAndBeginCommandBuffer:
+			reset(a.PCommandBuffer.Read(ctx, a, s, nil))
+			continue
+		case *VkResetCommandBuffer:
+			reset(a.CommandBuffer)
+			continue
+		case *VkEndCommandBuffer:
+			reset(a.CommandBuffer)
+			continue
+		case *VkResetCommandPool, *VkFreeCommandBuffers:
+			for cb := range recorders {
+				reset(cb)
+			}
+			continue
+		}
+
+		cb, ok := recordedCommandBuffer(a)
+		if !ok {
+			continue
+		}
+		r := get(cb)
+		r.since++
+		switch a := a.(type) {
+		case *VkCmdBindPipeline:
+			if a.PipelineBindPoint == graphics && a.Pipeline != r.pipeline {
+				flush(r)
+				r.pipeline = a.Pipeline
+			}
+		case *VkCmdBindDescriptorSets:
+			if a.PipelineBindPoint != graphics {
+				break
+			}
+			sets := a.PDescriptorSets.Slice(0, uint64(a.DescriptorSetCount), s).Read(ctx, a, s, nil)
+			offsets := a.PDynamicOffsets.Slice(0, uint64(a.DynamicOffsetCount), s).Read(ctx, a, s, nil)
+			for i, set := range sets {
+				number := a.FirstSet + uint32(i)
+				v := []interface{}{set, offsets}
+				if !reflect.DeepEqual(r.sets[number], v) {
+					flush(r)
+					r.sets[number] = v
+				}
+			}
+		case *VkCmdBindVertexBuffers:
+			buffers := a.PBuffers.Slice(0, uint64(a.BindingCount), s).Read(ctx, a, s, nil)
+			offsets := a.POffsets.Slice(0, uint64(a.BindingCount), s).Read(ctx, a, s, nil)
+			for i := range buffers {
+				binding := a.FirstBinding + uint32(i)
+				v := [2]interface{}{buffers[i], offsets[i]}
+				if r.buffers[binding] != v {
+					r.buffers[binding] = v
+					r.version++
+				}
+			}
+		case *VkCmdBindIndexBuffer:
+			if v := [3]interface{}{a.Buffer, a.Offset, a.IndexType}; r.index != v {
+				r.index = v
+				r.version++
+			}
+		case *VkCmdPushConstants:
+			// Per-draw data, which merged draws would index by draw or instance.
+		case *VkCmdDraw:
+			r.add(id, [5]interface{}{"draw", a.VertexCount, a.InstanceCount, a.FirstVertex, r.version})
+		case *VkCmdDrawIndexed:
+			r.add(id, [6]interface{}{"indexed", a.IndexCount, a.InstanceCount, a.FirstIndex, a.VertexOffset, r.version})
+		default:
+			flush(r)
+		}
+	}
+	for cb := range recorders {
+		reset(cb)
+	}
+	sort.Sort(batchesByFirstDraw(out))
+	return out, nil
+}
+
+// add adds the draw atom id with the given parameters to the batch of the
+// last draw, or starts a new batch if there is none.
+func (r *batchRecorder) add(id atom.ID, draw interface{}) {
+	if r.batch == nil {
+		r.batch = &atom.DrawBatch{Draws: []atom.ID{id}, Commands: 1, Instanceable: true}
+	} else {
+		r.batch.Draws = append(r.batch.Draws, id)
+		r.batch.Commands += r.since
+		r.batch.Instanceable = r.batch.Instanceable && draw == r.draw
+	}
+	r.draw, r.since = draw, 0
+}
+
+// batchesByFirstDraw sorts the batches by first draw.
+type batchesByFirstDraw []atom.DrawBatch
+
+func (l batchesByFirstDraw) Len() int           { return len(l) }
+func (l batchesByFirstDraw) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l batchesByFirstDraw) Less(i, j int) bool { return l[i].Draws[0] < l[j].Draws[0] }
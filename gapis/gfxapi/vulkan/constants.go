@@ -0,0 +1,460 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service/path"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// boundBuffer is the range of a buffer bound to a buffer descriptor.
+type boundBuffer struct {
+	ty           VkDescriptorType
+	buffer       VkBuffer
+	offset, size uint64
+}
+
+// boundImage is the image view bound to an image descriptor.
+type boundImage struct {
+	ty   VkDescriptorType
+	view VkImageView
+}
+
+// descriptorKey identifies a descriptor by its set and binding numbers.
+type descriptorKey struct {
+	set, binding uint32
+}
+
+// commandBufferBindings is the state recorded to a command buffer that is used
+// to find the resources read by a draw or dispatch. Only the first element of
+// the arrays of descriptors is recorded.
+type commandBufferBindings struct {
+	pipelines     map[VkPipelineBindPoint]VkPipeline
+	buffers       map[VkPipelineBindPoint]map[descriptorKey]boundBuffer
+	images        map[VkPipelineBindPoint]map[descriptorKey]boundImage
+	pushConstants []byte
+}
+
+func newCommandBufferBindings() *commandBufferBindings {
+	return &commandBufferBindings{
+		pipelines: map[VkPipelineBindPoint]VkPipeline{},
+		buffers:   map[VkPipelineBindPoint]map[descriptorKey]boundBuffer{},
+		images:    map[VkPipelineBindPoint]map[descriptorKey]boundImage{},
+	}
+}
+
+// Constants implements the gfxapi.ConstantsProvider interface.
+// The pipeline and the uniform buffers bound to the command buffer of the
+// draw or dispatch are found by mutating the capture up to the command, and
+// the SPIR-V of the pipeline's shaders is reflected to decode the values.
+func (api) Constants(ctx context.Context, o interface{}, p *path.Constants) (*gfxapi.ConstantTable, error) {
+	a, ok := o.(atom.Atom)
+	if !ok {
+		return nil, nil
+	}
+	cb, point, ok := constantsCommandBuffer(a)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx = capture.Put(ctx, p.Command.Commands.Capture)
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := c.NewState()
+	bindings := newCommandBufferBindings()
+	for i, a := range list.Atoms[:p.Command.Index+1] {
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", i, a, err)
+		}
+		bindings.record(ctx, s, a, cb)
+	}
+
+	st := GetState(s)
+	handle, ok := bindings.pipelines[point]
+	if !ok {
+		return nil, nil
+	}
+	stages := []StageData{}
+	switch {
+	case point == VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE && st.ComputePipelines.Contains(handle):
+		stages = append(stages, st.ComputePipelines.Get(handle).Stage)
+	case point == VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS && st.GraphicsPipelines.Contains(handle):
+		pipeline := st.GraphicsPipelines.Get(handle)
+		for _, i := range pipeline.Stages.KeysSorted() {
+			stages = append(stages, pipeline.Stages[i])
+		}
+	default:
+		return nil, nil
+	}
+
+	table := &gfxapi.ConstantTable{}
+	find := func(b shadertools.UniformBlock) *gfxapi.ConstantBlock {
+		for _, t := range table.Blocks {
+			if t.PushConstant == b.PushConstant && t.Set == b.Set && t.Binding == b.Binding {
+				return t
+			}
+		}
+		t := &gfxapi.ConstantBlock{
+			Name:         b.Name,
+			PushConstant: b.PushConstant,
+			Set:          b.Set,
+			Binding:      b.Binding,
+		}
+		table.Blocks = append(table.Blocks, t)
+		return t
+	}
+
+	for _, stage := range stages {
+		if stage.Module == nil {
+			continue
+		}
+		words := stage.Module.Words.Read(ctx, nil, s, nil)
+		blocks, err := shadertools.UniformBlocks(words)
+		if err != nil {
+			log.W(ctx, "Failed to reflect shader module %v: %v", stage.Module.VulkanHandle, err)
+			continue
+		}
+		for _, b := range blocks {
+			var data []byte
+			if b.PushConstant {
+				data = bindings.pushConstants
+			} else if buf, ok := bindings.buffers[point][descriptorKey{b.Set, b.Binding}]; ok {
+				data = bufferData(ctx, s, buf)
+			}
+			t := find(b)
+			t.Stages = append(t.Stages, shaderStageType(stage.Stage))
+			for _, m := range b.Members {
+				if hasConstant(t, m) {
+					continue // Declared by a previous stage.
+				}
+				t.Constants = append(t.Constants, newConstant(s, m, data))
+			}
+		}
+	}
+	return table, nil
+}
+
+// constantsCommandBuffer returns the command buffer and the pipeline bind
+// point of the draw or dispatch atom a.
+func constantsCommandBuffer(a atom.Atom) (VkCommandBuffer, VkPipelineBindPoint, bool) {
+	compute := VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE
+	switch a := a.(type) {
+	case *VkCmdDispatch:
+		return a.CommandBuffer, compute, true
+	case *VkCmdDispatchIndirect:
+		return a.CommandBuffer, compute, true
+	case *RecreateCmdDispatch:
+		return a.CommandBuffer, compute, true
+	case *RecreateCmdDispatchIndirect:
+		return a.CommandBuffer, compute, true
+	}
+	cb, ok := drawCommandBuffer(a)
+	return cb, VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, ok
+}
+
+// record updates the bindings with the atom a if it is recorded to the
+// command buffer cb. s is the state after the atom.
+func (b *commandBufferBindings) record(ctx context.Context, s *gfxapi.State, a atom.Atom, cb VkCommandBuffer) {
+	switch a := a.(type) {
+	case *VkBeginCommandBuffer:
+		if a.CommandBuffer == cb {
+			*b = *newCommandBufferBindings()
+		}
+	case *RecreateAndBeginCommandBuffer:
+		if a.PCommandBuffer.Read(ctx, a, s, nil) == cb {
+			*b = *newCommandBufferBindings()
+		}
+	case *VkResetCommandBuffer:
+		if a.CommandBuffer == cb {
+			*b = *newCommandBufferBindings()
+		}
+	case *VkCmdBindPipeline:
+		if a.CommandBuffer == cb {
+			b.pipelines[a.PipelineBindPoint] = a.Pipeline
+		}
+	case *RecreateCmdBindPipeline:
+		if a.CommandBuffer == cb {
+			b.pipelines[a.PipelineBindPoint] = a.Pipeline
+		}
+	case *VkCmdBindDescriptorSets:
+		if a.CommandBuffer == cb {
+			sets := a.PDescriptorSets.Slice(0, uint64(a.DescriptorSetCount), s).Read(ctx, a, s, nil)
+			offsets := a.PDynamicOffsets.Slice(0, uint64(a.DynamicOffsetCount), s).Read(ctx, a, s, nil)
+			b.bindDescriptorSets(s, a.PipelineBindPoint, a.FirstSet, sets, offsets)
+		}
+	case *RecreateCmdBindDescriptorSets:
+		if a.CommandBuffer == cb {
+			sets := a.PDescriptorSets.Slice(0, uint64(a.DescriptorSetCount), s).Read(ctx, a, s, nil)
+			offsets := a.PDynamicOffsets.Slice(0, uint64(a.DynamicOffsetCount), s).Read(ctx, a, s, nil)
+			b.bindDescriptorSets(s, a.PipelineBindPoint, a.FirstSet, sets, offsets)
+		}
+	case *VkCmdPushConstants:
+		if a.CommandBuffer == cb {
+			b.pushConstant(a.Offset, U8ᵖ(a.PValues).Slice(0, uint64(a.Size), s).Read(ctx, a, s, nil))
+		}
+	case *RecreateCmdPushConstants:
+		if a.CommandBuffer == cb {
+			b.pushConstant(a.Offset, U8ᵖ(a.PValues).Slice(0, uint64(a.Size), s).Read(ctx, a, s, nil))
+		}
+	}
+}
+
+// bindDescriptorSets records the buffers and image views of the descriptor
+// sets bound from the set number first. The dynamic offsets apply to the
+// dynamic descriptors of the sets, in set, binding and array element order.
+func (b *commandBufferBindings) bindDescriptorSets(s *gfxapi.State, point VkPipelineBindPoint, first uint32, sets []VkDescriptorSet, dynamicOffsets []uint32) {
+	buffers, ok := b.buffers[point]
+	if !ok {
+		buffers = map[descriptorKey]boundBuffer{}
+		b.buffers[point] = buffers
+	}
+	images, ok := b.images[point]
+	if !ok {
+		images = map[descriptorKey]boundImage{}
+		b.images[point] = images
+	}
+	for i, handle := range sets {
+		number := first + uint32(i)
+		for key := range buffers {
+			if key.set == number {
+				delete(buffers, key)
+			}
+		}
+		for key := range images {
+			if key.set == number {
+				delete(images, key)
+			}
+		}
+		if !GetState(s).DescriptorSets.Contains(handle) {
+			continue
+		}
+		set := GetState(s).DescriptorSets.Get(handle)
+		for _, binding := range set.Bindings.KeysSorted() {
+			descBinding := set.Bindings[binding]
+			dynamic := isDynamicDescriptorType(descBinding.BindingType)
+			for j, element := range descBinding.BufferBinding.KeysSorted() {
+				dynamicOffset := uint64(0)
+				if dynamic && len(dynamicOffsets) > 0 {
+					dynamicOffset, dynamicOffsets = uint64(dynamicOffsets[0]), dynamicOffsets[1:]
+				}
+				bufferInfo := descBinding.BufferBinding[element]
+				if j > 0 || bufferInfo == nil {
+					continue
+				}
+				buffers[descriptorKey{number, binding}] = boundBuffer{
+					ty:     descBinding.BindingType,
+					buffer: bufferInfo.Buffer,
+					offset: uint64(bufferInfo.Offset) + dynamicOffset,
+					size:   uint64(bufferInfo.Range),
+				}
+			}
+			if elements := descBinding.ImageBinding.KeysSorted(); len(elements) > 0 {
+				if imageInfo := descBinding.ImageBinding[elements[0]]; imageInfo != nil {
+					images[descriptorKey{number, binding}] = boundImage{descBinding.BindingType, imageInfo.ImageView}
+				}
+			}
+			if elements := descBinding.BufferViewBindings.KeysSorted(); len(elements) > 0 {
+				view := descBinding.BufferViewBindings[elements[0]]
+				if GetState(s).BufferViews.Contains(view) {
+					if v := GetState(s).BufferViews.Get(view); v.Buffer != nil {
+						buffers[descriptorKey{number, binding}] = boundBuffer{
+							ty:     descBinding.BindingType,
+							buffer: v.Buffer.VulkanHandle,
+							offset: uint64(v.Offset),
+							size:   uint64(v.Range),
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// pushConstant records the push constant data written at offset.
+func (b *commandBufferBindings) pushConstant(offset uint32, data []byte) {
+	if end := int(offset) + len(data); end > len(b.pushConstants) {
+		b.pushConstants = append(b.pushConstants, make([]byte, end-len(b.pushConstants))...)
+	}
+	copy(b.pushConstants[offset:], data)
+}
+
+// bufferData returns the content of the bound buffer range, or nil if the
+// buffer has no memory bound.
+func bufferData(ctx context.Context, s *gfxapi.State, b boundBuffer) []byte {
+	if !GetState(s).Buffers.Contains(b.buffer) {
+		return nil
+	}
+	buffer := GetState(s).Buffers.Get(b.buffer)
+	if buffer.Memory == nil {
+		return nil
+	}
+	size := b.size
+	if size == 0xFFFFFFFFFFFFFFFF { // VK_WHOLE_SIZE
+		size = uint64(buffer.Info.Size) - b.offset
+	}
+	start := uint64(buffer.MemoryOffset) + b.offset
+	end := start + size
+	if end > buffer.Memory.Data.Count {
+		end = buffer.Memory.Data.Count
+	}
+	if start >= end {
+		return nil
+	}
+	return buffer.Memory.Data.Slice(start, end, s).Read(ctx, nil, s, nil)
+}
+
+func hasConstant(t *gfxapi.ConstantBlock, m shadertools.BlockMember) bool {
+	for _, c := range t.Constants {
+		if c.Offset == m.Offset && c.Name == m.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// newConstant returns the constant for the block member m, with its value
+// decoded from the block data if available.
+func newConstant(s *gfxapi.State, m shadertools.BlockMember, data []byte) *gfxapi.Constant {
+	c := &gfxapi.Constant{
+		Name:   m.Name,
+		Offset: m.Offset,
+		Format: uniformFormat(m.Columns, m.Rows),
+		Type:   uniformType(m.Kind),
+		Count:  m.Count,
+	}
+	// Gather the scalars, skipping the padding between them.
+	size := m.ScalarSize()
+	packed := []byte{}
+	for _, o := range m.ScalarOffsets() {
+		if int(o+size) > len(data) {
+			return c
+		}
+		packed = append(packed, data[o:o+size]...)
+	}
+	r := endian.Reader(bytes.NewReader(packed), s.MemoryLayout.GetEndian())
+	n := len(packed) / int(size)
+	switch c.Type {
+	case gfxapi.UniformType_Int32:
+		v := make([]int32, n)
+		for i := range v {
+			v[i] = r.Int32()
+		}
+		c.Value = pod.NewValue(v)
+	case gfxapi.UniformType_Uint32:
+		v := make([]uint32, n)
+		for i := range v {
+			v[i] = r.Uint32()
+		}
+		c.Value = pod.NewValue(v)
+	case gfxapi.UniformType_Bool:
+		v := make([]bool, n)
+		for i := range v {
+			v[i] = r.Uint32() != 0
+		}
+		c.Value = pod.NewValue(v)
+	case gfxapi.UniformType_Float:
+		v := make([]float32, n)
+		for i := range v {
+			v[i] = r.Float32()
+		}
+		c.Value = pod.NewValue(v)
+	case gfxapi.UniformType_Double:
+		v := make([]float64, n)
+		for i := range v {
+			v[i] = r.Float64()
+		}
+		c.Value = pod.NewValue(v)
+	}
+	return c
+}
+
+func uniformFormat(columns, rows uint32) gfxapi.UniformFormat {
+	switch {
+	case columns == 1 && rows == 2:
+		return gfxapi.UniformFormat_Vec2
+	case columns == 1 && rows == 3:
+		return gfxapi.UniformFormat_Vec3
+	case columns == 1 && rows == 4:
+		return gfxapi.UniformFormat_Vec4
+	case columns == 2 && rows == 2:
+		return gfxapi.UniformFormat_Mat2
+	case columns == 3 && rows == 3:
+		return gfxapi.UniformFormat_Mat3
+	case columns == 4 && rows == 4:
+		return gfxapi.UniformFormat_Mat4
+	case columns == 2 && rows == 3:
+		return gfxapi.UniformFormat_Mat2x3
+	case columns == 2 && rows == 4:
+		return gfxapi.UniformFormat_Mat2x4
+	case columns == 3 && rows == 2:
+		return gfxapi.UniformFormat_Mat3x2
+	case columns == 3 && rows == 4:
+		return gfxapi.UniformFormat_Mat3x4
+	case columns == 4 && rows == 2:
+		return gfxapi.UniformFormat_Mat4x2
+	case columns == 4 && rows == 3:
+		return gfxapi.UniformFormat_Mat4x3
+	default:
+		return gfxapi.UniformFormat_Scalar
+	}
+}
+
+func uniformType(kind shadertools.ScalarKind) gfxapi.UniformType {
+	switch kind {
+	case shadertools.Int:
+		return gfxapi.UniformType_Int32
+	case shadertools.Uint:
+		return gfxapi.UniformType_Uint32
+	case shadertools.Bool:
+		return gfxapi.UniformType_Bool
+	case shadertools.Double:
+		return gfxapi.UniformType_Double
+	default:
+		return gfxapi.UniformType_Float
+	}
+}
+
+func shaderStageType(stage VkShaderStageFlagBits) gfxapi.ShaderType {
+	switch stage {
+	case VkShaderStageFlagBits_VK_SHADER_STAGE_TESSELLATION_CONTROL_BIT:
+		return gfxapi.ShaderType_TessControl
+	case VkShaderStageFlagBits_VK_SHADER_STAGE_TESSELLATION_EVALUATION_BIT:
+		return gfxapi.ShaderType_TessEvaluation
+	case VkShaderStageFlagBits_VK_SHADER_STAGE_GEOMETRY_BIT:
+		return gfxapi.ShaderType_Geometry
+	case VkShaderStageFlagBits_VK_SHADER_STAGE_FRAGMENT_BIT:
+		return gfxapi.ShaderType_Fragment
+	case VkShaderStageFlagBits_VK_SHADER_STAGE_COMPUTE_BIT:
+		return gfxapi.ShaderType_Compute
+	default:
+		return gfxapi.ShaderType_Vertex
+	}
+}
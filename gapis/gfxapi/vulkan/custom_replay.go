@@ -233,6 +233,13 @@ func (i VkDebugReportCallbackEXT) remap(_ atom.Atom, _ *gfxapi.State) (key inter
 	return
 }
 
+func (i VkSamplerYcbcrConversionKHR) remap(_ atom.Atom, _ *gfxapi.State) (key interface{}, remap bool) {
+	if i != 0 {
+		key, remap = i, true
+	}
+	return
+}
+
 func (a *VkCreateInstance) Mutate(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
 	// Hijack VkCreateInstance's Mutate() method entirely with our ReplayCreateVkInstance's Mutate().
 
@@ -903,6 +910,17 @@ func (a *RecreateSampler) Mutate(ctx context.Context, s *gfxapi.State, b *builde
 	return hijack.Mutate(ctx, s, b)
 }
 
+func (a *RecreateSamplerYcbcrConversion) Mutate(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+	hijack := NewVkCreateSamplerYcbcrConversionKHR(
+		a.Device,
+		memory.Pointer(a.PCreateInfo),
+		memory.Pointer{},
+		memory.Pointer(a.PYcbcrConversion),
+		VkResult(0))
+	hijack.Extras().Add(a.Extras().All()...)
+	return hijack.Mutate(ctx, s, b)
+}
+
 func (a *RecreateFramebuffer) Mutate(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
 	createInfo := memory.Pointer(a.PCreateInfo)
 	allocator := memory.Pointer{}
@@ -1406,7 +1424,6 @@ func (a *RecreateImageData) Mutate(ctx context.Context, s *gfxapi.State, b *buil
 		srcLayout := VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED
 
 		if a.Data != NewVoidᵖ(0) {
-			imageInfo := imageObject.Info
 			bufferId, memoryId, err = createAndBindSourceBuffer(ctx, s, b, device, a.DataSize, a.HostMemoryIndex)
 			if err != nil {
 				return err
@@ -1433,41 +1450,7 @@ func (a *RecreateImageData) Mutate(ctx context.Context, s *gfxapi.State, b *buil
 				return err
 			}
 			srcLayout = VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL
-			copies := []VkBufferImageCopy{}
-
-			block_info, _ := subGetElementAndTexelBlockSize(ctx, a, nil, nil, nil, nil, imageInfo.Format)
-			offset := VkDeviceSize(0)
-
-			for i := uint32(0); i < imageInfo.MipLevels; i++ {
-				width, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, imageInfo.Extent.Width, i)
-				height, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, imageInfo.Extent.Height, i)
-				depth, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, imageInfo.Extent.Depth, i)
-				width_in_blocks, _ := subRoundUpTo(ctx, a, nil, nil, nil, nil, width, block_info.TexelBlockSize.Width)
-				height_in_blocks, _ := subRoundUpTo(ctx, a, nil, nil, nil, nil, height, block_info.TexelBlockSize.Height)
-				copies = append(copies, VkBufferImageCopy{
-					BufferOffset:      offset,
-					BufferRowLength:   0, // Tightly packed
-					BufferImageHeight: 0, // Tightly packed
-					ImageSubresource: VkImageSubresourceLayers{
-						AspectMask:     imageObject.ImageAspect,
-						MipLevel:       i,
-						BaseArrayLayer: 0,
-						LayerCount:     imageObject.Info.ArrayLayers,
-					},
-					ImageOffset: VkOffset3D{
-						X: 0,
-						Y: 0,
-						Z: 0,
-					},
-					ImageExtent: VkExtent3D{
-						Width:  width,
-						Height: height,
-						Depth:  depth,
-					},
-				})
-
-				offset += VkDeviceSize(width_in_blocks * height_in_blocks * depth * block_info.ElementSize * imageObject.Info.ArrayLayers)
-			}
+			copies := imageDataCopies(ctx, a, imageObject)
 
 			pointer := atom.Must(atom.AllocData(ctx, s, copies))
 			defer pointer.Free()
@@ -1516,6 +1499,58 @@ func (a *RecreateImageData) Mutate(ctx context.Context, s *gfxapi.State, b *buil
 	return nil
 }
 
+// imageDataCopies returns the copies of the tightly packed data of all the
+// planes, levels and layers of the image to the image. Multi-planar images
+// are copied plane by plane, each plane in its own format and with its own
+// subsampled extent.
+func imageDataCopies(ctx context.Context, a *RecreateImageData, imageObject *ImageObject) []VkBufferImageCopy {
+	imageInfo := imageObject.Info
+	copies := []VkBufferImageCopy{}
+	aspects := []VkImageAspectFlags{imageObject.ImageAspect}
+	if planeCount, _ := subGetPlaneCount(ctx, a, nil, nil, nil, nil, imageInfo.Format); planeCount > 0 {
+		aspects = planeAspects[:planeCount]
+	}
+	offset := VkDeviceSize(0)
+
+	for p, aspect := range aspects {
+		plane_info, _ := subGetPlaneInfo(ctx, a, nil, nil, nil, nil, imageInfo.Format, uint32(p))
+		block_info, _ := subGetElementAndTexelBlockSize(ctx, a, nil, nil, nil, nil, plane_info.Format)
+		for i := uint32(0); i < imageInfo.MipLevels; i++ {
+			width, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, imageInfo.Extent.Width, i)
+			height, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, imageInfo.Extent.Height, i)
+			depth, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, imageInfo.Extent.Depth, i)
+			width, _ = subRoundUpTo(ctx, a, nil, nil, nil, nil, width, plane_info.WidthDivisor)
+			height, _ = subRoundUpTo(ctx, a, nil, nil, nil, nil, height, plane_info.HeightDivisor)
+			width_in_blocks, _ := subRoundUpTo(ctx, a, nil, nil, nil, nil, width, block_info.TexelBlockSize.Width)
+			height_in_blocks, _ := subRoundUpTo(ctx, a, nil, nil, nil, nil, height, block_info.TexelBlockSize.Height)
+			copies = append(copies, VkBufferImageCopy{
+				BufferOffset:      offset,
+				BufferRowLength:   0, // Tightly packed
+				BufferImageHeight: 0, // Tightly packed
+				ImageSubresource: VkImageSubresourceLayers{
+					AspectMask:     aspect,
+					MipLevel:       i,
+					BaseArrayLayer: 0,
+					LayerCount:     imageInfo.ArrayLayers,
+				},
+				ImageOffset: VkOffset3D{
+					X: 0,
+					Y: 0,
+					Z: 0,
+				},
+				ImageExtent: VkExtent3D{
+					Width:  width,
+					Height: height,
+					Depth:  depth,
+				},
+			})
+
+			offset += VkDeviceSize(width_in_blocks * height_in_blocks * depth * block_info.ElementSize * imageInfo.ArrayLayers)
+		}
+	}
+	return copies
+}
+
 func (a *RecreateBuffer) Mutate(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
 	// ApplyReads() is necessary only because we need to access the Read
 	// observation data prior to calling the VkCreateBuffer's Mutate().
@@ -0,0 +1,379 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/config"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi/interop"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/shadertools"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+)
+
+const (
+	vsSource = `
+		#version 450
+		void main() {
+			gl_Position = vec4(0.0, 0.0, 0.0, 1.0);
+		}`
+	fsSource = `
+		#version 450
+		layout(location = 0) out vec4 color;
+		void main() {
+			color = vec4(1.0);
+		}`
+	csSource = `
+		#version 450
+		layout(local_size_x = 1) in;
+		layout(set = 0, binding = 0) uniform Values {
+			vec4 values[16];
+		};
+		void main() {
+		}`
+	bindlessSource = `
+		#version 450
+		layout(local_size_x = 1) in;
+		layout(set = 0, binding = 0) buffer Values {
+			vec4 values[16];
+		};
+		layout(set = 0, binding = 1) buffer Unused {
+			vec4 unused[16];
+		};
+		void main() {
+			values[0] = vec4(1.0);
+		}`
+	storage = vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_STORAGE_BUFFER_BIT |
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_SRC_BIT |
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT
+	attachment = vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT |
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT
+)
+
+// dceTest records the atoms requested and the atoms expected to be removed
+// from the atoms of the builder, so a test only lists the behaviour it checks.
+type dceTest struct {
+	*samples.Builder
+	requests []atom.ID
+	removed  atom.IDSet
+}
+
+func newDCETest(ctx context.Context) *dceTest {
+	return &dceTest{Builder: samples.NewBuilder(ctx), removed: atom.IDSet{}}
+}
+
+// request requests the atom id.
+func (b *dceTest) request(id atom.ID) {
+	b.requests = append(b.requests, id)
+}
+
+// dead calls f, expecting all the atoms it adds to be removed.
+func (b *dceTest) dead(f func()) {
+	first := atom.ID(len(b.Atoms))
+	f()
+	for id := first; id < atom.ID(len(b.Atoms)); id++ {
+		b.removed.Add(id)
+	}
+}
+
+// bindlessDescriptors adds the submission of a dispatch using a descriptor set
+// that can be updated after it is bound, with a descriptor overwritten before
+// the submission and a descriptor that the shader does not use. The atoms of
+// those two descriptors are added by calling overwritten and unused. The
+// descriptors are written after the set is bound if afterBind is true.
+func (b *dceTest) bindlessDescriptors(ctx context.Context, afterBind bool, overwritten, unused func(func())) {
+	const (
+		storageBuffer = vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER
+		compute       = vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE
+	)
+	device, queue, pool := b.NewDevice(ctx)
+	set, layout := b.BindlessDescriptorSet(ctx, device, storageBuffer, vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_COMPUTE_BIT, 2)
+	cs := b.ShaderModule(ctx, device, bindlessSource, shadertools.StageCompute)
+	pipeline := b.ComputePipeline(ctx, device, layout, cs)
+	overwritten(func() {
+		stale := b.Buffer(ctx, device, 256, storage)
+		b.WriteBufferAt(ctx, device, set, 0, storageBuffer, stale, 0, 256)
+	})
+	values := b.Buffer(ctx, device, 256, storage)
+	write := func() {
+		b.WriteBufferAt(ctx, device, set, 0, storageBuffer, values, 0, 256)
+		unused(func() {
+			buffer := b.Buffer(ctx, device, 256, storage)
+			b.WriteBufferAt(ctx, device, set, 1, storageBuffer, buffer, 0, 256)
+		})
+	}
+	if !afterBind {
+		write()
+	}
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	b.Add(vulkan.NewVkCmdBindPipeline(cb, compute, pipeline))
+	b.BindDescriptorSet(ctx, cb, compute, layout, set)
+	b.Add(vulkan.NewVkCmdDispatch(cb, 1, 1, 1))
+	if afterBind {
+		write()
+	}
+	b.request(b.Submit(ctx, queue, cb))
+}
+
+// check imports the atoms as the capture name, then asserts that dead code
+// elimination keeps exactly the atoms which are not expected to be removed.
+func (b *dceTest) check(ctx context.Context, name string) {
+	ctx = log.V{"test": name}.Bind(ctx)
+	c, err := capture.ImportAtomList(ctx, name, &b.List)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+	tracker := b.Atoms[0].API().(atom.DependencyTracker)
+	kept, err := tracker.Dependencies(ctx, b.requests)
+	if !assert.For(ctx, "Dependencies").ThatError(err).Succeeded() {
+		return
+	}
+	expected := []atom.ID{}
+	for i := range b.Atoms {
+		if id := atom.ID(i); !b.removed.Contains(id) {
+			expected = append(expected, id)
+		}
+	}
+	assert.For(ctx, "Test '%v'", name).ThatSlice(kept).Equals(expected)
+}
+
+func TestDeadAtomRemoval(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	tests := map[string]func(ctx context.Context, b *dceTest){
+		"Resources not used by submitted commands are removed": func(ctx context.Context, b *dceTest) {
+			device, queue, pool := b.NewDevice(ctx)
+			buffer := b.Buffer(ctx, device, 64, storage)
+			b.dead(func() { b.Buffer(ctx, device, 64, storage) })
+			image := b.Image(ctx, device, 64, 64, attachment)
+			b.dead(func() { b.ImageView(ctx, device, image) })
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.UpdateBuffer(ctx, cb, buffer, make([]uint32, 16))
+			b.ClearImage(ctx, cb, image)
+			b.request(b.Submit(ctx, queue, cb))
+		},
+		"Command buffers never submitted are removed": func(ctx context.Context, b *dceTest) {
+			device, queue, pool := b.NewDevice(ctx)
+			src := b.Buffer(ctx, device, 64, storage)
+			dst := b.Buffer(ctx, device, 64, storage)
+			unsubmitted := b.CommandBuffer(ctx, device, pool)
+			b.dead(func() {
+				b.Begin(ctx, unsubmitted)
+				b.CopyBuffer(ctx, unsubmitted, dst, src, 64)
+				b.Add(vulkan.NewVkEndCommandBuffer(unsubmitted, vulkan.VkResult_VK_SUCCESS))
+			})
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.UpdateBuffer(ctx, cb, src, make([]uint32, 16))
+			b.CopyBuffer(ctx, cb, src, dst, 64)
+			b.request(b.Submit(ctx, queue, cb))
+		},
+		"Atoms after the last request are removed": func(ctx context.Context, b *dceTest) {
+			device, queue, pool := b.NewDevice(ctx)
+			buffer := b.Buffer(ctx, device, 64, storage)
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.UpdateBuffer(ctx, cb, buffer, make([]uint32, 16))
+			b.request(b.Submit(ctx, queue, cb))
+			b.dead(func() {
+				b.WaitIdle(queue)
+				b.Buffer(ctx, device, 64, storage)
+			})
+		},
+		"Destroyed resources keep their creation": func(ctx context.Context, b *dceTest) {
+			device, queue, pool := b.NewDevice(ctx)
+			buffer := b.Buffer(ctx, device, 64, storage)
+			b.Add(vulkan.NewVkDestroyBuffer(device, buffer, memory.Nullptr))
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.request(b.Submit(ctx, queue, cb))
+		},
+		"Draws keep the pipeline and framebuffer they use": func(ctx context.Context, b *dceTest) {
+			device, queue, pool := b.NewDevice(ctx)
+			image := b.Image(ctx, device, 64, 64, attachment)
+			view := b.ImageView(ctx, device, image)
+			renderPass := b.RenderPass(ctx, device, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL)
+			framebuffer := b.Framebuffer(ctx, device, renderPass, view, 64, 64)
+			vs := b.ShaderModule(ctx, device, vsSource, shadertools.StageVertex)
+			fs := b.ShaderModule(ctx, device, fsSource, shadertools.StageFragment)
+			layout := b.PipelineLayout(ctx, device)
+			pipeline := b.GraphicsPipeline(ctx, device, renderPass, layout, vs, fs, 64, 64, 0)
+			b.dead(func() {
+				b.GraphicsPipeline(ctx, device, renderPass, layout, vs, fs, 64, 64, 0)
+				b.ShaderModule(ctx, device, fsSource, shadertools.StageFragment)
+			})
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.BeginRenderPass(ctx, cb, renderPass, framebuffer, 64, 64, [4]float32{})
+			b.Add(
+				vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, pipeline),
+				vulkan.NewVkCmdDraw(cb, 3, 1, 0, 0),
+				vulkan.NewVkCmdEndRenderPass(cb),
+			)
+			b.request(b.Submit(ctx, queue, cb))
+		},
+		"Presents keep the submissions before them": func(ctx context.Context, b *dceTest) {
+			device, queue, pool := b.NewDevice(ctx)
+			swapchain := b.Swapchain(ctx, device, 64, 64)
+			buffer := b.Buffer(ctx, device, 64, storage)
+			b.dead(func() { b.Buffer(ctx, device, 64, storage) })
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.UpdateBuffer(ctx, cb, buffer, make([]uint32, 16))
+			b.Submit(ctx, queue, cb)
+			b.request(b.Present(ctx, queue, swapchain))
+			b.dead(func() { b.Present(ctx, queue, swapchain) })
+		},
+		"Dynamic uniform buffers keep the buffers aliasing their bound ranges": func(ctx context.Context, b *dceTest) {
+			const (
+				dynamic = vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER_DYNAMIC
+				uniform = vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_UNIFORM_BUFFER_BIT
+			)
+			device, queue, pool := b.NewDevice(ctx)
+			mem := b.DeviceMemory(ctx, device, 768)
+			uniforms := b.BufferIn(ctx, device, mem, 0, 768, uniform)
+			// Only the buffers within the ranges at the two dynamic offsets are read.
+			b.BufferIn(ctx, device, mem, 64, 128, uniform)
+			b.dead(func() { b.BufferIn(ctx, device, mem, 320, 128, uniform) })
+			b.BufferIn(ctx, device, mem, 576, 128, uniform)
+			set, layout := b.DescriptorSet(ctx, device, dynamic, vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_COMPUTE_BIT)
+			b.WriteBuffer(ctx, device, set, dynamic, uniforms, 0, 256)
+			cs := b.ShaderModule(ctx, device, csSource, shadertools.StageCompute)
+			pipeline := b.ComputePipeline(ctx, device, layout, cs)
+			cb := b.BeginCommandBuffer(ctx, device, pool)
+			b.Add(vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE, pipeline))
+			b.BindDescriptorSet(ctx, cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE, layout, set, 0)
+			b.Add(vulkan.NewVkCmdDispatch(cb, 1, 1, 1))
+			b.BindDescriptorSet(ctx, cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE, layout, set, 512)
+			b.Add(vulkan.NewVkCmdDispatch(cb, 1, 1, 1))
+			b.request(b.Submit(ctx, queue, cb))
+		},
+		"Bindless descriptors overwritten or not used by the shaders are removed": func(ctx context.Context, b *dceTest) {
+			b.bindlessDescriptors(ctx, true, b.dead, b.dead)
+		},
+	}
+
+	for name, f := range tests {
+		b := newDCETest(ctx)
+		f(ctx, b)
+		b.check(ctx, name)
+	}
+}
+
+func TestPerDescriptorBindlessDCEOption(t *testing.T) {
+	ctx := log.Testing(t)
+	defer func(enabled bool) { config.PerDescriptorBindlessDCE = enabled }(config.PerDescriptorBindlessDCE)
+
+	// Without per-descriptor tracking, every write to the set is kept.
+	kept := func(f func()) { f() }
+	for _, enabled := range []bool{true, false} {
+		config.PerDescriptorBindlessDCE = enabled
+		ctx := database.Put(ctx, database.NewInMemory(ctx))
+		b := newDCETest(ctx)
+		if enabled {
+			b.bindlessDescriptors(ctx, false, b.dead, b.dead)
+		} else {
+			b.bindlessDescriptors(ctx, false, kept, kept)
+		}
+		b.check(ctx, fmt.Sprintf("Per-descriptor bindless DCE %v", enabled))
+	}
+}
+
+func TestImageSubresourceDependencies(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	b := samples.NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+	buffer := b.Buffer(ctx, device, 64*64*4, storage)
+	image := b.MipmappedImage(ctx, device, 64, 64, 2, attachment)
+	src := b.Image(ctx, device, 64, 64, attachment)
+	submit := func(record func(cb vulkan.VkCommandBuffer)) int {
+		cb := b.BeginCommandBuffer(ctx, device, pool)
+		record(cb)
+		return int(b.Submit(ctx, queue, cb))
+	}
+	corner := vulkan.VkOffset3D{X: 64, Y: 64, Z: 1}
+	origin := vulkan.VkOffset3D{X: 0, Y: 0, Z: 0}
+	clear := submit(func(cb vulkan.VkCommandBuffer) { b.ClearImage(ctx, cb, image) })
+	level0 := submit(func(cb vulkan.VkCommandBuffer) { b.CopyBufferToImage(ctx, cb, buffer, image, 64, 64, 0) })
+	level1 := submit(func(cb vulkan.VkCommandBuffer) { b.CopyBufferToImage(ctx, cb, buffer, image, 64, 64, 1) })
+	partialBlit := submit(func(cb vulkan.VkCommandBuffer) {
+		b.BlitImage(ctx, cb, src, corner, image, [2]vulkan.VkOffset3D{origin, {X: 32, Y: 32, Z: 1}})
+	})
+	fullBlit := submit(func(cb vulkan.VkCommandBuffer) {
+		// The corners of the destination box can be given in any order.
+		b.BlitImage(ctx, cb, src, corner, image, [2]vulkan.VkOffset3D{corner, origin})
+	})
+	undefined := submit(func(cb vulkan.VkCommandBuffer) {
+		b.ImageBarrier(ctx, cb, image, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL)
+	})
+	transition := submit(func(cb vulkan.VkCommandBuffer) {
+		b.ImageBarrier(ctx, cb, image, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL)
+	})
+
+	c, err := capture.ImportAtomList(ctx, "image subresources", &b.List)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+	g, err := vulkan.GetDependencyGraph(ctx)
+	if !assert.For(ctx, "GetDependencyGraph").ThatError(err).Succeeded() {
+		return
+	}
+	contains := func(addresses []interop.StateAddress, address interop.StateAddress) bool {
+		for _, a := range addresses {
+			if a == address {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The clear of the first mip level only writes its subresource.
+	writes := g.Behaviour(clear).Write
+	if !assert.For(ctx, "Clear writes").ThatInteger(len(writes)).Equals(1) {
+		return
+	}
+	mip0 := writes[0]
+	assert.For(ctx, "Copy to mip 0 writes").ThatSlice(g.Behaviour(level0).Write).Equals([]interop.StateAddress{mip0})
+
+	// Overwriting mip 0 does not touch mip 1, nor the image as a whole.
+	writes = g.Behaviour(level1).Write
+	if !assert.For(ctx, "Copy to mip 1 writes").ThatInteger(len(writes)).Equals(1) {
+		return
+	}
+	toMip0 := g.Behaviour(level0)
+	for mip1 := writes[0]; mip1 != 0; mip1 = g.Parent(mip1) {
+		assert.For(ctx, "Copy to mip 0 writes %v", mip1).That(contains(toMip0.Write, mip1)).Equals(false)
+		assert.For(ctx, "Copy to mip 0 modifies %v", mip1).That(contains(toMip0.Modify, mip1)).Equals(false)
+	}
+
+	// A blit to part of a mip level keeps the previous content.
+	assert.For(ctx, "Partial blit modifies mip 0").That(contains(g.Behaviour(partialBlit).Modify, mip0)).Equals(true)
+	assert.For(ctx, "Partial blit writes mip 0").That(contains(g.Behaviour(partialBlit).Write, mip0)).Equals(false)
+	assert.For(ctx, "Full blit writes mip 0").That(contains(g.Behaviour(fullBlit).Write, mip0)).Equals(true)
+
+	// A transition from the undefined layout discards the content.
+	assert.For(ctx, "Barrier from undefined writes mip 0").That(contains(g.Behaviour(undefined).Write, mip0)).Equals(true)
+	assert.For(ctx, "Transition modifies mip 0").That(contains(g.Behaviour(transition).Modify, mip0)).Equals(true)
+	assert.For(ctx, "Transition writes mip 0").That(contains(g.Behaviour(transition).Write, mip0)).Equals(false)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/gapid/gapis/config"
 	"github.com/google/gapid/gapis/database"
 	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/shadertools"
 )
 
 var dependencyGraphBuildCounter = benchmark.GlobalCounters.Duration("dependencyGraph.build")
@@ -49,6 +50,8 @@ type vulkanDeviceMemoryBinding struct {
 	start  uint64
 	end    uint64
 	data   *vulkanDeviceMemoryData
+	image  VkImage  // The image bound to the memory range, if any.
+	buffer VkBuffer // The buffer bound to the memory range, if any.
 }
 
 var emptyMemoryBindings = []*vulkanDeviceMemoryBinding{}
@@ -127,13 +130,20 @@ type vulkanCommandBufferHandle struct {
 
 type vulkanRecordedCommands struct {
 	CommandBuffer *vulkanCommandBuffer
-	Commands      []func(b *AtomBehaviour)
+	Commands      []recordedCommand
+}
+
+// recordedCommand is a command recorded to a command buffer, with the
+// behaviours to carry out when the command buffer is submitted.
+type recordedCommand struct {
+	id     atom.ID
+	behave func(b *AtomBehaviour)
 }
 
 func newVulkanCommandBuffer(handle VkCommandBuffer) *vulkanCommandBuffer {
 	cb := &vulkanCommandBuffer{handle: nil, records: nil}
 	cb.handle = &vulkanCommandBufferHandle{CommandBuffer: cb, vkCommandBuffer: handle}
-	cb.records = &vulkanRecordedCommands{CommandBuffer: cb, Commands: []func(b *AtomBehaviour){}}
+	cb.records = &vulkanRecordedCommands{CommandBuffer: cb, Commands: []recordedCommand{}}
 	return cb
 }
 
@@ -149,11 +159,210 @@ func (c *vulkanRecordedCommands) Parent() stateKey {
 	return c.CommandBuffer
 }
 
-func (c *vulkanRecordedCommands) appendCommand(f func(b *AtomBehaviour)) *vulkanRecordedCommands {
-	c.Commands = append(c.Commands, f)
+func (c *vulkanRecordedCommands) appendCommand(id atom.ID, f func(b *AtomBehaviour)) *vulkanRecordedCommands {
+	c.Commands = append(c.Commands, recordedCommand{id, f})
 	return c
 }
 
+// Descriptor set composition hierarchy (parent -> child), used for the
+// descriptor sets whose descriptors can be updated after the set is bound or
+// left partially bound (VK_EXT_descriptor_indexing):
+// vulkanDescriptorSet -> vulkanDescriptor
+type vulkanDescriptorSet struct {
+	vkDescriptorSet VkDescriptorSet
+	descriptors     map[vulkanDescriptorIndex]*vulkanDescriptor
+}
+
+type vulkanDescriptorIndex struct {
+	binding uint32
+	element uint32
+}
+
+type vulkanDescriptor struct {
+	set   *vulkanDescriptorSet
+	index vulkanDescriptorIndex
+}
+
+func newVulkanDescriptorSet(handle VkDescriptorSet) *vulkanDescriptorSet {
+	return &vulkanDescriptorSet{
+		vkDescriptorSet: handle,
+		descriptors:     map[vulkanDescriptorIndex]*vulkanDescriptor{},
+	}
+}
+
+func (s *vulkanDescriptorSet) Parent() stateKey {
+	return nil
+}
+
+func (d *vulkanDescriptor) Parent() stateKey {
+	return d.set
+}
+
+// descriptor returns the stateKey of the descriptor at the given binding and
+// array element of the descriptor set.
+func (s *vulkanDescriptorSet) descriptor(binding, element uint32) *vulkanDescriptor {
+	index := vulkanDescriptorIndex{binding, element}
+	if d, ok := s.descriptors[index]; ok {
+		return d
+	}
+	d := &vulkanDescriptor{set: s, index: index}
+	s.descriptors[index] = d
+	return d
+}
+
+// Image composition hierarchy (parent -> child), used to track the aspects,
+// array layers and mip levels of an image bound to device memory:
+// vulkanDeviceMemoryData -> vulkanImageSubresource
+// Reading or writing the memory binding of the image as a whole touches all
+// of its subresources.
+type vulkanImage struct {
+	vkImage      VkImage
+	data         *vulkanDeviceMemoryData
+	subresources map[vulkanImageSubresourceIndex]*vulkanImageSubresource
+}
+
+type vulkanImageSubresourceIndex struct {
+	aspect uint32
+	layer  uint32
+	level  uint32
+}
+
+type vulkanImageSubresource struct {
+	image *vulkanImage
+	index vulkanImageSubresourceIndex
+}
+
+func newVulkanImage(handle VkImage, data *vulkanDeviceMemoryData) *vulkanImage {
+	return &vulkanImage{
+		vkImage:      handle,
+		data:         data,
+		subresources: map[vulkanImageSubresourceIndex]*vulkanImageSubresource{},
+	}
+}
+
+func (s *vulkanImageSubresource) Parent() stateKey {
+	return s.image.data
+}
+
+// subresource returns the stateKey of the subresource at the given aspect,
+// array layer and mip level of the image.
+func (i *vulkanImage) subresource(aspect, layer, level uint32) *vulkanImageSubresource {
+	index := vulkanImageSubresourceIndex{aspect, layer, level}
+	if s, ok := i.subresources[index]; ok {
+		return s
+	}
+	s := &vulkanImageSubresource{image: i, index: index}
+	i.subresources[index] = s
+	return s
+}
+
+// imageSubresourceRange is a range of subresources of an image touched by a
+// command. Level and layer counts can be VK_REMAINING_MIP_LEVELS and
+// VK_REMAINING_ARRAY_LAYERS.
+type imageSubresourceRange struct {
+	image      VkImage
+	aspectMask VkImageAspectFlags
+	baseLevel  uint32
+	levelCount uint32
+	baseLayer  uint32
+	layerCount uint32
+}
+
+const vkRemaining = uint32(0xFFFFFFFF)
+
+func subresourceRangeFromLayers(image VkImage, l VkImageSubresourceLayers) imageSubresourceRange {
+	return imageSubresourceRange{image, l.AspectMask, l.MipLevel, 1, l.BaseArrayLayer, l.LayerCount}
+}
+
+func subresourceRangeFromRange(image VkImage, r VkImageSubresourceRange) imageSubresourceRange {
+	return imageSubresourceRange{image, r.AspectMask, r.BaseMipLevel, r.LevelCount, r.BaseArrayLayer, r.LayerCount}
+}
+
+// coversImageLevel returns true if the box at the given offset and extent
+// covers the whole of the given mip level of an image.
+func coversImageLevel(info ImageInfo, level uint32, offset VkOffset3D, extent VkExtent3D) bool {
+	levelSize := func(size uint32) uint32 {
+		if size>>level == 0 {
+			return 1
+		}
+		return size >> level
+	}
+	return offset.X <= 0 && offset.Y <= 0 && offset.Z <= 0 &&
+		int64(offset.X)+int64(extent.Width) >= int64(levelSize(info.Extent.Width)) &&
+		int64(offset.Y)+int64(extent.Height) >= int64(levelSize(info.Extent.Height)) &&
+		int64(offset.Z)+int64(extent.Depth) >= int64(levelSize(info.Extent.Depth))
+}
+
+// blitBox returns the offset and extent of the box between the two corners
+// of a blit region, which may be given in any order.
+func blitBox(corners VkOffset3Dː2ᵃ) (VkOffset3D, VkExtent3D) {
+	a, b := corners.Elements[0], corners.Elements[1]
+	minMax := func(x, y int32) (int32, int32) {
+		if x < y {
+			return x, y
+		}
+		return y, x
+	}
+	x0, x1 := minMax(a.X, b.X)
+	y0, y1 := minMax(a.Y, b.Y)
+	z0, z1 := minMax(a.Z, b.Z)
+	return VkOffset3D{X: x0, Y: y0, Z: z0},
+		VkExtent3D{Width: uint32(x1 - x0), Height: uint32(y1 - y0), Depth: uint32(z1 - z0)}
+}
+
+// vkWholeSize is the VK_WHOLE_SIZE value of buffer ranges.
+const vkWholeSize = uint64(0xFFFFFFFFFFFFFFFF)
+
+// isDynamicDescriptorType returns true if descriptors of type ty take a
+// dynamic offset when their descriptor set is bound.
+func isDynamicDescriptorType(ty VkDescriptorType) bool {
+	return ty == VkDescriptorType_VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER_DYNAMIC ||
+		ty == VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER_DYNAMIC
+}
+
+// dynamicDescriptorCount returns the number of dynamic offsets consumed by the
+// descriptor set when it is bound, capped to max.
+func dynamicDescriptorCount(s *gfxapi.State, set VkDescriptorSet, max int) int {
+	if !GetState(s).DescriptorSets.Contains(set) {
+		return 0
+	}
+	count := 0
+	for _, binding := range GetState(s).DescriptorSets.Get(set).Bindings {
+		if isDynamicDescriptorType(binding.BindingType) {
+			count += len(binding.BufferBinding)
+		}
+	}
+	if count > max {
+		return max
+	}
+	return count
+}
+
+// isBindlessDescriptorSet returns true if the descriptors of the given
+// descriptor set are tracked individually in the dependency graph. That is
+// the case for sets allocated with an update-after-bind layout, or with
+// bindings that can be updated after bind or left partially bound.
+func isBindlessDescriptorSet(s *gfxapi.State, set VkDescriptorSet) bool {
+	if !config.PerDescriptorBindlessDCE || !GetState(s).DescriptorSets.Contains(set) {
+		return false
+	}
+	layout := GetState(s).DescriptorSets.Get(set).Layout
+	if layout == nil {
+		return false
+	}
+	if uint32(layout.Flags)&uint32(VkDescriptorSetLayoutCreateFlagBits_VK_DESCRIPTOR_SET_LAYOUT_CREATE_UPDATE_AFTER_BIND_POOL_BIT_EXT) != 0 {
+		return true
+	}
+	bindlessFlags := uint32(VkDescriptorBindingFlagBitsEXT_VK_DESCRIPTOR_BINDING_UPDATE_AFTER_BIND_BIT_EXT |
+		VkDescriptorBindingFlagBitsEXT_VK_DESCRIPTOR_BINDING_PARTIALLY_BOUND_BIT_EXT)
+	for _, binding := range layout.Bindings {
+		if uint32(binding.Flags)&bindlessFlags != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Dependency graph and the node type in the graph
 // TODO(qining): Move the dependency graph and other types, which are shared
 // with GLES, to another proper place.
@@ -166,6 +375,28 @@ type DependencyGraph struct {
 	addressMap     addressMapping        // Remap state keys to integers for performance.
 	deviceMemories map[VkDeviceMemory]*vulkanDeviceMemory
 	commandBuffers map[VkCommandBuffer]*vulkanCommandBuffer
+	descriptorSets map[VkDescriptorSet]*vulkanDescriptorSet
+	images         map[VkImage]*vulkanImage
+	// The addresses of the AHardwareBuffers that device memories were
+	// imported from or exported to.
+	hardwareBuffers map[VkDeviceMemory]uint64
+	// The names given by the application to the Vulkan objects, by handle.
+	debugNames map[uint64]string
+	// Notified while the graph is built, if not nil.
+	observer dependencyGraphObserver
+	// The descriptor bindings declared by the shader modules of the pipelines,
+	// nil for the modules which could not be reflected.
+	shaderBindings map[*ShaderModuleObject][]shadertools.DescriptorBinding
+}
+
+// dependencyGraphObserver is notified of the atoms and of the executions of
+// the recorded commands while a dependency graph is built.
+type dependencyGraphObserver interface {
+	// mutated is called once the atom id has been mutated on s.
+	mutated(ctx context.Context, s *gfxapi.State, id atom.ID, a atom.Atom)
+	// executed is called with the behaviours of the recorded command id, each
+	// time it is executed by a submission.
+	executed(id atom.ID, b *AtomBehaviour)
 }
 
 type AtomBehaviour struct {
@@ -185,21 +416,48 @@ type addressMapping struct {
 func (g *DependencyGraph) Print(ctx context.Context, b *AtomBehaviour) {
 	for _, read := range b.Read {
 		key := g.addressMap.key[read]
-		log.I(ctx, " - read [%v]%T%+v", read, key, key)
+		log.I(ctx, " - read [%v]%T%+v%s", read, key, key, g.debugNameOf(key))
 	}
 	for _, modify := range b.Modify {
 		key := g.addressMap.key[modify]
-		log.I(ctx, " - modify [%v]%T%+v", modify, key, key)
+		log.I(ctx, " - modify [%v]%T%+v%s", modify, key, key, g.debugNameOf(key))
 	}
 	for _, write := range b.Write {
 		key := g.addressMap.key[write]
-		log.I(ctx, " - write [%v]%T%+v", write, key, key)
+		log.I(ctx, " - write [%v]%T%+v%s", write, key, key, g.debugNameOf(key))
 	}
 	if b.Aborted {
 		log.I(ctx, " - aborted")
 	}
 }
 
+// debugNameOf returns the name given by the application to the Vulkan object
+// holding the state key, formatted for the debug output, or the empty string
+// if the object has not been named.
+func (g *DependencyGraph) debugNameOf(key stateKey) string {
+	for ; key != nil; key = key.Parent() {
+		var handle uint64
+		switch k := key.(type) {
+		case vulkanStateKey:
+			handle = uint64(k)
+		case *vulkanDeviceMemory:
+			handle = uint64(k.handle.vkDeviceMemory)
+		case *vulkanCommandBuffer:
+			handle = uint64(k.handle.vkCommandBuffer)
+		case *vulkanDescriptorSet:
+			handle = uint64(k.vkDescriptorSet)
+		case *vulkanImageSubresource:
+			handle = uint64(k.image.vkImage)
+		default:
+			continue
+		}
+		if name, ok := g.debugNames[handle]; ok && name != "" {
+			return fmt.Sprintf(" \"%s\"", name)
+		}
+	}
+	return ""
+}
+
 // For a given Vulkan handle of device memory, returns the corresponding
 // stateKey of the device memory if it has been created and added to the graph
 // before. Otherwise, creates and adds the stateKey for the handle and returns
@@ -226,6 +484,68 @@ func (g *DependencyGraph) getOrCreateCommandBuffer(handle VkCommandBuffer) *vulk
 	return newCb
 }
 
+// For a given Vulkan handle of descriptor set, returns the corresponding
+// stateKey of the descriptor set if it has been created and added to the graph
+// before. Otherwise, creates and adds the stateKey for the handle and returns
+// the new created stateKey
+func (g *DependencyGraph) getOrCreateDescriptorSet(handle VkDescriptorSet) *vulkanDescriptorSet {
+	if ds, ok := g.descriptorSets[handle]; ok {
+		return ds
+	}
+	newDs := newVulkanDescriptorSet(handle)
+	g.descriptorSets[handle] = newDs
+	return newDs
+}
+
+// descriptorSetKey returns the stateKey that represents the whole content of
+// the given descriptor set.
+func (g *DependencyGraph) descriptorSetKey(s *gfxapi.State, handle VkDescriptorSet) stateKey {
+	if isBindlessDescriptorSet(s, handle) {
+		return g.getOrCreateDescriptorSet(handle)
+	}
+	return vulkanStateKey(handle)
+}
+
+// usedBindings returns the bindings of the descriptor set number set that
+// are statically used by the shaders of the pipelines in the state s. As the
+// shaders may index the arrays of descriptors dynamically, every element of a
+// used binding is used. all is true if a shader could not be reflected, in
+// which case every binding has to be considered used.
+func (g *DependencyGraph) usedBindings(ctx context.Context, s *gfxapi.State, set uint32) (used map[uint32]bool, all bool) {
+	used = map[uint32]bool{}
+	addModule := func(m *ShaderModuleObject) {
+		if m == nil {
+			return
+		}
+		bindings, ok := g.shaderBindings[m]
+		if !ok {
+			var err error
+			if bindings, err = shadertools.DescriptorBindings(m.Words.Read(ctx, nil, s, nil)); err != nil {
+				log.W(ctx, "Failed to reflect shader module %v: %v", m.VulkanHandle, err)
+				bindings = nil
+			}
+			g.shaderBindings[m] = bindings
+		}
+		if bindings == nil {
+			all = true
+		}
+		for _, b := range bindings {
+			if b.Set == set && b.Used {
+				used[b.Binding] = true
+			}
+		}
+	}
+	for _, pipeline := range GetState(s).GraphicsPipelines {
+		for _, stage := range pipeline.Stages {
+			addModule(stage.Module)
+		}
+	}
+	for _, pipeline := range GetState(s).ComputePipelines {
+		addModule(pipeline.Stage.Module)
+	}
+	return used, all
+}
+
 // The public accessible entrance of building a dep graph from atom list
 func GetDependencyGraph(ctx context.Context) (*DependencyGraph, error) {
 	r, err := database.Build(ctx, &DependencyGraphResolvable{Capture: capture.Get(ctx)})
@@ -241,6 +561,12 @@ func (r *DependencyGraphResolvable) Resolve(ctx context.Context) (interface{}, e
 	if err != nil {
 		return nil, err
 	}
+	return buildDependencyGraph(ctx, c, nil)
+}
+
+// buildDependencyGraph builds the dependency graph of the atoms of the
+// capture c, notifying o if it is not nil.
+func buildDependencyGraph(ctx context.Context, c *capture.Capture, o dependencyGraphObserver) (*DependencyGraph, error) {
 	atoms, err := c.Atoms(ctx)
 	if err != nil {
 		return nil, err
@@ -257,13 +583,24 @@ func (r *DependencyGraphResolvable) Resolve(ctx context.Context) (interface{}, e
 		},
 		deviceMemories: map[VkDeviceMemory]*vulkanDeviceMemory{},
 		commandBuffers: map[VkCommandBuffer]*vulkanCommandBuffer{},
+		descriptorSets: map[VkDescriptorSet]*vulkanDescriptorSet{},
+		images:         map[VkImage]*vulkanImage{},
+
+		hardwareBuffers: map[VkDeviceMemory]uint64{},
+		observer:        o,
+		shaderBindings:  map[*ShaderModuleObject][]shadertools.DescriptorBinding{},
 	}
 
 	s := c.NewState()
 	t0 := dependencyGraphBuildCounter.Start()
 	for i, a := range g.atoms {
 		g.behaviours[i] = g.getBehaviour(ctx, s, atom.ID(i), a)
+		if o != nil {
+			o.mutated(ctx, s, atom.ID(i), a)
+		}
 	}
+	g.debugNames = GetState(s).DebugObjectNames
+	g.shaderBindings = nil // Keeps the shader modules alive otherwise.
 	dependencyGraphBuildCounter.Stop(t0)
 	return g, nil
 }
@@ -282,6 +619,22 @@ func (m *addressMapping) addressOf(state stateKey) StateAddress {
 	return address
 }
 
+// execute carries out the behaviours of the recorded command c on b. The
+// observer of the graph, if any, is notified with the behaviours of c alone.
+func (g *DependencyGraph) execute(b *AtomBehaviour, c recordedCommand) {
+	if g.observer == nil {
+		c.behave(b)
+		return
+	}
+	e := AtomBehaviour{}
+	c.behave(&e)
+	g.observer.executed(c.id, &e)
+	b.Read = append(b.Read, e.Read...)
+	b.Modify = append(b.Modify, e.Modify...)
+	b.Write = append(b.Write, e.Write...)
+	b.KeepAlive = b.KeepAlive || e.KeepAlive
+}
+
 func (b *AtomBehaviour) read(g *DependencyGraph, state stateKey) {
 	if state != nil {
 		b.Read = append(b.Read, g.addressMap.addressOf(state))
@@ -370,11 +723,27 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 		}
 	}
 
-	// Helper function that reads the given image handle, and returns the memory
-	// bindings of the image
-	readImageHandleAndGetBindings := func(b *AtomBehaviour, image VkImage) []*vulkanDeviceMemoryBinding {
-		b.read(g, vulkanStateKey(image))
-		return getOverlappedBindingsForImage(image)
+	// Helper function that gets the overlapped memory bindings for the range of
+	// size bytes at offset in the given buffer. A size of VK_WHOLE_SIZE extends
+	// the range to the end of the buffer.
+	getOverlappedBindingsForBufferRange := func(buffer VkBuffer, offset, size uint64) []*vulkanDeviceMemoryBinding {
+		if !GetState(s).Buffers.Contains(buffer) {
+			log.E(ctx, "Error Buffer: %v: does not exist in state", buffer)
+			return []*vulkanDeviceMemoryBinding{}
+		}
+		bufferObj := GetState(s).Buffers.Get(buffer)
+		if bufferObj.Memory == nil {
+			log.E(ctx, "Error Buffer: %v: Cannot get the bound memory for a buffer which has not been bound yet", buffer)
+			return []*vulkanDeviceMemoryBinding{}
+		}
+		if bufferSize := uint64(bufferObj.Info.Size); size == vkWholeSize || offset+size > bufferSize {
+			if offset >= bufferSize {
+				return []*vulkanDeviceMemoryBinding{}
+			}
+			size = bufferSize - offset
+		}
+		boundMemory := bufferObj.Memory.VulkanHandle
+		return getOverlappingMemoryBindings(boundMemory, uint64(bufferObj.MemoryOffset)+offset, size)
 	}
 
 	// Helper function that reads the given buffer handle, and returns the memory
@@ -425,7 +794,7 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			currentBehaviour.modify(g, cmdBuf.records)
 		}
 
-		cmdBuf.records.appendCommand(c)
+		cmdBuf.records.appendCommand(id, c)
 	}
 
 	// Helper function that adds 'read' to the given command buffer handle and
@@ -447,13 +816,194 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			currentBehaviour.modify(g, cmdBuf.records)
 		}
 
-		cmdBuf.records.appendCommand(func(b *AtomBehaviour) {
+		cmdBuf.records.appendCommand(id, func(b *AtomBehaviour) {
 			readMemoryBindingsData(b, readBindings)
 			modifyMemoryBindingsData(b, modifyBindings)
 			writeMemoryBindingsData(b, writeBindings)
 		})
 	}
 
+	// Helper function that returns the stateKeys of the given range of image
+	// subresources, and the memory bindings of other resources that alias the
+	// image's memory. If the subresources of the image are not tracked
+	// individually, no stateKeys are returned and all the memory bindings
+	// overlapping with the image are returned instead.
+	getImageSubresources := func(r imageSubresourceRange) ([]stateKey, []*vulkanDeviceMemoryBinding) {
+		img, ok := g.images[r.image]
+		if !ok || !GetState(s).Images.Contains(r.image) {
+			return []stateKey{}, getOverlappedBindingsForImage(r.image)
+		}
+		info := GetState(s).Images.Get(r.image).Info
+		levelCount, layerCount := r.levelCount, r.layerCount
+		if levelCount == vkRemaining {
+			levelCount = info.MipLevels - r.baseLevel
+		}
+		if layerCount == vkRemaining {
+			layerCount = info.ArrayLayers - r.baseLayer
+		}
+		keys := []stateKey{}
+		mask := uint32(r.aspectMask)
+		for aspect := uint32(1); aspect != 0 && aspect <= mask; aspect <<= 1 {
+			if mask&aspect == 0 {
+				continue
+			}
+			for layer := r.baseLayer; layer < r.baseLayer+layerCount; layer++ {
+				for level := r.baseLevel; level < r.baseLevel+levelCount; level++ {
+					keys = append(keys, img.subresource(aspect, layer, level))
+				}
+			}
+		}
+		aliases := []*vulkanDeviceMemoryBinding{}
+		for _, binding := range getOverlappedBindingsForImage(r.image) {
+			if binding.data != img.data {
+				aliases = append(aliases, binding)
+			}
+		}
+		return keys, aliases
+	}
+
+	// Helper function that works like recordTouchingMemoryBindingsData, but
+	// for ranges of image subresources. The memory bindings of other
+	// resources aliasing the images are conservatively read or modified. For
+	// images whose subresources are not tracked individually, the written
+	// ranges are labelled as 'modify' to keep the previous writes.
+	recordTouchingImageSubresources := func(currentBehaviour *AtomBehaviour,
+		handle VkCommandBuffer,
+		readRanges, modifyRanges, writeRanges []imageSubresourceRange) {
+		read, modify, write := []stateKey{}, []stateKey{}, []stateKey{}
+		readAliases, modifyAliases := []*vulkanDeviceMemoryBinding{}, []*vulkanDeviceMemoryBinding{}
+		for _, r := range readRanges {
+			keys, aliases := getImageSubresources(r)
+			read = append(read, keys...)
+			readAliases = append(readAliases, aliases...)
+		}
+		for _, r := range modifyRanges {
+			keys, aliases := getImageSubresources(r)
+			modify = append(modify, keys...)
+			modifyAliases = append(modifyAliases, aliases...)
+		}
+		for _, r := range writeRanges {
+			keys, aliases := getImageSubresources(r)
+			write = append(write, keys...)
+			modifyAliases = append(modifyAliases, aliases...)
+		}
+		recordCommand(currentBehaviour, handle, func(b *AtomBehaviour) {
+			for _, k := range read {
+				addRead(b, g, k)
+			}
+			for _, k := range modify {
+				addModify(b, g, k)
+			}
+			for _, k := range write {
+				addWrite(b, g, k)
+			}
+			readMemoryBindingsData(b, readAliases)
+			modifyMemoryBindingsData(b, modifyAliases)
+		})
+	}
+
+	// Helper function that returns whether a copy to the given subresources
+	// of an image at the given offset and extent overwrites the whole of the
+	// subresources.
+	overwritesImageLevel := func(image VkImage, l VkImageSubresourceLayers, offset VkOffset3D, extent VkExtent3D) bool {
+		if !GetState(s).Images.Contains(image) {
+			return false
+		}
+		return coversImageLevel(GetState(s).Images.Get(image).Info, l.MipLevel, offset, extent)
+	}
+
+	// Helper function that adds the behaviours of copying from the source
+	// subresources to the destination subresources of images, where
+	// each destination range is either fully overwritten or only modified.
+	recordImageCopy := func(currentBehaviour *AtomBehaviour, handle VkCommandBuffer,
+		src []imageSubresourceRange, dst []imageSubresourceRange, dstOverwritten []bool) {
+		modifyRanges, writeRanges := []imageSubresourceRange{}, []imageSubresourceRange{}
+		for i, r := range dst {
+			if dstOverwritten[i] {
+				writeRanges = append(writeRanges, r)
+			} else {
+				modifyRanges = append(modifyRanges, r)
+			}
+		}
+		recordTouchingImageSubresources(currentBehaviour, handle, src, modifyRanges, writeRanges)
+	}
+
+	// Helper function that adds the behaviours of the image memory barriers.
+	// A layout transition from VK_IMAGE_LAYOUT_UNDEFINED discards the contents
+	// of the subresources, other layout transitions modify them.
+	recordImageMemoryBarriers := func(currentBehaviour *AtomBehaviour, handle VkCommandBuffer,
+		barriers VkImageMemoryBarrierˢ) {
+		modifyRanges, writeRanges := []imageSubresourceRange{}, []imageSubresourceRange{}
+		for i := uint64(0); i < barriers.Info().Count; i++ {
+			barrier := barriers.Index(i, s).Read(ctx, a, s, nil)
+			addRead(currentBehaviour, g, vulkanStateKey(barrier.Image))
+			if barrier.OldLayout == barrier.NewLayout {
+				continue
+			}
+			r := subresourceRangeFromRange(barrier.Image, barrier.SubresourceRange)
+			if barrier.OldLayout == VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED {
+				writeRanges = append(writeRanges, r)
+			} else {
+				modifyRanges = append(modifyRanges, r)
+			}
+		}
+		recordTouchingImageSubresources(currentBehaviour, handle, nil, modifyRanges, writeRanges)
+	}
+
+	// Helper function that reads the descriptors of a descriptor set whose
+	// descriptors are tracked individually, bound as the set number index. It
+	// is expected to be called when the command buffer is submitted, as the
+	// descriptors may have been updated after the set was bound. Descriptors
+	// which have never been written, as allowed for partially bound bindings,
+	// and the bindings that no shader uses are skipped. The pipelines used by
+	// the command buffer cannot be destroyed while it is pending, so the
+	// shaders of the pipelines in the state at submission include them.
+	readBindlessDescriptorSet := func(b *AtomBehaviour, set VkDescriptorSet, index uint32) {
+		if !GetState(s).DescriptorSets.Contains(set) {
+			return
+		}
+		descriptors := g.getOrCreateDescriptorSet(set)
+		used, all := g.usedBindings(ctx, s, index)
+		for binding, descBinding := range GetState(s).DescriptorSets.Get(set).Bindings {
+			if !all && !used[binding] {
+				continue
+			}
+			for element, bufferInfo := range descBinding.BufferBinding {
+				if bufferInfo == nil || bufferInfo.Buffer == VkBuffer(0) {
+					continue
+				}
+				buf := bufferInfo.Buffer
+				addRead(b, g, descriptors.descriptor(binding, element))
+				// Descriptors might be modified
+				addModify(b, g, vulkanStateKey(buf))
+				modifyMemoryBindingsData(b, getOverlappedBindingsForBuffer(buf))
+			}
+			for element, imageInfo := range descBinding.ImageBinding {
+				if imageInfo == nil || (imageInfo.ImageView == VkImageView(0) && imageInfo.Sampler == VkSampler(0)) {
+					continue
+				}
+				view := imageInfo.ImageView
+				addRead(b, g, descriptors.descriptor(binding, element))
+				addRead(b, g, vulkanStateKey(view))
+				if GetState(s).ImageViews.Contains(view) {
+					img := GetState(s).ImageViews.Get(view).Image.VulkanHandle
+					readMemoryBindingsData(b, getOverlappedBindingsForImage(img))
+				}
+			}
+			for element, bufferView := range descBinding.BufferViewBindings {
+				if bufferView == VkBufferView(0) {
+					continue
+				}
+				addRead(b, g, descriptors.descriptor(binding, element))
+				addRead(b, g, vulkanStateKey(bufferView))
+				if GetState(s).BufferViews.Contains(bufferView) {
+					buf := GetState(s).BufferViews.Get(bufferView).Buffer.VulkanHandle
+					readMemoryBindingsData(b, getOverlappedBindingsForBuffer(buf))
+				}
+			}
+		}
+	}
+
 	// Mutate the state with the atom.
 	if err := a.Mutate(ctx, s, nil); err != nil {
 		log.E(ctx, "Atom %v %v: %v", id, a, err)
@@ -523,6 +1073,10 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 					if uint64(buffer) != 0 {
 						addRead(&b, g, vulkanStateKey(buffer))
 					}
+				case VkStructureType_VK_STRUCTURE_TYPE_IMPORT_ANDROID_HARDWARE_BUFFER_INFO_ANDROID:
+					// The content of the memory is shared with the hardware buffer.
+					ext := VkImportAndroidHardwareBufferInfoANDROIDᵖ(pNext).Read(ctx, a, s, nil)
+					g.hardwareBuffers[memory] = ext.Buffer.Address
 				}
 				pNext = (VulkanStructHeaderᵖ(pNext)).Read(ctx, a, s, nil).PNext
 			}
@@ -549,11 +1103,26 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 					if uint64(buffer) != 0 {
 						addRead(&b, g, vulkanStateKey(buffer))
 					}
+				case VkStructureType_VK_STRUCTURE_TYPE_IMPORT_ANDROID_HARDWARE_BUFFER_INFO_ANDROID:
+					// The content of the memory is shared with the hardware buffer.
+					ext := VkImportAndroidHardwareBufferInfoANDROIDᵖ(pNext).Read(ctx, a, s, nil)
+					g.hardwareBuffers[memory] = ext.Buffer.Address
 				}
 				pNext = (VulkanStructHeaderᵖ(pNext)).Read(ctx, a, s, nil).PNext
 			}
 		}
 
+	case *VkGetMemoryAndroidHardwareBufferANDROID:
+		// The hardware buffer shares the content of the memory, which other
+		// graphics APIs may use through the buffer.
+		info := a.PInfo.Read(ctx, a, s, nil)
+		buffer := a.PBuffer.Read(ctx, a, s, nil)
+		addRead(&b, g, g.getOrCreateDeviceMemory(info.Memory).handle)
+		g.hardwareBuffers[info.Memory] = buffer.Address
+
+	case *VkGetAndroidHardwareBufferPropertiesANDROID:
+		// Only queries the properties of the buffer, no state is accessed.
+
 	case *VkBindImageMemory:
 		image := a.Image
 		memory := a.Memory
@@ -573,7 +1142,11 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			// TODO(qining) Fix this
 			size := uint64(GetState(s).Images.Get(image).Size)
 			binding := g.getOrCreateDeviceMemory(memory).addBinding(offset, size)
+			binding.image = image
 			addWrite(&b, g, binding)
+			if config.PerSubresourceImageDCE {
+				g.images[image] = newVulkanImage(image, binding.data)
+			}
 		}
 
 	case *VkBindBufferMemory:
@@ -585,6 +1158,7 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			offset := uint64(GetState(s).Buffers.Get(buffer).MemoryOffset)
 			size := uint64(GetState(s).Buffers.Get(buffer).Info.Size)
 			binding := g.getOrCreateDeviceMemory(memory).addBinding(offset, size)
+			binding.buffer = buffer
 			addWrite(&b, g, binding)
 		}
 
@@ -597,7 +1171,11 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			offset := uint64(GetState(s).Images.Get(image).BoundMemoryOffset)
 			size := uint64(GetState(s).Images.Get(image).Size)
 			binding := g.getOrCreateDeviceMemory(memory).addBinding(offset, size)
+			binding.image = image
 			addWrite(&b, g, binding)
+			if config.PerSubresourceImageDCE {
+				g.images[image] = newVulkanImage(image, binding.data)
+			}
 		}
 
 	case *RecreateBindBufferMemory:
@@ -609,6 +1187,7 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			offset := uint64(GetState(s).Buffers.Get(buffer).MemoryOffset)
 			size := uint64(GetState(s).Buffers.Get(buffer).Info.Size)
 			binding := g.getOrCreateDeviceMemory(memory).addBinding(offset, size)
+			binding.buffer = buffer
 			addWrite(&b, g, binding)
 		}
 
@@ -738,8 +1317,8 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 				copy := copies.Index(uint64(i), s).Read(ctx, a, s, nil)
 				srcDescriptor := copy.SrcSet
 				dstDescriptor := copy.DstSet
-				addRead(&b, g, vulkanStateKey(srcDescriptor))
-				addModify(&b, g, vulkanStateKey(dstDescriptor))
+				addRead(&b, g, g.descriptorSetKey(s, srcDescriptor))
+				addModify(&b, g, g.descriptorSetKey(s, dstDescriptor))
 			}
 		}
 
@@ -843,66 +1422,92 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 		addWrite(&b, g, vulkanStateKey(a.PShaderModule.Read(ctx, a, s, nil)))
 
 	case *VkCmdCopyImage:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
-		// TODO(qining): Track all the memory ranges
-		recordTouchingMemoryBindingsData(&b, a.CommandBuffer, srcBindings,
-			dstBindings, emptyMemoryBindings)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
+		addRead(&b, g, vulkanStateKey(a.DstImage))
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src, dst, overwritten := []imageSubresourceRange{}, []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.SrcSubresource))
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.DstSubresource))
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.DstSubresource, region.DstOffset, region.Extent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, src, dst, overwritten)
 
 	case *RecreateCmdCopyImage:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
-		// TODO(qining): Track all the memory ranges
-		recordTouchingMemoryBindingsData(&b, a.CommandBuffer, srcBindings,
-			dstBindings, emptyMemoryBindings)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
+		addRead(&b, g, vulkanStateKey(a.DstImage))
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src, dst, overwritten := []imageSubresourceRange{}, []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.SrcSubresource))
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.DstSubresource))
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.DstSubresource, region.DstOffset, region.Extent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, src, dst, overwritten)
 
 	case *VkCmdCopyImageToBuffer:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
 		dstBindings := readBufferHandleAndGetBindings(&b, a.DstBuffer)
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src := []imageSubresourceRange{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.ImageSubresource))
+		}
+		recordTouchingImageSubresources(&b, a.CommandBuffer, src, nil, nil)
 		// Be conservative here. Without tracking all the memory ranges and
 		// calculating the memory according to the copy region, we cannot assume
 		// this command overwrites the data. So it is labelled as 'modify' to
 		// kept the previous writes
 		recordTouchingMemoryBindingsData(&b, a.CommandBuffer,
-			srcBindings, dstBindings, emptyMemoryBindings)
+			emptyMemoryBindings, dstBindings, emptyMemoryBindings)
 
 	case *RecreateCmdCopyImageToBuffer:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
 		dstBindings := readBufferHandleAndGetBindings(&b, a.DstBuffer)
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src := []imageSubresourceRange{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.ImageSubresource))
+		}
+		recordTouchingImageSubresources(&b, a.CommandBuffer, src, nil, nil)
 		// Be conservative here. Without tracking all the memory ranges and
 		// calculating the memory according to the copy region, we cannot assume
 		// this command overwrites the data. So it is labelled as 'modify' to
 		// kept the previous writes
 		recordTouchingMemoryBindingsData(&b, a.CommandBuffer,
-			srcBindings, dstBindings, emptyMemoryBindings)
+			emptyMemoryBindings, dstBindings, emptyMemoryBindings)
 
 	case *VkCmdCopyBufferToImage:
 		srcBindings := readBufferHandleAndGetBindings(&b, a.SrcBuffer)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
+		addRead(&b, g, vulkanStateKey(a.DstImage))
 		recordTouchingMemoryBindingsData(&b, a.CommandBuffer,
-			srcBindings, dstBindings, emptyMemoryBindings)
+			srcBindings, emptyMemoryBindings, emptyMemoryBindings)
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		dst, overwritten := []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.ImageSubresource))
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.ImageSubresource, region.ImageOffset, region.ImageExtent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, nil, dst, overwritten)
 
 	case *RecreateCmdCopyBufferToImage:
 		srcBindings := readBufferHandleAndGetBindings(&b, a.SrcBuffer)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
+		addRead(&b, g, vulkanStateKey(a.DstImage))
 		recordTouchingMemoryBindingsData(&b, a.CommandBuffer,
-			srcBindings, dstBindings, emptyMemoryBindings)
+			srcBindings, emptyMemoryBindings, emptyMemoryBindings)
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		dst, overwritten := []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.ImageSubresource))
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.ImageSubresource, region.ImageOffset, region.ImageExtent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, nil, dst, overwritten)
 
 	case *VkCmdCopyBuffer:
 		srcBindings := readBufferHandleAndGetBindings(&b, a.SrcBuffer)
@@ -925,48 +1530,58 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			srcBindings, dstBindings, emptyMemoryBindings)
 
 	case *VkCmdBlitImage:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
-		// TODO(qining): Track all the memory ranges
-		recordTouchingMemoryBindingsData(&b, a.CommandBuffer, srcBindings,
-			dstBindings, emptyMemoryBindings)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
+		addRead(&b, g, vulkanStateKey(a.DstImage))
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src, dst, overwritten := []imageSubresourceRange{}, []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.SrcSubresource))
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.DstSubresource))
+			offset, extent := blitBox(region.DstOffsets)
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.DstSubresource, offset, extent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, src, dst, overwritten)
 
 	case *RecreateCmdBlitImage:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
-		// TODO(qining): Track all the memory ranges
-		recordTouchingMemoryBindingsData(&b, a.CommandBuffer, srcBindings,
-			dstBindings, emptyMemoryBindings)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
+		addRead(&b, g, vulkanStateKey(a.DstImage))
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src, dst, overwritten := []imageSubresourceRange{}, []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.SrcSubresource))
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.DstSubresource))
+			offset, extent := blitBox(region.DstOffsets)
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.DstSubresource, offset, extent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, src, dst, overwritten)
 
 	case *VkCmdResolveImage:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
-		// TODO(qining): Track all the memory ranges
-		recordTouchingMemoryBindingsData(&b, a.CommandBuffer, srcBindings,
-			dstBindings, emptyMemoryBindings)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
+		addRead(&b, g, vulkanStateKey(a.DstImage))
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src, dst, overwritten := []imageSubresourceRange{}, []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.SrcSubresource))
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.DstSubresource))
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.DstSubresource, region.DstOffset, region.Extent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, src, dst, overwritten)
 
 	case *RecreateCmdResolveImage:
-		srcBindings := readImageHandleAndGetBindings(&b, a.SrcImage)
-		dstBindings := readImageHandleAndGetBindings(&b, a.DstImage)
-		// Be conservative here. Without tracking all the memory ranges and
-		// calculating the memory according to the copy region, we cannot assume
-		// this command overwrites the data. So it is labelled as 'modify' to
-		// kept the previous writes
-		// TODO(qining): Track all the memory ranges
-		recordTouchingMemoryBindingsData(&b, a.CommandBuffer, srcBindings,
-			dstBindings, emptyMemoryBindings)
+		addRead(&b, g, vulkanStateKey(a.SrcImage))
+		addRead(&b, g, vulkanStateKey(a.DstImage))
+		regions := a.PRegions.Slice(0, uint64(a.RegionCount), s)
+		src, dst, overwritten := []imageSubresourceRange{}, []imageSubresourceRange{}, []bool{}
+		for i := uint64(0); i < regions.Info().Count; i++ {
+			region := regions.Index(i, s).Read(ctx, a, s, nil)
+			src = append(src, subresourceRangeFromLayers(a.SrcImage, region.SrcSubresource))
+			dst = append(dst, subresourceRangeFromLayers(a.DstImage, region.DstSubresource))
+			overwritten = append(overwritten, overwritesImageLevel(a.DstImage, region.DstSubresource, region.DstOffset, region.Extent))
+		}
+		recordImageCopy(&b, a.CommandBuffer, src, dst, overwritten)
 
 	case *VkCmdFillBuffer:
 		dstBindings := readBufferHandleAndGetBindings(&b, a.DstBuffer)
@@ -1266,21 +1881,45 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 	case *VkCmdBindDescriptorSets:
 		descriptorSetCount := a.DescriptorSetCount
 		descriptorSets := a.PDescriptorSets.Slice(0, uint64(descriptorSetCount), s)
+		// The dynamic offsets apply to the dynamic uniform and storage buffer
+		// descriptors of the sets, in set, binding and array element order.
+		dynamicOffsets := a.PDynamicOffsets.Slice(0, uint64(a.DynamicOffsetCount), s).Read(ctx, a, s, nil)
 		for i := uint32(0); i < descriptorSetCount; i++ {
 			descriptorSet := descriptorSets.Index(uint64(i), s).Read(ctx, a, s, nil)
 			addRead(&b, g, vulkanStateKey(descriptorSet))
+			if isBindlessDescriptorSet(s, descriptorSet) {
+				index := a.FirstSet + i
+				recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {
+					readBindlessDescriptorSet(b, descriptorSet, index)
+				})
+				dynamicOffsets = dynamicOffsets[dynamicDescriptorCount(s, descriptorSet, len(dynamicOffsets)):]
+				continue
+			}
 			if GetState(s).DescriptorSets.Contains(descriptorSet) {
-				for _, descBinding := range GetState(s).DescriptorSets.Get(descriptorSet).Bindings {
-					for _, bufferInfo := range descBinding.BufferBinding {
+				set := GetState(s).DescriptorSets.Get(descriptorSet)
+				for _, binding := range set.Bindings.KeysSorted() {
+					descBinding := set.Bindings[binding]
+					dynamic := isDynamicDescriptorType(descBinding.BindingType)
+					for _, element := range descBinding.BufferBinding.KeysSorted() {
+						dynamicOffset := uint64(0)
+						if dynamic && len(dynamicOffsets) > 0 {
+							dynamicOffset, dynamicOffsets = uint64(dynamicOffsets[0]), dynamicOffsets[1:]
+						}
+						bufferInfo := descBinding.BufferBinding[element]
+						if bufferInfo == nil {
+							continue
+						}
 						buf := bufferInfo.Buffer
+						offset, size := uint64(bufferInfo.Offset)+dynamicOffset, uint64(bufferInfo.Range)
 
 						recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {
 							// Descriptors might be modified
 							addModify(b, g, vulkanStateKey(buf))
 							// Advance the read/modify behavior of the descriptors from
 							// draw and dispatch calls to here. Details in the handling
-							// of vkCmdDispatch and vkCmdDraw.
-							modifyMemoryBindingsData(b, getOverlappedBindingsForBuffer(buf))
+							// of vkCmdDispatch and vkCmdDraw. Only the range of the
+							// buffer bound to the descriptor is accessed.
+							modifyMemoryBindingsData(b, getOverlappedBindingsForBufferRange(buf, offset, size))
 						})
 					}
 					for _, imageInfo := range descBinding.ImageBinding {
@@ -1320,6 +1959,13 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 		for i := uint32(0); i < descriptorSetCount; i++ {
 			descriptorSet := descriptorSets.Index(uint64(i), s).Read(ctx, a, s, nil)
 			addRead(&b, g, vulkanStateKey(descriptorSet))
+			if isBindlessDescriptorSet(s, descriptorSet) {
+				index := a.FirstSet + i
+				recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {
+					readBindlessDescriptorSet(b, descriptorSet, index)
+				})
+				continue
+			}
 			if GetState(s).DescriptorSets.Contains(descriptorSet) {
 				for _, descBinding := range GetState(s).DescriptorSets.Get(descriptorSet).Bindings {
 					for _, bufferInfo := range descBinding.BufferBinding {
@@ -1365,12 +2011,14 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 		addModify(&b, g, cmdbuf)
 
 	case *VkCmdPipelineBarrier:
-		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
-		//TODO: handle the image and buffer memory barriers?
+		recordImageMemoryBarriers(&b, a.CommandBuffer,
+			a.PImageMemoryBarriers.Slice(0, uint64(a.ImageMemoryBarrierCount), s))
+		//TODO: handle the buffer memory barriers?
 
 	case *RecreateCmdPipelineBarrier:
-		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
-		//TODO: handle the image and buffer memory barriers?
+		recordImageMemoryBarriers(&b, a.CommandBuffer,
+			a.PImageMemoryBarriers.Slice(0, uint64(a.ImageMemoryBarrierCount), s))
+		//TODO: handle the buffer memory barriers?
 
 	case *VkCmdBindPipeline:
 		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {
@@ -1410,20 +2058,40 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 		//TODO: handle the case that the attachment is fully cleared.
 
 	case *VkCmdClearColorImage:
-		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
-		//TODO: handle the color image
+		addRead(&b, g, vulkanStateKey(a.Image))
+		ranges := a.PRanges.Slice(0, uint64(a.RangeCount), s)
+		cleared := []imageSubresourceRange{}
+		for i := uint64(0); i < ranges.Info().Count; i++ {
+			cleared = append(cleared, subresourceRangeFromRange(a.Image, ranges.Index(i, s).Read(ctx, a, s, nil)))
+		}
+		recordTouchingImageSubresources(&b, a.CommandBuffer, nil, nil, cleared)
 
 	case *RecreateCmdClearColorImage:
-		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
-		//TODO: handle the color image
+		addRead(&b, g, vulkanStateKey(a.Image))
+		ranges := a.PRanges.Slice(0, uint64(a.RangeCount), s)
+		cleared := []imageSubresourceRange{}
+		for i := uint64(0); i < ranges.Info().Count; i++ {
+			cleared = append(cleared, subresourceRangeFromRange(a.Image, ranges.Index(i, s).Read(ctx, a, s, nil)))
+		}
+		recordTouchingImageSubresources(&b, a.CommandBuffer, nil, nil, cleared)
 
 	case *VkCmdClearDepthStencilImage:
-		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
-		//TODO: handle the depth/stencil image
+		addRead(&b, g, vulkanStateKey(a.Image))
+		ranges := a.PRanges.Slice(0, uint64(a.RangeCount), s)
+		cleared := []imageSubresourceRange{}
+		for i := uint64(0); i < ranges.Info().Count; i++ {
+			cleared = append(cleared, subresourceRangeFromRange(a.Image, ranges.Index(i, s).Read(ctx, a, s, nil)))
+		}
+		recordTouchingImageSubresources(&b, a.CommandBuffer, nil, nil, cleared)
 
 	case *RecreateCmdClearDepthStencilImage:
-		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
-		//TODO: handle the depth/stencil image
+		addRead(&b, g, vulkanStateKey(a.Image))
+		ranges := a.PRanges.Slice(0, uint64(a.RangeCount), s)
+		cleared := []imageSubresourceRange{}
+		for i := uint64(0); i < ranges.Info().Count; i++ {
+			cleared = append(cleared, subresourceRangeFromRange(a.Image, ranges.Index(i, s).Read(ctx, a, s, nil)))
+		}
+		recordTouchingImageSubresources(&b, a.CommandBuffer, nil, nil, cleared)
 
 	case *VkCmdSetDepthBias:
 		recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {})
@@ -1445,7 +2113,7 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			addRead(&b, g, scb)
 			recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {
 				for _, c := range scb.records.Commands {
-					c(b)
+					g.execute(b, c)
 				}
 			})
 		}
@@ -1458,7 +2126,7 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 			addRead(&b, g, scb)
 			recordCommand(&b, a.CommandBuffer, func(b *AtomBehaviour) {
 				for _, c := range scb.records.Commands {
-					c(b)
+					g.execute(b, c)
 				}
 			})
 		}
@@ -1485,7 +2153,7 @@ func (g *DependencyGraph) getBehaviour(ctx context.Context, s *gfxapi.State, id
 
 				// Carry out the behaviors in the recorded commands.
 				for _, c := range cb.records.Commands {
-					c(&b)
+					g.execute(&b, c)
 				}
 			}
 		}
@@ -1512,7 +2180,16 @@ func processDescriptorWrites(writes VkWriteDescriptorSetˢ, b *AtomBehaviour, g
 		write := writes.Index(uint64(i), s).Read(ctx, a, s, nil)
 		if write.DescriptorCount > 0 {
 			// handle the target descriptor set
-			b.modify(g, vulkanStateKey(write.DstSet))
+			if isBindlessDescriptorSet(s, write.DstSet) {
+				// Only the written descriptors are overwritten, so that updates to
+				// other descriptors of the same set do not depend on this one.
+				set := g.getOrCreateDescriptorSet(write.DstSet)
+				for j := uint32(0); j < write.DescriptorCount; j++ {
+					b.write(g, set.descriptor(write.DstBinding, write.DstArrayElement+j))
+				}
+			} else {
+				b.modify(g, vulkanStateKey(write.DstSet))
+			}
 			switch write.DescriptorType {
 			case VkDescriptorType_VK_DESCRIPTOR_TYPE_SAMPLER,
 				VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER,
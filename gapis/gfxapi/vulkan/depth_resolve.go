@@ -0,0 +1,549 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// depthResolveVertexShader covers the viewport with a single triangle.
+const depthResolveVertexShader = `
+#version 450
+void main() {
+  vec2 position = vec2((gl_VertexIndex << 1) & 2, gl_VertexIndex & 2);
+  gl_Position = vec4(position * 2.0 - 1.0, 0.0, 1.0);
+}
+`
+
+// depthResolveFragmentShader writes the depth of the first sample of the
+// texel of the multisampled depth image.
+const depthResolveFragmentShader = `
+#version 450
+layout(set = 0, binding = 0) uniform sampler2DMS depth;
+void main() {
+  gl_FragDepth = texelFetch(depth, ivec2(gl_FragCoord.xy), 0).r;
+}
+`
+
+// allocData allocates data which stays valid until the atoms reading it are
+// written.
+type allocData func(ctx context.Context, s *gfxapi.State, v ...interface{}) atom.AllocResult
+
+// depthResolve holds the objects of a draw copying the first sample of each
+// texel of a multisampled depth image to a single-sampled depth image. Unlike
+// color aspects, depth aspects cannot be resolved with vkCmdResolveImage.
+//
+// The source image must be in the DEPTH_STENCIL_READ_ONLY_OPTIMAL layout when
+// the draw runs, and the destination image ends in the TRANSFER_SRC_OPTIMAL
+// layout. The stencil aspect of the destination image is left undefined.
+type depthResolve struct {
+	device              VkDevice
+	src                 *ImageObject
+	dst                 VkImage
+	width, height       uint32
+	vertexShaderWords   []uint32
+	fragmentShaderWords []uint32
+	srcView             VkImageView
+	dstView             VkImageView
+	sampler             VkSampler
+	setLayout           VkDescriptorSetLayout
+	pool                VkDescriptorPool
+	set                 VkDescriptorSet
+	pipelineLayout      VkPipelineLayout
+	vertexShader        VkShaderModule
+	fragmentShader      VkShaderModule
+	renderPass          VkRenderPass
+	framebuffer         VkFramebuffer
+	pipeline            VkPipeline
+}
+
+// depthStencilAspects returns the aspects of the depth and stencil format.
+func depthStencilAspects(format VkFormat) VkImageAspectFlags {
+	aspects := VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_DEPTH_BIT)
+	if _, err := getStencilImageFormatFromVulkanFormat(format); err == nil {
+		aspects |= VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_STENCIL_BIT)
+	}
+	return aspects
+}
+
+// newDepthResolve compiles the shaders and picks the handles of the draw
+// resolving the width by height multisampled src image to the dst image.
+func newDepthResolve(s *gfxapi.State, device VkDevice, src *ImageObject, dst VkImage, width, height uint32) (*depthResolve, error) {
+	vs, err := shadertools.CompileGlsl(depthResolveVertexShader, shadertools.StageVertex)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := shadertools.CompileGlsl(depthResolveFragmentShader, shadertools.StageFragment)
+	if err != nil {
+		return nil, err
+	}
+
+	st := GetState(s)
+	r := &depthResolve{
+		device:              device,
+		src:                 src,
+		dst:                 dst,
+		width:               width,
+		height:              height,
+		vertexShaderWords:   vs,
+		fragmentShaderWords: fs,
+	}
+	r.srcView = VkImageView(newUnusedID(false, func(x uint64) bool { _, ok := st.ImageViews[VkImageView(x)]; return ok }))
+	r.dstView = VkImageView(newUnusedID(false, func(x uint64) bool {
+		_, ok := st.ImageViews[VkImageView(x)]
+		return ok || VkImageView(x) == r.srcView
+	}))
+	r.sampler = VkSampler(newUnusedID(false, func(x uint64) bool { _, ok := st.Samplers[VkSampler(x)]; return ok }))
+	r.setLayout = VkDescriptorSetLayout(newUnusedID(false, func(x uint64) bool { _, ok := st.DescriptorSetLayouts[VkDescriptorSetLayout(x)]; return ok }))
+	r.pool = VkDescriptorPool(newUnusedID(false, func(x uint64) bool { _, ok := st.DescriptorPools[VkDescriptorPool(x)]; return ok }))
+	r.set = VkDescriptorSet(newUnusedID(false, func(x uint64) bool { _, ok := st.DescriptorSets[VkDescriptorSet(x)]; return ok }))
+	r.pipelineLayout = VkPipelineLayout(newUnusedID(false, func(x uint64) bool { _, ok := st.PipelineLayouts[VkPipelineLayout(x)]; return ok }))
+	r.vertexShader = VkShaderModule(newUnusedID(false, func(x uint64) bool { _, ok := st.ShaderModules[VkShaderModule(x)]; return ok }))
+	r.fragmentShader = VkShaderModule(newUnusedID(false, func(x uint64) bool {
+		_, ok := st.ShaderModules[VkShaderModule(x)]
+		return ok || VkShaderModule(x) == r.vertexShader
+	}))
+	r.renderPass = VkRenderPass(newUnusedID(false, func(x uint64) bool { _, ok := st.RenderPasses[VkRenderPass(x)]; return ok }))
+	r.framebuffer = VkFramebuffer(newUnusedID(false, func(x uint64) bool { _, ok := st.Framebuffers[VkFramebuffer(x)]; return ok }))
+	r.pipeline = VkPipeline(newUnusedID(false, func(x uint64) bool {
+		_, graphics := st.GraphicsPipelines[VkPipeline(x)]
+		_, compute := st.ComputePipelines[VkPipeline(x)]
+		return graphics || compute
+	}))
+	return r, nil
+}
+
+// create writes the atoms creating the objects of the draw. The dst image must
+// have been created and bound to memory.
+func (r *depthResolve) create(ctx context.Context, s *gfxapi.State, out transform.Writer, alloc allocData) {
+	device, src, dst := r.device, r.src, r.dst
+	format := src.Info.Format
+	identity := VkComponentMapping{
+		R: VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+		G: VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+		B: VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+		A: VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+	}
+	// Only the depth aspect of the source image is sampled, while the view of
+	// an attachment covers all the aspects of its format.
+	srcViewInfo := alloc(ctx, s, VkImageViewCreateInfo{
+		SType:      VkStructureType_VK_STRUCTURE_TYPE_IMAGE_VIEW_CREATE_INFO,
+		PNext:      NewVoidᶜᵖ(0),
+		Image:      src.VulkanHandle,
+		ViewType:   VkImageViewType_VK_IMAGE_VIEW_TYPE_2D,
+		Format:     format,
+		Components: identity,
+		SubresourceRange: VkImageSubresourceRange{
+			AspectMask:     VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_DEPTH_BIT),
+			BaseMipLevel:   0,
+			LevelCount:     1,
+			BaseArrayLayer: 0,
+			LayerCount:     1,
+		},
+	})
+	srcViewData := alloc(ctx, s, r.srcView)
+	dstViewInfo := alloc(ctx, s, VkImageViewCreateInfo{
+		SType:      VkStructureType_VK_STRUCTURE_TYPE_IMAGE_VIEW_CREATE_INFO,
+		PNext:      NewVoidᶜᵖ(0),
+		Image:      dst,
+		ViewType:   VkImageViewType_VK_IMAGE_VIEW_TYPE_2D,
+		Format:     format,
+		Components: identity,
+		SubresourceRange: VkImageSubresourceRange{
+			AspectMask:     depthStencilAspects(format),
+			BaseMipLevel:   0,
+			LevelCount:     1,
+			BaseArrayLayer: 0,
+			LayerCount:     1,
+		},
+	})
+	dstViewData := alloc(ctx, s, r.dstView)
+	samplerInfo := alloc(ctx, s, VkSamplerCreateInfo{
+		SType:         VkStructureType_VK_STRUCTURE_TYPE_SAMPLER_CREATE_INFO,
+		PNext:         NewVoidᶜᵖ(0),
+		MagFilter:     VkFilter_VK_FILTER_NEAREST,
+		MinFilter:     VkFilter_VK_FILTER_NEAREST,
+		MipmapMode:    VkSamplerMipmapMode_VK_SAMPLER_MIPMAP_MODE_NEAREST,
+		AddressModeU:  VkSamplerAddressMode_VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		AddressModeV:  VkSamplerAddressMode_VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		AddressModeW:  VkSamplerAddressMode_VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		MaxAnisotropy: 1,
+		CompareOp:     VkCompareOp_VK_COMPARE_OP_NEVER,
+		BorderColor:   VkBorderColor_VK_BORDER_COLOR_FLOAT_TRANSPARENT_BLACK,
+	})
+	samplerData := alloc(ctx, s, r.sampler)
+
+	writeEach(ctx, out,
+		NewVkCreateImageView(device, srcViewInfo.Ptr(), memory.Pointer{}, srcViewData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(srcViewInfo.Data()).
+			AddWrite(srcViewData.Data()),
+		NewVkCreateImageView(device, dstViewInfo.Ptr(), memory.Pointer{}, dstViewData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(dstViewInfo.Data()).
+			AddWrite(dstViewData.Data()),
+		NewVkCreateSampler(device, samplerInfo.Ptr(), memory.Pointer{}, samplerData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(samplerInfo.Data()).
+			AddWrite(samplerData.Data()),
+	)
+
+	r.createDescriptorSet(ctx, s, out, alloc)
+	r.createShaderModule(ctx, s, out, alloc, r.vertexShader, r.vertexShaderWords)
+	r.createShaderModule(ctx, s, out, alloc, r.fragmentShader, r.fragmentShaderWords)
+	r.createRenderPass(ctx, s, out, alloc, format)
+	r.createPipeline(ctx, s, out, alloc)
+}
+
+// createDescriptorSet writes the atoms creating the descriptor set sampling
+// the source image and the pipeline layout using it.
+func (r *depthResolve) createDescriptorSet(ctx context.Context, s *gfxapi.State, out transform.Writer, alloc allocData) {
+	const ty = VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER
+
+	binding := alloc(ctx, s, VkDescriptorSetLayoutBinding{
+		Binding:            0,
+		DescriptorType:     ty,
+		DescriptorCount:    1,
+		StageFlags:         VkShaderStageFlags(VkShaderStageFlagBits_VK_SHADER_STAGE_FRAGMENT_BIT),
+		PImmutableSamplers: NewVkSamplerᶜᵖ(0),
+	})
+	setLayoutInfo := alloc(ctx, s, VkDescriptorSetLayoutCreateInfo{
+		SType:        VkStructureType_VK_STRUCTURE_TYPE_DESCRIPTOR_SET_LAYOUT_CREATE_INFO,
+		PNext:        NewVoidᶜᵖ(0),
+		BindingCount: 1,
+		PBindings:    NewVkDescriptorSetLayoutBindingᶜᵖ(binding.Address()),
+	})
+	setLayoutData := alloc(ctx, s, r.setLayout)
+	pipelineLayoutInfo := alloc(ctx, s, VkPipelineLayoutCreateInfo{
+		SType:               VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_LAYOUT_CREATE_INFO,
+		PNext:               NewVoidᶜᵖ(0),
+		SetLayoutCount:      1,
+		PSetLayouts:         NewVkDescriptorSetLayoutᶜᵖ(setLayoutData.Address()),
+		PPushConstantRanges: NewVkPushConstantRangeᶜᵖ(0),
+	})
+	pipelineLayoutData := alloc(ctx, s, r.pipelineLayout)
+	poolSize := alloc(ctx, s, VkDescriptorPoolSize{
+		Type:            ty,
+		DescriptorCount: 1,
+	})
+	poolInfo := alloc(ctx, s, VkDescriptorPoolCreateInfo{
+		SType:         VkStructureType_VK_STRUCTURE_TYPE_DESCRIPTOR_POOL_CREATE_INFO,
+		PNext:         NewVoidᶜᵖ(0),
+		MaxSets:       1,
+		PoolSizeCount: 1,
+		PPoolSizes:    NewVkDescriptorPoolSizeᶜᵖ(poolSize.Address()),
+	})
+	poolData := alloc(ctx, s, r.pool)
+	setInfo := alloc(ctx, s, VkDescriptorSetAllocateInfo{
+		SType:              VkStructureType_VK_STRUCTURE_TYPE_DESCRIPTOR_SET_ALLOCATE_INFO,
+		PNext:              NewVoidᶜᵖ(0),
+		DescriptorPool:     r.pool,
+		DescriptorSetCount: 1,
+		PSetLayouts:        NewVkDescriptorSetLayoutᶜᵖ(setLayoutData.Address()),
+	})
+	setData := alloc(ctx, s, r.set)
+	imageInfo := alloc(ctx, s, VkDescriptorImageInfo{
+		Sampler:     r.sampler,
+		ImageView:   r.srcView,
+		ImageLayout: VkImageLayout_VK_IMAGE_LAYOUT_DEPTH_STENCIL_READ_ONLY_OPTIMAL,
+	})
+	write := alloc(ctx, s, VkWriteDescriptorSet{
+		SType:            VkStructureType_VK_STRUCTURE_TYPE_WRITE_DESCRIPTOR_SET,
+		PNext:            NewVoidᶜᵖ(0),
+		DstSet:           r.set,
+		DescriptorCount:  1,
+		DescriptorType:   ty,
+		PImageInfo:       NewVkDescriptorImageInfoᶜᵖ(imageInfo.Address()),
+		PBufferInfo:      NewVkDescriptorBufferInfoᶜᵖ(0),
+		PTexelBufferView: NewVkBufferViewᶜᵖ(0),
+	})
+
+	writeEach(ctx, out,
+		NewVkCreateDescriptorSetLayout(r.device, setLayoutInfo.Ptr(), memory.Pointer{}, setLayoutData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(setLayoutInfo.Data()).
+			AddRead(binding.Data()).
+			AddWrite(setLayoutData.Data()),
+		NewVkCreatePipelineLayout(r.device, pipelineLayoutInfo.Ptr(), memory.Pointer{}, pipelineLayoutData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(pipelineLayoutInfo.Data()).
+			AddRead(setLayoutData.Data()).
+			AddWrite(pipelineLayoutData.Data()),
+		NewVkCreateDescriptorPool(r.device, poolInfo.Ptr(), memory.Pointer{}, poolData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(poolInfo.Data()).
+			AddRead(poolSize.Data()).
+			AddWrite(poolData.Data()),
+		NewVkAllocateDescriptorSets(r.device, setInfo.Ptr(), setData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(setInfo.Data()).
+			AddRead(setLayoutData.Data()).
+			AddWrite(setData.Data()),
+		NewVkUpdateDescriptorSets(r.device, 1, write.Ptr(), 0, memory.Pointer{}).
+			AddRead(write.Data()).
+			AddRead(imageInfo.Data()),
+	)
+}
+
+// createShaderModule writes the atom creating the shader module holding the
+// SPIR-V words.
+func (r *depthResolve) createShaderModule(ctx context.Context, s *gfxapi.State, out transform.Writer, alloc allocData,
+	module VkShaderModule, words []uint32) {
+
+	info, code := newShaderModuleCreateInfo(ctx, s, VkShaderModuleCreateInfo{
+		SType: VkStructureType_VK_STRUCTURE_TYPE_SHADER_MODULE_CREATE_INFO,
+		PNext: NewVoidᶜᵖ(0),
+	}, words)
+	defer info.Free()
+	defer code.Free()
+	moduleData := alloc(ctx, s, module)
+	writeEach(ctx, out,
+		NewVkCreateShaderModule(r.device, info.Ptr(), memory.Pointer{}, moduleData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(info.Data()).
+			AddRead(code.Data()).
+			AddWrite(moduleData.Data()),
+	)
+}
+
+// createRenderPass writes the atoms creating the render pass drawing to the
+// depth of the destination image, and the framebuffer holding its view.
+func (r *depthResolve) createRenderPass(ctx context.Context, s *gfxapi.State, out transform.Writer, alloc allocData, format VkFormat) {
+	attachment := alloc(ctx, s, VkAttachmentDescription{
+		Format:         format,
+		Samples:        VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT,
+		LoadOp:         VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_DONT_CARE,
+		StoreOp:        VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_STORE,
+		StencilLoadOp:  VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_DONT_CARE,
+		StencilStoreOp: VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_DONT_CARE,
+		InitialLayout:  VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED,
+		FinalLayout:    VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL,
+	})
+	reference := alloc(ctx, s, VkAttachmentReference{
+		Attachment: 0,
+		Layout:     VkImageLayout_VK_IMAGE_LAYOUT_DEPTH_STENCIL_ATTACHMENT_OPTIMAL,
+	})
+	subpass := alloc(ctx, s, VkSubpassDescription{
+		PipelineBindPoint:       VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS,
+		PInputAttachments:       NewVkAttachmentReferenceᶜᵖ(0),
+		PColorAttachments:       NewVkAttachmentReferenceᶜᵖ(0),
+		PResolveAttachments:     NewVkAttachmentReferenceᶜᵖ(0),
+		PDepthStencilAttachment: NewVkAttachmentReferenceᶜᵖ(reference.Address()),
+		PPreserveAttachments:    NewU32ᶜᵖ(0),
+	})
+	// The depth written by the draw is copied out of the image after the pass.
+	dependency := alloc(ctx, s, VkSubpassDependency{
+		SrcSubpass:    0,
+		DstSubpass:    0xFFFFFFFF, // VK_SUBPASS_EXTERNAL
+		SrcStageMask:  VkPipelineStageFlags(VkPipelineStageFlagBits_VK_PIPELINE_STAGE_LATE_FRAGMENT_TESTS_BIT),
+		DstStageMask:  VkPipelineStageFlags(VkPipelineStageFlagBits_VK_PIPELINE_STAGE_TRANSFER_BIT),
+		SrcAccessMask: VkAccessFlags(VkAccessFlagBits_VK_ACCESS_DEPTH_STENCIL_ATTACHMENT_WRITE_BIT),
+		DstAccessMask: VkAccessFlags(VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT),
+	})
+	renderPassInfo := alloc(ctx, s, VkRenderPassCreateInfo{
+		SType:           VkStructureType_VK_STRUCTURE_TYPE_RENDER_PASS_CREATE_INFO,
+		PNext:           NewVoidᶜᵖ(0),
+		AttachmentCount: 1,
+		PAttachments:    NewVkAttachmentDescriptionᶜᵖ(attachment.Address()),
+		SubpassCount:    1,
+		PSubpasses:      NewVkSubpassDescriptionᶜᵖ(subpass.Address()),
+		DependencyCount: 1,
+		PDependencies:   NewVkSubpassDependencyᶜᵖ(dependency.Address()),
+	})
+	renderPassData := alloc(ctx, s, r.renderPass)
+	views := alloc(ctx, s, r.dstView)
+	framebufferInfo := alloc(ctx, s, VkFramebufferCreateInfo{
+		SType:           VkStructureType_VK_STRUCTURE_TYPE_FRAMEBUFFER_CREATE_INFO,
+		PNext:           NewVoidᶜᵖ(0),
+		RenderPass:      r.renderPass,
+		AttachmentCount: 1,
+		PAttachments:    NewVkImageViewᶜᵖ(views.Address()),
+		Width:           r.width,
+		Height:          r.height,
+		Layers:          1,
+	})
+	framebufferData := alloc(ctx, s, r.framebuffer)
+
+	writeEach(ctx, out,
+		NewVkCreateRenderPass(r.device, renderPassInfo.Ptr(), memory.Pointer{}, renderPassData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(renderPassInfo.Data()).
+			AddRead(attachment.Data()).
+			AddRead(subpass.Data()).
+			AddRead(reference.Data()).
+			AddRead(dependency.Data()).
+			AddWrite(renderPassData.Data()),
+		NewVkCreateFramebuffer(r.device, framebufferInfo.Ptr(), memory.Pointer{}, framebufferData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(framebufferInfo.Data()).
+			AddRead(views.Data()).
+			AddWrite(framebufferData.Data()),
+	)
+}
+
+// createPipeline writes the atom creating the pipeline drawing the triangle
+// of the vertex shader, writing the depth of the fragment shader regardless
+// of the depth already in the attachment.
+func (r *depthResolve) createPipeline(ctx context.Context, s *gfxapi.State, out transform.Writer, alloc allocData) {
+	entry := alloc(ctx, s, "main")
+	stages := alloc(ctx, s, []VkPipelineShaderStageCreateInfo{
+		{
+			SType:               VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_SHADER_STAGE_CREATE_INFO,
+			PNext:               NewVoidᶜᵖ(0),
+			Stage:               VkShaderStageFlagBits_VK_SHADER_STAGE_VERTEX_BIT,
+			Module:              r.vertexShader,
+			PName:               NewCharᶜᵖ(entry.Address()),
+			PSpecializationInfo: NewVkSpecializationInfoᶜᵖ(0),
+		}, {
+			SType:               VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_SHADER_STAGE_CREATE_INFO,
+			PNext:               NewVoidᶜᵖ(0),
+			Stage:               VkShaderStageFlagBits_VK_SHADER_STAGE_FRAGMENT_BIT,
+			Module:              r.fragmentShader,
+			PName:               NewCharᶜᵖ(entry.Address()),
+			PSpecializationInfo: NewVkSpecializationInfoᶜᵖ(0),
+		},
+	})
+	vertexInputState := alloc(ctx, s, VkPipelineVertexInputStateCreateInfo{
+		SType:                        VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_VERTEX_INPUT_STATE_CREATE_INFO,
+		PNext:                        NewVoidᶜᵖ(0),
+		PVertexBindingDescriptions:   NewVkVertexInputBindingDescriptionᶜᵖ(0),
+		PVertexAttributeDescriptions: NewVkVertexInputAttributeDescriptionᶜᵖ(0),
+	})
+	inputAssemblyState := alloc(ctx, s, VkPipelineInputAssemblyStateCreateInfo{
+		SType:    VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_INPUT_ASSEMBLY_STATE_CREATE_INFO,
+		PNext:    NewVoidᶜᵖ(0),
+		Topology: VkPrimitiveTopology_VK_PRIMITIVE_TOPOLOGY_TRIANGLE_LIST,
+	})
+	viewport := alloc(ctx, s, VkViewport{
+		X:        0,
+		Y:        0,
+		Width:    float32(r.width),
+		Height:   float32(r.height),
+		MinDepth: 0,
+		MaxDepth: 1,
+	})
+	scissor := alloc(ctx, s, VkRect2D{
+		Offset: VkOffset2D{X: 0, Y: 0},
+		Extent: VkExtent2D{Width: r.width, Height: r.height},
+	})
+	viewportState := alloc(ctx, s, VkPipelineViewportStateCreateInfo{
+		SType:         VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_VIEWPORT_STATE_CREATE_INFO,
+		PNext:         NewVoidᶜᵖ(0),
+		ViewportCount: 1,
+		PViewports:    NewVkViewportᶜᵖ(viewport.Address()),
+		ScissorCount:  1,
+		PScissors:     NewVkRect2Dᶜᵖ(scissor.Address()),
+	})
+	rasterizationState := alloc(ctx, s, VkPipelineRasterizationStateCreateInfo{
+		SType:       VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_RASTERIZATION_STATE_CREATE_INFO,
+		PNext:       NewVoidᶜᵖ(0),
+		PolygonMode: VkPolygonMode_VK_POLYGON_MODE_FILL,
+		CullMode:    VkCullModeFlags(VkCullModeFlagBits_VK_CULL_MODE_NONE),
+		FrontFace:   VkFrontFace_VK_FRONT_FACE_COUNTER_CLOCKWISE,
+		LineWidth:   1,
+	})
+	multisampleState := alloc(ctx, s, VkPipelineMultisampleStateCreateInfo{
+		SType:                VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_MULTISAMPLE_STATE_CREATE_INFO,
+		PNext:                NewVoidᶜᵖ(0),
+		RasterizationSamples: VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT,
+		PSampleMask:          NewVkSampleMaskᶜᵖ(0),
+	})
+	depthStencilState := alloc(ctx, s, VkPipelineDepthStencilStateCreateInfo{
+		SType:            VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_DEPTH_STENCIL_STATE_CREATE_INFO,
+		PNext:            NewVoidᶜᵖ(0),
+		DepthTestEnable:  1,
+		DepthWriteEnable: 1,
+		DepthCompareOp:   VkCompareOp_VK_COMPARE_OP_ALWAYS,
+		MaxDepthBounds:   1,
+	})
+	info := alloc(ctx, s, VkGraphicsPipelineCreateInfo{
+		SType:               VkStructureType_VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
+		PNext:               NewVoidᶜᵖ(0),
+		StageCount:          2,
+		PStages:             NewVkPipelineShaderStageCreateInfoᶜᵖ(stages.Address()),
+		PVertexInputState:   NewVkPipelineVertexInputStateCreateInfoᶜᵖ(vertexInputState.Address()),
+		PInputAssemblyState: NewVkPipelineInputAssemblyStateCreateInfoᶜᵖ(inputAssemblyState.Address()),
+		PTessellationState:  NewVkPipelineTessellationStateCreateInfoᶜᵖ(0),
+		PViewportState:      NewVkPipelineViewportStateCreateInfoᶜᵖ(viewportState.Address()),
+		PRasterizationState: NewVkPipelineRasterizationStateCreateInfoᶜᵖ(rasterizationState.Address()),
+		PMultisampleState:   NewVkPipelineMultisampleStateCreateInfoᶜᵖ(multisampleState.Address()),
+		PDepthStencilState:  NewVkPipelineDepthStencilStateCreateInfoᶜᵖ(depthStencilState.Address()),
+		PColorBlendState:    NewVkPipelineColorBlendStateCreateInfoᶜᵖ(0),
+		PDynamicState:       NewVkPipelineDynamicStateCreateInfoᶜᵖ(0),
+		Layout:              r.pipelineLayout,
+		RenderPass:          r.renderPass,
+		Subpass:             0,
+		BasePipelineIndex:   -1,
+	})
+	pipelineData := alloc(ctx, s, r.pipeline)
+
+	writeEach(ctx, out,
+		NewVkCreateGraphicsPipelines(r.device, VkPipelineCache(0), 1, info.Ptr(), memory.Pointer{}, pipelineData.Ptr(), VkResult_VK_SUCCESS).
+			AddRead(info.Data()).
+			AddRead(stages.Data()).
+			AddRead(entry.Data()).
+			AddRead(vertexInputState.Data()).
+			AddRead(inputAssemblyState.Data()).
+			AddRead(viewportState.Data()).
+			AddRead(viewport.Data()).
+			AddRead(scissor.Data()).
+			AddRead(rasterizationState.Data()).
+			AddRead(multisampleState.Data()).
+			AddRead(depthStencilState.Data()).
+			AddWrite(pipelineData.Data()),
+	)
+}
+
+// record writes the atoms recording the draw to the command buffer.
+func (r *depthResolve) record(ctx context.Context, s *gfxapi.State, out transform.Writer, alloc allocData, commandBuffer VkCommandBuffer) {
+	beginInfo := alloc(ctx, s, VkRenderPassBeginInfo{
+		SType:       VkStructureType_VK_STRUCTURE_TYPE_RENDER_PASS_BEGIN_INFO,
+		PNext:       NewVoidᶜᵖ(0),
+		RenderPass:  r.renderPass,
+		Framebuffer: r.framebuffer,
+		RenderArea: VkRect2D{
+			Offset: VkOffset2D{X: 0, Y: 0},
+			Extent: VkExtent2D{Width: r.width, Height: r.height},
+		},
+		ClearValueCount: 0,
+		PClearValues:    NewVkClearValueᶜᵖ(0),
+	})
+	sets := alloc(ctx, s, r.set)
+
+	writeEach(ctx, out,
+		NewVkCmdBeginRenderPass(commandBuffer, beginInfo.Ptr(), VkSubpassContents_VK_SUBPASS_CONTENTS_INLINE).
+			AddRead(beginInfo.Data()),
+		NewVkCmdBindPipeline(commandBuffer, VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, r.pipeline),
+		NewVkCmdBindDescriptorSets(commandBuffer, VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS,
+			r.pipelineLayout, 0, 1, sets.Ptr(), 0, memory.Pointer{}).
+			AddRead(sets.Data()),
+		NewVkCmdDraw(commandBuffer, 3, 1, 0, 0),
+		NewVkCmdEndRenderPass(commandBuffer),
+	)
+}
+
+// destroy writes the atoms destroying the objects of the draw, once the
+// command buffer recording it has completed.
+func (r *depthResolve) destroy(ctx context.Context, out transform.Writer) {
+	writeEach(ctx, out,
+		NewVkDestroyPipeline(r.device, r.pipeline, memory.Pointer{}),
+		NewVkDestroyFramebuffer(r.device, r.framebuffer, memory.Pointer{}),
+		NewVkDestroyRenderPass(r.device, r.renderPass, memory.Pointer{}),
+		NewVkDestroyShaderModule(r.device, r.fragmentShader, memory.Pointer{}),
+		NewVkDestroyShaderModule(r.device, r.vertexShader, memory.Pointer{}),
+		NewVkDestroyPipelineLayout(r.device, r.pipelineLayout, memory.Pointer{}),
+		NewVkDestroyDescriptorPool(r.device, r.pool, memory.Pointer{}),
+		NewVkDestroyDescriptorSetLayout(r.device, r.setLayout, memory.Pointer{}),
+		NewVkDestroySampler(r.device, r.sampler, memory.Pointer{}),
+		NewVkDestroyImageView(r.device, r.dstView, memory.Pointer{}),
+		NewVkDestroyImageView(r.device, r.srcView, memory.Pointer{}),
+	)
+}
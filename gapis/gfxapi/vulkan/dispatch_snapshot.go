@@ -0,0 +1,503 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/replay/builder"
+	"github.com/google/gapid/gapis/replay/value"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// stagingAlignment is the alignment of the copies in the staging buffer. It
+// is a multiple of all the texel sizes of the uncompressed formats, as
+// required by vkCmdCopyImageToBuffer.
+const stagingAlignment = 768
+
+// dispatchSnapshotConfig is a replay.Config used by dispatchSnapshotRequests.
+type dispatchSnapshotConfig struct {
+	dispatch atom.ID
+}
+
+// dispatchSnapshotRequest requests the content of the storage resources bound
+// to a dispatch, before and after the dispatch executed.
+type dispatchSnapshotRequest struct {
+	dispatch atom.ID         // The dispatch atom.
+	submit   atom.ID         // The vkQueueSubmit executing the dispatch.
+	cb       VkCommandBuffer // The command buffer the dispatch is recorded to.
+}
+
+// storageResource is a storage resource bound to the snapshot dispatch, and
+// the offsets of its content in the staging buffer.
+type storageResource struct {
+	key    descriptorKey
+	buffer boundBuffer
+	image  *ImageObject
+	format *image.Format
+	region VkBufferImageCopy
+	size   uint64
+	before uint64
+	after  uint64
+}
+
+// dispatchSnapshot is a transform that copies the storage resources bound to
+// a dispatch to a host-visible staging buffer, right before and right after
+// the dispatch in its command buffer. The staging buffer is read back once
+// the command buffer has been submitted.
+type dispatchSnapshot struct {
+	req       dispatchSnapshotRequest
+	res       []replay.Result
+	bindings  *commandBufferBindings
+	resources []storageResource
+	names     map[descriptorKey]string
+	device    VkDevice
+	buffer    VkBuffer
+	memory    VkDeviceMemory
+	size      uint64
+}
+
+func newDispatchSnapshot(req dispatchSnapshotRequest) *dispatchSnapshot {
+	return &dispatchSnapshot{
+		req:      req,
+		bindings: newCommandBufferBindings(),
+		names:    map[descriptorKey]string{},
+	}
+}
+
+// reportTo adds r to the results notified with the snapshot.
+func (t *dispatchSnapshot) reportTo(r replay.Result) {
+	t.res = append(t.res, r)
+}
+
+func (t *dispatchSnapshot) notify(val interface{}, err error) {
+	for _, r := range t.res {
+		r(val, err)
+	}
+}
+
+func (t *dispatchSnapshot) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	s := out.State()
+	switch {
+	case id == t.req.dispatch:
+		t.copyResources(ctx, out, true)
+		out.MutateAndWrite(ctx, id, a)
+		t.copyResources(ctx, out, false)
+		return
+	case id == t.req.submit:
+		out.MutateAndWrite(ctx, id, a)
+		if submit, ok := a.(*VkQueueSubmit); ok {
+			t.post(ctx, submit.Queue, out)
+		}
+		return
+	case id < t.req.dispatch:
+		// Storage buffers need to be copied to the staging buffer.
+		switch a := a.(type) {
+		case *VkCreateBuffer:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			if usage, changed := patchBufferUsage(info.Usage); changed {
+				info.Usage = usage
+				newInfo := atom.Must(atom.AllocData(ctx, s, info))
+				defer newInfo.Free()
+				newAtom := NewVkCreateBuffer(a.Device, newInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PBuffer), a.Result)
+				out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, newInfo))
+				return
+			}
+		case *RecreateBuffer:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			if usage, changed := patchBufferUsage(info.Usage); changed {
+				info.Usage = usage
+				newInfo := atom.Must(atom.AllocData(ctx, s, info))
+				defer newInfo.Free()
+				newAtom := NewRecreateBuffer(a.Device, newInfo.Ptr(), memory.Pointer(a.PBuffer))
+				out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, newInfo))
+				return
+			}
+		}
+		out.MutateAndWrite(ctx, id, a)
+		t.bindings.record(ctx, s, a, t.req.cb)
+		return
+	}
+	out.MutateAndWrite(ctx, id, a)
+}
+
+func (t *dispatchSnapshot) Flush(ctx context.Context, out transform.Writer) {}
+
+// patchBufferUsage returns usage with the transfer source bit added if the
+// buffer can be bound to a storage descriptor.
+func patchBufferUsage(usage VkBufferUsageFlags) (VkBufferUsageFlags, bool) {
+	storage := VkBufferUsageFlags(VkBufferUsageFlagBits_VK_BUFFER_USAGE_STORAGE_BUFFER_BIT |
+		VkBufferUsageFlagBits_VK_BUFFER_USAGE_STORAGE_TEXEL_BUFFER_BIT)
+	src := VkBufferUsageFlags(VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_SRC_BIT)
+	if usage&storage == 0 || usage&src != 0 {
+		return usage, false
+	}
+	return usage | src, true
+}
+
+// findResources fills the storage resources bound to the dispatch and their
+// offsets in the staging buffer.
+func (t *dispatchSnapshot) findResources(ctx context.Context, s *gfxapi.State) {
+	st := GetState(s)
+	point := VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE
+
+	if pipeline, ok := t.bindings.pipelines[point]; ok && st.ComputePipelines.Contains(pipeline) {
+		if module := st.ComputePipelines.Get(pipeline).Stage.Module; module != nil {
+			words := module.Words.Read(ctx, nil, s, nil)
+			if bindings, err := shadertools.DescriptorBindings(words); err == nil {
+				for _, b := range bindings {
+					t.names[descriptorKey{b.Set, b.Binding}] = b.Name
+				}
+			}
+		}
+	}
+
+	add := func(r storageResource) {
+		r.before = t.size
+		r.after = align(r.before+r.size, stagingAlignment)
+		t.size = align(r.after+r.size, stagingAlignment)
+		t.resources = append(t.resources, r)
+	}
+
+	for key, b := range t.bindings.buffers[point] {
+		switch b.ty {
+		case VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER,
+			VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER_DYNAMIC,
+			VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_TEXEL_BUFFER:
+		default:
+			continue
+		}
+		if !st.Buffers.Contains(b.buffer) {
+			continue
+		}
+		size := b.size
+		if size == 0xFFFFFFFFFFFFFFFF { // VK_WHOLE_SIZE
+			size = uint64(st.Buffers.Get(b.buffer).Info.Size) - b.offset
+		}
+		add(storageResource{key: key, buffer: b, size: size})
+	}
+
+	for key, b := range t.bindings.images[point] {
+		if b.ty != VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_IMAGE || !st.ImageViews.Contains(b.view) {
+			continue
+		}
+		view := st.ImageViews.Get(b.view)
+		img := view.Image
+		if img == nil {
+			continue
+		}
+		switch img.Info.Layout {
+		case VkImageLayout_VK_IMAGE_LAYOUT_GENERAL, VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL:
+		default:
+			log.W(ctx, "Storage image %v is not in a layout it can be copied from", img.VulkanHandle)
+			continue
+		}
+		format, err := getImageFormatFromVulkanFormat(img.Info.Format)
+		if err != nil {
+			log.W(ctx, "Storage image %v: %v", img.VulkanHandle, err)
+			continue
+		}
+		level := view.SubresourceRange.BaseMipLevel
+		width, height := img.Info.Extent.Width>>level, img.Info.Extent.Height>>level
+		if width == 0 {
+			width = 1
+		}
+		if height == 0 {
+			height = 1
+		}
+		region := VkBufferImageCopy{
+			ImageSubresource: VkImageSubresourceLayers{
+				AspectMask:     VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT),
+				MipLevel:       level,
+				BaseArrayLayer: view.SubresourceRange.BaseArrayLayer,
+				LayerCount:     1,
+			},
+			ImageExtent: VkExtent3D{Width: width, Height: height, Depth: 1},
+		}
+		size := uint64(format.Size(int(width), int(height)))
+		add(storageResource{key: key, image: img, format: format, region: region, size: size})
+	}
+}
+
+func align(v, alignment uint64) uint64 {
+	return (v + alignment - 1) / alignment * alignment
+}
+
+// copyResources records the copies of the storage resources to the staging
+// buffer in the dispatch command buffer. The staging buffer is created before
+// the copies preceding the dispatch.
+func (t *dispatchSnapshot) copyResources(ctx context.Context, out transform.Writer, before bool) {
+	s := out.State()
+	cb := t.req.cb
+	if before {
+		t.findResources(ctx, s)
+		if len(t.resources) == 0 || !t.createStagingBuffer(ctx, out) {
+			t.resources = nil
+			return
+		}
+	}
+	if len(t.resources) == 0 {
+		return
+	}
+
+	var allocated []atom.AllocResult
+	defer func() {
+		for _, d := range allocated {
+			d.Free()
+		}
+	}()
+	alloc := func(v ...interface{}) atom.AllocResult {
+		d := atom.Must(atom.AllocData(ctx, s, v...))
+		allocated = append(allocated, d)
+		return d
+	}
+	barrier := func(src, dst VkAccessFlagBits, dstStage VkPipelineStageFlagBits) atom.Atom {
+		data := alloc(VkMemoryBarrier{
+			SType:         VkStructureType_VK_STRUCTURE_TYPE_MEMORY_BARRIER,
+			PNext:         NewVoidᶜᵖ(0),
+			SrcAccessMask: VkAccessFlags(src),
+			DstAccessMask: VkAccessFlags(dst),
+		})
+		return NewVkCmdPipelineBarrier(
+			cb,
+			VkPipelineStageFlags(VkPipelineStageFlagBits_VK_PIPELINE_STAGE_ALL_COMMANDS_BIT),
+			VkPipelineStageFlags(dstStage),
+			VkDependencyFlags(0),
+			1,
+			data.Ptr(),
+			0,
+			memory.Pointer{},
+			0,
+			memory.Pointer{},
+		).AddRead(data.Data())
+	}
+
+	shaderWrite := VkAccessFlagBits_VK_ACCESS_SHADER_WRITE_BIT | VkAccessFlagBits_VK_ACCESS_TRANSFER_WRITE_BIT
+	allCommands := VkPipelineStageFlagBits_VK_PIPELINE_STAGE_ALL_COMMANDS_BIT
+	writeEach(ctx, out, barrier(shaderWrite, VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT, allCommands))
+
+	for _, r := range t.resources {
+		offset := r.before
+		if !before {
+			offset = r.after
+		}
+		if r.image != nil {
+			region := r.region
+			region.BufferOffset = VkDeviceSize(offset)
+			data := alloc(region)
+			writeEach(ctx, out, NewVkCmdCopyImageToBuffer(
+				cb,
+				r.image.VulkanHandle,
+				r.image.Info.Layout,
+				t.buffer,
+				1,
+				data.Ptr(),
+			).AddRead(data.Data()))
+			continue
+		}
+		data := alloc(VkBufferCopy{
+			SrcOffset: VkDeviceSize(r.buffer.offset),
+			DstOffset: VkDeviceSize(offset),
+			Size:      VkDeviceSize(r.size),
+		})
+		writeEach(ctx, out, NewVkCmdCopyBuffer(cb, r.buffer.buffer, t.buffer, 1, data.Ptr()).AddRead(data.Data()))
+	}
+
+	if before {
+		// The dispatch must not write the resources before they are copied.
+		writeEach(ctx, out, barrier(VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT,
+			VkAccessFlagBits_VK_ACCESS_SHADER_READ_BIT|VkAccessFlagBits_VK_ACCESS_SHADER_WRITE_BIT, allCommands))
+	} else {
+		writeEach(ctx, out, barrier(VkAccessFlagBits_VK_ACCESS_TRANSFER_WRITE_BIT,
+			VkAccessFlagBits_VK_ACCESS_HOST_READ_BIT, VkPipelineStageFlagBits_VK_PIPELINE_STAGE_HOST_BIT))
+	}
+}
+
+// createStagingBuffer writes the atoms creating the host-visible staging
+// buffer, returning false if no host-visible memory is available.
+func (t *dispatchSnapshot) createStagingBuffer(ctx context.Context, out transform.Writer) bool {
+	s := out.State()
+	st := GetState(s)
+	if !st.CommandBuffers.Contains(t.req.cb) {
+		return false
+	}
+	t.device = st.CommandBuffers.Get(t.req.cb).Device
+	device := st.Devices.Get(t.device)
+	physicalDevice := st.PhysicalDevices.Get(device.PhysicalDevice)
+
+	memoryTypeIndex, found := uint32(0), false
+	hostVisible := VkMemoryPropertyFlags(VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_HOST_VISIBLE_BIT |
+		VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_HOST_COHERENT_BIT)
+	for i := uint32(0); i < physicalDevice.MemoryProperties.MemoryTypeCount; i++ {
+		if physicalDevice.MemoryProperties.MemoryTypes.Elements[i].PropertyFlags&hostVisible == hostVisible {
+			memoryTypeIndex, found = i, true
+			break
+		}
+	}
+	if !found {
+		t.notify(nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage("No host visible memory to read the storage resources")})
+		return false
+	}
+
+	t.buffer = VkBuffer(newUnusedID(false, func(x uint64) bool { return st.Buffers.Contains(VkBuffer(x)) }))
+	t.memory = VkDeviceMemory(newUnusedID(false, func(x uint64) bool { return st.DeviceMemories.Contains(VkDeviceMemory(x)) }))
+
+	bufferCreateInfo := atom.Must(atom.AllocData(ctx, s, VkBufferCreateInfo{
+		SType:                 VkStructureType_VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO,
+		PNext:                 NewVoidᶜᵖ(0),
+		Flags:                 VkBufferCreateFlags(0),
+		Size:                  VkDeviceSize(t.size),
+		Usage:                 VkBufferUsageFlags(VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT),
+		SharingMode:           VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
+		QueueFamilyIndexCount: 0,
+		PQueueFamilyIndices:   NewU32ᶜᵖ(0),
+	}))
+	defer bufferCreateInfo.Free()
+	bufferData := atom.Must(atom.AllocData(ctx, s, t.buffer))
+	defer bufferData.Free()
+	memoryAllocateInfo := atom.Must(atom.AllocData(ctx, s, VkMemoryAllocateInfo{
+		SType:           VkStructureType_VK_STRUCTURE_TYPE_MEMORY_ALLOCATE_INFO,
+		PNext:           NewVoidᶜᵖ(0),
+		AllocationSize:  VkDeviceSize(t.size),
+		MemoryTypeIndex: memoryTypeIndex,
+	}))
+	defer memoryAllocateInfo.Free()
+	memoryData := atom.Must(atom.AllocData(ctx, s, t.memory))
+	defer memoryData.Free()
+
+	writeEach(ctx, out,
+		NewVkCreateBuffer(
+			t.device,
+			bufferCreateInfo.Ptr(),
+			memory.Pointer{},
+			bufferData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(bufferCreateInfo.Data()).AddWrite(bufferData.Data()),
+		NewVkAllocateMemory(
+			t.device,
+			memoryAllocateInfo.Ptr(),
+			memory.Pointer{},
+			memoryData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(memoryAllocateInfo.Data()).AddWrite(memoryData.Data()),
+		NewVkBindBufferMemory(t.device, t.buffer, t.memory, VkDeviceSize(0), VkResult_VK_SUCCESS),
+	)
+	return true
+}
+
+// post writes the atoms waiting for the submission of the dispatch on queue,
+// posting back the staging buffer and destroying it.
+func (t *dispatchSnapshot) post(ctx context.Context, queue VkQueue, out transform.Writer) {
+	s := out.State()
+	if len(t.resources) == 0 {
+		t.notify(&service.DispatchSnapshot{}, nil)
+		return
+	}
+
+	at, err := s.Allocator.Alloc(t.size, 8)
+	if err != nil {
+		t.notify(nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage("Device Memory -> Host mapping failed")})
+		return
+	}
+	mappedPointer := atom.Must(atom.AllocData(ctx, s, NewVoidᶜᵖ(at)))
+	defer mappedPointer.Free()
+	mappedMemoryRange := atom.Must(atom.AllocData(ctx, s, VkMappedMemoryRange{
+		SType:  VkStructureType_VK_STRUCTURE_TYPE_MAPPED_MEMORY_RANGE,
+		PNext:  NewVoidᶜᵖ(0),
+		Memory: t.memory,
+		Offset: VkDeviceSize(0),
+		Size:   VkDeviceSize(0xFFFFFFFFFFFFFFFF),
+	}))
+	defer mappedMemoryRange.Free()
+
+	writeEach(ctx, out,
+		NewVkQueueWaitIdle(queue, VkResult_VK_SUCCESS),
+		NewVkMapMemory(
+			t.device,
+			t.memory,
+			VkDeviceSize(0),
+			VkDeviceSize(t.size),
+			VkMemoryMapFlags(0),
+			mappedPointer.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddWrite(mappedPointer.Data()),
+		NewVkInvalidateMappedMemoryRanges(
+			t.device,
+			1,
+			mappedMemoryRange.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(mappedMemoryRange.Data()),
+		replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+			b.Post(value.ObservedPointer(at), t.size, func(r pod.Reader, err error) error {
+				if err != nil {
+					err = fmt.Errorf("Could not read the storage resources (expected length %d bytes): %v", t.size, err)
+					t.notify(nil, err)
+					return err
+				}
+				data := make([]byte, t.size)
+				r.Data(data)
+				if err := r.Error(); err != nil {
+					t.notify(nil, err)
+					return err
+				}
+				t.notify(t.snapshot(data), nil)
+				return nil
+			})
+			return nil
+		}),
+		NewVkUnmapMemory(t.device, t.memory),
+		NewVkDestroyBuffer(t.device, t.buffer, memory.Pointer{}),
+		NewVkFreeMemory(t.device, t.memory, memory.Pointer{}),
+	)
+}
+
+// snapshot returns the DispatchSnapshot read from the staging buffer data.
+func (t *dispatchSnapshot) snapshot(data []byte) *service.DispatchSnapshot {
+	out := &service.DispatchSnapshot{}
+	for _, r := range t.resources {
+		before := data[r.before : r.before+r.size]
+		after := data[r.after : r.after+r.size]
+		res := &service.DispatchResource{
+			Set:     r.key.set,
+			Binding: r.key.binding,
+			Name:    t.names[r.key],
+		}
+		if r.image != nil {
+			extent := r.region.ImageExtent
+			res.Content = &service.DispatchResource_Image{Image: &service.DispatchImage{
+				Before: &image.Image2D{Format: r.format, Width: extent.Width, Height: extent.Height, Data: before},
+				After:  &image.Image2D{Format: r.format, Width: extent.Width, Height: extent.Height, Data: after},
+			}}
+		} else {
+			res.Content = &service.DispatchResource_Buffer{Buffer: &service.DispatchBuffer{
+				Before: before,
+				After:  after,
+			}}
+		}
+		out.Resources = append(out.Resources, res)
+	}
+	return out
+}
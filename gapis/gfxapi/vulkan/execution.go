@@ -0,0 +1,170 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+)
+
+// ExecutionOrder implements the atom.DeferredExecutor interface.
+// Commands recorded to command buffers, including the begin and end of the
+// recording, are moved to follow the vkQueueSubmit that submits them.
+// Commands of secondary command buffers follow the vkCmdExecuteCommands that
+// executes them.
+func (api) ExecutionOrder(ctx context.Context, atoms []atom.Atom, order []atom.ID) ([]atom.ID, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := map[VkCommandBuffer][]atom.ID{}
+	isRecorded := map[atom.ID]bool{}
+	secondaries := map[atom.ID][]VkCommandBuffer{}
+	submitted := map[atom.ID][]atom.ID{}
+
+	var expand func(cb VkCommandBuffer) []atom.ID
+	expand = func(cb VkCommandBuffer) []atom.ID {
+		out := []atom.ID{}
+		for _, id := range recorded[cb] {
+			out = append(out, id)
+			for _, scb := range secondaries[id] {
+				out = append(out, expand(scb)...)
+			}
+		}
+		return out
+	}
+	record := func(cb VkCommandBuffer, id atom.ID) {
+		recorded[cb] = append(recorded[cb], id)
+		isRecorded[id] = true
+	}
+
+	s := c.NewState()
+	for i, a := range atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+		}
+		switch a := a.(type) {
+		case *VkBeginCommandBuffer:
+			delete(recorded, a.CommandBuffer)
+			record(a.CommandBuffer, id)
+		case *RecreateAndBeginCommandBuffer:
+			cb := a.PCommandBuffer.Read(ctx, a, s, nil)
+			delete(recorded, cb)
+			record(cb, id)
+		case *VkEndCommandBuffer:
+			record(a.CommandBuffer, id)
+		case *RecreateEndCommandBuffer:
+			record(a.CommandBuffer, id)
+		case *VkResetCommandBuffer:
+			delete(recorded, a.CommandBuffer)
+		case *VkCmdExecuteCommands:
+			record(a.CommandBuffer, id)
+			cbs := a.PCommandBuffers.Slice(0, uint64(a.CommandBufferCount), s)
+			for j := uint64(0); j < cbs.Info().Count; j++ {
+				secondaries[id] = append(secondaries[id], cbs.Index(j, s).Read(ctx, a, s, nil))
+			}
+		case *RecreateCmdExecuteCommands:
+			record(a.CommandBuffer, id)
+			cbs := a.PCommandBuffers.Slice(0, uint64(a.CommandBufferCount), s)
+			for j := uint64(0); j < cbs.Info().Count; j++ {
+				secondaries[id] = append(secondaries[id], cbs.Index(j, s).Read(ctx, a, s, nil))
+			}
+		case *VkQueueSubmit:
+			ids := []atom.ID{}
+			submits := a.PSubmits.Slice(0, uint64(a.SubmitCount), s)
+			for j := uint64(0); j < submits.Info().Count; j++ {
+				submit := submits.Index(j, s).Read(ctx, a, s, nil)
+				cbs := submit.PCommandBuffers.Slice(0, uint64(submit.CommandBufferCount), s)
+				for k := uint64(0); k < cbs.Info().Count; k++ {
+					ids = append(ids, expand(cbs.Index(k, s).Read(ctx, a, s, nil))...)
+				}
+			}
+			submitted[id] = ids
+		default:
+			if cb, ok := recordedCommandBuffer(a); ok {
+				record(cb, id)
+			}
+		}
+	}
+
+	out := make([]atom.ID, 0, len(order))
+	for _, id := range order {
+		if isRecorded[id] {
+			continue
+		}
+		out = append(out, id)
+		out = append(out, submitted[id]...)
+	}
+	return out, nil
+}
+
+// recordedCommandBuffer returns the command buffer that the vkCmd* or
+// RecreateCmd* atom a is recorded to. If a is not recorded to a command
+// buffer then false is returned.
+func recordedCommandBuffer(a atom.Atom) (VkCommandBuffer, bool) {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return VkCommandBuffer(0), false
+	}
+	name := v.Elem().Type().Name()
+	if !strings.HasPrefix(name, "VkCmd") && !strings.HasPrefix(name, "RecreateCmd") {
+		return VkCommandBuffer(0), false
+	}
+	f := v.Elem().FieldByName("CommandBuffer")
+	if !f.IsValid() {
+		return VkCommandBuffer(0), false
+	}
+	cb, ok := f.Interface().(VkCommandBuffer)
+	return cb, ok
+}
+
+// ExecutionKind implements the atom.DeferredExecutor interface.
+func (api) ExecutionKind(a atom.Atom) atom.ExecutionKind {
+	switch a.(type) {
+	case *VkCmdBeginRenderPass, *RecreateCmdBeginRenderPass:
+		return atom.BeginPass
+	case *VkCmdEndRenderPass, *RecreateCmdEndRenderPass:
+		return atom.EndPass
+	case *VkQueueSubmit:
+		return atom.Submit
+	case *VkCmdDraw, *RecreateCmdDraw,
+		*VkCmdDrawIndexed, *RecreateCmdDrawIndexed,
+		*VkCmdDrawIndirect, *RecreateCmdDrawIndirect,
+		*VkCmdDrawIndexedIndirect, *RecreateCmdDrawIndexedIndirect,
+		*VkCmdDispatch, *RecreateCmdDispatch,
+		*VkCmdDispatchIndirect, *RecreateCmdDispatchIndirect,
+		*VkCmdCopyBuffer, *RecreateCmdCopyBuffer,
+		*VkCmdCopyImage, *RecreateCmdCopyImage,
+		*VkCmdBlitImage, *RecreateCmdBlitImage,
+		*VkCmdCopyBufferToImage, *RecreateCmdCopyBufferToImage,
+		*VkCmdCopyImageToBuffer, *RecreateCmdCopyImageToBuffer,
+		*VkCmdUpdateBuffer, *RecreateCmdUpdateBuffer,
+		*VkCmdFillBuffer, *RecreateCmdFillBuffer,
+		*VkCmdClearColorImage, *RecreateCmdClearColorImage,
+		*VkCmdClearDepthStencilImage, *RecreateCmdClearDepthStencilImage,
+		*VkCmdClearAttachments, *RecreateCmdClearAttachments,
+		*VkCmdResolveImage, *RecreateCmdResolveImage,
+		*VkCmdCopyQueryPoolResults, *RecreateCmdCopyQueryPoolResults:
+		return atom.Action
+	}
+	return atom.Other
+}
@@ -0,0 +1,560 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/experiments"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+func init() {
+	experiments.DisableDraws.Implement(api{}, disableDrawsExperiment)
+	experiments.PresentMode.Implement(api{}, presentModeExperiment)
+	experiments.Texture1x1.Implement(api{}, texture1x1Experiment)
+	experiments.SimpleShader.Implement(api{}, simpleShaderExperiment)
+	experiments.ResolutionScale.Implement(api{}, resolutionScaleExperiment)
+}
+
+// disableDrawsExperiment drops the recording of the draw commands listed by
+// the commands parameter, or of all draw commands if the parameter is not set.
+func disableDrawsExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	ids, err := p.IDs("commands")
+	if err != nil {
+		return nil, err
+	}
+	return transform.Transform("DisableDraws", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		switch a.(type) {
+		case *VkCmdDraw, *VkCmdDrawIndexed, *VkCmdDrawIndirect, *VkCmdDrawIndexedIndirect,
+			*RecreateCmdDraw, *RecreateCmdDrawIndexed, *RecreateCmdDrawIndirect, *RecreateCmdDrawIndexedIndirect:
+			if ids == nil || ids[i] {
+				return
+			}
+		}
+		out.MutateAndWrite(ctx, i, a)
+	}), nil
+}
+
+var presentModes = map[string]VkPresentModeKHR{
+	"immediate":    VkPresentModeKHR_VK_PRESENT_MODE_IMMEDIATE_KHR,
+	"mailbox":      VkPresentModeKHR_VK_PRESENT_MODE_MAILBOX_KHR,
+	"fifo":         VkPresentModeKHR_VK_PRESENT_MODE_FIFO_KHR,
+	"fifo_relaxed": VkPresentModeKHR_VK_PRESENT_MODE_FIFO_RELAXED_KHR,
+}
+
+// presentModeExperiment changes the present mode of every swapchain created
+// to the one named by the mode parameter.
+func presentModeExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	name := strings.ToLower(p.String("mode", "fifo"))
+	mode, ok := presentModes[name]
+	if !ok {
+		return nil, experiments.Invalid("mode", "must be one of immediate, mailbox, fifo or fifo_relaxed")
+	}
+	return transform.Transform("PresentMode", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		create, ok := a.(*VkCreateSwapchainKHR)
+		if !ok {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		s := out.State()
+		create.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		info := create.PCreateInfo.Read(ctx, create, s, nil)
+		if info.PresentMode == mode {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		info.PresentMode = mode
+		newInfo := atom.Must(atom.AllocData(ctx, s, info))
+		newAtom := NewVkCreateSwapchainKHR(create.Device, newInfo.Ptr(),
+			memory.Pointer(create.PAllocator), memory.Pointer(create.PSwapchain), create.Result)
+		for _, e := range create.Extras().All() {
+			if _, ok := e.(*atom.Observations); !ok {
+				newAtom.Extras().Add(e)
+			}
+		}
+		observations := create.Extras().Observations()
+		for _, r := range observations.Reads {
+			newAtom.AddRead(r.Range, r.ID)
+		}
+		// The new create info must be read after the original observations.
+		newAtom.AddRead(newInfo.Data())
+		for _, w := range observations.Writes {
+			newAtom.AddWrite(w.Range, w.ID)
+		}
+		out.MutateAndWrite(ctx, i, newAtom)
+	}), nil
+}
+
+// resolutionScaleExperiment scales the viewports, the scissor rectangles, the
+// render areas of the render passes and the extent of the framebuffers by the
+// scale parameter. The images keep their size so that the copies from and to
+// them remain valid, only their scaled top-left part is rendered to.
+func resolutionScaleExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	scale, err := p.Float("scale", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if scale <= 0 || scale > 1 {
+		return nil, experiments.Invalid("scale", "the scale must be in the range (0, 1]")
+	}
+	size := func(v uint32) uint32 {
+		if v == 0 {
+			return 0
+		}
+		if scaled := uint32(float64(v) * scale); scaled > 0 {
+			return scaled
+		}
+		return 1
+	}
+	scaleViewport := func(v VkViewport) VkViewport {
+		v.X, v.Y = float32(float64(v.X)*scale), float32(float64(v.Y)*scale)
+		v.Width, v.Height = float32(float64(v.Width)*scale), float32(float64(v.Height)*scale)
+		return v
+	}
+	scaleRect := func(r VkRect2D) VkRect2D {
+		r.Offset.X, r.Offset.Y = int32(float64(r.Offset.X)*scale), int32(float64(r.Offset.Y)*scale)
+		r.Extent.Width, r.Extent.Height = size(r.Extent.Width), size(r.Extent.Height)
+		return r
+	}
+	return transform.Transform("ResolutionScale", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		s := out.State()
+		a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		alloc := func(v interface{}) atom.AllocResult { return atom.Must(atom.AllocData(ctx, s, v)) }
+
+		switch a := a.(type) {
+		case *VkCmdSetViewport:
+			viewports := a.PViewports.Slice(0, uint64(a.ViewportCount), s).Read(ctx, a, s, nil)
+			for j := range viewports {
+				viewports[j] = scaleViewport(viewports[j])
+			}
+			data := alloc(viewports)
+			newAtom := NewVkCmdSetViewport(a.CommandBuffer, a.FirstViewport, a.ViewportCount, data.Ptr())
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *RecreateCmdSetViewport:
+			viewports := a.PViewports.Slice(0, uint64(a.ViewportCount), s).Read(ctx, a, s, nil)
+			for j := range viewports {
+				viewports[j] = scaleViewport(viewports[j])
+			}
+			data := alloc(viewports)
+			newAtom := NewRecreateCmdSetViewport(a.CommandBuffer, a.FirstViewport, a.ViewportCount, data.Ptr())
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *VkCmdSetScissor:
+			scissors := a.PScissors.Slice(0, uint64(a.ScissorCount), s).Read(ctx, a, s, nil)
+			for j := range scissors {
+				scissors[j] = scaleRect(scissors[j])
+			}
+			data := alloc(scissors)
+			newAtom := NewVkCmdSetScissor(a.CommandBuffer, a.FirstScissor, a.ScissorCount, data.Ptr())
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *RecreateCmdSetScissor:
+			scissors := a.PScissors.Slice(0, uint64(a.ScissorCount), s).Read(ctx, a, s, nil)
+			for j := range scissors {
+				scissors[j] = scaleRect(scissors[j])
+			}
+			data := alloc(scissors)
+			newAtom := NewRecreateCmdSetScissor(a.CommandBuffer, a.FirstScissor, a.ScissorCount, data.Ptr())
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *VkCmdBeginRenderPass:
+			info := a.PRenderPassBegin.Read(ctx, a, s, nil)
+			info.RenderArea = scaleRect(info.RenderArea)
+			data := alloc(info)
+			newAtom := NewVkCmdBeginRenderPass(a.CommandBuffer, data.Ptr(), a.Contents)
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *RecreateCmdBeginRenderPass:
+			info := a.PRenderPassBegin.Read(ctx, a, s, nil)
+			info.RenderArea = scaleRect(info.RenderArea)
+			data := alloc(info)
+			newAtom := NewRecreateCmdBeginRenderPass(a.CommandBuffer, data.Ptr(), a.Contents)
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *VkCreateFramebuffer:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			info.Width, info.Height = size(info.Width), size(info.Height)
+			data := alloc(info)
+			newAtom := NewVkCreateFramebuffer(a.Device, data.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PFramebuffer), a.Result)
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *RecreateFramebuffer:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			info.Width, info.Height = size(info.Width), size(info.Height)
+			data := alloc(info)
+			newAtom := NewRecreateFramebuffer(a.Device, data.Ptr(), memory.Pointer(a.PFramebuffer))
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, data))
+		case *VkCreateGraphicsPipelines:
+			infos := a.PCreateInfos.Slice(0, uint64(a.CreateInfoCount), s).Read(ctx, a, s, nil)
+			data := []atom.AllocResult{}
+			for j := range infos {
+				if viewport, ok := scaledViewportState(ctx, s, a, infos[j], scaleViewport, scaleRect); ok {
+					infos[j].PViewportState = VkPipelineViewportStateCreateInfoᶜᵖ(viewport[len(viewport)-1].Ptr())
+					data = append(data, viewport...)
+				}
+			}
+			newInfos := alloc(infos)
+			newAtom := NewVkCreateGraphicsPipelines(a.Device, a.PipelineCache, a.CreateInfoCount,
+				newInfos.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PPipelines), a.Result)
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, append(data, newInfos)...))
+		case *RecreateGraphicsPipeline:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			viewport, ok := scaledViewportState(ctx, s, a, info, scaleViewport, scaleRect)
+			if !ok {
+				out.MutateAndWrite(ctx, i, a)
+				return
+			}
+			info.PViewportState = VkPipelineViewportStateCreateInfoᶜᵖ(viewport[len(viewport)-1].Ptr())
+			newInfo := alloc(info)
+			newAtom := NewRecreateGraphicsPipeline(a.Device, a.PipelineCache, newInfo.Ptr(), memory.Pointer(a.PPipeline))
+			out.MutateAndWrite(ctx, i, withExtrasOf(a, newAtom, append(viewport, newInfo)...))
+		default:
+			out.MutateAndWrite(ctx, i, a)
+		}
+	}), nil
+}
+
+// scaledViewportState returns the copies of the static viewports and scissor
+// rectangles of the pipeline created with info, scaled with scaleViewport and
+// scaleRect, followed by the copy of its viewport state pointing to them. It
+// returns false if the pipeline has no viewport state, or if both its
+// viewports and scissor rectangles are dynamic.
+func scaledViewportState(
+	ctx context.Context,
+	s *gfxapi.State,
+	a atom.Atom,
+	info VkGraphicsPipelineCreateInfo,
+	scaleViewport func(VkViewport) VkViewport,
+	scaleRect func(VkRect2D) VkRect2D) ([]atom.AllocResult, bool) {
+
+	if info.PViewportState.Address == 0 {
+		return nil, false
+	}
+	dynamicViewport, dynamicScissor := false, false
+	if info.PDynamicState.Address != 0 {
+		dynamic := info.PDynamicState.Read(ctx, a, s, nil)
+		for _, d := range dynamic.PDynamicStates.Slice(0, uint64(dynamic.DynamicStateCount), s).Read(ctx, a, s, nil) {
+			switch d {
+			case VkDynamicState_VK_DYNAMIC_STATE_VIEWPORT:
+				dynamicViewport = true
+			case VkDynamicState_VK_DYNAMIC_STATE_SCISSOR:
+				dynamicScissor = true
+			}
+		}
+	}
+	if dynamicViewport && dynamicScissor {
+		return nil, false
+	}
+
+	state := info.PViewportState.Read(ctx, a, s, nil)
+	out := []atom.AllocResult{}
+	if !dynamicViewport && state.PViewports.Address != 0 {
+		viewports := state.PViewports.Slice(0, uint64(state.ViewportCount), s).Read(ctx, a, s, nil)
+		for i := range viewports {
+			viewports[i] = scaleViewport(viewports[i])
+		}
+		data := atom.Must(atom.AllocData(ctx, s, viewports))
+		state.PViewports = VkViewportᶜᵖ(data.Ptr())
+		out = append(out, data)
+	}
+	if !dynamicScissor && state.PScissors.Address != 0 {
+		scissors := state.PScissors.Slice(0, uint64(state.ScissorCount), s).Read(ctx, a, s, nil)
+		for i := range scissors {
+			scissors[i] = scaleRect(scissors[i])
+		}
+		data := atom.Must(atom.AllocData(ctx, s, scissors))
+		state.PScissors = VkRect2Dᶜᵖ(data.Ptr())
+		out = append(out, data)
+	}
+	return append(out, atom.Must(atom.AllocData(ctx, s, state))), true
+}
+
+// texture1x1Experiment makes the image views of the sampled images view 1x1
+// images instead, with the same format, type and number of layers. The 1x1
+// images hold a zeroed texel, and are in the shader read-only layout.
+//
+// Only the images that are not also attachments or storage images are
+// replaced, so that the rendering writes the same images.
+func texture1x1Experiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	images := map[texture1x1Key]VkImage{}
+	return transform.Transform("Texture1x1", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		var device VkDevice
+		var pInfo VkImageViewCreateInfoᶜᵖ
+		var pView memory.Pointer
+		switch a := a.(type) {
+		case *VkCreateImageView:
+			device, pInfo, pView = a.Device, a.PCreateInfo, memory.Pointer(a.PView)
+		case *RecreateImageView:
+			device, pInfo, pView = a.Device, a.PCreateInfo, memory.Pointer(a.PImageView)
+		default:
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		s := out.State()
+		st := GetState(s)
+		a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		info := pInfo.Read(ctx, a, s, nil)
+		if info.PNext != (Voidᶜᵖ{}) || !st.Images.Contains(info.Image) || !isSampledOnly(st.Images.Get(info.Image)) {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		if _, err := getImageFormatFromVulkanFormat(info.Format); err != nil {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+
+		original := st.Images.Get(info.Image)
+		layers := info.SubresourceRange.LayerCount
+		if layers == 0xFFFFFFFF {
+			layers = original.Info.ArrayLayers - info.SubresourceRange.BaseArrayLayer
+		}
+		key := texture1x1Key{
+			device:    device,
+			format:    info.Format,
+			imageType: original.Info.ImageType,
+			layers:    layers,
+			flags:     original.Info.Flags & VkImageCreateFlags(VkImageCreateFlagBits_VK_IMAGE_CREATE_CUBE_COMPATIBLE_BIT),
+		}
+		image, ok := images[key]
+		if !ok || !st.Images.Contains(image) {
+			if image, ok = createTexture1x1(ctx, out, key); !ok {
+				out.MutateAndWrite(ctx, i, a)
+				return
+			}
+			images[key] = image
+		}
+
+		info.Image = image
+		info.SubresourceRange.BaseMipLevel = 0
+		info.SubresourceRange.LevelCount = 1
+		info.SubresourceRange.BaseArrayLayer = 0
+		info.SubresourceRange.LayerCount = layers
+		newInfo := atom.Must(atom.AllocData(ctx, s, info))
+		defer newInfo.Free()
+		newAtom := NewVkCreateImageView(device, newInfo.Ptr(), memory.Pointer{}, pView, VkResult_VK_SUCCESS)
+		for _, e := range a.Extras().All() {
+			if _, ok := e.(*atom.Observations); !ok {
+				newAtom.Extras().Add(e)
+			}
+		}
+		observations := a.Extras().Observations()
+		for _, r := range observations.Reads {
+			newAtom.AddRead(r.Range, r.ID)
+		}
+		// The new create info must be read after the original observations.
+		newAtom.AddRead(newInfo.Data())
+		for _, w := range observations.Writes {
+			newAtom.AddWrite(w.Range, w.ID)
+		}
+		out.MutateAndWrite(ctx, i, newAtom)
+	}), nil
+}
+
+// texture1x1Key identifies the 1x1 images created by texture1x1Experiment.
+type texture1x1Key struct {
+	device    VkDevice
+	format    VkFormat
+	imageType VkImageType
+	layers    uint32
+	flags     VkImageCreateFlags
+}
+
+// isSampledOnly returns true if the image is sampled, and is neither an
+// attachment nor a storage image.
+func isSampledOnly(image *ImageObject) bool {
+	written := VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT |
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT |
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_INPUT_ATTACHMENT_BIT |
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_STORAGE_BIT)
+	usage := image.Info.Usage
+	return usage&VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT) != 0 &&
+		usage&written == 0 &&
+		image.Info.Samples == VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT &&
+		!image.IsSwapchainImage
+}
+
+// createTexture1x1 writes the atoms creating the 1x1 image identified by key,
+// returning false if the device has no queue or host visible memory to
+// initialize it.
+func createTexture1x1(ctx context.Context, out transform.Writer, key texture1x1Key) (VkImage, bool) {
+	s := out.State()
+	st := GetState(s)
+	queue, ok := deviceQueue(st, key.device, nil)
+	if !ok {
+		return VkImage(0), false
+	}
+	hostMemory, ok := hostMemoryType(st, key.device)
+	if !ok {
+		return VkImage(0), false
+	}
+	format, err := getImageFormatFromVulkanFormat(key.format)
+	if err != nil {
+		return VkImage(0), false
+	}
+	physicalDevice := st.PhysicalDevices.Get(st.Devices.Get(key.device).PhysicalDevice)
+
+	image := VkImage(newUnusedID(false, func(x uint64) bool { return st.Images.Contains(VkImage(x)) }))
+	deviceMemory := VkDeviceMemory(newUnusedID(false, func(x uint64) bool { return st.DeviceMemories.Contains(VkDeviceMemory(x)) }))
+
+	createInfo := MustAllocData(ctx, s, VkImageCreateInfo{
+		SType:                 VkStructureType_VK_STRUCTURE_TYPE_IMAGE_CREATE_INFO,
+		PNext:                 NewVoidᶜᵖ(0),
+		Flags:                 key.flags,
+		ImageType:             key.imageType,
+		Format:                key.format,
+		Extent:                VkExtent3D{Width: 1, Height: 1, Depth: 1},
+		MipLevels:             1,
+		ArrayLayers:           key.layers,
+		Samples:               VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT,
+		Tiling:                VkImageTiling_VK_IMAGE_TILING_OPTIMAL,
+		Usage:                 VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT | VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT),
+		SharingMode:           VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
+		QueueFamilyIndexCount: 0,
+		PQueueFamilyIndices:   NewU32ᶜᵖ(0),
+		InitialLayout:         VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED,
+	})
+	defer createInfo.Free()
+	imageData := MustAllocData(ctx, s, image)
+	defer imageData.Free()
+	memoryProperties := MustAllocData(ctx, s, physicalDevice.MemoryProperties)
+	defer memoryProperties.Free()
+	memoryData := MustAllocData(ctx, s, deviceMemory)
+	defer memoryData.Free()
+	texels := MustAllocData(ctx, s, make([]byte, format.Size(1, 1)*int(key.layers)))
+	defer texels.Free()
+
+	writeEach(ctx, out,
+		NewVkCreateImage(
+			key.device,
+			createInfo.Ptr(),
+			memory.Pointer{},
+			imageData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(createInfo.Data()).AddWrite(imageData.Data()),
+		NewReplayAllocateImageMemory(
+			key.device,
+			memoryProperties.Ptr(),
+			image,
+			memoryData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(memoryProperties.Data()).AddWrite(memoryData.Data()),
+		NewVkBindImageMemory(key.device, image, deviceMemory, VkDeviceSize(0), VkResult_VK_SUCCESS),
+		NewRecreateImageData(
+			key.device,
+			image,
+			VkImageLayout_VK_IMAGE_LAYOUT_SHADER_READ_ONLY_OPTIMAL,
+			hostMemory,
+			queue,
+			VkDeviceSize(texels.Data().Size),
+			texels.Ptr(),
+		).AddRead(texels.Data()),
+	)
+	return image, true
+}
+
+// constantColorShader is the fragment shader writing a constant color used by
+// simpleShaderExperiment.
+const constantColorShader = `#version 450
+layout(location = 0) out vec4 color;
+void main() {
+  color = vec4(1.0, 0.0, 1.0, 1.0);
+}
+`
+
+// The SPIR-V opcode of the entry points, the execution model of the fragment
+// shaders, and the index of the first word of the entry point names.
+const (
+	spirvOpEntryPoint            = 15
+	spirvExecutionModelFragment  = 4
+	spirvEntryPointNameWordIndex = 3
+)
+
+// simpleShaderExperiment replaces the code of the shader modules holding a
+// single fragment shader entry point, named main, with a shader writing a
+// constant color to the first color attachment.
+func simpleShaderExperiment(ctx context.Context, p experiments.Parameters) (transform.Transformer, error) {
+	words, err := shadertools.CompileGlsl(constantColorShader, shadertools.StageFragment)
+	if err != nil {
+		return nil, err
+	}
+	return transform.Transform("SimpleShader", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		var pInfo VkShaderModuleCreateInfoᶜᵖ
+		switch a := a.(type) {
+		case *VkCreateShaderModule:
+			pInfo = a.PCreateInfo
+		case *RecreateShaderModule:
+			pInfo = a.PCreateInfo
+		default:
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		s := out.State()
+		a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		info := pInfo.Read(ctx, a, s, nil)
+		code := info.PCode.Slice(0, info.CodeSize/4, s).Read(ctx, a, s, nil)
+		if !isFragmentMain(code) {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+
+		newInfo, newCode := newShaderModuleCreateInfo(ctx, s, info, words)
+		defer newInfo.Free()
+		defer newCode.Free()
+		var newAtom atom.Atom
+		switch a := a.(type) {
+		case *VkCreateShaderModule:
+			newAtom = NewVkCreateShaderModule(a.Device, newInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PShaderModule), a.Result)
+		case *RecreateShaderModule:
+			newAtom = NewRecreateShaderModule(a.Device, newInfo.Ptr(), memory.Pointer(a.PShaderModule))
+		}
+		for _, e := range a.Extras().All() {
+			if _, ok := e.(*atom.Observations); !ok {
+				newAtom.Extras().Add(e)
+			}
+		}
+		observations := newAtom.Extras().GetOrAppendObservations()
+		observations.AddRead(newInfo.Data())
+		observations.AddRead(newCode.Data())
+		for _, w := range a.Extras().Observations().Writes {
+			observations.AddWrite(w.Range, w.ID)
+		}
+		out.MutateAndWrite(ctx, i, newAtom)
+	}), nil
+}
+
+// isFragmentMain returns true if the SPIR-V module code has a single entry
+// point, which is a fragment shader named main.
+func isFragmentMain(code []uint32) bool {
+	found := false
+	for i := spirvHeaderWords; i < len(code); {
+		count, op := int(code[i]>>16), code[i]&0xffff
+		if count == 0 || i+count > len(code) {
+			return false
+		}
+		if op == spirvOpEntryPoint {
+			if found || count < spirvEntryPointNameWordIndex+2 || code[i+1] != spirvExecutionModelFragment {
+				return false
+			}
+			// "main" followed by its terminator, in little-endian words.
+			name := code[i+spirvEntryPointNameWordIndex:]
+			if name[0] != 0x6e69616d || name[1] != 0 {
+				return false
+			}
+			found = true
+		}
+		i += count
+	}
+	return found
+}
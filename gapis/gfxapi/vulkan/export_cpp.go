@@ -0,0 +1,205 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/replay/cpp"
+)
+
+// exportLoopLabel is the id of the atom preceding the atoms repeated by the
+// exported programs. It is the largest id labelled by the replay builder.
+const exportLoopLabel = atom.ID(0x3ffffff)
+
+// exportConfig is a replay.Config used by exportRequests.
+type exportConfig struct{}
+
+// exportRequest requests the payload of a replay priming the state at the
+// start of a frame, and replaying the frame twice.
+type exportRequest struct {
+	end atom.ID // The last atom of the frame.
+}
+
+var _ = replay.ExportCpp(api{})
+
+// ExportCpp returns the source of a C++ program performing the replay of the
+// capture up to the end of the frame ending with the atom end, then repeating
+// the frame. The program links against the Vulkan loader, and uses the
+// VirtualSwapchain layer when it is installed.
+func (a api) ExportCpp(ctx context.Context, intent replay.Intent, mgr *replay.Manager, end atom.ID) ([]byte, error) {
+	payload, layout, err := mgr.Export(ctx, intent, exportConfig{}, exportRequest{end: end}, a)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([][]byte, len(payload.Resources))
+	for i, r := range payload.Resources {
+		rid, err := id.Parse(r.ID)
+		if err != nil {
+			return nil, log.Errf(ctx, err, "Invalid resource identifier %v", r.ID)
+		}
+		obj, err := database.Resolve(ctx, rid)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := obj.([]byte)
+		if !ok {
+			return nil, log.Errf(ctx, nil, "Resource %v is not a byte slice", r.ID)
+		}
+		resources[i] = data
+	}
+
+	functions := map[uint16]cpp.Function{}
+	index := uint8(0)
+	for info, name := range builderFunctionNames {
+		index = info.ApiIndex
+		functions[info.ID] = cpp.Function{
+			Name:       name,
+			Parameters: info.Parameters,
+			ReturnType: info.ReturnType,
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	err = cpp.Generate(cpp.Program{
+		Payload:   payload,
+		ByteOrder: layout.Endian,
+		Resources: resources,
+		Function: func(apiIndex uint8, functionID uint16) (cpp.Function, bool) {
+			f, ok := functions[functionID]
+			return f, ok && apiIndex == index
+		},
+		Prelude: cppPrelude,
+		Loop:    uint32(exportLoopLabel),
+	}, buf)
+	if err != nil {
+		return nil, log.Err(ctx, err, "Failed to generate the C++ program")
+	}
+	return buf.Bytes(), nil
+}
+
+// cppPrelude implements the functions only known to the replay system, and
+// looks up the Vulkan functions through the loader.
+const cppPrelude = `
+#include <string.h>
+#include <string>
+#include <unordered_map>
+#include <vector>
+
+#include <vulkan/vulkan.h>
+
+#define GAPID_API VKAPI_PTR
+
+static VkInstance gapid_instance = VK_NULL_HANDLE;
+
+// Replaces the capture layer with the VirtualSwapchain layer, when installed,
+// as the surfaces of the capture may not exist on this machine.
+static VkResult VKAPI_PTR replayCreateVkInstance(VkInstanceCreateInfo* pCreateInfo, const VkAllocationCallbacks* pAllocator, VkInstance* pInstance) {
+  uint32_t count = 0;
+  vkEnumerateInstanceLayerProperties(&count, nullptr);
+  std::vector<VkLayerProperties> properties(count);
+  vkEnumerateInstanceLayerProperties(&count, properties.data());
+  bool virtualSwapchain = false;
+  for (const auto& p : properties) {
+    virtualSwapchain = virtualSwapchain || strcmp(p.layerName, "VirtualSwapchain") == 0;
+  }
+  std::vector<const char*> layers;
+  for (uint32_t i = 0; i < pCreateInfo->enabledLayerCount; i++) {
+    if (strcmp(pCreateInfo->ppEnabledLayerNames[i], "VkGraphicsSpy") != 0) {
+      layers.push_back(pCreateInfo->ppEnabledLayerNames[i]);
+    }
+  }
+  if (virtualSwapchain) {
+    layers.push_back("VirtualSwapchain");
+  }
+  VkInstanceCreateInfo info = *pCreateInfo;
+  info.pNext = nullptr;
+  info.enabledLayerCount = static_cast<uint32_t>(layers.size());
+  info.ppEnabledLayerNames = layers.data();
+  return vkCreateInstance(&info, pAllocator, pInstance);
+}
+
+static VkResult VKAPI_PTR ReplayCreateVkDevice(VkPhysicalDevice physicalDevice, VkDeviceCreateInfo* pCreateInfo, const VkAllocationCallbacks* pAllocator, VkDevice* pDevice) {
+  VkDeviceCreateInfo info = *pCreateInfo;
+  info.pNext = nullptr;
+  return vkCreateDevice(physicalDevice, &info, pAllocator, pDevice);
+}
+
+static void VKAPI_PTR replayRegisterVkInstance(VkInstance instance) {
+  gapid_instance = instance;
+}
+
+static void VKAPI_PTR replayUnregisterVkInstance(VkInstance instance) {
+  if (gapid_instance == instance) {
+    gapid_instance = VK_NULL_HANDLE;
+  }
+}
+
+static void VKAPI_PTR replayRegisterVkDevice(VkPhysicalDevice, VkDevice, VkDeviceCreateInfo*) {}
+static void VKAPI_PTR replayUnregisterVkDevice(VkDevice) {}
+static void VKAPI_PTR replayRegisterVkCommandBuffers(VkDevice, uint32_t, VkCommandBuffer*) {}
+static void VKAPI_PTR replayUnregisterVkCommandBuffers(uint32_t, VkCommandBuffer*) {}
+
+// Requires the internals of the VirtualSwapchain layer, the acquired images
+// are returned in the order of the driver.
+static void VKAPI_PTR toggleVirtualSwapchainReturnAcquiredImage(VkSwapchainKHR*) {}
+
+static VkResult VKAPI_PTR replayGetFenceStatus(VkDevice device, VkFence fence, VkResult expected) {
+  if (expected != VK_SUCCESS) {
+    return vkGetFenceStatus(device, fence);
+  }
+  return vkWaitForFences(device, 1, &fence, VK_TRUE, UINT64_MAX);
+}
+
+static VkResult VKAPI_PTR replayAllocateImageMemory(VkDevice device, VkPhysicalDeviceMemoryProperties* pProperties, VkImage image, VkDeviceMemory* pMemory) {
+  VkMemoryRequirements requirements;
+  vkGetImageMemoryRequirements(device, image, &requirements);
+  uint32_t index = 0;
+  while (index < pProperties->memoryTypeCount && (requirements.memoryTypeBits & (1u << index)) == 0) {
+    index++;
+  }
+  VkMemoryAllocateInfo info = {VK_STRUCTURE_TYPE_MEMORY_ALLOCATE_INFO, nullptr, requirements.size, index};
+  return vkAllocateMemory(device, &info, nullptr, pMemory);
+}
+
+static void* gapid_proc(const char* name) {
+  static const std::unordered_map<std::string, void*> builtins = {
+    {"replayCreateVkInstance", reinterpret_cast<void*>(&replayCreateVkInstance)},
+    {"ReplayCreateVkDevice", reinterpret_cast<void*>(&ReplayCreateVkDevice)},
+    {"replayRegisterVkInstance", reinterpret_cast<void*>(&replayRegisterVkInstance)},
+    {"replayUnregisterVkInstance", reinterpret_cast<void*>(&replayUnregisterVkInstance)},
+    {"replayRegisterVkDevice", reinterpret_cast<void*>(&replayRegisterVkDevice)},
+    {"replayUnregisterVkDevice", reinterpret_cast<void*>(&replayUnregisterVkDevice)},
+    {"replayRegisterVkCommandBuffers", reinterpret_cast<void*>(&replayRegisterVkCommandBuffers)},
+    {"replayUnregisterVkCommandBuffers", reinterpret_cast<void*>(&replayUnregisterVkCommandBuffers)},
+    {"toggleVirtualSwapchainReturnAcquiredImage", reinterpret_cast<void*>(&toggleVirtualSwapchainReturnAcquiredImage)},
+    {"replayGetFenceStatus", reinterpret_cast<void*>(&replayGetFenceStatus)},
+    {"replayAllocateImageMemory", reinterpret_cast<void*>(&replayAllocateImageMemory)},
+  };
+  auto it = builtins.find(name);
+  if (it != builtins.end()) {
+    return it->second;
+  }
+  // The loader dispatches the device functions on their first parameter.
+  return reinterpret_cast<void*>(vkGetInstanceProcAddr(gapid_instance, name));
+}
+`
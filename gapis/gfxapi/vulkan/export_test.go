@@ -0,0 +1,44 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+)
+
+// NewReadFramebuffer exposes the framebuffer reading transform to the tests of
+// the vulkan_test package, whose samples import this package.
+var NewReadFramebuffer = newReadFramebuffer
+
+// Overdraw exposes the overdraw counting transform to the tests of the
+// vulkan_test package.
+var Overdraw = overdraw
+
+// NewStatePrimer exposes the state priming transform to the tests of the
+// vulkan_test package. It returns nil if the state cannot be primed.
+func NewStatePrimer(ctx context.Context, atoms []atom.Atom, requested []atom.ID) (transform.Transformer, error) {
+	p, err := newStatePrimer(ctx, atoms, requested)
+	if p == nil || err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ImageDataCopies exposes the copies of the image data primed by
+// RecreateImageData to the tests of the vulkan_test package.
+var ImageDataCopies = imageDataCopies
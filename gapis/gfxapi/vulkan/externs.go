@@ -21,6 +21,7 @@ import (
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
 	rb "github.com/google/gapid/gapis/replay/builder"
 	"github.com/google/gapid/gapis/replay/protocol"
 )
@@ -137,6 +138,28 @@ func (e externs) readMappedCoherentMemory(memory_handle VkDeviceMemory, offset_i
 }
 func (e externs) untrackMappedCoherentMemory(start uint64, size uint64) {}
 
+func (e externs) onDebugUtilsMessage(severity VkDebugUtilsMessageSeverityFlagBitsEXT, message string) {
+	// Call the state's callback function for message.
+	if f := e.s.NewMessage; f != nil {
+		f(debugUtilsSeverity(severity), messages.ErrMessage(message))
+	}
+}
+
+// debugUtilsSeverity maps the severity of a debug utils message to a log
+// severity.
+func debugUtilsSeverity(severity VkDebugUtilsMessageSeverityFlagBitsEXT) log.Severity {
+	switch {
+	case severity&VkDebugUtilsMessageSeverityFlagBitsEXT_VK_DEBUG_UTILS_MESSAGE_SEVERITY_ERROR_BIT_EXT != 0:
+		return log.Error
+	case severity&VkDebugUtilsMessageSeverityFlagBitsEXT_VK_DEBUG_UTILS_MESSAGE_SEVERITY_WARNING_BIT_EXT != 0:
+		return log.Warning
+	case severity&VkDebugUtilsMessageSeverityFlagBitsEXT_VK_DEBUG_UTILS_MESSAGE_SEVERITY_INFO_BIT_EXT != 0:
+		return log.Info
+	default:
+		return log.Debug
+	}
+}
+
 func (e externs) numberOfPNext(pNext Voidᶜᵖ) uint32 {
 	counter := uint32(0)
 	for (pNext) != (Voidᶜᵖ{}) {
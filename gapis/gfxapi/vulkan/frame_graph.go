@@ -0,0 +1,195 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.FrameGrapher(api{})
+
+// FramePasses returns the render passes, dispatches and transfers executed by
+// the capture held by ctx, with the resources they access as found by the
+// dependency graph. The binding commands recorded outside of the passes are
+// accounted to the passes that follow them, up to the next binding command
+// following a pass.
+func (api) FramePasses(ctx context.Context) ([]atom.FramePass, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	atoms, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := &framePassBuilder{
+		atoms:       atoms.Atoms,
+		attachments: map[atom.ID][]gfxapi.FramebufferAttachment{},
+	}
+	g, err := buildDependencyGraph(ctx, c, b)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]atom.FramePass, len(b.passes))
+	for i, p := range b.passes {
+		out[i] = atom.FramePass{
+			Kind:        p.kind,
+			First:       p.first,
+			Last:        p.last,
+			Attachments: b.attachments[p.first],
+			Reads:       frameResources(g, p.reads),
+			Writes:      frameResources(g, p.writes),
+		}
+	}
+	return out, nil
+}
+
+// framePassBuilder is a dependencyGraphObserver that splits the executed
+// commands into passes.
+type framePassBuilder struct {
+	atoms       []atom.Atom
+	attachments map[atom.ID][]gfxapi.FramebufferAttachment
+	passes      []*framePass
+	current     *framePass    // The render pass being executed, if any.
+	bound       AtomBehaviour // The behaviours of the binding commands.
+	consumed    bool          // True once bound has been used by a pass.
+}
+
+type framePass struct {
+	kind          atom.FramePassKind
+	first, last   atom.ID
+	reads, writes []StateAddress
+}
+
+func (p *framePass) add(b *AtomBehaviour) {
+	p.reads = append(p.reads, b.Read...)
+	p.reads = append(p.reads, b.Modify...)
+	p.writes = append(p.writes, b.Modify...)
+	p.writes = append(p.writes, b.Write...)
+}
+
+func (f *framePassBuilder) mutated(ctx context.Context, s *gfxapi.State, id atom.ID, a atom.Atom) {
+	var begin VkRenderPassBeginInfo
+	switch a := a.(type) {
+	case *VkCmdBeginRenderPass:
+		begin = a.PRenderPassBegin.Read(ctx, a, s, nil)
+	case *RecreateCmdBeginRenderPass:
+		begin = a.PRenderPassBegin.Read(ctx, a, s, nil)
+	default:
+		return
+	}
+	st := GetState(s)
+	if !st.RenderPasses.Contains(begin.RenderPass) || !st.Framebuffers.Contains(begin.Framebuffer) {
+		return
+	}
+	subpasses := st.RenderPasses.Get(begin.RenderPass).SubpassDescriptions
+	if len(subpasses) == 0 {
+		return
+	}
+	images := st.Framebuffers.Get(begin.Framebuffer).ImageAttachments
+	subpass := subpasses[uint32(len(subpasses)-1)]
+	list := []gfxapi.FramebufferAttachment{}
+	for _, i := range subpass.ColorAttachments.KeysSorted() {
+		if _, ok := images[subpass.ColorAttachments[i].Attachment]; !ok {
+			continue
+		}
+		if attachment := gfxapi.FramebufferAttachment_Color0 + gfxapi.FramebufferAttachment(len(list)); attachment <= gfxapi.FramebufferAttachment_Color3 {
+			list = append(list, attachment)
+		}
+	}
+	if subpass.DepthStencilAttachment != nil {
+		list = append(list, gfxapi.FramebufferAttachment_Depth)
+	}
+	f.attachments[id] = list
+}
+
+func (f *framePassBuilder) executed(id atom.ID, b *AtomBehaviour) {
+	a := f.atoms[id]
+	kind := api{}.ExecutionKind(a)
+	if f.current != nil {
+		f.current.add(b)
+		f.current.last = id
+		if kind == atom.EndPass {
+			f.current = nil
+		}
+		return
+	}
+	switch kind {
+	case atom.BeginPass:
+		f.current = f.begin(atom.RenderPass, id)
+		f.current.add(b)
+	case atom.Action:
+		pass := atom.TransferPass
+		switch a.(type) {
+		case *VkCmdDispatch, *RecreateCmdDispatch,
+			*VkCmdDispatchIndirect, *RecreateCmdDispatchIndirect:
+			pass = atom.ComputePass
+		}
+		f.begin(pass, id).add(b)
+	default:
+		if f.consumed {
+			f.bound, f.consumed = AtomBehaviour{}, false
+		}
+		f.bound.Read = append(f.bound.Read, b.Read...)
+		f.bound.Modify = append(f.bound.Modify, b.Modify...)
+		f.bound.Write = append(f.bound.Write, b.Write...)
+	}
+}
+
+// begin starts a new pass at the command id, accessing the bound state.
+func (f *framePassBuilder) begin(kind atom.FramePassKind, id atom.ID) *framePass {
+	p := &framePass{kind: kind, first: id, last: id}
+	if kind != atom.TransferPass {
+		p.add(&f.bound)
+		f.consumed = true
+	}
+	f.passes = append(f.passes, p)
+	return p
+}
+
+// frameResources returns the images, buffers and device memories holding the
+// state at the given addresses, without duplicates.
+func frameResources(g *DependencyGraph, addresses []StateAddress) []atom.FrameResource {
+	out := []atom.FrameResource{}
+	seen := map[atom.FrameResource]bool{}
+	for _, address := range addresses {
+		var r atom.FrameResource
+		switch k := g.addressMap.key[address].(type) {
+		case *vulkanDeviceMemoryData:
+			switch binding := k.binding; {
+			case binding.image != VkImage(0):
+				r = atom.FrameResource{Kind: "VkImage", Handle: uint64(binding.image)}
+			case binding.buffer != VkBuffer(0):
+				r = atom.FrameResource{Kind: "VkBuffer", Handle: uint64(binding.buffer)}
+			default:
+				r = atom.FrameResource{Kind: "VkDeviceMemory", Handle: uint64(binding.memory.handle.vkDeviceMemory)}
+			}
+		case *vulkanImageSubresource:
+			r = atom.FrameResource{Kind: "VkImage", Handle: uint64(k.image.vkImage)}
+		default:
+			continue
+		}
+		if !seen[r] {
+			seen[r] = true
+			out = append(out, r)
+		}
+	}
+	return out
+}
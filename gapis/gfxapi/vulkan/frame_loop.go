@@ -0,0 +1,377 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/experiments"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/replay/builder"
+	"github.com/google/gapid/gapis/replay/value"
+	"github.com/google/gapid/gapis/service"
+)
+
+// frameLoopConfig is a replay.Config used by frameLoopRequests.
+type frameLoopConfig struct {
+	// The experiments applied to the capture, or nil. Configs applying
+	// experiments are never batched together.
+	experiments *frameLoopExperiments
+}
+
+// frameLoopExperiments are the experiments applied to the capture by a
+// frameLoopConfig.
+type frameLoopExperiments struct {
+	names      []string
+	parameters experiments.Parameters
+}
+
+// frameLoopRequest requests the GPU time of each iteration of a frame replayed
+// several times in a loop.
+type frameLoopRequest struct {
+	end        atom.ID // The last atom of the frame.
+	iterations uint32
+}
+
+// Uncached implements the replay.Uncached interface, as timings are measured
+// anew by each replay.
+func (frameLoopRequest) Uncached() {}
+
+// loopAtom is an atom of the looped frame.
+type loopAtom struct {
+	id   atom.ID
+	atom atom.Atom
+}
+
+// frameLoop is a transform that replays the atoms of a frame several times,
+// dropping the atoms after the frame. A timestamp is written before and after
+// each iteration on the queue of the first submission of the frame, and read
+// back once the device is idle.
+//
+// Between the iterations, the fences and semaphores are restored to their
+// state at the start of the frame. The swapchain images are acquired with the
+// indices of the capture, see VkAcquireNextImageKHR.Mutate, and released by
+// the presentation ending the frame. The results of the queries made by the
+// frame are the values recorded in the capture.
+type frameLoop struct {
+	start, end  atom.ID
+	iterations  uint32
+	res         []replay.Result
+	atoms       []loopAtom
+	done        bool
+	queue       VkQueue
+	device      VkDevice
+	commandPool VkCommandPool
+	queryPool   VkQueryPool
+	period      float64 // Nanoseconds per timestamp tick.
+	durations   []time.Duration
+	fences      fenceTracker
+	startFences map[VkFence]bool     // The signal state at the start of the frame.
+	startSems   map[VkSemaphore]bool // The signal state at the start of the frame.
+	exported    bool                 // The frame is exported, see newFrameExport.
+}
+
+// frameStart returns the first atom of the frame ending with the atom end.
+func frameStart(atoms []atom.Atom, end atom.ID) atom.ID {
+	for i := end; i > 0; i-- {
+		if atoms[i-1].AtomFlags().IsEndOfFrame() {
+			return i
+		}
+	}
+	return 0
+}
+
+func newFrameLoop(start, end atom.ID, iterations uint32) *frameLoop {
+	return &frameLoop{
+		start:      start,
+		end:        end,
+		iterations: iterations,
+		period:     1,
+		fences:     fenceTracker{},
+	}
+}
+
+// newFrameExport returns a frameLoop writing the frame twice, without timing
+// it, for the programs exported by ExportCpp. The atoms of the second
+// iteration, repeated by the programs, follow an atom with the id
+// exportLoopLabel. Each iteration ends once the device is idle.
+func newFrameExport(start, end atom.ID) *frameLoop {
+	t := newFrameLoop(start, end, 2)
+	t.exported = true
+	return t
+}
+
+// reportTo adds r to the results notified with the durations.
+func (t *frameLoop) reportTo(r replay.Result) {
+	t.res = append(t.res, r)
+}
+
+func (t *frameLoop) notify(val interface{}, err error) {
+	for _, res := range t.res {
+		res(val, err)
+	}
+}
+
+func (t *frameLoop) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	switch {
+	case t.done:
+		// The atoms after the frame are not replayed.
+	case len(t.atoms) == 0 && (id < t.start || id == atom.NoID):
+		t.write(ctx, id, a, out)
+	default:
+		t.atoms = append(t.atoms, loopAtom{id, a})
+		if id == t.end {
+			t.loop(ctx, out)
+			t.done = true
+		}
+	}
+}
+
+// write writes the atom a, tracking the fences it signals or resets.
+func (t *frameLoop) write(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	out.MutateAndWrite(ctx, id, a)
+	t.fences.update(ctx, a, out.State())
+}
+
+// loop writes the iterations of the frame.
+func (t *frameLoop) loop(ctx context.Context, out transform.Writer) {
+	if !t.setup(ctx, out) {
+		for _, a := range t.atoms {
+			t.write(ctx, a.id, a.atom, out)
+		}
+		return
+	}
+	t.save(out.State())
+	for i := uint32(0); i < t.iterations; i++ {
+		if i > 0 {
+			if t.exported {
+				out.MutateAndWrite(ctx, exportLoopLabel, replay.Custom(func(context.Context, *gfxapi.State, *builder.Builder) error {
+					return nil
+				}))
+			}
+			t.restore(ctx, out)
+		}
+		if t.exported {
+			for _, a := range t.atoms {
+				t.write(ctx, a.id, a.atom, out)
+			}
+			writeEach(ctx, out, NewVkDeviceWaitIdle(t.device, VkResult_VK_SUCCESS))
+			continue
+		}
+		t.timestamp(ctx, out, VkPipelineStageFlagBits_VK_PIPELINE_STAGE_TOP_OF_PIPE_BIT, 0)
+		for _, a := range t.atoms {
+			t.write(ctx, a.id, a.atom, out)
+		}
+		t.timestamp(ctx, out, VkPipelineStageFlagBits_VK_PIPELINE_STAGE_BOTTOM_OF_PIPE_BIT, 1)
+		t.post(ctx, out)
+	}
+}
+
+// setup writes the atoms creating the command and query pools of the
+// timestamps, returning false if the frame submits nothing to time. Exported
+// frames are not timed, and only need the queue to wait for.
+func (t *frameLoop) setup(ctx context.Context, out transform.Writer) bool {
+	s := out.State()
+	st := GetState(s)
+	for _, a := range t.atoms {
+		if submit, ok := a.atom.(*VkQueueSubmit); ok {
+			t.queue = submit.Queue
+			break
+		}
+	}
+	if !st.Queues.Contains(t.queue) {
+		t.queue = VkQueue(0)
+		return false
+	}
+	t.device = st.Queues.Get(t.queue).Device
+	if t.exported {
+		return true
+	}
+
+	t.queryPool = VkQueryPool(newUnusedID(false, func(x uint64) bool { return st.QueryPools.Contains(VkQueryPool(x)) }))
+	createInfo := atom.Must(atom.AllocData(ctx, s, VkQueryPoolCreateInfo{
+		SType:      VkStructureType_VK_STRUCTURE_TYPE_QUERY_POOL_CREATE_INFO,
+		PNext:      NewVoidᶜᵖ(0),
+		Flags:      VkQueryPoolCreateFlags(0),
+		QueryType:  VkQueryType_VK_QUERY_TYPE_TIMESTAMP,
+		QueryCount: 2,
+	}))
+	defer createInfo.Free()
+	poolData := atom.Must(atom.AllocData(ctx, s, t.queryPool))
+	defer poolData.Free()
+
+	writeEach(ctx, out,
+		NewVkCreateQueryPool(
+			t.device,
+			createInfo.Ptr(),
+			memory.Pointer{},
+			poolData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(createInfo.Data()).AddWrite(poolData.Data()),
+		replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) (err error) {
+			t.commandPool, err = createCommandPool(ctx, s, b, t.queue, t.device)
+			return err
+		}),
+	)
+	postTimestampPeriod(ctx, out, t.device, func(period float64) { t.period = period })
+	return true
+}
+
+// save records the signal state of the fences and semaphores at the start of
+// the frame.
+func (t *frameLoop) save(s *gfxapi.State) {
+	st := GetState(s)
+	t.startFences = map[VkFence]bool{}
+	for _, handle := range st.Fences.KeysSorted() {
+		t.startFences[handle] = t.fences.signaled(st, handle)
+	}
+	t.startSems = map[VkSemaphore]bool{}
+	for _, handle := range st.Semaphores.KeysSorted() {
+		t.startSems[handle] = st.Semaphores.Get(handle).Signaled
+	}
+}
+
+// restore writes the atoms restoring the fences and semaphores to their
+// signal state at the start of the frame. The device is idle.
+func (t *frameLoop) restore(ctx context.Context, out transform.Writer) {
+	s := out.State()
+	st := GetState(s)
+	for _, handle := range st.Fences.KeysSorted() {
+		signaled, ok := t.startFences[handle]
+		if !ok || signaled == t.fences.signaled(st, handle) {
+			continue
+		}
+		fence := st.Fences.Get(handle)
+		if signaled {
+			if queue, ok := deviceQueue(st, fence.Device, nil); ok {
+				writeEach(ctx, out, NewVkQueueSubmit(queue, 0, memory.Pointer{}, handle, VkResult_VK_SUCCESS))
+			}
+		} else {
+			fences := MustAllocData(ctx, s, handle)
+			writeEach(ctx, out, NewVkResetFences(fence.Device, 1, fences.Ptr(), VkResult_VK_SUCCESS).AddRead(fences.Data()))
+			fences.Free()
+		}
+		t.fences[handle] = signaled
+	}
+	for _, handle := range st.Semaphores.KeysSorted() {
+		signaled, ok := t.startSems[handle]
+		semaphore := st.Semaphores.Get(handle)
+		if !ok || signaled == semaphore.Signaled {
+			continue
+		}
+		queue := semaphore.LastQueue
+		if !st.Queues.Contains(queue) {
+			if queue, ok = deviceQueue(st, semaphore.Device, nil); !ok {
+				continue
+			}
+		}
+		submitSemaphore(ctx, out, queue, handle, signaled)
+	}
+}
+
+// timestamp writes the atoms submitting a command buffer writing the
+// timestamp query after the pipeline stage.
+func (t *frameLoop) timestamp(ctx context.Context, out transform.Writer, stage VkPipelineStageFlagBits, query uint32) {
+	writeEach(ctx, out, replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+		cb, err := createAndBeginCommandBuffer(ctx, s, b, t.device, t.commandPool)
+		if err != nil {
+			return err
+		}
+		if query == 0 {
+			if err := NewVkCmdResetQueryPool(cb, t.queryPool, 0, 2).Mutate(ctx, s, b); err != nil {
+				return err
+			}
+		}
+		if err := NewVkCmdWriteTimestamp(cb, stage, t.queryPool, query).Mutate(ctx, s, b); err != nil {
+			return err
+		}
+		return createEndCommandBufferAndQueueSubmit(ctx, s, b, t.queue, cb)
+	}))
+}
+
+// post writes the atoms waiting for the iteration to complete and reading
+// back its timestamps.
+func (t *frameLoop) post(ctx context.Context, out transform.Writer) {
+	s := out.State()
+	results := atom.Must(atom.AllocData(ctx, s, make([]uint64, 2)))
+	defer results.Free()
+	writeEach(ctx, out,
+		NewVkDeviceWaitIdle(t.device, VkResult_VK_SUCCESS),
+		NewVkGetQueryPoolResults(
+			t.device,
+			t.queryPool,
+			0,
+			2,
+			16,
+			results.Ptr(),
+			8,
+			VkQueryResultFlags(VkQueryResultFlagBits_VK_QUERY_RESULT_64_BIT|VkQueryResultFlagBits_VK_QUERY_RESULT_WAIT_BIT),
+			VkResult_VK_SUCCESS,
+		).AddWrite(results.Data()),
+		replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+			b.Post(value.ObservedPointer(results.Address()), 16, func(r pod.Reader, err error) error {
+				if err != nil {
+					return err
+				}
+				start, end := r.Uint64(), r.Uint64()
+				if err := r.Error(); err != nil {
+					return err
+				}
+				d := time.Duration(0)
+				if end > start {
+					d = time.Duration(float64(end-start) * t.period)
+				}
+				t.durations = append(t.durations, d)
+				return nil
+			})
+			return nil
+		}),
+	)
+}
+
+func (t *frameLoop) Flush(ctx context.Context, out transform.Writer) {
+	if t.exported {
+		return
+	}
+	if t.queue == VkQueue(0) {
+		t.notify(nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage("The frame submits no work to time")})
+		return
+	}
+	// Notify the results once all the posted timestamps have been received.
+	out.MutateAndWrite(ctx, atom.NoID, replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+		code := uint32(0x7133e7a5)
+		b.Push(value.U32(code))
+		b.Post(b.Buffer(1), 4, func(r pod.Reader, err error) error {
+			if err == nil && r.Uint32() != code {
+				err = fmt.Errorf("Flush did not get expected EOS code")
+			}
+			if err != nil {
+				t.notify(nil, err)
+				return err
+			}
+			t.notify(t.durations, nil)
+			return nil
+		})
+		return nil
+	}))
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.Grouper(api{})
+
+// NewGroupBuilder implements the atom.Grouper interface.
+func (api) NewGroupBuilder() atom.GroupBuilder {
+	return &groupBuilder{commandBuffers: map[VkCommandBuffer]*commandBufferGroups{}}
+}
+
+// groupBuilder groups the Vulkan atoms of each frame into queue submissions,
+// and the recording of each command buffer into render passes and draws.
+type groupBuilder struct {
+	submitStart    uint64
+	submitCount    int
+	commandBuffers map[VkCommandBuffer]*commandBufferGroups
+}
+
+// commandBufferGroups tracks the groups of a command buffer being recorded.
+type commandBufferGroups struct {
+	start           uint64 // Index of the vkBeginCommandBuffer atom.
+	renderPassStart uint64 // Index of the current vkCmdBeginRenderPass atom.
+	renderPassCount int
+	inRenderPass    bool
+	drawStart       uint64 // Index of the first atom of the next draw group.
+	drawCount       int
+}
+
+func (b *groupBuilder) Process(ctx context.Context, a atom.Atom, i uint64, s *gfxapi.State, root *atom.Group) {
+	switch a := a.(type) {
+	case *VkBeginCommandBuffer:
+		b.commandBuffers[a.CommandBuffer] = &commandBufferGroups{start: i, drawStart: i + 1}
+
+	case *VkEndCommandBuffer:
+		if cb, ok := b.commandBuffers[a.CommandBuffer]; ok {
+			name := fmt.Sprintf("Command Buffer %v", a.CommandBuffer)
+			if n := debugName(s, uint64(a.CommandBuffer)); n != "" {
+				name = fmt.Sprintf("%s (%s)", name, n)
+			}
+			root.SubGroups.Add(cb.start, i+1, name)
+			delete(b.commandBuffers, a.CommandBuffer)
+		}
+
+	case *VkCmdBeginRenderPass:
+		if cb, ok := b.commandBuffers[a.CommandBuffer]; ok {
+			cb.renderPassStart, cb.inRenderPass = i, true
+			cb.drawStart = i + 1
+		}
+
+	case *VkCmdEndRenderPass:
+		if cb, ok := b.commandBuffers[a.CommandBuffer]; ok && cb.inRenderPass {
+			root.SubGroups.Add(cb.renderPassStart, i+1, fmt.Sprintf("Render Pass %d", cb.renderPassCount))
+			cb.renderPassCount++
+			cb.inRenderPass = false
+			cb.drawStart = i + 1
+		}
+
+	case *VkCmdDraw:
+		b.addDraw(root, a.CommandBuffer, i, "Draw")
+	case *VkCmdDrawIndexed:
+		b.addDraw(root, a.CommandBuffer, i, "Draw")
+	case *VkCmdDrawIndirect:
+		b.addDraw(root, a.CommandBuffer, i, "Draw")
+	case *VkCmdDrawIndexedIndirect:
+		b.addDraw(root, a.CommandBuffer, i, "Draw")
+	case *VkCmdDispatch:
+		b.addDraw(root, a.CommandBuffer, i, "Dispatch")
+	case *VkCmdDispatchIndirect:
+		b.addDraw(root, a.CommandBuffer, i, "Dispatch")
+
+	case *VkQueueSubmit:
+		root.SubGroups.Add(b.submitStart, i+1, fmt.Sprintf("Queue Submit %d", b.submitCount))
+		b.submitStart = i + 1
+		b.submitCount++
+
+	case *VkQueuePresentKHR:
+		// Submissions are numbered from the start of each frame.
+		b.submitStart = i + 1
+		b.submitCount = 0
+	}
+}
+
+// addDraw adds a group for the draw or dispatch command at index i, recorded
+// to the command buffer cb. The group starts after the previous draw of cb.
+func (b *groupBuilder) addDraw(root *atom.Group, cb VkCommandBuffer, i uint64, kind string) {
+	if g, ok := b.commandBuffers[cb]; ok {
+		root.SubGroups.Add(g.drawStart, i+1, fmt.Sprintf("%v %d", kind, g.drawCount))
+		g.drawStart = i + 1
+		g.drawCount++
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// highlightDimFactor is the factor applied to the color output of the draw
+// calls that are not highlighted.
+const highlightDimFactor = 0.25
+
+// highlight is an atom transform that dims the output of all the draw calls
+// but one. Each graphics pipeline gets a dimmed variant that blends its color
+// output with a constant factor. The variants are bound in place of the
+// original pipelines, except for the highlighted draw call which is
+// surrounded with binds of the original pipeline.
+type highlight struct {
+	draw   atom.ID                        // The draw call to render in full color.
+	dimmed map[VkPipeline]VkPipeline      // Dimmed variant of each pipeline.
+	bound  map[VkCommandBuffer]VkPipeline // Original pipeline bound to each command buffer.
+}
+
+func newHighlight(draw atom.ID) *highlight {
+	return &highlight{
+		draw:   draw,
+		dimmed: map[VkPipeline]VkPipeline{},
+		bound:  map[VkCommandBuffer]VkPipeline{},
+	}
+}
+
+func (t *highlight) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	s := out.State()
+	switch a := a.(type) {
+	case *VkCreateGraphicsPipelines:
+		out.MutateAndWrite(ctx, id, a)
+		count := uint64(a.CreateInfoCount)
+		infos := a.PCreateInfos.Slice(0, count, s).Read(ctx, a, s, nil)
+		pipelines := a.PPipelines.Slice(0, count, s).Read(ctx, a, s, nil)
+		for i := range infos {
+			t.createDimmed(ctx, a, a.Device, a.PipelineCache, infos[i], pipelines[i], out)
+		}
+		return
+	case *RecreateGraphicsPipeline:
+		out.MutateAndWrite(ctx, id, a)
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		pipeline := a.PPipeline.Read(ctx, a, s, nil)
+		t.createDimmed(ctx, a, a.Device, a.PipelineCache, info, pipeline, out)
+		return
+	case *VkDestroyPipeline:
+		out.MutateAndWrite(ctx, id, a)
+		if dimmed, ok := t.dimmed[a.Pipeline]; ok {
+			writeEach(ctx, out, NewVkDestroyPipeline(a.Device, dimmed, memory.Pointer{}))
+			delete(t.dimmed, a.Pipeline)
+		}
+		return
+	case *VkCmdBindPipeline:
+		if dimmed, ok := t.bind(a.CommandBuffer, a.PipelineBindPoint, a.Pipeline); ok {
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, NewVkCmdBindPipeline(a.CommandBuffer, a.PipelineBindPoint, dimmed)))
+			return
+		}
+	case *RecreateCmdBindPipeline:
+		if dimmed, ok := t.bind(a.CommandBuffer, a.PipelineBindPoint, a.Pipeline); ok {
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, NewRecreateCmdBindPipeline(a.CommandBuffer, a.PipelineBindPoint, dimmed)))
+			return
+		}
+	}
+
+	if id == t.draw {
+		if cb, ok := drawCommandBuffer(a); ok {
+			if pipeline, ok := t.bound[cb]; ok {
+				if dimmed, ok := t.dimmed[pipeline]; ok {
+					graphics := VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS
+					writeEach(ctx, out, NewVkCmdBindPipeline(cb, graphics, pipeline))
+					out.MutateAndWrite(ctx, id, a)
+					writeEach(ctx, out, NewVkCmdBindPipeline(cb, graphics, dimmed))
+					return
+				}
+			}
+		}
+	}
+	out.MutateAndWrite(ctx, id, a)
+}
+
+func (t *highlight) Flush(ctx context.Context, out transform.Writer) {}
+
+// bind records the graphics pipeline bound to the command buffer cb, and
+// returns its dimmed variant if it has one.
+func (t *highlight) bind(cb VkCommandBuffer, point VkPipelineBindPoint, pipeline VkPipeline) (VkPipeline, bool) {
+	if point != VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS {
+		return pipeline, false
+	}
+	t.bound[cb] = pipeline
+	dimmed, ok := t.dimmed[pipeline]
+	return dimmed, ok
+}
+
+// createDimmed writes the atoms creating the dimmed variant of the graphics
+// pipeline created with info by the atom a.
+func (t *highlight) createDimmed(
+	ctx context.Context,
+	a atom.Atom,
+	device VkDevice,
+	cache VkPipelineCache,
+	info VkGraphicsPipelineCreateInfo,
+	pipeline VkPipeline,
+	out transform.Writer) {
+
+	s := out.State()
+	if info.PColorBlendState.Address == 0 {
+		// Rasterization is disabled, there is nothing to dim.
+		return
+	}
+	data := []atom.AllocResult{}
+	alloc := func(v ...interface{}) atom.AllocResult {
+		d := atom.Must(atom.AllocData(ctx, s, v...))
+		data = append(data, d)
+		return d
+	}
+
+	blend := info.PColorBlendState.Read(ctx, a, s, nil)
+	attachments := blend.PAttachments.Slice(0, uint64(blend.AttachmentCount), s).Read(ctx, a, s, nil)
+	for i := range attachments {
+		attachments[i].BlendEnable = VkBool32(1)
+		attachments[i].SrcColorBlendFactor = VkBlendFactor_VK_BLEND_FACTOR_CONSTANT_COLOR
+		attachments[i].DstColorBlendFactor = VkBlendFactor_VK_BLEND_FACTOR_ZERO
+		attachments[i].ColorBlendOp = VkBlendOp_VK_BLEND_OP_ADD
+		attachments[i].SrcAlphaBlendFactor = VkBlendFactor_VK_BLEND_FACTOR_ONE
+		attachments[i].DstAlphaBlendFactor = VkBlendFactor_VK_BLEND_FACTOR_ZERO
+		attachments[i].AlphaBlendOp = VkBlendOp_VK_BLEND_OP_ADD
+	}
+	blend.LogicOpEnable = VkBool32(0)
+	blend.PAttachments = VkPipelineColorBlendAttachmentStateᶜᵖ(alloc(attachments).Ptr())
+	blend.BlendConstants = F32ː4ᵃ{Elements: [4]float32{
+		highlightDimFactor, highlightDimFactor, highlightDimFactor, 1,
+	}}
+	info.PColorBlendState = VkPipelineColorBlendStateCreateInfoᶜᵖ(alloc(blend).Ptr())
+
+	if info.PDynamicState.Address != 0 {
+		// The blend constants must not be overridden by the application.
+		dynamic := info.PDynamicState.Read(ctx, a, s, nil)
+		states := dynamic.PDynamicStates.Slice(0, uint64(dynamic.DynamicStateCount), s).Read(ctx, a, s, nil)
+		kept := []VkDynamicState{}
+		for _, state := range states {
+			if state != VkDynamicState_VK_DYNAMIC_STATE_BLEND_CONSTANTS {
+				kept = append(kept, state)
+			}
+		}
+		dynamic.DynamicStateCount = uint32(len(kept))
+		if len(kept) > 0 {
+			dynamic.PDynamicStates = VkDynamicStateᶜᵖ(alloc(kept).Ptr())
+		}
+		info.PDynamicState = VkPipelineDynamicStateCreateInfoᶜᵖ(alloc(dynamic).Ptr())
+	}
+
+	t.dimmed[pipeline] = createPipelineVariant(ctx, a, device, cache, info, data, out)
+}
+
+// createPipelineVariant writes the atoms creating a new graphics pipeline
+// with info, a modified copy of a create info of the atom a. data holds the
+// modified parts of the create info, the other parts are read from the
+// observations of a.
+func createPipelineVariant(
+	ctx context.Context,
+	a atom.Atom,
+	device VkDevice,
+	cache VkPipelineCache,
+	info VkGraphicsPipelineCreateInfo,
+	data []atom.AllocResult,
+	out transform.Writer) VkPipeline {
+
+	s := out.State()
+
+	// The variant is created on its own, so it cannot derive from a pipeline
+	// by index.
+	info.Flags = VkPipelineCreateFlags(uint32(info.Flags) &^ uint32(VkPipelineCreateFlagBits_VK_PIPELINE_CREATE_DERIVATIVE_BIT))
+	info.BasePipelineHandle = VkPipeline(0)
+	info.BasePipelineIndex = -1
+
+	variant := VkPipeline(newUnusedID(false, func(x uint64) bool {
+		_, ok := GetState(s).GraphicsPipelines[VkPipeline(x)]
+		return ok
+	}))
+	infoData := atom.Must(atom.AllocData(ctx, s, info))
+	variantData := atom.Must(atom.AllocData(ctx, s, variant))
+
+	create := NewVkCreateGraphicsPipelines(device, cache, 1, infoData.Ptr(), memory.Pointer{}, variantData.Ptr(), VkResult_VK_SUCCESS)
+	// The rest of the create info is held by the reads of the original atom.
+	if o := a.Extras().Observations(); o != nil {
+		for _, r := range o.Reads {
+			create.AddRead(r.Range, r.ID)
+		}
+	}
+	for _, d := range data {
+		create.AddRead(d.Data())
+	}
+	create.AddRead(infoData.Data())
+	create.AddWrite(variantData.Data())
+	writeEach(ctx, out, create)
+	return variant
+}
+
+// drawCommandBuffer returns the command buffer the draw call a is recorded
+// into, or false if a is not a draw call.
+func drawCommandBuffer(a atom.Atom) (VkCommandBuffer, bool) {
+	switch a := a.(type) {
+	case *VkCmdDraw:
+		return a.CommandBuffer, true
+	case *VkCmdDrawIndexed:
+		return a.CommandBuffer, true
+	case *VkCmdDrawIndirect:
+		return a.CommandBuffer, true
+	case *VkCmdDrawIndexedIndirect:
+		return a.CommandBuffer, true
+	case *RecreateCmdDraw:
+		return a.CommandBuffer, true
+	case *RecreateCmdDrawIndexed:
+		return a.CommandBuffer, true
+	case *RecreateCmdDrawIndirect:
+		return a.CommandBuffer, true
+	case *RecreateCmdDrawIndexedIndirect:
+		return a.CommandBuffer, true
+	}
+	return 0, false
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+)
+
+const (
+	twoPlane420   = vulkan.VkFormat_VK_FORMAT_G8_B8R8_2PLANE_420_UNORM_KHR
+	threePlane422 = vulkan.VkFormat_VK_FORMAT_G8_B8_R8_3PLANE_422_UNORM_KHR
+	sampled       = vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT |
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT
+)
+
+// planarImages creates a 64x32 image of each of the formats, and returns the
+// state after their creation with the image objects.
+func planarImages(ctx context.Context, formats ...vulkan.VkFormat) (*gfxapi.State, vulkan.VkDevice, []*vulkan.ImageObject) {
+	b := samples.NewBuilder(ctx)
+	device, _, _ := b.NewDevice(ctx)
+	handles := make([]vulkan.VkImage, len(formats))
+	for i, format := range formats {
+		handles[i] = b.PlanarImage(ctx, device, 64, 32, format, sampled)
+	}
+	c, err := capture.ImportAtomList(ctx, "planar-images", &b.List)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return nil, device, nil
+	}
+	ctx = capture.Put(ctx, c)
+
+	out := &recorder{state: capture.NewState(ctx)}
+	for i, a := range b.List.Atoms {
+		out.MutateAndWrite(ctx, atom.ID(i), a)
+	}
+	assert.For(ctx, "Mutate").ThatError(out.err).Succeeded()
+
+	images := make([]*vulkan.ImageObject, len(handles))
+	for i, h := range handles {
+		images[i] = vulkan.GetState(out.state).Images.Get(h)
+	}
+	return out.state, device, images
+}
+
+func TestImageDataCopies(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	const color = vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT
+	const plane0 = vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_0_BIT_KHR
+	const plane1 = vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_1_BIT_KHR
+	const plane2 = vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_2_BIT_KHR
+
+	type planeCopy struct {
+		aspect        vulkan.VkImageAspectFlagBits
+		offset        vulkan.VkDeviceSize
+		width, height uint32
+	}
+	formats := []vulkan.VkFormat{vulkan.VkFormat_VK_FORMAT_R8G8B8A8_UNORM, twoPlane420, threePlane422}
+	_, device, images := planarImages(ctx, formats...)
+	if images == nil {
+		return
+	}
+	for i, expected := range [][]planeCopy{
+		{{color, 0, 64, 32}},
+		// The R8G8 chroma plane is subsampled in both dimensions.
+		{{plane0, 0, 64, 32}, {plane1, 64 * 32, 32, 16}},
+		// The R8 chroma planes are subsampled horizontally.
+		{{plane0, 0, 64, 32}, {plane1, 64 * 32, 32, 32}, {plane2, 64*32 + 32*32, 32, 32}},
+	} {
+		ctx := log.V{"format": formats[i]}.Bind(ctx)
+		image := images[i]
+		a := vulkan.NewRecreateImageData(device, image.VulkanHandle, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL,
+			0, vulkan.VkQueue(0), 0, memory.Pointer{})
+		got := []planeCopy{}
+		for _, c := range vulkan.ImageDataCopies(ctx, a, image) {
+			assert.For(ctx, "Layers").That(c.ImageSubresource.LayerCount).Equals(uint32(1))
+			got = append(got, planeCopy{
+				vulkan.VkImageAspectFlagBits(c.ImageSubresource.AspectMask),
+				c.BufferOffset,
+				c.ImageExtent.Width,
+				c.ImageExtent.Height,
+			})
+		}
+		assert.For(ctx, "Copies").ThatSlice(got).Equals(expected)
+	}
+}
+
+func TestImagePlaneResources(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	s, _, images := planarImages(ctx, twoPlane420)
+	if images == nil {
+		return
+	}
+	image := images[0]
+
+	// The planes are the resources of a multi-planar image.
+	assert.For(ctx, "Image is resource").That(image.IsResource()).Equals(false)
+	assert.For(ctx, "Planes").That(len(image.Planes)).Equals(2)
+	assert.For(ctx, "Plane 0 layer").That(image.Planes.Get(0).Layers.Get(0)).Equals(image.Layers.Get(0))
+
+	for plane, expected := range []struct {
+		format        vulkan.VkFormat
+		width, height uint32
+	}{
+		{vulkan.VkFormat_VK_FORMAT_R8_UNORM, 64, 32},
+		{vulkan.VkFormat_VK_FORMAT_R8G8_UNORM, 32, 16},
+	} {
+		ctx := log.V{"plane": plane}.Bind(ctx)
+		p := image.Planes.Get(uint32(plane))
+		assert.For(ctx, "Format").That(p.Format).Equals(expected.format)
+		assert.For(ctx, "Is resource").That(p.IsResource()).Equals(true)
+		assert.For(ctx, "Handle").That(p.ResourceHandle()).Equals(
+			fmt.Sprintf("Image<%d> plane %d", image.VulkanHandle, plane))
+		assert.For(ctx, "Type").That(p.ResourceType(ctx)).Equals(gfxapi.ResourceType_Texture2DResource)
+
+		data, err := p.ResourceData(ctx, s)
+		if !assert.For(ctx, "ResourceData").ThatError(err).Succeeded() {
+			continue
+		}
+		texture, ok := data.(*gfxapi.Texture2D)
+		if !assert.For(ctx, "Texture2D").That(ok).Equals(true) ||
+			!assert.For(ctx, "Levels").That(len(texture.Levels)).Equals(1) {
+			continue
+		}
+		level := texture.Levels[0]
+		assert.For(ctx, "Width").That(level.Width).Equals(expected.width)
+		assert.For(ctx, "Height").That(level.Height).Equals(expected.height)
+		assert.For(ctx, "Data").That(level.Data).IsNotNil()
+	}
+}
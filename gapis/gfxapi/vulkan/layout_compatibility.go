@@ -0,0 +1,129 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"fmt"
+
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// compatibleDescriptorTypes maps the kinds of resources declared by shaders
+// to the descriptor types that can be bound to them.
+var compatibleDescriptorTypes = map[shadertools.DescriptorType][]VkDescriptorType{
+	shadertools.Sampler: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_SAMPLER,
+	},
+	shadertools.CombinedImageSampler: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER,
+	},
+	shadertools.SampledImage: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_SAMPLED_IMAGE,
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER,
+	},
+	shadertools.StorageImage: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_IMAGE,
+	},
+	shadertools.UniformTexelBuffer: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_UNIFORM_TEXEL_BUFFER,
+	},
+	shadertools.StorageTexelBuffer: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_TEXEL_BUFFER,
+	},
+	shadertools.UniformBuffer: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER,
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER_DYNAMIC,
+	},
+	shadertools.StorageBuffer: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER,
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER_DYNAMIC,
+	},
+	shadertools.InputAttachment: {
+		VkDescriptorType_VK_DESCRIPTOR_TYPE_INPUT_ATTACHMENT,
+	},
+}
+
+// checkShaderLayoutCompatibility returns an error if any descriptor binding
+// used by the SPIR-V code is incompatible with the pipeline layout of a
+// pipeline created from the shader module in the state s.
+func checkShaderLayoutCompatibility(s *State, module VkShaderModule, words []uint32) error {
+	bindings, err := shadertools.DescriptorBindings(words)
+	if err != nil {
+		return err
+	}
+	for handle, pipeline := range s.GraphicsPipelines {
+		for _, stage := range pipeline.Stages {
+			if stage.Module != nil && stage.Module.VulkanHandle == module {
+				if err := checkPipelineLayout(handle, pipeline.Layout, bindings); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for handle, pipeline := range s.ComputePipelines {
+		if m := pipeline.Stage.Module; m != nil && m.VulkanHandle == module {
+			if err := checkPipelineLayout(handle, pipeline.PipelineLayout, bindings); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPipelineLayout returns an error describing the first binding used by
+// a shader of the pipeline that does not match the pipeline's layout.
+func checkPipelineLayout(pipeline VkPipeline, layout *PipelineLayoutObject, bindings []shadertools.DescriptorBinding) error {
+	if layout == nil {
+		return nil
+	}
+	for _, b := range bindings {
+		if !b.Used || b.Type == shadertools.UnknownDescriptor {
+			continue
+		}
+		incompatible := func(reason string, args ...interface{}) error {
+			return &service.ErrInvalidArgument{Reason: messages.ErrIncompatibleShaderBinding(
+				b.Name, b.Set, b.Binding, uint64(pipeline), fmt.Sprintf(reason, args...))}
+		}
+		setLayout, ok := layout.SetLayouts[b.Set]
+		if !ok || setLayout == nil {
+			return incompatible("the pipeline layout has no descriptor set %d", b.Set)
+		}
+		layoutBinding, ok := setLayout.Bindings[b.Binding]
+		if !ok {
+			return incompatible("descriptor set layout %v has no binding %d",
+				setLayout.VulkanHandle, b.Binding)
+		}
+		if !isCompatibleDescriptorType(b.Type, layoutBinding.Type) {
+			return incompatible("the shader declares a %v, the layout binding is of type %v",
+				b.Type, layoutBinding.Type)
+		}
+		if b.Count > layoutBinding.Count {
+			return incompatible("the shader declares %d descriptors, the layout binding has %d",
+				b.Count, layoutBinding.Count)
+		}
+	}
+	return nil
+}
+
+func isCompatibleDescriptorType(shader shadertools.DescriptorType, layout VkDescriptorType) bool {
+	for _, ty := range compatibleDescriptorTypes[shader] {
+		if ty == layout {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.LeakTracker(api{})
+
+// createdObject is a Vulkan object created by an atom of the capture.
+type createdObject struct {
+	created atom.ID
+	kind    string
+	handle  uint64
+}
+
+// Leaks returns the Vulkan objects created by the capture held by ctx and
+// never destroyed, the device memory never freed, and the command pools never
+// reset with vkResetCommandPool.
+func (api) Leaks(ctx context.Context) ([]atom.Leak, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []createdObject{}
+	reset := map[VkCommandPool]bool{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		objects = append(objects, createdObjects(ctx, id, a, s)...)
+		if a, ok := a.(*VkResetCommandPool); ok {
+			reset[a.CommandPool] = true
+		}
+	}
+
+	st := GetState(s)
+	leaks := []atom.Leak{}
+	for _, o := range objects {
+		leak := atom.Leak{Created: o.created, Kind: o.kind, Handle: o.handle}
+		if o.kind == "VkCommandPool" && !reset[VkCommandPool(o.handle)] {
+			leak.Reason = atom.NeverReset
+			leaks = append(leaks, leak)
+		}
+		if !objectAlive(st, o.kind, o.handle) {
+			continue
+		}
+		leak.Reason = atom.NeverDestroyed
+		if o.kind == "VkDeviceMemory" {
+			leak.Reason = atom.NeverFreed
+			leak.Size = uint64(st.DeviceMemories.Get(VkDeviceMemory(o.handle)).AllocationSize)
+		}
+		leaks = append(leaks, leak)
+	}
+	return leaks, nil
+}
+
+// createdObjects returns the objects created by the atom a, which has been
+// applied to the state s.
+func createdObjects(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) []createdObject {
+	out := []createdObject{}
+	add := func(kind string, handle uint64) {
+		out = append(out, createdObject{id, kind, handle})
+	}
+	switch a := a.(type) {
+	case *VkCreateInstance:
+		add("VkInstance", uint64(a.PInstance.Read(ctx, a, s, nil)))
+	case *VkCreateDevice:
+		add("VkDevice", uint64(a.PDevice.Read(ctx, a, s, nil)))
+	case *VkAllocateMemory:
+		add("VkDeviceMemory", uint64(a.PMemory.Read(ctx, a, s, nil)))
+	case *VkCreateBuffer:
+		add("VkBuffer", uint64(a.PBuffer.Read(ctx, a, s, nil)))
+	case *VkCreateBufferView:
+		add("VkBufferView", uint64(a.PView.Read(ctx, a, s, nil)))
+	case *VkCreateImage:
+		add("VkImage", uint64(a.PImage.Read(ctx, a, s, nil)))
+	case *VkCreateImageView:
+		add("VkImageView", uint64(a.PView.Read(ctx, a, s, nil)))
+	case *VkCreateSampler:
+		add("VkSampler", uint64(a.PSampler.Read(ctx, a, s, nil)))
+	case *VkCreateShaderModule:
+		add("VkShaderModule", uint64(a.PShaderModule.Read(ctx, a, s, nil)))
+	case *VkCreatePipelineLayout:
+		add("VkPipelineLayout", uint64(a.PPipelineLayout.Read(ctx, a, s, nil)))
+	case *VkCreatePipelineCache:
+		add("VkPipelineCache", uint64(a.PPipelineCache.Read(ctx, a, s, nil)))
+	case *VkCreateGraphicsPipelines:
+		for _, p := range a.PPipelines.Slice(0, uint64(a.CreateInfoCount), s).Read(ctx, a, s, nil) {
+			add("VkPipeline", uint64(p))
+		}
+	case *VkCreateComputePipelines:
+		for _, p := range a.PPipelines.Slice(0, uint64(a.CreateInfoCount), s).Read(ctx, a, s, nil) {
+			add("VkPipeline", uint64(p))
+		}
+	case *VkCreateDescriptorSetLayout:
+		add("VkDescriptorSetLayout", uint64(a.PSetLayout.Read(ctx, a, s, nil)))
+	case *VkCreateDescriptorPool:
+		add("VkDescriptorPool", uint64(a.PDescriptorPool.Read(ctx, a, s, nil)))
+	case *VkCreateRenderPass:
+		add("VkRenderPass", uint64(a.PRenderPass.Read(ctx, a, s, nil)))
+	case *VkCreateFramebuffer:
+		add("VkFramebuffer", uint64(a.PFramebuffer.Read(ctx, a, s, nil)))
+	case *VkCreateCommandPool:
+		if a.Result == VkResult_VK_SUCCESS {
+			add("VkCommandPool", uint64(a.PCommandPool.Read(ctx, a, s, nil)))
+		}
+	case *VkCreateFence:
+		add("VkFence", uint64(a.PFence.Read(ctx, a, s, nil)))
+	case *VkCreateSemaphore:
+		add("VkSemaphore", uint64(a.PSemaphore.Read(ctx, a, s, nil)))
+	case *VkCreateEvent:
+		add("VkEvent", uint64(a.PEvent.Read(ctx, a, s, nil)))
+	case *VkCreateQueryPool:
+		add("VkQueryPool", uint64(a.PQueryPool.Read(ctx, a, s, nil)))
+	case *VkCreateSwapchainKHR:
+		add("VkSwapchainKHR", uint64(a.PSwapchain.Read(ctx, a, s, nil)))
+	}
+	return out
+}
+
+// objectAlive returns true if the object of the given kind and handle exists
+// in the state.
+func objectAlive(st *State, kind string, handle uint64) bool {
+	switch kind {
+	case "VkInstance":
+		return st.Instances.Contains(VkInstance(handle))
+	case "VkDevice":
+		return st.Devices.Contains(VkDevice(handle))
+	case "VkDeviceMemory":
+		return st.DeviceMemories.Contains(VkDeviceMemory(handle))
+	case "VkBuffer":
+		return st.Buffers.Contains(VkBuffer(handle))
+	case "VkBufferView":
+		return st.BufferViews.Contains(VkBufferView(handle))
+	case "VkImage":
+		return st.Images.Contains(VkImage(handle))
+	case "VkImageView":
+		return st.ImageViews.Contains(VkImageView(handle))
+	case "VkSampler":
+		return st.Samplers.Contains(VkSampler(handle))
+	case "VkShaderModule":
+		return st.ShaderModules.Contains(VkShaderModule(handle))
+	case "VkPipelineLayout":
+		return st.PipelineLayouts.Contains(VkPipelineLayout(handle))
+	case "VkPipelineCache":
+		return st.PipelineCaches.Contains(VkPipelineCache(handle))
+	case "VkPipeline":
+		return st.GraphicsPipelines.Contains(VkPipeline(handle)) ||
+			st.ComputePipelines.Contains(VkPipeline(handle))
+	case "VkDescriptorSetLayout":
+		return st.DescriptorSetLayouts.Contains(VkDescriptorSetLayout(handle))
+	case "VkDescriptorPool":
+		return st.DescriptorPools.Contains(VkDescriptorPool(handle))
+	case "VkRenderPass":
+		return st.RenderPasses.Contains(VkRenderPass(handle))
+	case "VkFramebuffer":
+		return st.Framebuffers.Contains(VkFramebuffer(handle))
+	case "VkCommandPool":
+		return st.CommandPools.Contains(VkCommandPool(handle))
+	case "VkFence":
+		return st.Fences.Contains(VkFence(handle))
+	case "VkSemaphore":
+		return st.Semaphores.Contains(VkSemaphore(handle))
+	case "VkEvent":
+		return st.Events.Contains(VkEvent(handle))
+	case "VkQueryPool":
+		return st.QueryPools.Contains(VkQueryPool(handle))
+	case "VkSwapchainKHR":
+		return st.Swapchains.Contains(VkSwapchainKHR(handle))
+	}
+	return false
+}
@@ -0,0 +1,486 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.Linter(api{})
+
+// The identifiers of the rules checked by Lint.
+const (
+	lintMissingBarrier  = "missing-barrier"
+	lintDestroyedHandle = "destroyed-handle"
+	lintImageLayout     = "image-layout"
+	lintUnresetFence    = "unreset-fence"
+)
+
+// objectKinds is the set of the object types whose use after destruction is
+// checked by Lint.
+var objectKinds = map[string]bool{
+	"VkInstance":            true,
+	"VkDevice":              true,
+	"VkDeviceMemory":        true,
+	"VkBuffer":              true,
+	"VkBufferView":          true,
+	"VkImage":               true,
+	"VkImageView":           true,
+	"VkSampler":             true,
+	"VkShaderModule":        true,
+	"VkPipelineLayout":      true,
+	"VkPipelineCache":       true,
+	"VkPipeline":            true,
+	"VkDescriptorSetLayout": true,
+	"VkDescriptorPool":      true,
+	"VkRenderPass":          true,
+	"VkFramebuffer":         true,
+	"VkCommandPool":         true,
+	"VkFence":               true,
+	"VkSemaphore":           true,
+	"VkEvent":               true,
+	"VkQueryPool":           true,
+	"VkSwapchainKHR":        true,
+}
+
+// objectHandle identifies a Vulkan object by type and handle.
+type objectHandle struct {
+	kind   string
+	handle uint64
+}
+
+// lintEventKind is the kind of a lintEvent.
+type lintEventKind int
+
+const (
+	lintRead          lintEventKind = iota // A transfer read of an image or buffer.
+	lintWrite                              // A transfer write of an image or buffer.
+	lintBarrier                            // An image or buffer barrier.
+	lintMemoryBarrier                      // A global memory barrier.
+	lintRenderPass                         // A render pass attachment.
+	lintExecute                            // The execution of a secondary command buffer.
+)
+
+// lintEvent is a command recorded to a command buffer, checked when the
+// command buffer is submitted.
+type lintEvent struct {
+	kind      lintEventKind
+	id        atom.ID
+	image     VkImage
+	buffer    VkBuffer
+	secondary VkCommandBuffer
+	levels    [2]uint32     // The first mip level and the level count.
+	layers    [2]uint32     // The first array layer and the layer count.
+	oldLayout VkImageLayout // The expected image layout, UNDEFINED for any.
+	newLayout VkImageLayout // The image layout after the event.
+}
+
+func (e lintEvent) resource() string {
+	if e.image != VkImage(0) {
+		return fmt.Sprintf("Image %v", e.image)
+	}
+	return fmt.Sprintf("Buffer %v", e.buffer)
+}
+
+// imageSubresource is a mip level of an array layer of an image.
+type imageSubresource struct {
+	layer, level uint32
+}
+
+// pendingWrites holds the atoms writing the images and buffers that are not
+// followed by a barrier yet.
+type pendingWrites struct {
+	images  map[VkImage]atom.ID
+	buffers map[VkBuffer]atom.ID
+}
+
+func newPendingWrites() *pendingWrites {
+	return &pendingWrites{images: map[VkImage]atom.ID{}, buffers: map[VkBuffer]atom.ID{}}
+}
+
+// lintKey identifies an issue, to report it once when a command buffer is
+// submitted several times.
+type lintKey struct {
+	atom    atom.ID
+	rule    string
+	message string
+}
+
+// linter checks the atoms of a capture.
+type linter struct {
+	issues    []atom.LintIssue
+	reported  map[lintKey]bool
+	events    map[VkCommandBuffer][]lintEvent
+	layouts   map[VkImage]map[imageSubresource]VkImageLayout
+	destroyed map[objectHandle]atom.ID
+	fences    fenceTracker
+}
+
+// Lint checks the atoms of the capture held by ctx for uses of destroyed
+// object handles and submissions of fences that are still signaled. The
+// transfer commands, barriers and render passes of the submitted command
+// buffers are checked for reads of the images and buffers written without a
+// barrier in between, and for images used in a layout other than their
+// current one. The commands of the command buffers recorded before the start
+// of the capture are not checked.
+func (api) Lint(ctx context.Context) ([]atom.LintIssue, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{
+		reported:  map[lintKey]bool{},
+		events:    map[VkCommandBuffer][]lintEvent{},
+		layouts:   map[VkImage]map[imageSubresource]VkImageLayout{},
+		destroyed: map[objectHandle]atom.ID{},
+		fences:    fenceTracker{},
+	}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		l.check(ctx, id, a, s)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		l.update(ctx, id, a, s)
+	}
+	return l.issues, nil
+}
+
+func (l *linter) report(id, related atom.ID, rule, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	key := lintKey{id, rule, message}
+	if l.reported[key] {
+		return
+	}
+	l.reported[key] = true
+	l.issues = append(l.issues, atom.LintIssue{
+		Atom:    id,
+		Related: related,
+		Rule:    rule,
+		Message: message,
+	})
+}
+
+// check checks the atom a before it is applied to the state s.
+func (l *linter) check(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) {
+	l.checkHandles(id, a)
+	st := GetState(s)
+	switch a := a.(type) {
+	case *VkQueueSubmit:
+		l.checkFence(id, a.Fence, st)
+		writes := newPendingWrites()
+		submits := a.PSubmits.Slice(0, uint64(a.SubmitCount), s)
+		for i := uint64(0); i < submits.Info().Count; i++ {
+			submit := submits.Index(i, s).Read(ctx, a, s, nil)
+			for _, cb := range submit.PCommandBuffers.Slice(0, uint64(submit.CommandBufferCount), s).Read(ctx, a, s, nil) {
+				l.execute(id, cb, writes, st)
+			}
+		}
+	case *VkQueueBindSparse:
+		l.checkFence(id, a.Fence, st)
+	case *VkAcquireNextImageKHR:
+		l.checkFence(id, a.Fence, st)
+	}
+}
+
+// update records the objects created and destroyed, the fences signaled and
+// reset, and the commands recorded by the atom a, after it was applied to
+// the state s.
+func (l *linter) update(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) {
+	l.fences.update(ctx, a, s)
+	for _, o := range createdObjects(ctx, id, a, s) {
+		delete(l.destroyed, objectHandle{o.kind, o.handle})
+		if o.kind == "VkImage" {
+			delete(l.layouts, VkImage(o.handle))
+		}
+	}
+	if h, ok := destroyedObject(a); ok {
+		l.destroyed[h] = id
+	}
+	switch a := a.(type) {
+	case *VkBeginCommandBuffer:
+		delete(l.events, a.CommandBuffer)
+	case *VkResetCommandBuffer:
+		delete(l.events, a.CommandBuffer)
+	default:
+		if cb, ok := recordedCommandBuffer(a); ok {
+			l.record(ctx, id, cb, a, s)
+		}
+	}
+}
+
+// checkHandles reports the object handle operands of the atom a that were
+// destroyed by a previous atom.
+func (l *linter) checkHandles(id atom.ID, a atom.Atom) {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		kind := f.Type().Name()
+		if !f.CanInterface() || f.Kind() != reflect.Uint64 || !objectKinds[kind] || f.Uint() == 0 {
+			continue
+		}
+		if by, ok := l.destroyed[objectHandle{kind, f.Uint()}]; ok {
+			l.report(id, by, lintDestroyedHandle, "%v %v is used after being destroyed by atom %v", kind, f.Uint(), by)
+		}
+	}
+}
+
+// destroyedObject returns the object destroyed by the atom a, if any.
+func destroyedObject(a atom.Atom) (objectHandle, bool) {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return objectHandle{}, false
+	}
+	v = v.Elem()
+	name := v.Type().Name()
+	kind := ""
+	switch {
+	case name == "VkFreeMemory":
+		kind = "VkDeviceMemory"
+	case strings.HasPrefix(name, "VkDestroy"):
+		kind = "Vk" + strings.TrimPrefix(name, "VkDestroy")
+	}
+	if !objectKinds[kind] {
+		return objectHandle{}, false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.CanInterface() && f.Kind() == reflect.Uint64 && f.Type().Name() == kind {
+			return objectHandle{kind, f.Uint()}, f.Uint() != 0
+		}
+	}
+	return objectHandle{}, false
+}
+
+// checkFence reports the submission of a fence that is signaled.
+func (l *linter) checkFence(id atom.ID, fence VkFence, st *State) {
+	if fence != VkFence(0) && l.fences.signaled(st, fence) {
+		l.report(id, atom.NoID, lintUnresetFence, "Fence %v is submitted while signaled, without being reset", fence)
+	}
+}
+
+// record adds the events of the command a recorded to the command buffer cb.
+func (l *linter) record(ctx context.Context, id atom.ID, cb VkCommandBuffer, a atom.Atom, s *gfxapi.State) {
+	add := func(e lintEvent) {
+		e.id = id
+		l.events[cb] = append(l.events[cb], e)
+	}
+	switch a := a.(type) {
+	case *VkCmdCopyBuffer:
+		add(lintEvent{kind: lintRead, buffer: a.SrcBuffer})
+		add(lintEvent{kind: lintWrite, buffer: a.DstBuffer})
+	case *VkCmdUpdateBuffer:
+		add(lintEvent{kind: lintWrite, buffer: a.DstBuffer})
+	case *VkCmdFillBuffer:
+		add(lintEvent{kind: lintWrite, buffer: a.DstBuffer})
+	case *VkCmdCopyImage:
+		for _, r := range a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil) {
+			add(imageLayersEvent(lintRead, a.SrcImage, a.SrcImageLayout, r.SrcSubresource))
+			add(imageLayersEvent(lintWrite, a.DstImage, a.DstImageLayout, r.DstSubresource))
+		}
+	case *VkCmdBlitImage:
+		for _, r := range a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil) {
+			add(imageLayersEvent(lintRead, a.SrcImage, a.SrcImageLayout, r.SrcSubresource))
+			add(imageLayersEvent(lintWrite, a.DstImage, a.DstImageLayout, r.DstSubresource))
+		}
+	case *VkCmdResolveImage:
+		for _, r := range a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil) {
+			add(imageLayersEvent(lintRead, a.SrcImage, a.SrcImageLayout, r.SrcSubresource))
+			add(imageLayersEvent(lintWrite, a.DstImage, a.DstImageLayout, r.DstSubresource))
+		}
+	case *VkCmdCopyBufferToImage:
+		add(lintEvent{kind: lintRead, buffer: a.SrcBuffer})
+		for _, r := range a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil) {
+			add(imageLayersEvent(lintWrite, a.DstImage, a.DstImageLayout, r.ImageSubresource))
+		}
+	case *VkCmdCopyImageToBuffer:
+		for _, r := range a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil) {
+			add(imageLayersEvent(lintRead, a.SrcImage, a.SrcImageLayout, r.ImageSubresource))
+		}
+		add(lintEvent{kind: lintWrite, buffer: a.DstBuffer})
+	case *VkCmdClearColorImage:
+		for _, r := range a.PRanges.Slice(0, uint64(a.RangeCount), s).Read(ctx, a, s, nil) {
+			add(imageRangeEvent(lintWrite, a.Image, a.ImageLayout, a.ImageLayout, r))
+		}
+	case *VkCmdClearDepthStencilImage:
+		for _, r := range a.PRanges.Slice(0, uint64(a.RangeCount), s).Read(ctx, a, s, nil) {
+			add(imageRangeEvent(lintWrite, a.Image, a.ImageLayout, a.ImageLayout, r))
+		}
+	case *VkCmdPipelineBarrier:
+		if a.MemoryBarrierCount > 0 {
+			add(lintEvent{kind: lintMemoryBarrier})
+		}
+		for _, b := range a.PBufferMemoryBarriers.Slice(0, uint64(a.BufferMemoryBarrierCount), s).Read(ctx, a, s, nil) {
+			add(lintEvent{kind: lintBarrier, buffer: b.Buffer})
+		}
+		for _, b := range a.PImageMemoryBarriers.Slice(0, uint64(a.ImageMemoryBarrierCount), s).Read(ctx, a, s, nil) {
+			add(imageRangeEvent(lintBarrier, b.Image, b.OldLayout, b.NewLayout, b.SubresourceRange))
+		}
+	case *VkCmdWaitEvents:
+		if a.MemoryBarrierCount > 0 {
+			add(lintEvent{kind: lintMemoryBarrier})
+		}
+		for _, b := range a.PBufferMemoryBarriers.Slice(0, uint64(a.BufferMemoryBarrierCount), s).Read(ctx, a, s, nil) {
+			add(lintEvent{kind: lintBarrier, buffer: b.Buffer})
+		}
+		for _, b := range a.PImageMemoryBarriers.Slice(0, uint64(a.ImageMemoryBarrierCount), s).Read(ctx, a, s, nil) {
+			add(imageRangeEvent(lintBarrier, b.Image, b.OldLayout, b.NewLayout, b.SubresourceRange))
+		}
+	case *VkCmdBeginRenderPass:
+		st := GetState(s)
+		info := a.PRenderPassBegin.Read(ctx, a, s, nil)
+		if !st.Framebuffers.Contains(info.Framebuffer) || !st.RenderPasses.Contains(info.RenderPass) {
+			return
+		}
+		descriptions := st.RenderPasses.Get(info.RenderPass).AttachmentDescriptions
+		for i, view := range st.Framebuffers.Get(info.Framebuffer).ImageAttachments {
+			if view == nil || view.Image == nil || !descriptions.Contains(i) {
+				continue
+			}
+			d := descriptions.Get(i)
+			add(imageRangeEvent(lintRenderPass, view.Image.VulkanHandle, d.InitialLayout, d.FinalLayout, view.SubresourceRange))
+		}
+	case *VkCmdExecuteCommands:
+		for _, secondary := range a.PCommandBuffers.Slice(0, uint64(a.CommandBufferCount), s).Read(ctx, a, s, nil) {
+			add(lintEvent{kind: lintExecute, secondary: secondary})
+		}
+	}
+}
+
+func imageLayersEvent(kind lintEventKind, image VkImage, layout VkImageLayout, l VkImageSubresourceLayers) lintEvent {
+	return lintEvent{
+		kind:      kind,
+		image:     image,
+		levels:    [2]uint32{l.MipLevel, 1},
+		layers:    [2]uint32{l.BaseArrayLayer, l.LayerCount},
+		oldLayout: layout,
+		newLayout: layout,
+	}
+}
+
+func imageRangeEvent(kind lintEventKind, image VkImage, oldLayout, newLayout VkImageLayout, r VkImageSubresourceRange) lintEvent {
+	return lintEvent{
+		kind:      kind,
+		image:     image,
+		levels:    [2]uint32{r.BaseMipLevel, r.LevelCount},
+		layers:    [2]uint32{r.BaseArrayLayer, r.LayerCount},
+		oldLayout: oldLayout,
+		newLayout: newLayout,
+	}
+}
+
+// execute checks the events of the command buffer cb submitted by the atom
+// submit, in execution order.
+func (l *linter) execute(submit atom.ID, cb VkCommandBuffer, writes *pendingWrites, st *State) {
+	for _, e := range l.events[cb] {
+		switch e.kind {
+		case lintExecute:
+			l.execute(submit, e.secondary, writes, st)
+		case lintMemoryBarrier:
+			*writes = *newPendingWrites()
+		case lintBarrier:
+			delete(writes.images, e.image)
+			delete(writes.buffers, e.buffer)
+			l.transition(submit, e, st)
+		case lintRenderPass:
+			l.transition(submit, e, st)
+		case lintRead, lintWrite:
+			var write atom.ID
+			var pending bool
+			if e.image != VkImage(0) {
+				write, pending = writes.images[e.image]
+			} else {
+				write, pending = writes.buffers[e.buffer]
+			}
+			if e.kind == lintRead && pending {
+				l.report(e.id, write, lintMissingBarrier, "%v is read without a barrier after being written by atom %v", e.resource(), write)
+			}
+			if e.kind == lintWrite {
+				if e.image != VkImage(0) {
+					writes.images[e.image] = e.id
+				} else {
+					writes.buffers[e.buffer] = e.id
+				}
+			}
+			if e.image != VkImage(0) {
+				l.transition(submit, e, st)
+			}
+		}
+	}
+}
+
+// transition checks that the image subresources of the event e are in the
+// layout expected by e, and moves them to the new layout of e.
+func (l *linter) transition(submit atom.ID, e lintEvent, st *State) {
+	if !st.Images.Contains(e.image) {
+		return
+	}
+	image := st.Images.Get(e.image)
+	layouts, ok := l.layouts[e.image]
+	if !ok {
+		layouts = map[imageSubresource]VkImageLayout{}
+		l.layouts[e.image] = layouts
+	}
+	levels := subresourceCount(e.levels, image.Info.MipLevels)
+	layers := subresourceCount(e.layers, image.Info.ArrayLayers)
+	reported := false
+	for layer := e.layers[0]; layer < e.layers[0]+layers; layer++ {
+		for level := e.levels[0]; level < e.levels[0]+levels; level++ {
+			key := imageSubresource{layer, level}
+			current, ok := layouts[key]
+			if !ok {
+				current = image.Info.Layout
+			}
+			if !reported && e.oldLayout != VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED && current != e.oldLayout {
+				l.report(e.id, submit, lintImageLayout, "%v level %v layer %v is used in layout %v but is in layout %v",
+					e.resource(), level, layer, e.oldLayout, current)
+				reported = true
+			}
+			layouts[key] = e.newLayout
+		}
+	}
+}
+
+// subresourceCount returns the number of mip levels or array layers of the
+// range r, clamped to the total count. This resolves VK_REMAINING_MIP_LEVELS
+// and VK_REMAINING_ARRAY_LAYERS.
+func subresourceCount(r [2]uint32, total uint32) uint32 {
+	if r[0] >= total {
+		return 0
+	}
+	if r[1] > total-r[0] {
+		return total - r[0]
+	}
+	return r[1]
+}
@@ -0,0 +1,69 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = []atom.Labeled{
+	&VkCmdBeginDebugUtilsLabelEXT{},
+	&VkCmdInsertDebugUtilsLabelEXT{},
+	&VkQueueBeginDebugUtilsLabelEXT{},
+	&VkQueueInsertDebugUtilsLabelEXT{},
+}
+
+// Label returns the user marker name.
+func (a *VkCmdBeginDebugUtilsLabelEXT) Label(ctx context.Context, s *gfxapi.State) string {
+	return debugUtilsLabel(ctx, a, a.PLabelInfo, s)
+}
+
+// Label returns the user marker name.
+func (a *VkCmdInsertDebugUtilsLabelEXT) Label(ctx context.Context, s *gfxapi.State) string {
+	return debugUtilsLabel(ctx, a, a.PLabelInfo, s)
+}
+
+// Label returns the user marker name.
+func (a *VkQueueBeginDebugUtilsLabelEXT) Label(ctx context.Context, s *gfxapi.State) string {
+	return debugUtilsLabel(ctx, a, a.PLabelInfo, s)
+}
+
+// Label returns the user marker name.
+func (a *VkQueueInsertDebugUtilsLabelEXT) Label(ctx context.Context, s *gfxapi.State) string {
+	return debugUtilsLabel(ctx, a, a.PLabelInfo, s)
+}
+
+// debugUtilsLabel returns the name of the label pointed to by p.
+func debugUtilsLabel(ctx context.Context, a atom.Atom, p VkDebugUtilsLabelEXTᶜᵖ, s *gfxapi.State) string {
+	info := p.Read(ctx, a, s, nil)
+	if info.PLabelName == (Charᶜᵖ{}) {
+		return ""
+	}
+	name := info.PLabelName.StringSlice(ctx, s).Read(ctx, a, s, nil)
+	return strings.TrimRight(string(gfxapi.CharToBytes(name)), "\x00")
+}
+
+// debugName returns the name given by the application to the object with the
+// given handle, or the empty string if the object has not been named.
+func debugName(s *gfxapi.State, handle uint64) string {
+	if s == nil {
+		return ""
+	}
+	return GetState(s).DebugObjectNames[handle]
+}
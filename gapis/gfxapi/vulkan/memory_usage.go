@@ -0,0 +1,72 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = gfxapi.MemoryUsageProvider(api{})
+
+// MemoryUsage returns the device memory allocated with vkAllocateMemory in the
+// state, the memory of each heap, and the footprint of the images and buffers.
+func (api) MemoryUsage(ctx context.Context, s *gfxapi.State) gfxapi.MemoryUsage {
+	st := GetState(s)
+	u := gfxapi.MemoryUsage{}
+	for _, memory := range st.DeviceMemories {
+		size := uint64(memory.AllocationSize)
+		u.Allocated += size
+		heap := memoryHeap(st, memory)
+		for uint32(len(u.Heaps)) <= heap {
+			u.Heaps = append(u.Heaps, 0)
+		}
+		u.Heaps[heap] += size
+	}
+	for _, image := range st.Images {
+		if image.IsSwapchainImage {
+			continue
+		}
+		u.Images += uint64(image.Size)
+		if image.BoundMemory != nil {
+			u.Bound += uint64(image.Size)
+		}
+	}
+	for _, buffer := range st.Buffers {
+		u.Buffers += uint64(buffer.Info.Size)
+		if buffer.Memory != nil {
+			u.Bound += uint64(buffer.Info.Size)
+		}
+	}
+	return u
+}
+
+// memoryHeap returns the index of the heap of the memory allocation.
+func memoryHeap(st *State, memory *DeviceMemoryObject) uint32 {
+	device := st.Devices.Get(memory.Device)
+	if device == nil {
+		return 0
+	}
+	physicalDevice := st.PhysicalDevices.Get(device.PhysicalDevice)
+	if physicalDevice == nil {
+		return 0
+	}
+	properties := physicalDevice.MemoryProperties
+	if memory.MemoryTypeIndex >= properties.MemoryTypeCount {
+		return 0
+	}
+	return properties.MemoryTypes.Elements[memory.MemoryTypeIndex].HeapIndex
+}
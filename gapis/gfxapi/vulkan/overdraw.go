@@ -0,0 +1,220 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// overdraw returns an atom transform that counts the number of times each
+// pixel is drawn in the stencil aspect of the depth and stencil attachments.
+// The render passes clear the stencil to zero and store it, so the counts are
+// those of the render pass last drawing to the attachment, and all the
+// graphics pipelines increment the stencil of the fragments passing the depth
+// test. Draws to subpasses without a stencil attachment are not counted.
+func overdraw(ctx context.Context) transform.Transformer {
+	return transform.Transform("Overdraw", func(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+		s := out.State()
+		a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		switch a := a.(type) {
+		case *VkCreateRenderPass:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			attachments, ok := clearedStencilAttachments(ctx, s, a, info)
+			if !ok {
+				out.MutateAndWrite(ctx, id, a)
+				return
+			}
+			info.PAttachments = VkAttachmentDescriptionᶜᵖ(attachments.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewVkCreateRenderPass(a.Device, newInfo.Ptr(),
+				memory.Pointer(a.PAllocator), memory.Pointer(a.PRenderPass), a.Result)
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, attachments, newInfo))
+		case *RecreateRenderPass:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			attachments, ok := clearedStencilAttachments(ctx, s, a, info)
+			if !ok {
+				out.MutateAndWrite(ctx, id, a)
+				return
+			}
+			info.PAttachments = VkAttachmentDescriptionᶜᵖ(attachments.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewRecreateRenderPass(a.Device, newInfo.Ptr(), memory.Pointer(a.PRenderPass))
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, attachments, newInfo))
+		case *VkCmdBeginRenderPass:
+			info := a.PRenderPassBegin.Read(ctx, a, s, nil)
+			values, count, ok := clearedStencilValues(ctx, s, a, info)
+			if !ok {
+				out.MutateAndWrite(ctx, id, a)
+				return
+			}
+			info.ClearValueCount, info.PClearValues = count, VkClearValueᶜᵖ(values.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewVkCmdBeginRenderPass(a.CommandBuffer, newInfo.Ptr(), a.Contents)
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, values, newInfo))
+		case *RecreateCmdBeginRenderPass:
+			info := a.PRenderPassBegin.Read(ctx, a, s, nil)
+			values, count, ok := clearedStencilValues(ctx, s, a, info)
+			if !ok {
+				out.MutateAndWrite(ctx, id, a)
+				return
+			}
+			info.ClearValueCount, info.PClearValues = count, VkClearValueᶜᵖ(values.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewRecreateCmdBeginRenderPass(a.CommandBuffer, newInfo.Ptr(), a.Contents)
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, values, newInfo))
+		case *VkCreateGraphicsPipelines:
+			infos := a.PCreateInfos.Slice(0, uint64(a.CreateInfoCount), s).Read(ctx, a, s, nil)
+			data := []atom.AllocResult{}
+			for i := range infos {
+				data = append(data, countDraws(ctx, s, a, &infos[i])...)
+			}
+			newInfos := atom.Must(atom.AllocData(ctx, s, infos))
+			newAtom := NewVkCreateGraphicsPipelines(a.Device, a.PipelineCache, a.CreateInfoCount,
+				newInfos.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PPipelines), a.Result)
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, append(data, newInfos)...))
+		case *RecreateGraphicsPipeline:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			data := countDraws(ctx, s, a, &info)
+			if len(data) == 0 {
+				out.MutateAndWrite(ctx, id, a)
+				return
+			}
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewRecreateGraphicsPipeline(a.Device, a.PipelineCache, newInfo.Ptr(), memory.Pointer(a.PPipeline))
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, append(data, newInfo)...))
+		case *VkCmdSetStencilCompareMask, *VkCmdSetStencilWriteMask, *VkCmdSetStencilReference:
+			// Dropped, as countDraws makes the stencil state of the pipelines
+			// static.
+		default:
+			out.MutateAndWrite(ctx, id, a)
+		}
+	})
+}
+
+// hasStencil returns true if the format has a stencil aspect.
+func hasStencil(format VkFormat) bool {
+	_, err := getStencilImageFormatFromVulkanFormat(format)
+	return err == nil
+}
+
+// clearedStencilAttachments returns a copy of the attachments of the render
+// pass created with info, with the stencil of the attachments having one
+// cleared at the start of the render pass and stored at its end. It returns
+// false if no attachment has a stencil aspect.
+func clearedStencilAttachments(ctx context.Context, s *gfxapi.State, a atom.Atom, info VkRenderPassCreateInfo) (atom.AllocResult, bool) {
+	attachments := info.PAttachments.Slice(0, uint64(info.AttachmentCount), s).Read(ctx, a, s, nil)
+	changed := false
+	for i := range attachments {
+		if hasStencil(attachments[i].Format) {
+			attachments[i].StencilLoadOp = VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_CLEAR
+			attachments[i].StencilStoreOp = VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_STORE
+			changed = true
+		}
+	}
+	if !changed {
+		return atom.AllocResult{}, false
+	}
+	return atom.Must(atom.AllocData(ctx, s, attachments)), true
+}
+
+// clearedStencilValues returns the clear values of the render pass begun
+// with info, extended to all the attachments of the render pass, with the
+// stencil of the attachments having one cleared to zero, and their count.
+// It returns false if no attachment of the render pass has a stencil aspect.
+func clearedStencilValues(ctx context.Context, s *gfxapi.State, a atom.Atom, info VkRenderPassBeginInfo) (atom.AllocResult, uint32, bool) {
+	renderPass, ok := GetState(s).RenderPasses[info.RenderPass]
+	if !ok {
+		return atom.AllocResult{}, 0, false
+	}
+	values := info.PClearValues.Slice(0, uint64(info.ClearValueCount), s).Read(ctx, a, s, nil)
+	changed := false
+	for i := uint32(0); i < uint32(len(renderPass.AttachmentDescriptions)); i++ {
+		if !hasStencil(renderPass.AttachmentDescriptions[i].Format) {
+			continue
+		}
+		for uint32(len(values)) <= i {
+			values = append(values, VkClearValue{})
+		}
+		// The depth and stencil clear values alias the first two components
+		// of the color clear value.
+		values[i].Color.Uint32.Elements[1] = 0
+		changed = true
+	}
+	if !changed {
+		return atom.AllocResult{}, 0, false
+	}
+	return atom.Must(atom.AllocData(ctx, s, values)), uint32(len(values)), true
+}
+
+// countDraws changes the pipeline created with info to increment the stencil
+// of the fragments passing the depth test, returning the data it allocated.
+// Pipelines without depth and stencil state, which draw to subpasses without
+// a depth and stencil attachment, are left unchanged.
+func countDraws(ctx context.Context, s *gfxapi.State, a atom.Atom, info *VkGraphicsPipelineCreateInfo) []atom.AllocResult {
+	if info.PDepthStencilState.Address == 0 {
+		return nil
+	}
+	data := []atom.AllocResult{}
+	alloc := func(v interface{}) atom.AllocResult {
+		d := atom.Must(atom.AllocData(ctx, s, v))
+		data = append(data, d)
+		return d
+	}
+
+	count := VkStencilOpState{
+		FailOp:      VkStencilOp_VK_STENCIL_OP_KEEP,
+		PassOp:      VkStencilOp_VK_STENCIL_OP_INCREMENT_AND_CLAMP,
+		DepthFailOp: VkStencilOp_VK_STENCIL_OP_KEEP,
+		CompareOp:   VkCompareOp_VK_COMPARE_OP_ALWAYS,
+		CompareMask: 0xff,
+		WriteMask:   0xff,
+	}
+	depthStencil := info.PDepthStencilState.Read(ctx, a, s, nil)
+	depthStencil.StencilTestEnable = VkBool32(1)
+	depthStencil.Front, depthStencil.Back = count, count
+	info.PDepthStencilState = VkPipelineDepthStencilStateCreateInfoᶜᵖ(alloc(depthStencil).Ptr())
+
+	// The stencil state set by the command buffers would replace the counting
+	// state, so it is made static and the commands setting it are dropped.
+	if info.PDynamicState.Address != 0 {
+		dynamic := info.PDynamicState.Read(ctx, a, s, nil)
+		states := dynamic.PDynamicStates.Slice(0, uint64(dynamic.DynamicStateCount), s).Read(ctx, a, s, nil)
+		kept := []VkDynamicState{}
+		for _, state := range states {
+			switch state {
+			case VkDynamicState_VK_DYNAMIC_STATE_STENCIL_COMPARE_MASK,
+				VkDynamicState_VK_DYNAMIC_STATE_STENCIL_WRITE_MASK,
+				VkDynamicState_VK_DYNAMIC_STATE_STENCIL_REFERENCE:
+			default:
+				kept = append(kept, state)
+			}
+		}
+		switch {
+		case len(kept) == len(states):
+		case len(kept) == 0:
+			info.PDynamicState = NewVkPipelineDynamicStateCreateInfoᶜᵖ(0)
+		default:
+			dynamic.DynamicStateCount = uint32(len(kept))
+			dynamic.PDynamicStates = VkDynamicStateᶜᵖ(alloc(kept).Ptr())
+			info.PDynamicState = VkPipelineDynamicStateCreateInfoᶜᵖ(alloc(dynamic).Ptr())
+		}
+	}
+	return data
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+)
+
+func TestOverdraw(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	atoms, _, _ := samples.DrawMultisampledDepth(ctx)
+	c, err := capture.ImportAtomList(ctx, "overdraw", atoms)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+
+	overdraw := vulkan.Overdraw(ctx)
+	out := &recorder{state: capture.NewState(ctx)}
+	for i, a := range atoms.Atoms {
+		overdraw.Transform(ctx, atom.ID(i), a, out)
+	}
+	overdraw.Flush(ctx, out)
+	assert.For(ctx, "Mutate").ThatError(out.err).Succeeded()
+
+	// The stencil is cleared by the render passes and stored for the read.
+	st := vulkan.GetState(out.state)
+	assert.For(ctx, "Render passes").That(len(st.RenderPasses)).Equals(1)
+	for _, renderPass := range st.RenderPasses {
+		attachment := renderPass.AttachmentDescriptions[0]
+		assert.For(ctx, "Stencil load").That(attachment.StencilLoadOp).Equals(
+			vulkan.VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_CLEAR)
+		assert.For(ctx, "Stencil store").That(attachment.StencilStoreOp).Equals(
+			vulkan.VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_STORE)
+	}
+	// Each fragment passing the depth test increments the stencil.
+	assert.For(ctx, "Pipelines").That(len(st.GraphicsPipelines)).Equals(1)
+	for _, pipeline := range st.GraphicsPipelines {
+		depth := pipeline.DepthState
+		if !assert.For(ctx, "Depth state").That(depth).IsNotNil() {
+			continue
+		}
+		assert.For(ctx, "Stencil test").That(depth.StencilTestEnable).Equals(vulkan.VkBool32(1))
+		assert.For(ctx, "Front pass").That(depth.Front.PassOp).Equals(vulkan.VkStencilOp_VK_STENCIL_OP_INCREMENT_AND_CLAMP)
+		assert.For(ctx, "Back pass").That(depth.Back.PassOp).Equals(vulkan.VkStencilOp_VK_STENCIL_OP_INCREMENT_AND_CLAMP)
+		assert.For(ctx, "Compare").That(depth.Front.CompareOp).Equals(vulkan.VkCompareOp_VK_COMPARE_OP_ALWAYS)
+	}
+}
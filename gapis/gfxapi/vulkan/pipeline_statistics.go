@@ -0,0 +1,199 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/replay/builder"
+	"github.com/google/gapid/gapis/replay/value"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// allPipelineStatistics are the statistics collected for each command, in the
+// order of their results.
+const allPipelineStatistics = VkQueryPipelineStatisticFlags(0x7FF)
+
+// pipelineStatisticsCount is the number of statistics in allPipelineStatistics.
+const pipelineStatisticsCount = 11
+
+// statisticsConfig is a replay.Config used by statisticsRequests.
+type statisticsConfig struct{}
+
+// statisticsRequest requests the pipeline statistics of all the draw and
+// dispatch commands of the capture.
+type statisticsRequest struct {
+	capture *path.Capture
+}
+
+// pipelineStatistics is a transform that surrounds each draw and dispatch
+// recorded to primary command buffers with a pipeline statistics query. The
+// pipelineStatisticsQuery feature is enabled on all the devices.
+type pipelineStatistics struct {
+	res          []replay.Result
+	capture      *path.Capture
+	queries      *commandQueries
+	renderPass   map[VkCommandBuffer]atom.ID // The render pass being recorded.
+	renderPasses map[atom.ID]atom.ID         // The render pass of each command.
+	commands     map[atom.ID]*service.CommandStatistics
+}
+
+func newPipelineStatistics(capture *path.Capture) *pipelineStatistics {
+	return &pipelineStatistics{
+		capture:      capture,
+		queries:      newCommandQueries(VkQueryType_VK_QUERY_TYPE_PIPELINE_STATISTICS, allPipelineStatistics, 1, pipelineStatisticsCount),
+		renderPass:   map[VkCommandBuffer]atom.ID{},
+		renderPasses: map[atom.ID]atom.ID{},
+		commands:     map[atom.ID]*service.CommandStatistics{},
+	}
+}
+
+// reportTo adds r to the results notified with the statistics.
+func (t *pipelineStatistics) reportTo(r replay.Result) {
+	t.res = append(t.res, r)
+}
+
+func (t *pipelineStatistics) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	s := out.State()
+	switch a := a.(type) {
+	case *VkCreateDevice:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		features := enablePipelineStatisticsQuery(ctx, s, a, info.PEnabledFeatures)
+		info.PEnabledFeatures = VkPhysicalDeviceFeaturesᶜᵖ(features.Ptr())
+		newInfo := atom.Must(atom.AllocData(ctx, s, info))
+		newAtom := NewVkCreateDevice(a.PhysicalDevice, newInfo.Ptr(),
+			memory.Pointer(a.PAllocator), memory.Pointer(a.PDevice), a.Result)
+		out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, features, newInfo))
+		return
+	case *RecreateDevice:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		features := enablePipelineStatisticsQuery(ctx, s, a, info.PEnabledFeatures)
+		info.PEnabledFeatures = VkPhysicalDeviceFeaturesᶜᵖ(features.Ptr())
+		newInfo := atom.Must(atom.AllocData(ctx, s, info))
+		newAtom := NewRecreateDevice(a.PhysicalDevice, newInfo.Ptr(), memory.Pointer(a.PDevice))
+		out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, features, newInfo))
+		return
+	case *VkBeginCommandBuffer:
+		out.MutateAndWrite(ctx, id, a)
+		t.queries.begin(ctx, a.CommandBuffer, out)
+		return
+	case *RecreateAndBeginCommandBuffer:
+		out.MutateAndWrite(ctx, id, a)
+		if a.PBeginInfo.Address != 0 {
+			t.queries.begin(ctx, a.PCommandBuffer.Read(ctx, a, s, nil), out)
+		}
+		return
+	case *VkCmdBeginRenderPass:
+		t.renderPass[a.CommandBuffer] = id
+	case *RecreateCmdBeginRenderPass:
+		t.renderPass[a.CommandBuffer] = id
+	case *VkCmdEndRenderPass:
+		delete(t.renderPass, a.CommandBuffer)
+	case *RecreateCmdEndRenderPass:
+		delete(t.renderPass, a.CommandBuffer)
+	case *VkQueueSubmit:
+		out.MutateAndWrite(ctx, id, a)
+		t.queries.submit(ctx, a, out, t.read)
+		return
+	}
+
+	cb, _, ok := constantsCommandBuffer(a)
+	if !ok {
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	pool, query, ok := t.queries.add(ctx, cb, id)
+	if !ok {
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	if rp, ok := t.renderPass[cb]; ok {
+		t.renderPasses[id] = rp
+	}
+	writeEach(ctx, out, NewVkCmdBeginQuery(cb, pool, query, VkQueryControlFlags(0)))
+	out.MutateAndWrite(ctx, id, a)
+	writeEach(ctx, out, NewVkCmdEndQuery(cb, pool, query))
+}
+
+// enablePipelineStatisticsQuery returns a copy of the device features pointed
+// by p, or of no features if p is null, with pipelineStatisticsQuery enabled.
+func enablePipelineStatisticsQuery(ctx context.Context, s *gfxapi.State, a atom.Atom, p VkPhysicalDeviceFeaturesᶜᵖ) atom.AllocResult {
+	features := VkPhysicalDeviceFeatures{}
+	if p.Address != 0 {
+		features = p.Read(ctx, a, s, nil)
+	}
+	features.PipelineStatisticsQuery = VkBool32(1)
+	return atom.Must(atom.AllocData(ctx, s, features))
+}
+
+// read accumulates the statistics of the command id.
+func (t *pipelineStatistics) read(device VkDevice, id atom.ID, results []uint64) {
+	c, ok := t.commands[id]
+	if !ok {
+		c = &service.CommandStatistics{Command: t.capture.Commands().Index(uint64(id))}
+		if rp, ok := t.renderPasses[id]; ok {
+			c.RenderPass = t.capture.Commands().Index(uint64(rp))
+		}
+		t.commands[id] = c
+	}
+	c.Count++
+	c.InputAssemblyVertices += results[0]
+	c.InputAssemblyPrimitives += results[1]
+	c.VertexShaderInvocations += results[2]
+	c.GeometryShaderInvocations += results[3]
+	c.GeometryShaderPrimitives += results[4]
+	c.ClippingInvocations += results[5]
+	c.ClippingPrimitives += results[6]
+	c.FragmentShaderInvocations += results[7]
+	c.TessellationControlShaderPatches += results[8]
+	c.TessellationEvaluationShaderInvocations += results[9]
+	c.ComputeShaderInvocations += results[10]
+}
+
+func (t *pipelineStatistics) Flush(ctx context.Context, out transform.Writer) {
+	// Notify the results once all the posted statistics have been received.
+	out.MutateAndWrite(ctx, atom.NoID, replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+		code := uint32(0x57a75eed)
+		b.Push(value.U32(code))
+		b.Post(b.Buffer(1), 4, func(r pod.Reader, err error) error {
+			if err == nil && r.Uint32() != code {
+				err = fmt.Errorf("Flush did not get expected EOS code")
+			}
+			if err != nil {
+				for _, res := range t.res {
+					res(nil, err)
+				}
+				return err
+			}
+			stats := &service.PipelineStatistics{}
+			for _, c := range t.commands {
+				stats.Commands = append(stats.Commands, c)
+			}
+			for _, res := range t.res {
+				res(stats, nil)
+			}
+			return nil
+		})
+		return nil
+	}))
+}
@@ -0,0 +1,856 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// formatSubstitutes lists, for formats that are not supported by all devices,
+// the formats that can be used instead, in order of preference. The texel
+// data of substituted images is converted when the images are primed and when
+// it is copied to the images from buffers.
+var formatSubstitutes = map[VkFormat][]VkFormat{
+	VkFormat_VK_FORMAT_B8G8R8A8_UNORM:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_R8G8B8A8_UNORM:      {VkFormat_VK_FORMAT_B8G8R8A8_UNORM},
+	VkFormat_VK_FORMAT_B8G8R8A8_SRGB:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_R8G8B8A8_SRGB:       {VkFormat_VK_FORMAT_B8G8R8A8_SRGB},
+	VkFormat_VK_FORMAT_R8G8B8_UNORM:        {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_B8G8R8_UNORM:        {VkFormat_VK_FORMAT_B8G8R8A8_UNORM, VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_R16G16B16_SFLOAT:    {VkFormat_VK_FORMAT_R16G16B16A16_SFLOAT},
+	VkFormat_VK_FORMAT_R32G32B32_SFLOAT:    {VkFormat_VK_FORMAT_R32G32B32A32_SFLOAT},
+	VkFormat_VK_FORMAT_D24_UNORM_S8_UINT:   {VkFormat_VK_FORMAT_D32_SFLOAT_S8_UINT},
+	VkFormat_VK_FORMAT_D32_SFLOAT_S8_UINT:  {VkFormat_VK_FORMAT_D24_UNORM_S8_UINT},
+	VkFormat_VK_FORMAT_D16_UNORM_S8_UINT:   {VkFormat_VK_FORMAT_D24_UNORM_S8_UINT, VkFormat_VK_FORMAT_D32_SFLOAT_S8_UINT},
+	VkFormat_VK_FORMAT_X8_D24_UNORM_PACK32: {VkFormat_VK_FORMAT_D32_SFLOAT, VkFormat_VK_FORMAT_D24_UNORM_S8_UINT},
+	VkFormat_VK_FORMAT_D32_SFLOAT:          {VkFormat_VK_FORMAT_X8_D24_UNORM_PACK32, VkFormat_VK_FORMAT_D32_SFLOAT_S8_UINT},
+}
+
+// decompressedSubstitutes lists, for the compressed formats, the uncompressed
+// formats they are decoded to when the replay device does not support them.
+// The images are primed and copied to with the decoded texel data, so they are
+// bound to memory sized for the substitute format, like other substitutes.
+var decompressedSubstitutes = map[VkFormat][]VkFormat{
+	VkFormat_VK_FORMAT_BC1_RGB_UNORM_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_BC1_RGB_SRGB_BLOCK:        {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_BC1_RGBA_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_BC1_RGBA_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_BC2_UNORM_BLOCK:           {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_BC2_SRGB_BLOCK:            {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_BC3_UNORM_BLOCK:           {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_BC3_SRGB_BLOCK:            {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_BC4_UNORM_BLOCK:           {VkFormat_VK_FORMAT_R8_UNORM},
+	VkFormat_VK_FORMAT_BC4_SNORM_BLOCK:           {VkFormat_VK_FORMAT_R8_SNORM},
+	VkFormat_VK_FORMAT_BC5_UNORM_BLOCK:           {VkFormat_VK_FORMAT_R8G8_UNORM},
+	VkFormat_VK_FORMAT_BC5_SNORM_BLOCK:           {VkFormat_VK_FORMAT_R8G8_SNORM},
+	VkFormat_VK_FORMAT_BC6H_UFLOAT_BLOCK:         {VkFormat_VK_FORMAT_R16G16B16A16_SFLOAT},
+	VkFormat_VK_FORMAT_BC6H_SFLOAT_BLOCK:         {VkFormat_VK_FORMAT_R16G16B16A16_SFLOAT},
+	VkFormat_VK_FORMAT_BC7_UNORM_BLOCK:           {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_BC7_SRGB_BLOCK:            {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ETC2_R8G8B8_UNORM_BLOCK:   {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ETC2_R8G8B8_SRGB_BLOCK:    {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ETC2_R8G8B8A1_UNORM_BLOCK: {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ETC2_R8G8B8A1_SRGB_BLOCK:  {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ETC2_R8G8B8A8_UNORM_BLOCK: {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ETC2_R8G8B8A8_SRGB_BLOCK:  {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_EAC_R11_UNORM_BLOCK:       {VkFormat_VK_FORMAT_R16_UNORM},
+	VkFormat_VK_FORMAT_EAC_R11_SNORM_BLOCK:       {VkFormat_VK_FORMAT_R16_SNORM},
+	VkFormat_VK_FORMAT_EAC_R11G11_UNORM_BLOCK:    {VkFormat_VK_FORMAT_R16G16_UNORM},
+	VkFormat_VK_FORMAT_EAC_R11G11_SNORM_BLOCK:    {VkFormat_VK_FORMAT_R16G16_SNORM},
+	VkFormat_VK_FORMAT_ASTC_4x4_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_4x4_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_5x4_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_5x4_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_5x5_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_5x5_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_6x5_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_6x5_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_6x6_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_6x6_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_8x5_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_8x5_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_8x6_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_8x6_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_8x8_UNORM_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_8x8_SRGB_BLOCK:       {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_10x5_UNORM_BLOCK:     {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_10x5_SRGB_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_10x6_UNORM_BLOCK:     {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_10x6_SRGB_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_10x8_UNORM_BLOCK:     {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_10x8_SRGB_BLOCK:      {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_10x10_UNORM_BLOCK:    {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_10x10_SRGB_BLOCK:     {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_12x10_UNORM_BLOCK:    {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_12x10_SRGB_BLOCK:     {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+	VkFormat_VK_FORMAT_ASTC_12x12_UNORM_BLOCK:    {VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+	VkFormat_VK_FORMAT_ASTC_12x12_SRGB_BLOCK:     {VkFormat_VK_FORMAT_R8G8B8A8_SRGB},
+}
+
+var depthStencilFormats = map[VkFormat]bool{
+	VkFormat_VK_FORMAT_D16_UNORM:           true,
+	VkFormat_VK_FORMAT_X8_D24_UNORM_PACK32: true,
+	VkFormat_VK_FORMAT_D32_SFLOAT:          true,
+	VkFormat_VK_FORMAT_S8_UINT:             true,
+	VkFormat_VK_FORMAT_D16_UNORM_S8_UINT:   true,
+	VkFormat_VK_FORMAT_D24_UNORM_S8_UINT:   true,
+	VkFormat_VK_FORMAT_D32_SFLOAT_S8_UINT:  true,
+}
+
+// portability is a transform that adapts a replay to a device that differs
+// from the one the capture was taken on. Memory type indices and queue family
+// indices are remapped to the replay device's types and families with the same
+// capabilities, and images using formats unsupported by the replay device are
+// created with a substitute format.
+//
+// As the size and alignment of an image with a substitute format differ from
+// the captured ones, the image is bound to its own memory, allocated with the
+// requirements of the replay device, instead of the captured memory. The image
+// data primed by the state rebuilding atoms and copied from buffers by the
+// capture's commands is converted to the substitute format. Buffer data is
+// converted as it is when the copy is recorded.
+type portability struct {
+	devices       []*device.VulkanPhysicalDevice // The replay physical devices.
+	memoryTypes   map[remapKey]uint32
+	queueFamilies map[remapKey]uint32
+	images        map[VkImage]formatRemap     // The images created with a substitute format.
+	imageMemories map[VkImage]VkDeviceMemory  // The memories bound to the substituted images.
+	staging       map[VkImage][]stagingBuffer // The converted copy sources of the substituted images.
+	warned        map[VkFormat]bool
+}
+
+type remapKey struct {
+	physicalDevice VkPhysicalDevice
+	index          uint32
+}
+
+type formatRemap struct {
+	from, to VkFormat
+}
+
+// stagingBuffer is a buffer holding converted data, and its memory.
+type stagingBuffer struct {
+	buffer VkBuffer
+	memory VkDeviceMemory
+}
+
+// newPortability returns a portability transform for replaying on a device
+// with the given Vulkan driver, or nil if the driver does not describe its
+// physical devices.
+func newPortability(driver *device.VulkanDriver) *portability {
+	if len(driver.GetPhysicalDevices()) == 0 {
+		return nil
+	}
+	return &portability{
+		devices:       driver.PhysicalDevices,
+		memoryTypes:   map[remapKey]uint32{},
+		queueFamilies: map[remapKey]uint32{},
+		images:        map[VkImage]formatRemap{},
+		imageMemories: map[VkImage]VkDeviceMemory{},
+		staging:       map[VkImage][]stagingBuffer{},
+		warned:        map[VkFormat]bool{},
+	}
+}
+
+// replayDevice returns the replay physical device standing in for the
+// captured physical device pd.
+func (t *portability) replayDevice(st *State, pd VkPhysicalDevice) *device.VulkanPhysicalDevice {
+	if !st.PhysicalDevices.Contains(pd) {
+		return t.devices[0]
+	}
+	if i := int(st.PhysicalDevices.Get(pd).Index); i < len(t.devices) {
+		return t.devices[i]
+	}
+	return t.devices[0]
+}
+
+// physicalDevice returns the physical device of the logical device d.
+func physicalDevice(st *State, d VkDevice) VkPhysicalDevice {
+	if !st.Devices.Contains(d) {
+		return VkPhysicalDevice(0)
+	}
+	return st.Devices.Get(d).PhysicalDevice
+}
+
+// closestMatch returns the index of the flags in have, among the indices whose
+// bit is set in allowed, that miss the fewest bits of want and then hold the
+// fewest extra bits, preferring the index prefer if its flags equal want. If
+// no index is allowed, prefer is returned.
+func closestMatch(want uint32, have []uint32, allowed uint32, prefer uint32) uint32 {
+	isAllowed := func(i uint32) bool { return i < 32 && allowed&(1<<i) != 0 }
+	if int(prefer) < len(have) && have[prefer] == want && isAllowed(prefer) {
+		return prefer
+	}
+	best, bestMissing, bestExtra := prefer, 33, 33
+	for i, flags := range have {
+		if !isAllowed(uint32(i)) {
+			continue
+		}
+		missing, extra := bitCount(want&^flags), bitCount(flags&^want)
+		if missing < bestMissing || (missing == bestMissing && extra < bestExtra) {
+			best, bestMissing, bestExtra = uint32(i), missing, extra
+		}
+	}
+	return best
+}
+
+func bitCount(v uint32) int {
+	c := 0
+	for ; v != 0; v &= v - 1 {
+		c++
+	}
+	return c
+}
+
+// memoryTypeBits returns the memory types of the replay device d that both
+// buffers and images can be bound to, or those either can be bound to if they
+// have none in common. All the types are allowed if d does not list them.
+func memoryTypeBits(d *device.VulkanPhysicalDevice) uint32 {
+	buffers, images := d.BufferMemoryTypeBits, d.ImageMemoryTypeBits
+	switch {
+	case buffers&images != 0:
+		return buffers & images
+	case buffers|images != 0:
+		return buffers | images
+	default:
+		return ^uint32(0)
+	}
+}
+
+// memoryType returns the replay memory type index for the captured memory
+// type index i of the device d.
+func (t *portability) memoryType(st *State, d VkDevice, i uint32) uint32 {
+	pd := physicalDevice(st, d)
+	key := remapKey{pd, i}
+	if r, ok := t.memoryTypes[key]; ok {
+		return r
+	}
+	r := i
+	if st.PhysicalDevices.Contains(pd) {
+		props := st.PhysicalDevices.Get(pd).MemoryProperties
+		if i < props.MemoryTypeCount {
+			want := uint32(props.MemoryTypes.Elements[i].PropertyFlags)
+			replay := t.replayDevice(st, pd)
+			have := make([]uint32, len(replay.MemoryTypes))
+			for j, ty := range replay.MemoryTypes {
+				have[j] = ty.PropertyFlags
+			}
+			r = closestMatch(want, have, memoryTypeBits(replay), i)
+		}
+	}
+	t.memoryTypes[key] = r
+	return r
+}
+
+// memoryProperties returns the memory properties of the replay device standing
+// in for the captured physical device pd.
+func (t *portability) memoryProperties(st *State, pd VkPhysicalDevice) VkPhysicalDeviceMemoryProperties {
+	props := VkPhysicalDeviceMemoryProperties{}
+	if st.PhysicalDevices.Contains(pd) {
+		props = st.PhysicalDevices.Get(pd).MemoryProperties
+	}
+	types := t.replayDevice(st, pd).MemoryTypes
+	props.MemoryTypeCount = uint32(len(types))
+	for i, ty := range types {
+		props.MemoryTypes.Elements[i] = VkMemoryType{
+			PropertyFlags: VkMemoryPropertyFlags(ty.PropertyFlags),
+			HeapIndex:     ty.HeapIndex,
+		}
+	}
+	return props
+}
+
+// queueFamily returns the replay queue family index for the captured queue
+// family index i of the physical device pd.
+func (t *portability) queueFamily(st *State, pd VkPhysicalDevice, i uint32) uint32 {
+	key := remapKey{pd, i}
+	if r, ok := t.queueFamilies[key]; ok {
+		return r
+	}
+	r := i
+	if st.PhysicalDevices.Contains(pd) {
+		if props, ok := st.PhysicalDevices.Get(pd).QueueFamilyProperties[i]; ok {
+			families := t.replayDevice(st, pd).QueueFamilies
+			have := make([]uint32, len(families))
+			for j, f := range families {
+				have[j] = f.QueueFlags
+			}
+			r = closestMatch(uint32(props.QueueFlags), have, i)
+		}
+	}
+	t.queueFamilies[key] = r
+	return r
+}
+
+// requiredFeatures returns the format features needed by an image with the
+// given usage.
+func requiredFeatures(usage VkImageUsageFlags) uint32 {
+	features := uint32(0)
+	for bit, feature := range map[VkImageUsageFlagBits]VkFormatFeatureFlagBits{
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT:                  VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_SAMPLED_IMAGE_BIT,
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_STORAGE_BIT:                  VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_STORAGE_IMAGE_BIT,
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT:         VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_COLOR_ATTACHMENT_BIT,
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT: VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_DEPTH_STENCIL_ATTACHMENT_BIT,
+	} {
+		if uint32(usage)&uint32(bit) != 0 {
+			features |= uint32(feature)
+		}
+	}
+	return features
+}
+
+// format returns the format to use on the replay device for a format used
+// with the given tiling and features by the device d.
+func (t *portability) format(ctx context.Context, st *State, d VkDevice, f VkFormat, tiling VkImageTiling, features uint32) VkFormat {
+	formats := t.replayDevice(st, physicalDevice(st, d)).Formats
+	supported := func(f VkFormat) bool {
+		for _, p := range formats {
+			if VkFormat(p.Format) == f {
+				have := p.OptimalTilingFeatures
+				if tiling == VkImageTiling_VK_IMAGE_TILING_LINEAR {
+					have = p.LinearTilingFeatures
+				}
+				return have != 0 && have&features == features
+			}
+		}
+		return false
+	}
+	if len(formats) == 0 || supported(f) {
+		return f
+	}
+	for _, substitute := range substitutes(f) {
+		if supported(substitute) {
+			return substitute
+		}
+	}
+	if !t.warned[f] {
+		t.warned[f] = true
+		log.W(ctx, "Format %v is not supported by the replay device and has no substitute", f)
+	}
+	return f
+}
+
+// substitutes returns the formats that can be used instead of f, in order of
+// preference.
+func substitutes(f VkFormat) []VkFormat {
+	if s, ok := decompressedSubstitutes[f]; ok {
+		return s
+	}
+	return formatSubstitutes[f]
+}
+
+// attachmentFeatures returns the format features needed by a render pass
+// attachment of the format f.
+func attachmentFeatures(f VkFormat) uint32 {
+	if depthStencilFormats[f] {
+		return uint32(VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_DEPTH_STENCIL_ATTACHMENT_BIT)
+	}
+	return uint32(VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_COLOR_ATTACHMENT_BIT)
+}
+
+// rebuilt adds the extras and observations of a to the replacement atom n,
+// along with the reads of the replacement data.
+func rebuilt(a, n atom.Atom, data ...atom.AllocResult) atom.Atom {
+	for _, e := range a.Extras().All() {
+		if _, ok := e.(*atom.Observations); !ok {
+			n.Extras().Add(e)
+		}
+	}
+	o := n.Extras().GetOrAppendObservations()
+	old := a.Extras().Observations()
+	if old != nil {
+		for _, r := range old.Reads {
+			o.AddRead(r.Range, r.ID)
+		}
+	}
+	for _, d := range data {
+		o.AddRead(d.Data())
+	}
+	if old != nil {
+		for _, w := range old.Writes {
+			o.AddWrite(w.Range, w.ID)
+		}
+	}
+	return n
+}
+
+func (t *portability) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	s := out.State()
+	st := GetState(s)
+	a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+
+	switch a := a.(type) {
+	case *VkAllocateMemory:
+		info := a.PAllocateInfo.Read(ctx, a, s, nil)
+		if r := t.memoryType(st, a.Device, info.MemoryTypeIndex); r != info.MemoryTypeIndex {
+			info.MemoryTypeIndex = r
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewVkAllocateMemory(a.Device, newInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PMemory), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			return
+		}
+
+	case *RecreateDeviceMemory:
+		info := a.PAllocateInfo.Read(ctx, a, s, nil)
+		if r := t.memoryType(st, a.Device, info.MemoryTypeIndex); r != info.MemoryTypeIndex {
+			info.MemoryTypeIndex = r
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewRecreateDeviceMemory(a.Device, newInfo.Ptr(), a.MappedOffset, a.MappedSize, memory.Pointer(a.PpData), memory.Pointer(a.PMemory))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			return
+		}
+
+	case *RecreateBufferData:
+		if r := t.memoryType(st, a.Device, a.HostBufferMemoryIndex); r != a.HostBufferMemoryIndex {
+			n := NewRecreateBufferData(a.Device, a.Buffer, r, a.LastBoundQueue, memory.Pointer(a.Data))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n))
+			return
+		}
+
+	case *RecreateImageData:
+		t.recreateImageData(ctx, id, a, out)
+		return
+
+	case *VkCreateDevice:
+		if newInfo, ok := t.deviceCreateInfo(ctx, a, a.PhysicalDevice, a.PCreateInfo, s); ok {
+			n := NewVkCreateDevice(a.PhysicalDevice, newInfo[0].Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PDevice), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo...))
+			return
+		}
+
+	case *RecreateDevice:
+		if newInfo, ok := t.deviceCreateInfo(ctx, a, a.PhysicalDevice, a.PCreateInfo, s); ok {
+			n := NewRecreateDevice(a.PhysicalDevice, newInfo[0].Ptr(), memory.Pointer(a.PDevice))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo...))
+			return
+		}
+
+	case *VkGetDeviceQueue:
+		pd := physicalDevice(st, a.Device)
+		if r := t.queueFamily(st, pd, a.QueueFamilyIndex); r != a.QueueFamilyIndex {
+			n := NewVkGetDeviceQueue(a.Device, r, a.QueueIndex, memory.Pointer(a.PQueue))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n))
+			return
+		}
+
+	case *RecreateQueue:
+		pd := physicalDevice(st, a.Device)
+		if r := t.queueFamily(st, pd, a.QueueFamilyIndex); r != a.QueueFamilyIndex {
+			n := NewRecreateQueue(a.Device, r, a.QueueIndex, memory.Pointer(a.PQueue))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n))
+			return
+		}
+
+	case *VkCreateCommandPool:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		pd := physicalDevice(st, a.Device)
+		if r := t.queueFamily(st, pd, info.QueueFamilyIndex); r != info.QueueFamilyIndex {
+			info.QueueFamilyIndex = r
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewVkCreateCommandPool(a.Device, newInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PCommandPool), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			return
+		}
+
+	case *RecreateCommandPool:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		pd := physicalDevice(st, a.Device)
+		if r := t.queueFamily(st, pd, info.QueueFamilyIndex); r != info.QueueFamilyIndex {
+			info.QueueFamilyIndex = r
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewRecreateCommandPool(a.Device, newInfo.Ptr(), memory.Pointer(a.PCommandPool))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			return
+		}
+
+	case *VkCreateImage:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		f := t.format(ctx, st, a.Device, info.Format, info.Tiling, requiredFeatures(info.Usage))
+		if f != info.Format {
+			remap := formatRemap{info.Format, f}
+			info.Format = f
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewVkCreateImage(a.Device, newInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PImage), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			t.images[a.PImage.Slice(0, 1, s).Index(0, s).Read(ctx, a, s, nil)] = remap
+			return
+		}
+
+	case *RecreateImage:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		f := t.format(ctx, st, a.Device, info.Format, info.Tiling, requiredFeatures(info.Usage))
+		if f != info.Format {
+			remap := formatRemap{info.Format, f}
+			info.Format = f
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewRecreateImage(a.Device, newInfo.Ptr(), memory.Pointer(a.PImage))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			t.images[a.PImage.Slice(0, 1, s).Index(0, s).Read(ctx, a, s, nil)] = remap
+			return
+		}
+
+	case *VkCreateImageView:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		if remap, ok := t.images[info.Image]; ok && info.Format == remap.from {
+			info.Format = remap.to
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewVkCreateImageView(a.Device, newInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PView), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			return
+		}
+
+	case *RecreateImageView:
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		if remap, ok := t.images[info.Image]; ok && info.Format == remap.from {
+			info.Format = remap.to
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			n := NewRecreateImageView(a.Device, newInfo.Ptr(), memory.Pointer(a.PImageView))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo))
+			return
+		}
+
+	case *VkBindImageMemory:
+		if dedicated, ok := t.imageMemory(ctx, a.Device, a.Image, out); ok {
+			n := NewVkBindImageMemory(a.Device, a.Image, dedicated, VkDeviceSize(0), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n))
+			return
+		}
+
+	case *RecreateBindImageMemory:
+		if a.Memory == VkDeviceMemory(0) {
+			break
+		}
+		if dedicated, ok := t.imageMemory(ctx, a.Device, a.Image, out); ok {
+			n := NewRecreateBindImageMemory(a.Device, a.Image, dedicated, VkDeviceSize(0))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n))
+			return
+		}
+
+	case *VkDestroyImage:
+		out.MutateAndWrite(ctx, id, a)
+		t.freeImageMemory(ctx, a.Device, a.Image, out)
+		return
+
+	case *VkCmdCopyBufferToImage:
+		if remap, ok := t.images[a.DstImage]; ok {
+			t.copyBufferToImage(ctx, id, a, remap, out)
+			return
+		}
+
+	case *VkCreateRenderPass:
+		if newInfo, ok := t.renderPassCreateInfo(ctx, a, a.Device, a.PCreateInfo, s); ok {
+			n := NewVkCreateRenderPass(a.Device, newInfo[0].Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PRenderPass), a.Result)
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo...))
+			return
+		}
+
+	case *RecreateRenderPass:
+		if newInfo, ok := t.renderPassCreateInfo(ctx, a, a.Device, a.PCreateInfo, s); ok {
+			n := NewRecreateRenderPass(a.Device, newInfo[0].Ptr(), memory.Pointer(a.PRenderPass))
+			out.MutateAndWrite(ctx, id, rebuilt(a, n, newInfo...))
+			return
+		}
+	}
+
+	out.MutateAndWrite(ctx, id, a)
+}
+
+// deviceCreateInfo returns the device create info and queue create infos with
+// the queue family indices remapped, or false if no index needs remapping.
+func (t *portability) deviceCreateInfo(ctx context.Context, a atom.Atom, pd VkPhysicalDevice, p VkDeviceCreateInfoᶜᵖ, s *gfxapi.State) ([]atom.AllocResult, bool) {
+	st := GetState(s)
+	info := p.Read(ctx, a, s, nil)
+	queues := info.PQueueCreateInfos.Slice(0, uint64(info.QueueCreateInfoCount), s).Read(ctx, a, s, nil)
+	changed := false
+	for i := range queues {
+		if r := t.queueFamily(st, pd, queues[i].QueueFamilyIndex); r != queues[i].QueueFamilyIndex {
+			queues[i].QueueFamilyIndex = r
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	newQueues := atom.Must(atom.AllocData(ctx, s, queues))
+	info.PQueueCreateInfos = VkDeviceQueueCreateInfoᶜᵖ(newQueues.Ptr())
+	newInfo := atom.Must(atom.AllocData(ctx, s, info))
+	return []atom.AllocResult{newInfo, newQueues}, true
+}
+
+// renderPassCreateInfo returns the render pass create info and attachments
+// with the attachment formats substituted, or false if no attachment needs
+// a substitute format.
+func (t *portability) renderPassCreateInfo(ctx context.Context, a atom.Atom, d VkDevice, p VkRenderPassCreateInfoᶜᵖ, s *gfxapi.State) ([]atom.AllocResult, bool) {
+	st := GetState(s)
+	info := p.Read(ctx, a, s, nil)
+	attachments := info.PAttachments.Slice(0, uint64(info.AttachmentCount), s).Read(ctx, a, s, nil)
+	changed := false
+	for i := range attachments {
+		f := attachments[i].Format
+		r := t.format(ctx, st, d, f, VkImageTiling_VK_IMAGE_TILING_OPTIMAL, attachmentFeatures(f))
+		if r != f {
+			attachments[i].Format = r
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	newAttachments := atom.Must(atom.AllocData(ctx, s, attachments))
+	info.PAttachments = VkAttachmentDescriptionᶜᵖ(newAttachments.Ptr())
+	newInfo := atom.Must(atom.AllocData(ctx, s, info))
+	return []atom.AllocResult{newInfo, newAttachments}, true
+}
+
+// recreateImageData writes the atom priming the data of an image, converting
+// the data if the image was created with a substitute format.
+func (t *portability) recreateImageData(ctx context.Context, id atom.ID, a *RecreateImageData, out transform.Writer) {
+	s := out.State()
+	st := GetState(s)
+	hostMemoryIndex := t.memoryType(st, a.Device, a.HostMemoryIndex)
+	remap, converted := t.images[a.Image]
+	if !converted || a.Data == NewVoidᵖ(0) {
+		if hostMemoryIndex == a.HostMemoryIndex {
+			out.MutateAndWrite(ctx, id, a)
+			return
+		}
+		n := NewRecreateImageData(a.Device, a.Image, a.LastLayout, hostMemoryIndex, a.LastBoundQueue, a.DataSize, memory.Pointer(a.Data))
+		out.MutateAndWrite(ctx, id, rebuilt(a, n))
+		return
+	}
+
+	data, err := convertImageData(ctx, a, st.Images.Get(a.Image).Info, remap, s)
+	if err != nil {
+		log.W(ctx, "Dropping the data of image %v: %v", a.Image, err)
+		return
+	}
+	newData := atom.Must(atom.AllocData(ctx, s, data))
+	n := NewRecreateImageData(a.Device, a.Image, a.LastLayout, hostMemoryIndex, a.LastBoundQueue, VkDeviceSize(len(data)), newData.Ptr())
+	out.MutateAndWrite(ctx, id, rebuilt(a, n, newData))
+}
+
+// convertImageData returns the tightly packed data of all the levels and
+// layers of the image primed by a, converted from the captured format to the
+// substitute format.
+func convertImageData(ctx context.Context, a *RecreateImageData, info ImageInfo, remap formatRemap, s *gfxapi.State) ([]byte, error) {
+	from, err := getImageFormatFromVulkanFormat(remap.from)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getImageFormatFromVulkanFormat(remap.to)
+	if err != nil {
+		return nil, err
+	}
+	src := U8ᵖ(a.Data).Slice(0, uint64(a.DataSize), s).Read(ctx, a, s, nil)
+	out := []byte{}
+	offset := 0
+	for level := uint32(0); level < info.MipLevels; level++ {
+		width, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, info.Extent.Width, level)
+		height, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, info.Extent.Height, level)
+		depth, _ := subGetMipSize(ctx, a, nil, nil, nil, nil, info.Extent.Depth, level)
+		// The layers and slices of the level are converted separately as
+		// compressed formats cannot be treated as a single tall image.
+		size := from.Size(int(width), int(height))
+		for i := uint32(0); i < depth*info.ArrayLayers; i++ {
+			if offset+size > len(src) {
+				return nil, log.Errf(ctx, nil, "Image data too small for format %v", remap.from)
+			}
+			converted, err := image.Convert(src[offset:offset+size], int(width), int(height), from, to)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, converted...)
+			offset += size
+		}
+	}
+	return out, nil
+}
+
+// imageMemory writes the atom allocating the memory of the image created with
+// a substitute format, sized for the image by the replay device, and returns
+// the memory. It returns false if the image has the captured format.
+func (t *portability) imageMemory(ctx context.Context, d VkDevice, img VkImage, out transform.Writer) (VkDeviceMemory, bool) {
+	if _, ok := t.images[img]; !ok {
+		return VkDeviceMemory(0), false
+	}
+	s := out.State()
+	st := GetState(s)
+	deviceMemory := VkDeviceMemory(newUnusedID(false, func(x uint64) bool { return st.DeviceMemories.Contains(VkDeviceMemory(x)) }))
+	memoryProperties := atom.Must(atom.AllocData(ctx, s, t.memoryProperties(st, physicalDevice(st, d))))
+	defer memoryProperties.Free()
+	memoryData := atom.Must(atom.AllocData(ctx, s, deviceMemory))
+	defer memoryData.Free()
+	writeEach(ctx, out,
+		NewReplayAllocateImageMemory(
+			d,
+			memoryProperties.Ptr(),
+			img,
+			memoryData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(memoryProperties.Data()).AddWrite(memoryData.Data()),
+	)
+	t.imageMemories[img] = deviceMemory
+	return deviceMemory, true
+}
+
+// freeImageMemory writes the atoms freeing the memory allocated for the image
+// and the staging buffers of its copies.
+func (t *portability) freeImageMemory(ctx context.Context, d VkDevice, img VkImage, out transform.Writer) {
+	if m, ok := t.imageMemories[img]; ok {
+		writeEach(ctx, out, NewVkFreeMemory(d, m, memory.Pointer{}))
+		delete(t.imageMemories, img)
+	}
+	for _, b := range t.staging[img] {
+		writeEach(ctx, out,
+			NewVkDestroyBuffer(d, b.buffer, memory.Pointer{}),
+			NewVkFreeMemory(d, b.memory, memory.Pointer{}),
+		)
+	}
+	delete(t.staging, img)
+}
+
+// copyBufferToImage writes the atom recording the copy a to an image created
+// with a substitute format, copying from a staging buffer primed with the
+// source data converted to the substitute format. The copy is left as is if
+// the data cannot be converted.
+func (t *portability) copyBufferToImage(ctx context.Context, id atom.ID, a *VkCmdCopyBufferToImage, remap formatRemap, out transform.Writer) {
+	s := out.State()
+	st := GetState(s)
+	d := st.CommandBuffers.Get(a.CommandBuffer).Device
+	regions := a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil)
+	data, newRegions, err := convertCopyData(ctx, a, st.Buffers.Get(a.SrcBuffer), regions, remap, s)
+	queue, hasQueue := deviceQueue(st, d, nil)
+	hostMemory, hasHostMemory := hostMemoryType(st, d)
+	switch {
+	case err != nil:
+		log.W(ctx, "Copying unconverted data to image %v: %v", a.DstImage, err)
+	case !hasQueue || !hasHostMemory:
+		log.W(ctx, "Copying unconverted data to image %v: No queue or host visible memory", a.DstImage)
+	}
+	if err != nil || !hasQueue || !hasHostMemory {
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	hostMemory = t.memoryType(st, d, hostMemory)
+
+	buffer := VkBuffer(newUnusedID(false, func(x uint64) bool { return st.Buffers.Contains(VkBuffer(x)) }))
+	deviceMemory := VkDeviceMemory(newUnusedID(false, func(x uint64) bool { return st.DeviceMemories.Contains(VkDeviceMemory(x)) }))
+	createInfo := atom.Must(atom.AllocData(ctx, s, VkBufferCreateInfo{
+		SType:                 VkStructureType_VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO,
+		PNext:                 NewVoidᶜᵖ(0),
+		Flags:                 VkBufferCreateFlags(0),
+		Size:                  VkDeviceSize(len(data)),
+		Usage:                 VkBufferUsageFlags(VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_SRC_BIT | VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT),
+		SharingMode:           VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
+		QueueFamilyIndexCount: 0,
+		PQueueFamilyIndices:   NewU32ᶜᵖ(0),
+	}))
+	defer createInfo.Free()
+	bufferData := atom.Must(atom.AllocData(ctx, s, buffer))
+	defer bufferData.Free()
+	allocateInfo := atom.Must(atom.AllocData(ctx, s, VkMemoryAllocateInfo{
+		SType:           VkStructureType_VK_STRUCTURE_TYPE_MEMORY_ALLOCATE_INFO,
+		PNext:           NewVoidᶜᵖ(0),
+		AllocationSize:  VkDeviceSize(len(data)),
+		MemoryTypeIndex: hostMemory,
+	}))
+	defer allocateInfo.Free()
+	memoryData := atom.Must(atom.AllocData(ctx, s, deviceMemory))
+	defer memoryData.Free()
+	texels := atom.Must(atom.AllocData(ctx, s, data))
+	defer texels.Free()
+
+	writeEach(ctx, out,
+		NewVkCreateBuffer(
+			d,
+			createInfo.Ptr(),
+			memory.Pointer{},
+			bufferData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(createInfo.Data()).AddWrite(bufferData.Data()),
+		NewVkAllocateMemory(
+			d,
+			allocateInfo.Ptr(),
+			memory.Pointer{},
+			memoryData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(allocateInfo.Data()).AddWrite(memoryData.Data()),
+		NewVkBindBufferMemory(d, buffer, deviceMemory, VkDeviceSize(0), VkResult_VK_SUCCESS),
+		NewRecreateBufferData(d, buffer, hostMemory, queue, texels.Ptr()).AddRead(texels.Data()),
+	)
+	t.staging[a.DstImage] = append(t.staging[a.DstImage], stagingBuffer{buffer, deviceMemory})
+
+	regionsData := atom.Must(atom.AllocData(ctx, s, newRegions))
+	n := NewVkCmdCopyBufferToImage(a.CommandBuffer, buffer, a.DstImage, a.DstImageLayout, uint32(len(newRegions)), regionsData.Ptr())
+	out.MutateAndWrite(ctx, id, rebuilt(a, n, regionsData))
+}
+
+// convertCopyData returns the data copied by the regions from the buffer,
+// converted from the captured format to the substitute format, and the regions
+// copying the converted data. Only tightly packed regions are supported.
+func convertCopyData(ctx context.Context, a atom.Atom, buffer *BufferObject, regions []VkBufferImageCopy, remap formatRemap, s *gfxapi.State) ([]byte, []VkBufferImageCopy, error) {
+	if buffer == nil || buffer.Memory == nil {
+		return nil, nil, log.Errf(ctx, nil, "Copy source buffer has no memory")
+	}
+	from, err := getImageFormatFromVulkanFormat(remap.from)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := getImageFormatFromVulkanFormat(remap.to)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := []byte{}
+	newRegions := make([]VkBufferImageCopy, len(regions))
+	for i, r := range regions {
+		width, height := r.ImageExtent.Width, r.ImageExtent.Height
+		if (r.BufferRowLength != 0 && r.BufferRowLength != width) ||
+			(r.BufferImageHeight != 0 && r.BufferImageHeight != height) {
+			return nil, nil, log.Errf(ctx, nil, "Unsupported buffer layout %dx%d for a %dx%d copy",
+				r.BufferRowLength, r.BufferImageHeight, width, height)
+		}
+		size := uint64(from.Size(int(width), int(height)))
+		slices := uint64(r.ImageExtent.Depth) * uint64(r.ImageSubresource.LayerCount)
+		start := uint64(buffer.MemoryOffset) + uint64(r.BufferOffset)
+		if start+size*slices > buffer.Memory.Data.Count {
+			return nil, nil, log.Errf(ctx, nil, "Copy source out of the bounds of buffer %v", buffer.VulkanHandle)
+		}
+		src := buffer.Memory.Data.Slice(start, start+size*slices, s).Read(ctx, a, s, nil)
+		// Keep the offsets of the regions a multiple of any texel size.
+		for len(out)%16 != 0 {
+			out = append(out, 0)
+		}
+		newRegions[i] = r
+		newRegions[i].BufferOffset = VkDeviceSize(len(out))
+		newRegions[i].BufferRowLength = 0
+		newRegions[i].BufferImageHeight = 0
+		for j := uint64(0); j < slices; j++ {
+			converted, err := image.Convert(src[j*size:(j+1)*size], int(width), int(height), from, to)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, converted...)
+		}
+	}
+	return out, newRegions, nil
+}
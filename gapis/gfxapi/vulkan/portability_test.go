@@ -0,0 +1,117 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+func TestClosestMatch(t *testing.T) {
+	ctx := log.Testing(t)
+	all := ^uint32(0)
+	for _, test := range []struct {
+		name    string
+		want    uint32
+		have    []uint32
+		allowed uint32
+		prefer  uint32
+		match   uint32
+	}{
+		{"preferred", 0x1, []uint32{0x1, 0x1}, all, 1, 1},
+		{"fewest extra bits", 0x1, []uint32{0x7, 0x3}, all, 0, 1},
+		{"fewest missing bits", 0x7, []uint32{0x1, 0x3}, all, 0, 1},
+		{"preferred not allowed", 0x1, []uint32{0x1, 0x3}, 0x2, 0, 1},
+		{"none allowed", 0x1, []uint32{0x1}, 0, 3, 3},
+	} {
+		got := closestMatch(test.want, test.have, test.allowed, test.prefer)
+		assert.For(ctx, test.name).That(got).Equals(test.match)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	ctx := log.Testing(t)
+	const (
+		sampled    = uint32(VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_SAMPLED_IMAGE_BIT)
+		attachment = uint32(VkFormatFeatureFlagBits_VK_FORMAT_FEATURE_COLOR_ATTACHMENT_BIT)
+		optimal    = VkImageTiling_VK_IMAGE_TILING_OPTIMAL
+		linear     = VkImageTiling_VK_IMAGE_TILING_LINEAR
+	)
+	p := newPortability(&device.VulkanDriver{PhysicalDevices: []*device.VulkanPhysicalDevice{{
+		Formats: []*device.VulkanFormatProperties{
+			{Format: uint32(VkFormat_VK_FORMAT_R8G8B8A8_UNORM), OptimalTilingFeatures: sampled | attachment},
+			{Format: uint32(VkFormat_VK_FORMAT_B8G8R8A8_UNORM), OptimalTilingFeatures: sampled},
+		},
+	}}})
+	st := &State{}
+	for _, test := range []struct {
+		name     string
+		format   VkFormat
+		tiling   VkImageTiling
+		features uint32
+		expected VkFormat
+	}{
+		{"supported", VkFormat_VK_FORMAT_B8G8R8A8_UNORM, optimal, sampled, VkFormat_VK_FORMAT_B8G8R8A8_UNORM},
+		{"missing features", VkFormat_VK_FORMAT_B8G8R8A8_UNORM, optimal, attachment, VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+		{"first supported substitute", VkFormat_VK_FORMAT_B8G8R8_UNORM, optimal, sampled | attachment, VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+		{"compressed", VkFormat_VK_FORMAT_BC1_RGBA_UNORM_BLOCK, optimal, sampled, VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+		{"unsupported tiling", VkFormat_VK_FORMAT_R8G8B8A8_UNORM, linear, sampled, VkFormat_VK_FORMAT_R8G8B8A8_UNORM},
+		{"no substitute", VkFormat_VK_FORMAT_R16_UNORM, optimal, sampled, VkFormat_VK_FORMAT_R16_UNORM},
+	} {
+		got := p.format(ctx, st, VkDevice(1), test.format, test.tiling, test.features)
+		assert.For(ctx, test.name).That(got).Equals(test.expected)
+	}
+
+	// Devices not listing their formats are assumed to support all of them.
+	p = newPortability(&device.VulkanDriver{PhysicalDevices: []*device.VulkanPhysicalDevice{{}}})
+	got := p.format(ctx, st, VkDevice(1), VkFormat_VK_FORMAT_B8G8R8_UNORM, optimal, sampled)
+	assert.For(ctx, "no formats").That(got).Equals(VkFormat_VK_FORMAT_B8G8R8_UNORM)
+}
+
+func TestConvertImageData(t *testing.T) {
+	ctx := log.Testing(t)
+	s := gfxapi.NewStateWithEmptyAllocator()
+	remap := formatRemap{VkFormat_VK_FORMAT_B8G8R8A8_UNORM, VkFormat_VK_FORMAT_R8G8B8A8_UNORM}
+	// The 2x2 and 1x1 levels of two layers.
+	info := ImageInfo{Extent: VkExtent3D{Width: 2, Height: 2, Depth: 1}, MipLevels: 2, ArrayLayers: 2}
+	bgra, rgba := []byte{}, []byte{}
+	for i := byte(0); i < 10; i++ {
+		b, g, r, a := i*4, i*4+1, i*4+2, i*4+3
+		bgra = append(bgra, b, g, r, a)
+		rgba = append(rgba, r, g, b, a)
+	}
+	prime := func(data []byte) *RecreateImageData {
+		d := atom.Must(atom.AllocData(ctx, s, data))
+		a := NewRecreateImageData(VkDevice(1), VkImage(1), VkImageLayout_VK_IMAGE_LAYOUT_GENERAL,
+			0, VkQueue(1), VkDeviceSize(len(data)), d.Ptr())
+		a.AddRead(d.Data())
+		a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		return a
+	}
+
+	got, err := convertImageData(ctx, prime(bgra), info, remap, s)
+	if assert.For(ctx, "convert").ThatError(err).Succeeded() {
+		assert.For(ctx, "converted").ThatSlice(got).Equals(rgba)
+	}
+
+	_, err = convertImageData(ctx, prime(bgra[:36]), info, remap, s)
+	assert.For(ctx, "truncated").ThatError(err).Failed()
+}
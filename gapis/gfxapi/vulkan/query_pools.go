@@ -0,0 +1,193 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/replay/builder"
+	"github.com/google/gapid/gapis/replay/value"
+)
+
+// commandQueryCount is the number of queries of the query pool created for
+// each instrumented command buffer.
+const commandQueryCount = 4096
+
+// commandQueryPool is the query pool of a primary command buffer.
+type commandQueryPool struct {
+	device VkDevice
+	pool   VkQueryPool
+	atoms  []atom.ID // The command instrumented by each group of queries.
+}
+
+// commandQueries manages the query pools used by replay instrumentations that
+// surround commands recorded to primary command buffers with queries. The
+// results of the queries are read back after each submission of the command
+// buffers.
+type commandQueries struct {
+	info       VkQueryPoolCreateInfo // The create info of the query pools.
+	perCommand uint32                // The number of queries per command.
+	values     uint32                // The number of results per query.
+	pools      map[VkCommandBuffer]*commandQueryPool
+	overflow   bool
+}
+
+func newCommandQueries(ty VkQueryType, statistics VkQueryPipelineStatisticFlags, perCommand, values uint32) *commandQueries {
+	return &commandQueries{
+		info: VkQueryPoolCreateInfo{
+			SType:              VkStructureType_VK_STRUCTURE_TYPE_QUERY_POOL_CREATE_INFO,
+			PNext:              NewVoidᶜᵖ(0),
+			Flags:              VkQueryPoolCreateFlags(0),
+			QueryType:          ty,
+			QueryCount:         commandQueryCount,
+			PipelineStatistics: statistics,
+		},
+		perCommand: perCommand,
+		values:     values,
+		pools:      map[VkCommandBuffer]*commandQueryPool{},
+	}
+}
+
+// begin resets the query pool of the primary command buffer cb, creating it
+// if needed. It returns the device of the command buffer, and false if the
+// command buffer is not instrumented.
+func (q *commandQueries) begin(ctx context.Context, cb VkCommandBuffer, out transform.Writer) (VkDevice, bool) {
+	s := out.State()
+	st := GetState(s)
+	if !st.CommandBuffers.Contains(cb) {
+		return 0, false
+	}
+	obj := st.CommandBuffers.Get(cb)
+	if obj.Level != VkCommandBufferLevel_VK_COMMAND_BUFFER_LEVEL_PRIMARY {
+		// Secondary command buffers may begin inside a render pass, where
+		// queries cannot be reset.
+		return 0, false
+	}
+
+	p, ok := q.pools[cb]
+	if !ok {
+		p = &commandQueryPool{
+			device: obj.Device,
+			pool:   VkQueryPool(newUnusedID(false, func(x uint64) bool { return st.QueryPools.Contains(VkQueryPool(x)) })),
+		}
+		createInfo := atom.Must(atom.AllocData(ctx, s, q.info))
+		defer createInfo.Free()
+		poolData := atom.Must(atom.AllocData(ctx, s, p.pool))
+		defer poolData.Free()
+
+		writeEach(ctx, out, NewVkCreateQueryPool(
+			p.device,
+			createInfo.Ptr(),
+			memory.Pointer{},
+			poolData.Ptr(),
+			VkResult_VK_SUCCESS,
+		).AddRead(createInfo.Data()).AddWrite(poolData.Data()))
+		q.pools[cb] = p
+	}
+	p.atoms = p.atoms[:0]
+	writeEach(ctx, out, NewVkCmdResetQueryPool(cb, p.pool, 0, commandQueryCount))
+	return p.device, true
+}
+
+// add allocates the queries of the command id recorded to cb. It returns the
+// query pool and the first query, and false if the command buffer is not
+// instrumented or has no query left.
+func (q *commandQueries) add(ctx context.Context, cb VkCommandBuffer, id atom.ID) (VkQueryPool, uint32, bool) {
+	p, ok := q.pools[cb]
+	if !ok {
+		return 0, 0, false
+	}
+	first := uint32(len(p.atoms)) * q.perCommand
+	if first+q.perCommand > commandQueryCount {
+		if !q.overflow {
+			log.W(ctx, "Too many commands in command buffer %v, only the first %d are instrumented",
+				cb, commandQueryCount/q.perCommand)
+			q.overflow = true
+		}
+		return 0, 0, false
+	}
+	p.atoms = append(p.atoms, id)
+	return p.pool, first, true
+}
+
+// submit writes the atoms waiting for the submission a and reading back the
+// results of the queries of the submitted command buffers. read is called
+// during the replay with the results of the queries of each command.
+func (q *commandQueries) submit(ctx context.Context, a *VkQueueSubmit, out transform.Writer, read func(device VkDevice, id atom.ID, results []uint64)) {
+	s := out.State()
+	pools := []*commandQueryPool{}
+	submits := a.PSubmits.Slice(0, uint64(a.SubmitCount), s)
+	for i := uint64(0); i < submits.Info().Count; i++ {
+		submit := submits.Index(i, s).Read(ctx, a, s, nil)
+		cbs := submit.PCommandBuffers.Slice(0, uint64(submit.CommandBufferCount), s)
+		for j := uint64(0); j < cbs.Info().Count; j++ {
+			if p, ok := q.pools[cbs.Index(j, s).Read(ctx, a, s, nil)]; ok && len(p.atoms) > 0 {
+				pools = append(pools, p)
+			}
+		}
+	}
+	if len(pools) == 0 {
+		return
+	}
+
+	writeEach(ctx, out, NewVkQueueWaitIdle(a.Queue, VkResult_VK_SUCCESS))
+	for _, p := range pools {
+		device, atoms := p.device, append([]atom.ID{}, p.atoms...)
+		count := uint32(len(atoms)) * q.perCommand
+		stride := uint64(q.values) * 8
+		size := uint64(count) * stride
+		results := atom.Must(atom.AllocData(ctx, s, make([]uint64, size/8)))
+		writeEach(ctx, out,
+			NewVkGetQueryPoolResults(
+				p.device,
+				p.pool,
+				0,
+				count,
+				size,
+				results.Ptr(),
+				VkDeviceSize(stride),
+				VkQueryResultFlags(VkQueryResultFlagBits_VK_QUERY_RESULT_64_BIT|VkQueryResultFlagBits_VK_QUERY_RESULT_WAIT_BIT),
+				VkResult_VK_SUCCESS,
+			).AddWrite(results.Data()),
+			replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+				b.Post(value.ObservedPointer(results.Address()), size, func(r pod.Reader, err error) error {
+					if err != nil {
+						return err
+					}
+					values := make([]uint64, q.perCommand*q.values)
+					for _, id := range atoms {
+						for i := range values {
+							values[i] = r.Uint64()
+						}
+						if err := r.Error(); err != nil {
+							return err
+						}
+						read(device, id, values)
+					}
+					return nil
+				})
+				return nil
+			}),
+		)
+		results.Free()
+	}
+}
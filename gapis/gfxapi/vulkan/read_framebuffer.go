@@ -83,6 +83,21 @@ func (t *readFramebuffer) Depth(id atom.ID, res replay.Result) {
 	})
 }
 
+func (t *readFramebuffer) Stencil(id atom.ID, res replay.Result) {
+	t.injections[id] = append(t.injections[id], func(ctx context.Context, out transform.Writer) {
+		s := out.State()
+		attachment := gfxapi.FramebufferAttachment_Stencil
+		w, h, form, attachmentIndex, err := GetState(s).getFramebufferAttachmentInfo(attachment)
+		if err != nil {
+			res(nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage("Invalid Stencil attachment")})
+			return
+		}
+		imageViewStencil := GetState(s).LastDrawInfo.Framebuffer.ImageAttachments[attachmentIndex]
+		stencilImageObject := imageViewStencil.Image
+		postImageData(ctx, s, stencilImageObject, form, VkImageAspectFlagBits_VK_IMAGE_ASPECT_STENCIL_BIT, w, h, w, h, out, res)
+	})
+}
+
 func (t *readFramebuffer) Color(id atom.ID, width, height, bufferIdx uint32, res replay.Result) {
 	t.injections[id] = append(t.injections[id], func(ctx context.Context, out transform.Writer) {
 		s := out.State()
@@ -96,16 +111,48 @@ func (t *readFramebuffer) Color(id atom.ID, width, height, bufferIdx uint32, res
 		// TODO: Figure out a better way to select the framebuffer here.
 		imageView := GetState(s).LastDrawInfo.Framebuffer.ImageAttachments[attachmentIndex]
 		imageObject := imageView.Image
-		postImageData(ctx, s, imageObject, form, VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT, w, h, width, height, out, res)
+		aspect := VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT
+		if len(imageObject.Planes) > 0 {
+			// Only the plane of the view is attached, and is read.
+			aspect = VkImageAspectFlagBits(imageView.SubresourceRange.AspectMask)
+		}
+		postImageData(ctx, s, imageObject, form, aspect, w, h, width, height, out, res)
 	})
 }
 
+// isPlaneAspect returns true if the aspect selects a plane of a multi-planar
+// image.
+func isPlaneAspect(aspect VkImageAspectFlagBits) bool {
+	for _, p := range planeAspects {
+		if VkImageAspectFlags(aspect) == p {
+			return true
+		}
+	}
+	return false
+}
+
 func writeEach(ctx context.Context, out transform.Writer, atoms ...atom.Atom) {
 	for _, a := range atoms {
 		out.MutateAndWrite(ctx, atom.NoID, a)
 	}
 }
 
+// saveLastDrawInfo returns a function restoring the last draw of the state,
+// which the draws injected for reading the framebuffer replace once submitted.
+func saveLastDrawInfo(st *State) func() {
+	last := st.LastDrawInfo
+	sets := map[uint32]*DescriptorSetObject{}
+	for i, set := range last.DescriptorSets {
+		sets[i] = set
+	}
+	return func() {
+		for i := range st.LastDrawInfo.DescriptorSets {
+			st.LastDrawInfo.DescriptorSets[i] = sets[i]
+		}
+		st.LastDrawInfo = last
+	}
+}
+
 func newUnusedID(isDispatchable bool, existenceTest func(uint64) bool) uint64 {
 	for {
 		x := uint64(rand.Uint32())
@@ -135,7 +182,8 @@ func postImageData(ctx context.Context,
 	// image is not created with this format.
 	var formatOfImgRes *image.Format = nil
 	var err error = nil
-	if aspectMask == VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT {
+	plane := isPlaneAspect(aspectMask)
+	if aspectMask == VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT || plane {
 		formatOfImgRes, err = getImageFormatFromVulkanFormat(vkFormat)
 	} else if aspectMask == VkImageAspectFlagBits_VK_IMAGE_ASPECT_DEPTH_BIT {
 		// When depth image is requested, the format, which is used for
@@ -144,6 +192,8 @@ func postImageData(ctx context.Context,
 		// because we need to strip the stencil data if the source attachment image
 		// contains both depth and stencil data.
 		formatOfImgRes, err = getDepthImageFormatFromVulkanFormat(vkFormat)
+	} else if aspectMask == VkImageAspectFlagBits_VK_IMAGE_ASPECT_STENCIL_BIT {
+		formatOfImgRes, err = getStencilImageFormatFromVulkanFormat(vkFormat)
 	} else {
 		res(nil, &service.ErrDataUnavailable{Reason: messages.ErrFramebufferUnavailable()})
 		return
@@ -153,6 +203,15 @@ func postImageData(ctx context.Context,
 		return
 	}
 
+	// The plane of a multi-planar image, vkFormat being the format of the
+	// plane, is copied to color images. Multi-planar images cannot be
+	// blitted, so the plane is not scaled to the requested size.
+	srcAspectMask := VkImageAspectFlags(aspectMask)
+	if plane {
+		aspectMask = VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT
+		reqWidth, reqHeight = imgWidth, imgHeight
+	}
+
 	queue := imageObject.LastBoundQueue
 	vkQueue := queue.VulkanHandle
 	vkDevice := queue.Device
@@ -272,6 +331,16 @@ func postImageData(ctx context.Context,
 	}))
 	stagingImageMemoryData := MustAllocData(ctx, s, stagingImageMemoryId)
 
+	// vkCmdResolveImage cannot resolve depth aspects, so multi-sampled depth is
+	// resolved by a draw writing the depth of the resolve image instead.
+	depthResolving := aspectMask == VkImageAspectFlagBits_VK_IMAGE_ASPECT_DEPTH_BIT &&
+		imageObject.Info.Samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT
+	resolveImageUsage := VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT |
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT)
+	if depthResolving {
+		resolveImageUsage |= VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT)
+	}
+
 	// Data and info for resolve image creation. Resolve image is used when the attachment image is multi-sampled
 	resolveImageId := VkImage(newUnusedID(false, func(x uint64) bool { _, ok := GetState(s).Images[VkImage(x)]; return ok }))
 	resolveImageCreateInfo := VkImageCreateInfo{
@@ -285,12 +354,11 @@ func postImageData(ctx context.Context,
 			Height: imgHeight, // same height as the attachment image, not the request
 			Depth:  1,
 		},
-		MipLevels:   1,
-		ArrayLayers: 1,
-		Samples:     VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT,
-		Tiling:      VkImageTiling_VK_IMAGE_TILING_OPTIMAL,
-		Usage: VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT |
-			VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT),
+		MipLevels:             1,
+		ArrayLayers:           1,
+		Samples:               VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT,
+		Tiling:                VkImageTiling_VK_IMAGE_TILING_OPTIMAL,
+		Usage:                 resolveImageUsage,
 		SharingMode:           VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
 		QueueFamilyIndexCount: 0,
 		PQueueFamilyIndices:   NewU32ᶜᵖ(0),
@@ -305,6 +373,15 @@ func postImageData(ctx context.Context,
 	}))
 	resolveImageMemoryData := MustAllocData(ctx, s, resolveImageMemoryId)
 
+	var depthResolver *depthResolve
+	if depthResolving {
+		depthResolver, err = newDepthResolve(s, vkDevice, imageObject, resolveImageId, imgWidth, imgHeight)
+		if err != nil {
+			res(nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage(fmt.Sprintf("Could not resolve the multi-sampled depth: %v", err))})
+			return
+		}
+	}
+
 	// Command pool and command buffer
 	commandPoolId := VkCommandPool(newUnusedID(false, func(x uint64) bool { _, ok := GetState(s).CommandPools[VkCommandPool(x)]; return ok }))
 	commandPoolCreateInfo := VkCommandPoolCreateInfo{
@@ -475,7 +552,17 @@ func postImageData(ctx context.Context,
 	}
 	resolveImageToSrcBarrierData := MustAllocData(ctx, s, resolveImageToSrcBarrier)
 
-	// Barrier data for layout transitions of attachment image
+	// Barrier data for layout transitions of attachment image. The depth
+	// resolve samples the attachment image, instead of transferring from it.
+	// The color aspect of a multi-planar image covers all its planes.
+	attachmentImageLayout := VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL
+	attachmentImageAccess := VkAccessFlags(VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT)
+	attachmentImageAspects := VkImageAspectFlags(aspectMask)
+	if depthResolving {
+		attachmentImageLayout = VkImageLayout_VK_IMAGE_LAYOUT_DEPTH_STENCIL_READ_ONLY_OPTIMAL
+		attachmentImageAccess = VkAccessFlags(VkAccessFlagBits_VK_ACCESS_SHADER_READ_BIT)
+		attachmentImageAspects = depthStencilAspects(vkFormat)
+	}
 	attachmentImageToSrcBarrier := VkImageMemoryBarrier{
 		SType: VkStructureType_VK_STRUCTURE_TYPE_IMAGE_MEMORY_BARRIER,
 		PNext: NewVoidᶜᵖ(0),
@@ -486,14 +573,14 @@ func postImageData(ctx context.Context,
 				VkAccessFlagBits_VK_ACCESS_TRANSFER_WRITE_BIT |
 				VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT,
 		),
-		DstAccessMask:       VkAccessFlags(VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT),
-		NewLayout:           VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL,
+		DstAccessMask:       attachmentImageAccess,
+		NewLayout:           attachmentImageLayout,
 		OldLayout:           imageObject.Info.Layout,
 		SrcQueueFamilyIndex: 0xFFFFFFFF,
 		DstQueueFamilyIndex: 0xFFFFFFFF,
 		Image:               imageObject.VulkanHandle,
 		SubresourceRange: VkImageSubresourceRange{
-			AspectMask:     VkImageAspectFlags(aspectMask),
+			AspectMask:     attachmentImageAspects,
 			BaseMipLevel:   0,
 			LevelCount:     1,
 			BaseArrayLayer: 0,
@@ -503,11 +590,9 @@ func postImageData(ctx context.Context,
 	attachmentImageToSrcBarrierData := MustAllocData(ctx, s, attachmentImageToSrcBarrier)
 
 	attachmentImageResetLayoutBarrier := VkImageMemoryBarrier{
-		SType: VkStructureType_VK_STRUCTURE_TYPE_IMAGE_MEMORY_BARRIER,
-		PNext: NewVoidᶜᵖ(0),
-		SrcAccessMask: VkAccessFlags(
-			VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT,
-		),
+		SType:         VkStructureType_VK_STRUCTURE_TYPE_IMAGE_MEMORY_BARRIER,
+		PNext:         NewVoidᶜᵖ(0),
+		SrcAccessMask: attachmentImageAccess,
 		DstAccessMask: VkAccessFlags(
 			VkAccessFlagBits_VK_ACCESS_COLOR_ATTACHMENT_WRITE_BIT |
 				VkAccessFlagBits_VK_ACCESS_DEPTH_STENCIL_ATTACHMENT_WRITE_BIT |
@@ -515,12 +600,12 @@ func postImageData(ctx context.Context,
 				VkAccessFlagBits_VK_ACCESS_TRANSFER_WRITE_BIT |
 				VkAccessFlagBits_VK_ACCESS_TRANSFER_READ_BIT),
 		NewLayout:           imageObject.Info.Layout,
-		OldLayout:           VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL,
+		OldLayout:           attachmentImageLayout,
 		SrcQueueFamilyIndex: 0xFFFFFFFF,
 		DstQueueFamilyIndex: 0xFFFFFFFF,
 		Image:               imageObject.VulkanHandle,
 		SubresourceRange: VkImageSubresourceRange{
-			AspectMask:     VkImageAspectFlags(aspectMask),
+			AspectMask:     attachmentImageAspects,
 			BaseMipLevel:   0,
 			LevelCount:     1,
 			BaseArrayLayer: 0,
@@ -532,7 +617,7 @@ func postImageData(ctx context.Context,
 	// Observation data for vkCmdBlitImage
 	imageBlit := VkImageBlit{
 		SrcSubresource: VkImageSubresourceLayers{
-			AspectMask:     VkImageAspectFlags(aspectMask),
+			AspectMask:     srcAspectMask,
 			MipLevel:       0,
 			BaseArrayLayer: 0,
 			LayerCount:     1,
@@ -574,10 +659,20 @@ func postImageData(ctx context.Context,
 	}
 	imageBlitData := MustAllocData(ctx, s, imageBlit)
 
+	// Observation data for vkCmdCopyImage
+	imageCopy := VkImageCopy{
+		SrcSubresource: imageBlit.SrcSubresource,
+		SrcOffset:      VkOffset3D{X: 0, Y: 0, Z: 0},
+		DstSubresource: imageBlit.DstSubresource,
+		DstOffset:      VkOffset3D{X: 0, Y: 0, Z: 0},
+		Extent:         VkExtent3D{Width: imgWidth, Height: imgHeight, Depth: 1},
+	}
+	imageCopyData := MustAllocData(ctx, s, imageCopy)
+
 	// Observation data for vkCmdResolveImage
 	imageResolve := VkImageResolve{
 		SrcSubresource: VkImageSubresourceLayers{
-			AspectMask:     VkImageAspectFlags(aspectMask),
+			AspectMask:     srcAspectMask,
 			MipLevel:       0,
 			BaseArrayLayer: 0,
 			LayerCount:     1,
@@ -607,6 +702,9 @@ func postImageData(ctx context.Context,
 	imageResolveData := MustAllocData(ctx, s, imageResolve)
 
 	// Write atoms to writer
+	if depthResolving {
+		defer saveLastDrawInfo(GetState(s))()
+	}
 	// Create staging image, allocate and bind memory
 	writeEach(ctx, out,
 		NewVkCreateImage(
@@ -707,6 +805,9 @@ func postImageData(ctx context.Context,
 			),
 		)
 	}
+	if depthResolving {
+		depthResolver.create(ctx, s, out, MustAllocData)
+	}
 
 	// Create command pool, allocate command buffer
 	writeEach(ctx, out,
@@ -789,8 +890,11 @@ func postImageData(ctx context.Context,
 
 	// If the attachment image is multi-sampled, resolve the attchment image to resolve image before
 	// blit the image. Change the resolve image layout, call vkCmdResolveImage, change the resolve
-	// image layout again.fmt
-	if imageObject.Info.Samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT {
+	// image layout again. Multi-sampled depth is resolved by a draw instead,
+	// which leaves the resolve image in the transfer source layout.
+	if depthResolving {
+		depthResolver.record(ctx, s, out, MustAllocData, commandBufferId)
+	} else if imageObject.Info.Samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT {
 		writeEach(ctx, out,
 			NewVkCmdPipelineBarrier(
 				commandBufferId,
@@ -838,23 +942,39 @@ func postImageData(ctx context.Context,
 	if imageObject.Info.Samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT {
 		blitSrcImage = resolveImageId
 	}
-	// If the src image is a depth/stencil image, the filter must be NEAREST
-	filter := VkFilter_VK_FILTER_LINEAR
-	if aspectMask != VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT {
-		filter = VkFilter_VK_FILTER_NEAREST
+	if srcAspectMask != VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT) && imgWidth == reqWidth && imgHeight == reqHeight {
+		// Depth and stencil formats rarely support blits, copy the aspect
+		// instead when no scaling is needed. Planes are always copied.
+		writeEach(ctx, out,
+			NewVkCmdCopyImage(
+				commandBufferId,
+				blitSrcImage,
+				VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL,
+				stagingImageId,
+				VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL,
+				1,
+				imageCopyData.Ptr(),
+			).AddRead(imageCopyData.Data()),
+		)
+	} else {
+		// If the src image is a depth/stencil image, the filter must be NEAREST
+		filter := VkFilter_VK_FILTER_LINEAR
+		if aspectMask != VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT {
+			filter = VkFilter_VK_FILTER_NEAREST
+		}
+		writeEach(ctx, out,
+			NewVkCmdBlitImage(
+				commandBufferId,
+				blitSrcImage,
+				VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL,
+				stagingImageId,
+				VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL,
+				1,
+				imageBlitData.Ptr(),
+				filter,
+			).AddRead(imageBlitData.Data()),
+		)
 	}
-	writeEach(ctx, out,
-		NewVkCmdBlitImage(
-			commandBufferId,
-			blitSrcImage,
-			VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL,
-			stagingImageId,
-			VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL,
-			1,
-			imageBlitData.Ptr(),
-			filter,
-		).AddRead(imageBlitData.Data()),
-	)
 
 	// Change the layout of staging image and attachment image, copy staging image to buffer,
 	// end command buffer
@@ -997,6 +1117,9 @@ func postImageData(ctx context.Context,
 		NewVkDestroyImage(vkDevice, stagingImageId, memory.Pointer{}),
 		NewVkFreeMemory(vkDevice, stagingImageMemoryId, memory.Pointer{}),
 		NewVkFreeMemory(vkDevice, bufferMemoryId, memory.Pointer{}))
+	if depthResolving {
+		depthResolver.destroy(ctx, out)
+	}
 	if imageObject.Info.Samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT {
 		writeEach(ctx, out,
 			NewVkDestroyImage(vkDevice, resolveImageId, memory.Pointer{}),
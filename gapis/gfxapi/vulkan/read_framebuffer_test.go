@@ -0,0 +1,158 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+)
+
+// recorder mutates its state with the atoms written to it, recording the
+// identifiers of the atoms, the injected atoms and the first mutation error.
+type recorder struct {
+	state    *gfxapi.State
+	written  []atom.ID
+	injected []atom.Atom
+	err      error
+}
+
+func (r *recorder) State() *gfxapi.State { return r.state }
+
+func (r *recorder) MutateAndWrite(ctx context.Context, id atom.ID, a atom.Atom) {
+	if err := a.Mutate(ctx, r.state, nil); err != nil && r.err == nil {
+		r.err = fmt.Errorf("%T: %v", a, err)
+	}
+	r.written = append(r.written, id)
+	if id == atom.NoID {
+		r.injected = append(r.injected, a)
+	}
+}
+
+func TestReadMultisampledDepth(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	atoms, _, submit := samples.DrawMultisampledDepth(ctx)
+	c, err := capture.ImportAtomList(ctx, "multisampled-depth", atoms)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+
+	read := vulkan.NewReadFramebuffer(ctx)
+	var depthErr, stencilErr error
+	read.Depth(submit, func(val interface{}, err error) { depthErr = err })
+	read.Stencil(submit, func(val interface{}, err error) { stencilErr = err })
+
+	out := &recorder{state: capture.NewState(ctx)}
+	for i, a := range atoms.Atoms {
+		read.Transform(ctx, atom.ID(i), a, out)
+	}
+	read.Flush(ctx, out)
+
+	assert.For(ctx, "Mutate").ThatError(out.err).Succeeded()
+	assert.For(ctx, "Depth").ThatError(depthErr).Succeeded()
+	// Only the depth is resolved by a draw, stencil exports have no such resolve.
+	assert.For(ctx, "Stencil").ThatError(stencilErr).Failed()
+
+	draws, resolves := 0, 0
+	for _, a := range out.injected {
+		switch a.(type) {
+		case *vulkan.VkCmdDraw:
+			draws++
+		case *vulkan.VkCmdResolveImage:
+			resolves++
+		}
+	}
+	assert.For(ctx, "Resolve draws").That(draws).Equals(1)
+	assert.For(ctx, "Image resolves").That(resolves).Equals(0)
+
+	// The resolve draw must not replace the draw of the sample as the last
+	// draw, its framebuffer is destroyed after the read.
+	st := vulkan.GetState(out.state)
+	if assert.For(ctx, "Last framebuffer").That(st.LastDrawInfo.Framebuffer).IsNotNil() {
+		_, ok := st.Framebuffers[st.LastDrawInfo.Framebuffer.VulkanHandle]
+		assert.For(ctx, "Last framebuffer exists").That(ok).Equals(true)
+	}
+}
+
+func TestReadImagePlane(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	atoms, _, submit := samples.DrawToImagePlane(ctx)
+	c, err := capture.ImportAtomList(ctx, "image-plane", atoms)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+
+	// A larger size is requested, planes are read at their own size.
+	read := vulkan.NewReadFramebuffer(ctx)
+	var colorErr error
+	read.Color(submit, 64, 64, 0, func(val interface{}, err error) { colorErr = err })
+
+	out := &recorder{state: capture.NewState(ctx)}
+	for i, a := range atoms.Atoms {
+		read.Transform(ctx, atom.ID(i), a, out)
+	}
+	read.Flush(ctx, out)
+
+	assert.For(ctx, "Mutate").ThatError(out.err).Succeeded()
+	assert.For(ctx, "Color").ThatError(colorErr).Succeeded()
+
+	// The observations of the injected atoms are read back on their own state.
+	s := gfxapi.NewStateWithEmptyAllocator()
+	var staging *vulkan.VkImageCreateInfo
+	var copies []vulkan.VkImageCopy
+	blits := 0
+	for _, a := range out.injected {
+		switch a := a.(type) {
+		case *vulkan.VkCreateImage:
+			a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+			if info := a.PCreateInfo.Read(ctx, a, s, nil); staging == nil {
+				staging = &info
+			}
+		case *vulkan.VkCmdCopyImage:
+			a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+			copies = append(copies, a.PRegions.Slice(0, uint64(a.RegionCount), s).Read(ctx, a, s, nil)...)
+		case *vulkan.VkCmdBlitImage:
+			blits++
+		}
+	}
+	assert.For(ctx, "Blits").That(blits).Equals(0)
+	if assert.For(ctx, "Staging image").That(staging).IsNotNil() {
+		assert.For(ctx, "Staging format").That(staging.Format).Equals(vulkan.VkFormat_VK_FORMAT_R8G8_UNORM)
+		assert.For(ctx, "Staging extent").That(staging.Extent).Equals(vulkan.VkExtent3D{Width: 32, Height: 32, Depth: 1})
+	}
+	if assert.For(ctx, "Copies").ThatSlice(copies).IsLength(1) {
+		assert.For(ctx, "Source aspect").That(copies[0].SrcSubresource.AspectMask).Equals(
+			vulkan.VkImageAspectFlags(vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_1_BIT_KHR))
+		assert.For(ctx, "Destination aspect").That(copies[0].DstSubresource.AspectMask).Equals(
+			vulkan.VkImageAspectFlags(vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT))
+		assert.For(ctx, "Extent").That(copies[0].Extent).Equals(vulkan.VkExtent3D{Width: 32, Height: 32, Depth: 1})
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+)
+
+// recordedValues returns a transform that drops the queries for query pool
+// results from the replay, so that the values recorded in the capture are used
+// instead of those of the replay device. Swapchain image indices are always
+// fed back, see VkAcquireNextImageKHR.Mutate.
+func recordedValues(ctx context.Context) transform.Transformer {
+	return transform.Transform("RecordedValues", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		switch a.(type) {
+		case *VkGetQueryPoolResults, *VkGetEventStatus:
+			// Neither has side effects on the replay device.
+		default:
+			out.MutateAndWrite(ctx, i, a)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.RedundancyChecker(api{})
+
+// vkSlot identifies a piece of the state of a command buffer being recorded.
+type vkSlot struct {
+	commandBuffer VkCommandBuffer
+	state         string
+	key           interface{}
+}
+
+// pipelineStates are the pieces of command buffer state that are not kept
+// when a different pipeline is bound: the dynamic state, which is undefined
+// for the pipelines not declaring it dynamic, and the descriptor sets, which
+// are disturbed by incompatible pipeline layouts.
+var pipelineStates = map[string]bool{
+	"viewport":          true,
+	"scissor":           true,
+	"line-width":        true,
+	"depth-bias":        true,
+	"blend-constants":   true,
+	"depth-bounds":      true,
+	"stencil-compare":   true,
+	"stencil-write":     true,
+	"stencil-reference": true,
+	"descriptor-set":    true,
+}
+
+// Redundancies returns the vkCmd atoms of the capture held by ctx that bind
+// the pipeline, descriptor sets, vertex or index buffers, or set the dynamic
+// state, to the values already bound or set in the command buffer they are
+// recorded to.
+func (api) Redundancies(ctx context.Context) ([]atom.Redundancy, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.Redundancy{}
+	slots := atom.StateSlots{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		if cb, values := vkStateValues(ctx, a, s); len(values) > 0 {
+			previous, ok := slots.Set(id, values...)
+			if ok {
+				out = append(out, atom.Redundancy{Atom: id, Previous: previous})
+			} else if _, ok := a.(*VkCmdBindPipeline); ok {
+				forgetVkState(slots, cb, func(state string) bool { return pipelineStates[state] })
+			}
+			continue
+		}
+		switch a := a.(type) {
+		case *VkBeginCommandBuffer:
+			forgetVkState(slots, a.CommandBuffer, nil)
+		case *RecreateAndBeginCommandBuffer:
+			forgetVkState(slots, a.PCommandBuffer.Read(ctx, a, s, nil), nil)
+		case *VkResetCommandBuffer:
+			forgetVkState(slots, a.CommandBuffer, nil)
+		case *VkCmdExecuteCommands:
+			// The state is undefined after the execution of secondary command
+			// buffers.
+			forgetVkState(slots, a.CommandBuffer, nil)
+		case *VkResetCommandPool, *VkFreeCommandBuffers:
+			slots.Forget(func(interface{}) bool { return true })
+		}
+	}
+	return out, nil
+}
+
+// forgetVkState forgets the values of the state of the command buffer cb for
+// which f returns true, or all of them if f is nil.
+func forgetVkState(slots atom.StateSlots, cb VkCommandBuffer, f func(state string) bool) {
+	slots.Forget(func(slot interface{}) bool {
+		s, ok := slot.(vkSlot)
+		return ok && s.commandBuffer == cb && (f == nil || f(s.state))
+	})
+}
+
+// vkStateValues returns the command buffer that the atom a, mutated on s, is
+// recorded to, and the values it sets to the state of the command buffer.
+func vkStateValues(ctx context.Context, a atom.Atom, s *gfxapi.State) (VkCommandBuffer, []atom.SlotValue) {
+	var cb VkCommandBuffer
+	values := []atom.SlotValue{}
+	set := func(state string, key, v interface{}) {
+		values = append(values, atom.SlotValue{Slot: vkSlot{cb, state, key}, Value: v})
+	}
+	// setFaces sets the value v to the stencil state of each face of the mask.
+	setFaces := func(state string, mask VkStencilFaceFlags, v interface{}) {
+		for _, face := range []VkStencilFaceFlagBits{
+			VkStencilFaceFlagBits_VK_STENCIL_FACE_FRONT_BIT,
+			VkStencilFaceFlagBits_VK_STENCIL_FACE_BACK_BIT,
+		} {
+			if uint32(mask)&uint32(face) != 0 {
+				set(state, face, v)
+			}
+		}
+	}
+
+	switch a := a.(type) {
+	case *VkCmdBindPipeline:
+		cb = a.CommandBuffer
+		set("pipeline", a.PipelineBindPoint, a.Pipeline)
+	case *VkCmdBindDescriptorSets:
+		cb = a.CommandBuffer
+		sets := a.PDescriptorSets.Slice(0, uint64(a.DescriptorSetCount), s).Read(ctx, a, s, nil)
+		offsets := a.PDynamicOffsets.Slice(0, uint64(a.DynamicOffsetCount), s).Read(ctx, a, s, nil)
+		for i, ds := range sets {
+			// The dynamic offsets are not split between the sets, so all of
+			// them have to match.
+			set("descriptor-set", [2]interface{}{a.PipelineBindPoint, a.FirstSet + uint32(i)},
+				[]interface{}{a.Layout, ds, offsets})
+		}
+	case *VkCmdBindVertexBuffers:
+		cb = a.CommandBuffer
+		buffers := a.PBuffers.Slice(0, uint64(a.BindingCount), s).Read(ctx, a, s, nil)
+		offsets := a.POffsets.Slice(0, uint64(a.BindingCount), s).Read(ctx, a, s, nil)
+		for i := range buffers {
+			set("vertex-buffer", a.FirstBinding+uint32(i), [2]interface{}{buffers[i], offsets[i]})
+		}
+	case *VkCmdBindIndexBuffer:
+		cb = a.CommandBuffer
+		set("index-buffer", nil, [3]interface{}{a.Buffer, a.Offset, a.IndexType})
+	case *VkCmdSetViewport:
+		cb = a.CommandBuffer
+		for i, v := range a.PViewports.Slice(0, uint64(a.ViewportCount), s).Read(ctx, a, s, nil) {
+			set("viewport", a.FirstViewport+uint32(i), v)
+		}
+	case *VkCmdSetScissor:
+		cb = a.CommandBuffer
+		for i, r := range a.PScissors.Slice(0, uint64(a.ScissorCount), s).Read(ctx, a, s, nil) {
+			set("scissor", a.FirstScissor+uint32(i), r)
+		}
+	case *VkCmdSetLineWidth:
+		cb = a.CommandBuffer
+		set("line-width", nil, a.LineWidth)
+	case *VkCmdSetDepthBias:
+		cb = a.CommandBuffer
+		set("depth-bias", nil, [3]interface{}{a.DepthBiasConstantFactor, a.DepthBiasClamp, a.DepthBiasSlopeFactor})
+	case *VkCmdSetBlendConstants:
+		cb = a.CommandBuffer
+		set("blend-constants", nil, a.BlendConstants)
+	case *VkCmdSetDepthBounds:
+		cb = a.CommandBuffer
+		set("depth-bounds", nil, [2]interface{}{a.MinDepthBounds, a.MaxDepthBounds})
+	case *VkCmdSetStencilCompareMask:
+		cb = a.CommandBuffer
+		setFaces("stencil-compare", a.FaceMask, a.CompareMask)
+	case *VkCmdSetStencilWriteMask:
+		cb = a.CommandBuffer
+		setFaces("stencil-write", a.FaceMask, a.WriteMask)
+	case *VkCmdSetStencilReference:
+		cb = a.CommandBuffer
+		setFaces("stencil-reference", a.FaceMask, a.Reference)
+	}
+	return cb, values
+}
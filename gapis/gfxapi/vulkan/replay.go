@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/gapid/core/image"
 	"github.com/google/gapid/core/log"
@@ -26,8 +27,11 @@ import (
 	"github.com/google/gapid/gapis/atom/transform"
 	"github.com/google/gapid/gapis/capture"
 	"github.com/google/gapid/gapis/config"
+	"github.com/google/gapid/gapis/experiments"
 	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/interop"
 	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/messages"
 	"github.com/google/gapid/gapis/replay"
 	"github.com/google/gapid/gapis/service"
 )
@@ -36,6 +40,13 @@ var (
 	// Interface compliance tests
 	_ = replay.QueryIssues(api{})
 	_ = replay.QueryFramebufferAttachment(api{})
+	_ = replay.QueryHighlightedDraw(api{})
+	_ = replay.QuerySkippedCommands(api{})
+	_ = replay.QueryStateOverride(api{})
+	_ = replay.QueryDispatchSnapshot(api{})
+	_ = replay.QueryTimings(api{})
+	_ = replay.QueryPipelineStatistics(api{})
+	_ = replay.QueryFrameLoop(api{})
 	_ = replay.Support(api{})
 )
 
@@ -60,11 +71,14 @@ type makeAttachementReadable struct {
 // drawConfig is a replay.Config used by colorBufferRequest and
 // depthBufferRequests.
 type drawConfig struct {
-}
-
-type imgRes struct {
-	img *image.Image2D // The image data.
-	err error          // The error that occurred generating the image.
+	wireframeMode replay.WireframeMode
+	highlight     atom.ID // The draw call to highlight, or atom.NoID.
+	// The commands whose rendering is skipped, or nil. As the ranges are held
+	// by pointer, configs skipping commands are never batched together.
+	skip *atom.RangeList
+	// The fixed-function state forced on the draw calls, or nil. As for skip,
+	// configs overriding state are never batched together.
+	state *replay.StateOverride
 }
 
 // framebufferRequest requests a postback of a framebuffer's attachment.
@@ -72,23 +86,36 @@ type framebufferRequest struct {
 	after            atom.ID
 	width, height    uint32
 	attachment       gfxapi.FramebufferAttachment
-	out              chan imgRes
 	wireframeOverlay bool
 }
 
+// deadCodeEliminator is the interface implemented by the dead code
+// elimination transforms, for Vulkan only and interop captures.
+type deadCodeEliminator interface {
+	transform.Transformer
+	// Request ensures that we keep alive all atoms needed to render
+	// framebuffer at the given point.
+	Request(id atom.ID)
+}
+
 type deadCodeEliminationInfo struct {
-	dependencyGraph     *DependencyGraph
-	deadCodeElimination *DeadCodeElimination
+	deadCodeElimination deadCodeEliminator
 }
 
-// color/depth/stencil attachment bit.
-func patchImageUsage(usage VkImageUsageFlags) (VkImageUsageFlags, bool) {
+// color/depth/stencil attachment or storage bit. Multisampled depth/stencil
+// attachments also get the sampled bit, as their depth is resolved by a draw.
+func patchImageUsage(usage VkImageUsageFlags, samples VkSampleCountFlagBits) (VkImageUsageFlags, bool) {
 	hasBit := func(flag VkImageUsageFlags, bit VkImageUsageFlagBits) bool {
 		return (uint32(flag) & uint32(bit)) == uint32(bit)
 	}
 
+	if hasBit(usage, VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT) &&
+		samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT {
+		usage |= VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT)
+	}
 	if hasBit(usage, VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT) ||
-		hasBit(usage, VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT) {
+		hasBit(usage, VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT) ||
+		hasBit(usage, VkImageUsageFlagBits_VK_IMAGE_USAGE_STORAGE_BIT) {
 		return VkImageUsageFlags(uint32(usage) | uint32(VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT)), true
 	}
 	return usage, false
@@ -101,7 +128,7 @@ func (t *makeAttachementReadable) Transform(ctx context.Context, id atom.ID, a a
 		pinfo := image.PCreateInfo
 		info := pinfo.Read(ctx, image, s, nil)
 
-		if newUsage, changed := patchImageUsage(info.Usage); changed {
+		if newUsage, changed := patchImageUsage(info.Usage, info.Samples); changed {
 			device := image.Device
 			palloc := memory.Pointer(image.PAllocator)
 			pimage := memory.Pointer(image.PImage)
@@ -137,7 +164,7 @@ func (t *makeAttachementReadable) Transform(ctx context.Context, id atom.ID, a a
 		pinfo := recreateImage.PCreateInfo
 		info := pinfo.Read(ctx, image, s, nil)
 
-		if newUsage, changed := patchImageUsage(info.Usage); changed {
+		if newUsage, changed := patchImageUsage(info.Usage, info.Samples); changed {
 			device := recreateImage.Device
 			pimage := memory.Pointer(recreateImage.PImage)
 
@@ -171,7 +198,7 @@ func (t *makeAttachementReadable) Transform(ctx context.Context, id atom.ID, a a
 		pinfo := swapchain.PCreateInfo
 		info := pinfo.Read(ctx, swapchain, s, nil)
 
-		if newUsage, changed := patchImageUsage(info.ImageUsage); changed {
+		if newUsage, changed := patchImageUsage(info.ImageUsage, VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT); changed {
 			device := swapchain.Device
 			palloc := memory.Pointer(swapchain.PAllocator)
 			pswapchain := memory.Pointer(swapchain.PSwapchain)
@@ -202,7 +229,7 @@ func (t *makeAttachementReadable) Transform(ctx context.Context, id atom.ID, a a
 		pinfo := recreateSwapchain.PCreateInfo
 		info := pinfo.Read(ctx, recreateSwapchain, s, nil)
 
-		if newUsage, changed := patchImageUsage(info.ImageUsage); changed {
+		if newUsage, changed := patchImageUsage(info.ImageUsage, VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT); changed {
 			device := recreateSwapchain.Device
 			pswapchain := memory.Pointer(recreateSwapchain.PSwapchain)
 			pswapchainImages := memory.Pointer(recreateSwapchain.PSwapchainImages)
@@ -463,27 +490,83 @@ func (a api) Replay(
 	if err != nil {
 		return log.Err(ctx, err, "Failed to load atom stream")
 	}
+	recorded := atom.HasRecordedValues(atoms)
 
 	transforms := transform.Transforms{}
+	// Adapt the capture to the replay device before any other transform
+	// inspects the created objects.
+	if p := newPortability(device.GetConfiguration().GetDrivers().GetVulkan()); p != nil {
+		transforms.Add(p)
+	}
 	transforms.Add(&makeAttachementReadable{})
 
+	// TODO: Support WireframeMode_Overlay, which is currently replayed as
+	// WireframeMode_None.
+	if c, ok := cfg.(drawConfig); ok && c.wireframeMode == replay.WireframeMode_All {
+		transforms.Add(wireframe(ctx))
+	}
+	if c, ok := cfg.(drawConfig); ok && c.wireframeMode == replay.WireframeMode_Overdraw {
+		transforms.Add(overdraw(ctx))
+	}
+	if c, ok := cfg.(drawConfig); ok && c.highlight != atom.NoID {
+		transforms.Add(newHighlight(c.highlight))
+	}
+	if c, ok := cfg.(drawConfig); ok && c.skip != nil {
+		transforms.Add(skipCommands(*c.skip))
+	}
+	if c, ok := cfg.(drawConfig); ok && c.state != nil {
+		transforms.Add(newStateOverride(*c.state))
+	}
+	if c, ok := cfg.(frameLoopConfig); ok && c.experiments != nil {
+		for _, name := range c.experiments.names {
+			e := experiments.Find(name)
+			if e == nil {
+				return &service.ErrInvalidArgument{Reason: messages.ErrUnknownExperiment(name)}
+			}
+			t, err := e.Transformer(ctx, a, c.experiments.parameters.Select(e))
+			if err != nil {
+				return err
+			}
+			transforms.Add(t)
+		}
+	}
+
 	readFramebuffer := newReadFramebuffer(ctx)
 	injector := &transform.Injector{}
 	// Gathers and reports any issues found.
 	var issues *findIssues
+	// Copies the storage resources around a dispatch.
+	var snapshot *dispatchSnapshot
+	// Measures the GPU time of the draws and dispatches.
+	var profile *timings
+	// Collects the pipeline statistics of the draws and dispatches.
+	var statistics *pipelineStatistics
+	// Replays a frame in a loop.
+	var loop *frameLoop
 
 	// Prepare data for dead-code-elimination
 	dceInfo := deadCodeEliminationInfo{}
 	if !config.DisableDeadCodeElimination {
-		dceInfo.dependencyGraph, err = GetDependencyGraph(ctx)
-		if err != nil {
-			return err
+		if interop.IsInterop(capture) {
+			// The capture also uses other APIs, trim the atoms of all of them.
+			dependencyGraph, err := interop.GetDependencyGraph(ctx)
+			if err != nil {
+				return err
+			}
+			dceInfo.deadCodeElimination = interop.NewDeadCodeElimination(ctx, dependencyGraph)
+		} else {
+			dependencyGraph, err := GetDependencyGraph(ctx)
+			if err != nil {
+				return err
+			}
+			dceInfo.deadCodeElimination = newDeadCodeElimination(ctx, dependencyGraph)
 		}
-		dceInfo.deadCodeElimination = newDeadCodeElimination(ctx, dceInfo.dependencyGraph)
 	}
 
 	// Terminate after all atoms of interest.
 	earlyTerminator := &transform.EarlyTerminator{}
+	// The atoms the requests are made after, for the state priming.
+	requested := []atom.ID{}
 
 	for _, rr := range rrs {
 		switch req := rr.Request.(type) {
@@ -495,6 +578,7 @@ func (a api) Replay(
 
 		case framebufferRequest:
 			earlyTerminator.Add(req.after)
+			requested = append(requested, req.after)
 
 			if !config.DisableDeadCodeElimination {
 				dceInfo.deadCodeElimination.Request(req.after)
@@ -504,29 +588,112 @@ func (a api) Replay(
 			case gfxapi.FramebufferAttachment_Depth:
 				readFramebuffer.Depth(req.after, rr.Result)
 			case gfxapi.FramebufferAttachment_Stencil:
-				return fmt.Errorf("Stencil attachments are not currently supported")
+				readFramebuffer.Stencil(req.after, rr.Result)
 			default:
 				idx := uint32(req.attachment - gfxapi.FramebufferAttachment_Color0)
 				readFramebuffer.Color(req.after, req.width, req.height, idx, rr.Result)
 			}
+
+		case dispatchSnapshotRequest:
+			earlyTerminator.Add(req.submit)
+			requested = append(requested, req.submit)
+
+			if !config.DisableDeadCodeElimination {
+				dceInfo.deadCodeElimination.Request(req.submit)
+			}
+
+			if snapshot == nil {
+				snapshot = newDispatchSnapshot(req)
+			}
+			snapshot.reportTo(rr.Result)
+
+		case timingsRequest:
+			if profile == nil {
+				profile = newTimings()
+			}
+			profile.reportTo(rr.Result)
+
+		case statisticsRequest:
+			if statistics == nil {
+				statistics = newPipelineStatistics(req.capture)
+			}
+			statistics.reportTo(rr.Result)
+
+		case frameLoopRequest:
+			requested = append(requested, req.end)
+			if loop == nil {
+				loop = newFrameLoop(frameStart(atoms.Atoms, req.end), req.end, req.iterations)
+			}
+			loop.reportTo(rr.Result)
+
+		case exportRequest:
+			requested = append(requested, req.end)
+			if loop == nil {
+				loop = newFrameExport(frameStart(atoms.Atoms, req.end), req.end)
+			}
 		}
 	}
 
-	// Use the dead code elimination pass
-	if !config.DisableDeadCodeElimination {
+	// Use the dead code elimination pass. All the commands are instrumented
+	// when profiling, none can be removed.
+	profiling := profile != nil || statistics != nil
+
+	// Skip the GPU work of the frames before the requests by priming the
+	// state at the start of their frame. The issues and the profiles are
+	// gathered from all the commands, which must be executed.
+	var primer *statePrimer
+	if !config.DisableStatePriming && issues == nil && !profiling && !interop.IsInterop(capture) {
+		if primer, err = newStatePrimer(ctx, atoms.Atoms, requested); err != nil {
+			return err
+		}
+	}
+
+	if loop != nil {
+		// The loop needs all the atoms of the frame, and repeats them before
+		// they are adapted by the other transforms.
+		transforms.Prepend(loop)
+	}
+	if primer != nil {
+		// The primer only restores the resources kept by the dead code
+		// elimination.
+		transforms.Prepend(primer)
+	}
+	if !config.DisableDeadCodeElimination && !profiling && loop == nil {
 		atoms = atom.NewList()
 		transforms.Prepend(dceInfo.deadCodeElimination)
 	}
 
-	if issues != nil {
+	switch {
+	case issues != nil:
 		transforms.Add(issues) // Issue reporting required.
-	} else {
+	case !profiling && loop == nil:
+		// The loop drops the atoms after its frame, and repeats its last
+		// atom.
 		transforms.Add(earlyTerminator)
 	}
 
 	// Cleanup
 	transforms.Add(readFramebuffer, injector)
-	transforms.Add(&destroyResourcesAtEOS{})
+
+	if snapshot != nil {
+		transforms.Add(snapshot)
+	}
+
+	// Feed back the query results held by the capture.
+	if recorded {
+		transforms.Add(recordedValues(ctx))
+	}
+	// After recordedValues, which drops the queries for query results.
+	if profile != nil {
+		transforms.Add(profile)
+	}
+	if statistics != nil {
+		transforms.Add(statistics)
+	}
+	if loop == nil || !loop.exported {
+		// The exported programs repeat the atoms at the end of the payload.
+		transforms.Add(&destroyResourcesAtEOS{})
+	}
 
 	if config.DebugReplay {
 		log.I(ctx, "Replaying %d atoms using transform chain:", len(atoms.Atoms))
@@ -575,9 +742,35 @@ func (a api) QueryFramebufferAttachment(
 	wireframeMode replay.WireframeMode,
 	hints *service.UsageHints) (*image.Image2D, error) {
 
-	c := drawConfig{}
-	out := make(chan imgRes, 1)
-	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment, out: out}
+	c := drawConfig{wireframeMode: wireframeMode, highlight: atom.NoID}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
+	if wireframeMode == replay.WireframeMode_Overdraw {
+		// The draws are counted in the stencil of the depth and stencil
+		// attachment, which is turned into the heatmap.
+		r.attachment = gfxapi.FramebufferAttachment_Stencil
+	}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	if wireframeMode == replay.WireframeMode_Overdraw {
+		return replay.OverdrawHeatmap(res.(*image.Image2D))
+	}
+	return res.(*image.Image2D), nil
+}
+
+func (a api) QueryHighlightedDraw(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	after atom.ID,
+	draw atom.ID,
+	width, height uint32,
+	attachment gfxapi.FramebufferAttachment,
+	hints *service.UsageHints) (*image.Image2D, error) {
+
+	c := drawConfig{highlight: draw}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
 	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
 	if err != nil {
 		return nil, err
@@ -585,6 +778,153 @@ func (a api) QueryFramebufferAttachment(
 	return res.(*image.Image2D), nil
 }
 
+func (a api) QuerySkippedCommands(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	after atom.ID,
+	skip atom.RangeList,
+	width, height uint32,
+	attachment gfxapi.FramebufferAttachment,
+	hints *service.UsageHints) (*image.Image2D, error) {
+
+	c := drawConfig{highlight: atom.NoID, skip: &skip}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*image.Image2D), nil
+}
+
+func (a api) QueryStateOverride(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	after atom.ID,
+	override replay.StateOverride,
+	width, height uint32,
+	attachment gfxapi.FramebufferAttachment,
+	hints *service.UsageHints) (*image.Image2D, error) {
+
+	c := drawConfig{highlight: atom.NoID, state: &override}
+	r := framebufferRequest{after: after, width: width, height: height, attachment: attachment}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*image.Image2D), nil
+}
+
+func (a api) QueryDispatchSnapshot(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	dispatch atom.ID,
+	hints *service.UsageHints) (*service.DispatchSnapshot, error) {
+
+	ctx = capture.Put(ctx, intent.Capture)
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(dispatch) >= uint64(len(list.Atoms)) {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrMessage("Command index out of range")}
+	}
+	cb, point, ok := constantsCommandBuffer(list.Atoms[dispatch])
+	if !ok || point != VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrMessage("Command is not a dispatch")}
+	}
+
+	// The dispatch executes when its command buffer is submitted, find the
+	// vkQueueSubmit executing it.
+	order := make([]atom.ID, len(list.Atoms))
+	for i := range order {
+		order[i] = atom.ID(i)
+	}
+	if order, err = a.ExecutionOrder(ctx, list.Atoms, order); err != nil {
+		return nil, err
+	}
+	submit := atom.NoID
+	for i, id := range order {
+		if id != dispatch {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if _, ok := list.Atoms[order[j]].(*VkQueueSubmit); ok {
+				submit = order[j]
+				break
+			}
+		}
+		break
+	}
+	if submit == atom.NoID || submit < dispatch {
+		return nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage("The dispatch is never submitted")}
+	}
+
+	cfg := dispatchSnapshotConfig{dispatch: dispatch}
+	r := dispatchSnapshotRequest{dispatch: dispatch, submit: submit, cb: cb}
+	res, err := mgr.Replay(ctx, intent, cfg, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*service.DispatchSnapshot), nil
+}
+
+func (a api) QueryTimings(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	hints *service.UsageHints) ([]replay.Timing, error) {
+
+	c, r := timingsConfig{}, timingsRequest{}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]replay.Timing), nil
+}
+
+func (a api) QueryPipelineStatistics(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	hints *service.UsageHints) (*service.PipelineStatistics, error) {
+
+	c, r := statisticsConfig{}, statisticsRequest{capture: intent.Capture}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*service.PipelineStatistics), nil
+}
+
+func (a api) QueryFrameLoop(
+	ctx context.Context,
+	intent replay.Intent,
+	mgr *replay.Manager,
+	end atom.ID,
+	iterations uint32,
+	names []string,
+	parameters map[string]string,
+	hints *service.UsageHints) ([]time.Duration, error) {
+
+	c := frameLoopConfig{}
+	if len(names) > 0 {
+		c.experiments = &frameLoopExperiments{names, parameters}
+	}
+	r := frameLoopRequest{end: end, iterations: iterations}
+	res, err := mgr.Replay(ctx, intent, c, r, a, hints)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]time.Duration), nil
+}
+
 func (a api) QueryIssues(
 	ctx context.Context,
 	intent replay.Intent,
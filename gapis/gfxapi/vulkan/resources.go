@@ -41,7 +41,8 @@ func (t *ImageObject) IsResource() bool {
 	// replays to get back gpu-generated image data.
 	is_texture := 0 != (uint32(t.Info.Usage) & uint32(VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT|
 		VkImageUsageFlagBits_VK_IMAGE_USAGE_STORAGE_BIT))
-	return t.VulkanHandle != 0 && is_texture
+	// The planes of multi-planar images are presented as separate resources.
+	return t.VulkanHandle != 0 && is_texture && len(t.Planes) == 0
 }
 
 // ResourceHandle returns the UI identity for the resource.
@@ -51,7 +52,12 @@ func (t *ImageObject) ResourceHandle() string {
 
 // ResourceLabel returns an optional debug label for the resource.
 func (t *ImageObject) ResourceLabel() string {
-	return ""
+	return t.DebugName
+}
+
+// IsDeleted returns true if the handle of the image no longer refers to it.
+func (t *ImageObject) IsDeleted(s *gfxapi.State) bool {
+	return GetState(s).Images.Get(t.VulkanHandle) != t
 }
 
 // Order returns an integer used to sort the resources for presentation.
@@ -61,13 +67,24 @@ func (t *ImageObject) Order() uint64 {
 
 // ResourceType returns the type of this resource.
 func (t *ImageObject) ResourceType(ctx context.Context) gfxapi.ResourceType {
-	if uint32(t.Info.Flags)&uint32(VkImageCreateFlagBits_VK_IMAGE_CREATE_CUBE_COMPATIBLE_BIT) != 0 {
+	switch {
+	case t.Info.ImageType == VkImageType_VK_IMAGE_TYPE_3D:
+		return gfxapi.ResourceType_Texture3DResource
+	case t.isCubemap():
 		return gfxapi.ResourceType_CubemapResource
-	} else {
+	default:
 		return gfxapi.ResourceType_Texture2DResource
 	}
 }
 
+// isCubemap returns true if the image is presented as a cube-map, which it is
+// when it is cube compatible and has the six layers of a single cube.
+// Arrays of cubes are presented as arrays of layers.
+func (t *ImageObject) isCubemap() bool {
+	return uint32(t.Info.Flags)&uint32(VkImageCreateFlagBits_VK_IMAGE_CREATE_CUBE_COMPATIBLE_BIT) != 0 &&
+		len(t.Layers) == 6
+}
+
 type unsupportedVulkanFormatError struct {
 	Format VkFormat
 }
@@ -341,21 +358,21 @@ func getImageFormatFromVulkanFormat(vkfmt VkFormat) (*image.Format, error) {
 	case VkFormat_VK_FORMAT_BC3_SRGB_BLOCK:
 		return image.NewS3_DXT5_RGBA("VK_FORMAT_BC3_SRGB_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC4_UNORM_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC4_R_U8_NORM("VK_FORMAT_BC4_UNORM_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC4_SNORM_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC4_R_S8_NORM("VK_FORMAT_BC4_SNORM_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC5_UNORM_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC5_RG_U8_NORM("VK_FORMAT_BC5_UNORM_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC5_SNORM_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC5_RG_S8_NORM("VK_FORMAT_BC5_SNORM_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC6H_UFLOAT_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC6H_RGB_UF16("VK_FORMAT_BC6H_UFLOAT_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC6H_SFLOAT_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC6H_RGB_SF16("VK_FORMAT_BC6H_SFLOAT_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC7_UNORM_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC7_RGBA_U8_NORM("VK_FORMAT_BC7_UNORM_BLOCK"), nil
 	case VkFormat_VK_FORMAT_BC7_SRGB_BLOCK:
-		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+		return image.NewBC7_SRGBA_U8_NORM("VK_FORMAT_BC7_SRGB_BLOCK"), nil
 	case VkFormat_VK_FORMAT_ETC2_R8G8B8_UNORM_BLOCK:
 		return image.NewETC2_RGB_U8_NORM("VK_FORMAT_ETC2_R8G8B8_UNORM_BLOCK"), nil
 	case VkFormat_VK_FORMAT_ETC2_R8G8B8_SRGB_BLOCK:
@@ -475,6 +492,21 @@ func getDepthImageFormatFromVulkanFormat(vkfmt VkFormat) (*image.Format, error)
 	}
 }
 
+// getStencilImageFormatFromVulkanFormat returns the format of the stencil
+// aspect of images of the format vkfmt, as copied to a buffer.
+func getStencilImageFormatFromVulkanFormat(vkfmt VkFormat) (*image.Format, error) {
+	switch vkfmt {
+	case VkFormat_VK_FORMAT_S8_UINT,
+		VkFormat_VK_FORMAT_D16_UNORM_S8_UINT,
+		VkFormat_VK_FORMAT_D24_UNORM_S8_UINT,
+		VkFormat_VK_FORMAT_D32_SFLOAT_S8_UINT:
+		// Copies of the stencil aspect are always tightly packed 8 bit values.
+		return image.NewUncompressed(fmt.Sprintf("%v (stencil)", vkfmt), fmts.S_U8), nil
+	default:
+		return nil, &unsupportedVulkanFormatError{Format: vkfmt}
+	}
+}
+
 func setCubemapFace(img *image.Info2D, cubeMap *gfxapi.CubemapLevel, layerIndex uint32) (success bool) {
 	if cubeMap == nil || img == nil {
 		return false
@@ -502,16 +534,17 @@ func setCubemapFace(img *image.Info2D, cubeMap *gfxapi.CubemapLevel, layerIndex
 func (t *ImageObject) ResourceData(ctx context.Context, s *gfxapi.State) (interface{}, error) {
 	ctx = log.Enter(ctx, "ImageObject.Resource()")
 
+	unavailable := &service.ErrDataUnavailable{Reason: messages.ErrNoTextureData(t.ResourceHandle())}
 	vkFmt := t.Info.Format
 	format, err := getImageFormatFromVulkanFormat(vkFmt)
-	if err != nil {
-		return nil, &service.ErrDataUnavailable{Reason: messages.ErrNoTextureData(t.ResourceHandle())}
+	if err != nil || len(t.Layers) == 0 {
+		return nil, unavailable
 	}
 	switch t.Info.ImageType {
 	case VkImageType_VK_IMAGE_TYPE_2D:
 		// If this image has VK_IMAGE_CREATE_CUBE_COMPATIBLE_BIT set, it should have six layers to
 		// represent a cubemap
-		if uint32(t.Info.Flags)&uint32(VkImageCreateFlagBits_VK_IMAGE_CREATE_CUBE_COMPATIBLE_BIT) != 0 {
+		if t.isCubemap() {
 			// Cubemap
 			cubeMapLevels := make([]*gfxapi.CubemapLevel, len(t.Layers[0].Levels))
 			for l := range cubeMapLevels {
@@ -526,26 +559,67 @@ func (t *ImageObject) ResourceData(ctx context.Context, s *gfxapi.State) (interf
 						Data:   image.NewID(imageLevel.Data.ResourceID(ctx, s)),
 					}
 					if !setCubemapFace(img, cubeMapLevels[levelIndex], layerIndex) {
-						return nil, &service.ErrDataUnavailable{Reason: messages.ErrNoTextureData(t.ResourceHandle())}
+						return nil, unavailable
 					}
 				}
 			}
 			return &gfxapi.Cubemap{Levels: cubeMapLevels}, nil
-		} else {
-			levels := make([]*image.Info2D, len(t.Layers[0].Levels))
-			for i, level := range t.Layers[0].Levels {
-				levels[i] = &image.Info2D{
+		}
+		layers := make([]*gfxapi.Texture2D, len(t.Layers))
+		for i := range layers {
+			layer, ok := t.Layers[uint32(i)]
+			if !ok {
+				return nil, unavailable
+			}
+			layers[i] = layerTexture(ctx, s, format, layer)
+		}
+		if len(layers) == 1 {
+			return layers[0], nil
+		}
+		return &gfxapi.Texture2DArray{Layers: layers}, nil
+	case VkImageType_VK_IMAGE_TYPE_3D:
+		// The data of each level holds all of its depth slices.
+		levels := make([]*gfxapi.Texture3DLevel, len(t.Layers[0].Levels))
+		for i := range levels {
+			level, ok := t.Layers[0].Levels[uint32(i)]
+			if !ok {
+				return nil, unavailable
+			}
+			sliceSize := uint64(format.Size(int(level.Width), int(level.Height)))
+			if sliceSize == 0 || uint64(level.Depth)*sliceSize > level.Data.Count {
+				return nil, unavailable
+			}
+			slices := make([]*image.Info2D, level.Depth)
+			for j := range slices {
+				data := level.Data.Slice(uint64(j)*sliceSize, uint64(j+1)*sliceSize, s)
+				slices[j] = &image.Info2D{
 					Format: format,
 					Width:  level.Width,
 					Height: level.Height,
-					Data:   image.NewID(level.Data.ResourceID(ctx, s)),
+					Data:   image.NewID(data.ResourceID(ctx, s)),
 				}
 			}
-			return &gfxapi.Texture2D{Levels: levels}, nil
+			levels[i] = &gfxapi.Texture3DLevel{Slices: slices}
 		}
+		return &gfxapi.Texture3D{Levels: levels}, nil
 	default:
-		return nil, &service.ErrDataUnavailable{Reason: messages.ErrNoTextureData(t.ResourceHandle())}
+		return nil, unavailable
+	}
+}
+
+// layerTexture returns the mip-map levels of the image layer as a texture of
+// the given format.
+func layerTexture(ctx context.Context, s *gfxapi.State, format *image.Format, layer *ImageLayer) *gfxapi.Texture2D {
+	levels := make([]*image.Info2D, len(layer.Levels))
+	for i, level := range layer.Levels {
+		levels[i] = &image.Info2D{
+			Format: format,
+			Width:  level.Width,
+			Height: level.Height,
+			Data:   image.NewID(level.Data.ResourceID(ctx, s)),
+		}
 	}
+	return &gfxapi.Texture2D{Levels: levels}
 }
 
 func (t *ImageObject) SetResourceData(ctx context.Context, at *path.Command,
@@ -553,6 +627,132 @@ func (t *ImageObject) SetResourceData(ctx context.Context, at *path.Command,
 	return fmt.Errorf("SetResourceData is not supported for ImageObject")
 }
 
+// IsResource returns true if this instance should be considered as a resource.
+func (p *ImagePlane) IsResource() bool {
+	is_texture := 0 != (uint32(p.Usage) & uint32(VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT|
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_STORAGE_BIT))
+	return p.Image != 0 && is_texture
+}
+
+// ResourceHandle returns the UI identity for the resource.
+func (p *ImagePlane) ResourceHandle() string {
+	return fmt.Sprintf("Image<%d> plane %d", p.Image, p.Plane)
+}
+
+// ResourceLabel returns an optional debug label for the resource.
+func (p *ImagePlane) ResourceLabel() string {
+	return ""
+}
+
+// Order returns an integer used to sort the resources for presentation.
+func (p *ImagePlane) Order() uint64 {
+	return uint64(p.Image)
+}
+
+// ResourceType returns the type of this resource.
+func (p *ImagePlane) ResourceType(ctx context.Context) gfxapi.ResourceType {
+	return gfxapi.ResourceType_Texture2DResource
+}
+
+// ResourceData returns the resource data given the current state.
+func (p *ImagePlane) ResourceData(ctx context.Context, s *gfxapi.State) (interface{}, error) {
+	ctx = log.Enter(ctx, "ImagePlane.Resource()")
+
+	format, err := getImageFormatFromVulkanFormat(p.Format)
+	if err != nil || len(p.Layers) == 0 {
+		return nil, &service.ErrDataUnavailable{Reason: messages.ErrNoTextureData(p.ResourceHandle())}
+	}
+	levels := make([]*image.Info2D, len(p.Layers[0].Levels))
+	for i, level := range p.Layers[0].Levels {
+		levels[i] = &image.Info2D{
+			Format: format,
+			Width:  level.Width,
+			Height: level.Height,
+			Data:   image.NewID(level.Data.ResourceID(ctx, s)),
+		}
+	}
+	return &gfxapi.Texture2D{Levels: levels}, nil
+}
+
+func (p *ImagePlane) SetResourceData(ctx context.Context, at *path.Command,
+	data interface{}, resources gfxapi.ResourceMap, edits gfxapi.ReplaceCallback) error {
+	return fmt.Errorf("SetResourceData is not supported for ImagePlane")
+}
+
+// IsResource returns true if this instance should be considered as a resource.
+func (v *BufferViewObject) IsResource() bool {
+	return v.VulkanHandle != 0
+}
+
+// ResourceHandle returns the UI identity for the resource.
+func (v *BufferViewObject) ResourceHandle() string {
+	return fmt.Sprintf("BufferView<%d>", v.VulkanHandle)
+}
+
+// ResourceLabel returns an optional debug label for the resource.
+func (v *BufferViewObject) ResourceLabel() string {
+	return v.DebugName
+}
+
+// IsDeleted returns true if the handle of the buffer view no longer refers to it.
+func (v *BufferViewObject) IsDeleted(s *gfxapi.State) bool {
+	return GetState(s).BufferViews.Get(v.VulkanHandle) != v
+}
+
+// Order returns an integer used to sort the resources for presentation.
+func (v *BufferViewObject) Order() uint64 {
+	return uint64(v.VulkanHandle)
+}
+
+// ResourceType returns the type of this resource.
+func (v *BufferViewObject) ResourceType(ctx context.Context) gfxapi.ResourceType {
+	return gfxapi.ResourceType_Texture1DResource
+}
+
+// ResourceData returns the resource data given the current state.
+// The texels of the view are decoded using the view's format, as read by
+// shaders through uniform and storage texel buffers, and returned as a single
+// level texture of height 1.
+func (v *BufferViewObject) ResourceData(ctx context.Context, s *gfxapi.State) (interface{}, error) {
+	ctx = log.Enter(ctx, "BufferViewObject.Resource()")
+
+	unavailable := &service.ErrDataUnavailable{Reason: messages.ErrNoTextureData(v.ResourceHandle())}
+	format, err := getImageFormatFromVulkanFormat(v.Format)
+	if err != nil {
+		return nil, unavailable
+	}
+	buffer := v.Buffer
+	if buffer == nil || buffer.Memory == nil {
+		return nil, unavailable
+	}
+	texelSize := uint64(format.Size(1, 1))
+	size := uint64(v.Range)
+	if v.Range == VkDeviceSize(0xFFFFFFFFFFFFFFFF) { // VK_WHOLE_SIZE
+		size = uint64(buffer.Info.Size) - uint64(v.Offset)
+	}
+	if texelSize == 0 || size < texelSize {
+		return nil, unavailable
+	}
+	width := size / texelSize
+	start := uint64(buffer.MemoryOffset) + uint64(v.Offset)
+	end := start + width*texelSize
+	if end > buffer.Memory.Data.Count {
+		return nil, unavailable
+	}
+	data := buffer.Memory.Data.Slice(start, end, s)
+	return &gfxapi.Texture2D{Levels: []*image.Info2D{{
+		Format: format,
+		Width:  uint32(width),
+		Height: 1,
+		Data:   image.NewID(data.ResourceID(ctx, s)),
+	}}}, nil
+}
+
+func (v *BufferViewObject) SetResourceData(ctx context.Context, at *path.Command,
+	data interface{}, resources gfxapi.ResourceMap, edits gfxapi.ReplaceCallback) error {
+	return fmt.Errorf("SetResourceData is not supported for BufferViewObject")
+}
+
 // IsResource returns true if this instance should be considered as a resource.
 func (s *ShaderModuleObject) IsResource() bool {
 	return true
@@ -565,7 +765,12 @@ func (s *ShaderModuleObject) ResourceHandle() string {
 
 // ResourceLabel returns an optional debug label for the resource.
 func (s *ShaderModuleObject) ResourceLabel() string {
-	return ""
+	return s.DebugName
+}
+
+// IsDeleted returns true if the handle of the shader module no longer refers to it.
+func (s *ShaderModuleObject) IsDeleted(t *gfxapi.State) bool {
+	return GetState(t).ShaderModules.Get(s.VulkanHandle) != s
 }
 
 // Order returns an integer used to sort the resources for presentation.
@@ -583,7 +788,7 @@ func (s *ShaderModuleObject) ResourceData(ctx context.Context, t *gfxapi.State)
 	ctx = log.Enter(ctx, "Shader.ResourceData()")
 	words := s.Words.Read(ctx, nil, t, nil)
 	source := shadertools.DisassembleSpirvBinary(words)
-	return &gfxapi.Shader{Type: gfxapi.ShaderType_Spirv, Source: source}, nil
+	return &gfxapi.Shader{Type: gfxapi.ShaderType_Spirv, Source: source, Issues: analyzeSpirv(words)}, nil
 }
 
 func (shader *ShaderModuleObject) SetResourceData(ctx context.Context, at *path.Command,
@@ -602,6 +807,21 @@ func (shader *ShaderModuleObject) SetResourceData(ctx context.Context, at *path.
 		return fmt.Errorf("Couldn't find resource")
 	}
 
+	// Reject edits that would break the pipelines using the shader at replay.
+	if edit, ok := data.(*gfxapi.Shader); ok {
+		words, err := shaderWords(edit)
+		if err != nil {
+			return err
+		}
+		state, err := resolve.GlobalState(ctx, at.StateAfter())
+		if err != nil {
+			return err
+		}
+		if err := checkShaderLayoutCompatibility(GetState(state), shader.VulkanHandle, words); err != nil {
+			return err
+		}
+	}
+
 	c, err := capture.ResolveFromPath(ctx, capturePath)
 	if err != nil {
 		return err
@@ -618,7 +838,11 @@ func (shader *ShaderModuleObject) SetResourceData(ctx context.Context, at *path.
 	}
 	for j := index; j >= 0; j-- {
 		i := resource.Accesses[j]
-		if a, ok := list.Atoms[i].(*VkCreateShaderModule); ok {
+		switch a := list.Atoms[i].(type) {
+		case *VkCreateShaderModule:
+			edits(uint64(i), a.Replace(ctx, data))
+			return nil
+		case *RecreateShaderModule:
 			edits(uint64(i), a.Replace(ctx, data))
 			return nil
 		}
@@ -626,26 +850,41 @@ func (shader *ShaderModuleObject) SetResourceData(ctx context.Context, at *path.
 	return fmt.Errorf("No atom to set data in")
 }
 
-func (a *VkCreateShaderModule) Replace(ctx context.Context, data interface{}) gfxapi.ResourceAtom {
-	ctx = log.Enter(ctx, "VkCreateShaderModule.Replace()")
-	state := capture.NewState(ctx)
-	a.Mutate(ctx, state, nil)
-
-	shader := data.(*gfxapi.Shader)
-	codeSlice := shadertools.AssembleSpirvText(shader.Source)
-	if codeSlice == nil {
-		return nil
+// shaderWords returns the SPIR-V of the edited shader, given either as SPIR-V
+// disassembly or as Vulkan GLSL source for the stage of the shader type.
+func shaderWords(shader *gfxapi.Shader) ([]uint32, error) {
+	var stage shadertools.ShaderStage
+	switch shader.Type {
+	case gfxapi.ShaderType_Spirv:
+		words := shadertools.AssembleSpirvText(shader.Source)
+		if words == nil {
+			return nil, fmt.Errorf("Failed to assemble the SPIR-V shader")
+		}
+		return words, nil
+	case gfxapi.ShaderType_Vertex:
+		stage = shadertools.StageVertex
+	case gfxapi.ShaderType_TessControl:
+		stage = shadertools.StageTessControl
+	case gfxapi.ShaderType_TessEvaluation:
+		stage = shadertools.StageTessEvaluation
+	case gfxapi.ShaderType_Geometry:
+		stage = shadertools.StageGeometry
+	case gfxapi.ShaderType_Fragment:
+		stage = shadertools.StageFragment
+	case gfxapi.ShaderType_Compute:
+		stage = shadertools.StageCompute
+	default:
+		return nil, fmt.Errorf("Unsupported shader type %v", shader.Type)
 	}
+	return shadertools.CompileGlsl(shader.Source, stage)
+}
 
-	code := atom.Must(atom.AllocData(ctx, state, codeSlice))
-	device := a.Device
-	pAlloc := memory.Pointer(a.PAllocator)
-	pShaderModule := memory.Pointer(a.PShaderModule)
-	result := a.Result
-	createInfo := a.PCreateInfo.Read(ctx, a, state, nil)
-
+// newShaderModuleCreateInfo returns the encoded copy of createInfo using the
+// SPIR-V words as code, and the allocated code.
+func newShaderModuleCreateInfo(ctx context.Context, state *gfxapi.State, createInfo VkShaderModuleCreateInfo, words []uint32) (info, code atom.AllocResult) {
+	code = atom.Must(atom.AllocData(ctx, state, words))
 	createInfo.PCode = U32ᶜᵖ(code.Ptr())
-	createInfo.CodeSize = uint64(len(codeSlice)) * 4
+	createInfo.CodeSize = uint64(len(words)) * 4
 	// TODO(qining): The following is a hack to work around memory.Write().
 	// In VkShaderModuleCreateInfo, CodeSize should be of type 'size', but
 	// 'uint64' is used for now, and memory.Write() will always treat is as
@@ -656,8 +895,53 @@ func (a *VkCreateShaderModule) Replace(ctx context.Context, data interface{}) gf
 	buf := &bytes.Buffer{}
 	writer := endian.Writer(buf, state.MemoryLayout.GetEndian())
 	VkShaderModuleCreateInfoEncodeRaw(state, writer, &createInfo)
-	newCreateInfo := atom.Must(atom.AllocData(ctx, state, buf.Bytes()))
-	newAtom := NewVkCreateShaderModule(device, newCreateInfo.Ptr(), pAlloc, pShaderModule, result)
+	info = atom.Must(atom.AllocData(ctx, state, buf.Bytes()))
+	return info, code
+}
+
+func (a *VkCreateShaderModule) Replace(ctx context.Context, data interface{}) gfxapi.ResourceAtom {
+	ctx = log.Enter(ctx, "VkCreateShaderModule.Replace()")
+	state := capture.NewState(ctx)
+	a.Mutate(ctx, state, nil)
+
+	words, err := shaderWords(data.(*gfxapi.Shader))
+	if err != nil {
+		return nil
+	}
+
+	createInfo := a.PCreateInfo.Read(ctx, a, state, nil)
+	newCreateInfo, code := newShaderModuleCreateInfo(ctx, state, createInfo, words)
+	newAtom := NewVkCreateShaderModule(a.Device, newCreateInfo.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PShaderModule), a.Result)
+
+	// Carry all non-observation extras through.
+	for _, e := range a.Extras().All() {
+		if _, ok := e.(*atom.Observations); !ok {
+			newAtom.Extras().Add(e)
+		}
+	}
+
+	// Add observations
+	newAtom.AddRead(newCreateInfo.Data()).AddRead(code.Data())
+
+	for _, w := range a.Extras().Observations().Writes {
+		newAtom.AddWrite(w.Range, w.ID)
+	}
+	return newAtom
+}
+
+func (a *RecreateShaderModule) Replace(ctx context.Context, data interface{}) gfxapi.ResourceAtom {
+	ctx = log.Enter(ctx, "RecreateShaderModule.Replace()")
+	state := capture.NewState(ctx)
+	a.Mutate(ctx, state, nil)
+
+	words, err := shaderWords(data.(*gfxapi.Shader))
+	if err != nil {
+		return nil
+	}
+
+	createInfo := a.PCreateInfo.Read(ctx, a, state, nil)
+	newCreateInfo, code := newShaderModuleCreateInfo(ctx, state, createInfo, words)
+	newAtom := NewRecreateShaderModule(a.Device, newCreateInfo.Ptr(), memory.Pointer(a.PShaderModule))
 
 	// Carry all non-observation extras through.
 	for _, e := range a.Extras().All() {
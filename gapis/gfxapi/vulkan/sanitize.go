@@ -0,0 +1,175 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+var _ = atom.Sanitizer(api{})
+
+// The SPIR-V opcodes of the instructions holding strings.
+const (
+	spirvOpSourceContinued = 2
+	spirvOpSource          = 3
+	spirvOpName            = 5
+	spirvOpMemberName      = 6
+	spirvOpString          = 7
+)
+
+// spirvHeaderWords is the number of words of the header of a SPIR-V module.
+const spirvHeaderWords = 5
+
+// SensitiveData returns the application and engine names, the debug names and
+// labels, and the strings of the shader modules read from the application
+// memory by the capture held by ctx. Of the shader modules, the embedded
+// source code is reported as shader source, and the debug names and file
+// names as names, which leaves the modules valid once sanitized.
+func (api) SensitiveData(ctx context.Context) ([]atom.SensitiveData, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.SensitiveData{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if o := a.Extras().Observations(); o != nil {
+			o.ApplyReads(s.Memory[memory.ApplicationPool])
+		}
+		out = append(out, sensitiveData(ctx, id, a, s)...)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+		}
+	}
+	return out, nil
+}
+
+// sensitiveData returns the sensitive data read by the atom a, before it is
+// applied to the state s.
+func sensitiveData(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) []atom.SensitiveData {
+	out := []atom.SensitiveData{}
+	name := func(p Charᶜᵖ) {
+		if p.Address != 0 {
+			rng := p.StringSlice(ctx, s).Range(s)
+			out = append(out, atom.SensitiveData{Atom: id, Kind: atom.SensitiveName, Range: rng})
+		}
+	}
+	label := func(p VkDebugUtilsLabelEXTᶜᵖ) {
+		if p.Address != 0 {
+			name(p.Read(ctx, a, s, nil).PLabelName)
+		}
+	}
+
+	switch a := a.(type) {
+	case *VkCreateInstance:
+		if a.PCreateInfo.Address == 0 {
+			break
+		}
+		if p := a.PCreateInfo.Read(ctx, a, s, nil).PApplicationInfo; p.Address != 0 {
+			info := p.Read(ctx, a, s, nil)
+			name(info.PApplicationName)
+			name(info.PEngineName)
+		}
+	case *VkSetDebugUtilsObjectNameEXT:
+		if a.PNameInfo.Address != 0 {
+			name(a.PNameInfo.Read(ctx, a, s, nil).PObjectName)
+		}
+	case *VkCmdBeginDebugUtilsLabelEXT:
+		label(a.PLabelInfo)
+	case *VkCmdInsertDebugUtilsLabelEXT:
+		label(a.PLabelInfo)
+	case *VkQueueBeginDebugUtilsLabelEXT:
+		label(a.PLabelInfo)
+	case *VkQueueInsertDebugUtilsLabelEXT:
+		label(a.PLabelInfo)
+	case *VkCreateShaderModule:
+		if a.PCreateInfo.Address == 0 {
+			break
+		}
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		if info.PCode.Address == 0 {
+			break
+		}
+		code := info.PCode.Slice(0, info.CodeSize/4, s).Read(ctx, a, s, nil)
+		for _, str := range spirvStrings(code) {
+			out = append(out, atom.SensitiveData{
+				Atom:  id,
+				Kind:  str.kind,
+				Range: memory.Range{Base: info.PCode.Address + str.offset, Size: str.size},
+			})
+		}
+	}
+	return out
+}
+
+// spirvString is a string operand of a SPIR-V instruction.
+type spirvString struct {
+	kind   atom.SensitiveKind
+	offset uint64 // The offset in bytes of the string in the module.
+	size   uint64 // The size in bytes of the string, without terminator.
+}
+
+// spirvStrings returns the source code, debug names and file names of the
+// SPIR-V module code.
+func spirvStrings(code []uint32) []spirvString {
+	out := []spirvString{}
+	// str adds the string starting at the word i of the instruction ending
+	// at the word end.
+	str := func(kind atom.SensitiveKind, i, end int) {
+		size := uint64(0)
+		for ; i+int(size/4) < end; size++ {
+			if code[i+int(size/4)]>>(8*(size%4))&0xff == 0 {
+				break
+			}
+		}
+		if size > 0 {
+			out = append(out, spirvString{kind, uint64(i) * 4, size})
+		}
+	}
+	for i := spirvHeaderWords; i < len(code); {
+		count, op := int(code[i]>>16), code[i]&0xffff
+		if count == 0 || i+count > len(code) {
+			break
+		}
+		end := i + count
+		switch op {
+		case spirvOpSource:
+			// Language, version, optional file and optional source.
+			if count > 4 {
+				str(atom.SensitiveShader, i+4, end)
+			}
+		case spirvOpSourceContinued:
+			str(atom.SensitiveShader, i+1, end)
+		case spirvOpName, spirvOpString:
+			str(atom.SensitiveName, i+2, end)
+		case spirvOpMemberName:
+			str(atom.SensitiveName, i+3, end)
+		}
+		i = end
+	}
+	return out
+}
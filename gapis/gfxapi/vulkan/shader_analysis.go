@@ -0,0 +1,110 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+var _ = atom.ShaderAnalyzer(api{})
+
+// ShaderIssues returns the issues of the shader modules created by the
+// vkCreateShaderModule atoms of the capture held by ctx.
+func (api) ShaderIssues(ctx context.Context) ([]atom.ShaderIssues, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.ShaderIssues{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		create, ok := a.(*VkCreateShaderModule)
+		if !ok {
+			continue
+		}
+		handle := create.PShaderModule.Read(ctx, a, s, nil)
+		module := GetState(s).ShaderModules.Get(handle)
+		if module == nil {
+			continue
+		}
+		out = append(out, atom.ShaderIssues{
+			Atom:   id,
+			Handle: uint64(handle),
+			Type:   gfxapi.ShaderType_Spirv,
+			Issues: analyzeSpirv(module.Words.Read(ctx, a, s, nil)),
+		})
+	}
+	return out, nil
+}
+
+// analyzeSpirv returns the unused descriptor bindings and vertex inputs of the
+// SPIR-V module words, and the float outputs of its fragment entry points that
+// are not decorated RelaxedPrecision. Color attachments rarely hold more than
+// 16 bits per component, so these outputs are medium precision candidates.
+// Modules that cannot be reflected have no issues.
+func analyzeSpirv(words []uint32) []*gfxapi.ShaderIssue {
+	out := []*gfxapi.ShaderIssue{}
+	bindings, err := shadertools.DescriptorBindings(words)
+	if err != nil {
+		return out
+	}
+	for _, b := range bindings {
+		if !b.Used {
+			out = append(out, &gfxapi.ShaderIssue{
+				Kind:       gfxapi.ShaderIssueKind_UnusedBinding,
+				Name:       b.Name,
+				HasBinding: true,
+				Set:        b.Set,
+				Binding:    b.Binding,
+			})
+		}
+	}
+	variables, err := shadertools.InterfaceVariables(words)
+	if err != nil {
+		return out
+	}
+	for _, v := range variables {
+		issue := &gfxapi.ShaderIssue{Name: v.Name, HasLocation: true, Location: v.Location}
+		for _, stage := range v.Stages {
+			switch {
+			case stage == shadertools.VertexStage && !v.Output && !v.Used:
+				issue.Kind = gfxapi.ShaderIssueKind_UnusedVertexInput
+			case stage == shadertools.FragmentStage && v.Output && v.Float && !v.RelaxedPrecision:
+				issue.Kind = gfxapi.ShaderIssueKind_MediumPrecisionCandidate
+			default:
+				continue
+			}
+			out = append(out, issue)
+			break
+		}
+	}
+	return out
+}
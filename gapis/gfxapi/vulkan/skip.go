@@ -0,0 +1,71 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/math/interval"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// skipCommands returns an atom transform that disables the rendering of the
+// commands within the ranges skip. The draws, dispatches and clears recorded
+// within the ranges are dropped, and the queue submissions within the ranges
+// are replaced with submissions of no command buffers, which still wait on
+// and signal their semaphores and fence. The other commands are kept, so
+// that the recorded render passes remain valid.
+func skipCommands(skip atom.RangeList) transform.Transformer {
+	return transform.Transform("SkipCommands", func(ctx context.Context, i atom.ID, a atom.Atom, out transform.Writer) {
+		if !interval.Contains(&skip, uint64(i)) {
+			out.MutateAndWrite(ctx, i, a)
+			return
+		}
+		switch a := a.(type) {
+		case *VkCmdDraw, *VkCmdDrawIndexed, *VkCmdDrawIndirect, *VkCmdDrawIndexedIndirect,
+			*RecreateCmdDraw, *RecreateCmdDrawIndexed, *RecreateCmdDrawIndirect, *RecreateCmdDrawIndexedIndirect,
+			*VkCmdDispatch, *VkCmdDispatchIndirect, *RecreateCmdDispatch, *RecreateCmdDispatchIndirect,
+			*VkCmdClearAttachments, *VkCmdClearColorImage, *VkCmdClearDepthStencilImage,
+			*RecreateCmdClearAttachments, *RecreateCmdClearColorImage, *RecreateCmdClearDepthStencilImage:
+			return
+		case *VkQueueSubmit:
+			writeEmptySubmit(ctx, i, a, out)
+			return
+		}
+		out.MutateAndWrite(ctx, i, a)
+	})
+}
+
+// writeEmptySubmit writes a copy of the queue submission a, with the command
+// buffers removed from all of its batches.
+func writeEmptySubmit(ctx context.Context, i atom.ID, a *VkQueueSubmit, out transform.Writer) {
+	s := out.State()
+	a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+	infos := a.PSubmits.Slice(0, uint64(a.SubmitCount), s).Read(ctx, a, s, nil)
+	if len(infos) == 0 {
+		out.MutateAndWrite(ctx, i, a)
+		return
+	}
+	for i := range infos {
+		infos[i].CommandBufferCount = 0
+		infos[i].PCommandBuffers = NewVkCommandBufferᶜᵖ(0)
+	}
+	data := atom.Must(atom.AllocData(ctx, s, infos))
+	submit := NewVkQueueSubmit(a.Queue, a.SubmitCount, data.Ptr(), a.Fence, a.Result)
+	out.MutateAndWrite(ctx, i, withExtrasOf(a, submit, data))
+	data.Free()
+}
@@ -44,31 +44,72 @@ func (st *State) getFramebufferAttachmentInfo(attachment gfxapi.FramebufferAttac
 		num_of_color_att_before_the_query_one := attachment - gfxapi.FramebufferAttachment_Color0
 		for _, att_ref_index := range subpass_desc.ColorAttachments.KeysSorted() {
 			att_ref := subpass_desc.ColorAttachments[att_ref_index]
-			color_img := st.LastDrawInfo.Framebuffer.ImageAttachments[att_ref.Attachment].Image
+			color_view := st.LastDrawInfo.Framebuffer.ImageAttachments[att_ref.Attachment]
+			color_img := color_view.Image
 			if uint32(color_img.Info.Usage)&uint32(VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT) != 0 {
 				if num_of_color_att_before_the_query_one == 0 {
+					if len(color_img.Planes) > 0 {
+						// Only the plane of the view is attached.
+						plane, ok := viewPlane(color_view)
+						if !ok {
+							return returnError("%s plane is not bound", attachment)
+						}
+						level := plane.Layers.Get(0).Levels.Get(0)
+						return level.Width, level.Height, plane.Format, att_ref.Attachment, nil
+					}
 					return color_img.Info.Extent.Width, color_img.Info.Extent.Height, color_img.Info.Format, att_ref.Attachment, nil
 				} else {
 					num_of_color_att_before_the_query_one -= 1
 				}
 			}
 		}
-	case gfxapi.FramebufferAttachment_Depth:
+	case gfxapi.FramebufferAttachment_Depth,
+		gfxapi.FramebufferAttachment_Stencil:
 		if subpass_desc.DepthStencilAttachment != nil && st.LastDrawInfo.Framebuffer != nil {
 			att_ref := subpass_desc.DepthStencilAttachment
-			if attachment, ok := st.LastDrawInfo.Framebuffer.ImageAttachments[att_ref.Attachment]; ok {
-				depth_img := attachment.Image
-				if (uint32(depth_img.Info.Usage)&uint32(VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT) != 0) &&
-					(depth_img.Info.Samples == VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT) {
+			if view, ok := st.LastDrawInfo.Framebuffer.ImageAttachments[att_ref.Attachment]; ok {
+				depth_img := view.Image
+				if uint32(depth_img.Info.Usage)&uint32(VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT) != 0 {
+					if attachment == gfxapi.FramebufferAttachment_Stencil {
+						if _, err := getStencilImageFormatFromVulkanFormat(depth_img.Info.Format); err != nil {
+							return returnError("%s is not bound", attachment)
+						}
+						if depth_img.Info.Samples != VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT {
+							// TODO: Multisampled depth is resolved by a draw writing
+							// gl_FragDepth, stencil has no such shader output.
+							return returnError("Multisampled %s is not supported", attachment)
+						}
+					}
 					return depth_img.Info.Extent.Width, depth_img.Info.Extent.Height, depth_img.Info.Format, att_ref.Attachment, nil
 				}
 			}
 		}
-	case gfxapi.FramebufferAttachment_Stencil:
-		fallthrough
 	default:
 		return returnError("Framebuffer attachment %v currently unsupported", attachment)
 	}
 
 	return returnError("%s is not bound", attachment)
 }
+
+// planeAspects are the aspects selecting each plane of a multi-planar image.
+var planeAspects = []VkImageAspectFlags{
+	VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_0_BIT_KHR),
+	VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_1_BIT_KHR),
+	VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_2_BIT_KHR),
+}
+
+// viewPlane returns the plane of the multi-planar image selected by the
+// aspect of the view.
+func viewPlane(view *ImageViewObject) (*ImagePlane, bool) {
+	for i, aspect := range planeAspects {
+		if view.SubresourceRange.AspectMask&aspect == 0 {
+			continue
+		}
+		plane := view.Image.Planes.Get(uint32(i))
+		if plane == nil || !plane.Layers.Contains(0) || !plane.Layers.Get(0).Levels.Contains(0) {
+			return nil, false
+		}
+		return plane, true
+	}
+	return nil, false
+}
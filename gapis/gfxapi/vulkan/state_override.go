@@ -0,0 +1,177 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+)
+
+// stateOverride is an atom transform that forces fixed-function state on
+// the draw calls. As the state is baked into the graphics pipelines, each
+// pipeline gets an overridden variant. When all the draw calls are
+// overridden the variants are bound in place of the original pipelines,
+// otherwise the overridden draw call is surrounded with binds of the variant
+// and of the original pipeline.
+type stateOverride struct {
+	override   replay.StateOverride
+	overridden map[VkPipeline]VkPipeline      // Overridden variant of each pipeline.
+	bound      map[VkCommandBuffer]VkPipeline // Original pipeline bound to each command buffer.
+}
+
+func newStateOverride(override replay.StateOverride) *stateOverride {
+	return &stateOverride{
+		override:   override,
+		overridden: map[VkPipeline]VkPipeline{},
+		bound:      map[VkCommandBuffer]VkPipeline{},
+	}
+}
+
+func (t *stateOverride) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	s := out.State()
+	switch a := a.(type) {
+	case *VkCreateGraphicsPipelines:
+		out.MutateAndWrite(ctx, id, a)
+		count := uint64(a.CreateInfoCount)
+		infos := a.PCreateInfos.Slice(0, count, s).Read(ctx, a, s, nil)
+		pipelines := a.PPipelines.Slice(0, count, s).Read(ctx, a, s, nil)
+		for i := range infos {
+			t.createOverridden(ctx, a, a.Device, a.PipelineCache, infos[i], pipelines[i], out)
+		}
+		return
+	case *RecreateGraphicsPipeline:
+		out.MutateAndWrite(ctx, id, a)
+		info := a.PCreateInfo.Read(ctx, a, s, nil)
+		pipeline := a.PPipeline.Read(ctx, a, s, nil)
+		t.createOverridden(ctx, a, a.Device, a.PipelineCache, info, pipeline, out)
+		return
+	case *VkDestroyPipeline:
+		out.MutateAndWrite(ctx, id, a)
+		if overridden, ok := t.overridden[a.Pipeline]; ok {
+			writeEach(ctx, out, NewVkDestroyPipeline(a.Device, overridden, memory.Pointer{}))
+			delete(t.overridden, a.Pipeline)
+		}
+		return
+	case *VkCmdBindPipeline:
+		if overridden, ok := t.bind(a.CommandBuffer, a.PipelineBindPoint, a.Pipeline); ok {
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, NewVkCmdBindPipeline(a.CommandBuffer, a.PipelineBindPoint, overridden)))
+			return
+		}
+	case *RecreateCmdBindPipeline:
+		if overridden, ok := t.bind(a.CommandBuffer, a.PipelineBindPoint, a.Pipeline); ok {
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, NewRecreateCmdBindPipeline(a.CommandBuffer, a.PipelineBindPoint, overridden)))
+			return
+		}
+	}
+
+	if id == t.override.Draw {
+		if cb, ok := drawCommandBuffer(a); ok {
+			if pipeline, ok := t.bound[cb]; ok {
+				if overridden, ok := t.overridden[pipeline]; ok {
+					graphics := VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS
+					writeEach(ctx, out, NewVkCmdBindPipeline(cb, graphics, overridden))
+					out.MutateAndWrite(ctx, id, a)
+					writeEach(ctx, out, NewVkCmdBindPipeline(cb, graphics, pipeline))
+					return
+				}
+			}
+		}
+	}
+	out.MutateAndWrite(ctx, id, a)
+}
+
+func (t *stateOverride) Flush(ctx context.Context, out transform.Writer) {}
+
+// bind records the graphics pipeline bound to the command buffer cb, and
+// returns the variant to bind in its place if all the draw calls are
+// overridden.
+func (t *stateOverride) bind(cb VkCommandBuffer, point VkPipelineBindPoint, pipeline VkPipeline) (VkPipeline, bool) {
+	if point != VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS {
+		return pipeline, false
+	}
+	t.bound[cb] = pipeline
+	if t.override.Draw != atom.NoID {
+		return pipeline, false
+	}
+	overridden, ok := t.overridden[pipeline]
+	return overridden, ok
+}
+
+// createOverridden writes the atoms creating the overridden variant of the
+// graphics pipeline created with info by the atom a.
+func (t *stateOverride) createOverridden(
+	ctx context.Context,
+	a atom.Atom,
+	device VkDevice,
+	cache VkPipelineCache,
+	info VkGraphicsPipelineCreateInfo,
+	pipeline VkPipeline,
+	out transform.Writer) {
+
+	s := out.State()
+	if info.PRasterizationState.Address == 0 {
+		return
+	}
+	data := []atom.AllocResult{}
+	alloc := func(v ...interface{}) atom.AllocResult {
+		d := atom.Must(atom.AllocData(ctx, s, v...))
+		data = append(data, d)
+		return d
+	}
+
+	raster := info.PRasterizationState.Read(ctx, a, s, nil)
+	if raster.RasterizerDiscardEnable != 0 {
+		// Nothing is drawn, there is no state to override.
+		return
+	}
+	switch t.override.CullMode {
+	case service.CullModeOverride_CullNone:
+		raster.CullMode = VkCullModeFlags(VkCullModeFlagBits_VK_CULL_MODE_NONE)
+	case service.CullModeOverride_CullFront:
+		raster.CullMode = VkCullModeFlags(VkCullModeFlagBits_VK_CULL_MODE_FRONT_BIT)
+	case service.CullModeOverride_CullBack:
+		raster.CullMode = VkCullModeFlags(VkCullModeFlagBits_VK_CULL_MODE_BACK_BIT)
+	}
+	if t.override.ForceCCW {
+		raster.FrontFace = VkFrontFace_VK_FRONT_FACE_COUNTER_CLOCKWISE
+	}
+	info.PRasterizationState = VkPipelineRasterizationStateCreateInfoᶜᵖ(alloc(raster).Ptr())
+
+	if t.override.DisableDepthTest && info.PDepthStencilState.Address != 0 {
+		depth := info.PDepthStencilState.Read(ctx, a, s, nil)
+		depth.DepthTestEnable = VkBool32(0)
+		info.PDepthStencilState = VkPipelineDepthStencilStateCreateInfoᶜᵖ(alloc(depth).Ptr())
+	}
+
+	if t.override.DisableBlending && info.PColorBlendState.Address != 0 {
+		blend := info.PColorBlendState.Read(ctx, a, s, nil)
+		attachments := blend.PAttachments.Slice(0, uint64(blend.AttachmentCount), s).Read(ctx, a, s, nil)
+		for i := range attachments {
+			attachments[i].BlendEnable = VkBool32(0)
+		}
+		blend.LogicOpEnable = VkBool32(0)
+		if len(attachments) > 0 {
+			blend.PAttachments = VkPipelineColorBlendAttachmentStateᶜᵖ(alloc(attachments).Ptr())
+		}
+		info.PColorBlendState = VkPipelineColorBlendStateCreateInfoᶜᵖ(alloc(blend).Ptr())
+	}
+
+	t.overridden[pipeline] = createPipelineVariant(ctx, a, device, cache, info, data, out)
+}
@@ -0,0 +1,719 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// statePrimer is a transform that skips the GPU work of the frames before the
+// requested atoms. The atoms before the boundary, the end of the last frame
+// preceding the requests, still create the objects and record the command
+// buffers, but nothing is submitted to the queues. Instead, the content of the
+// buffers and images, and the signaled fences and semaphores, are restored at
+// the boundary with atoms synthesized from the state tracked at that point.
+//
+// The swapchain images are still acquired and presented before the boundary,
+// so that the images acquired after it have the indices of the capture.
+type statePrimer struct {
+	*primedState
+	primed bool
+}
+
+// primedState is the state restored by a statePrimer at the boundary. It is
+// resolved once for each boundary and end of the replayed atoms.
+type primedState struct {
+	boundary atom.ID
+	buffers  []primedBuffer
+	images   []primedImage
+	signals  []primedSignal
+}
+
+// primedBuffer is the content of a buffer at the boundary.
+type primedBuffer struct {
+	device     VkDevice
+	buffer     VkBuffer
+	queue      VkQueue
+	memoryType uint32
+	data       []byte
+}
+
+// primedImage is the layout and the content of an image at the boundary. The
+// data is nil for the images written by the GPU, whose content is not tracked
+// and only their layout is restored.
+type primedImage struct {
+	device     VkDevice
+	image      VkImage
+	layout     VkImageLayout
+	queue      VkQueue
+	memoryType uint32
+	data       []byte
+}
+
+// primedSignal is a fence or a semaphore signaled at the boundary.
+type primedSignal struct {
+	queue     VkQueue
+	fence     VkFence
+	semaphore VkSemaphore
+}
+
+// resourceTouch is the use of an image or a buffer by a command recorded to a
+// command buffer, or the execution of a secondary command buffer.
+type resourceTouch struct {
+	image     VkImage
+	buffer    VkBuffer
+	overwrite bool // The whole content is replaced without being read.
+	secondary VkCommandBuffer
+}
+
+// primingAnalysis checks that the atoms after the boundary do not depend on
+// content that the priming cannot restore: the images and buffers written by
+// the GPU, whose content is not tracked in the state, must be overwritten
+// before being read.
+type primingAnalysis struct {
+	primer         *primedState
+	touches        map[VkCommandBuffer][]resourceTouch
+	writtenImages  map[VkImage]bool  // Written by the commands before the boundary.
+	writtenBuffers map[VkBuffer]bool // Written by the commands before the boundary.
+	dirtyImages    map[VkImage]bool  // Not primed, and not yet overwritten.
+	dirtyBuffers   map[VkBuffer]bool // Not primed, and not yet overwritten.
+	fences         fenceTracker
+	acquired       map[VkFence]bool // Last signaled by an acquisition before the boundary.
+}
+
+// fenceTracker holds the signal state of the fences that were signaled or
+// reset since their creation. The state only holds the signal state of the
+// fences at their creation.
+type fenceTracker map[VkFence]bool
+
+// update records the fences signaled or reset by the atom a, after it was
+// mutated on s.
+func (f fenceTracker) update(ctx context.Context, a atom.Atom, s *gfxapi.State) {
+	switch a := a.(type) {
+	case *VkQueueSubmit:
+		if a.Fence != VkFence(0) {
+			f[a.Fence] = true
+		}
+	case *VkQueueBindSparse:
+		if a.Fence != VkFence(0) {
+			f[a.Fence] = true
+		}
+	case *VkAcquireNextImageKHR:
+		if a.Fence != VkFence(0) {
+			f[a.Fence] = true
+		}
+	case *VkResetFences:
+		for _, fence := range a.PFences.Slice(0, uint64(a.FenceCount), s).Read(ctx, a, s, nil) {
+			f[fence] = false
+		}
+	case *VkDestroyFence:
+		delete(f, a.Fence)
+	}
+}
+
+// signaled returns true if the fence is signaled.
+func (f fenceTracker) signaled(st *State, fence VkFence) bool {
+	if signaled, ok := f[fence]; ok {
+		return signaled
+	}
+	return st.Fences.Contains(fence) && st.Fences.Get(fence).Signaled
+}
+
+// newStatePrimer returns the state primer for a replay of the atoms of the
+// capture held by ctx terminating after the requested atoms, or nil if all the
+// atoms must be replayed.
+func newStatePrimer(ctx context.Context, atoms []atom.Atom, requested []atom.ID) (*statePrimer, error) {
+	boundary, end, ok := primingBoundary(atoms, requested)
+	if !ok {
+		return nil, nil
+	}
+	r, err := database.Build(ctx, &StatePrimingResolvable{
+		Capture:  capture.Get(ctx),
+		Boundary: uint64(boundary),
+		End:      uint64(end),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p := r.(*primedState); p != nil {
+		return &statePrimer{primedState: p}, nil
+	}
+	return nil, nil
+}
+
+// primingBoundary returns the boundary, the end of the last frame preceding
+// the requested atoms, and the last requested atom. ok is false if the
+// requests are in the first frame.
+func primingBoundary(atoms []atom.Atom, requested []atom.ID) (boundary, end atom.ID, ok bool) {
+	if len(requested) == 0 {
+		return 0, 0, false
+	}
+	first, last := requested[0], requested[0]
+	for _, id := range requested {
+		if id < first {
+			first = id
+		}
+		if id > last {
+			last = id
+		}
+	}
+	if uint64(last) >= uint64(len(atoms)) {
+		return 0, 0, false
+	}
+	for i := first; i > 0; i-- {
+		if _, ok := atoms[i-1].(*VkQueuePresentKHR); ok {
+			return i, last, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Resolve implements the database.Resolver interface. The resolved value is
+// nil if the atoms up to the end depend on the GPU work before the boundary.
+func (r *StatePrimingResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	c, err := capture.ResolveFromPath(ctx, r.Capture)
+	if err != nil {
+		return nil, err
+	}
+	atoms, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	boundary, end := atom.ID(r.Boundary), atom.ID(r.End)
+	if uint64(end) >= uint64(len(atoms.Atoms)) || boundary > end {
+		return nil, fmt.Errorf("Invalid state priming range [%v, %v] of %v atoms", boundary, end, len(atoms.Atoms))
+	}
+
+	t := &primingAnalysis{
+		primer:         &primedState{boundary: boundary},
+		touches:        map[VkCommandBuffer][]resourceTouch{},
+		writtenImages:  map[VkImage]bool{},
+		writtenBuffers: map[VkBuffer]bool{},
+		dirtyImages:    map[VkImage]bool{},
+		dirtyBuffers:   map[VkBuffer]bool{},
+		fences:         fenceTracker{},
+		acquired:       map[VkFence]bool{},
+	}
+	s := c.NewState()
+	for i, a := range atoms.Atoms[:end+1] {
+		id := atom.ID(i)
+		if id == boundary {
+			t.snapshot(ctx, s)
+		}
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+		}
+		if !t.analyse(ctx, id, a, s) {
+			log.D(ctx, "State priming: atom %v %v depends on the GPU work before atom %v", id, a, boundary)
+			return (*primedState)(nil), nil
+		}
+	}
+	log.D(ctx, "State priming: %v buffers, %v images primed at atom %v",
+		len(t.primer.buffers), len(t.primer.images), boundary)
+	return t.primer, nil
+}
+
+// analyse records the resources touched by the command buffers, and checks
+// the submissions after the boundary. It returns false if the atom a prevents
+// the state priming.
+func (t *primingAnalysis) analyse(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) bool {
+	t.fences.update(ctx, a, s)
+	t.updateAcquired(ctx, id, a, s)
+	switch a := a.(type) {
+	case *VkBeginCommandBuffer:
+		delete(t.touches, a.CommandBuffer)
+	case *RecreateAndBeginCommandBuffer:
+		delete(t.touches, a.PCommandBuffer.Read(ctx, a, s, nil))
+	case *VkResetCommandBuffer:
+		delete(t.touches, a.CommandBuffer)
+	case *VkQueueBindSparse:
+		// The sparse bindings are made on the queue, they cannot be skipped.
+		return id >= t.primer.boundary
+	case *VkQueueSubmit:
+		if id < t.primer.boundary {
+			return true
+		}
+		submits := a.PSubmits.Slice(0, uint64(a.SubmitCount), s)
+		for i := uint64(0); i < submits.Info().Count; i++ {
+			submit := submits.Index(i, s).Read(ctx, a, s, nil)
+			cbs := submit.PCommandBuffers.Slice(0, uint64(submit.CommandBufferCount), s)
+			for j := uint64(0); j < cbs.Info().Count; j++ {
+				if !t.execute(cbs.Index(j, s).Read(ctx, a, s, nil)) {
+					return false
+				}
+			}
+		}
+	default:
+		if cb, ok := recordedCommandBuffer(a); ok {
+			t.record(ctx, id, cb, a, s)
+		}
+	}
+	return true
+}
+
+// updateAcquired records the fences signaled by the acquisitions of swapchain
+// images before the boundary. Those acquisitions are replayed, and signal
+// their fence without the priming.
+func (t *primingAnalysis) updateAcquired(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) {
+	switch a := a.(type) {
+	case *VkAcquireNextImageKHR:
+		if a.Fence != VkFence(0) && id < t.primer.boundary {
+			t.acquired[a.Fence] = true
+		}
+	case *VkQueueSubmit:
+		delete(t.acquired, a.Fence)
+	case *VkQueueBindSparse:
+		delete(t.acquired, a.Fence)
+	case *VkResetFences:
+		for _, fence := range a.PFences.Slice(0, uint64(a.FenceCount), s).Read(ctx, a, s, nil) {
+			delete(t.acquired, fence)
+		}
+	case *VkDestroyFence:
+		delete(t.acquired, a.Fence)
+	}
+}
+
+// execute checks the resources touched by the submitted command buffer cb,
+// returning false if a resource that is not primed is read before being
+// overwritten.
+func (t *primingAnalysis) execute(cb VkCommandBuffer) bool {
+	for _, touch := range t.touches[cb] {
+		switch {
+		case touch.secondary != VkCommandBuffer(0):
+			if !t.execute(touch.secondary) {
+				return false
+			}
+		case t.dirtyImages[touch.image]:
+			if !touch.overwrite {
+				return false
+			}
+			delete(t.dirtyImages, touch.image)
+		case t.dirtyBuffers[touch.buffer]:
+			if !touch.overwrite {
+				return false
+			}
+			delete(t.dirtyBuffers, touch.buffer)
+		}
+	}
+	return true
+}
+
+// record adds the resources touched by the recorded command a to the list of
+// the command buffer cb.
+func (t *primingAnalysis) record(ctx context.Context, id atom.ID, cb VkCommandBuffer, a atom.Atom, s *gfxapi.State) {
+	st := GetState(s)
+	add := func(touch resourceTouch) { t.touches[cb] = append(t.touches[cb], touch) }
+	switch a := a.(type) {
+	case *VkCmdBeginRenderPass:
+		t.beginRenderPass(cb, a.PRenderPassBegin.Read(ctx, a, s, nil), st)
+	case *RecreateCmdBeginRenderPass:
+		t.beginRenderPass(cb, a.PRenderPassBegin.Read(ctx, a, s, nil), st)
+	case *VkCmdBindDescriptorSets:
+		t.bindDescriptorSets(cb, a.PDescriptorSets.Slice(0, uint64(a.DescriptorSetCount), s).Read(ctx, a, s, nil), st)
+	case *RecreateCmdBindDescriptorSets:
+		t.bindDescriptorSets(cb, a.PDescriptorSets.Slice(0, uint64(a.DescriptorSetCount), s).Read(ctx, a, s, nil), st)
+	case *VkCmdBindVertexBuffers:
+		for _, buffer := range a.PBuffers.Slice(0, uint64(a.BindingCount), s).Read(ctx, a, s, nil) {
+			add(resourceTouch{buffer: buffer})
+		}
+	case *RecreateCmdBindVertexBuffers:
+		for _, buffer := range a.PBuffers.Slice(0, uint64(a.BindingCount), s).Read(ctx, a, s, nil) {
+			add(resourceTouch{buffer: buffer})
+		}
+	case *VkCmdExecuteCommands:
+		for _, secondary := range a.PCommandBuffers.Slice(0, uint64(a.CommandBufferCount), s).Read(ctx, a, s, nil) {
+			add(resourceTouch{secondary: secondary})
+		}
+	case *RecreateCmdExecuteCommands:
+		for _, secondary := range a.PCommandBuffers.Slice(0, uint64(a.CommandBufferCount), s).Read(ctx, a, s, nil) {
+			add(resourceTouch{secondary: secondary})
+		}
+	default:
+		// Any other image or buffer operand is conservatively considered
+		// read. The copies from buffers are executed on the tracked state,
+		// the other transfer destinations are not.
+		modeled := false
+		switch a.(type) {
+		case *VkCmdCopyBuffer, *RecreateCmdCopyBuffer,
+			*VkCmdCopyBufferToImage, *RecreateCmdCopyBufferToImage:
+			modeled = true
+		}
+		v := reflect.ValueOf(a).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanInterface() {
+				continue
+			}
+			written := !modeled && id < t.primer.boundary && isTransferDestination(v.Type().Field(i).Name)
+			switch h := f.Interface().(type) {
+			case VkImage:
+				add(resourceTouch{image: h})
+				if written {
+					t.writtenImages[h] = true
+				}
+			case VkBuffer:
+				add(resourceTouch{buffer: h})
+				if written {
+					t.writtenBuffers[h] = true
+				}
+			}
+		}
+	}
+}
+
+// isTransferDestination returns true if the operand field of a command
+// recorded to a command buffer is written by the command.
+func isTransferDestination(field string) bool {
+	switch field {
+	case "DstImage", "DstBuffer", "Image": // Image is the cleared image.
+		return true
+	}
+	return false
+}
+
+// beginRenderPass records the attachments of the render pass: they are
+// overwritten if neither their color, depth nor stencil data is loaded, and
+// the render area covers the whole framebuffer.
+func (t *primingAnalysis) beginRenderPass(cb VkCommandBuffer, info VkRenderPassBeginInfo, st *State) {
+	if !st.Framebuffers.Contains(info.Framebuffer) || !st.RenderPasses.Contains(info.RenderPass) {
+		return
+	}
+	framebuffer := st.Framebuffers.Get(info.Framebuffer)
+	descriptions := st.RenderPasses.Get(info.RenderPass).AttachmentDescriptions
+	area := info.RenderArea
+	whole := area.Offset.X == 0 && area.Offset.Y == 0 &&
+		area.Extent.Width >= framebuffer.Width && area.Extent.Height >= framebuffer.Height
+	for i, view := range framebuffer.ImageAttachments {
+		if view == nil || view.Image == nil || !descriptions.Contains(i) {
+			continue
+		}
+		image := view.Image
+		description := descriptions.Get(i)
+		load := description.LoadOp == VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_LOAD
+		stencil := image.ImageAspect&VkImageAspectFlags(VkImageAspectFlagBits_VK_IMAGE_ASPECT_STENCIL_BIT) != 0
+		if stencil && description.StencilLoadOp == VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_LOAD {
+			load = true
+		}
+		t.touches[cb] = append(t.touches[cb], resourceTouch{image: image.VulkanHandle, overwrite: whole && !load})
+	}
+}
+
+// bindDescriptorSets records the images and buffers of the bound descriptor
+// sets as read.
+func (t *primingAnalysis) bindDescriptorSets(cb VkCommandBuffer, sets []VkDescriptorSet, st *State) {
+	for _, handle := range sets {
+		if !st.DescriptorSets.Contains(handle) {
+			continue
+		}
+		for _, binding := range st.DescriptorSets.Get(handle).Bindings {
+			for _, info := range binding.BufferBinding {
+				if info != nil {
+					t.touches[cb] = append(t.touches[cb], resourceTouch{buffer: info.Buffer})
+				}
+			}
+			for _, info := range binding.ImageBinding {
+				if info != nil && st.ImageViews.Contains(info.ImageView) {
+					if view := st.ImageViews.Get(info.ImageView); view.Image != nil {
+						t.touches[cb] = append(t.touches[cb], resourceTouch{image: view.Image.VulkanHandle})
+					}
+				}
+			}
+			for _, handle := range binding.BufferViewBindings {
+				if st.BufferViews.Contains(handle) {
+					if view := st.BufferViews.Get(handle); view.Buffer != nil {
+						t.touches[cb] = append(t.touches[cb], resourceTouch{buffer: view.Buffer.VulkanHandle})
+					}
+				}
+			}
+		}
+	}
+}
+
+// snapshot collects the content of the buffers and images, and the signaled
+// fences and semaphores, from the state at the boundary. The resources
+// written by the GPU, other than by the tracked copies, are not primed and
+// marked as dirty.
+func (t *primingAnalysis) snapshot(ctx context.Context, s *gfxapi.State) {
+	st := GetState(s)
+	p := t.primer
+
+	storageBuffer := VkBufferUsageFlags(VkBufferUsageFlagBits_VK_BUFFER_USAGE_STORAGE_BUFFER_BIT |
+		VkBufferUsageFlagBits_VK_BUFFER_USAGE_STORAGE_TEXEL_BUFFER_BIT)
+	for _, handle := range st.Buffers.KeysSorted() {
+		buffer := st.Buffers.Get(handle)
+		if buffer.Memory == nil {
+			continue
+		}
+		if buffer.Info.Usage&storageBuffer != 0 || t.writtenBuffers[handle] {
+			t.dirtyBuffers[handle] = true
+			continue
+		}
+		device := buffer.Memory.Device
+		queue, ok := deviceQueue(st, device, buffer.LastBoundQueue)
+		if !ok {
+			continue
+		}
+		memoryType, ok := hostMemoryType(st, device)
+		if !ok {
+			continue
+		}
+		data := bufferData(ctx, s, boundBuffer{buffer: handle, size: uint64(buffer.Info.Size)})
+		p.buffers = append(p.buffers, primedBuffer{device, handle, queue, memoryType, data})
+	}
+
+	renderTarget := VkImageUsageFlags(VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT |
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT |
+		VkImageUsageFlagBits_VK_IMAGE_USAGE_STORAGE_BIT)
+	for _, handle := range st.Images.KeysSorted() {
+		image := st.Images.Get(handle)
+		dirty := image.IsSwapchainImage || image.Info.Usage&renderTarget != 0 || t.writtenImages[handle]
+		if dirty {
+			t.dirtyImages[handle] = true
+		}
+		if image.IsSwapchainImage || image.BoundMemory == nil ||
+			image.Info.Layout == VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED {
+			continue
+		}
+		device := image.BoundMemory.Device
+		queue, ok := deviceQueue(st, device, image.LastBoundQueue)
+		if !ok {
+			continue
+		}
+		memoryType, ok := hostMemoryType(st, device)
+		if !ok {
+			continue
+		}
+		var data []byte
+		if !dirty {
+			data = imageData(ctx, s, image)
+		}
+		p.images = append(p.images, primedImage{device, handle, image.Info.Layout, queue, memoryType, data})
+	}
+
+	for _, handle := range st.Fences.KeysSorted() {
+		if !t.fences.signaled(st, handle) || t.acquired[handle] {
+			continue
+		}
+		if queue, ok := deviceQueue(st, st.Fences.Get(handle).Device, nil); ok {
+			p.signals = append(p.signals, primedSignal{queue: queue, fence: handle})
+		}
+	}
+	for _, handle := range st.Semaphores.KeysSorted() {
+		semaphore := st.Semaphores.Get(handle)
+		if !semaphore.Signaled {
+			continue
+		}
+		queue := semaphore.LastQueue
+		if !st.Queues.Contains(queue) {
+			var ok bool
+			if queue, ok = deviceQueue(st, semaphore.Device, nil); !ok {
+				continue
+			}
+		}
+		p.signals = append(p.signals, primedSignal{queue: queue, semaphore: handle})
+	}
+}
+
+// imageData returns the data of all the planes, levels and layers of the
+// image, packed as expected by RecreateImageData.
+func imageData(ctx context.Context, s *gfxapi.State, image *ImageObject) []byte {
+	planes := []U32ːImageLayerʳᵐ{image.Layers}
+	if len(image.Planes) > 0 {
+		planes = planes[:0]
+		for _, p := range image.Planes.KeysSorted() {
+			planes = append(planes, image.Planes.Get(p).Layers)
+		}
+	}
+	out := []byte{}
+	for _, layers := range planes {
+		for level := uint32(0); level < image.Info.MipLevels; level++ {
+			for layer := uint32(0); layer < image.Info.ArrayLayers; layer++ {
+				if !layers.Contains(layer) || !layers.Get(layer).Levels.Contains(level) {
+					return nil
+				}
+				out = append(out, layers.Get(layer).Levels.Get(level).Data.Read(ctx, nil, s, nil)...)
+			}
+		}
+	}
+	return out
+}
+
+// deviceQueue returns the queue last used for a resource of the device, or
+// any queue of the device.
+func deviceQueue(st *State, device VkDevice, last *QueueObject) (VkQueue, bool) {
+	if last != nil {
+		return last.VulkanHandle, true
+	}
+	for _, handle := range st.Queues.KeysSorted() {
+		if st.Queues.Get(handle).Device == device {
+			return handle, true
+		}
+	}
+	return VkQueue(0), false
+}
+
+// hostMemoryType returns the index of a host visible and coherent memory type
+// of the device.
+func hostMemoryType(st *State, device VkDevice) (uint32, bool) {
+	if !st.Devices.Contains(device) || !st.PhysicalDevices.Contains(st.Devices.Get(device).PhysicalDevice) {
+		return 0, false
+	}
+	props := st.PhysicalDevices.Get(st.Devices.Get(device).PhysicalDevice).MemoryProperties
+	hostVisible := VkMemoryPropertyFlags(VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_HOST_VISIBLE_BIT |
+		VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_HOST_COHERENT_BIT)
+	for i := uint32(0); i < props.MemoryTypeCount; i++ {
+		if props.MemoryTypes.Elements[i].PropertyFlags&hostVisible == hostVisible {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Transform implements the transform.Transformer interface.
+func (t *statePrimer) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	if id < t.boundary {
+		switch a := a.(type) {
+		case *VkQueueSubmit, *VkQueueWaitIdle, *VkDeviceWaitIdle, *VkWaitForFences,
+			*VkGetFenceStatus, *VkGetEventStatus, *VkGetQueryPoolResults:
+			// Nothing is executed on the queues before the boundary.
+			return
+		case *VkAcquireNextImageKHR:
+			out.MutateAndWrite(ctx, id, a)
+			if a.Semaphore != VkSemaphore(0) {
+				// The submission waiting on the semaphore is skipped.
+				if queue, ok := deviceQueue(GetState(out.State()), a.Device, nil); ok {
+					submitSemaphore(ctx, out, queue, a.Semaphore, false)
+				}
+			}
+			return
+		case *VkQueuePresentKHR:
+			writePresentWithoutWaits(ctx, id, a, out)
+			return
+		}
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	if !t.primed {
+		t.prime(ctx, out)
+		t.primed = true
+	}
+	out.MutateAndWrite(ctx, id, a)
+}
+
+// Flush implements the transform.Transformer interface.
+func (t *statePrimer) Flush(ctx context.Context, out transform.Writer) {}
+
+// writePresentWithoutWaits writes a copy of the presentation a, without the
+// semaphores to wait on, which are signaled by the skipped submissions.
+func writePresentWithoutWaits(ctx context.Context, id atom.ID, a *VkQueuePresentKHR, out transform.Writer) {
+	s := out.State()
+	a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+	info := a.PPresentInfo.Read(ctx, a, s, nil)
+	if info.WaitSemaphoreCount == 0 {
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	info.WaitSemaphoreCount = 0
+	info.PWaitSemaphores = NewVkSemaphoreᶜᵖ(0)
+	data := atom.Must(atom.AllocData(ctx, s, info))
+	present := NewVkQueuePresentKHR(a.Queue, data.Ptr(), a.Result)
+	out.MutateAndWrite(ctx, id, withExtrasOf(a, present, data))
+	data.Free()
+}
+
+// prime writes the atoms restoring the state at the boundary. The resources
+// removed by the dead code elimination are not restored.
+func (t *statePrimer) prime(ctx context.Context, out transform.Writer) {
+	s := out.State()
+	st := GetState(s)
+	for _, b := range t.buffers {
+		if !st.Buffers.Contains(b.buffer) || st.Buffers.Get(b.buffer).Memory == nil || !st.Queues.Contains(b.queue) {
+			continue
+		}
+		data := MustAllocData(ctx, s, b.data)
+		writeEach(ctx, out, NewRecreateBufferData(b.device, b.buffer, b.memoryType, b.queue, data.Ptr()).AddRead(data.Data()))
+		data.Free()
+	}
+	for _, i := range t.images {
+		if !st.Images.Contains(i.image) || st.Images.Get(i.image).BoundMemory == nil || !st.Queues.Contains(i.queue) {
+			continue
+		}
+		if i.data == nil {
+			writeEach(ctx, out, NewRecreateImageData(i.device, i.image, i.layout, i.memoryType, i.queue, 0, memory.Pointer{}))
+			continue
+		}
+		data := MustAllocData(ctx, s, i.data)
+		writeEach(ctx, out, NewRecreateImageData(i.device, i.image, i.layout, i.memoryType, i.queue,
+			VkDeviceSize(len(i.data)), data.Ptr()).AddRead(data.Data()))
+		data.Free()
+	}
+	for _, signal := range t.signals {
+		if !st.Queues.Contains(signal.queue) {
+			continue
+		}
+		if signal.fence != VkFence(0) {
+			if !st.Fences.Contains(signal.fence) {
+				continue
+			}
+			writeEach(ctx, out, NewVkQueueSubmit(signal.queue, 0, memory.Pointer{}, signal.fence, VkResult_VK_SUCCESS))
+			continue
+		}
+		if st.Semaphores.Contains(signal.semaphore) {
+			submitSemaphore(ctx, out, signal.queue, signal.semaphore, true)
+		}
+	}
+}
+
+// submitSemaphore writes an empty submission to the queue, signaling the
+// semaphore if signal is true, or waiting for it otherwise.
+func submitSemaphore(ctx context.Context, out transform.Writer, queue VkQueue, semaphore VkSemaphore, signal bool) {
+	s := out.State()
+	semaphores := MustAllocData(ctx, s, semaphore)
+	defer semaphores.Free()
+	stages := MustAllocData(ctx, s, VkPipelineStageFlags(VkPipelineStageFlagBits_VK_PIPELINE_STAGE_ALL_COMMANDS_BIT))
+	defer stages.Free()
+	info := VkSubmitInfo{
+		SType:             VkStructureType_VK_STRUCTURE_TYPE_SUBMIT_INFO,
+		PNext:             NewVoidᶜᵖ(0),
+		PWaitSemaphores:   NewVkSemaphoreᶜᵖ(0),
+		PWaitDstStageMask: NewVkPipelineStageFlagsᶜᵖ(0),
+		PCommandBuffers:   NewVkCommandBufferᶜᵖ(0),
+		PSignalSemaphores: NewVkSemaphoreᶜᵖ(0),
+	}
+	if signal {
+		info.SignalSemaphoreCount = 1
+		info.PSignalSemaphores = NewVkSemaphoreᶜᵖ(semaphores.Address())
+	} else {
+		info.WaitSemaphoreCount = 1
+		info.PWaitSemaphores = NewVkSemaphoreᶜᵖ(semaphores.Address())
+		info.PWaitDstStageMask = NewVkPipelineStageFlagsᶜᵖ(stages.Address())
+	}
+	submitInfo := MustAllocData(ctx, s, info)
+	defer submitInfo.Free()
+	writeEach(ctx, out, NewVkQueueSubmit(queue, 1, submitInfo.Ptr(), VkFence(0), VkResult_VK_SUCCESS).
+		AddRead(submitInfo.Data()).
+		AddRead(semaphores.Data()).
+		AddRead(stages.Data()))
+}
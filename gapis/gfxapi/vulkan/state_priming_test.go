@@ -0,0 +1,177 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+)
+
+// primingSample is a capture of two frames: the first uploads a texture from
+// a staging buffer, the second blits the texture to a framebuffer image.
+type primingSample struct {
+	atoms                *atom.List
+	texture, framebuffer vulkan.VkImage
+	upload, present      atom.ID // The submission and the presentation of the first frame.
+	blit                 atom.ID // The submission of the second frame.
+}
+
+// newPrimingSample builds the priming sample. If reupload is true, the second
+// frame uploads the texture again from the staging buffer, which was written
+// by the GPU in the first frame.
+func newPrimingSample(ctx context.Context, reupload bool) primingSample {
+	const size = 64
+	texels := make([]uint32, size*size)
+	for i := range texels {
+		texels[i] = uint32(i) * 0x01020304
+	}
+
+	b := samples.NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+	b.MemoryProperties(ctx)
+	swapchain := b.Swapchain(ctx, device, size, size)
+	staging := b.Buffer(ctx, device, size*size*4,
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_SRC_BIT|
+			vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT)
+	texture := b.Image(ctx, device, size, size,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT|
+			vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT|
+			vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT)
+	framebuffer := b.Image(ctx, device, size, size, vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT)
+
+	// Upload the texture in the first frame
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	b.UpdateBuffer(ctx, cb, staging, texels)
+	b.ImageBarrier(ctx, cb, texture, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL)
+	b.MemoryBarrier(ctx, cb)
+	b.CopyBufferToImage(ctx, cb, staging, texture, size, size, 0)
+	upload := b.Submit(ctx, queue, cb)
+	present := b.Present(ctx, queue, swapchain)
+
+	// Blit it to the framebuffer in the second one
+	cb = b.BeginCommandBuffer(ctx, device, pool)
+	if reupload {
+		b.CopyBufferToImage(ctx, cb, staging, texture, size, size, 0)
+	}
+	b.ImageBarrier(ctx, cb, framebuffer, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL)
+	corner := vulkan.VkOffset3D{X: size, Y: size, Z: 1}
+	b.BlitImage(ctx, cb, texture, corner, framebuffer, [2]vulkan.VkOffset3D{{X: 0, Y: 0, Z: 0}, corner})
+	blit := b.Submit(ctx, queue, cb)
+
+	return primingSample{&b.List, texture, framebuffer, upload, present, blit}
+}
+
+// imageLevel returns the layout and the data of the first level of the image.
+func imageLevel(ctx context.Context, s *gfxapi.State, image vulkan.VkImage) (vulkan.VkImageLayout, []byte) {
+	st := vulkan.GetState(s)
+	if !st.Images.Contains(image) {
+		return vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED, nil
+	}
+	i := st.Images.Get(image)
+	if !i.Layers.Contains(0) || !i.Layers.Get(0).Levels.Contains(0) {
+		return i.Info.Layout, nil
+	}
+	return i.Info.Layout, i.Layers.Get(0).Levels.Get(0).Data.Read(ctx, nil, s, nil)
+}
+
+func TestStatePriming(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	sample := newPrimingSample(ctx, false)
+	c, err := capture.ImportAtomList(ctx, "state-priming", sample.atoms)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+
+	primer, err := vulkan.NewStatePrimer(ctx, sample.atoms.Atoms, []atom.ID{sample.blit})
+	if !assert.For(ctx, "NewStatePrimer").ThatError(err).Succeeded() ||
+		!assert.For(ctx, "Primer").That(primer).IsNotNil() {
+		return
+	}
+
+	unprimed := &recorder{state: capture.NewState(ctx)}
+	primed := &recorder{state: capture.NewState(ctx)}
+	for i, a := range sample.atoms.Atoms {
+		unprimed.MutateAndWrite(ctx, atom.ID(i), a)
+		primer.Transform(ctx, atom.ID(i), a, primed)
+	}
+	primer.Flush(ctx, primed)
+
+	assert.For(ctx, "Unprimed mutate").ThatError(unprimed.err).Succeeded()
+	assert.For(ctx, "Primed mutate").ThatError(primed.err).Succeeded()
+
+	written := map[atom.ID]bool{}
+	for _, id := range primed.written {
+		written[id] = true
+	}
+	assert.For(ctx, "First frame submitted").That(written[sample.upload]).Equals(false)
+	assert.For(ctx, "First frame presented").That(written[sample.present]).Equals(true)
+	assert.For(ctx, "Second frame submitted").That(written[sample.blit]).Equals(true)
+
+	restored := 0
+	for _, a := range primed.injected {
+		if _, ok := a.(*vulkan.RecreateImageData); ok {
+			restored++
+		}
+	}
+	assert.For(ctx, "Restored images").That(restored).Equals(1)
+
+	for _, image := range []struct {
+		name   string
+		handle vulkan.VkImage
+	}{
+		{"Texture", sample.texture},
+		{"Framebuffer", sample.framebuffer},
+	} {
+		expectedLayout, expected := imageLevel(ctx, unprimed.state, image.handle)
+		gotLayout, got := imageLevel(ctx, primed.state, image.handle)
+		assert.For(ctx, "%v layout", image.name).That(gotLayout).Equals(expectedLayout)
+		assert.For(ctx, "%v data", image.name).ThatSlice(got).Equals(expected)
+	}
+}
+
+func TestStatePrimingRefused(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	sample := newPrimingSample(ctx, true)
+	c, err := capture.ImportAtomList(ctx, "state-priming-refused", sample.atoms)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+
+	// The staging buffer read by the second frame is written by the GPU in
+	// the first one, its content cannot be restored.
+	primer, err := vulkan.NewStatePrimer(ctx, sample.atoms.Atoms, []atom.ID{sample.blit})
+	assert.For(ctx, "NewStatePrimer").ThatError(err).Succeeded()
+	assert.For(ctx, "Primer").That(primer).IsNil()
+
+	// Requests in the first frame are never primed.
+	primer, err = vulkan.NewStatePrimer(ctx, sample.atoms.Atoms, []atom.ID{sample.upload})
+	assert.For(ctx, "NewStatePrimer first frame").ThatError(err).Succeeded()
+	assert.For(ctx, "Primer first frame").That(primer).IsNil()
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+var _ = atom.SubmissionTracker(api{})
+
+// Submissions returns the vkQueueSubmit, vkQueuePresentKHR,
+// vkAcquireNextImageKHR and vkWaitForFences atoms of the capture held by ctx,
+// with the semaphores and fences they wait on and signal.
+func (api) Submissions(ctx context.Context) ([]atom.Submission, error) {
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []atom.Submission{}
+	s := c.NewState()
+	for i, a := range list.Atoms {
+		id := atom.ID(i)
+		if err := a.Mutate(ctx, s, nil); err != nil {
+			log.W(ctx, "Atom %v %v: %v", id, a, err)
+			continue
+		}
+		if sub, ok := submission(ctx, id, a, s); ok {
+			out = append(out, sub)
+		}
+	}
+	return out, nil
+}
+
+// submission returns the submission of the atom a, mutated on s, if a is a
+// queue submission or a host synchronization atom.
+func submission(ctx context.Context, id atom.ID, a atom.Atom, s *gfxapi.State) (atom.Submission, bool) {
+	out := atom.Submission{Atom: id}
+	switch a := a.(type) {
+	case *VkQueueSubmit:
+		out.Kind, out.Queue = atom.QueueSubmit, uint64(a.Queue)
+		submits := a.PSubmits.Slice(0, uint64(a.SubmitCount), s)
+		for i := uint64(0); i < submits.Info().Count; i++ {
+			info := submits.Index(i, s).Read(ctx, a, s, nil)
+			waits := info.PWaitSemaphores.Slice(0, uint64(info.WaitSemaphoreCount), s)
+			out.Waits = appendSemaphores(out.Waits, waits.Read(ctx, a, s, nil))
+			signals := info.PSignalSemaphores.Slice(0, uint64(info.SignalSemaphoreCount), s)
+			out.Signals = appendSemaphores(out.Signals, signals.Read(ctx, a, s, nil))
+		}
+		if a.Fence != VkFence(0) {
+			out.Fences = []uint64{uint64(a.Fence)}
+		}
+	case *VkQueuePresentKHR:
+		out.Kind, out.Queue = atom.QueuePresent, uint64(a.Queue)
+		info := a.PPresentInfo.Read(ctx, a, s, nil)
+		if info.PWaitSemaphores.Address != 0 {
+			waits := info.PWaitSemaphores.Slice(0, uint64(info.WaitSemaphoreCount), s)
+			out.Waits = appendSemaphores(out.Waits, waits.Read(ctx, a, s, nil))
+		}
+	case *VkAcquireNextImageKHR:
+		out.Kind = atom.ImageAcquire
+		if a.Semaphore != VkSemaphore(0) {
+			out.Signals = []uint64{uint64(a.Semaphore)}
+		}
+		if a.Fence != VkFence(0) {
+			out.Fences = []uint64{uint64(a.Fence)}
+		}
+	case *VkWaitForFences:
+		out.Kind = atom.FenceWait
+		for _, fence := range a.PFences.Slice(0, uint64(a.FenceCount), s).Read(ctx, a, s, nil) {
+			out.Fences = append(out.Fences, uint64(fence))
+		}
+	default:
+		return atom.Submission{}, false
+	}
+	return out, true
+}
+
+func appendSemaphores(l []uint64, semaphores []VkSemaphore) []uint64 {
+	for _, semaphore := range semaphores {
+		l = append(l, uint64(semaphore))
+	}
+	return l
+}
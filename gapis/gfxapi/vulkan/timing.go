@@ -0,0 +1,192 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/replay/builder"
+	"github.com/google/gapid/gapis/replay/value"
+)
+
+// timingsConfig is a replay.Config used by timingsRequests.
+type timingsConfig struct{}
+
+// timingsRequest requests the GPU execution time of all the draw and dispatch
+// commands of the capture.
+type timingsRequest struct{}
+
+// Uncached implements the replay.Uncached interface, as timings are measured
+// anew by each replay.
+func (timingsRequest) Uncached() {}
+
+// timings is a transform that writes a timestamp before and after each draw
+// and dispatch recorded to primary command buffers. The timestamps are read
+// back after each submission of the command buffers.
+type timings struct {
+	res       []replay.Result
+	queries   *commandQueries
+	periods   map[VkDevice]float64 // Nanoseconds per timestamp tick.
+	durations map[atom.ID]*replay.Timing
+}
+
+func newTimings() *timings {
+	return &timings{
+		queries:   newCommandQueries(VkQueryType_VK_QUERY_TYPE_TIMESTAMP, 0, 2, 1),
+		periods:   map[VkDevice]float64{},
+		durations: map[atom.ID]*replay.Timing{},
+	}
+}
+
+// reportTo adds r to the results notified with the timings.
+func (t *timings) reportTo(r replay.Result) {
+	t.res = append(t.res, r)
+}
+
+func (t *timings) Transform(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+	s := out.State()
+	switch a := a.(type) {
+	case *VkBeginCommandBuffer:
+		out.MutateAndWrite(ctx, id, a)
+		t.begin(ctx, a.CommandBuffer, out)
+		return
+	case *RecreateAndBeginCommandBuffer:
+		out.MutateAndWrite(ctx, id, a)
+		if a.PBeginInfo.Address != 0 {
+			t.begin(ctx, a.PCommandBuffer.Read(ctx, a, s, nil), out)
+		}
+		return
+	case *VkQueueSubmit:
+		out.MutateAndWrite(ctx, id, a)
+		t.queries.submit(ctx, a, out, t.read)
+		return
+	}
+
+	cb, _, ok := constantsCommandBuffer(a)
+	if !ok {
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	pool, query, ok := t.queries.add(ctx, cb, id)
+	if !ok {
+		out.MutateAndWrite(ctx, id, a)
+		return
+	}
+	writeEach(ctx, out, NewVkCmdWriteTimestamp(cb, VkPipelineStageFlagBits_VK_PIPELINE_STAGE_TOP_OF_PIPE_BIT, pool, query))
+	out.MutateAndWrite(ctx, id, a)
+	writeEach(ctx, out, NewVkCmdWriteTimestamp(cb, VkPipelineStageFlagBits_VK_PIPELINE_STAGE_BOTTOM_OF_PIPE_BIT, pool, query+1))
+}
+
+// begin resets the timestamp queries of the command buffer cb. The timestamp
+// period of the device is read back the first time one of its command buffers
+// is instrumented.
+func (t *timings) begin(ctx context.Context, cb VkCommandBuffer, out transform.Writer) {
+	device, ok := t.queries.begin(ctx, cb, out)
+	if !ok {
+		return
+	}
+	if _, ok := t.periods[device]; ok {
+		return
+	}
+	t.periods[device] = 1
+	postTimestampPeriod(ctx, out, device, func(period float64) { t.periods[device] = period })
+}
+
+// postTimestampPeriod writes the atoms reading back the timestamp period of
+// the device, in nanoseconds per timestamp tick. f is called with the period
+// during the replay.
+func postTimestampPeriod(ctx context.Context, out transform.Writer, device VkDevice, f func(period float64)) {
+	s := out.State()
+	physicalDevice := GetState(s).Devices.Get(device).PhysicalDevice
+	size := VkPhysicalDevicePropertiesSize(s)
+	props := atom.Must(atom.AllocData(ctx, s, VkPhysicalDeviceProperties{}))
+	defer props.Free()
+
+	writeEach(ctx, out,
+		NewVkGetPhysicalDeviceProperties(physicalDevice, props.Ptr()).AddWrite(props.Data()),
+		replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+			b.Post(value.ObservedPointer(props.Address()), size, func(r pod.Reader, err error) error {
+				if err != nil {
+					return err
+				}
+				var properties VkPhysicalDeviceProperties
+				VkPhysicalDevicePropertiesDecodeRaw(s, r, &properties)
+				if err := r.Error(); err != nil {
+					return err
+				}
+				f(float64(properties.Limits.TimestampPeriod))
+				return nil
+			})
+			return nil
+		}),
+	)
+}
+
+// read accumulates the duration between the timestamps of the command id.
+func (t *timings) read(device VkDevice, id atom.ID, results []uint64) {
+	d, ok := t.durations[id]
+	if !ok {
+		d = &replay.Timing{Atom: id}
+		t.durations[id] = d
+	}
+	if start, end := results[0], results[1]; end > start {
+		d.Duration += time.Duration(float64(end-start) * t.periods[device])
+	}
+	d.Count++
+}
+
+func (t *timings) Flush(ctx context.Context, out transform.Writer) {
+	// Notify the results once all the posted timestamps have been received.
+	out.MutateAndWrite(ctx, atom.NoID, replay.Custom(func(ctx context.Context, s *gfxapi.State, b *builder.Builder) error {
+		code := uint32(0x7133e7a5)
+		b.Push(value.U32(code))
+		b.Post(b.Buffer(1), 4, func(r pod.Reader, err error) error {
+			if err == nil && r.Uint32() != code {
+				err = fmt.Errorf("Flush did not get expected EOS code")
+			}
+			if err != nil {
+				for _, res := range t.res {
+					res(nil, err)
+				}
+				return err
+			}
+			list := make([]replay.Timing, 0, len(t.durations))
+			for _, d := range t.durations {
+				list = append(list, *d)
+			}
+			sort.Sort(timingsByAtom(list))
+			for _, res := range t.res {
+				res(list, nil)
+			}
+			return nil
+		})
+		return nil
+	}))
+}
+
+// timingsByAtom sorts timings by increasing atom identifier.
+type timingsByAtom []replay.Timing
+
+func (l timingsByAtom) Len() int           { return len(l) }
+func (l timingsByAtom) Less(i, j int) bool { return l[i].Atom < l[j].Atom }
+func (l timingsByAtom) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
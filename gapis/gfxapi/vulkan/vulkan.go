@@ -44,7 +44,11 @@ func (api) GetFramebufferAttachmentInfo(state *gfxapi.State, attachment gfxapi.F
 	w, h, form, _, err := GetState(state).getFramebufferAttachmentInfo(attachment)
 	switch attachment {
 	case gfxapi.FramebufferAttachment_Stencil:
-		return 0, 0, nil, fmt.Errorf("Unsupported Stencil")
+		format, err := getStencilImageFormatFromVulkanFormat(form)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("Unknown format for Stencil attachment")
+		}
+		return w, h, format, err
 	case gfxapi.FramebufferAttachment_Depth:
 		format, err := getDepthImageFormatFromVulkanFormat(form)
 		if err != nil {
@@ -0,0 +1,122 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/transform"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// wireframe returns an atom transform that replaces the polygon mode of all
+// the graphics pipelines with VK_POLYGON_MODE_LINE, and enables the
+// fillModeNonSolid feature of all the devices the pipelines are created on.
+// TODO: Emulate the wireframe with a geometry shader for replay devices that
+// do not support fillModeNonSolid.
+func wireframe(ctx context.Context) transform.Transformer {
+	return transform.Transform("Wireframe", func(ctx context.Context, id atom.ID, a atom.Atom, out transform.Writer) {
+		s := out.State()
+		a.Extras().Observations().ApplyReads(s.Memory[memory.ApplicationPool])
+		switch a := a.(type) {
+		case *VkCreateDevice:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			features := enableFillModeNonSolid(ctx, s, a, info.PEnabledFeatures)
+			info.PEnabledFeatures = VkPhysicalDeviceFeaturesᶜᵖ(features.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewVkCreateDevice(a.PhysicalDevice, newInfo.Ptr(),
+				memory.Pointer(a.PAllocator), memory.Pointer(a.PDevice), a.Result)
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, features, newInfo))
+		case *RecreateDevice:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			features := enableFillModeNonSolid(ctx, s, a, info.PEnabledFeatures)
+			info.PEnabledFeatures = VkPhysicalDeviceFeaturesᶜᵖ(features.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewRecreateDevice(a.PhysicalDevice, newInfo.Ptr(), memory.Pointer(a.PDevice))
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, features, newInfo))
+		case *VkCreateGraphicsPipelines:
+			infos := a.PCreateInfos.Slice(0, uint64(a.CreateInfoCount), s).Read(ctx, a, s, nil)
+			data := []atom.AllocResult{}
+			for i := range infos {
+				if raster, ok := lineRasterizationState(ctx, s, a, infos[i].PRasterizationState); ok {
+					infos[i].PRasterizationState = VkPipelineRasterizationStateCreateInfoᶜᵖ(raster.Ptr())
+					data = append(data, raster)
+				}
+			}
+			newInfos := atom.Must(atom.AllocData(ctx, s, infos))
+			newAtom := NewVkCreateGraphicsPipelines(a.Device, a.PipelineCache, a.CreateInfoCount,
+				newInfos.Ptr(), memory.Pointer(a.PAllocator), memory.Pointer(a.PPipelines), a.Result)
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, append(data, newInfos)...))
+		case *RecreateGraphicsPipeline:
+			info := a.PCreateInfo.Read(ctx, a, s, nil)
+			raster, ok := lineRasterizationState(ctx, s, a, info.PRasterizationState)
+			if !ok {
+				out.MutateAndWrite(ctx, id, a)
+				return
+			}
+			info.PRasterizationState = VkPipelineRasterizationStateCreateInfoᶜᵖ(raster.Ptr())
+			newInfo := atom.Must(atom.AllocData(ctx, s, info))
+			newAtom := NewRecreateGraphicsPipeline(a.Device, a.PipelineCache, newInfo.Ptr(), memory.Pointer(a.PPipeline))
+			out.MutateAndWrite(ctx, id, withExtrasOf(a, newAtom, raster, newInfo))
+		default:
+			out.MutateAndWrite(ctx, id, a)
+		}
+	})
+}
+
+// enableFillModeNonSolid returns a copy of the device features pointed by p,
+// or of no features if p is null, with fillModeNonSolid enabled.
+func enableFillModeNonSolid(ctx context.Context, s *gfxapi.State, a atom.Atom, p VkPhysicalDeviceFeaturesᶜᵖ) atom.AllocResult {
+	features := VkPhysicalDeviceFeatures{}
+	if p.Address != 0 {
+		features = p.Read(ctx, a, s, nil)
+	}
+	features.FillModeNonSolid = VkBool32(1)
+	return atom.Must(atom.AllocData(ctx, s, features))
+}
+
+// lineRasterizationState returns a copy of the rasterization state pointed by
+// p with the polygon mode set to VK_POLYGON_MODE_LINE. It returns false if
+// p is null, which is the case for pipelines with rasterization disabled.
+func lineRasterizationState(ctx context.Context, s *gfxapi.State, a atom.Atom, p VkPipelineRasterizationStateCreateInfoᶜᵖ) (atom.AllocResult, bool) {
+	if p.Address == 0 {
+		return atom.AllocResult{}, false
+	}
+	raster := p.Read(ctx, a, s, nil)
+	raster.PolygonMode = VkPolygonMode_VK_POLYGON_MODE_LINE
+	raster.LineWidth = 1
+	return atom.Must(atom.AllocData(ctx, s, raster)), true
+}
+
+// withExtrasOf adds the extras and observations of the atom src to dst,
+// followed by read observations of the newly allocated data, and returns dst.
+func withExtrasOf(src, dst atom.Atom, data ...atom.AllocResult) atom.Atom {
+	for _, e := range src.Extras().All() {
+		if _, ok := e.(*atom.Observations); !ok {
+			dst.Extras().Add(e)
+		}
+	}
+	observations := dst.Extras().GetOrAppendObservations()
+	if o := src.Extras().Observations(); o != nil {
+		observations.Reads = append(observations.Reads, o.Reads...)
+		observations.Writes = append(observations.Writes, o.Writes...)
+	}
+	for _, d := range data {
+		observations.AddRead(d.Data())
+	}
+	return dst
+}
@@ -21,6 +21,8 @@ set(files
     allocator.go
     allocator_test.go
     blob.go
+    chunked.go
+    chunked_test.go
     doc.go
     id.go
     memory.pb.go
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"unsafe"
 
 	"github.com/google/gapid/core/math/interval"
 )
@@ -35,6 +36,10 @@ type Allocator interface {
 
 	// FreeList returns the free ranges this allocator can allocate from.
 	FreeList() interval.U64RangeList
+
+	// Clone returns a new allocator with the same allocations, which can be
+	// used independently of this allocator.
+	Clone() Allocator
 }
 
 // BasicAllocator is a simple memory range allocator
@@ -93,6 +98,25 @@ func (c *basicAllocator) FreeList() interval.U64RangeList {
 	return c.freeList.Clone()
 }
 
+// Clone implements Allocator.
+func (c *basicAllocator) Clone() Allocator {
+	allocations := make(map[uint64]uint64, len(c.allocations))
+	for base, count := range c.allocations {
+		allocations[base] = count
+	}
+	return &basicAllocator{
+		freeList:    c.freeList.Clone(),
+		allocations: allocations,
+	}
+}
+
+// AllocatorSize returns an estimate of the number of bytes of host memory held
+// by the allocator a.
+func AllocatorSize(a Allocator) uint64 {
+	ranges := len(a.AllocList()) + len(a.FreeList())
+	return uint64(ranges) * uint64(unsafe.Sizeof(interval.U64Range{}))
+}
+
 // NewBasicAllocator creates a new allocator which allocates
 // memory from the given list of free ranges. Memory is allocated
 // by finding the leftmost free block large enough to fit the
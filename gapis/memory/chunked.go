@@ -0,0 +1,51 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/gapis/database"
+)
+
+// NewChunkedResolvable returns a ChunkedResolvable that reassembles the data
+// of the resources identified by chunks, in order.
+func NewChunkedResolvable(chunks []id.ID) *ChunkedResolvable {
+	out := &ChunkedResolvable{Chunks: make([]*ID, len(chunks))}
+	for i, c := range chunks {
+		out.Chunks[i] = NewID(c)
+	}
+	return out
+}
+
+// Resolve implements the database.Resolver interface.
+func (c *ChunkedResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	chunks := make([][]byte, len(c.Chunks))
+	size := 0
+	for i, chunk := range c.Chunks {
+		data, err := database.Resolve(ctx, chunk.ID())
+		if err != nil {
+			return nil, err
+		}
+		chunks[i] = data.([]byte)
+		size += len(chunks[i])
+	}
+	out := make([]byte, 0, size)
+	for _, chunk := range chunks {
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
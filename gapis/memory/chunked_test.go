@@ -0,0 +1,44 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/memory"
+)
+
+func TestChunkedResolvable(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	chunks := []id.ID{}
+	for _, data := range [][]byte{{1, 2, 3}, {4, 5}, {1, 2, 3}} {
+		id, err := database.Store(ctx, data)
+		if !assert.With(ctx).ThatError(err).Succeeded() {
+			return
+		}
+		chunks = append(chunks, id)
+	}
+
+	got, err := database.Build(ctx, memory.NewChunkedResolvable(chunks))
+	if assert.With(ctx).ThatError(err).Succeeded() {
+		assert.With(ctx).ThatSlice(got).Equals([]byte{1, 2, 3, 4, 5, 1, 2, 3})
+	}
+}
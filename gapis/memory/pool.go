@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"unsafe"
 
 	"github.com/google/gapid/core/data/id"
 	"github.com/google/gapid/core/math/interval"
@@ -89,6 +90,48 @@ func (m *Pool) Write(dst uint64, src Slice) {
 	m.writes[i].src = src
 }
 
+// Clone returns a new pool holding the same data as m. Subsequent writes to
+// either pool do not affect the other. The OnRead and OnWrite callbacks are
+// not copied.
+func (m *Pool) Clone() *Pool {
+	writes := make(poolWriteList, len(m.writes))
+	copy(writes, m.writes)
+	return &Pool{writes: writes}
+}
+
+// HostMemorySize returns an estimate of the number of bytes of host memory held
+// by the pools: their records of the writes, and the data of the writes held
+// in memory. Data shared by several of the pools, as it is by cloned pools, is
+// counted once. The data of the writes backed by resources is held by the
+// database and is not counted.
+func HostMemorySize(pools ...*Pool) uint64 {
+	c := hostMemoryCounter{seen: map[*blob]bool{}}
+	for _, p := range pools {
+		c.add(p.writes)
+	}
+	return c.size
+}
+
+type hostMemoryCounter struct {
+	seen map[*blob]bool
+	size uint64
+}
+
+func (c *hostMemoryCounter) add(writes poolWriteList) {
+	c.size += uint64(len(writes)) * uint64(unsafe.Sizeof(poolWrite{}))
+	for _, w := range writes {
+		switch src := w.src.(type) {
+		case *blob:
+			if !c.seen[src] {
+				c.seen[src] = true
+				c.size += uint64(len(src.data))
+			}
+		case poolSlice:
+			c.add(src.writes)
+		}
+	}
+}
+
 // String returns the full history of writes performed to this pool.
 func (m *Pool) String() string {
 	l := make([]string, len(m.writes)+1)
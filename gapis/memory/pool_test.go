@@ -19,6 +19,7 @@ import (
 	"context"
 	"io"
 	"testing"
+	"unsafe"
 
 	"github.com/google/gapid/core/assert"
 	"github.com/google/gapid/core/data/id"
@@ -209,6 +210,36 @@ func TestMemoryResourceWriteReadScattered(t *testing.T) {
 	}
 }
 
+func TestPoolClone(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+	p := Pool{}
+	p.Write(0, Blob([]byte{10, 11, 12, 13}))
+	c := p.Clone()
+	p.Write(1, Blob([]byte{20, 21}))
+	c.Write(2, Blob([]byte{30, 31}))
+
+	checkSlice(ctx, p.Slice(Range{Base: 0, Size: 4}), []byte{10, 20, 21, 13})
+	checkSlice(ctx, c.Slice(Range{Base: 0, Size: 4}), []byte{10, 11, 30, 31})
+}
+
+func TestHostMemorySize(t *testing.T) {
+	ctx := log.Testing(t)
+	write := uint64(unsafe.Sizeof(poolWrite{}))
+	p := &Pool{}
+	p.Write(0, Blob([]byte{10, 11, 12, 13}))
+	p.Write(8, Resource(id.ID{}, 4))
+	assert.For(ctx, "pool").That(HostMemorySize(p)).Equals(2*write + 4)
+
+	c := p.Clone()
+	c.Write(16, Blob([]byte{20, 21}))
+	assert.For(ctx, "clone").That(HostMemorySize(p, c)).Equals(5*write + 6)
+
+	q := &Pool{}
+	q.Write(0, c.Slice(Range{Base: 0, Size: 32}))
+	assert.For(ctx, "slice").That(HostMemorySize(q)).Equals(4*write + 6)
+}
+
 func TestPoolSliceReaderErrorPropagation(t *testing.T) {
 	ctx := log.Testing(t)
 	ctx = database.Put(ctx, database.NewInMemory(ctx))
@@ -0,0 +1,162 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package perfetto writes traces in the Perfetto protobuf trace format, which
+// can be opened by the Perfetto UI and trace processor alongside system-wide
+// traces.
+//
+// Only the subset of the format needed to describe tracks, slices and instant
+// events is supported. The messages are encoded directly, so the Perfetto
+// protos are not required.
+package perfetto
+
+import "encoding/binary"
+
+// Field numbers of the Perfetto trace protos.
+const (
+	tracePacket = 1 // Trace.packet
+
+	packetTimestamp       = 8  // TracePacket.timestamp
+	packetSequenceID      = 10 // TracePacket.trusted_packet_sequence_id
+	packetTrackEvent      = 11 // TracePacket.track_event
+	packetSequenceFlags   = 13 // TracePacket.sequence_flags
+	packetTrackDescriptor = 60 // TracePacket.track_descriptor
+
+	descriptorUUID   = 1 // TrackDescriptor.uuid
+	descriptorName   = 2 // TrackDescriptor.name
+	descriptorParent = 5 // TrackDescriptor.parent_uuid
+
+	eventType       = 9  // TrackEvent.type
+	eventTrackUUID  = 11 // TrackEvent.track_uuid
+	eventCategories = 22 // TrackEvent.categories
+	eventName       = 23 // TrackEvent.name
+)
+
+// TrackEvent.Type values.
+const (
+	typeSliceBegin = 1
+	typeSliceEnd   = 2
+	typeInstant    = 3
+)
+
+// sequenceID is the trusted_packet_sequence_id of all the packets.
+const sequenceID = 1
+
+// seqIncrementalStateCleared is the TracePacket.SequenceFlags value marking
+// the first packet of the sequence.
+const seqIncrementalStateCleared = 1
+
+// Track identifies a track of a Trace.
+type Track uint64
+
+// Trace builds a Perfetto trace. Timestamps are in nanoseconds.
+type Trace struct {
+	data      []byte
+	nextTrack Track
+}
+
+// NewTrace returns a new, empty trace.
+func NewTrace() *Trace {
+	return &Trace{nextTrack: 1}
+}
+
+// Track adds a new track with the given name to the trace. If parent is not
+// zero, the track is nested under parent.
+func (t *Trace) Track(name string, parent Track) Track {
+	track := t.nextTrack
+	t.nextTrack++
+
+	d := message{}
+	d.uint(descriptorUUID, uint64(track))
+	d.string(descriptorName, name)
+	if parent != 0 {
+		d.uint(descriptorParent, uint64(parent))
+	}
+	p := message{}
+	if track == 1 {
+		p.uint(packetSequenceFlags, seqIncrementalStateCleared)
+	}
+	p.uint(packetSequenceID, sequenceID)
+	p.bytes(packetTrackDescriptor, d)
+	t.packet(p)
+	return track
+}
+
+// Slice adds a slice named name on track, from start to end.
+func (t *Trace) Slice(track Track, category, name string, start, end uint64) {
+	t.event(track, typeSliceBegin, category, name, start)
+	t.event(track, typeSliceEnd, "", "", end)
+}
+
+// Instant adds an instant event named name on track at time ts.
+func (t *Trace) Instant(track Track, category, name string, ts uint64) {
+	t.event(track, typeInstant, category, name, ts)
+}
+
+// Bytes returns the encoded trace.
+func (t *Trace) Bytes() []byte {
+	return t.data
+}
+
+func (t *Trace) event(track Track, ty uint64, category, name string, ts uint64) {
+	e := message{}
+	e.uint(eventType, ty)
+	e.uint(eventTrackUUID, uint64(track))
+	if category != "" {
+		e.string(eventCategories, category)
+	}
+	if name != "" {
+		e.string(eventName, name)
+	}
+	p := message{}
+	p.uint(packetTimestamp, ts)
+	p.uint(packetSequenceID, sequenceID)
+	p.bytes(packetTrackEvent, e)
+	t.packet(p)
+}
+
+func (t *Trace) packet(p message) {
+	m := message(t.data)
+	m.bytes(tracePacket, p)
+	t.data = m
+}
+
+// message is an encoded protobuf message.
+type message []byte
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (m *message) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	*m = append(*m, buf[:n]...)
+}
+
+func (m *message) uint(field int, v uint64) {
+	m.varint(uint64(field)<<3 | wireVarint)
+	m.varint(v)
+}
+
+func (m *message) bytes(field int, b []byte) {
+	m.varint(uint64(field)<<3 | wireBytes)
+	m.varint(uint64(len(b)))
+	*m = append(*m, b...)
+}
+
+func (m *message) string(field int, s string) {
+	m.bytes(field, []byte(s))
+}
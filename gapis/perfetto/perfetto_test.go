@@ -0,0 +1,49 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perfetto_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/perfetto"
+)
+
+func TestTrace(t *testing.T) {
+	ctx := log.Testing(t)
+	trace := perfetto.NewTrace()
+	track := trace.Track("GPU", 0)
+	assert.With(ctx).That(track).Equals(perfetto.Track(1))
+	trace.Instant(track, "gpu", "a", 300)
+
+	expected := []byte{
+		0x0a, 0x0e, // Trace.packet, 14 bytes
+		0x68, 0x01, // sequence_flags: 1
+		0x50, 0x01, // trusted_packet_sequence_id: 1
+		0xe2, 0x03, 0x07, // track_descriptor, 7 bytes
+		0x08, 0x01, // uuid: 1
+		0x12, 0x03, 'G', 'P', 'U', // name: "GPU"
+		0x0a, 0x15, // Trace.packet, 21 bytes
+		0x40, 0xac, 0x02, // timestamp: 300
+		0x50, 0x01, // trusted_packet_sequence_id: 1
+		0x5a, 0x0e, // track_event, 14 bytes
+		0x48, 0x03, // type: TYPE_INSTANT
+		0x58, 0x01, // track_uuid: 1
+		0xb2, 0x01, 0x03, 'g', 'p', 'u', // categories: "gpu"
+		0xba, 0x01, 0x01, 'a', // name: "a"
+	}
+	assert.With(ctx).That(trace.Bytes()).DeepEquals(expected)
+}
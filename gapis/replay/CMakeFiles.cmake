@@ -19,19 +19,30 @@
 
 set(files
     batch.go
+    cache.go
+    cache_test.go
+    coalesce.go
+    coalesce_test.go
     context.go
     custom.go
     doc.go
     events.go
     interfaces.go
     manager.go
+    overdraw.go
+    overdraw_test.go
+    pool.go
+    pool_test.go
     replay.go
     replay.pb.go
     replay.proto
+    status.go
+    status_test.go
 )
 set(dirs
     asm
     builder
+    cpp
     executor
     opcode
     protocol
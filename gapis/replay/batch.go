@@ -25,9 +25,11 @@ import (
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/capture"
 	"github.com/google/gapid/gapis/config"
+	"github.com/google/gapid/gapis/database"
 	"github.com/google/gapid/gapis/gfxapi"
 	"github.com/google/gapid/gapis/replay/builder"
 	"github.com/google/gapid/gapis/replay/executor"
+	"github.com/google/gapid/gapis/replay/protocol"
 	"github.com/google/gapid/gapis/replay/scheduler"
 	"github.com/google/gapid/gapis/service/path"
 )
@@ -67,7 +69,11 @@ func (m *Manager) batch(ctx context.Context, e []scheduler.Executable, b schedul
 		"priority": b.Priority,
 		"delay":    b.Precondition,
 	}.Bind(ctx)
+	// The requests are resolves waiting on the replay, which may hold all the
+	// database slots.
+	ctx = database.PutDependent(ctx)
 	log.I(ctx, "Replay for %d requests", len(e))
+	m.leaveBatch(b)
 
 	requests := make([]RequestAndResult, len(e))
 	for i, e := range e {
@@ -76,6 +82,7 @@ func (m *Manager) batch(ctx context.Context, e []scheduler.Executable, b schedul
 			Result:  Result(e.Result),
 		}
 	}
+	requests = coalesce(requests)
 	batch := b.Key.(batchKey)
 	err := m.execute(ctx, batch.device, batch.capture, batch.config, batch.generator, requests)
 	if err != nil {
@@ -90,41 +97,98 @@ func (m *Manager) execute(
 	deviceID, captureID id.ID,
 	cfg Config,
 	generator Generator,
-	requests []RequestAndResult) error {
+	requests []RequestAndResult) (err error) {
 
 	executeCounter.Increment()
 
-	devicePath := path.NewDevice(deviceID)
 	d := bind.GetRegistry(ctx).Device(deviceID)
 	if d == nil {
 		return log.Errf(ctx, nil, "Unknown device %v", deviceID)
 	}
 
-	capturePath := path.NewCapture(captureID)
-	c, err := capture.ResolveFromPath(ctx, capturePath)
+	ctx = bindReplay(ctx, d, captureID)
+	intent := Intent{path.NewDevice(deviceID), path.NewCapture(captureID)}
+	status := m.newStatusTracker(intent)
+	defer func() { status.done(err) }()
+
+	payload, decoder, replayABI, err := build(ctx, d, intent, cfg, generator, requests, status)
 	if err != nil {
-		return log.Err(ctx, err, "Failed to load capture")
+		return err
 	}
 
-	ctx = capture.Put(ctx, capturePath)
-	ctx = log.V{
+	connection, err := m.connections.connect(ctx, d, replayABI, captureID)
+	if err != nil {
+		return log.Err(ctx, err, "Failed to connect to device")
+	}
+	defer connection.Close()
+
+	if config.DebugReplay {
+		log.I(ctx, "Sending payload")
+	}
+
+	if Events.OnReplay != nil {
+		Events.OnReplay(d, intent, cfg)
+	}
+
+	resourceBytes := uint64(0)
+	for _, r := range payload.Resources {
+		resourceBytes += uint64(r.Size)
+	}
+	status.executing(resourceBytes)
+
+	t0 := executeTimer.Start()
+	err = executor.Execute(
+		ctx,
+		payload,
+		decoder,
+		connection,
+		replayABI.MemoryLayout,
+		m.gapir.Compress(ctx, d),
+		status,
+	)
+	executeTimer.Stop(t0)
+	return err
+}
+
+// bindReplay returns the context of a replay of the capture on the device d.
+func bindReplay(ctx context.Context, d bind.Device, captureID id.ID) context.Context {
+	ctx = capture.Put(ctx, path.NewCapture(captureID))
+	return log.V{
 		"capture": captureID,
 		"device":  d.Instance().GetName(),
 	}.Bind(ctx)
+}
 
-	intent := Intent{devicePath, capturePath}
+// build returns the payload generated for the requests replayed on the device
+// d, and the ABI it is built for. The progress of the build and of the
+// execution of the payload are reported to status.
+func build(
+	ctx context.Context,
+	d bind.Device,
+	intent Intent,
+	cfg Config,
+	generator Generator,
+	requests []RequestAndResult,
+	status *statusTracker) (protocol.Payload, builder.ResponseDecoder, *device.ABI, error) {
+
+	c, err := capture.ResolveFromPath(ctx, intent.Capture)
+	if err != nil {
+		return protocol.Payload{}, nil, nil, log.Err(ctx, err, "Failed to load capture")
+	}
 
 	atoms, err := c.Atoms(ctx)
 	if err != nil {
-		return log.Err(ctx, err, "Failed to load atom stream")
+		return protocol.Payload{}, nil, nil, log.Err(ctx, err, "Failed to load atom stream")
 	}
 
+	status.setAtoms(atoms)
+
 	cml := captureMemoryLayout(ctx, atoms)
 	ctx = log.V{"capture memory layout": cml}.Bind(ctx)
 
 	deviceABIs := d.Instance().GetConfiguration().GetABIs()
 	if len(deviceABIs) == 0 {
-		return log.Err(ctx, nil, "Replay device doesn't list any ABIs")
+		return protocol.Payload{}, nil, nil, log.Err(ctx, nil, "Replay device doesn't list any ABIs")
 	}
 
 	replayABI := findABI(cml, deviceABIs)
@@ -135,10 +199,12 @@ func (m *Manager) execute(
 	ctx = log.V{"replay target ABI": replayABI}.Bind(ctx)
 
 	builder := builder.New(replayABI.MemoryLayout)
+	builder.OnPostback = status.executed
 
 	out := &adapter{
 		state:   capture.NewState(ctx),
 		builder: builder,
+		status:  status,
 	}
 
 	t0 := generatorReplayTimer.Start()
@@ -150,7 +216,7 @@ func (m *Manager) execute(
 		d.Instance(),
 		c,
 		out); err != nil {
-		return log.Err(ctx, err, "Replay returned error")
+		return protocol.Payload{}, nil, nil, log.Err(ctx, err, "Replay returned error")
 	}
 	generatorReplayTimer.Stop(t0)
 
@@ -161,34 +227,10 @@ func (m *Manager) execute(
 	t0 = builderBuildTimer.Start()
 	payload, decoder, err := builder.Build(ctx)
 	if err != nil {
-		return log.Err(ctx, err, "Failed to build replay payload")
+		return protocol.Payload{}, nil, nil, log.Err(ctx, err, "Failed to build replay payload")
 	}
 	builderBuildTimer.Stop(t0)
-
-	connection, err := m.gapir.Connect(ctx, d, replayABI)
-	if err != nil {
-		return log.Err(ctx, err, "Failed to connect to device")
-	}
-	defer connection.Close()
-
-	if config.DebugReplay {
-		log.I(ctx, "Sending payload")
-	}
-
-	if Events.OnReplay != nil {
-		Events.OnReplay(d, intent, cfg)
-	}
-
-	t0 = executeTimer.Start()
-	err = executor.Execute(
-		ctx,
-		payload,
-		decoder,
-		connection,
-		replayABI.MemoryLayout,
-	)
-	executeTimer.Stop(t0)
-	return err
+	return payload, decoder, replayABI, nil
 }
 
 // adapter conforms to the the atom Writer interface, performing replay writes
@@ -196,6 +238,7 @@ func (m *Manager) execute(
 type adapter struct {
 	state   *gfxapi.State
 	builder *builder.Builder
+	status  *statusTracker
 }
 
 func (w *adapter) State() *gfxapi.State {
@@ -210,4 +253,5 @@ func (w *adapter) MutateAndWrite(ctx context.Context, i atom.ID, a atom.Atom) {
 		w.builder.RevertAtom(err)
 		log.W(ctx, "Failed to write atom %v (%T) for replay: %v", i, a, err)
 	}
+	w.status.built(i)
 }
@@ -73,8 +73,9 @@ type Builder struct {
 	decoders        []Postback
 	stack           []stackItem
 	memoryLayout    *device.MemoryLayout
-	inAtom          bool // true if between BeginAtom and CommitAtom/RevertAtom
-	atomStart       int  // index of current atom's first instruction
+	inAtom          bool   // true if between BeginAtom and CommitAtom/RevertAtom
+	atomStart       int    // index of current atom's first instruction
+	atomID          uint64 // identifier of the current atom
 
 	// Remappings is a map of a arbitrary keys to pointers. Typically, this is
 	// used as a map of observed values to values that are only known at replay
@@ -82,6 +83,11 @@ type Builder struct {
 	// The Remappings field is not accessed by the Builder and can be used in any
 	// way the developer requires.
 	Remappings map[interface{}]value.Pointer
+
+	// OnPostback, if not nil, is called with the identifier of the atom that
+	// issued each postback once its data has been received and decoded. It
+	// must be set before any call to Post.
+	OnPostback func(atomID uint64)
 }
 
 // New returns a newly constructed Builder configured to replay on a target
@@ -210,6 +216,7 @@ func (b *Builder) BeginAtom(id uint64) {
 	}
 	b.inAtom = true
 	b.atomStart = len(b.instructions)
+	b.atomID = id
 	if id <= 0x3ffffff { // Labels have 26 bit values.
 		b.instructions = append(b.instructions, asm.Label{
 			Value: uint32(id),
@@ -439,6 +446,16 @@ func (b *Builder) Post(addr value.Pointer, size uint64, p Postback) {
 		Source: b.remap(addr),
 		Size:   size,
 	})
+	if notify := b.OnPostback; notify != nil {
+		decode, atomID := p, b.atomID
+		p = func(d pod.Reader, err error) error {
+			err = decode(d, err)
+			if d != nil {
+				notify(atomID)
+			}
+			return err
+		}
+	}
 	b.decoders = append(b.decoders, p)
 }
 
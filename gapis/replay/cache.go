@@ -0,0 +1,146 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+
+	"github.com/google/gapid/core/app/benchmark"
+	"github.com/google/gapid/core/data/id"
+)
+
+// resultCacheSize is the maximum number of replay results held by a Manager.
+const resultCacheSize = 256
+
+var (
+	cacheHitCounter  = benchmark.GlobalCounters.Integer("replay.cache.hits")
+	cacheMissCounter = benchmark.GlobalCounters.Integer("replay.cache.misses")
+)
+
+// Uncached is the optional interface implemented by Requests whose results
+// differ between replays, such as GPU timings. Uncached requests are always
+// replayed.
+type Uncached interface {
+	Uncached()
+}
+
+// resultKey identifies the result of a single request. The batch key holds the
+// device, the capture and the config, which together with the request decide
+// the transforms applied to the replay.
+type resultKey struct {
+	batch   batchKey
+	request Request
+}
+
+type resultEntry struct {
+	key resultKey
+	val interface{}
+}
+
+// resultCache is a least-recently-used cache of successful replay results.
+type resultCache struct {
+	limit   int
+	entries map[resultKey]*list.Element
+	lru     *list.List // *resultEntry, most recent first.
+	mutex   sync.Mutex // guards entries and lru
+}
+
+func newResultCache(limit int) *resultCache {
+	return &resultCache{
+		limit:   limit,
+		entries: map[resultKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// cacheable returns true if the result of req with the batch key b can be
+// cached. Only keys that compare by value are cached, as keys holding pointers,
+// channels or interfaces would never be hit again.
+func cacheable(b batchKey, req Request) bool {
+	if _, uncached := req.(Uncached); uncached {
+		return false
+	}
+	return comparesByValue(reflect.TypeOf(b.config)) &&
+		comparesByValue(reflect.TypeOf(req))
+}
+
+func comparesByValue(t reflect.Type) bool {
+	if t == nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
+		reflect.Ptr, reflect.Slice, reflect.UnsafePointer:
+		return false
+	case reflect.Array:
+		return comparesByValue(t.Elem())
+	case reflect.Struct:
+		for i, c := 0, t.NumField(); i < c; i++ {
+			if !comparesByValue(t.Field(i).Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// get returns the cached result for k.
+func (c *resultCache) get(k resultKey) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		cacheMissCounter.Increment()
+		return nil, false
+	}
+	cacheHitCounter.Increment()
+	c.lru.MoveToFront(e)
+	return e.Value.(*resultEntry).val, true
+}
+
+// add stores the result val for k, evicting the least recently used results
+// if the cache is full.
+func (c *resultCache) add(k resultKey, val interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[k]; ok {
+		e.Value.(*resultEntry).val = val
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&resultEntry{k, val})
+	for c.lru.Len() > c.limit {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*resultEntry).key)
+	}
+}
+
+// forgetDevice removes all the results replayed on the device with the given
+// identifier.
+func (c *resultCache) forgetDevice(device id.ID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if k := e.Value.(*resultEntry).key; k.batch.device == device {
+			c.lru.Remove(e)
+			delete(c.entries, k)
+		}
+		e = next
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+)
+
+type valueRequest struct {
+	after  uint64
+	ids    [2]uint32
+	active bool
+}
+
+type channelRequest struct {
+	out chan int
+}
+
+type uncachedRequest struct{}
+
+func (uncachedRequest) Uncached() {}
+
+func TestCacheable(t *testing.T) {
+	ctx := assert.Context(t)
+	for _, test := range []struct {
+		name   string
+		config Config
+		req    Request
+		expect bool
+	}{
+		{"value", struct{}{}, valueRequest{}, true},
+		{"nil config", nil, valueRequest{}, true},
+		{"channel", struct{}{}, channelRequest{}, false},
+		{"pointer", struct{}{}, &valueRequest{}, false},
+		{"pointer config", &valueRequest{}, valueRequest{}, false},
+		{"uncached", struct{}{}, uncachedRequest{}, false},
+	} {
+		got := cacheable(batchKey{config: test.config}, test.req)
+		assert.For(ctx, test.name).That(got).Equals(test.expect)
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	ctx := assert.Context(t)
+	c := newResultCache(2)
+	devA, devB := id.OfString("a"), id.OfString("b")
+	key := func(device id.ID, after uint64) resultKey {
+		return resultKey{batchKey{device: device}, valueRequest{after: after}}
+	}
+
+	c.add(key(devA, 1), "a1")
+	c.add(key(devA, 2), "a2")
+	val, ok := c.get(key(devA, 1))
+	assert.For(ctx, "hit").That(ok).Equals(true)
+	assert.For(ctx, "val").That(val).Equals("a1")
+
+	// a2 is the least recently used result.
+	c.add(key(devB, 1), "b1")
+	_, ok = c.get(key(devA, 2))
+	assert.For(ctx, "evicted").That(ok).Equals(false)
+	_, ok = c.get(key(devA, 1))
+	assert.For(ctx, "kept").That(ok).Equals(true)
+
+	c.forgetDevice(devA)
+	_, ok = c.get(key(devA, 1))
+	assert.For(ctx, "forgotten").That(ok).Equals(false)
+	val, ok = c.get(key(devB, 1))
+	assert.For(ctx, "other device").That(ok).Equals(true)
+	assert.For(ctx, "other val").That(val).Equals("b1")
+}
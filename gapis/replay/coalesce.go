@@ -0,0 +1,153 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/gapid/core/app/benchmark"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/gapis/replay/scheduler"
+	"github.com/google/gapid/gapis/service"
+)
+
+var (
+	coalescedCounter = benchmark.GlobalCounters.Integer("replay.coalesced.requests")
+	duplicateCounter = benchmark.GlobalCounters.Integer("replay.coalesced.duplicates")
+)
+
+// pendingBatch is a batch that has been scheduled but not yet executed.
+// Requests with the same batch key join the pending batch, whatever their
+// hints, so they are all served by a single replay pass.
+type pendingBatch struct {
+	batch  scheduler.Batch
+	once   sync.Once
+	signal chan struct{}
+}
+
+// release fires the precondition of the batch, letting the scheduler execute
+// it.
+func (p *pendingBatch) release() {
+	p.once.Do(func() { close(p.signal) })
+}
+
+// joinBatch returns the scheduler batch for a request with the key k and the
+// given hints. If a batch with the same key is still waiting to be executed,
+// the request joins it, and a primary request releases it immediately.
+func (m *Manager) joinBatch(k batchKey, hints *service.UsageHints) scheduler.Batch {
+	primary := hints != nil && hints.Primary
+
+	m.pendingMutex.Lock()
+	defer m.pendingMutex.Unlock()
+
+	if p, ok := m.pending[k]; ok {
+		coalescedCounter.Increment()
+		if primary {
+			p.release()
+		}
+		return p.batch
+	}
+
+	priority, delay := defaultPriority, defaultBatchDelay
+	if hints != nil {
+		if hints.Preview {
+			priority = lowPriority
+		}
+		if primary {
+			priority, delay = highPriorty, 0
+		}
+	}
+
+	signal := make(chan struct{})
+	p := &pendingBatch{
+		batch: scheduler.Batch{
+			Key:          k,
+			Priority:     priority,
+			Precondition: signal,
+		},
+		signal: signal,
+	}
+	m.pending[k] = p
+	if delay == 0 {
+		p.release()
+	} else {
+		time.AfterFunc(delay, p.release)
+	}
+	return p.batch
+}
+
+// leaveBatch is called when the batch b starts executing. Requests made from
+// now on with the same key are scheduled in a new batch.
+func (m *Manager) leaveBatch(b scheduler.Batch) {
+	m.pendingMutex.Lock()
+	defer m.pendingMutex.Unlock()
+	k := b.Key.(batchKey)
+	if p, ok := m.pending[k]; ok && p.batch == b {
+		delete(m.pending, k)
+	}
+}
+
+// forgetPending drops the pending batches for the device with the given
+// identifier.
+func (m *Manager) forgetPending(device id.ID) {
+	m.pendingMutex.Lock()
+	defer m.pendingMutex.Unlock()
+	for k, p := range m.pending {
+		if k.device == device {
+			p.release()
+			delete(m.pending, k)
+		}
+	}
+}
+
+// coalesce merges the requests of rrs that are equal into a single request,
+// whose result is broadcast to all of the merged requests. Requests that cannot
+// be compared by value are left as they are.
+func coalesce(rrs []RequestAndResult) []RequestAndResult {
+	out := make([]RequestAndResult, 0, len(rrs))
+	merged := map[Request]int{}
+	results := map[int][]Result{}
+	for _, rr := range rrs {
+		if !comparesByValue(reflect.TypeOf(rr.Request)) {
+			out = append(out, rr)
+			continue
+		}
+		if i, ok := merged[rr.Request]; ok {
+			duplicateCounter.Increment()
+			results[i] = append(results[i], rr.Result)
+			continue
+		}
+		i := len(out)
+		merged[rr.Request] = i
+		results[i] = []Result{rr.Result}
+		out = append(out, rr)
+	}
+	for i, l := range results {
+		if len(l) > 1 {
+			out[i].Result = broadcast(l)
+		}
+	}
+	return out
+}
+
+func broadcast(l []Result) Result {
+	return func(val interface{}, err error) {
+		for _, r := range l {
+			r(val, err)
+		}
+	}
+}
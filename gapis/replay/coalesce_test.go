@@ -0,0 +1,72 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestCoalesce(t *testing.T) {
+	ctx := assert.Context(t)
+	got := map[int]interface{}{}
+	result := func(i int) Result {
+		return func(val interface{}, err error) { got[i] = val }
+	}
+	out := make(chan int)
+	rrs := []RequestAndResult{
+		{valueRequest{after: 1}, result(0)},
+		{valueRequest{after: 2}, result(1)},
+		{valueRequest{after: 1}, result(2)},
+		{channelRequest{out}, result(3)},
+		{channelRequest{out}, result(4)},
+	}
+	merged := coalesce(rrs)
+	assert.For(ctx, "requests").That(len(merged)).Equals(4)
+	for i, rr := range merged {
+		rr.Result(i, nil)
+	}
+	assert.For(ctx, "results").That(got).DeepEquals(map[int]interface{}{
+		0: 0, 1: 1, 2: 0, 3: 2, 4: 3,
+	})
+}
+
+func TestJoinBatch(t *testing.T) {
+	ctx := assert.Context(t)
+	m := &Manager{pending: map[batchKey]*pendingBatch{}}
+	a := batchKey{device: id.OfString("a")}
+	b := batchKey{device: id.OfString("b")}
+
+	first := m.joinBatch(a, &service.UsageHints{Preview: true})
+	second := m.joinBatch(a, nil)
+	other := m.joinBatch(b, nil)
+	assert.For(ctx, "joined").That(second == first).Equals(true)
+	assert.For(ctx, "other key").That(other == first).Equals(false)
+
+	// A primary request releases the pending batch without waiting.
+	m.joinBatch(a, &service.UsageHints{Primary: true})
+	select {
+	case <-first.Precondition.(chan struct{}):
+	default:
+		assert.For(ctx, "released").Error("Batch was not released")
+	}
+
+	m.leaveBatch(first)
+	third := m.joinBatch(a, nil)
+	assert.For(ctx, "after execution").That(third == first).Equals(false)
+}
@@ -0,0 +1,497 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/replay/opcode"
+	"github.com/google/gapid/gapis/replay/protocol"
+)
+
+// maxStatements is the number of statements after which the generated code is
+// split into a new function, to keep the functions small enough for the C++
+// compilers.
+const maxStatements = 2000
+
+// Function describes a function called by the opcodes of a payload.
+type Function struct {
+	Name       string        // The name the function is looked up with.
+	Parameters int           // The number of parameters of the function.
+	ReturnType protocol.Type // The return type of the function.
+}
+
+// Program describes the C++ program generated from a replay payload.
+type Program struct {
+	// Payload is the payload performed by the program.
+	Payload protocol.Payload
+	// ByteOrder is the byte order the payload is encoded with.
+	ByteOrder device.Endian
+	// Resources holds the data of each of the payload resources.
+	Resources [][]byte
+	// Function returns the function with the identifier id of the API with the
+	// given index.
+	Function func(api uint8, id uint16) (Function, bool)
+	// Prelude is the source placed before the generated code. It must declare
+	// gapid_proc, returning the address of the function with the given name,
+	// and may define GAPID_API, the calling convention of the functions.
+	Prelude string
+	// Loop is the label of the first opcode of the frame repeated by the
+	// program. If no opcode has this label, all the opcodes are performed
+	// once.
+	Loop uint32
+}
+
+// Generate writes the C++ source of the program p to w. The first argument of
+// the program is the number of times the frame is performed.
+func Generate(p Program, w io.Writer) error {
+	opcodes, err := opcode.Disassemble(bytes.NewReader(p.Payload.Opcodes), p.ByteOrder)
+	if err != nil {
+		return err
+	}
+	if len(p.Resources) != len(p.Payload.Resources) {
+		return fmt.Errorf("Got data for %d resources, expected %d", len(p.Resources), len(p.Payload.Resources))
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "// Generated from a replay payload.")
+	fmt.Fprintln(out, p.Prelude)
+	fmt.Fprint(out, header)
+	writeBytes(out, "gapid_constants", p.Payload.Constants)
+	for i, data := range p.Resources {
+		writeBytes(out, fmt.Sprintf("gapid_resource_%d", i), data)
+	}
+
+	g := &generator{program: &p, out: out}
+	for _, op := range opcodes {
+		if err := g.opcode(op); err != nil {
+			return err
+		}
+	}
+	g.flush()
+
+	fmt.Fprintln(out, "int main(int argc, char** argv) {")
+	fmt.Fprintln(out, "  int frames = argc > 1 ? atoi(argv[1]) : 1;")
+	fmt.Fprintf(out, "  gapid_volatile = static_cast<uint8_t*>(calloc(%d, 1));\n", p.Payload.VolatileMemorySize+1)
+	for _, f := range g.setup {
+		fmt.Fprintf(out, "  %s();\n", f)
+	}
+	if len(g.loop) > 0 {
+		fmt.Fprintln(out, "  for (int i = 1; i < frames; i++) {")
+		for _, f := range g.loop {
+			fmt.Fprintf(out, "    %s();\n", f)
+		}
+		fmt.Fprintln(out, "  }")
+	}
+	fmt.Fprintln(out, "  return 0;")
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// header declares the memory of the payload and the helpers used by the
+// generated code.
+const header = `
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+#ifndef GAPID_API
+#define GAPID_API
+#endif
+
+static uint8_t* gapid_volatile;
+
+template <typename T>
+static inline T gapid_load(const uint8_t* address) {
+  T value;
+  memcpy(&value, address, sizeof(T));
+  return value;
+}
+
+template <typename T>
+static inline void gapid_store(uint8_t* address, T value) {
+  memcpy(address, &value, sizeof(T));
+}
+
+static inline float gapid_float(uint32_t bits) {
+  float value;
+  memcpy(&value, &bits, sizeof(value));
+  return value;
+}
+
+static inline double gapid_double(uint64_t bits) {
+  double value;
+  memcpy(&value, &bits, sizeof(value));
+  return value;
+}
+
+static inline void gapid_strcpy(uint8_t* target, const uint8_t* source, uint32_t count) {
+  uint32_t i = 0;
+  for (; i + 1 < count && source[i] != 0; i++) {
+    target[i] = source[i];
+  }
+  for (; i < count; i++) {
+    target[i] = 0;
+  }
+}
+
+`
+
+// writeBytes writes the definition of the aligned array name holding data.
+func writeBytes(out *bufio.Writer, name string, data []byte) {
+	if len(data) == 0 {
+		// C++ does not allow empty arrays.
+		data = []byte{0}
+	}
+	fmt.Fprintf(out, "alignas(16) static uint8_t %s[%d] = {", name, len(data))
+	for i, b := range data {
+		if i%16 == 0 {
+			fmt.Fprint(out, "\n ")
+		}
+		fmt.Fprintf(out, " 0x%02x,", b)
+	}
+	fmt.Fprint(out, "\n};\n\n")
+}
+
+// value is a value of the virtual machine stack.
+type value struct {
+	ty       protocol.Type
+	constant bool
+	bits     uint64 // The bits of the value, if constant.
+	expr     string // The C++ expression of the value, if not constant.
+}
+
+// generator translates the opcodes into the statements of C++ functions.
+type generator struct {
+	program    *Program
+	out        *bufio.Writer
+	stack      []value
+	vars       int
+	statements []string
+	setup      []string // The functions performing the opcodes once.
+	loop       []string // The functions performing the frame.
+	looping    bool
+}
+
+func (g *generator) push(v value) {
+	g.stack = append(g.stack, v)
+}
+
+func (g *generator) pop() (value, error) {
+	if len(g.stack) == 0 {
+		return value{}, fmt.Errorf("Pop from an empty stack")
+	}
+	v := g.stack[len(g.stack)-1]
+	g.stack = g.stack[:len(g.stack)-1]
+	return v, nil
+}
+
+// emit adds the statement s to the current function.
+func (g *generator) emit(format string, args ...interface{}) {
+	g.statements = append(g.statements, fmt.Sprintf(format, args...))
+}
+
+// define emits the definition of a variable of type ty initialized with expr,
+// and pushes it to the stack.
+func (g *generator) define(ty protocol.Type, expr string) {
+	name := fmt.Sprintf("v%d", g.vars)
+	g.vars++
+	g.emit("%s const %s = %s;", typeName(ty), name, expr)
+	g.push(value{ty: ty, expr: name})
+}
+
+// flush writes the current function, if it holds any statement.
+func (g *generator) flush() {
+	if len(g.statements) == 0 {
+		return
+	}
+	var name string
+	if g.looping {
+		name = fmt.Sprintf("frame_%d", len(g.loop))
+		g.loop = append(g.loop, name)
+	} else {
+		name = fmt.Sprintf("setup_%d", len(g.setup))
+		g.setup = append(g.setup, name)
+	}
+	fmt.Fprintf(g.out, "static void %s() {\n", name)
+	for _, s := range g.statements {
+		fmt.Fprintf(g.out, "  %s\n", s)
+	}
+	fmt.Fprint(g.out, "}\n\n")
+	g.statements = g.statements[:0]
+}
+
+func (g *generator) opcode(op interface{}) error {
+	switch op := op.(type) {
+	case opcode.Label:
+		if len(g.stack) == 0 {
+			if op.Value == g.program.Loop && !g.looping {
+				g.flush()
+				g.looping = true
+				return nil
+			}
+			if len(g.statements) >= maxStatements {
+				g.flush()
+			}
+		}
+		g.emit("// label %d", op.Value)
+
+	case opcode.PushI:
+		bits := uint64(op.Value)
+		switch op.DataType {
+		case protocol.Type_Int32, protocol.Type_Int64:
+			if bits&0x80000 != 0 {
+				bits |= 0xfffffffffff00000
+			}
+		case protocol.Type_Float:
+			bits <<= 23
+		case protocol.Type_Double:
+			bits <<= 52
+		}
+		g.push(value{ty: op.DataType, constant: true, bits: bits})
+
+	case opcode.Extend:
+		v, err := g.pop()
+		if err != nil {
+			return err
+		}
+		if !v.constant {
+			return fmt.Errorf("Extend of a value that is not constant")
+		}
+		data := uint64(op.Value)
+		switch v.ty {
+		case protocol.Type_Float:
+			v.bits |= data & 0x007fffff
+		case protocol.Type_Double:
+			exponent := v.bits & 0xfff0000000000000
+			v.bits = (v.bits<<26|data)&0x000fffffffffffff | exponent
+		default:
+			v.bits = v.bits<<26 | data
+		}
+		g.push(v)
+
+	case opcode.LoadC:
+		g.define(op.DataType, fmt.Sprintf("gapid_load<%s>(gapid_constants + 0x%x)", typeName(op.DataType), op.Address))
+
+	case opcode.LoadV:
+		g.define(op.DataType, fmt.Sprintf("gapid_load<%s>(gapid_volatile + 0x%x)", typeName(op.DataType), op.Address))
+
+	case opcode.Load:
+		address, err := g.pop()
+		if err != nil {
+			return err
+		}
+		g.define(op.DataType, fmt.Sprintf("gapid_load<%s>(%s)", typeName(op.DataType), address.cpp()))
+
+	case opcode.Pop:
+		for i := uint32(0); i < op.Count; i++ {
+			if _, err := g.pop(); err != nil {
+				return err
+			}
+		}
+
+	case opcode.StoreV:
+		v, err := g.pop()
+		if err != nil {
+			return err
+		}
+		g.emit("gapid_store(gapid_volatile + 0x%x, %s);", op.Address, v.cpp())
+
+	case opcode.Store:
+		address, err := g.pop()
+		if err != nil {
+			return err
+		}
+		v, err := g.pop()
+		if err != nil {
+			return err
+		}
+		g.emit("gapid_store(%s, %s);", address.cpp(), v.cpp())
+
+	case opcode.Resource:
+		target, err := g.pop()
+		if err != nil {
+			return err
+		}
+		if int(op.ID) >= len(g.program.Resources) {
+			return fmt.Errorf("Resource %d out of range", op.ID)
+		}
+		name := fmt.Sprintf("gapid_resource_%d", op.ID)
+		g.emit("memcpy(%s, %s, sizeof(%s));", target.cpp(), name, name)
+
+	case opcode.Post:
+		// The program has no server to post the data back to.
+		for i := 0; i < 2; i++ {
+			if _, err := g.pop(); err != nil {
+				return err
+			}
+		}
+
+	case opcode.Copy:
+		target, err := g.pop()
+		if err != nil {
+			return err
+		}
+		source, err := g.pop()
+		if err != nil {
+			return err
+		}
+		g.emit("memcpy(%s, %s, %d);", target.cpp(), source.cpp(), op.Count)
+
+	case opcode.Clone:
+		if int(op.Index) >= len(g.stack) {
+			return fmt.Errorf("Clone of index %d out of the stack", op.Index)
+		}
+		g.push(g.stack[len(g.stack)-1-int(op.Index)])
+
+	case opcode.Strcpy:
+		target, err := g.pop()
+		if err != nil {
+			return err
+		}
+		source, err := g.pop()
+		if err != nil {
+			return err
+		}
+		g.emit("gapid_strcpy(%s, %s, %d);", target.cpp(), source.cpp(), op.MaxSize)
+
+	case opcode.Add:
+		if op.Count < 2 {
+			return nil
+		}
+		terms := make([]string, op.Count)
+		ty := protocol.Type_Void
+		for i := range terms {
+			v, err := g.pop()
+			if err != nil {
+				return err
+			}
+			ty = v.ty
+			if isPointer(ty) {
+				terms[i] = fmt.Sprintf("reinterpret_cast<uintptr_t>(%s)", v.cpp())
+			} else {
+				terms[i] = v.cpp()
+			}
+		}
+		sum := strings.Join(terms, " + ")
+		if isPointer(ty) {
+			g.define(ty, fmt.Sprintf("reinterpret_cast<uint8_t*>(%s)", sum))
+		} else {
+			g.define(ty, fmt.Sprintf("%s(%s)", typeName(ty), sum))
+		}
+
+	case opcode.Call:
+		f, ok := g.program.Function(op.ApiIndex, op.FunctionID)
+		if !ok {
+			return fmt.Errorf("Unknown function %d of API %d", op.FunctionID, op.ApiIndex)
+		}
+		if f.Parameters > len(g.stack) {
+			return fmt.Errorf("Call of %s with %d parameters on a stack of %d values", f.Name, f.Parameters, len(g.stack))
+		}
+		args := g.stack[len(g.stack)-f.Parameters:]
+		g.stack = g.stack[:len(g.stack)-f.Parameters]
+		types, values := make([]string, len(args)), make([]string, len(args))
+		for i, a := range args {
+			types[i], values[i] = typeName(a.ty), a.cpp()
+		}
+		call := fmt.Sprintf("reinterpret_cast<%s (GAPID_API*)(%s)>(gapid_proc(\"%s\"))(%s)",
+			typeName(f.ReturnType), strings.Join(types, ", "), f.Name, strings.Join(values, ", "))
+		if op.PushReturn && f.ReturnType != protocol.Type_Void {
+			g.define(f.ReturnType, call)
+		} else {
+			g.emit("%s;", call)
+		}
+
+	default:
+		return fmt.Errorf("Unsupported opcode %T", op)
+	}
+	return nil
+}
+
+// cpp returns the C++ expression of the value.
+func (v value) cpp() string {
+	if !v.constant {
+		return v.expr
+	}
+	switch v.ty {
+	case protocol.Type_Bool:
+		if v.bits != 0 {
+			return "true"
+		}
+		return "false"
+	case protocol.Type_Float:
+		return fmt.Sprintf("gapid_float(0x%xu)", uint32(v.bits))
+	case protocol.Type_Double:
+		return fmt.Sprintf("gapid_double(0x%xull)", v.bits)
+	case protocol.Type_AbsolutePointer:
+		return fmt.Sprintf("reinterpret_cast<uint8_t*>(uintptr_t(0x%xull))", v.bits)
+	case protocol.Type_ConstantPointer:
+		return fmt.Sprintf("(gapid_constants + 0x%x)", v.bits)
+	case protocol.Type_VolatilePointer:
+		return fmt.Sprintf("(gapid_volatile + 0x%x)", v.bits)
+	default:
+		bits := v.bits
+		if size := v.ty.Size(8); size < 8 {
+			bits &= 1<<uint(size*8) - 1
+		}
+		return fmt.Sprintf("%s(0x%xull)", typeName(v.ty), bits)
+	}
+}
+
+func isPointer(ty protocol.Type) bool {
+	switch ty {
+	case protocol.Type_AbsolutePointer, protocol.Type_ConstantPointer, protocol.Type_VolatilePointer:
+		return true
+	default:
+		return false
+	}
+}
+
+// typeName returns the C++ type of the values of type ty.
+func typeName(ty protocol.Type) string {
+	switch ty {
+	case protocol.Type_Bool:
+		return "bool"
+	case protocol.Type_Int8:
+		return "int8_t"
+	case protocol.Type_Int16:
+		return "int16_t"
+	case protocol.Type_Int32:
+		return "int32_t"
+	case protocol.Type_Int64:
+		return "int64_t"
+	case protocol.Type_Uint8:
+		return "uint8_t"
+	case protocol.Type_Uint16:
+		return "uint16_t"
+	case protocol.Type_Uint32:
+		return "uint32_t"
+	case protocol.Type_Uint64:
+		return "uint64_t"
+	case protocol.Type_Float:
+		return "float"
+	case protocol.Type_Double:
+		return "double"
+	case protocol.Type_AbsolutePointer, protocol.Type_ConstantPointer, protocol.Type_VolatilePointer:
+		return "uint8_t*"
+	default:
+		return "void"
+	}
+}
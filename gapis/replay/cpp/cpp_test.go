@@ -0,0 +1,105 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/replay/opcode"
+	"github.com/google/gapid/gapis/replay/protocol"
+)
+
+type encoder interface {
+	Encode(w pod.Writer) error
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := log.Testing(t)
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	for _, op := range []encoder{
+		opcode.Label{Value: 1},
+		opcode.PushI{DataType: protocol.Type_Uint32, Value: 5},
+		opcode.PushI{DataType: protocol.Type_VolatilePointer, Value: 0x10},
+		opcode.Call{PushReturn: true, ApiIndex: 1, FunctionID: 3},
+		opcode.StoreV{Address: 0x20},
+		opcode.Label{Value: 2},
+		opcode.PushI{DataType: protocol.Type_Float, Value: 0x7f},
+		opcode.StoreV{Address: 0x24},
+		opcode.PushI{DataType: protocol.Type_Uint64, Value: 1},
+		opcode.Extend{Value: 2},
+		opcode.StoreV{Address: 0x28},
+		opcode.PushI{DataType: protocol.Type_ConstantPointer, Value: 0},
+		opcode.Resource{ID: 0},
+	} {
+		assert.For(ctx, "Encode").ThatError(op.Encode(w)).Succeeded()
+	}
+
+	p := Program{
+		Payload: protocol.Payload{
+			VolatileMemorySize: 0x30,
+			Constants:          []byte{1, 2},
+			Resources:          []protocol.ResourceInfo{{ID: "res", Size: 3}},
+			Opcodes:            buf.Bytes(),
+		},
+		ByteOrder: device.LittleEndian,
+		Resources: [][]byte{{4, 5, 6}},
+		Function: func(api uint8, id uint16) (Function, bool) {
+			if api == 1 && id == 3 {
+				return Function{Name: "f", Parameters: 2, ReturnType: protocol.Type_Uint32}, true
+			}
+			return Function{}, false
+		},
+		Loop: 2,
+	}
+	out := &bytes.Buffer{}
+	assert.For(ctx, "Generate").ThatError(Generate(p, out)).Succeeded()
+	src := out.String()
+	for _, expected := range []string{
+		"alignas(16) static uint8_t gapid_constants[2] = {\n  0x01, 0x02,\n};",
+		"alignas(16) static uint8_t gapid_resource_0[3] = {\n  0x04, 0x05, 0x06,\n};",
+		"static void setup_0() {\n  // label 1\n" +
+			"  uint32_t const v0 = reinterpret_cast<uint32_t (GAPID_API*)(uint32_t, uint8_t*)>(gapid_proc(\"f\"))(uint32_t(0x5ull), (gapid_volatile + 0x10));\n" +
+			"  gapid_store(gapid_volatile + 0x20, v0);\n}",
+		"static void frame_0() {\n" +
+			"  gapid_store(gapid_volatile + 0x24, gapid_float(0x3f800000u));\n" +
+			"  gapid_store(gapid_volatile + 0x28, uint64_t(0x4000002ull));\n" +
+			"  memcpy((gapid_constants + 0x0), gapid_resource_0, sizeof(gapid_resource_0));\n}",
+		"  setup_0();\n  for (int i = 1; i < frames; i++) {\n    frame_0();\n  }",
+	} {
+		assert.For(ctx, "src").ThatString(src).Contains(expected)
+	}
+}
+
+func TestGenerateUnknownFunction(t *testing.T) {
+	ctx := log.Testing(t)
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	opcode.Call{ApiIndex: 1, FunctionID: 4}.Encode(w)
+
+	p := Program{
+		Payload:   protocol.Payload{Opcodes: buf.Bytes()},
+		ByteOrder: device.LittleEndian,
+		Function:  func(uint8, uint16) (Function, bool) { return Function{}, false },
+	}
+	err := Generate(p, &bytes.Buffer{})
+	assert.For(ctx, "err").ThatError(err).HasMessage("Unknown function 4 of API 1")
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/gapid/core/data/endian"
 	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/data/pod"
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/os/device"
 	"github.com/google/gapid/gapis/database"
@@ -35,6 +36,18 @@ type executor struct {
 	decoder      builder.ResponseDecoder
 	connection   io.ReadWriteCloser
 	memoryLayout *device.MemoryLayout
+	compress     bool
+	progress     Progress
+}
+
+// Progress is notified of the resources transferred to the replay device.
+type Progress interface {
+	// ResourcesCached is called with the number of bytes of resources the
+	// device already holds, and so will not be sent.
+	ResourcesCached(bytes uint64)
+	// ResourcesSent is called each time resources are sent to the device,
+	// with the number of bytes sent.
+	ResourcesSent(bytes uint64)
 }
 
 // Execute sends the replay payload for execution on the target replay device
@@ -42,18 +55,25 @@ type executor struct {
 // decoder will be used for decoding all postback reponses. Once a postback
 // response is decoded, the corresponding handler in the handlers map will be
 // called.
+// If compress is true the resources are sent compressed, if the device
+// supports it.
+// progress, if not nil, is notified of the resources transferred to the device.
 func Execute(
 	ctx context.Context,
 	payload protocol.Payload,
 	decoder builder.ResponseDecoder,
 	connection io.ReadWriteCloser,
-	memoryLayout *device.MemoryLayout) error {
+	memoryLayout *device.MemoryLayout,
+	compress bool,
+	progress Progress) error {
 
 	return executor{
 		payload:      payload,
 		decoder:      decoder,
 		connection:   connection,
 		memoryLayout: memoryLayout,
+		compress:     compress,
+		progress:     progress,
 	}.execute(ctx)
 }
 
@@ -109,6 +129,25 @@ func (r executor) handleReplayCommunication(ctx context.Context, replayID id.ID,
 	e := endian.Writer(connection, r.memoryLayout.GetEndian())
 	d := endian.Reader(connection, r.memoryLayout.GetEndian())
 
+	if err := r.queryCachedResources(ctx, e, d); err != nil {
+		return err
+	}
+
+	compressed := false
+	if r.compress {
+		e.Uint8(uint8(protocol.ConnectionType_Compression))
+		e.Uint8(uint8(protocol.Codec_LZ4))
+		if e.Error() != nil {
+			return e.Error()
+		}
+		if compressed = d.Uint8() != 0; d.Error() != nil {
+			return log.Err(ctx, d.Error(), "Failed to read the compression reply")
+		}
+		if !compressed {
+			log.W(ctx, "Device does not support compression, sending resources uncompressed")
+		}
+	}
+
 	e.Uint8(uint8(protocol.ConnectionType_Replay))
 	e.String(replayID.String())
 	e.Uint32(replaySize)
@@ -127,7 +166,7 @@ func (r executor) handleReplayCommunication(ctx context.Context, replayID id.ID,
 
 		switch protocol.MessageType(msg) {
 		case protocol.MessageType_Get:
-			if err := r.handleGetData(ctx); err != nil {
+			if err := r.handleGetData(ctx, compressed); err != nil {
 				return fmt.Errorf("Failed to read replay postback data: %v", err)
 			}
 		case protocol.MessageType_Post:
@@ -140,6 +179,34 @@ func (r executor) handleReplayCommunication(ctx context.Context, replayID id.ID,
 	}
 }
 
+// queryCachedResources asks the device which of the payload resources it
+// already holds from previous replays. The device only requests the resources
+// it is missing, so the answer is only used for reporting progress.
+func (r executor) queryCachedResources(ctx context.Context, e pod.Writer, d pod.Reader) error {
+	e.Uint8(uint8(protocol.ConnectionType_CachedResources))
+	e.Uint32(uint32(len(r.payload.Resources)))
+	for _, info := range r.payload.Resources {
+		e.String(info.ID)
+	}
+	if e.Error() != nil {
+		return e.Error()
+	}
+
+	cached := uint64(0)
+	for _, info := range r.payload.Resources {
+		if d.Uint8() != 0 {
+			cached += uint64(info.Size)
+		}
+	}
+	if err := d.Error(); err != nil {
+		return log.Err(ctx, err, "Failed to read the cached resources")
+	}
+	if r.progress != nil {
+		r.progress.ResourcesCached(cached)
+	}
+	return nil
+}
+
 func (r executor) handleDataResponse(ctx context.Context, postbacks io.Writer) error {
 	d := endian.Reader(r.connection, r.memoryLayout.GetEndian())
 
@@ -156,7 +223,7 @@ func (r executor) handleDataResponse(ctx context.Context, postbacks io.Writer) e
 	return nil
 }
 
-func (r executor) handleGetData(ctx context.Context) error {
+func (r executor) handleGetData(ctx context.Context, compressed bool) error {
 	ctx = log.Enter(ctx, "handleGetData")
 	d := endian.Reader(r.connection, r.memoryLayout.GetEndian())
 
@@ -183,6 +250,13 @@ func (r executor) handleGetData(ctx context.Context) error {
 		}
 	}
 
+	var out io.Writer = r.connection
+	var cw io.WriteCloser
+	if compressed {
+		cw = protocol.NewCompressedWriter(endian.Writer(r.connection, r.memoryLayout.GetEndian()))
+		out = cw
+	}
+
 	totalReturnedSize := uint64(0)
 	for _, rid := range resourceIDs {
 		obj, err := database.Resolve(ctx, rid)
@@ -191,17 +265,25 @@ func (r executor) handleGetData(ctx context.Context) error {
 		}
 
 		data := obj.([]byte)
-		n, err := r.connection.Write(data)
+		n, err := out.Write(data)
 		if err != nil {
 			return log.Errf(ctx, err, "Failed to send resource with id: %v", rid)
 		}
 
 		totalReturnedSize += uint64(n)
 	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			return log.Err(ctx, err, "Failed to send the last compressed chunk")
+		}
+	}
 
 	if totalExpectedSize != totalReturnedSize {
 		return log.Errf(ctx, nil, "Total resources size mismatch. expected: %v, got: %v",
 			totalExpectedSize, totalReturnedSize)
 	}
+	if r.progress != nil {
+		r.progress.ResourcesSent(totalReturnedSize)
+	}
 	return nil
 }
@@ -16,6 +16,7 @@ package replay
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/gapid/core/image"
 	"github.com/google/gapid/core/os/device"
@@ -61,9 +62,150 @@ type QueryFramebufferAttachment interface {
 		hints *service.UsageHints) (*image.Image2D, error)
 }
 
+// QueryHighlightedDraw is the interface implemented by types that can return
+// the content of a color attachment at a particular point in a capture, with
+// a single draw call in full color and all the other draw calls dimmed.
+type QueryHighlightedDraw interface {
+	QueryHighlightedDraw(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		after atom.ID,
+		draw atom.ID,
+		width, height uint32,
+		attachment gfxapi.FramebufferAttachment,
+		hints *service.UsageHints) (*image.Image2D, error)
+}
+
+// QuerySkippedCommands is the interface implemented by types that can return
+// the content of a framebuffer attachment at a particular point in a capture,
+// with the rendering of the commands within the ranges skip disabled.
+type QuerySkippedCommands interface {
+	QuerySkippedCommands(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		after atom.ID,
+		skip atom.RangeList,
+		width, height uint32,
+		attachment gfxapi.FramebufferAttachment,
+		hints *service.UsageHints) (*image.Image2D, error)
+}
+
+// TexturePattern returns the RGBA_U8_NORM data of the given size replacing
+// the data of an overridden texture.
+type TexturePattern func(width, height int) ([]byte, error)
+
+// QueryTextureOverride is the interface implemented by types that can return
+// the content of a framebuffer attachment at a particular point in a capture,
+// with the data of the texture resource replaced by pattern.
+type QueryTextureOverride interface {
+	QueryTextureOverride(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		after atom.ID,
+		texture gfxapi.Resource,
+		pattern TexturePattern,
+		width, height uint32,
+		attachment gfxapi.FramebufferAttachment,
+		hints *service.UsageHints) (*image.Image2D, error)
+}
+
+// StateOverride is the fixed-function state forced on draw calls by
+// QueryStateOverride.
+type StateOverride struct {
+	Draw             atom.ID // The only draw call to override, or atom.NoID for all of them.
+	DisableDepthTest bool
+	CullMode         service.CullModeOverride
+	ForceCCW         bool
+	DisableBlending  bool
+}
+
+// QueryStateOverride is the interface implemented by types that can return
+// the content of a framebuffer attachment at a particular point in a capture,
+// with the fixed-function state of the draw calls overridden.
+type QueryStateOverride interface {
+	QueryStateOverride(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		after atom.ID,
+		override StateOverride,
+		width, height uint32,
+		attachment gfxapi.FramebufferAttachment,
+		hints *service.UsageHints) (*image.Image2D, error)
+}
+
+// QueryDispatchSnapshot is the interface implemented by types that can return
+// the content of the storage resources bound to a compute dispatch, before and
+// after the dispatch executed.
+type QueryDispatchSnapshot interface {
+	QueryDispatchSnapshot(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		dispatch atom.ID,
+		hints *service.UsageHints) (*service.DispatchSnapshot, error)
+}
+
+// QueryTimings is the interface implemented by types that can measure the GPU
+// execution time of the draw and dispatch commands during a replay.
+type QueryTimings interface {
+	QueryTimings(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		hints *service.UsageHints) ([]Timing, error)
+}
+
+// QueryPipelineStatistics is the interface implemented by types that can
+// collect the pipeline statistics of the draw and dispatch commands during a
+// replay.
+type QueryPipelineStatistics interface {
+	QueryPipelineStatistics(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		hints *service.UsageHints) (*service.PipelineStatistics, error)
+}
+
+// QueryFrameLoop is the interface implemented by types that can replay a
+// frame several times in a loop, measuring the GPU time of each iteration.
+// The named experiments are applied to the commands of the capture, each
+// with the parameters it accepts.
+type QueryFrameLoop interface {
+	QueryFrameLoop(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		end atom.ID,
+		iterations uint32,
+		experiments []string,
+		parameters map[string]string,
+		hints *service.UsageHints) ([]time.Duration, error)
+}
+
+// ExportCpp is the interface implemented by types that can export a capture
+// as the source of a standalone C++ program, repeating a frame in a loop.
+type ExportCpp interface {
+	ExportCpp(
+		ctx context.Context,
+		intent Intent,
+		mgr *Manager,
+		end atom.ID) ([]byte, error)
+}
+
 // Issue represents a single replay issue reported by QueryIssues.
 type Issue struct {
 	Atom     atom.ID          // The atom that reported the issue.
 	Severity service.Severity // The severity of the issue.
 	Error    error            // The issue's error.
 }
+
+// Timing is the GPU execution time of a single atom reported by QueryTimings.
+type Timing struct {
+	Atom     atom.ID       // The timed atom.
+	Duration time.Duration // The total GPU execution time of the atom.
+	Count    uint32        // The number of times the atom was executed.
+}
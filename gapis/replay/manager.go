@@ -22,8 +22,10 @@ import (
 
 	"github.com/google/gapid/core/data/id"
 	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
 	"github.com/google/gapid/core/os/device/bind"
 	gapir "github.com/google/gapid/gapir/client"
+	"github.com/google/gapid/gapis/replay/protocol"
 	"github.com/google/gapid/gapis/replay/scheduler"
 	"github.com/google/gapid/gapis/service"
 )
@@ -38,9 +40,17 @@ const (
 // Manager is used discover replay devices and to send replay requests to those
 // discovered devices.
 type Manager struct {
-	gapir      *gapir.Client
-	schedulers map[id.ID]*scheduler.Scheduler
-	mutex      sync.Mutex // guards schedulers
+	replays     uint64 // number of replays started, first for atomic alignment
+	gapir       *gapir.Client
+	connections *connectionPool
+	schedulers  map[id.ID]*scheduler.Scheduler
+	mutex       sync.Mutex // guards schedulers
+	results     *resultCache
+
+	pending      map[batchKey]*pendingBatch
+	pendingMutex sync.Mutex // guards pending
+
+	status statusListeners
 }
 
 // batchKey is used as a key for the batch that's being formed.
@@ -53,11 +63,16 @@ type batchKey struct {
 	generator Generator
 }
 
-// New returns a new Manager instance using the database db.
-func New(ctx context.Context) *Manager {
+// New returns a new Manager instance using the database db. The GAPIR
+// instances used for replays are created with the given options.
+func New(ctx context.Context, options gapir.Options) *Manager {
+	client := gapir.New(ctx, options)
 	out := &Manager{
-		gapir:      gapir.New(ctx),
-		schedulers: make(map[id.ID]*scheduler.Scheduler),
+		gapir:       client,
+		connections: newConnectionPool(client, options.Prewarm),
+		schedulers:  make(map[id.ID]*scheduler.Scheduler),
+		results:     newResultCache(resultCacheSize),
+		pending:     map[batchKey]*pendingBatch{},
 	}
 	bind.GetRegistry(ctx).Listen(bind.NewDeviceListener(out.createScheduler, out.destroyScheduler))
 	return out
@@ -66,6 +81,10 @@ func New(ctx context.Context) *Manager {
 // Replay requests that req is to be performed on the device described by intent,
 // using the capture described by intent. Replay requests made with configs that
 // have equality (==) will likely be batched into the same replay pass.
+// Requests made while a batch with the same config is still pending join that
+// batch, and equal requests within a batch are only replayed once.
+// Successful results are cached, so repeating a request for the same device,
+// capture, config and generator returns without replaying again.
 func (m *Manager) Replay(
 	ctx context.Context,
 	intent Intent,
@@ -80,26 +99,52 @@ func (m *Manager) Replay(
 		return nil, err
 	}
 
-	b := scheduler.Batch{
-		Key: batchKey{
-			capture:   intent.Capture.Id.ID(),
-			device:    intent.Device.Id.ID(),
-			config:    cfg,
-			generator: generator,
-		},
-		Priority:     defaultPriority,
-		Precondition: defaultBatchDelay,
+	key := batchKey{
+		capture:   intent.Capture.Id.ID(),
+		device:    intent.Device.Id.ID(),
+		config:    cfg,
+		generator: generator,
 	}
-	if hints != nil {
-		if hints.Preview {
-			b.Priority = lowPriority
-		}
-		if hints.Primary {
-			b.Priority = highPriorty
-			b.Precondition = nil
+	cached := resultKey{key, req}
+	useCache := cacheable(key, req)
+	if useCache {
+		if val, ok := m.results.get(cached); ok {
+			log.I(ctx, "Replay result cache hit")
+			return val, nil
 		}
 	}
-	return s.Schedule(ctx, req, b)
+
+	val, err = s.Schedule(ctx, req, m.joinBatch(key, hints))
+	if err == nil && useCache {
+		m.results.add(cached, val)
+	}
+	return val, err
+}
+
+// Export returns the payload generated to perform req on the device described
+// by intent, without replaying it, and the memory layout the payload is built
+// for. The results of req are discarded.
+func (m *Manager) Export(
+	ctx context.Context,
+	intent Intent,
+	cfg Config,
+	req Request,
+	generator Generator) (protocol.Payload, *device.MemoryLayout, error) {
+
+	d := bind.GetRegistry(ctx).Device(intent.Device.Id.ID())
+	if d == nil {
+		return protocol.Payload{}, nil, log.Errf(ctx, nil, "Unknown device %v", intent.Device.Id.ID())
+	}
+	ctx = bindReplay(ctx, d, intent.Capture.Id.ID())
+
+	status := m.newStatusTracker(intent)
+	requests := []RequestAndResult{{Request: req, Result: func(interface{}, error) {}}}
+	payload, _, abi, err := build(ctx, d, intent, cfg, generator, requests, status)
+	status.done(err)
+	if err != nil {
+		return protocol.Payload{}, nil, err
+	}
+	return payload, abi.MemoryLayout, nil
 }
 
 func (m *Manager) scheduler(ctx context.Context, deviceID id.ID) (*scheduler.Scheduler, error) {
@@ -118,6 +163,7 @@ func (m *Manager) createScheduler(ctx context.Context, device bind.Device) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.schedulers[deviceID] = scheduler.New(ctx, m.batch)
+	m.connections.add(ctx, device)
 }
 
 func (m *Manager) destroyScheduler(ctx context.Context, device bind.Device) {
@@ -126,4 +172,7 @@ func (m *Manager) destroyScheduler(ctx context.Context, device bind.Device) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	delete(m.schedulers, deviceID)
+	m.results.forgetDevice(deviceID)
+	m.forgetPending(deviceID)
+	m.connections.remove(ctx, device)
 }
@@ -0,0 +1,59 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"fmt"
+
+	"github.com/google/gapid/core/image"
+)
+
+// OverdrawColors is the heatmap palette used to display the overdraw counts.
+// Pixels drawn more times than there are colors use the last color.
+var OverdrawColors = [][3]float32{
+	{0.0, 0.0, 0.0}, // Not drawn.
+	{0.0, 0.0, 0.6},
+	{0.0, 0.5, 1.0},
+	{0.0, 0.8, 0.3},
+	{0.7, 0.9, 0.0},
+	{1.0, 0.8, 0.0},
+	{1.0, 0.4, 0.0},
+	{0.9, 0.0, 0.0},
+	{1.0, 1.0, 1.0},
+}
+
+// OverdrawHeatmap returns the heatmap of the overdraw counts held by the
+// 8 bit stencil image, for APIs counting the draws on the replay device.
+func OverdrawHeatmap(counts *image.Image2D) (*image.Image2D, error) {
+	size := int(counts.Width * counts.Height)
+	if len(counts.Data) != size {
+		return nil, fmt.Errorf("Overdraw counts of %d bytes for a %dx%d image",
+			len(counts.Data), counts.Width, counts.Height)
+	}
+	data := make([]byte, 0, size*4)
+	for _, count := range counts.Data {
+		c := OverdrawColors[len(OverdrawColors)-1]
+		if int(count) < len(OverdrawColors) {
+			c = OverdrawColors[count]
+		}
+		data = append(data, byte(c[0]*255+0.5), byte(c[1]*255+0.5), byte(c[2]*255+0.5), 255)
+	}
+	return &image.Image2D{
+		Format: image.RGBA_U8_NORM,
+		Width:  counts.Width,
+		Height: counts.Height,
+		Data:   data,
+	}, nil
+}
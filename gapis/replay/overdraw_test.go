@@ -0,0 +1,42 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/image"
+)
+
+func TestOverdrawHeatmap(t *testing.T) {
+	ctx := assert.Context(t)
+	counts := &image.Image2D{Width: 2, Height: 2, Data: []byte{0, 1, 8, 200}}
+	got, err := OverdrawHeatmap(counts)
+	if assert.For(ctx, "heatmap").ThatError(err).Succeeded() {
+		assert.For(ctx, "format").That(got.Format).Equals(image.RGBA_U8_NORM)
+		assert.For(ctx, "data").ThatSlice(got.Data).Equals([]byte{
+			0, 0, 0, 255,
+			0, 0, 153, 255,
+			255, 255, 255, 255,
+			// Counts beyond the palette use its last color.
+			255, 255, 255, 255,
+		})
+	}
+
+	counts.Data = counts.Data[:3]
+	_, err = OverdrawHeatmap(counts)
+	assert.For(ctx, "truncated").ThatError(err).Failed()
+}
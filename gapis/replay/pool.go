@@ -0,0 +1,145 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/os/device/bind"
+)
+
+const (
+	// retryInterval is the time before retrying to start the GAPIR instance of
+	// a device that failed to start.
+	retryInterval = time.Second * 10
+	// maxRetryInterval is the longest time between the retries of a device
+	// whose GAPIR instance keeps failing to start.
+	maxRetryInterval = time.Minute
+)
+
+// gapirClient is the part of the GAPIR client used by the connectionPool.
+type gapirClient interface {
+	Connect(ctx context.Context, d bind.Device, abi *device.ABI, capture id.ID) (io.ReadWriteCloser, error)
+	Warm(ctx context.Context, d bind.Device, abi *device.ABI) (<-chan struct{}, error)
+}
+
+// connectionPool connects to the GAPIR instances of the replay devices. When
+// prewarming, the instances are started as soon as the devices are added and
+// restarted whenever they stop, so that the replays do not wait for them to
+// start and load their libraries. The health of the running instances is
+// checked by the heartbeat of the GAPIR client.
+type connectionPool struct {
+	gapir   gapirClient
+	prewarm bool
+	retry   time.Duration // The time before retrying to start an instance.
+	mutex   sync.Mutex
+	warm    map[id.ID]func() // Stops keeping a device warm. Guarded by mutex.
+}
+
+func newConnectionPool(gapir gapirClient, prewarm bool) *connectionPool {
+	return &connectionPool{
+		gapir:   gapir,
+		prewarm: prewarm,
+		retry:   retryInterval,
+		warm:    map[id.ID]func(){},
+	}
+}
+
+// add starts the GAPIR instance for the preferred ABI of the device d, if
+// prewarming, and keeps it running until the device is removed.
+func (p *connectionPool) add(ctx context.Context, d bind.Device) {
+	if !p.prewarm {
+		return
+	}
+	deviceID := d.Instance().Id.ID()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, found := p.warm[deviceID]; found {
+		return
+	}
+	ctx, stop := task.WithCancel(ctx)
+	p.warm[deviceID] = stop
+	go p.keepWarm(ctx, d, d.Instance().GetConfiguration().PreferredABI(nil))
+}
+
+// remove stops keeping the GAPIR instance of the device d running.
+func (p *connectionPool) remove(ctx context.Context, d bind.Device) {
+	deviceID := d.Instance().Id.ID()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if stop, found := p.warm[deviceID]; found {
+		stop()
+		delete(p.warm, deviceID)
+	}
+}
+
+// keepWarm starts the GAPIR instance of the device d, and restarts it each time
+// it stops, until ctx is stopped. The retries of an instance that fails to
+// start, or that stops within the retry interval of starting, back off, as
+// starting an instance is costly.
+func (p *connectionPool) keepWarm(ctx context.Context, d bind.Device, abi *device.ABI) {
+	ctx = log.V{"device": d, "abi": abi}.Bind(ctx)
+	retry := p.retry
+	for {
+		started := time.Now()
+		stopped, err := p.gapir.Warm(ctx, d, abi)
+		if err == nil {
+			select {
+			case <-task.ShouldStop(ctx):
+				return
+			case <-stopped:
+			}
+			if time.Since(started) >= p.retry {
+				log.W(ctx, "Replay device GAPIR stopped, restarting it")
+				retry = p.retry
+				continue
+			}
+			log.W(ctx, "Replay device GAPIR stopped right after starting, restarting in %v", retry)
+		} else {
+			log.W(ctx, "Failed to start the replay device GAPIR, retrying in %v: %v", retry, err)
+		}
+		select {
+		case <-task.ShouldStop(ctx):
+			return
+		case <-time.After(retry):
+		}
+		if retry *= 2; retry > maxRetryInterval {
+			retry = maxRetryInterval
+		}
+	}
+}
+
+// connect opens a connection to the GAPIR instance of the device d for
+// replaying the capture with the given ABI. If the connection fails, for
+// instance because the GAPIR instance has stopped since its last heartbeat,
+// the instance is restarted if it does not reply and connect tries once more.
+func (p *connectionPool) connect(ctx context.Context, d bind.Device, abi *device.ABI, capture id.ID) (io.ReadWriteCloser, error) {
+	connection, err := p.gapir.Connect(ctx, d, abi, capture)
+	if err == nil {
+		return connection, nil
+	}
+	log.W(ctx, "Failed to connect to the replay device, reconnecting: %v", err)
+	if _, err := p.gapir.Warm(ctx, d, abi); err != nil {
+		return nil, err
+	}
+	return p.gapir.Connect(ctx, d, abi, capture)
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/core/os/device/bind"
+)
+
+type fakeGapir struct {
+	mutex        sync.Mutex
+	connects     int
+	warms        int
+	failConnects int           // The number of the next Connect calls to fail.
+	warmErr      error         // The error returned by Warm.
+	crash        bool          // Whether the instances stop as soon as started.
+	warmed       chan struct{} // Signaled by each Warm call.
+	stopped      chan struct{} // Returned by Warm, closed to stop the instance.
+}
+
+func (f *fakeGapir) Connect(ctx context.Context, d bind.Device, abi *device.ABI, capture id.ID) (io.ReadWriteCloser, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.connects++
+	if f.failConnects > 0 {
+		f.failConnects--
+		return nil, errors.New("Connection refused")
+	}
+	return nil, nil
+}
+
+func (f *fakeGapir) Warm(ctx context.Context, d bind.Device, abi *device.ABI) (<-chan struct{}, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.warms++
+	if f.warmed != nil {
+		select {
+		case f.warmed <- struct{}{}:
+		default:
+		}
+	}
+	if f.warmErr != nil {
+		return nil, f.warmErr
+	}
+	if f.crash {
+		stopped := make(chan struct{})
+		close(stopped)
+		return stopped, nil
+	}
+	if f.stopped == nil {
+		f.stopped = make(chan struct{})
+	}
+	return f.stopped, nil
+}
+
+// stop stops the running instance.
+func (f *fakeGapir) stop() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	close(f.stopped)
+	f.stopped = nil
+}
+
+func (f *fakeGapir) counts() (connects, warms int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.connects, f.warms
+}
+
+func testDevice() bind.Device {
+	return &bind.Simple{To: &device.Instance{
+		Id:            device.NewID(id.OfString("device")),
+		Configuration: &device.Configuration{ABIs: []*device.ABI{device.LinuxX86_64}},
+	}}
+}
+
+func TestConnectionPoolConnect(t *testing.T) {
+	ctx := log.Testing(t)
+	d := testDevice()
+	for _, test := range []struct {
+		name         string
+		failConnects int
+		warmErr      error
+		connects     int
+		warms        int
+		fails        bool
+	}{
+		{"connected", 0, nil, 1, 0, false},
+		{"reconnected", 1, nil, 2, 1, false},
+		{"restart failed", 1, errors.New("No reply"), 1, 1, true},
+		{"reconnect failed", 2, nil, 2, 1, true},
+	} {
+		f := &fakeGapir{failConnects: test.failConnects, warmErr: test.warmErr}
+		p := newConnectionPool(f, false)
+		_, err := p.connect(ctx, d, device.LinuxX86_64, id.ID{})
+		connects, warms := f.counts()
+		assert.For(ctx, "%s: failed", test.name).That(err != nil).Equals(test.fails)
+		assert.For(ctx, "%s: connects", test.name).That(connects).Equals(test.connects)
+		assert.For(ctx, "%s: warms", test.name).That(warms).Equals(test.warms)
+	}
+}
+
+func TestConnectionPoolPrewarm(t *testing.T) {
+	ctx := log.Testing(t)
+	d := testDevice()
+	wait := func(f *fakeGapir, what string) {
+		select {
+		case <-f.warmed:
+		case <-time.After(time.Second * 10):
+			t.Fatalf("Timeout waiting for the %s", what)
+		}
+	}
+
+	f := &fakeGapir{}
+	p := newConnectionPool(f, false)
+	p.add(ctx, d)
+	time.Sleep(time.Millisecond * 10)
+	_, warms := f.counts()
+	assert.For(ctx, "warms without prewarm").That(warms).Equals(0)
+
+	f = &fakeGapir{warmed: make(chan struct{})}
+	p = newConnectionPool(f, true)
+	p.retry = time.Millisecond
+	p.add(ctx, d)
+	p.add(ctx, d) // Added devices are only warmed once.
+	wait(f, "start")
+	time.Sleep(time.Millisecond * 10)
+	_, warms = f.counts()
+	assert.For(ctx, "warms while running").That(warms).Equals(1)
+
+	// Stopped instances are restarted.
+	f.stop()
+	wait(f, "restart")
+
+	p.remove(ctx, d)
+	_, removed := f.counts()
+	time.Sleep(time.Millisecond * 20)
+	_, warms = f.counts()
+	assert.For(ctx, "warms after remove").That(warms).Equals(removed)
+
+	// Instances failing to start are retried.
+	f = &fakeGapir{warmed: make(chan struct{}), warmErr: errors.New("No reply")}
+	p = newConnectionPool(f, true)
+	p.retry = time.Millisecond
+	p.add(ctx, d)
+	for i := 0; i < 3; i++ {
+		wait(f, "retries")
+	}
+	p.remove(ctx, d)
+
+	// Instances stopping as soon as started are retried with a back off.
+	f = &fakeGapir{crash: true}
+	p = newConnectionPool(f, true)
+	p.retry = time.Millisecond * 50
+	p.add(ctx, d)
+	time.Sleep(time.Millisecond * 120)
+	p.remove(ctx, d)
+	_, warms = f.counts()
+	assert.For(ctx, "warms of crashing instance").ThatInteger(warms).IsAtMost(3)
+}
@@ -18,6 +18,8 @@
 # build and the file will be recreated, check in the new version.
 
 set(files
+    compression.go
+    compression_test.go
     doc.go
     opcode.go
     payload.go
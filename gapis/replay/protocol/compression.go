@@ -0,0 +1,208 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/google/gapid/core/data/pod"
+)
+
+// The resources are only compressed. Delta encoding them against the versions
+// previously sent is not implemented, as the resources the device already
+// holds from previous replays are not sent again at all.
+
+// ChunkSize is the maximum number of uncompressed bytes of a compressed chunk.
+const ChunkSize = 256 << 10
+
+const (
+	lz4MinMatch     = 4     // Shortest match that can be encoded.
+	lz4MaxOffset    = 65535 // Furthest back a match can be.
+	lz4LastLiterals = 5     // The last bytes of a block are always literals.
+	lz4MatchLimit   = 12    // The last match starts at least this far from the end.
+	lz4HashBits     = 16    // Size of the match finder hash table.
+)
+
+// CompressLZ4 returns src compressed as a single LZ4 block.
+func CompressLZ4(src []byte) []byte {
+	dst := make([]byte, 0, len(src)+len(src)/255+16)
+	table := make([]int32, 1<<lz4HashBits) // Position + 1 of the last sequence with the hash.
+	anchor := 0
+	for i := 0; i+lz4MatchLimit <= len(src); {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := (seq * 2654435761) >> (32 - lz4HashBits)
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if ref < 0 || i-ref > lz4MaxOffset || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i++
+			continue
+		}
+		length := lz4MinMatch
+		for end := len(src) - lz4LastLiterals; i+length < end && src[ref+length] == src[i+length]; {
+			length++
+		}
+		dst = appendLZ4Sequence(dst, src[anchor:i], i-ref, length)
+		i += length
+		anchor = i
+	}
+	return appendLZ4Sequence(dst, src[anchor:], 0, 0)
+}
+
+// appendLZ4Sequence appends the literals followed by the match of length
+// bytes at offset bytes back. The last sequence of a block has no match, and
+// is appended with a length of 0.
+func appendLZ4Sequence(dst, literals []byte, offset, length int) []byte {
+	token := byte(0)
+	if len(literals) >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(len(literals)) << 4
+	}
+	match := length - lz4MinMatch
+	if length > 0 {
+		if match >= 15 {
+			token |= 15
+		} else {
+			token |= byte(match)
+		}
+	}
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		dst = appendLZ4Length(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+	if length > 0 {
+		dst = append(dst, byte(offset), byte(offset>>8))
+		if match >= 15 {
+			dst = appendLZ4Length(dst, match-15)
+		}
+	}
+	return dst
+}
+
+func appendLZ4Length(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+// DecompressLZ4 returns the size bytes decompressed from the LZ4 block src.
+func DecompressLZ4(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	for len(src) > 0 {
+		token := src[0]
+		src = src[1:]
+		literals, ok := readLZ4Length(&src, int(token>>4))
+		if !ok || literals > len(src) || len(dst)+literals > size {
+			return nil, fmt.Errorf("Invalid LZ4 literals")
+		}
+		dst = append(dst, src[:literals]...)
+		src = src[literals:]
+		if len(src) == 0 {
+			break // The last sequence has no match.
+		}
+		if len(src) < 2 {
+			return nil, fmt.Errorf("Truncated LZ4 match offset")
+		}
+		offset := int(src[0]) | int(src[1])<<8
+		src = src[2:]
+		match, ok := readLZ4Length(&src, int(token&15))
+		match += lz4MinMatch
+		if !ok || offset == 0 || offset > len(dst) || len(dst)+match > size {
+			return nil, fmt.Errorf("Invalid LZ4 match")
+		}
+		// The match may overlap the bytes it produces.
+		for ref := len(dst) - offset; match > 0; match-- {
+			dst = append(dst, dst[ref])
+			ref++
+		}
+	}
+	if len(dst) != size {
+		return nil, fmt.Errorf("LZ4 block size mismatch. expected: %v, got: %v", size, len(dst))
+	}
+	return dst, nil
+}
+
+func readLZ4Length(src *[]byte, n int) (int, bool) {
+	if n != 15 {
+		return n, true
+	}
+	for {
+		if len(*src) == 0 {
+			return 0, false
+		}
+		b := (*src)[0]
+		*src = (*src)[1:]
+		n += int(b)
+		if b != 255 {
+			return n, true
+		}
+	}
+}
+
+// NewCompressedWriter returns a writer that sends the data written to it to w
+// in chunks of at most ChunkSize bytes. Each chunk is encoded as its size,
+// the size of its data and its data, which is compressed with LZ4 unless
+// that does not make it smaller.
+// The returned writer must be closed to send the last chunk, this does not
+// close w.
+func NewCompressedWriter(w pod.Writer) io.WriteCloser {
+	return &compressedWriter{w: w, buf: make([]byte, 0, ChunkSize)}
+}
+
+type compressedWriter struct {
+	w   pod.Writer
+	buf []byte
+}
+
+func (c *compressedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		count := ChunkSize - len(c.buf)
+		if count > len(p) {
+			count = len(p)
+		}
+		c.buf = append(c.buf, p[:count]...)
+		p = p[count:]
+		if len(c.buf) == ChunkSize {
+			c.flush()
+		}
+	}
+	return n, c.w.Error()
+}
+
+func (c *compressedWriter) Close() error {
+	c.flush()
+	return c.w.Error()
+}
+
+func (c *compressedWriter) flush() {
+	if len(c.buf) == 0 {
+		return
+	}
+	c.w.Uint32(uint32(len(c.buf)))
+	if packed := CompressLZ4(c.buf); len(packed) < len(c.buf) {
+		c.w.Uint32(uint32(len(packed)))
+		c.w.Data(packed)
+	} else {
+		// Stored as is, which the equal sizes denote.
+		c.w.Uint32(uint32(len(c.buf)))
+		c.w.Data(c.buf)
+	}
+	c.buf = c.buf[:0]
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/os/device"
+)
+
+func testData() map[string][]byte {
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+	return map[string][]byte{
+		"empty":    {},
+		"short":    []byte("abc"),
+		"repeated": bytes.Repeat([]byte{0x42}, 100000),
+		"pattern":  bytes.Repeat([]byte("0123456789abcdef"), 5000),
+		"random":   random,
+		"mixed":    append(append([]byte("literals before "), bytes.Repeat([]byte("match"), 100)...), random[:300]...),
+		"distant":  append(random[:70000], random[:70000]...),
+	}
+}
+
+func TestLZ4RoundTrip(t *testing.T) {
+	ctx := assert.Context(t)
+	for name, data := range testData() {
+		packed := CompressLZ4(data)
+		got, err := DecompressLZ4(packed, len(data))
+		assert.For(ctx, "%s err", name).ThatError(err).Succeeded()
+		assert.For(ctx, "%s data", name).ThatSlice(got).Equals(data)
+	}
+	packed := CompressLZ4(bytes.Repeat([]byte{0x42}, 100000))
+	assert.For(ctx, "repeated size").That(len(packed) < 1000).Equals(true)
+}
+
+func TestLZ4Invalid(t *testing.T) {
+	ctx := assert.Context(t)
+	packed := CompressLZ4(bytes.Repeat([]byte("0123456789abcdef"), 100))
+	_, err := DecompressLZ4(packed, 1599)
+	assert.For(ctx, "short size").ThatError(err).Failed()
+	_, err = DecompressLZ4(packed[:len(packed)-10], 1600)
+	assert.For(ctx, "truncated").ThatError(err).Failed()
+	// A match referring to before the start of the block.
+	_, err = DecompressLZ4([]byte{0x10, 'a', 0x02, 0x00, 0x00}, 5)
+	assert.For(ctx, "bad offset").ThatError(err).Failed()
+}
+
+func TestCompressedWriter(t *testing.T) {
+	ctx := assert.Context(t)
+	data := testData()
+	stream := append(append(data["pattern"], data["random"]...), data["repeated"]...)
+
+	buf := &bytes.Buffer{}
+	cw := NewCompressedWriter(endian.Writer(buf, device.LittleEndian))
+	// Write in pieces that do not line up with the chunks.
+	for p := stream; len(p) > 0; {
+		n := 1000 + rand.Intn(100000)
+		if n > len(p) {
+			n = len(p)
+		}
+		_, err := cw.Write(p[:n])
+		assert.For(ctx, "write").ThatError(err).Succeeded()
+		p = p[n:]
+	}
+	assert.For(ctx, "close").ThatError(cw.Close()).Succeeded()
+	assert.For(ctx, "compressed").That(buf.Len() < len(stream)).Equals(true)
+
+	r := endian.Reader(buf, device.LittleEndian)
+	got := []byte{}
+	for buf.Len() > 0 {
+		size, packedSize := r.Uint32(), r.Uint32()
+		assert.For(ctx, "chunk size").That(size <= ChunkSize).Equals(true)
+		chunk := make([]byte, packedSize)
+		r.Data(chunk)
+		if packedSize != size {
+			var err error
+			chunk, err = DecompressLZ4(chunk, int(size))
+			assert.For(ctx, "chunk").ThatError(err).Succeeded()
+		}
+		got = append(got, chunk...)
+	}
+	assert.For(ctx, "read").ThatError(r.Error()).Succeeded()
+	assert.For(ctx, "stream").ThatSlice(got).Equals(stream)
+}
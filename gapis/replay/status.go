@@ -0,0 +1,245 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+)
+
+// statusInterval is the minimum duration between two broadcasts of the
+// progress of a replay. Changes of stage are always broadcast.
+const statusInterval = time.Millisecond * 250
+
+// StatusListener is called with the status of a replay each time it
+// progresses.
+type StatusListener func(*service.ReplayStatus)
+
+// statusListeners holds the listeners registered with ListenStatus.
+type statusListeners struct {
+	mutex     sync.Mutex
+	listeners map[int]StatusListener
+	next      int
+}
+
+// ListenStatus registers l to be called with the status of the replays
+// performed by the manager, returning a function that unregisters it.
+// l is called from the replays themselves, and so must not block.
+func (m *Manager) ListenStatus(l StatusListener) (unregister func()) {
+	s := &m.status
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listeners == nil {
+		s.listeners = map[int]StatusListener{}
+	}
+	id := s.next
+	s.next++
+	s.listeners[id] = l
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+func (m *Manager) broadcastStatus(status service.ReplayStatus) {
+	s := &m.status
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, l := range s.listeners {
+		// Each listener gets its own copy, as they may be sent on concurrently.
+		c := status
+		l(&c)
+	}
+}
+
+// StatusQueue buffers the statuses of replays for a listener that may fall
+// behind them. Changes of stage are always kept, while the progress of a replay
+// that has not been popped yet is coalesced into its latest status.
+type StatusQueue struct {
+	mutex   sync.Mutex
+	pending []*service.ReplayStatus
+	ready   chan struct{}
+}
+
+// NewStatusQueue returns a new, empty StatusQueue.
+func NewStatusQueue() *StatusQueue {
+	return &StatusQueue{ready: make(chan struct{}, 1)}
+}
+
+// Push adds status to the queue. Push never blocks, and so can be used as a
+// StatusListener.
+func (q *StatusQueue) Push(status *service.ReplayStatus) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i := len(q.pending) - 1; i >= 0; i-- {
+		if p := q.pending[i]; p.Replay == status.Replay {
+			if p.Stage == status.Stage {
+				// Superseded by the new status.
+				q.pending[i] = status
+				return
+			}
+			break
+		}
+	}
+	q.pending = append(q.pending, status)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready returns a channel that is signaled when statuses are pushed to the
+// empty queue.
+func (q *StatusQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Pop removes all the statuses of the queue and returns them in the order they
+// were pushed.
+func (q *StatusQueue) Pop() []*service.ReplayStatus {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	out := q.pending
+	q.pending = nil
+	return out
+}
+
+// statusTracker follows the progress of a single replay, broadcasting it to
+// the listeners of the manager.
+type statusTracker struct {
+	manager *Manager
+	mutex   sync.Mutex
+	status  service.ReplayStatus
+	frames  []uint64 // identifiers of the atoms ending each frame
+	last    time.Time
+}
+
+func (m *Manager) newStatusTracker(intent Intent) *statusTracker {
+	return &statusTracker{
+		manager: m,
+		status: service.ReplayStatus{
+			Replay:  atomic.AddUint64(&m.replays, 1),
+			Capture: intent.Capture,
+			Device:  intent.Device,
+			Stage:   service.ReplayStage_ReplayBuilding,
+			Frame:   1,
+		},
+	}
+}
+
+// setAtoms sets the atoms of the capture being replayed.
+func (t *statusTracker) setAtoms(list *atom.List) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.frames = t.frames[:0]
+	for i, a := range list.Atoms {
+		if a.AtomFlags().IsEndOfFrame() {
+			t.frames = append(t.frames, uint64(i))
+		}
+	}
+	t.status.CommandsTotal = uint64(len(list.Atoms))
+	t.update(true)
+}
+
+// frame returns the frame of the atom with the given identifier, starting at
+// 1.
+func (t *statusTracker) frame(id uint64) uint64 {
+	return uint64(sort.Search(len(t.frames), func(i int) bool { return t.frames[i] >= id })) + 1
+}
+
+// built is called once the atom with the given identifier has been built into
+// the payload.
+func (t *statusTracker) built(id atom.ID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// Atoms injected by the generator are not part of the capture.
+	if uint64(id) >= t.status.CommandsTotal || uint64(id) < t.status.CommandsBuilt {
+		return
+	}
+	t.status.CommandsBuilt = uint64(id) + 1
+	t.status.Frame = t.frame(uint64(id))
+	t.update(false)
+}
+
+// executing is called once the payload has been built, and is about to be
+// sent to the device along with resourceBytes of resources.
+func (t *statusTracker) executing(resourceBytes uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.status.Stage = service.ReplayStage_ReplayExecuting
+	t.status.ResourceBytesTotal = resourceBytes
+	t.status.Frame = 1
+	t.update(true)
+}
+
+// ResourcesCached is called with the number of bytes of resources the device
+// already holds from previous replays.
+func (t *statusTracker) ResourcesCached(bytes uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.status.ResourceBytesCached = bytes
+	t.update(false)
+}
+
+// ResourcesSent is called each time resources are sent to the device, with the
+// number of bytes sent.
+func (t *statusTracker) ResourcesSent(bytes uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.status.ResourceBytesSent += bytes
+	t.update(false)
+}
+
+// executed is called each time the device posts back the data of the atom
+// with the given identifier.
+func (t *statusTracker) executed(id uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if id >= t.status.CommandsTotal || id < t.status.CommandsExecuted {
+		return
+	}
+	t.status.CommandsExecuted = id + 1
+	t.status.Frame = t.frame(id)
+	t.update(false)
+}
+
+// done is called once the replay has finished, successfully if err is nil.
+func (t *statusTracker) done(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err != nil {
+		t.status.Stage = service.ReplayStage_ReplayFailed
+	} else {
+		t.status.Stage = service.ReplayStage_ReplayDone
+	}
+	t.update(true)
+}
+
+// update broadcasts the status if force is true, or if it has not been for
+// statusInterval. t.mutex must be held.
+func (t *statusTracker) update(force bool) {
+	now := time.Now()
+	if !force && now.Sub(t.last) < statusInterval {
+		return
+	}
+	t.last = now
+	t.manager.broadcastStatus(t.status)
+}
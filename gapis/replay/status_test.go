@@ -0,0 +1,112 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/test"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestStatusTracker(t *testing.T) {
+	ctx := assert.Context(t)
+	m := &Manager{}
+	statuses := []*service.ReplayStatus{}
+	unregister := m.ListenStatus(func(s *service.ReplayStatus) { statuses = append(statuses, s) })
+
+	frame := &test.AtomA{Flags: atom.EndOfFrame}
+	list := atom.NewList(&test.AtomB{}, frame, &test.AtomB{}, &test.AtomB{}, frame)
+
+	s := m.newStatusTracker(Intent{})
+	s.setAtoms(list)
+	for _, f := range []struct {
+		id     uint64
+		expect uint64
+	}{{0, 1}, {1, 1}, {2, 2}, {4, 2}, {5, 3}} {
+		assert.For(ctx, "frame of %d", f.id).That(s.frame(f.id)).Equals(f.expect)
+	}
+
+	// Progress between the changes of stage is throttled, but the changes are
+	// always broadcast with the latest progress.
+	s.built(0)
+	s.built(atom.NoID)
+	s.built(3)
+	s.executing(384)
+	s.ResourcesCached(128)
+	s.ResourcesSent(128)
+	s.executed(2)
+	s.executed(uint64(atom.NoID))
+	s.ResourcesSent(128)
+	s.done(nil)
+	assert.For(ctx, "statuses").That(len(statuses) >= 3).Equals(true)
+
+	first := statuses[0]
+	assert.For(ctx, "first replay").That(first.Replay).Equals(uint64(1))
+	assert.For(ctx, "first stage").That(first.Stage).Equals(service.ReplayStage_ReplayBuilding)
+	assert.For(ctx, "first total").That(first.CommandsTotal).Equals(uint64(5))
+
+	last := statuses[len(statuses)-1]
+	assert.For(ctx, "last stage").That(last.Stage).Equals(service.ReplayStage_ReplayDone)
+	assert.For(ctx, "built").That(last.CommandsBuilt).Equals(uint64(4))
+	assert.For(ctx, "executed").That(last.CommandsExecuted).Equals(uint64(3))
+	assert.For(ctx, "sent").That(last.ResourceBytesSent).Equals(uint64(256))
+	assert.For(ctx, "cached").That(last.ResourceBytesCached).Equals(uint64(128))
+	assert.For(ctx, "total").That(last.ResourceBytesTotal).Equals(uint64(384))
+	assert.For(ctx, "frame").That(last.Frame).Equals(uint64(2))
+
+	unregister()
+	count := len(statuses)
+	s = m.newStatusTracker(Intent{})
+	s.done(nil)
+	assert.For(ctx, "unregistered").That(len(statuses)).Equals(count)
+	assert.For(ctx, "next replay").That(s.status.Replay).Equals(uint64(2))
+}
+
+func TestStatusQueue(t *testing.T) {
+	ctx := assert.Context(t)
+	q := NewStatusQueue()
+	status := func(replay uint64, stage service.ReplayStage, built uint64) *service.ReplayStatus {
+		return &service.ReplayStatus{Replay: replay, Stage: stage, CommandsBuilt: built}
+	}
+	building, executing, done := service.ReplayStage_ReplayBuilding, service.ReplayStage_ReplayExecuting, service.ReplayStage_ReplayDone
+
+	q.Push(status(1, building, 0))
+	q.Push(status(2, building, 0))
+	q.Push(status(1, building, 1))
+	q.Push(status(1, building, 2))
+	q.Push(status(1, executing, 3))
+	q.Push(status(2, done, 4))
+	q.Push(status(1, done, 5))
+	select {
+	case <-q.Ready():
+	default:
+		t.Fatal("Queue not ready")
+	}
+	got := q.Pop()
+	assert.For(ctx, "statuses").That(len(got)).Equals(5)
+	for i, expect := range []*service.ReplayStatus{
+		status(1, building, 2),
+		status(2, building, 0),
+		status(1, executing, 3),
+		status(2, done, 4),
+		status(1, done, 5),
+	} {
+		assert.For(ctx, "status %d", i).That(got[i]).DeepEquals(expect)
+	}
+	assert.For(ctx, "popped").That(len(q.Pop())).Equals(0)
+}
@@ -19,9 +19,37 @@
 
 set(files
     as.go
+    buffer_view.go
+    buffer_view_test.go
+    capture_diff.go
+    capture_diff_test.go
+    captures.go
+    captures_test.go
+    checkpoints.go
+    command_tracks.go
+    command_tracks_test.go
+    constants.go
     contexts.go
+    crash_dump.go
+    depth_mapping.go
+    depth_mapping_test.go
+    dispatch_snapshot.go
     doc.go
+    draw_batches.go
+    draw_batches_test.go
+    edit_commands.go
+    edit_commands_test.go
+    experiment.go
+    export_commands.go
+    export_commands_test.go
+    export_cpp.go
+    export_texture.go
     follow.go
+    frame_deltas.go
+    frame_graph.go
+    frame_graph_test.go
+    frame_loop_profile.go
+    frame_loop_profile_test.go
     framebuffer_attachment.go
     framebuffer_attachment_data.go
     framebuffer_changes.go
@@ -29,8 +57,25 @@ set(files
     get_set_test.go
     hierarchies.go
     index_limits.go
+    leak_report.go
+    leak_report_test.go
+    lint_report.go
+    lint_report_test.go
     memory.go
+    memory_profile.go
+    memory_profile_test.go
+    memory_provenance.go
+    memory_provenance_test.go
     mesh.go
+    minimal_repro.go
+    passes.go
+    passes_test.go
+    perfetto.go
+    pipeline_statistics.go
+    pixel_history.go
+    redundancy_report.go
+    redundancy_report_test.go
+    renderdoc_events.go
     report.go
     requests_test.go
     resolvables.pb.go
@@ -39,10 +84,32 @@ set(files
     resolve_binary_test.go
     resource_data.go
     resource_meta.go
+    resource_timeline.go
     resources.go
+    sanitize.go
+    sanitize_test.go
+    search.go
+    search_query.go
+    search_query_test.go
     set.go
+    shader_analysis.go
+    shader_analysis_test.go
+    splice.go
     state.go
+    state_diff.go
+    state_diff_test.go
+    state_override.go
+    state_override_test.go
+    submission_timeline.go
+    submission_timeline_test.go
+    texture_override.go
+    texture_override_test.go
     thumbnail.go
+    timing_profile.go
+    tonemap.go
+    tonemap_test.go
+    trim.go
+    trim_test.go
 )
 set(dirs
 
@@ -40,6 +40,10 @@ func As(ctx context.Context, p *path.As) (interface{}, error) {
 			return o.ConvertTo(ctx, f)
 		case *gfxapi.Cubemap:
 			return o.ConvertTo(ctx, f)
+		case *gfxapi.Texture2DArray:
+			return o.ConvertTo(ctx, f)
+		case *gfxapi.Texture3D:
+			return o.ConvertTo(ctx, f)
 		}
 	case *path.As_VertexBufferFormat:
 		f := to.VertexBufferFormat
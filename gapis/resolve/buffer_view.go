@@ -0,0 +1,373 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/math/f16"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// BufferView resolves the memory at p and interprets it as an array of
+// structs laid out as described by l.
+func BufferView(ctx context.Context, p *path.Memory, l *service.BufferLayout) (*service.BufferView, error) {
+	columns, size, stride, err := layoutBuffer(l)
+	if err != nil {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidBufferLayout(err.Error())}
+	}
+	mem, err := Memory(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBuffer(mem.Data, columns, size, stride), nil
+}
+
+// bufferColumn is a non-struct field of a BufferLayout, with its offset
+// relative to the start of the outermost struct.
+type bufferColumn struct {
+	info         *service.BufferColumn
+	normalized   bool
+	arrayStride  uint32 // The distance between two array elements.
+	matrixStride uint32 // The distance between two matrix columns.
+}
+
+// layoutBuffer returns the non-struct fields of the layout l, the size of
+// its struct and the distance between two structs.
+func layoutBuffer(l *service.BufferLayout) (columns []bufferColumn, size, stride uint32, err error) {
+	if l == nil || len(l.Fields) == 0 {
+		return nil, 0, 0, fmt.Errorf("the layout has no fields")
+	}
+	switch l.Packing {
+	case service.BufferPacking_Std430, service.BufferPacking_Std140, service.BufferPacking_Packed:
+	default:
+		return nil, 0, 0, fmt.Errorf("unknown packing %v", l.Packing)
+	}
+	columns, align, size, err := layoutStruct(l.Fields, l.Packing)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	stride = l.Stride
+	if stride == 0 {
+		stride = alignUp(size, align)
+	}
+	return columns, size, stride, nil
+}
+
+// layoutStruct returns the non-struct fields of the struct with the given
+// fields, with offsets relative to the start of the struct, along with the
+// alignment and the size of the struct.
+func layoutStruct(fields []*service.BufferField, packing service.BufferPacking) (columns []bufferColumn, align, size uint32, err error) {
+	align = 1
+	for _, f := range fields {
+		var (
+			elements            []bufferColumn
+			elemAlign, elemSize uint32
+		)
+		if len(f.Fields) > 0 {
+			elements, elemAlign, elemSize, err = layoutStruct(f.Fields, packing)
+		} else {
+			var c bufferColumn
+			c, elemAlign, elemSize, err = layoutScalars(f, packing)
+			elements = []bufferColumn{c}
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+
+		fieldAlign, fieldSize := elemAlign, elemSize
+		arrayStride := elemSize
+		if f.Count > 0 {
+			if packing == service.BufferPacking_Std140 {
+				fieldAlign = alignUp(fieldAlign, 16)
+			}
+			arrayStride = alignUp(elemSize, fieldAlign)
+			fieldSize = arrayStride * f.Count
+		}
+		offset := alignUp(size, fieldAlign)
+		size = offset + fieldSize
+		if fieldAlign > align {
+			align = fieldAlign
+		}
+
+		switch {
+		case len(f.Fields) == 0:
+			c := elements[0]
+			c.info.Offset += offset
+			c.info.Count = f.Count
+			c.arrayStride = arrayStride
+			columns = append(columns, c)
+		case f.Count == 0:
+			columns = append(columns, placeColumns(elements, f.Name+".", offset)...)
+		default:
+			for i := uint32(0); i < f.Count; i++ {
+				prefix := fmt.Sprintf("%s[%d].", f.Name, i)
+				columns = append(columns, placeColumns(elements, prefix, offset+i*arrayStride)...)
+			}
+		}
+	}
+	if packing == service.BufferPacking_Std140 {
+		align = alignUp(align, 16)
+	}
+	if packing != service.BufferPacking_Packed {
+		size = alignUp(size, align)
+	}
+	return columns, align, size, nil
+}
+
+// layoutScalars returns the column of the non-struct field f, at offset 0,
+// along with the alignment and the size of a single array element of f.
+func layoutScalars(f *service.BufferField, packing service.BufferPacking) (c bufferColumn, align, size uint32, err error) {
+	n, err := scalarSize(f.Scalar)
+	if err != nil {
+		return bufferColumn{}, 0, 0, err
+	}
+	rows, columns := orOne(f.Rows), orOne(f.Columns)
+	if rows > 4 || columns > 4 {
+		return bufferColumn{}, 0, 0, fmt.Errorf("the field '%s' has more than 4 rows or columns", f.Name)
+	}
+	c = bufferColumn{
+		info: &service.BufferColumn{
+			Name:    f.Name,
+			Scalar:  f.Scalar,
+			Rows:    rows,
+			Columns: columns,
+		},
+		normalized: f.Normalized,
+	}
+	if packing == service.BufferPacking_Packed {
+		c.matrixStride = rows * n
+		return c, 1, columns * rows * n, nil
+	}
+	// The alignment of a vector is that of its scalar times 2 or 4.
+	align = n
+	switch rows {
+	case 2:
+		align = 2 * n
+	case 3, 4:
+		align = 4 * n
+	}
+	if columns == 1 {
+		c.matrixStride = rows * n
+		return c, align, rows * n, nil
+	}
+	// Matrices are laid out as arrays of column vectors.
+	if packing == service.BufferPacking_Std140 {
+		align = alignUp(align, 16)
+	}
+	c.matrixStride = align
+	return c, align, columns * align, nil
+}
+
+// placeColumns returns a copy of the columns of a nested struct placed at
+// offset in its parent, with names prefixed by prefix.
+func placeColumns(columns []bufferColumn, prefix string, offset uint32) []bufferColumn {
+	out := make([]bufferColumn, len(columns))
+	for i, c := range columns {
+		info := *c.info
+		info.Name = prefix + info.Name
+		info.Offset += offset
+		c.info = &info
+		out[i] = c
+	}
+	return out
+}
+
+// decodeBuffer returns the view of data holding structs of the given size at
+// every stride bytes. Trailing bytes too small for a struct are ignored.
+func decodeBuffer(data []byte, columns []bufferColumn, size, stride uint32) *service.BufferView {
+	view := &service.BufferView{}
+	for _, c := range columns {
+		view.Columns = append(view.Columns, c.info)
+	}
+	for base := uint64(0); base+uint64(size) <= uint64(len(data)); base += uint64(stride) {
+		row := &service.BufferRow{}
+		for _, c := range columns {
+			row.Values = append(row.Values, c.decode(data[base:]))
+		}
+		view.Rows = append(view.Rows, row)
+	}
+	return view
+}
+
+// decode returns the values of the column in the struct at the start of data.
+func (c bufferColumn) decode(data []byte) *pod.Value {
+	n, _ := scalarSize(c.info.Scalar)
+	// Gather the scalars, skipping the padding between them.
+	packed := []byte{}
+	for e := uint32(0); e < orOne(c.info.Count); e++ {
+		for col := uint32(0); col < c.info.Columns; col++ {
+			for row := uint32(0); row < c.info.Rows; row++ {
+				o := c.info.Offset + e*c.arrayStride + col*c.matrixStride + row*n
+				packed = append(packed, data[o:o+n]...)
+			}
+		}
+	}
+	// Buffers are read with the byte order of all supported GPUs.
+	r := endian.Reader(bytes.NewReader(packed), device.LittleEndian)
+	count := len(packed) / int(n)
+	if c.normalized && isIntegerScalar(c.info.Scalar) {
+		v := make([]float32, count)
+		for i := range v {
+			v[i] = readNormalized(r, c.info.Scalar)
+		}
+		return pod.NewValue(v)
+	}
+	switch c.info.Scalar {
+	case service.BufferScalar_Float32:
+		v := make([]float32, count)
+		for i := range v {
+			v[i] = r.Float32()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Float16:
+		v := make([]float32, count)
+		for i := range v {
+			v[i] = f16.Number(r.Uint16()).Float32()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Float64:
+		v := make([]float64, count)
+		for i := range v {
+			v[i] = r.Float64()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Int8:
+		v := make([]int8, count)
+		for i := range v {
+			v[i] = r.Int8()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Uint8:
+		v := make([]uint8, count)
+		for i := range v {
+			v[i] = r.Uint8()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Int16:
+		v := make([]int16, count)
+		for i := range v {
+			v[i] = r.Int16()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Uint16:
+		v := make([]uint16, count)
+		for i := range v {
+			v[i] = r.Uint16()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Int32:
+		v := make([]int32, count)
+		for i := range v {
+			v[i] = r.Int32()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Uint32:
+		v := make([]uint32, count)
+		for i := range v {
+			v[i] = r.Uint32()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Int64:
+		v := make([]int64, count)
+		for i := range v {
+			v[i] = r.Int64()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Uint64:
+		v := make([]uint64, count)
+		for i := range v {
+			v[i] = r.Uint64()
+		}
+		return pod.NewValue(v)
+	case service.BufferScalar_Bool:
+		v := make([]bool, count)
+		for i := range v {
+			v[i] = r.Uint32() != 0
+		}
+		return pod.NewValue(v)
+	}
+	return nil
+}
+
+// readNormalized reads an integer of type s from r, normalized to the range
+// [0, 1] if unsigned or [-1, 1] if signed.
+func readNormalized(r pod.Reader, s service.BufferScalar) float32 {
+	signed := func(v, max float64) float32 { return float32(math.Max(v/max, -1)) }
+	switch s {
+	case service.BufferScalar_Int8:
+		return signed(float64(r.Int8()), math.MaxInt8)
+	case service.BufferScalar_Uint8:
+		return float32(float64(r.Uint8()) / math.MaxUint8)
+	case service.BufferScalar_Int16:
+		return signed(float64(r.Int16()), math.MaxInt16)
+	case service.BufferScalar_Uint16:
+		return float32(float64(r.Uint16()) / math.MaxUint16)
+	case service.BufferScalar_Int32:
+		return signed(float64(r.Int32()), math.MaxInt32)
+	case service.BufferScalar_Uint32:
+		return float32(float64(r.Uint32()) / math.MaxUint32)
+	case service.BufferScalar_Int64:
+		return signed(float64(r.Int64()), math.MaxInt64)
+	case service.BufferScalar_Uint64:
+		return float32(float64(r.Uint64()) / math.MaxUint64)
+	}
+	return 0
+}
+
+func isIntegerScalar(s service.BufferScalar) bool {
+	switch s {
+	case service.BufferScalar_Int8, service.BufferScalar_Uint8,
+		service.BufferScalar_Int16, service.BufferScalar_Uint16,
+		service.BufferScalar_Int32, service.BufferScalar_Uint32,
+		service.BufferScalar_Int64, service.BufferScalar_Uint64:
+		return true
+	}
+	return false
+}
+
+// scalarSize returns the size in bytes of the scalar type s.
+func scalarSize(s service.BufferScalar) (uint32, error) {
+	switch s {
+	case service.BufferScalar_Int8, service.BufferScalar_Uint8:
+		return 1, nil
+	case service.BufferScalar_Float16, service.BufferScalar_Int16, service.BufferScalar_Uint16:
+		return 2, nil
+	case service.BufferScalar_Float32, service.BufferScalar_Int32, service.BufferScalar_Uint32, service.BufferScalar_Bool:
+		return 4, nil
+	case service.BufferScalar_Float64, service.BufferScalar_Int64, service.BufferScalar_Uint64:
+		return 8, nil
+	}
+	return 0, fmt.Errorf("unknown scalar type %v", s)
+}
+
+func orOne(v uint32) uint32 {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
+
+func alignUp(v, align uint32) uint32 {
+	return (v + align - 1) / align * align
+}
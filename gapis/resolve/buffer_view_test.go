@@ -0,0 +1,135 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/service"
+)
+
+func columnOffsets(columns []bufferColumn) map[string]uint32 {
+	out := map[string]uint32{}
+	for _, c := range columns {
+		out[c.info.Name] = c.info.Offset
+	}
+	return out
+}
+
+func TestLayoutBuffer(t *testing.T) {
+	ctx := log.Testing(t)
+	f32 := service.BufferScalar_Float32
+	fields := []*service.BufferField{
+		{Name: "a", Scalar: f32},
+		{Name: "b", Scalar: f32, Rows: 3},
+		{Name: "c", Scalar: f32, Count: 2},
+		{Name: "m", Scalar: f32, Rows: 3, Columns: 3},
+		{Name: "lights", Count: 2, Fields: []*service.BufferField{
+			{Name: "color", Scalar: f32, Rows: 3},
+			{Name: "intensity", Scalar: f32},
+		}},
+	}
+	for _, test := range []struct {
+		packing  service.BufferPacking
+		offsets  map[string]uint32
+		size     uint32
+		stride   uint32
+		matrix   uint32
+		elements uint32
+	}{
+		{service.BufferPacking_Std140, map[string]uint32{
+			"a": 0, "b": 16, "c": 32, "m": 64,
+			"lights[0].color": 112, "lights[0].intensity": 124,
+			"lights[1].color": 128, "lights[1].intensity": 140,
+		}, 144, 144, 16, 16},
+		{service.BufferPacking_Std430, map[string]uint32{
+			"a": 0, "b": 16, "c": 28, "m": 48,
+			"lights[0].color": 96, "lights[0].intensity": 108,
+			"lights[1].color": 112, "lights[1].intensity": 124,
+		}, 128, 128, 16, 4},
+		{service.BufferPacking_Packed, map[string]uint32{
+			"a": 0, "b": 4, "c": 16, "m": 24,
+			"lights[0].color": 60, "lights[0].intensity": 72,
+			"lights[1].color": 76, "lights[1].intensity": 88,
+		}, 92, 92, 12, 4},
+	} {
+		ctx := log.V{"packing": test.packing}.Bind(ctx)
+		columns, size, stride, err := layoutBuffer(&service.BufferLayout{Packing: test.packing, Fields: fields})
+		if !assert.For(ctx, "err").ThatError(err).Succeeded() {
+			continue
+		}
+		assert.For(ctx, "offsets").That(columnOffsets(columns)).DeepEquals(test.offsets)
+		assert.For(ctx, "size").That(size).Equals(test.size)
+		assert.For(ctx, "stride").That(stride).Equals(test.stride)
+		assert.For(ctx, "matrix stride").That(columns[3].matrixStride).Equals(test.matrix)
+		assert.For(ctx, "array stride").That(columns[2].arrayStride).Equals(test.elements)
+	}
+}
+
+func TestDecodeBuffer(t *testing.T) {
+	ctx := log.Testing(t)
+	// Interleaved vertices with a position and a normalized color, followed by
+	// 4 bytes of another attribute.
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	for _, v := range []struct {
+		pos   [3]float32
+		color [4]uint8
+	}{
+		{[3]float32{1, 2, 3}, [4]uint8{0, 255, 0, 255}},
+		{[3]float32{4, 5, 6}, [4]uint8{255, 0, 0, 255}},
+	} {
+		for _, p := range v.pos {
+			w.Float32(p)
+		}
+		w.Data(v.color[:])
+		w.Uint32(0)
+	}
+	w.Uint32(0) // Not enough for another vertex.
+
+	columns, size, stride, err := layoutBuffer(&service.BufferLayout{
+		Packing: service.BufferPacking_Packed,
+		Stride:  20,
+		Fields: []*service.BufferField{
+			{Name: "pos", Scalar: service.BufferScalar_Float32, Rows: 3},
+			{Name: "color", Scalar: service.BufferScalar_Uint8, Rows: 4, Normalized: true},
+		},
+	})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+
+	view := decodeBuffer(buf.Bytes(), columns, size, stride)
+	assert.For(ctx, "rows").That(view.Rows).DeepEquals([]*service.BufferRow{
+		{Values: []*pod.Value{pod.NewValue([]float32{1, 2, 3}), pod.NewValue([]float32{0, 1, 0, 1})}},
+		{Values: []*pod.Value{pod.NewValue([]float32{4, 5, 6}), pod.NewValue([]float32{1, 0, 0, 1})}},
+	})
+}
+
+func TestLayoutBufferErrors(t *testing.T) {
+	ctx := log.Testing(t)
+	for name, l := range map[string]*service.BufferLayout{
+		"no fields":     {},
+		"bad scalar":    {Fields: []*service.BufferField{{Name: "a", Scalar: 100}}},
+		"too many rows": {Fields: []*service.BufferField{{Name: "a", Rows: 5}}},
+	} {
+		_, _, _, err := layoutBuffer(l)
+		assert.For(ctx, name).ThatError(err).Failed()
+	}
+}
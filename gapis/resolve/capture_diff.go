@@ -0,0 +1,265 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// maxFrameEdits is the maximum number of commands added or removed in a frame
+// for which the commands of the two captures are aligned. Frames with more
+// differences than this are reported as truncated.
+const maxFrameEdits = 1000
+
+// CaptureDiff compares the captures a and b frame by frame, and returns the
+// differences between their commands, API state and resources.
+func CaptureDiff(ctx context.Context, a, b *path.Capture) (*service.CaptureDiff, error) {
+	obj, err := database.Build(ctx, &CaptureDiffResolvable{A: a, B: b})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.CaptureDiff), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *CaptureDiffResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	framesA, err := snapshotFrames(ctx, r.A)
+	if err != nil {
+		return nil, err
+	}
+	framesB, err := snapshotFrames(ctx, r.B)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &service.CaptureDiff{
+		FramesA: uint64(len(framesA)),
+		FramesB: uint64(len(framesB)),
+	}
+	for i := 0; i < len(framesA) && i < len(framesB); i++ {
+		if diff := diffFrames(&framesA[i], &framesB[i]); diff != nil {
+			diff.Frame = uint64(i + 1)
+			out.Frames = append(out.Frames, diff)
+		}
+	}
+	return out, nil
+}
+
+// frameSnapshot holds what is compared between the frames of two captures.
+type frameSnapshot struct {
+	first     uint64           // Index of the first command of the frame.
+	names     []string         // Names of the commands of the frame.
+	state     map[string]id.ID // Hash of each API state at the end of the frame.
+	resources map[string]id.ID // Hash of each used resource at the end of the frame.
+}
+
+// snapshotFrames mutates all the commands of the capture p, and returns a
+// snapshot of each of its frames.
+func snapshotFrames(ctx context.Context, p *path.Capture) ([]frameSnapshot, error) {
+	ctx = capture.Put(ctx, p)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[gfxapi.Resource]bool{}
+	state := c.NewState()
+	state.OnResourceCreated = func(r gfxapi.Resource) { used[r] = true }
+	state.OnResourceAccessed = func(r gfxapi.Resource) { used[r] = true }
+
+	out := []frameSnapshot{}
+	current := frameSnapshot{}
+	for i, a := range list.Atoms {
+		a.Mutate(ctx, state, nil /* no builder, just mutate */)
+		current.names = append(current.names, a.Class().Schema().Name())
+		if !a.AtomFlags().IsEndOfFrame() {
+			continue
+		}
+
+		current.state = map[string]id.ID{}
+		for api, s := range state.APIs {
+			hash, err := database.Hash(s)
+			if err != nil {
+				return nil, err
+			}
+			current.state[api.Name()] = hash
+		}
+
+		current.resources = map[string]id.ID{}
+		for res := range used {
+			data, err := res.ResourceData(ctx, state)
+			if err != nil {
+				log.W(ctx, "Could not get the data of %v at command %v: %v", res.ResourceHandle(), i, err)
+				continue
+			}
+			hash, err := database.Hash(data)
+			if err != nil {
+				return nil, err
+			}
+			current.resources[res.ResourceHandle()] = hash
+		}
+
+		out = append(out, current)
+		current = frameSnapshot{first: uint64(i + 1)}
+		used = map[gfxapi.Resource]bool{}
+	}
+	return out, nil
+}
+
+// diffFrames returns the differences between the frames a and b, or nil if
+// the frames are identical.
+func diffFrames(a, b *frameSnapshot) *service.FrameDiff {
+	out := &service.FrameDiff{
+		CommandsA: uint64(len(a.names)),
+		CommandsB: uint64(len(b.names)),
+	}
+	different := false
+
+	removed, added, ok := diffStrings(a.names, b.names, maxFrameEdits)
+	if !ok {
+		out.CommandsTruncated = true
+		different = true
+	}
+	for _, i := range removed {
+		out.Removed = append(out.Removed, &service.CommandDiff{Index: a.first + uint64(i), Name: a.names[i]})
+		different = true
+	}
+	for _, i := range added {
+		out.Added = append(out.Added, &service.CommandDiff{Index: b.first + uint64(i), Name: b.names[i]})
+		different = true
+	}
+
+	out.ChangedState = diffHashes(a.state, b.state)
+	out.ChangedResources = diffHashes(a.resources, b.resources)
+	out.RemovedResources = missingKeys(a.resources, b.resources)
+	out.AddedResources = missingKeys(b.resources, a.resources)
+	if len(out.ChangedState) > 0 || len(out.ChangedResources) > 0 ||
+		len(out.RemovedResources) > 0 || len(out.AddedResources) > 0 {
+		different = true
+	}
+
+	if !different {
+		return nil
+	}
+	return out
+}
+
+// diffHashes returns the sorted keys present in both a and b with different
+// hashes.
+func diffHashes(a, b map[string]id.ID) []string {
+	out := []string{}
+	for k, h := range a {
+		if other, ok := b[k]; ok && other != h {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// missingKeys returns the sorted keys present in a but not in b.
+func missingKeys(a, b map[string]id.ID) []string {
+	out := []string{}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// diffStrings aligns the sequences a and b using the Myers difference
+// algorithm, and returns the indices of the elements of a removed and of the
+// elements of b added to turn a into b. If more than maxEdits elements need to
+// be removed or added, diffStrings returns false.
+func diffStrings(a, b []string, maxEdits int) (removed, added []int, ok bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := [][]int{}
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v...))
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				removed, added = backtrackEdits(trace, offset, n, m)
+				return removed, added, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// backtrackEdits walks back the trace of diffStrings from the end of both
+// sequences, returning the removed and added indices in increasing order.
+func backtrackEdits(trace [][]int, offset, x, y int) (removed, added []int) {
+	for d := len(trace) - 1; d > 0; d-- {
+		v, k := trace[d], x-y
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+		}
+		if prevK == k+1 {
+			added = append(added, prevY)
+		} else {
+			removed = append(removed, prevX)
+		}
+		x, y = prevX, prevY
+	}
+	reverse(removed)
+	reverse(added)
+	return removed, added
+}
+
+func reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+)
+
+func TestDiffStrings(t *testing.T) {
+	ctx := log.Testing(t)
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+	for _, test := range []struct {
+		name    string
+		a, b    string
+		max     int
+		removed []int
+		added   []int
+		ok      bool
+	}{
+		{"empty", "", "", 10, nil, nil, true},
+		{"identical", "a b c", "a b c", 10, nil, nil, true},
+		{"removed", "a b c", "a c", 10, []int{1}, nil, true},
+		{"added", "a c", "a b c", 10, nil, []int{1}, true},
+		{"replaced", "a b c", "a x c", 10, []int{1}, []int{1}, true},
+		{"mixed", "a b c a b b a", "c b a b a c", 10, []int{0, 1, 5}, []int{1, 5}, true},
+		{"too many edits", "a b c", "d e f", 3, nil, nil, false},
+	} {
+		ctx := log.Enter(ctx, test.name)
+		removed, added, ok := diffStrings(split(test.a), split(test.b), test.max)
+		assert.With(ctx).That(ok).Equals(test.ok)
+		assert.With(ctx).ThatSlice(removed).Equals(test.removed)
+		assert.With(ctx).ThatSlice(added).Equals(test.added)
+	}
+}
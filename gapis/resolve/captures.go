@@ -0,0 +1,76 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/service"
+)
+
+// LoadedCaptures returns all the captures loaded by the server, with the size
+// of the data observed by each capture. The values resolved from the captures
+// are not accounted. As the database is content addressed, the data observed
+// by several captures is shared, and is accounted to each of them.
+func LoadedCaptures(ctx context.Context) (*service.LoadedCaptures, error) {
+	paths := capture.Captures()
+	out := &service.LoadedCaptures{List: make([]*service.CaptureMemoryUsage, len(paths))}
+	resources := make([]map[id.ID]uint64, len(paths))
+	users := map[id.ID]int{}
+	for i, p := range paths {
+		c, err := capture.ResolveFromPath(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		list, err := c.Atoms(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sizes := map[id.ID]uint64{}
+		for _, a := range list.Atoms {
+			if o := a.Extras().Observations(); o != nil {
+				for _, r := range o.Reads {
+					sizes[r.ID] = r.Range.Size
+				}
+				for _, w := range o.Writes {
+					sizes[w.ID] = w.Range.Size
+				}
+			}
+		}
+		for id := range sizes {
+			users[id]++
+		}
+		resources[i] = sizes
+		out.List[i] = &service.CaptureMemoryUsage{
+			Capture:  p,
+			Name:     c.Name,
+			Commands: uint64(len(list.Atoms)),
+		}
+	}
+
+	for i, sizes := range resources {
+		usage := out.List[i]
+		usage.Resources = uint64(len(sizes))
+		for id, size := range sizes {
+			usage.ResourceBytes += size
+			if users[id] > 1 {
+				usage.SharedBytes += size
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi/test"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestLoadedCaptures(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	a := device.Little32
+	at := memory.Pointer{Address: 0x100000, Pool: memory.ApplicationPool}
+	sharedRng, sharedID := atom.Data(ctx, a, at, []uint8{1, 2, 3, 4, 5, 6, 7, 8})
+	ownRng, ownID := atom.Data(ctx, a, at, []uint8{9, 10, 11, 12})
+
+	first, err := capture.ImportAtomList(ctx, "first", &atom.List{Atoms: []atom.Atom{
+		test.NewCmdClone(at, 8).AddRead(sharedRng, sharedID),
+		test.NewCmdClone(at, 8).AddRead(sharedRng, sharedID),
+	}})
+	if !assert.For(ctx, "Import first").ThatError(err).Succeeded() {
+		return
+	}
+	second, err := capture.ImportAtomList(ctx, "second", &atom.List{Atoms: []atom.Atom{
+		test.NewCmdClone(at, 8).AddRead(sharedRng, sharedID),
+		test.NewCmdClone(at, 4).AddRead(ownRng, ownID),
+		test.NewCmdVoid(),
+	}})
+	if !assert.For(ctx, "Import second").ThatError(err).Succeeded() {
+		return
+	}
+	// Loading the same capture again does not list it twice.
+	_, err = capture.ImportAtomList(ctx, "second", &atom.List{Atoms: []atom.Atom{
+		test.NewCmdClone(at, 8).AddRead(sharedRng, sharedID),
+		test.NewCmdClone(at, 4).AddRead(ownRng, ownID),
+		test.NewCmdVoid(),
+	}})
+	if !assert.For(ctx, "Import second again").ThatError(err).Succeeded() {
+		return
+	}
+
+	loaded, err := LoadedCaptures(ctx)
+	if !assert.For(ctx, "LoadedCaptures").ThatError(err).Succeeded() {
+		return
+	}
+	// The captures are listed globally, other tests may have loaded some.
+	usages := map[string][]*service.CaptureMemoryUsage{}
+	for _, u := range loaded.List {
+		id := u.Capture.Id.ID()
+		if id == first.Id.ID() || id == second.Id.ID() {
+			usages[u.Name] = append(usages[u.Name], u)
+		}
+	}
+	for _, expected := range []struct {
+		name                       string
+		commands, resources        uint64
+		resourceBytes, sharedBytes uint64
+	}{
+		{"first", 2, 1, 8, 8},
+		{"second", 3, 2, 12, 8},
+	} {
+		ctx := log.Enter(ctx, expected.name)
+		if !assert.With(ctx).ThatSlice(usages[expected.name]).IsLength(1) {
+			continue
+		}
+		u := usages[expected.name][0]
+		assert.With(ctx).That(u.Commands).Equals(expected.commands)
+		assert.With(ctx).That(u.Resources).Equals(expected.resources)
+		assert.With(ctx).That(u.ResourceBytes).Equals(expected.resourceBytes)
+		assert.With(ctx).That(u.SharedBytes).Equals(expected.sharedBytes)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/framework/binary"
+	"github.com/google/gapid/framework/binary/cyclic"
+	"github.com/google/gapid/framework/binary/vle"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+const (
+	// checkpointInterval is the maximum number of commands between two state
+	// checkpoints.
+	checkpointInterval = 1000
+	// frameCheckpointInterval is the minimum number of commands between two
+	// state checkpoints for a checkpoint to be taken at the start of a frame.
+	frameCheckpointInterval = checkpointInterval / 4
+)
+
+// StateCheckpoints holds copies of the global state at regular points of a
+// capture, so that the state after any command can be built without mutating
+// all the commands before it.
+type StateCheckpoints struct {
+	list []*stateCheckpoint // Ordered by count.
+}
+
+// stateCheckpoint is a frozen copy of the global state after mutating the
+// first count commands of a capture.
+type stateCheckpoint struct {
+	count      uint64
+	layout     *device.MemoryLayout
+	memory     map[memory.PoolID]*memory.Pool
+	nextPoolID memory.PoolID
+	allocator  memory.Allocator
+	apis       map[gfxapi.API][]byte // Encoded API states.
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *StateCheckpointsResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &StateCheckpoints{}
+	s := c.NewState()
+	last := 0
+	for i, a := range list.Atoms {
+		a.Mutate(ctx, s, nil /* no builder, just mutate */)
+		count := i + 1
+		since := count - last
+		if since < checkpointInterval && !(a.AtomFlags().IsEndOfFrame() && since >= frameCheckpointInterval) {
+			continue
+		}
+		cp, err := newStateCheckpoint(uint64(count), s)
+		if err != nil {
+			// Keep the checkpoints built so far, later states are mutated
+			// from the last of them.
+			log.W(ctx, "Could not checkpoint the state after command %d: %v", i, err)
+			break
+		}
+		out.list = append(out.list, cp)
+		last = count
+	}
+	return out, nil
+}
+
+// DatabaseSize implements database.Sizer. The encoded API states, the memory
+// pools and the allocators of the checkpoints are counted.
+func (c *StateCheckpoints) DatabaseSize() uint64 {
+	size := uint64(0)
+	pools := []*memory.Pool{}
+	for _, cp := range c.list {
+		for _, data := range cp.apis {
+			size += uint64(len(data))
+		}
+		for _, pool := range cp.memory {
+			pools = append(pools, pool)
+		}
+		if cp.allocator != nil {
+			size += memory.AllocatorSize(cp.allocator)
+		}
+	}
+	return size + memory.HostMemorySize(pools...)
+}
+
+// closest returns the last checkpoint taken after at most count commands, or
+// nil if there is none.
+func (c *StateCheckpoints) closest(count uint64) *stateCheckpoint {
+	var out *stateCheckpoint
+	for _, cp := range c.list {
+		if cp.count > count {
+			break
+		}
+		out = cp
+	}
+	return out
+}
+
+func newStateCheckpoint(count uint64, s *gfxapi.State) (*stateCheckpoint, error) {
+	cp := &stateCheckpoint{
+		count:      count,
+		layout:     s.MemoryLayout,
+		memory:     make(map[memory.PoolID]*memory.Pool, len(s.Memory)),
+		nextPoolID: s.NextPoolID,
+		apis:       make(map[gfxapi.API][]byte, len(s.APIs)),
+	}
+	for id, pool := range s.Memory {
+		cp.memory[id] = pool.Clone()
+	}
+	if s.Allocator != nil {
+		cp.allocator = s.Allocator.Clone()
+	}
+	for api, state := range s.APIs {
+		var b bytes.Buffer
+		e := cyclic.Encoder(vle.Writer(&b))
+		e.Variant(state)
+		if err := e.Error(); err != nil {
+			return nil, err
+		}
+		cp.apis[api] = b.Bytes()
+	}
+	return cp, nil
+}
+
+// restore returns a new state holding a copy of the checkpointed state.
+func (cp *stateCheckpoint) restore() (*gfxapi.State, error) {
+	var allocator memory.Allocator
+	if cp.allocator != nil {
+		allocator = cp.allocator.Clone()
+	}
+	s := gfxapi.NewStateWithAllocator(allocator)
+	s.MemoryLayout = cp.layout
+	s.NextPoolID = cp.nextPoolID
+	for id, pool := range cp.memory {
+		s.Memory[id] = pool.Clone()
+	}
+	for api, data := range cp.apis {
+		d := cyclic.Decoder(vle.Reader(bytes.NewReader(data)))
+		state := d.Variant()
+		if err := d.Error(); err != nil {
+			return nil, err
+		}
+		s.APIs[api] = state
+	}
+	return s, nil
+}
+
+// mutatedState returns a new global state of the capture c, after mutating
+// the first count of its atoms. The state is built from the closest state
+// checkpoint.
+func mutatedState(ctx context.Context, c *path.Capture, atoms []atom.Atom, count uint64) (*gfxapi.State, error) {
+	var s *gfxapi.State
+	start := uint64(0)
+	if count >= frameCheckpointInterval {
+		obj, err := database.Build(ctx, &StateCheckpointsResolvable{Capture: c})
+		if err != nil {
+			return nil, err
+		}
+		if cp := obj.(*StateCheckpoints).closest(count); cp != nil {
+			if s, err = cp.restore(); err != nil {
+				log.W(ctx, "Could not restore the state checkpoint after command %d: %v", cp.count-1, err)
+				s = nil
+			} else {
+				start = cp.count
+			}
+		}
+	}
+	if s == nil {
+		s = capture.NewState(ctx)
+	}
+	for _, a := range atoms[start:count] {
+		a.Mutate(ctx, s, nil /* no builder, just mutate */)
+	}
+	return s, nil
+}
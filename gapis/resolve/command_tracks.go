@@ -0,0 +1,129 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/core/math/interval"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// CommandTracks resolves the partition of the commands of the capture c by
+// issuing thread, by context and by queue.
+func CommandTracks(ctx context.Context, c *path.Capture) (*service.CommandTracks, error) {
+	obj, err := database.Build(ctx, &CommandTracksResolvable{c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.CommandTracks), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *CommandTracksResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	obj, err := database.Build(ctx, &CommandIndexResolvable{r.Capture})
+	if err != nil {
+		return nil, err
+	}
+	index := obj.(*commandIndex)
+
+	out := &service.CommandTracks{}
+
+	threads := trackBuilder{}
+	queues := trackBuilder{}
+	for i, a := range index.atoms {
+		thread := index.threads[i]
+		if i > 0 && thread != index.threads[i-1] {
+			out.ThreadSwitches++
+		}
+		threads.add(thread, fmt.Sprintf("Thread %d", thread), uint64(i))
+		if queue, ok := commandQueue(a); ok {
+			queues.add(queue, fmt.Sprintf("Queue 0x%x", queue), uint64(i))
+		}
+	}
+	out.Threads = threads.tracks()
+	out.Queues = queues.tracks()
+
+	contexts, err := Contexts(ctx, r.Capture.Contexts())
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range contexts {
+		track := &service.CommandTrack{Name: c.Name, Context: c.Id, Ranges: c.Ranges}
+		for _, r := range c.Ranges {
+			track.Commands += r.Count
+		}
+		out.Contexts = append(out.Contexts, track)
+	}
+	return out, nil
+}
+
+// commandQueue returns the handle of the queue the command a is issued to,
+// if a has a queue parameter.
+func commandQueue(a atom.Atom) (uint64, bool) {
+	v, ok := commandParameter(a, "Queue")
+	if !ok {
+		return 0, false
+	}
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), true
+	default:
+		return 0, false
+	}
+}
+
+// trackBuilder builds the tracks of commands identified by a handle, in the
+// order of their first command.
+type trackBuilder struct {
+	order  []uint64
+	names  map[uint64]string
+	ranges map[uint64]*atom.RangeList
+}
+
+func (b *trackBuilder) add(handle uint64, name string, i uint64) {
+	if b.ranges == nil {
+		b.names, b.ranges = map[uint64]string{}, map[uint64]*atom.RangeList{}
+	}
+	ranges, ok := b.ranges[handle]
+	if !ok {
+		ranges = &atom.RangeList{}
+		b.order = append(b.order, handle)
+		b.names[handle], b.ranges[handle] = name, ranges
+	}
+	interval.Merge(ranges, interval.U64Span{Start: i, End: i + 1}, true)
+}
+
+func (b *trackBuilder) tracks() []*service.CommandTrack {
+	out := make([]*service.CommandTrack, len(b.order))
+	for i, handle := range b.order {
+		ranges := b.ranges[handle]
+		track := &service.CommandTrack{
+			Name:   b.names[handle],
+			Handle: handle,
+			Ranges: service.NewCommandRangeList(*ranges),
+		}
+		for _, r := range *ranges {
+			track.Commands += r.End - r.Start
+		}
+		out[i] = track
+	}
+	return out
+}
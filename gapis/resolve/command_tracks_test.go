@@ -0,0 +1,41 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestTrackBuilder(t *testing.T) {
+	ctx := log.Testing(t)
+	b := trackBuilder{}
+	for i, thread := range []uint64{7, 7, 3, 7, 3, 3} {
+		b.add(thread, "", uint64(i))
+	}
+	tracks := b.tracks()
+	assert.With(ctx).ThatSlice(tracks).IsLength(2)
+	assert.For(ctx, "first").That(tracks[0].Handle).Equals(uint64(7))
+	assert.For(ctx, "first commands").That(tracks[0].Commands).Equals(uint64(3))
+	assert.For(ctx, "first ranges").ThatSlice(tracks[0].Ranges).DeepEquals([]*service.CommandRange{
+		{First: 0, Count: 2},
+		{First: 3, Count: 1},
+	})
+	assert.For(ctx, "second").That(tracks[1].Handle).Equals(uint64(3))
+	assert.For(ctx, "second commands").That(tracks[1].Commands).Equals(uint64(3))
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/crashdump"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// CrashDump correlates the GPU crash dump held in data with the commands and
+// resources of the capture c.
+func CrashDump(ctx context.Context, c *path.Capture, data []byte) (*service.CrashDumpCorrelation, error) {
+	obj, err := database.Build(ctx, &CrashDumpResolvable{c, data})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.CrashDumpCorrelation), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *CrashDumpResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	dump, err := crashdump.Parse(r.Data)
+	if err != nil {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidCrashDump(err.Error())}
+	}
+
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	faulting := map[uint64]bool{}
+	for _, r := range dump.Faulting() {
+		faulting[r.Handle] = true
+	}
+
+	resources := []*service.Resource{}
+	seen := map[gfxapi.Resource]int{}
+	lastAccess := uint64(notStarted)
+
+	var currentAtomIndex uint64
+	var currentAtomResourceCount int
+
+	s := c.NewState()
+	s.OnResourceCreated = func(r gfxapi.Resource) {
+		// Keep the resource identifiers in sync with the Resources resolvable.
+		currentAtomResourceCount++
+		if h, ok := parseResourceHandle(r.ResourceHandle()); ok && faulting[h] {
+			seen[r] = len(resources)
+			resources = append(resources, &service.Resource{
+				Id:       path.NewID(genResourceID(currentAtomIndex, currentAtomResourceCount)),
+				Handle:   r.ResourceHandle(),
+				Label:    r.ResourceLabel(),
+				Order:    r.Order(),
+				Accesses: []uint64{currentAtomIndex},
+			})
+			lastAccess = currentAtomIndex
+		}
+	}
+	s.OnResourceAccessed = func(r gfxapi.Resource) {
+		if index, ok := seen[r]; ok {
+			c := len(resources[index].Accesses)
+			if c == 0 || resources[index].Accesses[c-1] != currentAtomIndex {
+				resources[index].Accesses = append(resources[index].Accesses, currentAtomIndex)
+			}
+			lastAccess = currentAtomIndex
+		}
+	}
+
+	// Find the last span of commands where the stack of user markers matches
+	// the markers reported by the crash dump.
+	crash := atom.Range{}
+	markers := []userMarker{}
+	matches := func() bool {
+		if len(dump.Markers) == 0 || len(markers) != len(dump.Markers) {
+			return false
+		}
+		for i, m := range markers {
+			if m.name != dump.Markers[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i, a := range list.Atoms {
+		currentAtomResourceCount = 0
+		currentAtomIndex = uint64(i)
+		a.Mutate(ctx, s, nil /* no builder, just mutate */)
+
+		flags := a.AtomFlags()
+		if flags.IsPushUserMarker() {
+			marker := userMarker{start: uint64(i)}
+			if labeled, ok := a.(atom.Labeled); ok {
+				marker.name = labeled.Label(ctx, s)
+			}
+			markers = append(markers, marker)
+		}
+		if flags.IsPopUserMarker() {
+			if c := len(markers); c > 0 {
+				if matches() {
+					crash = atom.Range{Start: markers[c-1].start, End: uint64(i) + 1}
+				}
+				markers = markers[:c-1]
+			}
+		}
+	}
+	if matches() {
+		// The crash happened within a marker that was never popped.
+		crash = atom.Range{Start: markers[len(markers)-1].start, End: uint64(len(list.Atoms))}
+	}
+
+	name := "GPU crash"
+	switch {
+	case crash.Length() > 0:
+		name = "GPU crash: " + strings.Join(dump.Markers, " / ")
+	case lastAccess != notStarted:
+		// No marker matched, fall back to the last use of a faulting resource.
+		crash = atom.Range{Start: lastAccess, End: lastAccess + 1}
+	default:
+		return nil, &service.ErrDataUnavailable{Reason: messages.ErrCrashNotLocated()}
+	}
+
+	root := atom.Group{Range: atom.Range{End: uint64(len(list.Atoms))}}
+	root.SubGroups.Add(crash.Start, crash.End, name)
+
+	return &service.CrashDumpCorrelation{
+		FaultAddress: dump.FaultAddress,
+		Markers:      dump.Markers,
+		Range:        &service.CommandRange{First: crash.Start, Count: crash.Length()},
+		Resources:    resources,
+		Hierarchy:    service.NewHierarchy("GPU crash", id.ID{}, root),
+	}, nil
+}
+
+// parseResourceHandle returns the API handle from a resource handle string of
+// the form "Type<handle>", where handle is in decimal or 0x prefixed hex.
+func parseResourceHandle(s string) (uint64, bool) {
+	start, end := strings.IndexRune(s, '<'), strings.LastIndex(s, ">")
+	if start < 0 || end < start {
+		return 0, false
+	}
+	h, err := strconv.ParseUint(s[start+1:end], 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return h, true
+}
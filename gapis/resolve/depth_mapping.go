@@ -0,0 +1,77 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+// depthMappingFormat returns the format of a depth attachment of the format f
+// once remapped with m.
+func depthMappingFormat(f *image.Format, m service.DepthMapping) *image.Format {
+	if m == service.DepthMapping_RawDepth {
+		return f
+	}
+	return image.D_F32
+}
+
+// remapDepth returns the depth image img remapped with m, using the near and
+// far values of the mapping. The values mapped outside of [0, 1] are clamped.
+func remapDepth(img *image.Image2D, m service.DepthMapping, near, far float32) (*image.Image2D, error) {
+	var remap func(d float32) float32
+	switch m {
+	case service.DepthMapping_RawDepth:
+		return img, nil
+	case service.DepthMapping_RangeDepth:
+		remap = func(d float32) float32 { return (d - near) / (far - near) }
+	case service.DepthMapping_LinearDepth:
+		if near <= 0 {
+			return nil, &service.ErrInvalidArgument{Reason: messages.ErrMessage("The near plane of a linear depth mapping must be positive")}
+		}
+		remap = func(d float32) float32 {
+			z := near * far / (far - d*(far-near))
+			return (z - near) / (far - near)
+		}
+	default:
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidEnumValue(m, "DepthMapping")}
+	}
+	if near == far {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrMessage("The near and far values of a depth mapping must differ")}
+	}
+
+	img, err := img.Convert(image.D_F32)
+	if err != nil {
+		return nil, err
+	}
+	r := endian.Reader(bytes.NewReader(img.Data), device.LittleEndian)
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	for i, c := 0, len(img.Data)/4; i < c; i++ {
+		d := remap(r.Float32())
+		w.Float32(float32(math.Min(math.Max(float64(d), 0), 1)))
+	}
+	if err := r.Error(); err != nil {
+		return nil, err
+	}
+	img.Data = buf.Bytes()
+	return img, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/service"
+)
+
+func depthImage(depths ...float32) *image.Image2D {
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	for _, d := range depths {
+		w.Float32(d)
+	}
+	return &image.Image2D{Data: buf.Bytes(), Width: uint32(len(depths)), Height: 1, Format: image.D_F32}
+}
+
+func depths(img *image.Image2D) []float32 {
+	r := endian.Reader(bytes.NewReader(img.Data), device.LittleEndian)
+	out := make([]float32, img.Width*img.Height)
+	for i := range out {
+		out[i] = r.Float32()
+	}
+	return out
+}
+
+func TestRemapDepth(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, test := range []struct {
+		name      string
+		mapping   service.DepthMapping
+		near, far float32
+		expected  []float32
+	}{
+		{"raw", service.DepthMapping_RawDepth, 0, 0, []float32{0, 0.5, 1}},
+		{"range", service.DepthMapping_RangeDepth, 0.25, 0.75, []float32{0, 0.5, 1}},
+		{"clamped range", service.DepthMapping_RangeDepth, 0.5, 0.75, []float32{0, 0, 1}},
+		// With near 1 and far 3, a depth of 0.5 is at a distance of 1.5.
+		{"linear", service.DepthMapping_LinearDepth, 1, 3, []float32{0, 0.25, 1}},
+	} {
+		ctx := log.V{"test": test.name}.Bind(ctx)
+		got, err := remapDepth(depthImage(0, 0.5, 1), test.mapping, test.near, test.far)
+		if assert.For(ctx, "err").ThatError(err).Succeeded() {
+			assert.For(ctx, "depths").That(depths(got)).DeepEquals(test.expected)
+		}
+	}
+
+	_, err := remapDepth(depthImage(0.5), service.DepthMapping_RangeDepth, 1, 1)
+	assert.For(ctx, "empty range").ThatError(err).Failed()
+}
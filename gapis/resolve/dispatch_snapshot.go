@@ -0,0 +1,79 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// DispatchSnapshot returns the content of the storage resources bound to the
+// compute dispatch command c, before and after it executed, replayed on the
+// given device.
+func DispatchSnapshot(ctx context.Context, device *path.Device, c *path.Command) (*service.DispatchSnapshot, error) {
+	obj, err := database.Build(ctx, &DispatchSnapshotResolvable{Device: device, Command: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.DispatchSnapshot), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *DispatchSnapshotResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	intent := replay.Intent{
+		Device:  r.Device,
+		Capture: r.Command.Commands.Capture,
+	}
+
+	a, err := Command(ctx, r.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	api := a.API()
+	if api == nil {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrMessage("The command does not belong to a graphics API"),
+		}
+	}
+
+	query, ok := api.(replay.QueryDispatchSnapshot)
+	if !ok {
+		return nil, &service.ErrDataUnavailable{
+			Reason: messages.ErrMessage(fmt.Sprintf("API %s does not support dispatch snapshots", api.Name())),
+		}
+	}
+
+	mgr := replay.GetManager(ctx)
+	res, err := query.QueryDispatchSnapshot(ctx, intent, mgr, atom.ID(r.Command.Index), nil)
+	if err != nil {
+		if _, ok := err.(*service.ErrDataUnavailable); ok {
+			return nil, err
+		}
+		if _, ok := err.(*service.ErrInvalidArgument); ok {
+			return nil, err
+		}
+		return nil, log.Err(ctx, err, "Couldn't get the dispatch snapshot")
+	}
+	return res, nil
+}
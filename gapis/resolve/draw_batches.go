@@ -0,0 +1,68 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+// drawBatches returns the draws of the capture c that could be merged, for all
+// the APIs of the capture. ctx must hold the capture.
+func drawBatches(ctx context.Context, c *capture.Capture) ([]atom.DrawBatch, error) {
+	out := []atom.DrawBatch{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if b, ok := api.(atom.DrawBatcher); ok {
+			batches, err := b.DrawBatches(ctx)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, batches...)
+		}
+	}
+	return out, nil
+}
+
+// drawBatchItems returns the report items suggesting to merge the draws of
+// each batch, on the first draw of the batch. Merging a batch saves all the
+// commands of the batch but the merged draw.
+func drawBatchItems(atoms []atom.Atom, batches []atom.DrawBatch) []*service.ReportItemRaw {
+	out := make([]*service.ReportItemRaw, 0, len(batches))
+	for _, b := range batches {
+		first := b.Draws[0]
+		draws, saved := uint32(len(b.Draws)), b.Commands-1
+		m := messages.WarnDrawBatch(draws, saved)
+		if b.Instanceable {
+			m = messages.WarnDrawBatchInstanced(draws, saved)
+		}
+		item := service.WrapReportItem(
+			&service.ReportItem{
+				Severity: service.Severity_WarningLevel,
+				Command:  uint64(first),
+			}, m)
+		item.Tags = append(item.Tags, messages.TagBatching())
+		if int(first) < len(atoms) {
+			item.Tags = append(item.Tags, getAtomNameTag(atoms[first]))
+		}
+		out = append(out, item)
+	}
+	return out
+}
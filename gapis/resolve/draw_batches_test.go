@@ -0,0 +1,45 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestDrawBatchItems(t *testing.T) {
+	ctx := log.Testing(t)
+	items := drawBatchItems(nil, []atom.DrawBatch{
+		{Draws: []atom.ID{3, 5, 7}, Commands: 5},
+		{Draws: []atom.ID{10, 11}, Commands: 2, Instanceable: true},
+	})
+	assert.For(ctx, "items").That(len(items)).Equals(2)
+
+	merge := items[0]
+	assert.For(ctx, "merge command").That(merge.Item.Command).Equals(uint64(3))
+	assert.For(ctx, "merge severity").That(merge.Item.Severity).Equals(service.Severity_WarningLevel)
+	assert.For(ctx, "merge message").That(merge.Message.Identifier).Equals(messages.WarnDrawBatch(0, 0).Identifier)
+	assert.For(ctx, "merge tags").That(len(merge.Tags)).Equals(1)
+	assert.For(ctx, "merge tag").That(merge.Tags[0].Identifier).Equals(messages.TagBatching().Identifier)
+
+	instanced := items[1]
+	assert.For(ctx, "instanced command").That(instanced.Item.Command).Equals(uint64(10))
+	assert.For(ctx, "instanced message").That(instanced.Message.Identifier).Equals(messages.WarnDrawBatchInstanced(0, 0).Identifier)
+}
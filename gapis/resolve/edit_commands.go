@@ -0,0 +1,99 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// EditCommands builds a new capture from the capture p, with the command
+// parameters overridden by edits, and returns the path to the new capture.
+// The edits are held by the new capture as a sparse list applied to the atoms
+// of p when the atom list is resolved.
+func EditCommands(ctx context.Context, p *path.Capture, edits []*service.CommandEdit) (*path.Capture, error) {
+	ctx = capture.Put(ctx, p)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &EditedCommandsResolvable{Capture: p, Edits: edits}
+	if _, err := database.Build(ctx, r); err != nil {
+		return nil, err
+	}
+	id, err := database.Store(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%v [edited]", c.Name)
+	return c.Derive(ctx, name, id)
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *EditedCommandsResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	edited := list.Clone()
+	copied := map[uint64]bool{}
+	for _, e := range r.Edits {
+		p := r.Capture.Commands().Index(e.Command).Parameter(e.Parameter)
+		if count := uint64(len(edited.Atoms)); e.Command >= count {
+			return nil, &service.ErrInvalidPath{
+				Reason: messages.ErrValueOutOfBounds(e.Command, "Index", uint64(0), count-1),
+				Path:   p.Path(),
+			}
+		}
+
+		// The atoms are shared with the original capture, so each edited atom
+		// is copied once.
+		if !copied[e.Command] {
+			obj, err := clone(reflect.ValueOf(edited.Atoms[e.Command]))
+			if err != nil {
+				return nil, err
+			}
+			edited.Atoms[e.Command] = obj.Interface().(atom.Atom)
+			copied[e.Command] = true
+		}
+
+		f, err := field(ctx, reflect.ValueOf(edited.Atoms[e.Command]), e.Parameter, p)
+		if err != nil {
+			return nil, err
+		}
+		if err := assign(f, reflect.ValueOf(e.Value.Get())); err != nil {
+			return nil, err
+		}
+	}
+	return edited, nil
+}
@@ -0,0 +1,69 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/pod"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestEditCommands(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+	atomA := &testAtom{api: testAPI{}.ID(), Str: "aaa"}
+	atomB := &testAtom{api: testAPI{}.ID(), Str: "bbb"}
+	p := newPathTest(ctx, atom.NewList(atomA, atomB))
+
+	edited, err := EditCommands(ctx, p, []*service.CommandEdit{
+		{Command: 1, Parameter: "Str", Value: pod.NewValue("xxx")},
+		{Command: 1, Parameter: "Any", Value: pod.NewValue(int32(42))},
+	})
+	if !assert.For(ctx, "err").ThatError(err).Succeeded() {
+		return
+	}
+
+	c, err := capture.ResolveFromPath(ctx, edited)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	list, err := c.Atoms(ctx)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "unedited").That(list.Atoms[0].(*testAtom).Str).Equals("aaa")
+	assert.For(ctx, "edited").That(list.Atoms[1].(*testAtom).Str).Equals("xxx")
+	assert.For(ctx, "edited").That(list.Atoms[1].(*testAtom).Any).Equals(int32(42))
+	assert.For(ctx, "original").That(atomB.Str).Equals("bbb")
+
+	_, err = EditCommands(ctx, p, []*service.CommandEdit{
+		{Command: 5, Parameter: "Str", Value: pod.NewValue("xxx")},
+	})
+	assert.For(ctx, "out of bounds").ThatError(err).DeepEquals(&service.ErrInvalidPath{
+		Reason: messages.ErrValueOutOfBounds(uint64(5), "Index", uint64(0), uint64(1)),
+		Path:   p.Commands().Index(5).Parameter("Str").Path(),
+	})
+
+	_, err = EditCommands(ctx, p, []*service.CommandEdit{
+		{Command: 0, Parameter: "doesnotexist", Value: pod.NewValue("xxx")},
+	})
+	assert.For(ctx, "missing parameter").ThatError(err).DeepEquals(&service.ErrInvalidPath{
+		Reason: messages.ErrFieldDoesNotExist("testAtom", "doesnotexist"),
+		Path:   p.Commands().Index(0).Parameter("doesnotexist").Path(),
+	})
+}
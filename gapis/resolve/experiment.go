@@ -0,0 +1,101 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/experiments"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// Experiment runs the experiment described by r. It returns the capture
+// holding the modified commands and the path to the color buffer after the
+// modified equivalent of the requested command.
+// Failures of the experiment itself are reported in the result's Error field.
+func Experiment(ctx context.Context, r *service.ExperimentRequest) (*service.ExperimentResult, error) {
+	if r.After == nil || r.After.Commands == nil {
+		return nil, fmt.Errorf("Experiment request is missing the command to inspect")
+	}
+
+	result := &service.ExperimentResult{
+		Request:   r,
+		Timestamp: time.Now().Unix(),
+	}
+
+	obj, err := database.Build(ctx, &ExperimentResolvable{r})
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	after := obj.(*path.Command)
+
+	image, err := FramebufferAttachment(ctx,
+		r.Device,
+		after,
+		gfxapi.FramebufferAttachment_Color0,
+		&service.RenderSettings{},
+		&service.UsageHints{},
+	)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.Capture = after.Commands.Capture
+	result.Image = image
+	return result, nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *ExperimentResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	req := r.Request
+	e := experiments.Find(req.Name)
+	if e == nil {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrUnknownExperiment(req.Name)}
+	}
+
+	ctx = capture.Put(ctx, req.After.Commands.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := e.Apply(ctx, c, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	index, ok := modified.Index(atom.ID(req.After.Index))
+	if !ok {
+		return nil, &service.ErrDataUnavailable{Reason: messages.ErrFramebufferUnavailable()}
+	}
+
+	name := fmt.Sprintf("%v [%v]", c.Name, e.Name)
+	p, err := capture.ImportAtomList(ctx, name, modified.Atoms)
+	if err != nil {
+		return nil, err
+	}
+	return p.Commands().Index(index), nil
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// exportBatchSize is the maximum number of commands passed to the handler of
+// ExportCommands at once.
+const exportBatchSize = 256
+
+// ExportCommands calls handler with the commands of the capture c decoded for
+// the tools not linking against GAPIS, in increasing order and in batches. If
+// data is true, the observations hold the observed memory.
+func ExportCommands(ctx context.Context, c *path.Capture, data bool, handler func(*service.ExportedCommands) error) error {
+	obj, err := database.Build(ctx, &CommandIndexResolvable{c})
+	if err != nil {
+		return err
+	}
+	index := obj.(*commandIndex)
+
+	batch := &service.ExportedCommands{}
+	for i, a := range index.atoms {
+		if task.Stopped(ctx) {
+			return task.StopReason(ctx)
+		}
+		cmd, err := exportCommand(ctx, a, data)
+		if err != nil {
+			return err
+		}
+		cmd.Index, cmd.Thread = uint64(i), index.threads[i]
+		if batch.Commands = append(batch.Commands, cmd); len(batch.Commands) == exportBatchSize {
+			if err := handler(batch); err != nil {
+				return err
+			}
+			batch = &service.ExportedCommands{}
+		}
+	}
+	if len(batch.Commands) > 0 {
+		return handler(batch)
+	}
+	return nil
+}
+
+// exportCommand returns the atom a decoded, without its index and thread.
+func exportCommand(ctx context.Context, a atom.Atom, data bool) (*service.ExportedCommand, error) {
+	cmd := &service.ExportedCommand{Name: a.Class().Schema().Name()}
+	if api := a.API(); api != nil {
+		cmd.Api = api.Name()
+	}
+
+	if v := reflect.Indirect(reflect.ValueOf(a)); v.Kind() == reflect.Struct {
+		t := v.Type()
+		for i, c := 0, t.NumField(); i < c; i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Anonymous {
+				continue
+			}
+			p := &service.ExportedParameter{
+				Name:  f.Name,
+				Type:  f.Type.Name(),
+				Value: exportValue(v.Field(i)),
+			}
+			if f.Name == "Result" {
+				cmd.Result = p
+			} else {
+				cmd.Parameters = append(cmd.Parameters, p)
+			}
+		}
+	}
+
+	if e := a.Extras(); e != nil {
+		if o := e.Observations(); o != nil {
+			var err error
+			if cmd.Reads, err = exportObservations(ctx, o.Reads, data); err != nil {
+				return nil, err
+			}
+			if cmd.Writes, err = exportObservations(ctx, o.Writes, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cmd, nil
+}
+
+// exportValue returns the parameter value v decoded.
+func exportValue(v reflect.Value) *service.ExportedValue {
+	out := &service.ExportedValue{}
+	switch v.Kind() {
+	case reflect.Bool:
+		out.Val = &service.ExportedValue_Bool{Bool: v.Bool()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out.Val = &service.ExportedValue_Int{Int: v.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		out.Val = &service.ExportedValue_Uint{Uint: v.Uint()}
+	case reflect.Float32, reflect.Float64:
+		out.Val = &service.ExportedValue_Float{Float: v.Float()}
+	case reflect.String:
+		out.Val = &service.ExportedValue_String_{String_: v.String()}
+	case reflect.Struct:
+		// Pointers are structs holding an address and a pool.
+		addr, pool := v.FieldByName("Address"), v.FieldByName("Pool")
+		if addr.IsValid() && pool.IsValid() {
+			out.Val = &service.ExportedValue_Pointer{Pointer: &service.ExportedPointer{
+				Address: addr.Uint(),
+				Pool:    uint32(pool.Uint()),
+			}}
+			return out
+		}
+	}
+	if out.Val == nil {
+		out.Val = &service.ExportedValue_String_{String_: fmt.Sprint(v.Interface())}
+		return out
+	}
+	// The numbers of the enum and bitfield types are named by their String.
+	if s, ok := v.Interface().(fmt.Stringer); ok && v.Kind() != reflect.String {
+		out.Constant = s.String()
+	}
+	return out
+}
+
+// exportObservations returns the observations l decoded, with their data if
+// data is true.
+func exportObservations(ctx context.Context, l []atom.Observation, data bool) ([]*service.ExportedObservation, error) {
+	out := make([]*service.ExportedObservation, len(l))
+	for i, o := range l {
+		out[i] = &service.ExportedObservation{
+			Base:     o.Range.Base,
+			Size:     o.Range.Size,
+			Resource: o.ID.String(),
+		}
+		if data {
+			obj, err := database.Resolve(ctx, o.ID)
+			if err != nil {
+				return nil, err
+			}
+			out[i].Data = obj.([]byte)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestExportValue(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, test := range []struct {
+		name     string
+		value    interface{}
+		expected *service.ExportedValue
+	}{
+		{"bool", true, &service.ExportedValue{Val: &service.ExportedValue_Bool{Bool: true}}},
+		{"int", int32(-5), &service.ExportedValue{Val: &service.ExportedValue_Int{Int: -5}}},
+		{"uint", uint16(7), &service.ExportedValue{Val: &service.ExportedValue_Uint{Uint: 7}}},
+		{"float", float32(0.5), &service.ExportedValue{Val: &service.ExportedValue_Float{Float: 0.5}}},
+		{"string", "Pizza", &service.ExportedValue{Val: &service.ExportedValue_String_{String_: "Pizza"}}},
+		{"pointer", memory.Pointer{Address: 0x1000, Pool: 2}, &service.ExportedValue{
+			Val: &service.ExportedValue_Pointer{Pointer: &service.ExportedPointer{Address: 0x1000, Pool: 2}},
+		}},
+		{"other", []int{1, 2}, &service.ExportedValue{Val: &service.ExportedValue_String_{String_: "[1 2]"}}},
+	} {
+		got := exportValue(reflect.ValueOf(test.value))
+		assert.For(ctx, test.name).That(got).DeepEquals(test.expected)
+	}
+}
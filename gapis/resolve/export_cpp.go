@@ -0,0 +1,76 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// ExportCpp resolves the source of a standalone C++ program reproducing the
+// capture c on the given device, and repeating its frame in a loop. Frames
+// are numbered from 1.
+func ExportCpp(ctx context.Context, d *path.Device, c *path.Capture, frame uint32) ([]byte, error) {
+	obj, err := database.Build(ctx, &ExportCppResolvable{
+		Device:  d,
+		Capture: c,
+		Frame:   frame,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.([]byte), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *ExportCppResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := frameEnd(list.Atoms, r.Frame)
+	if err != nil {
+		return nil, err
+	}
+
+	api := list.Atoms[end].API()
+	ec, ok := api.(replay.ExportCpp)
+	if !ok {
+		name := "unknown"
+		if api != nil {
+			name = api.Name()
+		}
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrExportCppNotSupported(name)}
+	}
+
+	intent := replay.Intent{
+		Capture: r.Capture,
+		Device:  r.Device,
+	}
+	return ec.ExportCpp(ctx, intent, replay.GetManager(ctx), end)
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// ExportTexture resolves the texture resource at p and returns all of its
+// mip levels and layers encoded in the container c. The texture data is kept
+// in its captured format when the container can hold it.
+func ExportTexture(ctx context.Context, p *path.ResourceData, c service.TextureContainer) ([]byte, error) {
+	obj, err := ResourceData(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, shape := [][]*image.Info2D{}, image.Texture2DShape
+	switch t := obj.(type) {
+	case *gfxapi.Texture2D:
+		for _, l := range t.Levels {
+			infos = append(infos, []*image.Info2D{l})
+		}
+	case *gfxapi.Texture2DArray:
+		if len(t.Layers) == 0 {
+			return nil, fmt.Errorf("Texture array %v has no layers", p.Id.ID())
+		}
+		for l := range t.Layers[0].Levels {
+			level := make([]*image.Info2D, len(t.Layers))
+			for i, layer := range t.Layers {
+				if l < len(layer.Levels) {
+					level[i] = layer.Levels[l]
+				}
+			}
+			infos = append(infos, level)
+		}
+	case *gfxapi.Cubemap:
+		shape = image.CubemapShape
+		for _, l := range t.Levels {
+			// Containers order the faces +X, -X, +Y, -Y, +Z, -Z.
+			infos = append(infos, []*image.Info2D{
+				l.PositiveX, l.NegativeX,
+				l.PositiveY, l.NegativeY,
+				l.PositiveZ, l.NegativeZ,
+			})
+		}
+	case *gfxapi.Texture3D:
+		shape = image.Texture3DShape
+		for _, l := range t.Levels {
+			infos = append(infos, l.Slices)
+		}
+	default:
+		return nil, fmt.Errorf("Resource %v is not a texture", p.Id.ID())
+	}
+
+	images := make([][]*image.Image2D, len(infos))
+	for i, level := range infos {
+		images[i] = make([]*image.Image2D, len(level))
+		for j, info := range level {
+			if info == nil {
+				return nil, fmt.Errorf("Texture level %d is missing layer %d", i, j)
+			}
+			data, err := database.Resolve(ctx, info.Data.ID())
+			if err != nil {
+				return nil, err
+			}
+			images[i][j] = &image.Image2D{
+				Format: info.Format,
+				Width:  info.Width,
+				Height: info.Height,
+				Data:   data.([]byte),
+			}
+		}
+	}
+
+	switch c {
+	case service.TextureContainer_KTX2:
+		return image.KTX2(images, shape)
+	case service.TextureContainer_DDS:
+		return image.DDS(images, shape)
+	default:
+		return nil, fmt.Errorf("Unsupported texture container %v", c)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// FrameDeltas resolves, for each frame of the capture c, the resources used
+// by the frame and which of them changed content since they were last used.
+func FrameDeltas(ctx context.Context, c *path.Capture) (*service.FrameDeltas, error) {
+	obj, err := database.Build(ctx, &FrameDeltasResolvable{c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.FrameDeltas), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *FrameDeltasResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The content hash of each resource at the end of the last frame using it.
+	hashes := map[gfxapi.Resource]id.ID{}
+
+	// The resources used by the current frame, in order of first use.
+	used := []gfxapi.Resource{}
+	seen := map[gfxapi.Resource]bool{}
+	created := map[gfxapi.Resource]bool{}
+
+	use := func(r gfxapi.Resource) {
+		if !seen[r] {
+			seen[r] = true
+			used = append(used, r)
+		}
+	}
+
+	state := c.NewState()
+	state.OnResourceCreated = func(r gfxapi.Resource) {
+		created[r] = true
+		use(r)
+	}
+	state.OnResourceAccessed = use
+
+	out := &service.FrameDeltas{}
+	start := 0
+	for i, a := range list.Atoms {
+		a.Mutate(ctx, state, nil /* no builder, just mutate */)
+		if !a.AtomFlags().IsEndOfFrame() {
+			continue
+		}
+
+		frame := &service.FrameDelta{
+			Frame:        uint64(len(out.Frames) + 1),
+			FirstCommand: uint64(start),
+			LastCommand:  uint64(i),
+		}
+		types := map[gfxapi.ResourceType]*service.ResourceDelta{}
+		for _, res := range used {
+			ty := res.ResourceType(ctx)
+			delta, ok := types[ty]
+			if !ok {
+				delta = &service.ResourceDelta{Type: ty}
+				types[ty] = delta
+				frame.Types = append(frame.Types, delta)
+			}
+			delta.Used++
+			if created[res] {
+				delta.Created++
+			}
+
+			data, err := res.ResourceData(ctx, state)
+			if err != nil {
+				log.W(ctx, "Could not get the data of %v at command %v: %v", res.ResourceHandle(), i, err)
+				continue
+			}
+			hash, err := database.Hash(data)
+			if err != nil {
+				return nil, err
+			}
+			if prev, ok := hashes[res]; !ok || prev != hash {
+				delta.Changed = append(delta.Changed, res.ResourceHandle())
+			}
+			hashes[res] = hash
+		}
+		out.Frames = append(out.Frames, frame)
+
+		used, seen, created = []gfxapi.Resource{}, map[gfxapi.Resource]bool{}, map[gfxapi.Resource]bool{}
+		start = i + 1
+	}
+
+	return out, nil
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// FrameGraph resolves the graph of the passes executed by the capture c,
+// connected by the resources they write and read.
+func FrameGraph(ctx context.Context, c *path.Capture) (*service.FrameGraph, error) {
+	obj, err := database.Build(ctx, &FrameGraphResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.FrameGraph), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *FrameGraphResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	passes := []atom.FramePass{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if g, ok := api.(atom.FrameGrapher); ok {
+			p, err := g.FramePasses(ctx)
+			if err != nil {
+				return nil, err
+			}
+			passes = append(passes, p...)
+		}
+	}
+	return frameGraph(list.Atoms, passes), nil
+}
+
+// frameGraph connects each pass to the last earlier passes writing the
+// resources it reads.
+func frameGraph(atoms []atom.Atom, passes []atom.FramePass) *service.FrameGraph {
+	out := &service.FrameGraph{}
+	writers := map[atom.FrameResource]uint32{}
+	for i, p := range passes {
+		pass := &service.FramePass{
+			Kind:        service.FramePassKind(p.Kind),
+			First:       uint64(p.First),
+			Last:        uint64(p.Last),
+			Attachments: p.Attachments,
+			Reads:       frameResources(p.Reads),
+			Writes:      frameResources(p.Writes),
+		}
+		if int(p.First) < len(atoms) {
+			pass.Name = atoms[p.First].Class().Schema().Name()
+		}
+		out.Passes = append(out.Passes, pass)
+
+		edges := map[uint32]*service.FrameGraphEdge{}
+		for _, r := range p.Reads {
+			from, ok := writers[r]
+			if !ok {
+				continue
+			}
+			e, ok := edges[from]
+			if !ok {
+				e = &service.FrameGraphEdge{From: from, To: uint32(i)}
+				edges[from] = e
+				out.Edges = append(out.Edges, e)
+			}
+			e.Resources = append(e.Resources, &service.FrameResource{Kind: r.Kind, Handle: r.Handle})
+		}
+		for _, r := range p.Writes {
+			writers[r] = uint32(i)
+		}
+	}
+	return out
+}
+
+func frameResources(l []atom.FrameResource) []*service.FrameResource {
+	out := make([]*service.FrameResource, len(l))
+	for i, r := range l {
+		out[i] = &service.FrameResource{Kind: r.Kind, Handle: r.Handle}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestFrameGraph(t *testing.T) {
+	ctx := log.Testing(t)
+	shadow := atom.FrameResource{Kind: "VkImage", Handle: 1}
+	color := atom.FrameResource{Kind: "VkImage", Handle: 2}
+	lights := atom.FrameResource{Kind: "VkBuffer", Handle: 3}
+	swapchain := atom.FrameResource{Kind: "VkImage", Handle: 4}
+	graph := frameGraph(nil, []atom.FramePass{
+		{Kind: atom.ComputePass, First: 1, Last: 1, Writes: []atom.FrameResource{lights}},
+		{Kind: atom.RenderPass, First: 2, Last: 5, Writes: []atom.FrameResource{shadow},
+			Attachments: []gfxapi.FramebufferAttachment{gfxapi.FramebufferAttachment_Depth}},
+		{Kind: atom.RenderPass, First: 6, Last: 9, Reads: []atom.FrameResource{shadow, lights}, Writes: []atom.FrameResource{color}},
+		{Kind: atom.TransferPass, First: 10, Last: 10, Reads: []atom.FrameResource{color}, Writes: []atom.FrameResource{swapchain}},
+	})
+	assert.For(ctx, "passes").That(len(graph.Passes)).Equals(4)
+	assert.For(ctx, "kind").That(graph.Passes[0].Kind).Equals(service.FramePassKind_ComputePass)
+	assert.For(ctx, "last").That(graph.Passes[1].Last).Equals(uint64(5))
+	assert.For(ctx, "edges").ThatSlice(graph.Edges).DeepEquals([]*service.FrameGraphEdge{
+		{From: 1, To: 2, Resources: []*service.FrameResource{{Kind: "VkImage", Handle: 1}}},
+		{From: 0, To: 2, Resources: []*service.FrameResource{{Kind: "VkBuffer", Handle: 3}}},
+		{From: 2, To: 3, Resources: []*service.FrameResource{{Kind: "VkImage", Handle: 2}}},
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/experiments"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// maxFrameLoopIterations is the maximum number of times a frame can be
+// replayed in a loop.
+const maxFrameLoopIterations = 10000
+
+// FrameLoopProfile resolves the GPU time of the frame of the capture c,
+// replayed iterations times in a loop on the given device, with the named
+// experiments applied with the given parameters. Frames are numbered from 1.
+func FrameLoopProfile(ctx context.Context, d *path.Device, c *path.Capture, frame, iterations uint32, experiments []string, parameters map[string]string) (*service.FrameLoopProfile, error) {
+	obj, err := database.Build(ctx, &FrameLoopProfileResolvable{
+		Device:      d,
+		Capture:     c,
+		Frame:       frame,
+		Iterations:  iterations,
+		Experiments: experiments,
+		Parameters:  parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.FrameLoopProfile), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *FrameLoopProfileResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	if r.Iterations == 0 || r.Iterations > maxFrameLoopIterations {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrValueOutOfBounds(uint64(r.Iterations), "Iterations", uint64(1), uint64(maxFrameLoopIterations)),
+		}
+	}
+	accepted := experiments.Parameters{}
+	for _, name := range r.Experiments {
+		e := experiments.Find(name)
+		if e == nil {
+			return nil, &service.ErrInvalidArgument{Reason: messages.ErrUnknownExperiment(name)}
+		}
+		for p, v := range experiments.Parameters(r.Parameters).Select(e) {
+			accepted[p] = v
+		}
+	}
+	for p := range r.Parameters {
+		if _, ok := accepted[p]; !ok {
+			return nil, experiments.Invalid(p, "unknown parameter")
+		}
+	}
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := frameEnd(list.Atoms, r.Frame)
+	if err != nil {
+		return nil, err
+	}
+
+	api := list.Atoms[end].API()
+	ql, ok := api.(replay.QueryFrameLoop)
+	if !ok {
+		name := "unknown"
+		if api != nil {
+			name = api.Name()
+		}
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrFrameLoopNotSupported(name)}
+	}
+
+	intent := replay.Intent{
+		Capture: r.Capture,
+		Device:  r.Device,
+	}
+	durations, err := ql.QueryFrameLoop(ctx, intent, replay.GetManager(ctx), end, r.Iterations, r.Experiments, r.Parameters, nil)
+	if err != nil {
+		return nil, err
+	}
+	profile := frameLoopProfile(r.Frame, durations)
+	profile.Experiments = r.Experiments
+	profile.Parameters = r.Parameters
+	return profile, nil
+}
+
+// frameEnd returns the last atom of the frame of atoms, numbered from 1.
+func frameEnd(atoms []atom.Atom, frame uint32) (atom.ID, error) {
+	end, count := -1, uint64(0)
+	for i, a := range atoms {
+		if a.AtomFlags().IsEndOfFrame() {
+			count++
+			if count == uint64(frame) {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return atom.NoID, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidFrameRange(uint64(frame), uint64(frame), count),
+		}
+	}
+	return atom.ID(end), nil
+}
+
+// frameLoopProfile returns the profile of the frame from the GPU time of each
+// iteration of its loop.
+func frameLoopProfile(frame uint32, durations []time.Duration) *service.FrameLoopProfile {
+	out := &service.FrameLoopProfile{Frame: frame}
+	if len(durations) == 0 {
+		return out
+	}
+	for _, d := range durations {
+		out.Durations = append(out.Durations, uint64(d.Nanoseconds()))
+	}
+	sorted := append(nanoseconds{}, out.Durations...)
+	sort.Sort(sorted)
+	out.Min, out.Max = sorted[0], sorted[len(sorted)-1]
+	if n := len(sorted); n%2 == 1 {
+		out.Median = sorted[n/2]
+	} else {
+		out.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return out
+}
+
+// nanoseconds sorts durations by increasing value.
+type nanoseconds []uint64
+
+func (l nanoseconds) Len() int           { return len(l) }
+func (l nanoseconds) Less(i, j int) bool { return l[i] < l[j] }
+func (l nanoseconds) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
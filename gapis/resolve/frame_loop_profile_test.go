@@ -0,0 +1,40 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+)
+
+func TestFrameLoopProfile(t *testing.T) {
+	ctx := log.Testing(t)
+
+	odd := frameLoopProfile(3, []time.Duration{30, 10, 20})
+	assert.For(ctx, "frame").That(odd.Frame).Equals(uint32(3))
+	assert.For(ctx, "durations").That(odd.Durations).DeepEquals([]uint64{30, 10, 20})
+	assert.For(ctx, "min").That(odd.Min).Equals(uint64(10))
+	assert.For(ctx, "median").That(odd.Median).Equals(uint64(20))
+	assert.For(ctx, "max").That(odd.Max).Equals(uint64(30))
+
+	even := frameLoopProfile(1, []time.Duration{40, 10, 20, 30})
+	assert.For(ctx, "even median").That(even.Median).Equals(uint64(25))
+
+	empty := frameLoopProfile(1, nil)
+	assert.For(ctx, "empty").That(len(empty.Durations)).Equals(0)
+}
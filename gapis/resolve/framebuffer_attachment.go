@@ -79,15 +79,36 @@ func (r *FramebufferAttachmentResolvable) Resolve(ctx context.Context) (interfac
 	}
 	width, height := uniformScale(fbInfo.width, fbInfo.height, r.Settings.MaxWidth, r.Settings.MaxHeight)
 
+	format, depthMapping := fbInfo.format, service.DepthMapping_RawDepth
+	var tonemapping *service.Tonemap
+	switch r.Attachment {
+	case gfxapi.FramebufferAttachment_Depth:
+		depthMapping = r.Settings.DepthMapping
+		format = depthMappingFormat(format, depthMapping)
+	case gfxapi.FramebufferAttachment_Stencil:
+		// Stencil values are always returned raw.
+	default:
+		tonemapping = r.Settings.Tonemap
+		format = tonemapFormat(format, tonemapping)
+	}
+
 	data, err := database.Store(ctx, &FramebufferAttachmentDataResolvable{
-		Device:        r.Device,
-		After:         r.After,
-		Width:         width,
-		Height:        height,
-		Attachment:    r.Attachment,
-		WireframeMode: r.Settings.WireframeMode,
-		Hints:         r.Hints,
-		ImageFormat:   fbInfo.format,
+		Device:          r.Device,
+		After:           r.After,
+		Width:           width,
+		Height:          height,
+		Attachment:      r.Attachment,
+		WireframeMode:   r.Settings.WireframeMode,
+		Hints:           r.Hints,
+		ImageFormat:     fbInfo.format,
+		Highlight:       r.Settings.Highlight,
+		DepthMapping:    depthMapping,
+		DepthNear:       r.Settings.DepthNear,
+		DepthFar:        r.Settings.DepthFar,
+		Tonemap:         tonemapping,
+		Skip:            r.Settings.Skip,
+		TextureOverride: r.Settings.TextureOverride,
+		StateOverride:   r.Settings.StateOverride,
 	})
 	if err != nil {
 		return nil, err
@@ -96,7 +117,7 @@ func (r *FramebufferAttachmentResolvable) Resolve(ctx context.Context) (interfac
 	return &image.Info2D{
 		Width:  width,
 		Height: height,
-		Format: fbInfo.format,
+		Format: format,
 		Data:   image.NewID(data),
 	}, nil
 }
@@ -16,8 +16,11 @@ package resolve
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/google/gapid/core/image"
 	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/math/interval"
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/messages"
 	"github.com/google/gapid/gapis/replay"
@@ -54,23 +57,135 @@ func (r *FramebufferAttachmentDataResolvable) Resolve(ctx context.Context) (inte
 		wireframeMode = replay.WireframeMode_All
 	case service.WireframeMode_Overlay:
 		wireframeMode = replay.WireframeMode_Overlay
+	case service.WireframeMode_Overdraw:
+		wireframeMode = replay.WireframeMode_Overdraw
 	default:
 		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidEnumValue(wireframeMode, "WireframeMode")}
 	}
 
+	override, overridesState, err := stateOverride(r.StateOverride)
+	if err != nil {
+		return nil, err
+	}
+
 	mgr := replay.GetManager(ctx)
 
-	res, err := query.QueryFramebufferAttachment(
-		ctx,
-		intent,
-		mgr,
-		atom.ID(r.After.Index),
-		r.Width,
-		r.Height,
-		r.Attachment,
-		wireframeMode,
-		r.Hints,
-	)
+	var res *image.Image2D
+	if len(r.Skip) > 0 {
+		skipped, ok := api.(replay.QuerySkippedCommands)
+		if !ok {
+			return nil, &service.ErrDataUnavailable{
+				Reason: messages.ErrMessage(fmt.Sprintf("API %s does not support skipping commands", api.Name())),
+			}
+		}
+		if r.Highlight != nil || wireframeMode != replay.WireframeMode_None || r.TextureOverride != nil || overridesState {
+			return nil, &service.ErrInvalidArgument{
+				Reason: messages.ErrMessage("Skipping commands can not be combined with wireframes, highlighting or overrides"),
+			}
+		}
+		skip := atom.RangeList{}
+		for _, s := range r.Skip {
+			if s.Count > 0 {
+				interval.Merge(&skip, interval.U64Span{Start: s.First, End: s.First + s.Count}, true)
+			}
+		}
+		res, err = skipped.QuerySkippedCommands(
+			ctx,
+			intent,
+			mgr,
+			atom.ID(r.After.Index),
+			skip,
+			r.Width,
+			r.Height,
+			r.Attachment,
+			r.Hints,
+		)
+	} else if r.TextureOverride != nil {
+		override, ok := api.(replay.QueryTextureOverride)
+		if !ok {
+			return nil, &service.ErrDataUnavailable{
+				Reason: messages.ErrMessage(fmt.Sprintf("API %s does not support overriding textures", api.Name())),
+			}
+		}
+		if r.Highlight != nil || wireframeMode != replay.WireframeMode_None || overridesState {
+			return nil, &service.ErrInvalidArgument{
+				Reason: messages.ErrMessage("Texture overrides can not be combined with wireframes, highlighting or state overrides"),
+			}
+		}
+		texture, err := overriddenTexture(ctx, r.After, r.TextureOverride)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := texturePattern(r.TextureOverride)
+		if err != nil {
+			return nil, err
+		}
+		res, err = override.QueryTextureOverride(
+			ctx,
+			intent,
+			mgr,
+			atom.ID(r.After.Index),
+			texture,
+			pattern,
+			r.Width,
+			r.Height,
+			r.Attachment,
+			r.Hints,
+		)
+	} else if overridesState {
+		overridden, ok := api.(replay.QueryStateOverride)
+		if !ok {
+			return nil, &service.ErrDataUnavailable{
+				Reason: messages.ErrMessage(fmt.Sprintf("API %s does not support overriding state", api.Name())),
+			}
+		}
+		if r.Highlight != nil || wireframeMode != replay.WireframeMode_None {
+			return nil, &service.ErrInvalidArgument{
+				Reason: messages.ErrMessage("State overrides can not be combined with wireframes or highlighting"),
+			}
+		}
+		res, err = overridden.QueryStateOverride(
+			ctx,
+			intent,
+			mgr,
+			atom.ID(r.After.Index),
+			override,
+			r.Width,
+			r.Height,
+			r.Attachment,
+			r.Hints,
+		)
+	} else if r.Highlight != nil {
+		highlight, ok := api.(replay.QueryHighlightedDraw)
+		if !ok {
+			return nil, &service.ErrDataUnavailable{
+				Reason: messages.ErrMessage(fmt.Sprintf("API %s does not support highlighting draw calls", api.Name())),
+			}
+		}
+		res, err = highlight.QueryHighlightedDraw(
+			ctx,
+			intent,
+			mgr,
+			atom.ID(r.After.Index),
+			atom.ID(r.Highlight.Index),
+			r.Width,
+			r.Height,
+			r.Attachment,
+			r.Hints,
+		)
+	} else {
+		res, err = query.QueryFramebufferAttachment(
+			ctx,
+			intent,
+			mgr,
+			atom.ID(r.After.Index),
+			r.Width,
+			r.Height,
+			r.Attachment,
+			wireframeMode,
+			r.Hints,
+		)
+	}
 	if err != nil {
 		if _, ok := err.(*service.ErrDataUnavailable); ok {
 			return nil, err
@@ -78,10 +193,33 @@ func (r *FramebufferAttachmentDataResolvable) Resolve(ctx context.Context) (inte
 		return nil, log.Err(ctx, err, "Couldn't get framebuffer attachment")
 	}
 
+	if r.StateOverride != nil && r.StateOverride.OpaqueAlpha {
+		res, err = opaqueAlpha(res, r.Attachment)
+		if err != nil {
+			return nil, log.Err(ctx, err, "Couldn't make the color attachment opaque")
+		}
+	}
+
 	res, err = res.Convert(r.ImageFormat)
 	if err != nil {
 		return nil, log.Err(ctx, err, "Couldn't get framebuffer attachment")
 	}
 
+	res, err = remapDepth(res, r.DepthMapping, r.DepthNear, r.DepthFar)
+	if err != nil {
+		if _, ok := err.(*service.ErrInvalidArgument); ok {
+			return nil, err
+		}
+		return nil, log.Err(ctx, err, "Couldn't remap the depth attachment")
+	}
+
+	res, err = tonemap(res, r.Tonemap)
+	if err != nil {
+		if _, ok := err.(*service.ErrInvalidArgument); ok {
+			return nil, err
+		}
+		return nil, log.Err(ctx, err, "Couldn't tonemap the color attachment")
+	}
+
 	return res.Data, nil
 }
@@ -96,12 +96,22 @@ func (r *HierarchiesResolvable) Resolve(ctx context.Context) (interface{}, error
 	}
 
 	// Add to each per-context hierarchy groups for draw calls and end-of-frames.
+	// Draw calls are described before mutation, so that generated passes can
+	// be added to contexts without user markers.
 	s = c.NewState()
 	for i, a := range atoms {
+		var draw *atom.DrawInfo
+		if d, ok := a.API().(atom.DrawDescriber); ok && a.AtomFlags().IsDrawCall() {
+			if info, ok := d.DescribeDraw(ctx, s, a); ok {
+				draw = &info
+			}
+		}
 		a.Mutate(ctx, s, nil)
 		if api := a.API(); api != nil {
 			if context := api.Context(s); context != nil {
-				contexts[context.ID()].addFrameAndDraws(ctx, a, uint64(i), s)
+				chb := contexts[context.ID()]
+				chb.addFrameAndDraws(ctx, a, uint64(i), s, draw)
+				chb.addAPIGroups(ctx, api, a, uint64(i), s)
 			}
 		}
 	}
@@ -172,6 +182,9 @@ type contextHierarchyBuilder struct {
 	frameCount      int
 	drawStart       uint64
 	drawCount       int
+	draws           []passDraw // Draws of the current frame, for generated passes.
+	undescribed     bool       // True if a draw of the current frame has no passDraw.
+	groupBuilders   map[gfxapi.API]atom.GroupBuilder
 	name            string
 	context         id.ID
 	root            atom.Group
@@ -213,7 +226,7 @@ func (h *contextHierarchyBuilder) addUserMarkers(ctx context.Context, a atom.Ato
 	}
 }
 
-func (h *contextHierarchyBuilder) addFrameAndDraws(ctx context.Context, a atom.Atom, i uint64, s *gfxapi.State) {
+func (h *contextHierarchyBuilder) addFrameAndDraws(ctx context.Context, a atom.Atom, i uint64, s *gfxapi.State, draw *atom.DrawInfo) {
 	if h.frameStart == notStarted {
 		h.frameStart = i
 	}
@@ -223,18 +236,54 @@ func (h *contextHierarchyBuilder) addFrameAndDraws(ctx context.Context, a atom.A
 	endIndex := uint64(i) + 1 // Increment by one, since atom.Range's end is non-inclusive.
 	if a.AtomFlags().IsEndOfFrame() {
 		h.root.SubGroups.Add(h.frameStart, endIndex, fmt.Sprintf("Frame %d", h.frameCount+1))
+		h.addPasses()
 		h.frameStart = notStarted
 		h.frameCount++
 		h.drawStart = notStarted
 		h.drawCount = 0 // Reset the draw index, it is relative to the new frame index.
 	}
 	if a.AtomFlags().IsDrawCall() {
+		if draw != nil {
+			h.draws = append(h.draws, passDraw{start: h.drawStart, end: endIndex, info: *draw})
+		} else {
+			h.undescribed = true
+		}
 		h.root.SubGroups.Add(h.drawStart, endIndex, fmt.Sprintf("Draw %d", h.drawCount))
 		h.drawStart = endIndex
 		h.drawCount++
 	}
 }
 
+// addAPIGroups lets the API of a add its own groups to the hierarchy, if the
+// API is an atom.Grouper.
+func (h *contextHierarchyBuilder) addAPIGroups(ctx context.Context, api gfxapi.API, a atom.Atom, i uint64, s *gfxapi.State) {
+	grouper, ok := api.(atom.Grouper)
+	if !ok {
+		return
+	}
+	b, ok := h.groupBuilders[api]
+	if !ok {
+		if h.groupBuilders == nil {
+			h.groupBuilders = map[gfxapi.API]atom.GroupBuilder{}
+		}
+		b = grouper.NewGroupBuilder()
+		h.groupBuilders[api] = b
+	}
+	b.Process(ctx, a, i, s, &h.root)
+}
+
+// addPasses groups the draws of the frame that just ended into generated
+// passes, if the context has no user markers to group them.
+func (h *contextHierarchyBuilder) addPasses() {
+	if h.userMarkerCount == 0 && !h.undescribed {
+		for i, pass := range clusterPasses(h.draws) {
+			start, end := pass[0].start, pass[len(pass)-1].end
+			h.root.SubGroups.Add(start, end, passName(i, pass))
+		}
+	}
+	h.draws, h.undescribed = nil, false
+}
+
 func (h *contextHierarchyBuilder) finalize(atoms []atom.Atom) {
 	if h.frameStart != notStarted && h.frameCount > 0 {
 		h.root.SubGroups.Add(h.frameStart, uint64(len(atoms)), "Incomplete Frame")
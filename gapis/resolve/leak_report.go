@@ -0,0 +1,97 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// LeakReport resolves the objects that the capture c creates and never
+// releases, grouped by creating command.
+func LeakReport(ctx context.Context, c *path.Capture) (*service.LeakReport, error) {
+	obj, err := database.Build(ctx, &LeakReportResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.LeakReport), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *LeakReportResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leaks := []atom.Leak{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if t, ok := api.(atom.LeakTracker); ok {
+			l, err := t.Leaks(ctx)
+			if err != nil {
+				return nil, err
+			}
+			leaks = append(leaks, l...)
+		}
+	}
+	return leakReport(list.Atoms, leaks), nil
+}
+
+// leakReport groups the leaks by the atom creating them, in atom order.
+func leakReport(atoms []atom.Atom, leaks []atom.Leak) *service.LeakReport {
+	out := &service.LeakReport{}
+	groups := map[atom.ID]*service.LeakGroup{}
+	for _, l := range leaks {
+		g, ok := groups[l.Created]
+		if !ok {
+			g = &service.LeakGroup{Command: uint64(l.Created)}
+			if int(l.Created) < len(atoms) {
+				g.CommandName = atoms[l.Created].Class().Schema().Name()
+			}
+			groups[l.Created] = g
+			out.Groups = append(out.Groups, g)
+		}
+		g.Leaks = append(g.Leaks, &service.Leak{
+			Kind:   l.Kind,
+			Handle: l.Handle,
+			Size:   l.Size,
+			Reason: service.LeakReason(l.Reason),
+		})
+		out.Size += l.Size
+	}
+	sort.Stable(leakGroupsByCommand(out.Groups))
+	return out
+}
+
+// leakGroupsByCommand sorts leak groups by increasing command index.
+type leakGroupsByCommand []*service.LeakGroup
+
+func (l leakGroupsByCommand) Len() int           { return len(l) }
+func (l leakGroupsByCommand) Less(i, j int) bool { return l[i].Command < l[j].Command }
+func (l leakGroupsByCommand) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
@@ -0,0 +1,44 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestLeakReport(t *testing.T) {
+	ctx := log.Testing(t)
+	report := leakReport(nil, []atom.Leak{
+		{Created: 7, Kind: "VkImage", Handle: 3},
+		{Created: 2, Kind: "VkDeviceMemory", Handle: 5, Size: 1024, Reason: atom.NeverFreed},
+		{Created: 7, Kind: "VkImage", Handle: 4},
+		{Created: 2, Kind: "VkCommandPool", Handle: 6, Reason: atom.NeverReset},
+	})
+
+	assert.For(ctx, "size").That(report.Size).Equals(uint64(1024))
+	assert.For(ctx, "groups").That(len(report.Groups)).Equals(2)
+	first, second := report.Groups[0], report.Groups[1]
+	assert.For(ctx, "first command").That(first.Command).Equals(uint64(2))
+	assert.For(ctx, "first leaks").That(len(first.Leaks)).Equals(2)
+	assert.For(ctx, "freed").That(first.Leaks[0].Reason).Equals(service.LeakReason_NeverFreed)
+	assert.For(ctx, "reset").That(first.Leaks[1].Reason).Equals(service.LeakReason_NeverReset)
+	assert.For(ctx, "second command").That(second.Command).Equals(uint64(7))
+	assert.For(ctx, "second handles").That([]uint64{second.Leaks[0].Handle, second.Leaks[1].Handle}).DeepEquals([]uint64{3, 4})
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// LintReport resolves the issues found by checking the commands of the
+// capture c against the correctness rules of their APIs.
+func LintReport(ctx context.Context, c *path.Capture) (*service.LintReport, error) {
+	obj, err := database.Build(ctx, &LintReportResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.LintReport), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *LintReportResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []atom.LintIssue{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if l, ok := api.(atom.Linter); ok {
+			i, err := l.Lint(ctx)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, i...)
+		}
+	}
+	return lintReport(issues), nil
+}
+
+// lintReport returns the report of the issues, sorted by offending atom.
+func lintReport(issues []atom.LintIssue) *service.LintReport {
+	sort.Stable(lintIssuesByAtom(issues))
+	out := &service.LintReport{}
+	for _, i := range issues {
+		issue := &service.LintIssue{
+			Command: uint64(i.Atom),
+			Rule:    i.Rule,
+			Message: i.Message,
+		}
+		if i.Related != atom.NoID {
+			issue.Related = []uint64{uint64(i.Related)}
+		}
+		out.Issues = append(out.Issues, issue)
+	}
+	return out
+}
+
+// lintIssuesByAtom sorts lint issues by increasing offending atom.
+type lintIssuesByAtom []atom.LintIssue
+
+func (l lintIssuesByAtom) Len() int           { return len(l) }
+func (l lintIssuesByAtom) Less(i, j int) bool { return l[i].Atom < l[j].Atom }
+func (l lintIssuesByAtom) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
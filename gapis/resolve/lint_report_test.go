@@ -0,0 +1,41 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+)
+
+func TestLintReport(t *testing.T) {
+	ctx := log.Testing(t)
+	report := lintReport([]atom.LintIssue{
+		{Atom: 9, Related: atom.NoID, Rule: "unreset-fence"},
+		{Atom: 4, Related: 2, Rule: "missing-barrier"},
+		{Atom: 9, Related: 7, Rule: "destroyed-handle"},
+	})
+
+	rules := []string{}
+	for _, i := range report.Issues {
+		rules = append(rules, i.Rule)
+	}
+	assert.For(ctx, "rules").ThatSlice(rules).Equals([]string{"missing-barrier", "unreset-fence", "destroyed-handle"})
+	assert.For(ctx, "command").That(report.Issues[0].Command).Equals(uint64(4))
+	assert.For(ctx, "related").That(report.Issues[0].Related).DeepEquals([]uint64{2})
+	assert.For(ctx, "no related").That(len(report.Issues[1].Related)).Equals(0)
+}
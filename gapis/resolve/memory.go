@@ -33,9 +33,9 @@ func Memory(ctx context.Context, p *path.Memory) (*service.MemoryInfo, error) {
 		return nil, err
 	}
 
-	s := capture.NewState(ctx)
-	for _, a := range list.Atoms[:p.After.Index] {
-		a.Mutate(ctx, s, nil /* no builder, just mutate */)
+	s, err := mutatedState(ctx, p.After.Commands.Capture, list.Atoms, p.After.Index)
+	if err != nil {
+		return nil, err
 	}
 
 	pool, ok := s.Memory[memory.PoolID(p.Pool)]
@@ -0,0 +1,98 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// MemoryProfile resolves the device memory used by the capture c at the end
+// of each frame.
+func MemoryProfile(ctx context.Context, c *path.Capture) (*service.MemoryProfile, error) {
+	obj, err := database.Build(ctx, &MemoryProfileResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.MemoryProfile), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *MemoryProfileResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := []gfxapi.MemoryUsageProvider{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if p, ok := api.(gfxapi.MemoryUsageProvider); ok {
+			providers = append(providers, p)
+		}
+	}
+
+	out := &service.MemoryProfile{}
+	if len(providers) == 0 || len(list.Atoms) == 0 {
+		return out, nil
+	}
+
+	state := c.NewState()
+	sample := func(i int) {
+		usage := gfxapi.MemoryUsage{}
+		for _, p := range providers {
+			usage.Add(p.MemoryUsage(ctx, state))
+		}
+		frame := uint32(len(out.Samples) + 1)
+		out.Samples = append(out.Samples, memorySample(frame, uint64(i), usage))
+	}
+	last := len(list.Atoms) - 1
+	for i, a := range list.Atoms {
+		a.Mutate(ctx, state, nil /* no builder, just mutate */)
+		if a.AtomFlags().IsEndOfFrame() || i == last {
+			sample(i)
+		}
+	}
+	return out, nil
+}
+
+// memorySample returns the sample of the memory usage u at the given frame and
+// command.
+func memorySample(frame uint32, command uint64, u gfxapi.MemoryUsage) *service.MemorySample {
+	out := &service.MemorySample{
+		Frame:     frame,
+		Command:   command,
+		Allocated: u.Allocated,
+		Heaps:     append([]uint64{}, u.Heaps...),
+		Bound:     u.Bound,
+		Images:    u.Images,
+		Buffers:   u.Buffers,
+	}
+	if u.Allocated > u.Bound {
+		out.Unbound = u.Allocated - u.Bound
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/gfxapi"
+)
+
+func TestMemorySample(t *testing.T) {
+	ctx := log.Testing(t)
+
+	usage := gfxapi.MemoryUsage{Allocated: 100, Heaps: []uint64{60}, Bound: 30, Images: 20, Buffers: 10}
+	usage.Add(gfxapi.MemoryUsage{Allocated: 50, Heaps: []uint64{0, 0, 50}, Bound: 50, Buffers: 50})
+
+	s := memorySample(2, 42, usage)
+	assert.For(ctx, "frame").That(s.Frame).Equals(uint32(2))
+	assert.For(ctx, "command").That(s.Command).Equals(uint64(42))
+	assert.For(ctx, "allocated").That(s.Allocated).Equals(uint64(150))
+	assert.For(ctx, "heaps").That(s.Heaps).DeepEquals([]uint64{60, 0, 50})
+	assert.For(ctx, "bound").That(s.Bound).Equals(uint64(80))
+	assert.For(ctx, "unbound").That(s.Unbound).Equals(uint64(70))
+	assert.For(ctx, "images").That(s.Images).Equals(uint64(20))
+	assert.For(ctx, "buffers").That(s.Buffers).Equals(uint64(60))
+
+	over := memorySample(1, 0, gfxapi.MemoryUsage{Allocated: 10, Bound: 20})
+	assert.For(ctx, "clamped unbound").That(over.Unbound).Equals(uint64(0))
+}
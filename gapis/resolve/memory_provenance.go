@@ -0,0 +1,145 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/math/interval"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// MemoryProvenance resolves, for each byte of the memory at p, the command
+// that last wrote it up to and including the command p.After.
+func MemoryProvenance(ctx context.Context, p *path.Memory) (*service.MemoryProvenance, error) {
+	obj, err := database.Build(ctx, &MemoryProvenanceResolvable{p})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.MemoryProvenance), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *MemoryProvenanceResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	p := r.Memory
+	ctx = capture.Put(ctx, p.After.Commands.Capture)
+	list, err := NCommands(ctx, p.After.Commands, p.After.Index+1)
+	if err != nil {
+		return nil, err
+	}
+
+	poolID := memory.PoolID(p.Pool)
+	rng := memory.Range{Base: p.Address, Size: p.Size}
+	sources := memorySourceList{}
+	current, observed := atom.ID(0), false
+	record := func(w memory.Range) {
+		if w.Overlaps(rng) {
+			i := interval.Replace(&sources, w.Intersect(rng).Span())
+			sources[i].command, sources[i].observed = current, observed
+		}
+	}
+	recordObservations := func(l []atom.Observation) {
+		observed = true
+		for _, o := range l {
+			record(o.Range)
+		}
+		observed = false
+	}
+
+	s := capture.NewState(ctx)
+	if pool, ok := s.Memory[poolID]; ok {
+		pool.OnWrite = record
+	}
+	for i, a := range list.Atoms {
+		current = atom.ID(i)
+		// Observed reads are applied before the command is mutated, and
+		// observed writes after, so they are recorded in the same order.
+		observations := a.Extras().Observations()
+		if poolID == memory.ApplicationPool && observations != nil {
+			recordObservations(observations.Reads)
+		}
+		a.Mutate(ctx, s, nil /* no builder, just mutate */)
+		if poolID == memory.ApplicationPool && observations != nil {
+			recordObservations(observations.Writes)
+		}
+		if pool, ok := s.Memory[poolID]; ok && pool.OnWrite == nil {
+			// The pool was created by this command, which wrote all its content.
+			for _, v := range pool.Slice(rng).ValidRanges() {
+				record(memory.Range{Base: rng.Base + v.Base, Size: v.Size})
+			}
+			pool.OnWrite = record
+		}
+	}
+
+	if _, ok := s.Memory[poolID]; !ok {
+		return nil, fmt.Errorf("Pool %d not found", p.Pool)
+	}
+
+	out := &service.MemoryProvenance{Sources: make([]*service.MemorySource, len(sources))}
+	for i, src := range sources {
+		out.Sources[i] = &service.MemorySource{
+			Range:    &service.MemoryRange{Base: src.rng.Base, Size: src.rng.Size},
+			Command:  uint64(src.command),
+			Observed: src.observed,
+		}
+	}
+	return out, nil
+}
+
+// memorySource is the command that last wrote a range of memory.
+type memorySource struct {
+	rng      memory.Range
+	command  atom.ID
+	observed bool
+}
+
+// memorySourceList is an interval.List of memorySources.
+type memorySourceList []memorySource
+
+func (l *memorySourceList) Length() int {
+	return len(*l)
+}
+
+func (l *memorySourceList) GetSpan(index int) interval.U64Span {
+	return (*l)[index].rng.Span()
+}
+
+func (l *memorySourceList) SetSpan(index int, span interval.U64Span) {
+	(*l)[index].rng = memory.Range{Base: span.Start, Size: span.End - span.Start}
+}
+
+func (l *memorySourceList) New(index int, span interval.U64Span) {
+	(*l)[index].rng = memory.Range{Base: span.Start, Size: span.End - span.Start}
+}
+
+func (l *memorySourceList) Copy(to, from, count int) {
+	copy((*l)[to:to+count], (*l)[from:from+count])
+}
+
+func (l *memorySourceList) Resize(length int) {
+	if cap(*l) > length {
+		*l = (*l)[:length]
+	} else {
+		old := *l
+		*l = make(memorySourceList, length, length*2)
+		copy(*l, old)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/math/interval"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/memory"
+)
+
+func TestMemorySourceList(t *testing.T) {
+	ctx := log.Testing(t)
+	l := memorySourceList{}
+	write := func(base, size uint64, command atom.ID) {
+		i := interval.Replace(&l, memory.Range{Base: base, Size: size}.Span())
+		l[i].command = command
+	}
+	write(0x100, 0x100, 1)
+	write(0x140, 0x40, 2)
+	write(0x1f0, 0x20, 3)
+
+	assert.With(ctx).ThatSlice(l).Equals(memorySourceList{
+		{rng: memory.Range{Base: 0x100, Size: 0x40}, command: 1},
+		{rng: memory.Range{Base: 0x140, Size: 0x40}, command: 2},
+		{rng: memory.Range{Base: 0x180, Size: 0x70}, command: 1},
+		{rng: memory.Range{Base: 0x1f0, Size: 0x20}, command: 3},
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// MinimalRepro builds a new capture holding only the commands required to
+// reproduce the output of the draw call at p, and returns the path to the new
+// capture. The draw call is the last command of the new capture.
+func MinimalRepro(ctx context.Context, p *path.Command) (*path.Capture, error) {
+	obj, err := database.Build(ctx, &MinimalReproResolvable{p})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*path.Capture), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *MinimalReproResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Command.Commands.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := NCommands(ctx, r.Command.Commands, r.Command.Index+1)
+	if err != nil {
+		return nil, err
+	}
+
+	id := atom.ID(r.Command.Index)
+	draw := list.Atoms[id]
+	if !draw.AtomFlags().IsDrawCall() {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrNotADrawCall(uint64(id))}
+	}
+
+	tracker, ok := draw.API().(atom.DependencyTracker)
+	if !ok {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrDependenciesNotSupported(draw.API().Name())}
+	}
+
+	ids, err := tracker.Dependencies(ctx, []atom.ID{id})
+	if err != nil {
+		return nil, err
+	}
+
+	repro := atom.NewList()
+	for _, i := range ids {
+		repro.Add(list.Atoms[i])
+	}
+
+	name := fmt.Sprintf("%v [repro %v]", c.Name, id)
+	return capture.ImportAtomList(ctx, name, repro)
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"fmt"
+
+	"github.com/google/gapid/gapis/atom"
+)
+
+// passDraw is a draw call considered for grouping into passes.
+type passDraw struct {
+	start uint64        // Index of the first command of the draw call group.
+	end   uint64        // Index one past the draw call.
+	info  atom.DrawInfo // Description of the draw call.
+}
+
+// clusterPasses heuristically groups the draws of a frame into passes, for
+// captures that have no debug markers. Contiguous draws belong to the same
+// pass while they draw to the same render target with the same kind of
+// output.
+func clusterPasses(draws []passDraw) [][]passDraw {
+	passes := [][]passDraw{}
+	for i, d := range draws {
+		if i == 0 || !samePass(draws[i-1].info, d.info) {
+			passes = append(passes, nil)
+		}
+		passes[len(passes)-1] = append(passes[len(passes)-1], d)
+	}
+	return passes
+}
+
+func samePass(a, b atom.DrawInfo) bool {
+	return a.Target == b.Target &&
+		a.ColorWrite == b.ColorWrite &&
+		isFullscreenDraw(a) == isFullscreenDraw(b)
+}
+
+// isFullscreenDraw returns true if the draw looks like a fullscreen triangle
+// or quad, as typically used by post-processing.
+func isFullscreenDraw(info atom.DrawInfo) bool {
+	switch info.Vertices {
+	case 3, 4, 6:
+		return info.ColorWrite && !info.DepthWrite
+	default:
+		return false
+	}
+}
+
+// passName returns a generated name for the pass holding draws.
+func passName(index int, draws []passDraw) string {
+	first := draws[0].info
+	blended := 0
+	for _, d := range draws {
+		if d.info.Blend {
+			blended++
+		}
+	}
+
+	kind := "Geometry pass"
+	switch {
+	case !first.ColorWrite && first.DepthWrite:
+		kind = "Depth prepass"
+	case !first.ColorWrite:
+		kind = "Stencil pass"
+	case isFullscreenDraw(first):
+		kind = "Fullscreen post"
+	case blended*2 > len(draws):
+		kind = "Transparent pass"
+	}
+
+	target := fmt.Sprintf("target %d", first.Target)
+	if first.Target == 0 {
+		target = "backbuffer"
+	}
+
+	draw := "draws"
+	if len(draws) == 1 {
+		draw = "draw"
+	}
+	return fmt.Sprintf("Pass %d: %v-like (%v, %d %v)", index, kind, target, len(draws), draw)
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+)
+
+func TestClusterPasses(t *testing.T) {
+	ctx := log.Testing(t)
+	depth := atom.DrawInfo{Target: 1, Vertices: 300, DepthWrite: true}
+	opaque := atom.DrawInfo{Target: 1, Vertices: 900, ColorWrite: true, DepthWrite: true}
+	blended := atom.DrawInfo{Target: 1, Vertices: 60, ColorWrite: true, Blend: true}
+	post := atom.DrawInfo{Target: 0, Vertices: 3, ColorWrite: true}
+
+	draws := []passDraw{}
+	for i, info := range []atom.DrawInfo{depth, depth, opaque, opaque, blended, post} {
+		draws = append(draws, passDraw{start: uint64(i * 2), end: uint64(i*2 + 2), info: info})
+	}
+
+	names := []string{}
+	for i, pass := range clusterPasses(draws) {
+		names = append(names, passName(i, pass))
+	}
+	assert.With(ctx).ThatSlice(names).Equals([]string{
+		"Pass 0: Depth prepass-like (target 1, 2 draws)",
+		"Pass 1: Geometry pass-like (target 1, 3 draws)",
+		"Pass 2: Fullscreen post-like (backbuffer, 1 draw)",
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/perfetto"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// perfettoCommandPitch is the time, in nanoseconds, given to each command of
+// the capture in the exported trace. Captures do not hold the time at which
+// the commands were called, so the commands are laid out at a fixed pitch.
+const perfettoCommandPitch = 1000
+
+// PerfettoTrace resolves the timeline of the capture c as a Perfetto trace.
+// If d is not nil, the GPU timings of the commands replayed on d are added.
+func PerfettoTrace(ctx context.Context, c *path.Capture, d *path.Device) ([]byte, error) {
+	obj, err := database.Build(ctx, &PerfettoTraceResolvable{Capture: c, Device: d})
+	if err != nil {
+		return nil, err
+	}
+	return obj.([]byte), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *PerfettoTraceResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	atoms := list.Atoms
+
+	trace := perfetto.NewTrace()
+	root := trace.Track(c.Name, 0)
+	frames := trace.Track("Frames", root)
+	commands := trace.Track("Commands", root)
+	submits := trace.Track("Queue submits", root)
+
+	at := func(id atom.ID) uint64 { return uint64(id) * perfettoCommandPitch }
+
+	executors := map[gfxapi.ID]atom.DeferredExecutor{}
+	frameStart, frame := atom.ID(0), 1
+	for i, a := range atoms {
+		id := atom.ID(i)
+		api := a.API()
+		if api == nil || api.Index() == 0 /* core */ {
+			continue
+		}
+		name := a.Class().Schema().Name()
+		trace.Slice(commands, api.Name(), name, at(id), at(id+1))
+
+		e, seen := executors[api.ID()]
+		if !seen {
+			e, _ = api.(atom.DeferredExecutor)
+			executors[api.ID()] = e
+		}
+		if e != nil && e.ExecutionKind(a) == atom.Submit {
+			trace.Instant(submits, api.Name(), name, at(id))
+		}
+
+		if a.AtomFlags().IsEndOfFrame() {
+			trace.Slice(frames, "frame", fmt.Sprintf("Frame %d", frame), at(frameStart), at(id+1))
+			frameStart, frame = id+1, frame+1
+		}
+	}
+
+	if r.Device != nil {
+		if err := addPerfettoGPUTimings(ctx, trace, root, r, atoms, executors, at); err != nil {
+			return nil, err
+		}
+	}
+
+	return trace.Bytes(), nil
+}
+
+// addPerfettoGPUTimings adds the GPU timings of the commands replayed on the
+// requested device to the trace. The commands are laid out in the order they
+// are executed by the device, starting no earlier than the command that
+// submitted them.
+func addPerfettoGPUTimings(
+	ctx context.Context,
+	trace *perfetto.Trace,
+	root perfetto.Track,
+	r *PerfettoTraceResolvable,
+	atoms []atom.Atom,
+	executors map[gfxapi.ID]atom.DeferredExecutor,
+	at func(atom.ID) uint64) error {
+
+	profile, err := TimingProfile(ctx, r.Device, r.Capture)
+	if err != nil {
+		return err
+	}
+	durations := map[atom.ID]uint64{}
+	for _, t := range profile.Commands {
+		if t.Count > 0 {
+			durations[atom.ID(t.Command.Index)] = t.Duration / uint64(t.Count)
+		}
+	}
+
+	order := make([]atom.ID, len(atoms))
+	for i := range order {
+		order[i] = atom.ID(i)
+	}
+	for _, e := range executors {
+		if e != nil {
+			if order, err = e.ExecutionOrder(ctx, atoms, order); err != nil {
+				return err
+			}
+		}
+	}
+
+	gpu := trace.Track("GPU (replay)", root)
+	submitted, end := uint64(0), uint64(0)
+	for _, id := range order {
+		a := atoms[id]
+		api := a.API()
+		if api == nil {
+			continue
+		}
+		if e := executors[api.ID()]; e != nil && e.ExecutionKind(a) == atom.Submit {
+			submitted = at(id)
+			continue
+		}
+		duration, ok := durations[id]
+		if !ok {
+			continue
+		}
+		start := end
+		if submitted > start {
+			start = submitted
+		}
+		end = start + duration
+		trace.Slice(gpu, "gpu", a.Class().Schema().Name(), start, end)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// PipelineStatistics resolves the pipeline statistics of the draw and
+// dispatch commands of the capture c, and of its render passes, replayed on
+// the given device.
+func PipelineStatistics(ctx context.Context, d *path.Device, c *path.Capture) (*service.PipelineStatistics, error) {
+	obj, err := database.Build(ctx, &PipelineStatisticsResolvable{Device: d, Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.PipelineStatistics), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *PipelineStatisticsResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	intent := replay.Intent{
+		Capture: r.Capture,
+		Device:  r.Device,
+	}
+	mgr := replay.GetManager(ctx)
+
+	// Capture can use multiple APIs. Collect the statistics of all the APIs
+	// supporting the QueryPipelineStatistics interface.
+	out := &service.PipelineStatistics{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		qs, ok := api.(replay.QueryPipelineStatistics)
+		if !ok {
+			continue
+		}
+		stats, err := qs.QueryPipelineStatistics(ctx, intent, mgr, nil)
+		if err != nil {
+			return nil, err
+		}
+		out.Commands = append(out.Commands, stats.Commands...)
+	}
+	sort.Sort(statisticsByCommand(out.Commands))
+
+	// Sum the statistics of the commands of each render pass.
+	renderPasses := map[uint64]*service.CommandStatistics{}
+	for _, s := range out.Commands {
+		if s.RenderPass == nil {
+			continue
+		}
+		rp, ok := renderPasses[s.RenderPass.Index]
+		if !ok {
+			rp = &service.CommandStatistics{Command: s.RenderPass}
+			renderPasses[s.RenderPass.Index] = rp
+			out.RenderPasses = append(out.RenderPasses, rp)
+		}
+		if s.Count > rp.Count {
+			rp.Count = s.Count
+		}
+		rp.InputAssemblyVertices += s.InputAssemblyVertices
+		rp.InputAssemblyPrimitives += s.InputAssemblyPrimitives
+		rp.VertexShaderInvocations += s.VertexShaderInvocations
+		rp.GeometryShaderInvocations += s.GeometryShaderInvocations
+		rp.GeometryShaderPrimitives += s.GeometryShaderPrimitives
+		rp.ClippingInvocations += s.ClippingInvocations
+		rp.ClippingPrimitives += s.ClippingPrimitives
+		rp.FragmentShaderInvocations += s.FragmentShaderInvocations
+		rp.TessellationControlShaderPatches += s.TessellationControlShaderPatches
+		rp.TessellationEvaluationShaderInvocations += s.TessellationEvaluationShaderInvocations
+		rp.ComputeShaderInvocations += s.ComputeShaderInvocations
+	}
+	sort.Sort(statisticsByCommand(out.RenderPasses))
+	return out, nil
+}
+
+// statisticsByCommand sorts the command statistics by command index.
+type statisticsByCommand []*service.CommandStatistics
+
+func (l statisticsByCommand) Len() int           { return len(l) }
+func (l statisticsByCommand) Less(i, j int) bool { return l[i].Command.Index < l[j].Command.Index }
+func (l statisticsByCommand) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
@@ -0,0 +1,164 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// PixelHistory returns the draw commands in the range [first, first+count)
+// of the capture c that changed the pixel at (x, y) of the framebuffer
+// attachment, replayed on the given device.
+func PixelHistory(
+	ctx context.Context,
+	device *path.Device,
+	c *path.Capture,
+	first, count uint64,
+	attachment gfxapi.FramebufferAttachment,
+	x, y uint32) (*service.PixelHistory, error) {
+
+	obj, err := database.Build(ctx, &PixelHistoryResolvable{
+		Device:     device,
+		Capture:    c,
+		First:      first,
+		Count:      count,
+		Attachment: attachment,
+		X:          x,
+		Y:          y,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.PixelHistory), nil
+}
+
+// Resolve implements the database.Resolver interface.
+// The framebuffer attachment is read back after each draw call of the range,
+// and the draws that changed the pixel are reported with the resulting color.
+func (r *PixelHistoryResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count := uint64(len(list.Atoms))
+	end := r.First + r.Count
+	if r.Count == 0 || end > count {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidCommandRange(r.First, end, count),
+		}
+	}
+
+	commands := r.Capture.Commands()
+	history := &service.PixelHistory{}
+
+	// The color before the range, if any, is used to skip draws that did not
+	// change the pixel.
+	var last *service.PixelWrite
+	if r.First > 0 {
+		last, err = r.pixelAfter(ctx, commands.Index(r.First-1))
+		if err != nil {
+			last = nil
+		}
+	}
+
+	for i := r.First; i < end; i++ {
+		if !list.Atoms[i].AtomFlags().IsDrawCall() {
+			continue
+		}
+		pixel, err := r.pixelAfter(ctx, commands.Index(i))
+		switch err.(type) {
+		case nil:
+		case *service.ErrDataUnavailable:
+			continue // No framebuffer bound at this draw.
+		default:
+			return nil, err
+		}
+		if pixel == nil || sameColor(pixel, last) {
+			continue
+		}
+		pixel.Command = i
+		history.Writes = append(history.Writes, pixel)
+		last = pixel
+	}
+
+	return history, nil
+}
+
+// pixelAfter returns the color of the requested pixel after the command, or
+// nil if the pixel lies outside of the framebuffer attachment.
+func (r *PixelHistoryResolvable) pixelAfter(ctx context.Context, after *path.Command) (*service.PixelWrite, error) {
+	info, err := FramebufferAttachmentInfo(ctx, after, r.Attachment)
+	if err != nil {
+		return nil, err
+	}
+	if r.X >= info.width || r.Y >= info.height {
+		return nil, nil
+	}
+	data, err := database.Build(ctx, &FramebufferAttachmentDataResolvable{
+		Device:        r.Device,
+		After:         after,
+		Width:         info.width,
+		Height:        info.height,
+		Attachment:    r.Attachment,
+		WireframeMode: service.WireframeMode_None,
+		Hints:         &service.UsageHints{},
+		ImageFormat:   image.RGBA_F32,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pixelAt(data.([]byte), info.width, r.X, r.Y), nil
+}
+
+// pixelAt returns the color of the pixel at (x, y) of the RGBA_F32 image data
+// of the given width.
+func pixelAt(data []byte, width, x, y uint32) *service.PixelWrite {
+	offset := int(y*width+x) * 16
+	if offset+16 > len(data) {
+		return nil
+	}
+	r := endian.Reader(bytes.NewReader(data[offset:offset+16]), device.LittleEndian)
+	return &service.PixelWrite{
+		Red:   r.Float32(),
+		Green: r.Float32(),
+		Blue:  r.Float32(),
+		Alpha: r.Float32(),
+	}
+}
+
+// sameColor returns true if a and b are both non-nil and hold the same color.
+func sameColor(a, b *service.PixelWrite) bool {
+	return a != nil && b != nil &&
+		a.Red == b.Red && a.Green == b.Green && a.Blue == b.Blue && a.Alpha == b.Alpha
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// RedundancyReport resolves the commands of the capture c that set state to
+// the value it already holds, summarized per frame.
+func RedundancyReport(ctx context.Context, c *path.Capture) (*service.RedundancyReport, error) {
+	obj, err := database.Build(ctx, &RedundancyReportResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.RedundancyReport), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *RedundancyReportResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	redundancies := []atom.Redundancy{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if checker, ok := api.(atom.RedundancyChecker); ok {
+			r, err := checker.Redundancies(ctx)
+			if err != nil {
+				return nil, err
+			}
+			redundancies = append(redundancies, r...)
+		}
+	}
+	return redundancyReport(list.Atoms, redundancies), nil
+}
+
+// redundancyReport summarizes the redundant atoms per frame, the frames being
+// delimited by the end of frame atoms.
+func redundancyReport(atoms []atom.Atom, redundancies []atom.Redundancy) *service.RedundancyReport {
+	sort.Sort(redundanciesByAtom(redundancies))
+
+	out := &service.RedundancyReport{}
+	totals := map[string]uint64{}
+	frame := &service.FrameRedundancy{}
+	counts := map[string]uint64{}
+	next := 0
+	for i, a := range atoms {
+		frame.Commands++
+		for next < len(redundancies) && int(redundancies[next].Atom) == i {
+			r := redundancies[next]
+			name := a.Class().Schema().Name()
+			out.Commands = append(out.Commands, &service.RedundantCommand{
+				Command:  uint64(r.Atom),
+				Previous: uint64(r.Previous),
+				Name:     name,
+			})
+			frame.Redundant++
+			counts[name]++
+			totals[name]++
+			next++
+		}
+		if a.AtomFlags().IsEndOfFrame() || i == len(atoms)-1 {
+			frame.Counts = redundantCommands(counts)
+			out.Frames = append(out.Frames, frame)
+			frame = &service.FrameRedundancy{Frame: uint32(len(out.Frames))}
+			counts = map[string]uint64{}
+		}
+	}
+	out.Totals = redundantCommands(totals)
+	return out
+}
+
+// redundantCommands returns the counts by command name, most frequent first.
+func redundantCommands(counts map[string]uint64) []*service.RedundantCommands {
+	out := make([]*service.RedundantCommands, 0, len(counts))
+	for name, count := range counts {
+		out = append(out, &service.RedundantCommands{Name: name, Count: count})
+	}
+	sort.Sort(byCount(out))
+	return out
+}
+
+// redundanciesByAtom sorts the redundancies by atom.
+type redundanciesByAtom []atom.Redundancy
+
+func (l redundanciesByAtom) Len() int           { return len(l) }
+func (l redundanciesByAtom) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l redundanciesByAtom) Less(i, j int) bool { return l[i].Atom < l[j].Atom }
+
+// byCount sorts the redundant command counts by decreasing count, then name.
+type byCount []*service.RedundantCommands
+
+func (l byCount) Len() int      { return len(l) }
+func (l byCount) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l byCount) Less(i, j int) bool {
+	if l[i].Count != l[j].Count {
+		return l[i].Count > l[j].Count
+	}
+	return l[i].Name < l[j].Name
+}
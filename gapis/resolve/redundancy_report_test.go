@@ -0,0 +1,62 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/test"
+)
+
+func TestRedundancyReport(t *testing.T) {
+	ctx := log.Testing(t)
+	a := (&test.AtomA{}).Class().Schema().Name()
+	b := (&test.AtomB{}).Class().Schema().Name()
+	atoms := []atom.Atom{
+		&test.AtomA{},
+		&test.AtomB{},
+		&test.AtomA{},
+		&test.AtomA{Flags: atom.EndOfFrame},
+		&test.AtomB{},
+		&test.AtomB{},
+		&test.AtomA{},
+	}
+	report := redundancyReport(atoms, []atom.Redundancy{
+		{Atom: 5, Previous: 4},
+		{Atom: 2, Previous: 0},
+		{Atom: 6, Previous: 2},
+	})
+
+	assert.For(ctx, "frames").That(len(report.Frames)).Equals(2)
+	assert.For(ctx, "frame 0 commands").That(report.Frames[0].Commands).Equals(uint64(4))
+	assert.For(ctx, "frame 0 redundant").That(report.Frames[0].Redundant).Equals(uint64(1))
+	assert.For(ctx, "frame 1").That(report.Frames[1].Frame).Equals(uint32(1))
+	assert.For(ctx, "frame 1 commands").That(report.Frames[1].Commands).Equals(uint64(3))
+	assert.For(ctx, "frame 1 redundant").That(report.Frames[1].Redundant).Equals(uint64(2))
+
+	assert.For(ctx, "commands").That(len(report.Commands)).Equals(3)
+	assert.For(ctx, "first command").That(report.Commands[0].Command).Equals(uint64(2))
+	assert.For(ctx, "first previous").That(report.Commands[0].Previous).Equals(uint64(0))
+	assert.For(ctx, "first name").That(report.Commands[0].Name).Equals(a)
+
+	assert.For(ctx, "totals").That(len(report.Totals)).Equals(2)
+	assert.For(ctx, "first total").That(report.Totals[0].Name).Equals(a)
+	assert.For(ctx, "first count").That(report.Totals[0].Count).Equals(uint64(2))
+	assert.For(ctx, "second total").That(report.Totals[1].Name).Equals(b)
+	assert.For(ctx, "second count").That(report.Totals[1].Count).Equals(uint64(1))
+}
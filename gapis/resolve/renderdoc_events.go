@@ -0,0 +1,177 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// RenderDocEvents resolves the RenderDoc-style event tree of the capture c.
+func RenderDocEvents(ctx context.Context, c *path.Capture) (*service.RenderDocEvents, error) {
+	obj, err := database.Build(ctx, &RenderDocEventsResolvable{c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.RenderDocEvents), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *RenderDocEventsResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	atoms := list.Atoms
+
+	// Collect the marker labels, which depend on the state at the time the
+	// marker atom is called.
+	labels := map[atom.ID]string{}
+	s := c.NewState()
+	for i, a := range atoms {
+		a.Mutate(ctx, s, nil)
+		flags := a.AtomFlags()
+		if flags.IsPushUserMarker() || flags.IsUserMarker() {
+			if labeled, ok := a.(atom.Labeled); ok {
+				labels[atom.ID(i)] = labeled.Label(ctx, s)
+			}
+		}
+	}
+
+	// RenderDoc lists the events in the order they are executed by the
+	// device, so let the APIs that defer execution reorder the atoms.
+	order := make([]atom.ID, len(atoms))
+	for i := range order {
+		order[i] = atom.ID(i)
+	}
+	executors := map[gfxapi.ID]atom.DeferredExecutor{}
+	for _, a := range atoms {
+		api := a.API()
+		if api == nil {
+			continue
+		}
+		if _, seen := executors[api.ID()]; seen {
+			continue
+		}
+		e, _ := api.(atom.DeferredExecutor)
+		executors[api.ID()] = e
+		if e != nil {
+			if order, err = e.ExecutionOrder(ctx, atoms, order); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	b := renderDocEventsBuilder{out: &service.RenderDocEvents{}}
+	for _, id := range order {
+		a := atoms[id]
+		api := a.API()
+		if api == nil || api.Index() == 0 /* core */ {
+			continue
+		}
+		kind := atom.Other
+		if e := executors[api.ID()]; e != nil {
+			kind = e.ExecutionKind(a)
+		}
+		b.add(a, id, kind, labels[id])
+	}
+	b.endFrame()
+
+	return b.out, nil
+}
+
+// renderDocEventsBuilder builds the per-frame RenderDoc event trees from the
+// atoms, in execution order.
+type renderDocEventsBuilder struct {
+	out    *service.RenderDocEvents
+	frame  *service.RenderDocFrame
+	stack  []*service.RenderDocEvent
+	nextID uint32
+}
+
+func (b *renderDocEventsBuilder) add(a atom.Atom, id atom.ID, kind atom.ExecutionKind, label string) {
+	if b.frame == nil {
+		b.frame = &service.RenderDocFrame{Frame: uint32(len(b.out.Frames) + 1)}
+		b.nextID = 1
+	}
+	e := &service.RenderDocEvent{
+		EventId: b.nextID,
+		Command: uint64(id),
+		Name:    a.Class().Schema().Name(),
+	}
+	b.nextID++
+
+	flags := a.AtomFlags()
+	switch {
+	case flags.IsPushUserMarker(), flags.IsUserMarker():
+		e.Kind = service.RenderDocEventKind_RenderDocMarker
+		if label != "" {
+			e.Name = label
+		}
+	case kind == atom.BeginPass:
+		e.Kind = service.RenderDocEventKind_RenderDocPass
+	case flags.IsDrawCall(), kind == atom.Action:
+		e.Kind = service.RenderDocEventKind_RenderDocAction
+	}
+
+	if c := len(b.stack); c > 0 {
+		b.stack[c-1].Children = append(b.stack[c-1].Children, e)
+	} else {
+		b.frame.Events = append(b.frame.Events, e)
+	}
+
+	switch {
+	case flags.IsPushUserMarker(), kind == atom.BeginPass:
+		b.stack = append(b.stack, e)
+	case flags.IsPopUserMarker():
+		b.pop(service.RenderDocEventKind_RenderDocMarker)
+	case kind == atom.EndPass:
+		b.pop(service.RenderDocEventKind_RenderDocPass)
+	}
+
+	if flags.IsEndOfFrame() {
+		b.endFrame()
+	}
+}
+
+// pop closes the innermost open marker or pass, if it is of the given kind.
+func (b *renderDocEventsBuilder) pop(kind service.RenderDocEventKind) {
+	if c := len(b.stack); c > 0 && b.stack[c-1].Kind == kind {
+		b.stack = b.stack[:c-1]
+	}
+}
+
+// endFrame completes the current frame, if any. Markers and passes left open
+// at the end of the frame are closed.
+func (b *renderDocEventsBuilder) endFrame() {
+	if b.frame != nil {
+		b.out.Frames = append(b.out.Frames, b.frame)
+	}
+	b.frame = nil
+	b.stack = nil
+}
@@ -127,6 +127,44 @@ func (r *ReportResolvable) Resolve(ctx context.Context) (interface{}, error) {
 		items, lastError = items[:0], nil
 	}
 
+	// Report the objects that the capture never releases.
+	leaks, err := LeakReport(ctx, r.Capture)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range leaks.Groups {
+		for _, leak := range group.Leaks {
+			item := service.WrapReportItem(
+				&service.ReportItem{
+					Severity: service.Severity_WarningLevel,
+					Command:  group.Command,
+				}, getLeakMessage(leak))
+			item.Tags = append(item.Tags, messages.TagLeak())
+			if group.Command < uint64(len(atoms)) {
+				item.Tags = append(item.Tags, getAtomNameTag(atoms[group.Command]))
+			}
+			builder.Add(ctx, item)
+		}
+	}
+
+	// Suggest merging the draws that could be batched.
+	batches, err := drawBatches(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range drawBatchItems(atoms, batches) {
+		builder.Add(ctx, item)
+	}
+
+	// Report the issues found by the static analysis of the shaders.
+	shaders, err := ShaderAnalysis(ctx, r.Capture)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range shaderIssueItems(atoms, shaders) {
+		builder.Add(ctx, item)
+	}
+
 	if r.Device != nil {
 		// Request is for a replay report too.
 		intent := replay.Intent{
@@ -158,14 +196,25 @@ func (r *ReportResolvable) Resolve(ctx context.Context) (interface{}, error) {
 				}
 			}
 		}
-
-		// Items are now all out of order. Sort them.
-		builder.SortReport()
 	}
 
+	// Items are now all out of order. Sort them.
+	builder.SortReport()
+
 	return builder.Build(), nil
 }
 
+func getLeakMessage(l *service.Leak) *stringtable.Msg {
+	switch l.Reason {
+	case service.LeakReason_NeverFreed:
+		return messages.WarnLeakNeverFreed(l.Kind)
+	case service.LeakReason_NeverReset:
+		return messages.WarnLeakNeverReset(l.Kind)
+	default:
+		return messages.WarnLeakNeverDestroyed(l.Kind)
+	}
+}
+
 func getAtomNameTag(a atom.Atom) *stringtable.Msg {
 	atomName := a.Class().Schema().Name()
 	return messages.TagAtomName(strings.ToLower(atomName[:1]) + atomName[1:])
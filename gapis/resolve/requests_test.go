@@ -19,8 +19,17 @@ import "github.com/google/gapid/gapis/database"
 // Interface compliance tests
 var _ = []database.Resolvable{
 	(*APIStateResolvable)(nil),
+	(*CaptureDiffResolvable)(nil),
+	(*CommandIndexResolvable)(nil),
+	(*CommandTracksResolvable)(nil),
 	(*ContextListResolvable)(nil),
+	(*DispatchSnapshotResolvable)(nil),
+	(*EditedCommandsResolvable)(nil),
+	(*ExportCppResolvable)(nil),
 	(*FollowResolvable)(nil),
+	(*FrameDeltasResolvable)(nil),
+	(*FrameGraphResolvable)(nil),
+	(*FrameLoopProfileResolvable)(nil),
 	(*FramebufferAttachmentDataResolvable)(nil),
 	(*FramebufferAttachmentResolvable)(nil),
 	(*FramebufferChangesResolvable)(nil),
@@ -28,9 +37,27 @@ var _ = []database.Resolvable{
 	(*GlobalStateResolvable)(nil),
 	(*HierarchiesResolvable)(nil),
 	(*IndexLimitsResolvable)(nil),
+	(*LeakReportResolvable)(nil),
+	(*LintReportResolvable)(nil),
+	(*MemoryProfileResolvable)(nil),
+	(*MemoryProvenanceResolvable)(nil),
+	(*MinimalReproResolvable)(nil),
+	(*PerfettoTraceResolvable)(nil),
+	(*PipelineStatisticsResolvable)(nil),
+	(*PixelHistoryResolvable)(nil),
+	(*RedundancyReportResolvable)(nil),
 	(*ReportResolvable)(nil),
 	(*ResourceDataResolvable)(nil),
 	(*ResourceMetaResolvable)(nil),
+	(*ResourceTimelineResolvable)(nil),
 	(*ResourcesResolvable)(nil),
+	(*SanitizeResolvable)(nil),
 	(*SetResolvable)(nil),
+	(*ShaderAnalysisResolvable)(nil),
+	(*SpliceResolvable)(nil),
+	(*StateCheckpointsResolvable)(nil),
+	(*StateDiffResolvable)(nil),
+	(*SubmissionTimelineResolvable)(nil),
+	(*TimingProfileResolvable)(nil),
+	(*TrimResolvable)(nil),
 }
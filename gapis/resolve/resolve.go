@@ -263,6 +263,8 @@ func Resolve(ctx context.Context, p path.Node) (interface{}, error) {
 		return Command(ctx, p)
 	case *path.Commands:
 		return Commands(ctx, p)
+	case *path.Constants:
+		return Constants(ctx, p)
 	case *path.Context:
 		return Context(ctx, p)
 	case *path.Contexts:
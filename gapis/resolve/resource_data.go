@@ -22,7 +22,10 @@ import (
 	"github.com/google/gapid/gapis/capture"
 	"github.com/google/gapid/gapis/database"
 	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
 	"github.com/google/gapid/gapis/service/path"
+	"github.com/google/gapid/gapis/shadertools"
 )
 
 // ResolvedResources contains all of the resolved resources for a
@@ -107,8 +110,42 @@ func (r *ResourceDataResolvable) Resolve(ctx context.Context) (interface{}, erro
 		if err, isErr := val.(error); isErr {
 			return nil, err
 		}
+		if shader, ok := val.(*gfxapi.Shader); ok && r.Path.ShaderLanguage != path.ShaderLanguage_Native {
+			return shaderInLanguage(shader, r.Path.ShaderLanguage)
+		}
 		return val, nil
 	}
 
 	return nil, fmt.Errorf("Cannot find resource with id: %v", id)
 }
+
+// shaderInLanguage returns a copy of shader with the source in language.
+// Only SPIR-V shaders can be decompiled to another language.
+func shaderInLanguage(shader *gfxapi.Shader, language path.ShaderLanguage) (*gfxapi.Shader, error) {
+	if shader.Type != gfxapi.ShaderType_Spirv {
+		if language == path.ShaderLanguage_Glsl {
+			return shader, nil
+		}
+		return nil, &service.ErrDataUnavailable{
+			Reason: messages.ErrMessage(fmt.Sprintf("Cannot convert %v shaders to %v", shader.Type, language)),
+		}
+	}
+	words := shadertools.AssembleSpirvText(shader.Source)
+	if words == nil {
+		return nil, fmt.Errorf("Failed to assemble the SPIR-V shader")
+	}
+	var lang shadertools.SourceLanguage
+	switch language {
+	case path.ShaderLanguage_Glsl:
+		lang = shadertools.GLSL
+	case path.ShaderLanguage_Hlsl:
+		lang = shadertools.HLSL
+	default:
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidEnumValue(uint32(language), "ShaderLanguage")}
+	}
+	source, err := shadertools.DecompileSpirvBinary(words, lang)
+	if err != nil {
+		return nil, &service.ErrDataUnavailable{Reason: messages.ErrMessage(err.Error())}
+	}
+	return &gfxapi.Shader{Type: shader.Type, Source: source}, nil
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// timelineThumbnailSize is the maximum width and height of the thumbnails
+// returned by ResourceTimeline.
+const timelineThumbnailSize = 128
+
+// ResourceTimeline resolves the commands of the capture c that created,
+// modified, used and deleted the resource with the identifier id.
+func ResourceTimeline(ctx context.Context, c *path.Capture, id *path.ID) (*service.ResourceTimeline, error) {
+	obj, err := database.Build(ctx, &ResourceTimelineResolvable{Capture: c, Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.ResourceTimeline), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *ResourceTimelineResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := r.Id.ID()
+	var resource gfxapi.Resource
+	var currentAtomIndex uint64
+	var currentAtomResourceCount int
+	created, accessed := false, false
+
+	state := c.NewState()
+	state.OnResourceCreated = func(r gfxapi.Resource) {
+		currentAtomResourceCount++
+		if resource == nil && genResourceID(currentAtomIndex, currentAtomResourceCount) == target {
+			resource, created = r, true
+		}
+	}
+	state.OnResourceAccessed = func(r gfxapi.Resource) {
+		if resource != nil && r == resource {
+			accessed = true
+		}
+	}
+
+	out := &service.ResourceTimeline{}
+	var data interface{}
+	for i, a := range list.Atoms {
+		currentAtomResourceCount = 0
+		currentAtomIndex = uint64(i)
+		created, accessed = false, false
+		a.Mutate(ctx, state, nil /* no builder, just mutate */)
+		if resource == nil {
+			continue
+		}
+
+		if d, ok := resource.(gfxapi.DeletableResource); ok && !created && d.IsDeleted(state) {
+			out.Events = append(out.Events, &service.ResourceEvent{
+				Command: currentAtomIndex,
+				Kind:    service.ResourceEventKind_ResourceDeleted,
+			})
+			break
+		}
+		if !created && !accessed {
+			continue
+		}
+
+		// Compare the data with the data after the last change to tell
+		// modifications from uses.
+		newData, err := resource.ResourceData(ctx, state)
+		if err != nil {
+			newData = nil
+		}
+		kind := service.ResourceEventKind_ResourceUsed
+		switch {
+		case created:
+			kind = service.ResourceEventKind_ResourceCreated
+		case !reflect.DeepEqual(newData, data):
+			kind = service.ResourceEventKind_ResourceModified
+		}
+		data = newData
+
+		event := &service.ResourceEvent{Command: currentAtomIndex, Kind: kind}
+		if kind != service.ResourceEventKind_ResourceUsed {
+			event.Data = &path.ResourceData{
+				Id:    r.Id,
+				After: r.Capture.Commands().Index(currentAtomIndex),
+			}
+			if _, ok := newData.(image.Thumbnailer); ok {
+				event.Thumbnail = &path.Thumbnail{
+					DesiredMaxWidth:  timelineThumbnailSize,
+					DesiredMaxHeight: timelineThumbnailSize,
+					Object:           &path.Thumbnail_Resource{Resource: event.Data},
+				}
+			}
+		}
+		out.Events = append(out.Events, event)
+	}
+
+	if resource == nil {
+		return nil, fmt.Errorf("Cannot find resource with id: %v", target)
+	}
+	return out, nil
+}
@@ -0,0 +1,179 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// checkerboardSize is the size in texels of the squares of the checkerboards
+// replacing the texture contents.
+const checkerboardSize = 8
+
+// Sanitize builds a new capture from the capture p, with the potentially
+// sensitive data selected by opts replaced, and returns the path to the new
+// capture.
+func Sanitize(ctx context.Context, p *path.Capture, opts *service.SanitizeOptions) (*path.Capture, error) {
+	obj, err := database.Build(ctx, &SanitizeResolvable{Capture: p, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*path.Capture), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *SanitizeResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := map[atom.SensitiveKind]bool{
+		atom.SensitiveShader:  r.Options.GetShaders(),
+		atom.SensitiveName:    r.Options.GetNames(),
+		atom.SensitiveTexture: r.Options.GetTextures(),
+	}
+	sensitive := map[atom.ID][]atom.SensitiveData{}
+	for _, id := range c.Apis {
+		api, ok := gfxapi.Find(gfxapi.ID(id.ID())).(atom.Sanitizer)
+		if !ok {
+			continue
+		}
+		data, err := api.SensitiveData(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range data {
+			if kinds[d.Kind] {
+				sensitive[d.Atom] = append(sensitive[d.Atom], d)
+			}
+		}
+	}
+
+	sanitized := atom.NewList()
+	for i, a := range list.Atoms {
+		if data, ok := sensitive[atom.ID(i)]; ok {
+			if a, err = sanitizeAtom(ctx, a, data); err != nil {
+				return nil, err
+			}
+		}
+		sanitized.Add(a)
+	}
+
+	name := fmt.Sprintf("%v [sanitized]", c.Name)
+	return capture.ImportAtomList(ctx, name, sanitized)
+}
+
+// sanitizeAtom returns a copy of the atom a with the sensitive data in its
+// read observations replaced. a is returned if it observed none of the data.
+func sanitizeAtom(ctx context.Context, a atom.Atom, data []atom.SensitiveData) (atom.Atom, error) {
+	obs := a.Extras().Observations()
+	if obs == nil {
+		return a, nil
+	}
+	reads := make([]atom.Observation, len(obs.Reads))
+	copy(reads, obs.Reads)
+	changed := false
+	for i, o := range reads {
+		var bytes []byte
+		for _, d := range data {
+			if !o.Range.Overlaps(d.Range) {
+				continue
+			}
+			if bytes == nil {
+				obj, err := database.Resolve(ctx, o.ID)
+				if err != nil {
+					return nil, err
+				}
+				bytes = append([]byte{}, obj.([]byte)...)
+			}
+			r := o.Range.Intersect(d.Range)
+			sanitizeBytes(bytes[r.Base-o.Range.Base:r.End()-o.Range.Base], r.Base-d.Range.Base, d)
+		}
+		if bytes == nil {
+			continue
+		}
+		id, err := database.Store(ctx, bytes)
+		if err != nil {
+			return nil, err
+		}
+		reads[i].ID, changed = id, true
+	}
+	if !changed {
+		return a, nil
+	}
+
+	// The atom is copied, as it is shared with the original capture.
+	obj, err := clone(reflect.ValueOf(a))
+	if err != nil {
+		return nil, err
+	}
+	out := obj.Interface().(atom.Atom)
+	extras := make(atom.Extras, len(out.Extras().All()))
+	for i, e := range out.Extras().All() {
+		if e == obs {
+			e = &atom.Observations{Reads: reads, Writes: obs.Writes}
+		}
+		extras[i] = e
+	}
+	*out.Extras() = extras
+	return out, nil
+}
+
+// sanitizeBytes replaces the bytes b of the sensitive data d, starting at the
+// given offset in d.
+func sanitizeBytes(b []byte, offset uint64, d atom.SensitiveData) {
+	if d.Kind == atom.SensitiveTexture {
+		if d.TexelSize == 0 || d.RowSize == 0 {
+			return
+		}
+		for i := range b {
+			o := offset + uint64(i)
+			x, y := (o%d.RowSize)/d.TexelSize, o/d.RowSize
+			if (x/checkerboardSize+y/checkerboardSize)%2 == 0 {
+				b[i] = 0xff
+			} else {
+				b[i] = 0x00
+			}
+		}
+		return
+	}
+	// The strings are replaced by the repeated hexadecimal digest of their
+	// content, keeping the terminators.
+	hash := sha1.Sum(b)
+	digest := hex.EncodeToString(hash[:])
+	for i := range b {
+		if b[i] != 0 {
+			b[i] = digest[i%len(digest)]
+		}
+	}
+}
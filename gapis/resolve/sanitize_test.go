@@ -0,0 +1,48 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+)
+
+func TestSanitizeBytes(t *testing.T) {
+	ctx := log.Testing(t)
+
+	name := atom.SensitiveData{Kind: atom.SensitiveName}
+	a, b := []byte("label\x00"), []byte("label\x00")
+	sanitizeBytes(a, 0, name)
+	sanitizeBytes(b, 0, name)
+	assert.For(ctx, "hashed").That(string(a)).NotEquals("label\x00")
+	assert.For(ctx, "terminator").That(a[5]).Equals(byte(0))
+	assert.For(ctx, "deterministic").That(string(a)).Equals(string(b))
+
+	// A 16x2 texture of 2-byte texels, with 4-byte aligned rows.
+	texture := atom.SensitiveData{Kind: atom.SensitiveTexture, TexelSize: 2, RowSize: 32}
+	data := make([]byte, 64)
+	sanitizeBytes(data, 0, texture)
+	assert.For(ctx, "first square").That(data[0]).Equals(byte(0xff))
+	assert.For(ctx, "second square").That(data[16]).Equals(byte(0x00))
+	assert.For(ctx, "second row").That(data[32]).Equals(byte(0xff))
+
+	// Sanitizing from an offset continues the pattern.
+	part := make([]byte, 2)
+	sanitizeBytes(part, 16, texture)
+	assert.For(ctx, "offset").That(part[0]).Equals(byte(0x00))
+}
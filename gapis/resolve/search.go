@@ -0,0 +1,108 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// searchBatchSize is the maximum number of matching command indices passed
+// to the handler of SearchCommands at once.
+const searchBatchSize = 256
+
+// commandIndex holds the searchable properties of the commands of a capture.
+type commandIndex struct {
+	atoms   []atom.Atom
+	names   []string // Lower-case name of each command.
+	threads []uint64 // Thread issuing each command.
+}
+
+// SearchCommands calls handler with the indices of the commands of the
+// capture c matching query, in increasing order and in batches.
+func SearchCommands(ctx context.Context, c *path.Capture, query string, handler func([]uint64) error) error {
+	expr, err := parseSearchQuery(query)
+	if err != nil {
+		return &service.ErrInvalidArgument{Reason: messages.ErrInvalidSearchQuery(query, err.Error())}
+	}
+
+	obj, err := database.Build(ctx, &CommandIndexResolvable{c})
+	if err != nil {
+		return err
+	}
+	index := obj.(*commandIndex)
+
+	batch := make([]uint64, 0, searchBatchSize)
+	for i := range index.atoms {
+		if task.Stopped(ctx) {
+			return task.StopReason(ctx)
+		}
+		if !expr.match(index, i) {
+			continue
+		}
+		if batch = append(batch, uint64(i)); len(batch) == searchBatchSize {
+			if err := handler(batch); err != nil {
+				return err
+			}
+			batch = make([]uint64, 0, searchBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		return handler(batch)
+	}
+	return nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *CommandIndexResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &commandIndex{
+		atoms:   list.Atoms,
+		names:   make([]string, len(list.Atoms)),
+		threads: make([]uint64, len(list.Atoms)),
+	}
+	thread := uint64(0)
+	for i, a := range list.Atoms {
+		name := a.Class().Schema().Name()
+		// Threads are switched by the core API's switchThread command.
+		if strings.EqualFold(name, "switchThread") {
+			if v, ok := commandParameter(a, "ThreadID"); ok {
+				thread = v.Uint()
+			}
+		}
+		index.names[i] = strings.ToLower(name)
+		index.threads[i] = thread
+	}
+	return index, nil
+}
@@ -0,0 +1,354 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/gapid/gapis/atom"
+)
+
+// searchExpr is a parsed command search query.
+//
+// Queries are made of terms combined with '&&', '||', '!' and parentheses.
+// A term is either a bare pattern matched against the command name, or a
+// comparison of the form 'key op value', where op is one of ':' (glob match),
+// '==', '!=', '<', '<=', '>' or '>='. The keys 'name', 'thread' and 'index'
+// refer to the command name, the thread issuing it and its index. Any other
+// key refers to the command parameter of that name, ignoring case.
+//
+// For example: 'name:vkCmdDraw* && framebuffer==0x3f'.
+type searchExpr interface {
+	match(index *commandIndex, i int) bool
+}
+
+type searchAnd struct{ lhs, rhs searchExpr }
+type searchOr struct{ lhs, rhs searchExpr }
+type searchNot struct{ expr searchExpr }
+type searchTerm struct{ key, op, value string }
+
+func (e searchAnd) match(index *commandIndex, i int) bool {
+	return e.lhs.match(index, i) && e.rhs.match(index, i)
+}
+
+func (e searchOr) match(index *commandIndex, i int) bool {
+	return e.lhs.match(index, i) || e.rhs.match(index, i)
+}
+
+func (e searchNot) match(index *commandIndex, i int) bool {
+	return !e.expr.match(index, i)
+}
+
+func (e searchTerm) match(index *commandIndex, i int) bool {
+	switch e.key {
+	case "name":
+		return compareString(index.names[i], e.op, strings.ToLower(e.value))
+	case "thread":
+		return compareValue(reflect.ValueOf(index.threads[i]), e.op, e.value)
+	case "index":
+		return compareValue(reflect.ValueOf(uint64(i)), e.op, e.value)
+	default:
+		v, ok := commandParameter(index.atoms[i], e.key)
+		return ok && compareValue(v, e.op, e.value)
+	}
+}
+
+// commandParameter returns the value of the parameter of a with the given
+// name, ignoring case.
+func commandParameter(a atom.Atom, name string) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(a))
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	for i, c := 0, t.NumField(); i < c; i++ {
+		if f := t.Field(i); f.PkgPath == "" && strings.EqualFold(f.Name, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// compareValue compares the parameter value v with the literal lit.
+func compareValue(v reflect.Value, op, lit string) bool {
+	// Pointers are compared by address.
+	if v.Kind() == reflect.Struct {
+		if addr := v.FieldByName("Address"); addr.IsValid() {
+			v = addr
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(lit); err == nil {
+			return compareOrdered(boolToInt(v.Bool())-boolToInt(b), op)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(lit, 0, 64); err == nil {
+			return compareOrdered(compareInts(v.Int(), n), op)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, err := strconv.ParseUint(lit, 0, 64); err == nil {
+			return compareOrdered(compareUints(v.Uint(), n), op)
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(lit, 64); err == nil {
+			return compareOrdered(compareFloats(v.Float(), f), op)
+		}
+	}
+	// Fall back to comparing the string form, so that enums can be searched
+	// by name.
+	if !v.CanInterface() {
+		return false
+	}
+	return compareString(fmt.Sprint(v.Interface()), op, lit)
+}
+
+func compareString(s, op, lit string) bool {
+	if op == ":" {
+		matched, _ := path.Match(lit, s)
+		return matched
+	}
+	return compareOrdered(strings.Compare(s, lit), op)
+}
+
+// compareOrdered returns the result of op given c, which is negative, zero or
+// positive if the left-hand side is less than, equal to or greater than the
+// right-hand side.
+func compareOrdered(c int, op string) bool {
+	switch op {
+	case ":", "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	default:
+		return false
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUints(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseSearchQuery parses the command search query q.
+func parseSearchQuery(q string) (searchExpr, error) {
+	tokens, err := tokenizeSearchQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("The query is empty")
+	}
+	p := &searchParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected '%v'", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+var searchOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "(", ")", ":", "<", ">"}
+
+func isSearchOperator(tok string) bool {
+	for _, op := range searchOperators {
+		if tok == op {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenizeSearchQuery(q string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(q); {
+		switch c := rune(q[i]); {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := strings.IndexByte(q[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated string at offset %d", i)
+			}
+			// Quoted values are kept quoted so they are never operators.
+			tokens = append(tokens, q[i:i+end+2])
+			i += end + 2
+		default:
+			op := ""
+			for _, o := range searchOperators {
+				if strings.HasPrefix(q[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, op)
+				i += len(op)
+				continue
+			}
+			start := i
+			for i < len(q) && !unicode.IsSpace(rune(q[i])) && !strings.ContainsRune("&|=!<>():\"", rune(q[i])) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("Unexpected '%c' at offset %d", q[i], i)
+			}
+			tokens = append(tokens, q[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+type searchParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *searchParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *searchParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *searchParser) parseOr() (searchExpr, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = searchOr{lhs, rhs}
+	}
+	return lhs, nil
+}
+
+func (p *searchParser) parseAnd() (searchExpr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = searchAnd{lhs, rhs}
+	}
+	return lhs, nil
+}
+
+func (p *searchParser) parseUnary() (searchExpr, error) {
+	switch tok := p.next(); {
+	case tok == "":
+		return nil, fmt.Errorf("Unexpected end of query")
+	case tok == "!":
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return searchNot{expr}, nil
+	case tok == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("Missing ')'")
+		}
+		return expr, nil
+	case isSearchOperator(tok):
+		return nil, fmt.Errorf("Unexpected '%v'", tok)
+	default:
+		switch op := p.peek(); op {
+		case ":", "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			value := p.next()
+			if value == "" || isSearchOperator(value) {
+				return nil, fmt.Errorf("Expected a value after '%v %v'", tok, op)
+			}
+			return searchTerm{key: strings.ToLower(tok), op: op, value: unquote(value)}, nil
+		}
+		return searchTerm{key: "name", op: ":", value: unquote(tok)}, nil
+	}
+}
+
+func unquote(tok string) string {
+	if len(tok) >= 2 && tok[0] == '"' && tok[len(tok)-1] == '"' {
+		return tok[1 : len(tok)-1]
+	}
+	return tok
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/atom/test"
+)
+
+func TestSearchQuery(t *testing.T) {
+	ctx := log.Testing(t)
+	index := &commandIndex{
+		atoms: []atom.Atom{
+			&test.AtomA{ID: 10},
+			&test.AtomB{ID: 20, Bool: true},
+			&test.AtomC{String: "Pizza"},
+			&test.AtomB{ID: 30},
+		},
+		names:   []string{"atoma", "atomb", "atomc", "atomb"},
+		threads: []uint64{1, 1, 2, 2},
+	}
+	for _, q := range []struct {
+		query    string
+		expected []int
+	}{
+		{"atomb", []int{1, 3}},
+		{"name:Atom*", []int{0, 1, 2, 3}},
+		{"name:atomb && bool==true", []int{1}},
+		{"id>=20", []int{1, 3}},
+		{"id==0x14 || string:Piz*", []int{1, 2}},
+		{"!(thread==1) && index!=2", []int{3}},
+		{`string=="Pizza"`, []int{2}},
+	} {
+		ctx := log.Enter(ctx, q.query)
+		expr, err := parseSearchQuery(q.query)
+		if !assert.With(ctx).ThatError(err).Succeeded() {
+			continue
+		}
+		got := []int{}
+		for i := range index.atoms {
+			if expr.match(index, i) {
+				got = append(got, i)
+			}
+		}
+		assert.With(ctx).ThatSlice(got).Equals(q.expected)
+	}
+}
+
+func TestSearchQueryErrors(t *testing.T) {
+	ctx := log.Testing(t)
+	for _, query := range []string{"", "name:", "(atoma", "atoma &&", "a & b", `name:"atoma`, "atoma)"} {
+		_, err := parseSearchQuery(query)
+		assert.With(log.Enter(ctx, query)).ThatError(err).Failed()
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+	"github.com/google/gapid/gapis/stringtable"
+)
+
+// ShaderAnalysis resolves the issues found by the static analysis of the
+// shaders of the capture c.
+func ShaderAnalysis(ctx context.Context, c *path.Capture) (*service.ShaderAnalysis, error) {
+	obj, err := database.Build(ctx, &ShaderAnalysisResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.ShaderAnalysis), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *ShaderAnalysisResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	shaders := []atom.ShaderIssues{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if a, ok := api.(atom.ShaderAnalyzer); ok {
+			s, err := a.ShaderIssues(ctx)
+			if err != nil {
+				return nil, err
+			}
+			shaders = append(shaders, s...)
+		}
+	}
+	return shaderAnalysis(list.Atoms, shaders), nil
+}
+
+// shaderAnalysis returns the shaders with issues, in atom order.
+func shaderAnalysis(atoms []atom.Atom, shaders []atom.ShaderIssues) *service.ShaderAnalysis {
+	out := &service.ShaderAnalysis{}
+	for _, s := range shaders {
+		if len(s.Issues) == 0 {
+			continue
+		}
+		issues := &service.ShaderIssues{
+			Command: uint64(s.Atom),
+			Handle:  s.Handle,
+			Type:    s.Type,
+			Issues:  s.Issues,
+		}
+		if int(s.Atom) < len(atoms) {
+			issues.CommandName = atoms[s.Atom].Class().Schema().Name()
+		}
+		out.Shaders = append(out.Shaders, issues)
+	}
+	sort.Sort(shadersByCommand(out.Shaders))
+	return out
+}
+
+// shaderIssueItems returns a report item for each issue of the analysis, on
+// the command that created or compiled the shader.
+func shaderIssueItems(atoms []atom.Atom, analysis *service.ShaderAnalysis) []*service.ReportItemRaw {
+	out := []*service.ReportItemRaw{}
+	for _, s := range analysis.Shaders {
+		for _, issue := range s.Issues {
+			variable := shaderIssueVariable(issue)
+			var m *stringtable.Msg
+			switch issue.Kind {
+			case gfxapi.ShaderIssueKind_UnusedBinding:
+				m = messages.WarnShaderUnusedBinding(variable, s.Handle)
+			case gfxapi.ShaderIssueKind_UnusedVertexInput:
+				m = messages.WarnShaderUnusedVertexInput(variable, s.Handle)
+			default:
+				m = messages.WarnShaderMediumPrecision(variable, s.Handle)
+			}
+			item := service.WrapReportItem(
+				&service.ReportItem{
+					Severity: service.Severity_WarningLevel,
+					Command:  s.Command,
+				}, m)
+			item.Tags = append(item.Tags, messages.TagShader())
+			if s.Command < uint64(len(atoms)) {
+				item.Tags = append(item.Tags, getAtomNameTag(atoms[s.Command]))
+			}
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// shaderIssueVariable returns the quoted name of the variable of the issue,
+// followed by its binding or location if it has one.
+func shaderIssueVariable(issue *gfxapi.ShaderIssue) string {
+	out := fmt.Sprintf("'%s'", issue.Name)
+	switch {
+	case issue.HasBinding:
+		out += fmt.Sprintf(" (set %d, binding %d)", issue.Set, issue.Binding)
+	case issue.HasLocation:
+		out += fmt.Sprintf(" (location %d)", issue.Location)
+	}
+	return out
+}
+
+// shadersByCommand sorts the shader issues by command.
+type shadersByCommand []*service.ShaderIssues
+
+func (l shadersByCommand) Len() int           { return len(l) }
+func (l shadersByCommand) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l shadersByCommand) Less(i, j int) bool { return l[i].Command < l[j].Command }
@@ -0,0 +1,61 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+)
+
+func TestShaderAnalysis(t *testing.T) {
+	ctx := log.Testing(t)
+	unused := &gfxapi.ShaderIssue{
+		Kind:       gfxapi.ShaderIssueKind_UnusedBinding,
+		Name:       "lights",
+		HasBinding: true,
+		Set:        1,
+		Binding:    2,
+	}
+	precision := &gfxapi.ShaderIssue{
+		Kind:        gfxapi.ShaderIssueKind_MediumPrecisionCandidate,
+		Name:        "color",
+		HasLocation: true,
+	}
+	analysis := shaderAnalysis(nil, []atom.ShaderIssues{
+		{Atom: 7, Handle: 0x20, Type: gfxapi.ShaderType_Spirv, Issues: []*gfxapi.ShaderIssue{precision}},
+		{Atom: 3, Handle: 0x10, Type: gfxapi.ShaderType_Spirv},
+		{Atom: 5, Handle: 0x30, Type: gfxapi.ShaderType_Spirv, Issues: []*gfxapi.ShaderIssue{unused}},
+	})
+	assert.For(ctx, "shaders").That(len(analysis.Shaders)).Equals(2)
+	assert.For(ctx, "first shader").That(analysis.Shaders[0].Command).Equals(uint64(5))
+	assert.For(ctx, "second shader").That(analysis.Shaders[1].Command).Equals(uint64(7))
+
+	assert.For(ctx, "binding").That(shaderIssueVariable(unused)).Equals("'lights' (set 1, binding 2)")
+	assert.For(ctx, "location").That(shaderIssueVariable(precision)).Equals("'color' (location 0)")
+
+	items := shaderIssueItems(nil, analysis)
+	assert.For(ctx, "items").That(len(items)).Equals(2)
+	assert.For(ctx, "unused command").That(items[0].Item.Command).Equals(uint64(5))
+	assert.For(ctx, "unused message").That(items[0].Message.Identifier).Equals(
+		messages.WarnShaderUnusedBinding("", 0).Identifier)
+	assert.For(ctx, "precision message").That(items[1].Message.Identifier).Equals(
+		messages.WarnShaderMediumPrecision("", 0).Identifier)
+	assert.For(ctx, "tag").That(items[1].Tags[0].Identifier).Equals(messages.TagShader().Identifier)
+}
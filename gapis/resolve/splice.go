@@ -0,0 +1,77 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// Splice builds a new capture holding the commands of the capture p, with the
+// commands in the range [start, end) replaced by commands, and returns the
+// path to the new capture.
+func Splice(ctx context.Context, p *path.Capture, start, end uint64, commands *atom.List) (*path.Capture, error) {
+	obj, err := database.Build(ctx, &SpliceResolvable{
+		Capture:  p,
+		Start:    start,
+		End:      end,
+		Commands: service.NewValue(commands),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*path.Capture), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *SpliceResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, ok := r.Commands.Get().(*atom.List)
+	if !ok {
+		return nil, fmt.Errorf("Expected *atom.List, got %T", r.Commands.Get())
+	}
+
+	count := uint64(len(list.Atoms))
+	if r.Start > r.End || r.End > count {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidCommandRange(r.Start, r.End, count),
+		}
+	}
+
+	spliced, err := list.Splice(atom.Range{Start: r.Start, End: r.End}, commands.Atoms...)
+	if err != nil {
+		return nil, err
+	}
+
+	return capture.ImportAtomList(ctx, c.Name+"*", spliced)
+}
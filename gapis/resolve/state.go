@@ -53,11 +53,7 @@ func (r *GlobalStateResolvable) Resolve(ctx context.Context) (interface{}, error
 	if err != nil {
 		return nil, err
 	}
-	s := capture.NewState(ctx)
-	for _, a := range list.Atoms[:r.Path.After.Index+1] {
-		a.Mutate(ctx, s, nil)
-	}
-	return s, nil
+	return mutatedState(ctx, r.Path.After.Commands.Capture, list.Atoms, r.Path.After.Index+1)
 }
 
 // Resolve implements the database.Resolver interface.
@@ -81,9 +77,9 @@ func apiState(ctx context.Context, atoms []atom.Atom, p *path.State) (binary.Obj
 	if api == nil {
 		return nil, &service.ErrDataUnavailable{Reason: messages.ErrStateUnavailable()}
 	}
-	s := capture.NewState(ctx)
-	for _, a := range atoms[:p.After.Index+1] {
-		a.Mutate(ctx, s, nil)
+	s, err := mutatedState(ctx, p.After.Commands.Capture, atoms, p.After.Index+1)
+	if err != nil {
+		return nil, err
 	}
 	res, found := s.APIs[api]
 	if !found {
@@ -0,0 +1,341 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// StateDiff resolves the changes made to the API state by the commands after
+// from, up to and including to. Both commands must belong to the same capture.
+func StateDiff(ctx context.Context, from, to *path.Command) (*service.StateDiff, error) {
+	if !reflect.DeepEqual(from.Commands.Capture, to.Commands.Capture) {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrMessage("Commands belong to different captures"),
+		}
+	}
+	obj, err := database.Build(ctx, &StateDiffResolvable{
+		Capture: from.Commands.Capture,
+		From:    from.Index,
+		To:      to.Index,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.StateDiff), nil
+}
+
+// StateLeaves returns the leaves of the API state after the command p whose
+// path starts with prefix, keyed by their path.
+func StateLeaves(ctx context.Context, p *path.Command, prefix string) (map[string]string, error) {
+	ctx = capture.Put(ctx, p.Commands.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count := uint64(len(list.Atoms))
+	if p.Index >= count {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidCommandRange(p.Index, p.Index, count),
+		}
+	}
+
+	s, err := mutatedState(ctx, p.Commands.Capture, list.Atoms, p.Index+1)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	for k, leaf := range flattenState(s).leaves {
+		if strings.HasPrefix(k, prefix) {
+			out[k] = leaf.value
+		}
+	}
+	return out, nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *StateDiffResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count := uint64(len(list.Atoms))
+	if r.From > r.To || r.To >= count {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidCommandRange(r.From, r.To, count),
+		}
+	}
+
+	s, err := mutatedState(ctx, r.Capture, list.Atoms, r.From+1)
+	if err != nil {
+		return nil, err
+	}
+	before := flattenState(s)
+	for _, a := range list.Atoms[r.From+1 : r.To+1] {
+		a.Mutate(ctx, s, nil /* no builder, just mutate */)
+	}
+	after := flattenState(s)
+
+	return diffState(before, after), nil
+}
+
+// stateValue is a flattened leaf of the API state.
+type stateValue struct {
+	value string
+	ref   bool // value is the path of the object referenced by the leaf.
+}
+
+// flatState is the API state flattened to leaves keyed by their path.
+// objects holds the paths of the objects held by the top-level maps of each
+// API state, which are the objects identified by a handle.
+type flatState struct {
+	leaves  map[string]stateValue
+	objects map[string]bool
+}
+
+// flattenState flattens the state of all the APIs of s.
+func flattenState(s *gfxapi.State) flatState {
+	out := flatState{leaves: map[string]stateValue{}, objects: map[string]bool{}}
+	for api, state := range s.APIs {
+		flattenAPIState(out, api.Name(), state)
+	}
+	return out
+}
+
+// flattenAPIState adds the leaves of the state of the API named api to out.
+func flattenAPIState(out flatState, api string, state interface{}) {
+	f := stateFlattener{out: out, paths: map[uintptr]string{}}
+	v := reflect.Indirect(reflect.ValueOf(state))
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	// Objects held by the handle maps are reported at their handle,
+	// wherever else they are referenced from.
+	f.forEachGlobal(v, api, func(name string, g reflect.Value) {
+		if g.Kind() != reflect.Map {
+			return
+		}
+		for _, k := range sortedKeys(g) {
+			if e := g.MapIndex(k); e.Kind() == reflect.Ptr && !e.IsNil() {
+				p := fmt.Sprintf("%s[%v]", name, k.Interface())
+				f.paths[e.Pointer()] = p
+				out.objects[p] = true
+			}
+		}
+	})
+	f.forEachGlobal(v, api, func(name string, g reflect.Value) {
+		if g.Kind() == reflect.Map {
+			for _, k := range sortedKeys(g) {
+				f.value(fmt.Sprintf("%s[%v]", name, k.Interface()), g.MapIndex(k), true)
+			}
+			return
+		}
+		f.value(name, g, false)
+	})
+}
+
+type stateFlattener struct {
+	out   flatState
+	paths map[uintptr]string // Path of each object already visited.
+}
+
+func (f *stateFlattener) forEachGlobal(v reflect.Value, api string, cb func(string, reflect.Value)) {
+	t := v.Type()
+	for i, c := 0, t.NumField(); i < c; i++ {
+		if field := t.Field(i); !field.Anonymous && field.PkgPath == "" {
+			cb(api+"."+field.Name, v.Field(i))
+		}
+	}
+}
+
+// value flattens v at path p. If owner is true then v is the owning reference
+// of the object, otherwise pointers to already known objects become references.
+func (f *stateFlattener) value(p string, v reflect.Value, owner bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			f.out.leaves[p] = stateValue{value: "nil"}
+			return
+		}
+		if known, ok := f.paths[v.Pointer()]; ok && (!owner || known != p) {
+			f.out.leaves[p] = stateValue{value: known, ref: true}
+			return
+		}
+		if !owner {
+			f.paths[v.Pointer()] = p
+		}
+		f.value(p, v.Elem(), false)
+	case reflect.Interface:
+		if v.IsNil() {
+			f.out.leaves[p] = stateValue{value: "nil"}
+			return
+		}
+		f.value(p, v.Elem(), false)
+	case reflect.Struct:
+		if s, ok := stringer(v); ok {
+			f.out.leaves[p] = stateValue{value: s}
+			return
+		}
+		t := v.Type()
+		for i, c := 0, t.NumField(); i < c; i++ {
+			if field := t.Field(i); !field.Anonymous && field.PkgPath == "" {
+				f.value(p+"."+field.Name, v.Field(i), false)
+			}
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(v) {
+			f.value(fmt.Sprintf("%s[%v]", p, k.Interface()), v.MapIndex(k), false)
+		}
+	case reflect.Slice, reflect.Array:
+		for i, c := 0, v.Len(); i < c; i++ {
+			f.value(fmt.Sprintf("%s[%d]", p, i), v.Index(i), false)
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+	default:
+		f.out.leaves[p] = stateValue{value: fmt.Sprint(v.Interface())}
+	}
+}
+
+// sortedKeys returns the keys of the map v, ordered so that the objects
+// referenced from multiple places are flattened at the same path every time.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Sort(mapKeys(keys))
+	return keys
+}
+
+type mapKeys []reflect.Value
+
+func (l mapKeys) Len() int      { return len(l) }
+func (l mapKeys) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l mapKeys) Less(i, j int) bool {
+	a, b := l[i], l[j]
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+// stringer returns the String() of v if its type implements fmt.Stringer.
+func stringer(v reflect.Value) (string, bool) {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
+
+// diffState returns the changes between the flattened states a and b.
+func diffState(a, b flatState) *service.StateDiff {
+	out := &service.StateDiff{}
+	for p := range b.objects {
+		if !a.objects[p] {
+			out.Changes = append(out.Changes, &service.StateChange{
+				Kind: service.StateChangeKind_StateCreated,
+				Path: p,
+			})
+		}
+	}
+	for p := range a.objects {
+		if !b.objects[p] {
+			out.Changes = append(out.Changes, &service.StateChange{
+				Kind: service.StateChangeKind_StateDestroyed,
+				Path: p,
+			})
+		}
+	}
+
+	// inObject returns true if p is a leaf of an object created or destroyed.
+	inObject := func(p string, objects map[string]bool) bool {
+		for i := range p {
+			if (p[i] == '.' || p[i] == '[') && objects[p[:i]] {
+				return true
+			}
+		}
+		return objects[p]
+	}
+	created := map[string]bool{}
+	destroyed := map[string]bool{}
+	for _, c := range out.Changes {
+		if c.Kind == service.StateChangeKind_StateCreated {
+			created[c.Path] = true
+		} else {
+			destroyed[c.Path] = true
+		}
+	}
+
+	change := func(p string, old, new stateValue) {
+		kind := service.StateChangeKind_StateChanged
+		if old.ref || new.ref {
+			kind = service.StateChangeKind_StateRebound
+		}
+		out.Changes = append(out.Changes, &service.StateChange{
+			Kind:     kind,
+			Path:     p,
+			OldValue: old.value,
+			NewValue: new.value,
+		})
+	}
+	for p, new := range b.leaves {
+		old, ok := a.leaves[p]
+		switch {
+		case !ok && !inObject(p, created):
+			change(p, stateValue{}, new)
+		case ok && old != new:
+			change(p, old, new)
+		}
+	}
+	for p, old := range a.leaves {
+		if _, ok := b.leaves[p]; !ok && !inObject(p, destroyed) {
+			change(p, old, stateValue{})
+		}
+	}
+
+	sort.Sort(stateChangesByPath(out.Changes))
+	return out
+}
+
+type stateChangesByPath []*service.StateChange
+
+func (l stateChangesByPath) Len() int           { return len(l) }
+func (l stateChangesByPath) Less(i, j int) bool { return l[i].Path < l[j].Path }
+func (l stateChangesByPath) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
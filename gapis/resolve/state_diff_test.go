@@ -0,0 +1,75 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+type testBuffer struct {
+	Size  uint64
+	Usage string
+}
+
+type testBinding struct {
+	Buffer *testBuffer
+	Offset uint64
+}
+
+type testNode struct {
+	Next  *testNode
+	Value int
+}
+
+type testState struct {
+	Buffers map[uint64]*testBuffer
+	Bound   testBinding
+	Frame   int
+	Cycle   *testNode
+}
+
+func TestDiffState(t *testing.T) {
+	ctx := log.Testing(t)
+
+	node := &testNode{Value: 1}
+	node.Next = node
+	s := &testState{
+		Buffers: map[uint64]*testBuffer{1: {10, "vertex"}, 2: {20, "index"}},
+		Cycle:   node,
+	}
+	s.Bound.Buffer = s.Buffers[1]
+	before := flatState{leaves: map[string]stateValue{}, objects: map[string]bool{}}
+	flattenAPIState(before, "Test", s)
+
+	delete(s.Buffers, 2)
+	s.Buffers[3] = &testBuffer{30, "uniform"}
+	s.Buffers[1].Size = 11
+	s.Bound.Buffer = s.Buffers[3]
+	s.Frame = 1
+	after := flatState{leaves: map[string]stateValue{}, objects: map[string]bool{}}
+	flattenAPIState(after, "Test", s)
+
+	assert.With(ctx).ThatSlice(diffState(before, after).Changes).DeepEquals([]*service.StateChange{
+		{Kind: service.StateChangeKind_StateRebound, Path: "Test.Bound.Buffer", OldValue: "Test.Buffers[1]", NewValue: "Test.Buffers[3]"},
+		{Kind: service.StateChangeKind_StateChanged, Path: "Test.Buffers[1].Size", OldValue: "10", NewValue: "11"},
+		{Kind: service.StateChangeKind_StateDestroyed, Path: "Test.Buffers[2]"},
+		{Kind: service.StateChangeKind_StateCreated, Path: "Test.Buffers[3]"},
+		{Kind: service.StateChangeKind_StateChanged, Path: "Test.Frame", OldValue: "0", NewValue: "1"},
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+)
+
+// stateOverride returns the replay state override described by o, and
+// whether it overrides any of the fixed-function state of the draw calls.
+func stateOverride(o *service.StateOverride) (replay.StateOverride, bool, error) {
+	if o == nil {
+		return replay.StateOverride{}, false, nil
+	}
+	switch o.CullMode {
+	case service.CullModeOverride_KeepCullMode,
+		service.CullModeOverride_CullNone,
+		service.CullModeOverride_CullFront,
+		service.CullModeOverride_CullBack:
+	default:
+		return replay.StateOverride{}, false, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidEnumValue(o.CullMode, "CullModeOverride"),
+		}
+	}
+	out := replay.StateOverride{
+		Draw:             atom.NoID,
+		DisableDepthTest: o.DisableDepthTest,
+		CullMode:         o.CullMode,
+		ForceCCW:         o.ForceCcw,
+		DisableBlending:  o.DisableBlending,
+	}
+	if o.Draw != nil {
+		out.Draw = atom.ID(o.Draw.Index)
+	}
+	overrides := out.DisableDepthTest ||
+		out.CullMode != service.CullModeOverride_KeepCullMode ||
+		out.ForceCCW ||
+		out.DisableBlending
+	return out, overrides, nil
+}
+
+// opaqueAlpha returns the color attachment img as 32 bit floating point RGBA,
+// with its alpha channel set to fully opaque. Depth and stencil attachments
+// are returned unaltered.
+func opaqueAlpha(img *image.Image2D, attachment gfxapi.FramebufferAttachment) (*image.Image2D, error) {
+	switch attachment {
+	case gfxapi.FramebufferAttachment_Depth, gfxapi.FramebufferAttachment_Stencil:
+		return img, nil
+	}
+	img, err := img.Convert(image.RGBA_F32)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(img.Data))
+	copy(data, img.Data)
+	for i := 12; i+4 <= len(data); i += 16 {
+		binary.LittleEndian.PutUint32(data[i:], math.Float32bits(1))
+	}
+	return &image.Image2D{
+		Data:   data,
+		Width:  img.Width,
+		Height: img.Height,
+		Format: image.RGBA_F32,
+	}, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+func TestStateOverride(t *testing.T) {
+	ctx := log.Testing(t)
+
+	_, overrides, err := stateOverride(nil)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "nil overrides").That(overrides).Equals(false)
+
+	_, overrides, err = stateOverride(&service.StateOverride{OpaqueAlpha: true})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "opaque alpha overrides").That(overrides).Equals(false)
+
+	o, overrides, err := stateOverride(&service.StateOverride{
+		Draw:     &path.Command{Index: 12},
+		CullMode: service.CullModeOverride_CullNone,
+	})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "cull mode overrides").That(overrides).Equals(true)
+	assert.For(ctx, "draw").That(o.Draw).Equals(atom.ID(12))
+
+	o, _, err = stateOverride(&service.StateOverride{DisableDepthTest: true})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "all draws").That(o.Draw).Equals(atom.NoID)
+
+	_, _, err = stateOverride(&service.StateOverride{CullMode: 42})
+	assert.For(ctx, "invalid cull mode").ThatError(err).Failed()
+}
+
+func TestOpaqueAlpha(t *testing.T) {
+	ctx := log.Testing(t)
+
+	img := &image.Image2D{
+		Format: image.RGBA_U8_NORM,
+		Width:  2,
+		Height: 1,
+		Data:   []byte{0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x80},
+	}
+	opaque, err := opaqueAlpha(img, gfxapi.FramebufferAttachment_Color0)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	opaque, err = opaque.Convert(image.RGBA_U8_NORM)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "data").ThatSlice(opaque.Data).Equals([]byte{0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff})
+
+	depth, err := opaqueAlpha(img, gfxapi.FramebufferAttachment_Depth)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "depth").That(depth).Equals(img)
+}
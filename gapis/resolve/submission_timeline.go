@@ -0,0 +1,118 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// SubmissionTimeline resolves the queue submissions of the capture c, with
+// their semaphore, fence and queue order dependencies.
+func SubmissionTimeline(ctx context.Context, c *path.Capture) (*service.SubmissionTimeline, error) {
+	obj, err := database.Build(ctx, &SubmissionTimelineResolvable{Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.SubmissionTimeline), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *SubmissionTimelineResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions := []atom.Submission{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if t, ok := api.(atom.SubmissionTracker); ok {
+			s, err := t.Submissions(ctx)
+			if err != nil {
+				return nil, err
+			}
+			submissions = append(submissions, s...)
+		}
+	}
+	return submissionTimeline(list.Atoms, submissions), nil
+}
+
+// submissionTimeline builds the graph of the submissions, which are in atom
+// order. A semaphore wait depends on the last signal of the semaphore and
+// consumes it, a fence wait depends on the last signals of its fences, and
+// each submission to a queue depends on the previous one.
+func submissionTimeline(atoms []atom.Atom, submissions []atom.Submission) *service.SubmissionTimeline {
+	out := &service.SubmissionTimeline{}
+	signaledSemaphores := map[uint64]uint32{}
+	signaledFences := map[uint64]uint32{}
+	queues := map[uint64]uint32{}
+	edge := func(from, to uint32, kind service.SubmissionEdgeKind, handle uint64) {
+		out.Edges = append(out.Edges, &service.SubmissionEdge{From: from, To: to, Kind: kind, Handle: handle})
+	}
+	for _, s := range submissions {
+		i := uint32(len(out.Nodes))
+		node := &service.SubmissionNode{
+			Command: uint64(s.Atom),
+			Kind:    service.SubmissionKind(s.Kind),
+			Queue:   s.Queue,
+			Waits:   s.Waits,
+			Signals: s.Signals,
+			Fences:  s.Fences,
+		}
+		if int(s.Atom) < len(atoms) {
+			node.CommandName = atoms[s.Atom].Class().Schema().Name()
+		}
+		out.Nodes = append(out.Nodes, node)
+
+		if s.Queue != 0 {
+			if prev, ok := queues[s.Queue]; ok {
+				edge(prev, i, service.SubmissionEdgeKind_QueueOrder, s.Queue)
+			}
+			queues[s.Queue] = i
+		}
+		for _, semaphore := range s.Waits {
+			if from, ok := signaledSemaphores[semaphore]; ok {
+				edge(from, i, service.SubmissionEdgeKind_Semaphore, semaphore)
+				delete(signaledSemaphores, semaphore)
+			} else {
+				node.UnsignaledWaits = append(node.UnsignaledWaits, semaphore)
+			}
+		}
+		for _, semaphore := range s.Signals {
+			signaledSemaphores[semaphore] = i
+		}
+		for _, fence := range s.Fences {
+			if s.Kind != atom.FenceWait {
+				signaledFences[fence] = i
+			} else if from, ok := signaledFences[fence]; ok {
+				edge(from, i, service.SubmissionEdgeKind_Fence, fence)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestSubmissionTimeline(t *testing.T) {
+	ctx := log.Testing(t)
+	const compute, graphics = 10, 20
+	timeline := submissionTimeline(nil, []atom.Submission{
+		{Atom: 1, Kind: atom.ImageAcquire, Signals: []uint64{1}},
+		{Atom: 2, Kind: atom.QueueSubmit, Queue: compute, Signals: []uint64{2}},
+		{Atom: 3, Kind: atom.QueueSubmit, Queue: graphics, Waits: []uint64{1, 2}, Signals: []uint64{3}, Fences: []uint64{4}},
+		{Atom: 4, Kind: atom.QueuePresent, Queue: graphics, Waits: []uint64{3}},
+		{Atom: 5, Kind: atom.QueueSubmit, Queue: graphics, Waits: []uint64{2}},
+		{Atom: 6, Kind: atom.FenceWait, Fences: []uint64{4}},
+	})
+	assert.For(ctx, "nodes").That(len(timeline.Nodes)).Equals(6)
+	assert.For(ctx, "kind").That(timeline.Nodes[3].Kind).Equals(service.SubmissionKind_QueuePresent)
+	assert.For(ctx, "unsignaled").ThatSlice(timeline.Nodes[4].UnsignaledWaits).Equals([]uint64{2})
+	assert.For(ctx, "edges").ThatSlice(timeline.Edges).DeepEquals([]*service.SubmissionEdge{
+		{From: 0, To: 2, Kind: service.SubmissionEdgeKind_Semaphore, Handle: 1},
+		{From: 1, To: 2, Kind: service.SubmissionEdgeKind_Semaphore, Handle: 2},
+		{From: 2, To: 3, Kind: service.SubmissionEdgeKind_QueueOrder, Handle: graphics},
+		{From: 2, To: 3, Kind: service.SubmissionEdgeKind_Semaphore, Handle: 3},
+		{From: 3, To: 4, Kind: service.SubmissionEdgeKind_QueueOrder, Handle: graphics},
+		{From: 2, To: 5, Kind: service.SubmissionEdgeKind_Fence, Handle: 4},
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// checkerboardColors are the colors of the squares of the CheckerboardPattern.
+var checkerboardColors = [2][4]byte{{0xff, 0x00, 0xff, 0xff}, {0x00, 0x00, 0x00, 0xff}}
+
+// overriddenTexture returns the texture resource of the override o, as
+// created by the commands up to after.
+func overriddenTexture(ctx context.Context, after *path.Command, o *service.TextureOverride) (gfxapi.Resource, error) {
+	obj, err := database.Build(ctx, &AllResourceDataResolvable{after})
+	if err != nil {
+		return nil, err
+	}
+	id := o.Texture.ID()
+	texture, ok := obj.(*ResolvedResources).resources[id]
+	if !ok {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrMessage(fmt.Sprintf("Unknown texture resource %v", id)),
+		}
+	}
+	return texture, nil
+}
+
+// texturePattern returns the pattern generating the data of the override o.
+func texturePattern(o *service.TextureOverride) (replay.TexturePattern, error) {
+	switch o.Pattern {
+	case service.TexturePattern_CheckerboardPattern:
+		return func(width, height int) ([]byte, error) {
+			data := make([]byte, 0, width*height*4)
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					c := checkerboardColors[(x/checkerboardSize+y/checkerboardSize)%2]
+					data = append(data, c[:]...)
+				}
+			}
+			return data, nil
+		}, nil
+
+	case service.TexturePattern_SolidColorPattern:
+		c := [4]byte{unorm8(o.Red), unorm8(o.Green), unorm8(o.Blue), unorm8(o.Alpha)}
+		return func(width, height int) ([]byte, error) {
+			data := make([]byte, 0, width*height*4)
+			for i := 0; i < width*height; i++ {
+				data = append(data, c[:]...)
+			}
+			return data, nil
+		}, nil
+
+	case service.TexturePattern_ImagePattern:
+		if o.Image == nil || o.Image.Width == 0 || o.Image.Height == 0 {
+			return nil, &service.ErrInvalidArgument{
+				Reason: messages.ErrMessage("The image pattern requires a non-empty image"),
+			}
+		}
+		img, err := o.Image.Convert(image.RGBA_U8_NORM)
+		if err != nil {
+			return nil, err
+		}
+		return func(width, height int) ([]byte, error) {
+			return image.RGBA_U8_NORM.Resize(img.Data, int(img.Width), int(img.Height), width, height)
+		}, nil
+
+	default:
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidEnumValue(o.Pattern, "TexturePattern")}
+	}
+}
+
+// unorm8 returns the normalized value v, clamped to [0, 1], as a byte.
+func unorm8(v float32) byte {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 1:
+		return 0xff
+	default:
+		return byte(v*0xff + 0.5)
+	}
+}
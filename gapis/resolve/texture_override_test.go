@@ -0,0 +1,62 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service"
+)
+
+func TestTexturePattern(t *testing.T) {
+	ctx := log.Testing(t)
+
+	checkerboard, err := texturePattern(&service.TextureOverride{Pattern: service.TexturePattern_CheckerboardPattern})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	data, err := checkerboard(16, 1)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "first square").ThatSlice(data[:4]).Equals([]byte{0xff, 0x00, 0xff, 0xff})
+	assert.For(ctx, "second square").ThatSlice(data[32:36]).Equals([]byte{0x00, 0x00, 0x00, 0xff})
+
+	solid, err := texturePattern(&service.TextureOverride{
+		Pattern: service.TexturePattern_SolidColorPattern,
+		Red:     1, Green: 0.5, Blue: -1, Alpha: 2,
+	})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	data, err = solid(2, 2)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "size").That(len(data)).Equals(16)
+	assert.For(ctx, "color").ThatSlice(data[12:]).Equals([]byte{0xff, 0x80, 0x00, 0xff})
+
+	img, err := texturePattern(&service.TextureOverride{
+		Pattern: service.TexturePattern_ImagePattern,
+		Image: &image.Image2D{
+			Format: image.RGBA_U8_NORM,
+			Width:  1,
+			Height: 1,
+			Data:   []byte{0x10, 0x20, 0x30, 0x40},
+		},
+	})
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	data, err = img(2, 1)
+	assert.For(ctx, "err").ThatError(err).Succeeded()
+	assert.For(ctx, "resized").ThatSlice(data).Equals([]byte{0x10, 0x20, 0x30, 0x40, 0x10, 0x20, 0x30, 0x40})
+
+	_, err = texturePattern(&service.TextureOverride{Pattern: service.TexturePattern_ImagePattern})
+	assert.For(ctx, "missing image").ThatError(err).Failed()
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/replay"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// TimingProfile resolves the GPU execution time of the draw and dispatch
+// commands of the capture c, replayed on the given device.
+func TimingProfile(ctx context.Context, d *path.Device, c *path.Capture) (*service.TimingProfile, error) {
+	obj, err := database.Build(ctx, &TimingProfileResolvable{Device: d, Capture: c})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*service.TimingProfile), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *TimingProfileResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	intent := replay.Intent{
+		Capture: r.Capture,
+		Device:  r.Device,
+	}
+	mgr := replay.GetManager(ctx)
+
+	// Capture can use multiple APIs. Collect the timings of all the APIs
+	// supporting the QueryTimings interface.
+	out := &service.TimingProfile{}
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		qt, ok := api.(replay.QueryTimings)
+		if !ok {
+			continue
+		}
+		timings, err := qt.QueryTimings(ctx, intent, mgr, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range timings {
+			out.Commands = append(out.Commands, &service.CommandTiming{
+				Command:  r.Capture.Commands().Index(uint64(t.Atom)),
+				Duration: uint64(t.Duration.Nanoseconds()),
+				Count:    t.Count,
+			})
+		}
+	}
+
+	sort.Sort(byDuration(out.Commands))
+	return out, nil
+}
+
+// byDuration sorts the command timings by decreasing duration.
+type byDuration []*service.CommandTiming
+
+func (l byDuration) Len() int      { return len(l) }
+func (l byDuration) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l byDuration) Less(i, j int) bool {
+	if l[i].Duration != l[j].Duration {
+		return l[i].Duration > l[j].Duration
+	}
+	return l[i].Command.Index < l[j].Command.Index
+}
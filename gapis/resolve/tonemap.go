@@ -0,0 +1,132 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+)
+
+// tonemapFormat returns the format of a color attachment of the format f once
+// tonemapped with t.
+func tonemapFormat(f *image.Format, t *service.Tonemap) *image.Format {
+	if t == nil {
+		return f
+	}
+	return image.RGBA_U8_NORM
+}
+
+// tonemap returns the color image img mapped to 8 bit normalized RGBA using t.
+// If t is nil then img is returned unaltered.
+func tonemap(img *image.Image2D, t *service.Tonemap) (*image.Image2D, error) {
+	if t == nil {
+		return img, nil
+	}
+	if t.Gamma < 0 {
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrMessage("The gamma of a tonemap must not be negative")}
+	}
+
+	// channels lists the offsets of the channels used for display.
+	var channels []int
+	switch t.Channel {
+	case service.TonemapChannel_AllChannels:
+		channels = []int{0, 1, 2}
+	case service.TonemapChannel_RedChannel:
+		channels = []int{0}
+	case service.TonemapChannel_GreenChannel:
+		channels = []int{1}
+	case service.TonemapChannel_BlueChannel:
+		channels = []int{2}
+	case service.TonemapChannel_AlphaChannel:
+		channels = []int{3}
+	default:
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrInvalidEnumValue(t.Channel, "TonemapChannel")}
+	}
+
+	img, err := img.Convert(image.RGBA_F32)
+	if err != nil {
+		return nil, err
+	}
+	r := endian.Reader(bytes.NewReader(img.Data), device.LittleEndian)
+	values := make([]float64, len(img.Data)/4)
+	for i := range values {
+		values[i] = float64(r.Float32())
+	}
+	if err := r.Error(); err != nil {
+		return nil, err
+	}
+
+	offset, scale := 0.0, math.Exp2(float64(t.Exposure))
+	if t.Normalize {
+		min, max := math.Inf(1), math.Inf(-1)
+		for i := 0; i < len(values); i += 4 {
+			for _, c := range channels {
+				if v := values[i+c]; !math.IsNaN(v) && !math.IsInf(v, 0) {
+					min, max = math.Min(min, v), math.Max(max, v)
+				}
+			}
+		}
+		if min < max {
+			offset, scale = min, scale/(max-min)
+		}
+	}
+
+	toU8 := func(v float64) byte {
+		if math.IsNaN(v) || v <= 0 {
+			return 0
+		}
+		if t.Gamma > 0 {
+			v = math.Pow(v, 1/float64(t.Gamma))
+		}
+		if v >= 1 {
+			return 0xff
+		}
+		return byte(v*0xff + 0.5)
+	}
+
+	data := make([]byte, len(values))
+	for i := 0; i < len(values); i += 4 {
+		if len(channels) == 1 {
+			v := toU8((values[i+channels[0]] - offset) * scale)
+			data[i], data[i+1], data[i+2], data[i+3] = v, v, v, 0xff
+			continue
+		}
+		for _, c := range channels {
+			data[i+c] = toU8((values[i+c] - offset) * scale)
+		}
+		a := values[i+3]
+		switch {
+		case math.IsNaN(a) || a <= 0:
+			data[i+3] = 0
+		case a >= 1:
+			data[i+3] = 0xff
+		default:
+			data[i+3] = byte(a*0xff + 0.5)
+		}
+	}
+
+	return &image.Image2D{
+		Data:   data,
+		Width:  img.Width,
+		Height: img.Height,
+		Format: image.RGBA_U8_NORM,
+	}, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/core/image"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/os/device"
+	"github.com/google/gapid/gapis/service"
+)
+
+func colorImage(rgba ...float32) *image.Image2D {
+	buf := &bytes.Buffer{}
+	w := endian.Writer(buf, device.LittleEndian)
+	for _, v := range rgba {
+		w.Float32(v)
+	}
+	return &image.Image2D{Data: buf.Bytes(), Width: uint32(len(rgba) / 4), Height: 1, Format: image.RGBA_F32}
+}
+
+func TestTonemap(t *testing.T) {
+	ctx := log.Testing(t)
+	hdr := []float32{
+		0, 0.5, 2, 0.5,
+		4, 1, 0.25, 1,
+	}
+	for _, test := range []struct {
+		name     string
+		tonemap  *service.Tonemap
+		expected []byte
+	}{
+		{"clamp", &service.Tonemap{}, []byte{
+			0x00, 0x80, 0xff, 0x80,
+			0xff, 0xff, 0x40, 0xff,
+		}},
+		{"exposure", &service.Tonemap{Exposure: -2}, []byte{
+			0x00, 0x20, 0x80, 0x80,
+			0xff, 0x40, 0x10, 0xff,
+		}},
+		{"gamma", &service.Tonemap{Gamma: 0.5}, []byte{
+			0x00, 0x40, 0xff, 0x80,
+			0xff, 0xff, 0x10, 0xff,
+		}},
+		{"isolate", &service.Tonemap{Channel: service.TonemapChannel_BlueChannel}, []byte{
+			0xff, 0xff, 0xff, 0xff,
+			0x40, 0x40, 0x40, 0xff,
+		}},
+		{"normalize", &service.Tonemap{Channel: service.TonemapChannel_RedChannel, Normalize: true}, []byte{
+			0x00, 0x00, 0x00, 0xff,
+			0xff, 0xff, 0xff, 0xff,
+		}},
+	} {
+		ctx := log.V{"test": test.name}.Bind(ctx)
+		got, err := tonemap(colorImage(hdr...), test.tonemap)
+		if assert.For(ctx, "err").ThatError(err).Succeeded() {
+			assert.For(ctx, "format").That(got.Format).Equals(image.RGBA_U8_NORM)
+			assert.For(ctx, "data").That(got.Data).DeepEquals(test.expected)
+		}
+	}
+
+	img := colorImage(hdr...)
+	got, err := tonemap(img, nil)
+	if assert.For(ctx, "err").ThatError(err).Succeeded() {
+		assert.For(ctx, "raw").That(got).Equals(img)
+	}
+
+	_, err = tonemap(colorImage(hdr...), &service.Tonemap{Gamma: -1})
+	assert.For(ctx, "negative gamma").ThatError(err).Failed()
+}
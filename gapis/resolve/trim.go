@@ -0,0 +1,230 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/core/math/interval"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/messages"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+// Trim builds a new capture holding only the commands required to replay the
+// frames firstFrame to lastFrame (inclusive) of the capture p, and returns the
+// path to the new capture. Frames are numbered from 1, as in the command
+// hierarchy.
+//
+// The commands before the first frame are only kept if the frames depend on
+// them, so the head of the new capture holds the commands that create and
+// initialize the state used by the frames.
+//
+// The memory observations and resources of the eliminated commands are not
+// part of the new capture, and the reads of the kept commands that observe
+// data already in memory are dropped, so the trimmed capture shrinks in size
+// and not just in command count.
+func Trim(ctx context.Context, p *path.Capture, firstFrame, lastFrame uint64) (*path.Capture, error) {
+	obj, err := database.Build(ctx, &TrimResolvable{
+		Capture:    p,
+		FirstFrame: firstFrame,
+		LastFrame:  lastFrame,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*path.Capture), nil
+}
+
+// Resolve implements the database.Resolver interface.
+func (r *TrimResolvable) Resolve(ctx context.Context) (interface{}, error) {
+	ctx = capture.Put(ctx, r.Capture)
+
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ends := []int{}
+	for i, a := range list.Atoms {
+		if a.AtomFlags().IsEndOfFrame() {
+			ends = append(ends, i)
+		}
+	}
+
+	count := uint64(len(ends))
+	if r.FirstFrame == 0 || r.FirstFrame > r.LastFrame || r.LastFrame > count {
+		return nil, &service.ErrInvalidArgument{
+			Reason: messages.ErrInvalidFrameRange(r.FirstFrame, r.LastFrame, count),
+		}
+	}
+
+	start, end := 0, ends[r.LastFrame-1]
+	if r.FirstFrame > 1 {
+		start = ends[r.FirstFrame-2] + 1
+	}
+
+	var tracker atom.DependencyTracker
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if t, ok := api.(atom.DependencyTracker); ok {
+			tracker = t
+			break
+		}
+	}
+	if tracker == nil {
+		name := "unknown"
+		if a := list.Atoms[end].API(); a != nil {
+			name = a.Name()
+		}
+		return nil, &service.ErrInvalidArgument{Reason: messages.ErrDependenciesNotSupported(name)}
+	}
+
+	// Request every command of the frames, so that all their output is kept.
+	requests := make([]atom.ID, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		requests = append(requests, atom.ID(i))
+	}
+
+	ids, err := tracker.Dependencies(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := atom.NewList()
+	for _, i := range ids {
+		trimmed.Add(list.Atoms[i])
+	}
+
+	trimmed, err = pruneObservations(ctx, trimmed)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%v [frames %v-%v]", c.Name, r.FirstFrame, r.LastFrame)
+	return capture.ImportAtomList(ctx, name, trimmed)
+}
+
+// pruneObservations returns a copy of the atom list l without the read
+// observations of data that the memory already holds when the command is
+// executed. Such reads are common once the commands that modified the memory
+// in between have been eliminated, and dropping them also drops their
+// resources from the exported capture.
+func pruneObservations(ctx context.Context, l *atom.List) (*atom.List, error) {
+	out := atom.NewList(make([]atom.Atom, 0, len(l.Atoms))...)
+	observed := observationList{}
+	count, size := 0, uint64(0)
+	for _, a := range l.Atoms {
+		obs := a.Extras().Observations()
+		if obs == nil {
+			out.Add(a)
+			continue
+		}
+		reads := make([]atom.Observation, 0, len(obs.Reads))
+		for _, o := range obs.Reads {
+			if observed.holds(o) {
+				count, size = count+1, size+o.Range.Size
+				continue
+			}
+			observed.observe(o)
+			reads = append(reads, o)
+		}
+		for _, o := range obs.Writes {
+			observed.observe(o)
+		}
+		if len(reads) == len(obs.Reads) {
+			out.Add(a)
+			continue
+		}
+
+		// The atom is copied, as it is shared with the original capture.
+		obj, err := clone(reflect.ValueOf(a))
+		if err != nil {
+			return nil, err
+		}
+		c := obj.Interface().(atom.Atom)
+		extras := make(atom.Extras, len(c.Extras().All()))
+		for i, e := range c.Extras().All() {
+			if e == obs {
+				e = &atom.Observations{Reads: reads, Writes: obs.Writes}
+			}
+			extras[i] = e
+		}
+		*c.Extras() = extras
+		out.Add(c)
+	}
+	log.D(ctx, "Trim: pruned %v redundant reads of %v bytes", count, size)
+	return out, nil
+}
+
+// observationList is an interval.List of the last observation of each range
+// of memory.
+type observationList []atom.Observation
+
+// holds returns true if the last observation of the range of o observed the
+// same data as o.
+func (l *observationList) holds(o atom.Observation) bool {
+	first, count := interval.Intersect(l, o.Range.Span())
+	return count == 1 && (*l)[first] == o
+}
+
+// observe records o as the last observation of its range.
+func (l *observationList) observe(o atom.Observation) {
+	i := interval.Replace(l, o.Range.Span())
+	(*l)[i].ID = o.ID
+}
+
+func (l *observationList) Length() int {
+	return len(*l)
+}
+
+func (l *observationList) GetSpan(index int) interval.U64Span {
+	return (*l)[index].Range.Span()
+}
+
+func (l *observationList) SetSpan(index int, span interval.U64Span) {
+	(*l)[index].Range = memory.Range{Base: span.Start, Size: span.End - span.Start}
+}
+
+func (l *observationList) New(index int, span interval.U64Span) {
+	(*l)[index].Range = memory.Range{Base: span.Start, Size: span.End - span.Start}
+}
+
+func (l *observationList) Copy(to, from, count int) {
+	copy((*l)[to:to+count], (*l)[from:from+count])
+}
+
+func (l *observationList) Resize(length int) {
+	if cap(*l) > length {
+		*l = (*l)[:length]
+	} else {
+		old := *l
+		*l = make(observationList, length, length*2)
+		copy(*l, old)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/memory"
+)
+
+func TestObservationList(t *testing.T) {
+	ctx := log.Testing(t)
+	a, b := id.ID{1}, id.ID{2}
+	obs := func(base, size uint64, data id.ID) atom.Observation {
+		return atom.Observation{Range: memory.Range{Base: base, Size: size}, ID: data}
+	}
+
+	l := observationList{}
+	assert.For(ctx, "empty").That(l.holds(obs(0x100, 0x10, a))).Equals(false)
+
+	l.observe(obs(0x100, 0x10, a))
+	assert.For(ctx, "same data").That(l.holds(obs(0x100, 0x10, a))).Equals(true)
+	assert.For(ctx, "other data").That(l.holds(obs(0x100, 0x10, b))).Equals(false)
+	assert.For(ctx, "other range").That(l.holds(obs(0x100, 0x08, a))).Equals(false)
+
+	// An overlapping observation replaces the data.
+	l.observe(obs(0x108, 0x10, b))
+	assert.For(ctx, "overwritten").That(l.holds(obs(0x100, 0x10, a))).Equals(false)
+	assert.For(ctx, "new data").That(l.holds(obs(0x108, 0x10, b))).Equals(true)
+}
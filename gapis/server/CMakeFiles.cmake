@@ -19,6 +19,12 @@
 
 set(files
     grpc.go
+    origins.go
+    origins_test.go
+    prefetch.go
+    prefetch_test.go
+    requests.go
+    scripting.go
     server.go
 )
 set(dirs
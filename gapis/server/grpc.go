@@ -24,8 +24,11 @@ import (
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/log/log_pb"
 	"github.com/google/gapid/core/net/grpcutil"
+	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/scripting"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	xctx "golang.org/x/net/context"
 )
@@ -33,6 +36,9 @@ import (
 // Listen starts a new GRPC server listening on addr.
 // This is a blocking call.
 func Listen(ctx context.Context, addr string, cfg Config) error {
+	if len(cfg.AllowedOrigins) > 0 && cfg.AuthToken == auth.NoAuth {
+		return fmt.Errorf("Remote clients can only be allowed with an auth token")
+	}
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.F(ctx, "Could not start grpc server at %v: %s", addr, err.Error())
@@ -41,26 +47,47 @@ func Listen(ctx context.Context, addr string, cfg Config) error {
 }
 
 func NewWithListener(ctx context.Context, l net.Listener, cfg Config, srvChan chan<- *grpc.Server) error {
-	s := NewGapidServer(ctx, cfg)
+	h := newServer(ctx, cfg)
+	s := newGRPCServer(ctx, h)
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(chainInterceptors(
+			auth.ServerInterceptor(cfg.AuthToken),
+			h.requests.interceptor,
+		)),
+		grpc.StreamInterceptor(auth.StreamServerInterceptor(cfg.AuthToken)),
+	}
+	if cfg.TLS != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+	if _, ok := l.Addr().(*net.TCPAddr); ok {
+		l = &originListener{Listener: l, allowed: cfg.AllowedOrigins, ctx: ctx}
+	}
 	return grpcutil.ServeWithListener(ctx, l, func(ctx context.Context, listener net.Listener, server *grpc.Server) error {
 		if addr, ok := listener.Addr().(*net.TCPAddr); ok {
 			// The following message is parsed by launchers to detect the selected port. DO NOT CHANGE!
 			fmt.Printf("Bound on port '%d'\n", addr.Port)
 		}
 		service.RegisterGapidServer(server, s)
+		if cfg.EnableScripting {
+			scripting.RegisterScriptingServer(server, newScriptingServer(ctx, h))
+		}
 
 		if srvChan != nil {
 			srvChan <- server
 		}
 		return nil
-	}, grpc.UnaryInterceptor(auth.ServerInterceptor(cfg.AuthToken)))
+	}, options...)
 }
 
 // NewGapidServer returns a GapidServer interface to a new server instace.
 func NewGapidServer(ctx context.Context, cfg Config) service.GapidServer {
+	return newGRPCServer(ctx, New(ctx, cfg))
+}
+
+func newGRPCServer(ctx context.Context, handler Server) *grpcServer {
 	outer := ctx
 	return &grpcServer{
-		handler: New(ctx, cfg),
+		handler: handler,
 		bindCtx: func(ctx context.Context) context.Context { return keys.Clone(ctx, outer) },
 	}
 }
@@ -135,6 +162,14 @@ func (s *grpcServer) GetProfile(ctx xctx.Context, req *service.GetProfileRequest
 	return &service.GetProfileResponse{Res: &service.GetProfileResponse_Data{Data: data}}, nil
 }
 
+func (s *grpcServer) CancelRequests(ctx xctx.Context, req *service.CancelRequestsRequest) (*service.CancelRequestsResponse, error) {
+	count, err := s.handler.CancelRequests(s.bindCtx(ctx), req.Ids)
+	if err := service.NewError(err); err != nil {
+		return &service.CancelRequestsResponse{Res: &service.CancelRequestsResponse_Error{Error: err}}, nil
+	}
+	return &service.CancelRequestsResponse{Res: &service.CancelRequestsResponse_Cancelled{Cancelled: count}}, nil
+}
+
 func (s *grpcServer) GetSchema(ctx xctx.Context, req *service.GetSchemaRequest) (*service.GetSchemaResponse, error) {
 	msg, err := s.handler.GetSchema(s.bindCtx(ctx))
 	if err := service.NewError(err); err != nil {
@@ -191,6 +226,14 @@ func (s *grpcServer) LoadCapture(ctx xctx.Context, req *service.LoadCaptureReque
 	return &service.LoadCaptureResponse{Res: &service.LoadCaptureResponse_Capture{Capture: capture}}, nil
 }
 
+func (s *grpcServer) GetCaptures(ctx xctx.Context, req *service.GetCapturesRequest) (*service.GetCapturesResponse, error) {
+	captures, err := s.handler.GetCaptures(s.bindCtx(ctx))
+	if err := service.NewError(err); err != nil {
+		return &service.GetCapturesResponse{Res: &service.GetCapturesResponse_Error{Error: err}}, nil
+	}
+	return &service.GetCapturesResponse{Res: &service.GetCapturesResponse_Captures{Captures: captures}}, nil
+}
+
 func (s *grpcServer) GetDevices(ctx xctx.Context, req *service.GetDevicesRequest) (*service.GetDevicesResponse, error) {
 	devices, err := s.handler.GetDevices(s.bindCtx(ctx))
 	if err := service.NewError(err); err != nil {
@@ -230,8 +273,305 @@ func (s *grpcServer) GetFramebufferAttachment(ctx xctx.Context, req *service.Get
 	return &service.GetFramebufferAttachmentResponse{Res: &service.GetFramebufferAttachmentResponse_Image{Image: image}}, nil
 }
 
+func (s *grpcServer) ImportCrashDump(ctx xctx.Context, req *service.ImportCrashDumpRequest) (*service.ImportCrashDumpResponse, error) {
+	correlation, err := s.handler.ImportCrashDump(s.bindCtx(ctx), req.Capture, req.Data)
+	if err := service.NewError(err); err != nil {
+		return &service.ImportCrashDumpResponse{Res: &service.ImportCrashDumpResponse_Error{Error: err}}, nil
+	}
+	return &service.ImportCrashDumpResponse{Res: &service.ImportCrashDumpResponse_Correlation{Correlation: correlation}}, nil
+}
+
+func (s *grpcServer) ExportRenderDocEvents(ctx xctx.Context, req *service.ExportRenderDocEventsRequest) (*service.ExportRenderDocEventsResponse, error) {
+	events, err := s.handler.ExportRenderDocEvents(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.ExportRenderDocEventsResponse{Res: &service.ExportRenderDocEventsResponse_Error{Error: err}}, nil
+	}
+	return &service.ExportRenderDocEventsResponse{Res: &service.ExportRenderDocEventsResponse_Events{Events: events}}, nil
+}
+
+func (s *grpcServer) ExportPerfettoTrace(ctx xctx.Context, req *service.ExportPerfettoTraceRequest) (*service.ExportPerfettoTraceResponse, error) {
+	data, err := s.handler.ExportPerfettoTrace(s.bindCtx(ctx), req.Capture, req.Device)
+	if err := service.NewError(err); err != nil {
+		return &service.ExportPerfettoTraceResponse{Res: &service.ExportPerfettoTraceResponse_Error{Error: err}}, nil
+	}
+	return &service.ExportPerfettoTraceResponse{Res: &service.ExportPerfettoTraceResponse_Data{Data: data}}, nil
+}
+
+func (s *grpcServer) ExportTexture(ctx xctx.Context, req *service.ExportTextureRequest) (*service.ExportTextureResponse, error) {
+	data, err := s.handler.ExportTexture(s.bindCtx(ctx), req.Resource, req.Container)
+	if err := service.NewError(err); err != nil {
+		return &service.ExportTextureResponse{Res: &service.ExportTextureResponse_Error{Error: err}}, nil
+	}
+	return &service.ExportTextureResponse{Res: &service.ExportTextureResponse_Data{Data: data}}, nil
+}
+
+func (s *grpcServer) GetExperiments(ctx xctx.Context, req *service.GetExperimentsRequest) (*service.GetExperimentsResponse, error) {
+	list, err := s.handler.GetExperiments(s.bindCtx(ctx))
+	if err := service.NewError(err); err != nil {
+		return &service.GetExperimentsResponse{Res: &service.GetExperimentsResponse_Error{Error: err}}, nil
+	}
+	return &service.GetExperimentsResponse{Res: &service.GetExperimentsResponse_Experiments{Experiments: &service.Experiments{List: list}}}, nil
+}
+
+func (s *grpcServer) RunExperiment(ctx xctx.Context, req *service.RunExperimentRequest) (*service.RunExperimentResponse, error) {
+	result, err := s.handler.RunExperiment(s.bindCtx(ctx), req.Request)
+	if err := service.NewError(err); err != nil {
+		return &service.RunExperimentResponse{Res: &service.RunExperimentResponse_Error{Error: err}}, nil
+	}
+	return &service.RunExperimentResponse{Res: &service.RunExperimentResponse_Result{Result: result}}, nil
+}
+
+func (s *grpcServer) GetExperimentResults(ctx xctx.Context, req *service.GetExperimentResultsRequest) (*service.GetExperimentResultsResponse, error) {
+	results, err := s.handler.GetExperimentResults(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetExperimentResultsResponse{Res: &service.GetExperimentResultsResponse_Error{Error: err}}, nil
+	}
+	return &service.GetExperimentResultsResponse{Res: &service.GetExperimentResultsResponse_Results{Results: &service.ExperimentResults{Results: results}}}, nil
+}
+
+func (s *grpcServer) ExtractMinimalRepro(ctx xctx.Context, req *service.ExtractMinimalReproRequest) (*service.ExtractMinimalReproResponse, error) {
+	capture, err := s.handler.ExtractMinimalRepro(s.bindCtx(ctx), req.Command)
+	if err := service.NewError(err); err != nil {
+		return &service.ExtractMinimalReproResponse{Res: &service.ExtractMinimalReproResponse_Error{Error: err}}, nil
+	}
+	return &service.ExtractMinimalReproResponse{Res: &service.ExtractMinimalReproResponse_Capture{Capture: capture}}, nil
+}
+
+func (s *grpcServer) TrimCapture(ctx xctx.Context, req *service.TrimCaptureRequest) (*service.TrimCaptureResponse, error) {
+	capture, err := s.handler.TrimCapture(s.bindCtx(ctx), req.Capture, req.FirstFrame, req.LastFrame)
+	if err := service.NewError(err); err != nil {
+		return &service.TrimCaptureResponse{Res: &service.TrimCaptureResponse_Error{Error: err}}, nil
+	}
+	return &service.TrimCaptureResponse{Res: &service.TrimCaptureResponse_Capture{Capture: capture}}, nil
+}
+
+func (s *grpcServer) GetFrameDeltas(ctx xctx.Context, req *service.GetFrameDeltasRequest) (*service.GetFrameDeltasResponse, error) {
+	deltas, err := s.handler.GetFrameDeltas(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetFrameDeltasResponse{Res: &service.GetFrameDeltasResponse_Error{Error: err}}, nil
+	}
+	return &service.GetFrameDeltasResponse{Res: &service.GetFrameDeltasResponse_Deltas{Deltas: deltas}}, nil
+}
+
+func (s *grpcServer) DiffCaptures(ctx xctx.Context, req *service.DiffCapturesRequest) (*service.DiffCapturesResponse, error) {
+	diff, err := s.handler.DiffCaptures(s.bindCtx(ctx), req.A, req.B)
+	if err := service.NewError(err); err != nil {
+		return &service.DiffCapturesResponse{Res: &service.DiffCapturesResponse_Error{Error: err}}, nil
+	}
+	return &service.DiffCapturesResponse{Res: &service.DiffCapturesResponse_Diff{Diff: diff}}, nil
+}
+
+func (s *grpcServer) GetStateDiff(ctx xctx.Context, req *service.GetStateDiffRequest) (*service.GetStateDiffResponse, error) {
+	diff, err := s.handler.GetStateDiff(s.bindCtx(ctx), req.From, req.To)
+	if err := service.NewError(err); err != nil {
+		return &service.GetStateDiffResponse{Res: &service.GetStateDiffResponse_Error{Error: err}}, nil
+	}
+	return &service.GetStateDiffResponse{Res: &service.GetStateDiffResponse_Diff{Diff: diff}}, nil
+}
+
+func (s *grpcServer) SpliceCommands(ctx xctx.Context, req *service.SpliceCommandsRequest) (*service.SpliceCommandsResponse, error) {
+	commands, ok := req.Commands.Get().(*atom.List)
+	if !ok {
+		err := service.NewError(fmt.Errorf("Expected *atom.List, got %T", req.Commands.Get()))
+		return &service.SpliceCommandsResponse{Res: &service.SpliceCommandsResponse_Error{Error: err}}, nil
+	}
+	capture, err := s.handler.SpliceCommands(s.bindCtx(ctx), req.Capture, req.Range, commands)
+	if err := service.NewError(err); err != nil {
+		return &service.SpliceCommandsResponse{Res: &service.SpliceCommandsResponse_Error{Error: err}}, nil
+	}
+	return &service.SpliceCommandsResponse{Res: &service.SpliceCommandsResponse_Capture{Capture: capture}}, nil
+}
+
+func (s *grpcServer) GetMemoryProvenance(ctx xctx.Context, req *service.GetMemoryProvenanceRequest) (*service.GetMemoryProvenanceResponse, error) {
+	provenance, err := s.handler.GetMemoryProvenance(s.bindCtx(ctx), req.Memory)
+	if err := service.NewError(err); err != nil {
+		return &service.GetMemoryProvenanceResponse{Res: &service.GetMemoryProvenanceResponse_Error{Error: err}}, nil
+	}
+	return &service.GetMemoryProvenanceResponse{Res: &service.GetMemoryProvenanceResponse_Provenance{Provenance: provenance}}, nil
+}
+
+func (s *grpcServer) GetBufferView(ctx xctx.Context, req *service.GetBufferViewRequest) (*service.GetBufferViewResponse, error) {
+	view, err := s.handler.GetBufferView(s.bindCtx(ctx), req.Memory, req.Layout)
+	if err := service.NewError(err); err != nil {
+		return &service.GetBufferViewResponse{Res: &service.GetBufferViewResponse_Error{Error: err}}, nil
+	}
+	return &service.GetBufferViewResponse{Res: &service.GetBufferViewResponse_View{View: view}}, nil
+}
+
+func (s *grpcServer) SearchCommands(req *service.SearchCommandsRequest, server service.Gapid_SearchCommandsServer) error {
+	ctx := server.Context()
+	err := s.handler.SearchCommands(s.bindCtx(ctx), req.Capture, req.Query, func(commands []uint64) error {
+		results := &service.SearchResults{Commands: commands}
+		return server.Send(&service.SearchCommandsResponse{Res: &service.SearchCommandsResponse_Results{Results: results}})
+	})
+	if err := service.NewError(err); err != nil {
+		return server.Send(&service.SearchCommandsResponse{Res: &service.SearchCommandsResponse_Error{Error: err}})
+	}
+	return nil
+}
+
+func (s *grpcServer) GetPixelHistory(ctx xctx.Context, req *service.GetPixelHistoryRequest) (*service.GetPixelHistoryResponse, error) {
+	history, err := s.handler.GetPixelHistory(
+		s.bindCtx(ctx),
+		req.Device,
+		req.Capture,
+		req.Range,
+		req.Attachment,
+		req.X,
+		req.Y,
+	)
+	if err := service.NewError(err); err != nil {
+		return &service.GetPixelHistoryResponse{Res: &service.GetPixelHistoryResponse_Error{Error: err}}, nil
+	}
+	return &service.GetPixelHistoryResponse{Res: &service.GetPixelHistoryResponse_History{History: history}}, nil
+}
+
+func (s *grpcServer) GetResourceTimeline(ctx xctx.Context, req *service.GetResourceTimelineRequest) (*service.GetResourceTimelineResponse, error) {
+	timeline, err := s.handler.GetResourceTimeline(s.bindCtx(ctx), req.Capture, req.Id)
+	if err := service.NewError(err); err != nil {
+		return &service.GetResourceTimelineResponse{Res: &service.GetResourceTimelineResponse_Error{Error: err}}, nil
+	}
+	return &service.GetResourceTimelineResponse{Res: &service.GetResourceTimelineResponse_Timeline{Timeline: timeline}}, nil
+}
+
+func (s *grpcServer) GetDispatchSnapshot(ctx xctx.Context, req *service.GetDispatchSnapshotRequest) (*service.GetDispatchSnapshotResponse, error) {
+	snapshot, err := s.handler.GetDispatchSnapshot(s.bindCtx(ctx), req.Device, req.Command)
+	if err := service.NewError(err); err != nil {
+		return &service.GetDispatchSnapshotResponse{Res: &service.GetDispatchSnapshotResponse_Error{Error: err}}, nil
+	}
+	return &service.GetDispatchSnapshotResponse{Res: &service.GetDispatchSnapshotResponse_Snapshot{Snapshot: snapshot}}, nil
+}
+
+func (s *grpcServer) GetTimingProfile(ctx xctx.Context, req *service.GetTimingProfileRequest) (*service.GetTimingProfileResponse, error) {
+	profile, err := s.handler.GetTimingProfile(s.bindCtx(ctx), req.Device, req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetTimingProfileResponse{Res: &service.GetTimingProfileResponse_Error{Error: err}}, nil
+	}
+	return &service.GetTimingProfileResponse{Res: &service.GetTimingProfileResponse_Profile{Profile: profile}}, nil
+}
+
+func (s *grpcServer) GetPipelineStatistics(ctx xctx.Context, req *service.GetPipelineStatisticsRequest) (*service.GetPipelineStatisticsResponse, error) {
+	statistics, err := s.handler.GetPipelineStatistics(s.bindCtx(ctx), req.Device, req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetPipelineStatisticsResponse{Res: &service.GetPipelineStatisticsResponse_Error{Error: err}}, nil
+	}
+	return &service.GetPipelineStatisticsResponse{Res: &service.GetPipelineStatisticsResponse_Statistics{Statistics: statistics}}, nil
+}
+
+func (s *grpcServer) GetFrameLoopProfile(ctx xctx.Context, req *service.GetFrameLoopProfileRequest) (*service.GetFrameLoopProfileResponse, error) {
+	profile, err := s.handler.GetFrameLoopProfile(s.bindCtx(ctx), req.Device, req.Capture, req.Frame, req.Iterations, req.Experiments, req.Parameters)
+	if err := service.NewError(err); err != nil {
+		return &service.GetFrameLoopProfileResponse{Res: &service.GetFrameLoopProfileResponse_Error{Error: err}}, nil
+	}
+	return &service.GetFrameLoopProfileResponse{Res: &service.GetFrameLoopProfileResponse_Profile{Profile: profile}}, nil
+}
+
+func (s *grpcServer) GetLeakReport(ctx xctx.Context, req *service.GetLeakReportRequest) (*service.GetLeakReportResponse, error) {
+	report, err := s.handler.GetLeakReport(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetLeakReportResponse{Res: &service.GetLeakReportResponse_Error{Error: err}}, nil
+	}
+	return &service.GetLeakReportResponse{Res: &service.GetLeakReportResponse_Report{Report: report}}, nil
+}
+
+func (s *grpcServer) GetMemoryProfile(ctx xctx.Context, req *service.GetMemoryProfileRequest) (*service.GetMemoryProfileResponse, error) {
+	profile, err := s.handler.GetMemoryProfile(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetMemoryProfileResponse{Res: &service.GetMemoryProfileResponse_Error{Error: err}}, nil
+	}
+	return &service.GetMemoryProfileResponse{Res: &service.GetMemoryProfileResponse_Profile{Profile: profile}}, nil
+}
+
+func (s *grpcServer) GetLintReport(ctx xctx.Context, req *service.GetLintReportRequest) (*service.GetLintReportResponse, error) {
+	report, err := s.handler.GetLintReport(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetLintReportResponse{Res: &service.GetLintReportResponse_Error{Error: err}}, nil
+	}
+	return &service.GetLintReportResponse{Res: &service.GetLintReportResponse_Report{Report: report}}, nil
+}
+
+func (s *grpcServer) ExportCpp(ctx xctx.Context, req *service.ExportCppRequest) (*service.ExportCppResponse, error) {
+	source, err := s.handler.ExportCpp(s.bindCtx(ctx), req.Device, req.Capture, req.Frame)
+	if err := service.NewError(err); err != nil {
+		return &service.ExportCppResponse{Res: &service.ExportCppResponse_Error{Error: err}}, nil
+	}
+	return &service.ExportCppResponse{Res: &service.ExportCppResponse_Source{Source: source}}, nil
+}
+
+func (s *grpcServer) ExportCommands(req *service.ExportCommandsRequest, server service.Gapid_ExportCommandsServer) error {
+	ctx := server.Context()
+	err := s.handler.ExportCommands(s.bindCtx(ctx), req.Capture, req.Data, func(commands *service.ExportedCommands) error {
+		return server.Send(&service.ExportCommandsResponse{Res: &service.ExportCommandsResponse_Commands{Commands: commands}})
+	})
+	if err := service.NewError(err); err != nil {
+		return server.Send(&service.ExportCommandsResponse{Res: &service.ExportCommandsResponse_Error{Error: err}})
+	}
+	return nil
+}
+
+func (s *grpcServer) SanitizeCapture(ctx xctx.Context, req *service.SanitizeCaptureRequest) (*service.SanitizeCaptureResponse, error) {
+	capture, err := s.handler.SanitizeCapture(s.bindCtx(ctx), req.Capture, req.Options)
+	if err := service.NewError(err); err != nil {
+		return &service.SanitizeCaptureResponse{Res: &service.SanitizeCaptureResponse_Error{Error: err}}, nil
+	}
+	return &service.SanitizeCaptureResponse{Res: &service.SanitizeCaptureResponse_Capture{Capture: capture}}, nil
+}
+
+func (s *grpcServer) EditCommands(ctx xctx.Context, req *service.EditCommandsRequest) (*service.EditCommandsResponse, error) {
+	capture, err := s.handler.EditCommands(s.bindCtx(ctx), req.Capture, req.Edits)
+	if err := service.NewError(err); err != nil {
+		return &service.EditCommandsResponse{Res: &service.EditCommandsResponse_Error{Error: err}}, nil
+	}
+	return &service.EditCommandsResponse{Res: &service.EditCommandsResponse_Capture{Capture: capture}}, nil
+}
+
+func (s *grpcServer) GetCommandTracks(ctx xctx.Context, req *service.GetCommandTracksRequest) (*service.GetCommandTracksResponse, error) {
+	tracks, err := s.handler.GetCommandTracks(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetCommandTracksResponse{Res: &service.GetCommandTracksResponse_Error{Error: err}}, nil
+	}
+	return &service.GetCommandTracksResponse{Res: &service.GetCommandTracksResponse_Tracks{Tracks: tracks}}, nil
+}
+
+func (s *grpcServer) GetSubmissionTimeline(ctx xctx.Context, req *service.GetSubmissionTimelineRequest) (*service.GetSubmissionTimelineResponse, error) {
+	timeline, err := s.handler.GetSubmissionTimeline(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetSubmissionTimelineResponse{Res: &service.GetSubmissionTimelineResponse_Error{Error: err}}, nil
+	}
+	return &service.GetSubmissionTimelineResponse{Res: &service.GetSubmissionTimelineResponse_Timeline{Timeline: timeline}}, nil
+}
+
+func (s *grpcServer) GetFrameGraph(ctx xctx.Context, req *service.GetFrameGraphRequest) (*service.GetFrameGraphResponse, error) {
+	graph, err := s.handler.GetFrameGraph(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetFrameGraphResponse{Res: &service.GetFrameGraphResponse_Error{Error: err}}, nil
+	}
+	return &service.GetFrameGraphResponse{Res: &service.GetFrameGraphResponse_Graph{Graph: graph}}, nil
+}
+
+func (s *grpcServer) GetRedundancyReport(ctx xctx.Context, req *service.GetRedundancyReportRequest) (*service.GetRedundancyReportResponse, error) {
+	report, err := s.handler.GetRedundancyReport(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetRedundancyReportResponse{Res: &service.GetRedundancyReportResponse_Error{Error: err}}, nil
+	}
+	return &service.GetRedundancyReportResponse{Res: &service.GetRedundancyReportResponse_Report{Report: report}}, nil
+}
+
+func (s *grpcServer) GetShaderAnalysis(ctx xctx.Context, req *service.GetShaderAnalysisRequest) (*service.GetShaderAnalysisResponse, error) {
+	analysis, err := s.handler.GetShaderAnalysis(s.bindCtx(ctx), req.Capture)
+	if err := service.NewError(err); err != nil {
+		return &service.GetShaderAnalysisResponse{Res: &service.GetShaderAnalysisResponse_Error{Error: err}}, nil
+	}
+	return &service.GetShaderAnalysisResponse{Res: &service.GetShaderAnalysisResponse_Analysis{Analysis: analysis}}, nil
+}
+
 func (s *grpcServer) GetLogStream(req *service.GetLogStreamRequest, server service.Gapid_GetLogStreamServer) error {
 	ctx := server.Context()
 	h := log.NewHandler(func(m *log.Message) { server.Send(log_pb.From(m)) }, nil)
 	return s.handler.GetLogStream(s.bindCtx(ctx), h)
 }
+
+func (s *grpcServer) GetReplayStatusStream(req *service.GetReplayStatusStreamRequest, server service.Gapid_GetReplayStatusStreamServer) error {
+	ctx := server.Context()
+	return s.handler.GetReplayStatusStream(s.bindCtx(ctx), func(status *service.ReplayStatus) { server.Send(status) })
+}
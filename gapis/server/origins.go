@@ -0,0 +1,94 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/gapid/core/log"
+)
+
+// originListener is a net.Listener that closes the connections made from
+// addresses that are neither loopback addresses nor in the allowed networks.
+type originListener struct {
+	net.Listener
+	allowed []*net.IPNet
+	ctx     context.Context
+}
+
+func (l *originListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if isAllowedOrigin(conn.RemoteAddr(), l.allowed) {
+			return conn, nil
+		}
+		log.W(l.ctx, "Rejected connection from %v", conn.RemoteAddr())
+		conn.Close()
+	}
+}
+
+// isAllowedOrigin returns true if addr is a loopback address or an address of
+// one of the allowed networks.
+func isAllowedOrigin(addr net.Addr, allowed []*net.IPNet) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return true // Pipes and other local transports.
+	}
+	if tcp.IP.IsLoopback() {
+		return true
+	}
+	for _, n := range allowed {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseOrigins parses a comma-separated list of IP addresses and CIDR
+// networks, such as "192.168.0.0/16,10.1.2.3", into networks.
+func ParseOrigins(s string) ([]*net.IPNet, error) {
+	out := []*net.IPNet{}
+	for _, o := range strings.Split(s, ",") {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+		if !strings.Contains(o, "/") {
+			ip := net.ParseIP(o)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid origin address: '%v'", o)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(o)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid origin network: '%v'", o)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+)
+
+func TestParseOrigins(t *testing.T) {
+	ctx := log.Testing(t)
+	network := func(s string) *net.IPNet {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("Invalid network %v: %v", s, err)
+		}
+		return n
+	}
+	for _, test := range []struct {
+		origins  string
+		expected []*net.IPNet
+		fails    bool
+	}{
+		{"", []*net.IPNet{}, false},
+		{"10.1.2.3", []*net.IPNet{network("10.1.2.3/32")}, false},
+		{"::1", []*net.IPNet{network("::1/128")}, false},
+		{"fe80::1:2", []*net.IPNet{network("fe80::1:2/128")}, false},
+		{"192.168.0.0/16", []*net.IPNet{network("192.168.0.0/16")}, false},
+		{"192.168.1.2/16", []*net.IPNet{network("192.168.0.0/16")}, false},
+		{"fd00::/8", []*net.IPNet{network("fd00::/8")}, false},
+		{" 192.168.0.0/16 , 10.1.2.3,", []*net.IPNet{network("192.168.0.0/16"), network("10.1.2.3/32")}, false},
+		{"10.1.2", nil, true},
+		{"localhost", nil, true},
+		{"10.1.2.3/33", nil, true},
+		{"10.1.2.3/", nil, true},
+		{"10.1.2.3,bad", nil, true},
+	} {
+		got, err := ParseOrigins(test.origins)
+		if test.fails {
+			assert.For(ctx, "ParseOrigins(%q)", test.origins).ThatError(err).Failed()
+			continue
+		}
+		if assert.For(ctx, "ParseOrigins(%q)", test.origins).ThatError(err).Succeeded() {
+			assert.For(ctx, "ParseOrigins(%q)", test.origins).ThatSlice(got).DeepEquals(test.expected)
+		}
+	}
+}
+
+func TestIsAllowedOrigin(t *testing.T) {
+	ctx := log.Testing(t)
+	allowed, err := ParseOrigins("192.168.0.0/16,10.1.2.3,fd00::/8")
+	if !assert.For(ctx, "ParseOrigins").ThatError(err).Succeeded() {
+		return
+	}
+	tcp := func(ip string) net.Addr { return &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234} }
+	for _, test := range []struct {
+		name     string
+		addr     net.Addr
+		expected bool
+	}{
+		{"IPv4 loopback", tcp("127.0.0.1"), true},
+		{"IPv6 loopback", tcp("::1"), true},
+		{"IPv4 in network", tcp("192.168.10.20"), true},
+		{"IPv4 address", tcp("10.1.2.3"), true},
+		{"IPv4 mapped IPv6 in network", tcp("::ffff:192.168.10.20"), true},
+		{"IPv6 in network", tcp("fd12::34"), true},
+		{"IPv4 not allowed", tcp("10.1.2.4"), false},
+		{"IPv6 not allowed", tcp("fe80::1"), false},
+		{"Unix socket", &net.UnixAddr{Name: "/tmp/gapis", Net: "unix"}, true},
+	} {
+		assert.For(ctx, test.name).That(isAllowedOrigin(test.addr, allowed)).Equals(test.expected)
+	}
+	assert.For(ctx, "No allowed networks").That(isAllowedOrigin(tcp("192.168.10.20"), nil)).Equals(false)
+}
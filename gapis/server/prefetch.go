@@ -0,0 +1,142 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/gapid/core/context/keys"
+	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/resolve"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+const (
+	// prefetchFrames is the number of frames whose thumbnails are prefetched.
+	prefetchFrames = 4
+	// prefetchThumbnailSize is the size of the prefetched frame thumbnails.
+	prefetchThumbnailSize = 256
+)
+
+// prefetcher speculatively resolves, at low priority, the data commonly
+// requested by clients once a capture is loaded: the command tree, the
+// dependency graph, the state at the end of the first frame and the
+// thumbnails of the first frames. The results are held by the database, so
+// the later requests are served from there.
+type prefetcher struct {
+	ctx   context.Context                                  // The server lifetime context.
+	run   func(ctx context.Context, p *path.Capture) error // Prefetches a capture.
+	mutex sync.Mutex
+	stop  task.CancelFunc // Stops the running prefetch, or nil. Guarded by mutex.
+}
+
+func newPrefetcher(ctx context.Context, run func(ctx context.Context, p *path.Capture) error) *prefetcher {
+	return &prefetcher{ctx: ctx, run: run}
+}
+
+// start prefetches the data of the capture p in the background, stopping the
+// prefetch of the previously loaded capture. The prefetch outlives the request
+// that loaded the capture, but not the server. start returns immediately.
+func (f *prefetcher) start(ctx context.Context, p *path.Capture) {
+	ctx = keys.Clone(f.ctx, ctx)
+	ctx = database.PutPriority(ctx, database.LowPriority)
+	ctx = log.Enter(ctx, "Prefetch")
+	ctx, stop := task.WithCancel(ctx)
+
+	f.mutex.Lock()
+	if f.stop != nil {
+		f.stop()
+	}
+	f.stop = stop
+	f.mutex.Unlock()
+
+	go func() {
+		defer stop()
+		if err := f.run(ctx, p); err != nil {
+			log.D(ctx, "Prefetch failed: %v", err)
+		}
+	}()
+}
+
+func (s *server) prefetchCapture(ctx context.Context, p *path.Capture) error {
+	ctx = capture.Put(ctx, p)
+	c, err := capture.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := c.Atoms(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := resolve.Hierarchies(ctx, p.Hierarchies()); err != nil {
+		log.D(ctx, "Prefetch of the command tree failed: %v", err)
+	}
+
+	ends := []uint64{}
+	for i, a := range list.Atoms {
+		if a.AtomFlags().IsEndOfFrame() {
+			ends = append(ends, uint64(i))
+			if len(ends) == prefetchFrames {
+				break
+			}
+		}
+	}
+	if len(ends) == 0 {
+		return nil
+	}
+
+	for _, id := range c.Apis {
+		api := gfxapi.Find(gfxapi.ID(id.ID()))
+		if t, ok := api.(atom.DependencyTracker); ok {
+			if _, err := t.Dependencies(ctx, []atom.ID{atom.ID(ends[0])}); err != nil {
+				log.D(ctx, "Prefetch of the dependency graph failed: %v", err)
+			}
+		}
+	}
+
+	if _, err := resolve.APIState(ctx, p.Commands().Index(ends[0]).StateAfter()); err != nil {
+		log.D(ctx, "Prefetch of the first frame state failed: %v", err)
+	}
+
+	devices, err := s.GetDevicesForReplay(ctx, p)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+	settings := &service.RenderSettings{
+		MaxWidth:      prefetchThumbnailSize,
+		MaxHeight:     prefetchThumbnailSize,
+		WireframeMode: service.WireframeMode_None,
+	}
+	hints := &service.UsageHints{Preview: true}
+	for _, end := range ends {
+		after := p.Commands().Index(end)
+		info, err := resolve.FramebufferAttachment(ctx, devices[0], after, gfxapi.FramebufferAttachment_Color0, settings, hints)
+		if err == nil {
+			_, err = database.Resolve(ctx, info.Id.ID())
+		}
+		if err != nil {
+			log.D(ctx, "Prefetch of the thumbnail of command %v failed: %v", end, err)
+		}
+	}
+	return nil
+}
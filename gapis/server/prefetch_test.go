@@ -0,0 +1,68 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/data/id"
+	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/service/path"
+)
+
+func TestPrefetchStops(t *testing.T) {
+	ctx := log.Testing(t)
+	serverCtx, shutdown := task.WithCancel(ctx)
+	started, stopped := make(chan *path.Capture), make(chan *path.Capture)
+	f := newPrefetcher(serverCtx, func(ctx context.Context, p *path.Capture) error {
+		started <- p
+		<-task.ShouldStop(ctx)
+		stopped <- p
+		return task.StopReason(ctx)
+	})
+	expect := func(c chan *path.Capture, what string, p *path.Capture) {
+		select {
+		case got := <-c:
+			assert.For(ctx, what).That(got).Equals(p)
+		case <-time.After(time.Second * 10):
+			t.Fatalf("Timeout waiting for the %s", what)
+		}
+	}
+	a, b := path.NewCapture(id.OfString("a")), path.NewCapture(id.OfString("b"))
+
+	// The prefetch outlives the request that loaded the capture.
+	request, done := context.WithCancel(ctx)
+	f.start(request, a)
+	expect(started, "start of a", a)
+	done()
+	select {
+	case <-stopped:
+		t.Fatalf("Prefetch stopped with the request")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	// Loading another capture stops the prefetch of the previous one.
+	f.start(ctx, b)
+	expect(stopped, "stop of a", a)
+	expect(started, "start of b", b)
+
+	// Shutting the server down stops the prefetch.
+	shutdown()
+	expect(stopped, "stop of b", b)
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/gapid/core/event/task"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/service"
+	"google.golang.org/grpc"
+
+	xctx "golang.org/x/net/context"
+)
+
+// requests tracks the outstanding RPC calls tagged with a request identifier
+// so that they can be cancelled.
+type requests struct {
+	mutex   sync.Mutex
+	cancels map[string]*request
+}
+
+type request struct {
+	cancel task.CancelFunc
+}
+
+func newRequests() *requests {
+	return &requests{cancels: map[string]*request{}}
+}
+
+// interceptor is a grpc.UnaryServerInterceptor that applies the priority of
+// the incoming call to its resolves, and makes tagged calls cancellable.
+func (r *requests) interceptor(ctx xctx.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id, priority := service.RequestInfo(ctx)
+	if priority != 0 {
+		ctx = database.PutPriority(ctx, database.Priority(priority))
+	}
+	if id == "" {
+		return handler(ctx, req)
+	}
+	ctx, done := r.track(ctx, id)
+	defer done()
+	return handler(ctx, req)
+}
+
+// track returns a copy of ctx that is cancelled by a call to cancel with id,
+// or by tracking a newer request with the same id. done must be called once
+// the request has finished.
+func (r *requests) track(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := task.WithCancel(ctx)
+	req := &request{cancel}
+	r.mutex.Lock()
+	if old, ok := r.cancels[id]; ok {
+		old.cancel() // Superseded.
+	}
+	r.cancels[id] = req
+	r.mutex.Unlock()
+	return ctx, func() {
+		r.mutex.Lock()
+		if r.cancels[id] == req {
+			delete(r.cancels, id)
+		}
+		r.mutex.Unlock()
+		cancel()
+	}
+}
+
+// cancel cancels the outstanding requests with the given identifiers,
+// returning the number of requests cancelled.
+func (r *requests) cancel(ids []string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	count := 0
+	for _, id := range ids {
+		if req, ok := r.cancels[id]; ok {
+			req.cancel()
+			delete(r.cancels, id)
+			count++
+		}
+	}
+	return count
+}
+
+// chainInterceptors returns a grpc.UnaryServerInterceptor that calls outer,
+// then inner, then the handler.
+func chainInterceptors(outer, inner grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx xctx.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return outer(ctx, req, info, func(ctx xctx.Context, req interface{}) (interface{}, error) {
+			return inner(ctx, req, info, handler)
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/google/gapid/core/context/keys"
+	"github.com/google/gapid/core/data/id"
+	img "github.com/google/gapid/core/image"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/resolve"
+	"github.com/google/gapid/gapis/service"
+	"github.com/google/gapid/gapis/service/path"
+	"github.com/google/gapid/gapis/service/scripting"
+
+	xctx "golang.org/x/net/context"
+)
+
+// The version of the scripting API served. The minor version is bumped with
+// each backward compatible addition to scripting.proto.
+const (
+	scriptingVersionMajor = 1
+	scriptingVersionMinor = 0
+)
+
+// colorAttachments are the attachments of the screenshots, by index.
+var colorAttachments = []gfxapi.FramebufferAttachment{
+	gfxapi.FramebufferAttachment_Color0,
+	gfxapi.FramebufferAttachment_Color1,
+	gfxapi.FramebufferAttachment_Color2,
+	gfxapi.FramebufferAttachment_Color3,
+}
+
+// errEndOfRange stops the export of the commands past the requested range.
+var errEndOfRange = errors.New("End of the command range")
+
+func newScriptingServer(ctx context.Context, handler Server) *scriptingServer {
+	outer := ctx
+	return &scriptingServer{
+		handler: handler,
+		bindCtx: func(ctx context.Context) context.Context { return keys.Clone(ctx, outer) },
+	}
+}
+
+// scriptingServer serves the scripting API by translating its requests to the
+// requests of the Gapid service.
+type scriptingServer struct {
+	handler Server
+	bindCtx func(context.Context) context.Context
+}
+
+func (s *scriptingServer) GetVersion(ctx xctx.Context, req *scripting.GetVersionRequest) (*scripting.GetVersionResponse, error) {
+	return &scripting.GetVersionResponse{Major: scriptingVersionMajor, Minor: scriptingVersionMinor}, nil
+}
+
+func (s *scriptingServer) LoadCapture(ctx xctx.Context, req *scripting.LoadCaptureRequest) (*scripting.LoadCaptureResponse, error) {
+	c, err := s.handler.LoadCapture(s.bindCtx(ctx), req.Path)
+	if err != nil {
+		return &scripting.LoadCaptureResponse{Res: &scripting.LoadCaptureResponse_Error{Error: scriptingError(err)}}, nil
+	}
+	return &scripting.LoadCaptureResponse{Res: &scripting.LoadCaptureResponse_Capture{Capture: c.Id.ID().String()}}, nil
+}
+
+func (s *scriptingServer) GetCommands(ctx xctx.Context, req *scripting.GetCommandsRequest) (*scripting.GetCommandsResponse, error) {
+	commands, err := s.getCommands(s.bindCtx(ctx), req)
+	if err != nil {
+		return &scripting.GetCommandsResponse{Res: &scripting.GetCommandsResponse_Error{Error: scriptingError(err)}}, nil
+	}
+	return &scripting.GetCommandsResponse{Res: &scripting.GetCommandsResponse_Commands{Commands: commands}}, nil
+}
+
+func (s *scriptingServer) getCommands(ctx context.Context, req *scripting.GetCommandsRequest) (*scripting.Commands, error) {
+	c, err := capturePath(req.Capture)
+	if err != nil {
+		return nil, err
+	}
+	end := uint64(math.MaxUint64)
+	if req.Count > 0 && req.First+req.Count > req.First {
+		end = req.First + req.Count
+	}
+	out := &scripting.Commands{}
+	err = s.handler.ExportCommands(ctx, c, false, func(batch *service.ExportedCommands) error {
+		for _, cmd := range batch.Commands {
+			if cmd.Index >= end {
+				return errEndOfRange
+			}
+			if cmd.Index >= req.First {
+				out.Commands = append(out.Commands, scriptingCommand(cmd))
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errEndOfRange {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *scriptingServer) GetState(ctx xctx.Context, req *scripting.GetStateRequest) (*scripting.GetStateResponse, error) {
+	state, err := s.getState(s.bindCtx(ctx), req)
+	if err != nil {
+		return &scripting.GetStateResponse{Res: &scripting.GetStateResponse_Error{Error: scriptingError(err)}}, nil
+	}
+	return &scripting.GetStateResponse{Res: &scripting.GetStateResponse_State{State: state}}, nil
+}
+
+func (s *scriptingServer) getState(ctx context.Context, req *scripting.GetStateRequest) (*scripting.State, error) {
+	c, err := capturePath(req.Capture)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := resolve.StateLeaves(ctx, c.Commands().Index(req.After), req.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(leaves))
+	for p := range leaves {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	out := &scripting.State{Values: make([]*scripting.StateValue, len(paths))}
+	for i, p := range paths {
+		out.Values[i] = &scripting.StateValue{Path: p, Value: leaves[p]}
+	}
+	return out, nil
+}
+
+func (s *scriptingServer) Replay(ctx xctx.Context, req *scripting.ReplayRequest) (*scripting.ReplayResponse, error) {
+	timings, err := s.replay(s.bindCtx(ctx), req)
+	if err != nil {
+		return &scripting.ReplayResponse{Res: &scripting.ReplayResponse_Error{Error: scriptingError(err)}}, nil
+	}
+	return &scripting.ReplayResponse{Res: &scripting.ReplayResponse_Timings{Timings: timings}}, nil
+}
+
+func (s *scriptingServer) replay(ctx context.Context, req *scripting.ReplayRequest) (*scripting.Timings, error) {
+	c, err := capturePath(req.Capture)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.replayDevice(ctx, c, req.Device)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.handler.GetTimingProfile(ctx, d, c)
+	if err != nil {
+		return nil, err
+	}
+	out := &scripting.Timings{Commands: make([]*scripting.CommandTiming, len(profile.Commands))}
+	for i, t := range profile.Commands {
+		out.Commands[i] = &scripting.CommandTiming{
+			Index:    t.Command.Index,
+			Duration: t.Duration,
+			Count:    t.Count,
+		}
+	}
+	return out, nil
+}
+
+func (s *scriptingServer) GetScreenshot(ctx xctx.Context, req *scripting.GetScreenshotRequest) (*scripting.GetScreenshotResponse, error) {
+	screenshot, err := s.getScreenshot(s.bindCtx(ctx), req)
+	if err != nil {
+		return &scripting.GetScreenshotResponse{Res: &scripting.GetScreenshotResponse_Error{Error: scriptingError(err)}}, nil
+	}
+	return &scripting.GetScreenshotResponse{Res: &scripting.GetScreenshotResponse_Screenshot{Screenshot: screenshot}}, nil
+}
+
+func (s *scriptingServer) getScreenshot(ctx context.Context, req *scripting.GetScreenshotRequest) (*scripting.Screenshot, error) {
+	if req.Attachment >= uint32(len(colorAttachments)) {
+		return nil, fmt.Errorf("Invalid color attachment %d, expected 0 to %d", req.Attachment, len(colorAttachments)-1)
+	}
+	c, err := capturePath(req.Capture)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.replayDevice(ctx, c, req.Device)
+	if err != nil {
+		return nil, err
+	}
+
+	// The framebuffer is not scaled along the dimensions with no maximum.
+	settings := &service.RenderSettings{MaxWidth: math.MaxUint32, MaxHeight: math.MaxUint32}
+	if req.MaxWidth > 0 {
+		settings.MaxWidth = req.MaxWidth
+	}
+	if req.MaxHeight > 0 {
+		settings.MaxHeight = req.MaxHeight
+	}
+	iip, err := s.handler.GetFramebufferAttachment(ctx, d, c.Commands().Index(req.After), colorAttachments[req.Attachment], settings, nil)
+	if err != nil {
+		return nil, err
+	}
+	iio, err := s.handler.Get(ctx, iip.Path())
+	if err != nil {
+		return nil, err
+	}
+	ii := iio.(*img.Info2D)
+	data, err := s.handler.Get(ctx, path.NewBlob(ii.Data.ID()).Path())
+	if err != nil {
+		return nil, err
+	}
+	w, h := int(ii.Width), int(ii.Height)
+	rgba, err := img.Convert(data.([]byte), w, h, ii.Format, img.RGBA_U8_NORM)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.Buffer{}
+	err = png.Encode(&buf, &image.NRGBA{
+		Rect:   image.Rect(0, 0, w, h),
+		Stride: w * 4,
+		Pix:    rgba,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &scripting.Screenshot{Width: ii.Width, Height: ii.Height, Png: buf.Bytes()}, nil
+}
+
+// replayDevice returns the path of the device identified by dev, or of the
+// first device compatible with the capture c if dev is empty.
+func (s *scriptingServer) replayDevice(ctx context.Context, c *path.Capture, dev string) (*path.Device, error) {
+	if dev != "" {
+		d, err := id.Parse(dev)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid device identifier %q", dev)
+		}
+		return path.NewDevice(d), nil
+	}
+	devices, err := s.handler.GetDevicesForReplay(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("No device compatible with the capture")
+	}
+	return devices[0], nil
+}
+
+// capturePath returns the path of the capture identified by capture.
+func capturePath(capture string) (*path.Capture, error) {
+	c, err := id.Parse(capture)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid capture identifier %q", capture)
+	}
+	return path.NewCapture(c), nil
+}
+
+func scriptingError(err error) *scripting.Error {
+	return &scripting.Error{Message: err.Error()}
+}
+
+// scriptingCommand returns the exported command cmd in the form of the
+// scripting API.
+func scriptingCommand(cmd *service.ExportedCommand) *scripting.Command {
+	out := &scripting.Command{
+		Index:      cmd.Index,
+		Thread:     cmd.Thread,
+		Api:        cmd.Api,
+		Name:       cmd.Name,
+		Parameters: make([]*scripting.Parameter, len(cmd.Parameters)),
+		Result:     scriptingParameter(cmd.Result),
+	}
+	for i, p := range cmd.Parameters {
+		out.Parameters[i] = scriptingParameter(p)
+	}
+	return out
+}
+
+func scriptingParameter(p *service.ExportedParameter) *scripting.Parameter {
+	if p == nil {
+		return nil
+	}
+	return &scripting.Parameter{Name: p.Name, Type: p.Type, Value: formatValue(p.Value)}
+}
+
+// formatValue returns the exported value v formatted as a string.
+func formatValue(v *service.ExportedValue) string {
+	if v.Constant != "" {
+		return v.Constant
+	}
+	switch v := v.Val.(type) {
+	case *service.ExportedValue_Bool:
+		return strconv.FormatBool(v.Bool)
+	case *service.ExportedValue_Int:
+		return strconv.FormatInt(v.Int, 10)
+	case *service.ExportedValue_Uint:
+		return strconv.FormatUint(v.Uint, 10)
+	case *service.ExportedValue_Float:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case *service.ExportedValue_String_:
+		return v.String_
+	case *service.ExportedValue_Pointer:
+		if v.Pointer.Pool != 0 {
+			return fmt.Sprintf("0x%x@%d", v.Pointer.Address, v.Pointer.Pool)
+		}
+		return fmt.Sprintf("0x%x", v.Pointer.Address)
+	}
+	return ""
+}
@@ -19,8 +19,10 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime/pprof"
@@ -35,7 +37,9 @@ import (
 	"github.com/google/gapid/framework/binary"
 	"github.com/google/gapid/framework/binary/registry"
 	"github.com/google/gapid/framework/binary/schema"
+	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/experiments"
 	"github.com/google/gapid/gapis/gfxapi"
 	"github.com/google/gapid/gapis/gfxapi/all"
 	"github.com/google/gapid/gapis/replay"
@@ -52,6 +56,19 @@ type Config struct {
 	AuthToken      auth.Token
 	DeviceScanDone task.Signal
 	LogBroadcaster *log.Broadcaster
+	// ExperimentsDir is the directory used to persist experiment results.
+	// If empty, experiment results are only held in memory.
+	ExperimentsDir string
+	// EnableScripting serves the scripting API alongside the Gapid service.
+	EnableScripting bool
+	// TLS, if not nil, is the configuration used to serve over TLS.
+	TLS *tls.Config
+	// AllowedOrigins are the networks, beyond the loopback addresses, that
+	// clients are accepted from. Remote clients require an AuthToken.
+	AllowedOrigins []*net.IPNet
+	// Prefetch speculatively resolves, in the background, the data commonly
+	// requested once a capture is loaded.
+	Prefetch bool
 }
 
 // Server is the server interface to GAPIS.
@@ -61,13 +78,24 @@ type Server interface {
 
 // New constructs and returns a new Server.
 func New(ctx context.Context, cfg Config) Server {
-	return &server{
+	return newServer(ctx, cfg)
+}
+
+func newServer(ctx context.Context, cfg Config) *server {
+	s := &server{
 		cfg.Info,
 		cfg.StringTables,
 		cfg.DeviceScanDone,
 		cfg.LogBroadcaster,
+		experiments.NewStore(cfg.ExperimentsDir),
 		bytes.Buffer{},
+		newRequests(),
+		nil,
 	}
+	if cfg.Prefetch {
+		s.prefetcher = newPrefetcher(ctx, s.prefetchCapture)
+	}
+	return s
 }
 
 type server struct {
@@ -75,7 +103,10 @@ type server struct {
 	stbs           []*stringtable.StringTable
 	deviceScanDone task.Signal
 	logBroadcaster *log.Broadcaster
+	experiments    *experiments.Store
 	profile        bytes.Buffer
+	requests       *requests
+	prefetcher     *prefetcher // nil unless prefetching.
 }
 
 func (s *server) GetServerInfo(ctx context.Context) (*service.ServerInfo, error) {
@@ -115,7 +146,11 @@ func (s *server) GetStringTable(ctx context.Context, info *stringtable.Info) (*s
 }
 
 func (s *server) ImportCapture(ctx context.Context, name string, data []uint8) (*path.Capture, error) {
-	return capture.Import(ctx, name, bytes.NewReader(data))
+	p, err := capture.Import(ctx, name, bytes.NewReader(data))
+	if err == nil && p != nil && s.prefetcher != nil {
+		s.prefetcher.start(ctx, p)
+	}
+	return p, err
 }
 
 func (s *server) ExportCapture(ctx context.Context, c *path.Capture) ([]byte, error) {
@@ -127,7 +162,18 @@ func (s *server) ExportCapture(ctx context.Context, c *path.Capture) ([]byte, er
 }
 
 func (s *server) LoadCapture(ctx context.Context, path string) (*path.Capture, error) {
+	p, err := s.loadCapture(ctx, path)
+	if err == nil && p != nil && s.prefetcher != nil {
+		s.prefetcher.start(ctx, p)
+	}
+	return p, err
+}
+
+func (s *server) loadCapture(ctx context.Context, path string) (*path.Capture, error) {
 	name := filepath.Base(path)
+	if capture.IsChunkIndex(path) {
+		return capture.ImportChunks(ctx, name, path)
+	}
 	in, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -135,6 +181,10 @@ func (s *server) LoadCapture(ctx context.Context, path string) (*path.Capture, e
 	return capture.Import(ctx, name, in)
 }
 
+func (s *server) GetCaptures(ctx context.Context) (*service.LoadedCaptures, error) {
+	return resolve.LoadedCaptures(ctx)
+}
+
 // Returns all devices, sorted by Android first, and then Host
 func getSortedDevices(ctx context.Context) []bind.Device {
 	all := bind.GetRegistry(ctx).Devices()
@@ -264,6 +314,181 @@ func (s *server) GetFramebufferAttachment(
 	return resolve.FramebufferAttachment(ctx, device, after, attachment, settings, hints)
 }
 
+func (s *server) ImportCrashDump(ctx context.Context, c *path.Capture, data []byte) (*service.CrashDumpCorrelation, error) {
+	return resolve.CrashDump(ctx, c, data)
+}
+
+func (s *server) ExportRenderDocEvents(ctx context.Context, c *path.Capture) (*service.RenderDocEvents, error) {
+	return resolve.RenderDocEvents(ctx, c)
+}
+
+func (s *server) ExportPerfettoTrace(ctx context.Context, c *path.Capture, d *path.Device) ([]byte, error) {
+	return resolve.PerfettoTrace(ctx, c, d)
+}
+
+func (s *server) ExportTexture(ctx context.Context, r *path.ResourceData, c service.TextureContainer) ([]byte, error) {
+	return resolve.ExportTexture(ctx, r, c)
+}
+
+func (s *server) GetExperiments(ctx context.Context) ([]*service.ExperimentInfo, error) {
+	all := experiments.All()
+	out := make([]*service.ExperimentInfo, len(all))
+	for i, e := range all {
+		out[i] = e.Service()
+	}
+	return out, nil
+}
+
+func (s *server) RunExperiment(ctx context.Context, r *service.ExperimentRequest) (*service.ExperimentResult, error) {
+	result, err := resolve.Experiment(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.experiments.Add(ctx, r.After.Commands.Capture.Id.ID(), result); err != nil {
+		log.W(ctx, "Failed to persist experiment result: %v", err)
+	}
+	return result, nil
+}
+
+func (s *server) GetExperimentResults(ctx context.Context, c *path.Capture) ([]*service.ExperimentResult, error) {
+	results, err := s.experiments.Results(ctx, c.Id.ID())
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range results.Results {
+		if r.Capture == nil {
+			continue
+		}
+		if _, err := capture.ResolveFromPath(ctx, r.Capture); err == nil {
+			continue
+		}
+		// The result was loaded from disk, and the modified capture is not
+		// known to this server instance. Rebuild it.
+		refreshed, err := resolve.Experiment(ctx, r.Request)
+		if err != nil {
+			return nil, err
+		}
+		refreshed.Timestamp = r.Timestamp
+		results.Results[i] = refreshed
+	}
+	return results.Results, nil
+}
+
+func (s *server) ExtractMinimalRepro(ctx context.Context, p *path.Command) (*path.Capture, error) {
+	return resolve.MinimalRepro(ctx, p)
+}
+
+func (s *server) TrimCapture(ctx context.Context, c *path.Capture, firstFrame, lastFrame uint64) (*path.Capture, error) {
+	return resolve.Trim(ctx, c, firstFrame, lastFrame)
+}
+
+func (s *server) GetFrameDeltas(ctx context.Context, c *path.Capture) (*service.FrameDeltas, error) {
+	return resolve.FrameDeltas(ctx, c)
+}
+
+func (s *server) DiffCaptures(ctx context.Context, a, b *path.Capture) (*service.CaptureDiff, error) {
+	return resolve.CaptureDiff(ctx, a, b)
+}
+
+func (s *server) GetStateDiff(ctx context.Context, from, to *path.Command) (*service.StateDiff, error) {
+	return resolve.StateDiff(ctx, from, to)
+}
+
+func (s *server) SpliceCommands(ctx context.Context, c *path.Capture, r *service.CommandRange, commands *atom.List) (*path.Capture, error) {
+	return resolve.Splice(ctx, c, r.First, r.First+r.Count, commands)
+}
+
+func (s *server) GetMemoryProvenance(ctx context.Context, p *path.Memory) (*service.MemoryProvenance, error) {
+	return resolve.MemoryProvenance(ctx, p)
+}
+
+func (s *server) GetBufferView(ctx context.Context, p *path.Memory, l *service.BufferLayout) (*service.BufferView, error) {
+	return resolve.BufferView(ctx, p, l)
+}
+
+func (s *server) SearchCommands(ctx context.Context, c *path.Capture, query string, handler func([]uint64) error) error {
+	return resolve.SearchCommands(ctx, c, query, handler)
+}
+
+func (s *server) GetPixelHistory(
+	ctx context.Context,
+	device *path.Device,
+	c *path.Capture,
+	r *service.CommandRange,
+	attachment gfxapi.FramebufferAttachment,
+	x, y uint32) (*service.PixelHistory, error) {
+
+	return resolve.PixelHistory(ctx, device, c, r.First, r.Count, attachment, x, y)
+}
+
+func (s *server) GetResourceTimeline(ctx context.Context, c *path.Capture, id *path.ID) (*service.ResourceTimeline, error) {
+	return resolve.ResourceTimeline(ctx, c, id)
+}
+
+func (s *server) GetDispatchSnapshot(ctx context.Context, d *path.Device, c *path.Command) (*service.DispatchSnapshot, error) {
+	return resolve.DispatchSnapshot(ctx, d, c)
+}
+
+func (s *server) GetTimingProfile(ctx context.Context, d *path.Device, c *path.Capture) (*service.TimingProfile, error) {
+	return resolve.TimingProfile(ctx, d, c)
+}
+
+func (s *server) GetPipelineStatistics(ctx context.Context, d *path.Device, c *path.Capture) (*service.PipelineStatistics, error) {
+	return resolve.PipelineStatistics(ctx, d, c)
+}
+
+func (s *server) GetFrameLoopProfile(ctx context.Context, d *path.Device, c *path.Capture, frame, iterations uint32, experiments []string, parameters map[string]string) (*service.FrameLoopProfile, error) {
+	return resolve.FrameLoopProfile(ctx, d, c, frame, iterations, experiments, parameters)
+}
+
+func (s *server) GetLeakReport(ctx context.Context, c *path.Capture) (*service.LeakReport, error) {
+	return resolve.LeakReport(ctx, c)
+}
+
+func (s *server) GetMemoryProfile(ctx context.Context, c *path.Capture) (*service.MemoryProfile, error) {
+	return resolve.MemoryProfile(ctx, c)
+}
+
+func (s *server) GetLintReport(ctx context.Context, c *path.Capture) (*service.LintReport, error) {
+	return resolve.LintReport(ctx, c)
+}
+
+func (s *server) ExportCpp(ctx context.Context, d *path.Device, c *path.Capture, frame uint32) ([]byte, error) {
+	return resolve.ExportCpp(ctx, d, c, frame)
+}
+
+func (s *server) ExportCommands(ctx context.Context, c *path.Capture, data bool, handler func(*service.ExportedCommands) error) error {
+	return resolve.ExportCommands(ctx, c, data, handler)
+}
+
+func (s *server) SanitizeCapture(ctx context.Context, c *path.Capture, opts *service.SanitizeOptions) (*path.Capture, error) {
+	return resolve.Sanitize(ctx, c, opts)
+}
+
+func (s *server) EditCommands(ctx context.Context, c *path.Capture, edits []*service.CommandEdit) (*path.Capture, error) {
+	return resolve.EditCommands(ctx, c, edits)
+}
+
+func (s *server) GetCommandTracks(ctx context.Context, c *path.Capture) (*service.CommandTracks, error) {
+	return resolve.CommandTracks(ctx, c)
+}
+
+func (s *server) GetSubmissionTimeline(ctx context.Context, c *path.Capture) (*service.SubmissionTimeline, error) {
+	return resolve.SubmissionTimeline(ctx, c)
+}
+
+func (s *server) GetFrameGraph(ctx context.Context, c *path.Capture) (*service.FrameGraph, error) {
+	return resolve.FrameGraph(ctx, c)
+}
+
+func (s *server) GetRedundancyReport(ctx context.Context, c *path.Capture) (*service.RedundancyReport, error) {
+	return resolve.RedundancyReport(ctx, c)
+}
+
+func (s *server) GetShaderAnalysis(ctx context.Context, c *path.Capture) (*service.ShaderAnalysis, error) {
+	return resolve.ShaderAnalysis(ctx, c)
+}
+
 func (s *server) Get(ctx context.Context, p *path.Any) (interface{}, error) {
 	// TODO: Path validation
 	// if err := p.Validate(); err != nil {
@@ -318,6 +543,10 @@ func (s *server) GetProfile(ctx context.Context, name string, debug int32) ([]by
 	return b.Bytes(), nil
 }
 
+func (s *server) CancelRequests(ctx context.Context, ids []string) (uint32, error) {
+	return uint32(s.requests.cancel(ids)), nil
+}
+
 func (s *server) GetLogStream(ctx context.Context, handler log.Handler) error {
 	handler = log.Channel(handler, 64)
 	unregister := s.logBroadcaster.Listen(handler)
@@ -328,3 +557,21 @@ func (s *server) GetLogStream(ctx context.Context, handler log.Handler) error {
 	<-task.ShouldStop(ctx)
 	return task.StopReason(ctx)
 }
+
+func (s *server) GetReplayStatusStream(ctx context.Context, handler func(*service.ReplayStatus)) error {
+	// The progress of the replays is coalesced rather than blocking them if the
+	// handler falls behind. Changes of stage are never dropped.
+	queue := replay.NewStatusQueue()
+	unregister := replay.GetManager(ctx).ListenStatus(queue.Push)
+	defer unregister()
+	for {
+		select {
+		case <-queue.Ready():
+			for _, status := range queue.Pop() {
+				handler(status)
+			}
+		case <-task.ShouldStop(ctx):
+			return task.StopReason(ctx)
+		}
+	}
+}
@@ -22,6 +22,7 @@ set(files
     errors.go
     object.go
     report.go
+    request.go
     service.go
     service.pb.go
     service.proto
@@ -29,4 +30,5 @@ set(files
 set(dirs
     path
     pod
+    scripting
 )
@@ -53,6 +53,7 @@ func (n *Blob) Path() *Any         { return &Any{&Any_Blob{n}} }
 func (n *Capture) Path() *Any      { return &Any{&Any_Capture{n}} }
 func (n *Command) Path() *Any      { return &Any{&Any_Command{n}} }
 func (n *Commands) Path() *Any     { return &Any{&Any_Commands{n}} }
+func (n *Constants) Path() *Any    { return &Any{&Any_Constants{n}} }
 func (n *Context) Path() *Any      { return &Any{&Any_Context{n}} }
 func (n *Contexts) Path() *Any     { return &Any{&Any_Contexts{n}} }
 func (n *Device) Path() *Any       { return &Any{&Any_Device{n}} }
@@ -77,6 +78,7 @@ func (n Blob) Parent() Node         { return nil }
 func (n Capture) Parent() Node      { return nil }
 func (n Command) Parent() Node      { return n.Commands }
 func (n Commands) Parent() Node     { return n.Capture }
+func (n Constants) Parent() Node    { return n.Command }
 func (n Context) Parent() Node      { return n.Contexts }
 func (n Contexts) Parent() Node     { return n.Capture }
 func (n Device) Parent() Node       { return nil }
@@ -101,6 +103,7 @@ func (n Blob) Text() string        { return fmt.Sprintf("blob<%x>", n.Id.Data) }
 func (n Capture) Text() string     { return fmt.Sprintf("capture<%x>", n.Id.Data) }
 func (n Command) Text() string     { return fmt.Sprintf("%v[%v]", n.Parent().Text(), n.Index) }
 func (n Commands) Text() string    { return fmt.Sprintf("%v.commands", n.Parent().Text()) }
+func (n Constants) Text() string   { return fmt.Sprintf("%v.constants", n.Parent().Text()) }
 func (n Context) Text() string     { return fmt.Sprintf("%v[%x]", n.Parent().Text(), n.Id.Data) }
 func (n Contexts) Text() string    { return fmt.Sprintf("%v.contexts", n.Parent().Text()) }
 func (n Device) Text() string      { return fmt.Sprintf("device<%x>", n.Id.Data) }
@@ -287,6 +290,12 @@ func (n *Command) Mesh(faceted bool) *Mesh {
 	}
 }
 
+// Constants returns the path node to the shader constants used by this
+// command.
+func (n *Command) Constants() *Constants {
+	return &Constants{Command: n}
+}
+
 // StateAfter returns the path node to the state after this command.
 func (n *Command) StateAfter() *State {
 	return &State{After: n}
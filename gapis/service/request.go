@@ -0,0 +1,68 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	requestIDHeader       = "gapis-request-id"
+	requestPriorityHeader = "gapis-request-priority"
+)
+
+// WithRequestID returns a copy of ctx that tags the RPC calls made with it
+// with id, so that they can be cancelled with CancelRequests. Starting a
+// request with the same identifier as an outstanding request cancels the
+// outstanding request, which lets a client supersede requests it no longer
+// needs, such as the framebuffer of a command the user has scrubbed past.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return withHeader(ctx, requestIDHeader, id)
+}
+
+// WithRequestPriority returns a copy of ctx that asks the server to schedule
+// the work of the RPC calls made with it at priority p. Higher priorities are
+// scheduled first, and 0 is the default.
+func WithRequestPriority(ctx context.Context, p int32) context.Context {
+	return withHeader(ctx, requestPriorityHeader, strconv.Itoa(int(p)))
+}
+
+// RequestInfo returns the request identifier and priority attached to the
+// incoming RPC call held by ctx.
+func RequestInfo(ctx context.Context) (id string, priority int32) {
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		return "", 0
+	}
+	if got := md[requestIDHeader]; len(got) == 1 {
+		id = got[0]
+	}
+	if got := md[requestPriorityHeader]; len(got) == 1 {
+		if p, err := strconv.ParseInt(got[0], 10, 32); err == nil {
+			priority = int32(p)
+		}
+	}
+	return id, priority
+}
+
+func withHeader(ctx context.Context, key, value string) context.Context {
+	if md, ok := metadata.FromContext(ctx); ok {
+		return metadata.NewContext(ctx, metadata.Join(md, metadata.Pairs(key, value)))
+	}
+	return metadata.NewContext(ctx, metadata.Pairs(key, value))
+}
@@ -62,6 +62,10 @@ type Service interface {
 	// capture identifier.
 	LoadCapture(ctx context.Context, path string) (*path.Capture, error)
 
+	// GetCaptures returns all the captures loaded by the server, with the
+	// size of the data observed by each of them.
+	GetCaptures(ctx context.Context) (*LoadedCaptures, error)
+
 	// GetDevices returns the full list of replay devices avaliable to the server.
 	// These include local replay devices and any connected Android devices.
 	// This list may change over time, as devices are connected and disconnected.
@@ -90,6 +94,162 @@ type Service interface {
 		settings *RenderSettings,
 		hints *UsageHints) (*path.ImageInfo, error)
 
+	// ImportCrashDump correlates a vendor GPU crash dump, captured alongside
+	// the capture c, with the commands and resources of c.
+	ImportCrashDump(ctx context.Context, c *path.Capture, data []byte) (*CrashDumpCorrelation, error)
+
+	// ExportRenderDocEvents returns the commands of the capture c arranged
+	// into the per-frame event trees, and event IDs, of RenderDoc.
+	ExportRenderDocEvents(ctx context.Context, c *path.Capture) (*RenderDocEvents, error)
+
+	// ExportPerfettoTrace returns the timeline of the capture c encoded as a
+	// Perfetto protobuf trace. If d is not nil, the trace also holds the GPU
+	// timings of the commands replayed on d.
+	ExportPerfettoTrace(ctx context.Context, c *path.Capture, d *path.Device) ([]byte, error)
+
+	// ExportTexture returns all the mip levels and layers of the texture
+	// resource r encoded in the container c.
+	ExportTexture(ctx context.Context, r *path.ResourceData, c TextureContainer) ([]byte, error)
+
+	// GetExperiments returns the list of experiments that can be run on
+	// captures.
+	GetExperiments(ctx context.Context) ([]*ExperimentInfo, error)
+
+	// RunExperiment applies the experiment described by r to the capture of
+	// r.After, and returns the modified capture along with the framebuffer
+	// after the requested command. The result is persisted with the capture.
+	RunExperiment(ctx context.Context, r *ExperimentRequest) (*ExperimentResult, error)
+
+	// GetExperimentResults returns the results of all the experiments that
+	// have been run on the capture c.
+	GetExperimentResults(ctx context.Context, c *path.Capture) ([]*ExperimentResult, error)
+
+	// ExtractMinimalRepro builds a new capture holding only the commands
+	// required to reproduce the output of the draw call at p, and returns the
+	// path to the new capture.
+	ExtractMinimalRepro(ctx context.Context, p *path.Command) (*path.Capture, error)
+
+	// TrimCapture builds a new capture holding only the commands required to
+	// replay the frames firstFrame to lastFrame (inclusive) of the capture c,
+	// and returns the path to the new capture.
+	TrimCapture(ctx context.Context, c *path.Capture, firstFrame, lastFrame uint64) (*path.Capture, error)
+
+	// GetFrameDeltas returns, for each frame of the capture c, the resources
+	// used by the frame and which of them changed since they were last used.
+	GetFrameDeltas(ctx context.Context, c *path.Capture) (*FrameDeltas, error)
+
+	// DiffCaptures compares the captures a and b frame by frame, and returns
+	// the differences between their commands, API state and resources.
+	DiffCaptures(ctx context.Context, a, b *path.Capture) (*CaptureDiff, error)
+
+	// GetStateDiff returns the changes made to the API state by the commands
+	// after from, up to and including to: the objects created and destroyed,
+	// the fields that changed value and the bindings that changed object.
+	GetStateDiff(ctx context.Context, from, to *path.Command) (*StateDiff, error)
+
+	// SpliceCommands builds a new capture holding the commands of the capture
+	// c, with the commands in the range r replaced by commands, and returns
+	// the path to the new capture.
+	SpliceCommands(ctx context.Context, c *path.Capture, r *CommandRange, commands *atom.List) (*path.Capture, error)
+
+	// GetMemoryProvenance returns, for each byte of the memory at p, the
+	// command that last wrote it up to and including the command p.After.
+	GetMemoryProvenance(ctx context.Context, p *path.Memory) (*MemoryProvenance, error)
+
+	// GetBufferView returns the memory at p interpreted as an array of structs
+	// laid out as described by l.
+	GetBufferView(ctx context.Context, p *path.Memory, l *BufferLayout) (*BufferView, error)
+
+	// SearchCommands calls handler with the indices of the commands of the
+	// capture c matching query, in increasing order and in batches.
+	SearchCommands(ctx context.Context, c *path.Capture, query string, handler func([]uint64) error) error
+
+	// GetPixelHistory returns the draw commands within the range r of the
+	// capture c that changed the pixel at (x, y) of the given framebuffer
+	// attachment, when replayed on device, with the resulting colors.
+	GetPixelHistory(
+		ctx context.Context,
+		device *path.Device,
+		c *path.Capture,
+		r *CommandRange,
+		attachment gfxapi.FramebufferAttachment,
+		x, y uint32) (*PixelHistory, error)
+
+	// GetResourceTimeline returns the commands of the capture c that created,
+	// modified, used and deleted the resource with the identifier id.
+	GetResourceTimeline(ctx context.Context, c *path.Capture, id *path.ID) (*ResourceTimeline, error)
+
+	// GetDispatchSnapshot returns the content of the storage buffers and
+	// images bound to the compute dispatch command c, before and after it
+	// executed, replayed on the given device.
+	GetDispatchSnapshot(ctx context.Context, d *path.Device, c *path.Command) (*DispatchSnapshot, error)
+
+	// GetTimingProfile returns the GPU execution time of each draw and
+	// dispatch command of the capture c, replayed on the given device.
+	GetTimingProfile(ctx context.Context, d *path.Device, c *path.Capture) (*TimingProfile, error)
+
+	// GetPipelineStatistics returns the pipeline statistics of each draw and
+	// dispatch command of the capture c, and of each render pass, replayed on
+	// the given device.
+	GetPipelineStatistics(ctx context.Context, d *path.Device, c *path.Capture) (*PipelineStatistics, error)
+
+	// GetFrameLoopProfile returns the GPU time of each iteration of the frame
+	// of the capture c, replayed iterations times in a loop on the given
+	// device, with the named experiments applied with the given parameters.
+	// Frames are numbered from 1.
+	GetFrameLoopProfile(ctx context.Context, d *path.Device, c *path.Capture, frame, iterations uint32, experiments []string, parameters map[string]string) (*FrameLoopProfile, error)
+
+	// GetLeakReport returns the objects that the capture c creates and never
+	// releases, grouped by creating command.
+	GetLeakReport(ctx context.Context, c *path.Capture) (*LeakReport, error)
+
+	// GetMemoryProfile returns the device memory used by the capture c at the
+	// end of each frame.
+	GetMemoryProfile(ctx context.Context, c *path.Capture) (*MemoryProfile, error)
+
+	// GetLintReport returns the issues found by checking the commands of the
+	// capture c against correctness rules, without replaying them.
+	GetLintReport(ctx context.Context, c *path.Capture) (*LintReport, error)
+
+	// ExportCpp returns the source of a standalone C++ program reproducing the
+	// capture c on the given device, repeating the frame in a loop.
+	ExportCpp(ctx context.Context, d *path.Device, c *path.Capture, frame uint32) ([]byte, error)
+
+	// ExportCommands calls handler with the commands of the capture c decoded
+	// for the tools not linking against GAPIS, in batches. If data is true, the
+	// observations hold the observed memory.
+	ExportCommands(ctx context.Context, c *path.Capture, data bool, handler func(*ExportedCommands) error) error
+
+	// SanitizeCapture builds a new capture from the capture c with the
+	// potentially sensitive data selected by opts replaced, and returns its
+	// path.
+	SanitizeCapture(ctx context.Context, c *path.Capture, opts *SanitizeOptions) (*path.Capture, error)
+
+	// EditCommands builds a new capture from the capture c with the command
+	// parameters overridden by edits, and returns its path. The commands that
+	// are not edited are shared with c, whose resolved data is unaffected.
+	EditCommands(ctx context.Context, c *path.Capture, edits []*CommandEdit) (*path.Capture, error)
+
+	// GetCommandTracks partitions the commands of the capture c by issuing
+	// thread, by context and by queue, for display as a multi-track timeline.
+	GetCommandTracks(ctx context.Context, c *path.Capture) (*CommandTracks, error)
+
+	// GetSubmissionTimeline returns the queue submissions of the capture c as a
+	// graph of their semaphore, fence and queue order dependencies.
+	GetSubmissionTimeline(ctx context.Context, c *path.Capture) (*SubmissionTimeline, error)
+
+	// GetFrameGraph returns the render passes, dispatches and transfers
+	// executed by the capture c, connected by the resources they access.
+	GetFrameGraph(ctx context.Context, c *path.Capture) (*FrameGraph, error)
+
+	// GetRedundancyReport returns the commands of the capture c that set state
+	// to the value it already holds, summarized per frame.
+	GetRedundancyReport(ctx context.Context, c *path.Capture) (*RedundancyReport, error)
+
+	// GetShaderAnalysis returns the issues found by the static analysis of the
+	// shaders of the capture c.
+	GetShaderAnalysis(ctx context.Context, c *path.Capture) (*ShaderAnalysis, error)
+
 	// Get resolves and returns the object, value or memory at the path p.
 	Get(ctx context.Context, p *path.Any) (interface{}, error)
 
@@ -119,9 +279,20 @@ type Service interface {
 	// GetProfile returns the pprof profile with the given name.
 	GetProfile(ctx context.Context, name string, debug int32) ([]byte, error)
 
+	// CancelRequests cancels the outstanding requests started with a context
+	// returned by WithRequestID for any of the given identifiers, returning the
+	// number of requests cancelled. Work shared with other requests carries on
+	// until nothing is waiting for it.
+	CancelRequests(ctx context.Context, ids []string) (uint32, error)
+
 	// GetLogStream calls the handler with each log record raised until the
 	// context is cancelled.
 	GetLogStream(context.Context, log.Handler) error
+
+	// GetReplayStatusStream calls the handler with the status of the replays
+	// performed by the server as they progress, until the context is
+	// cancelled.
+	GetReplayStatusStream(ctx context.Context, handler func(*ReplayStatus)) error
 }
 
 // NewError attempts to box and return err into an Error.
@@ -170,10 +341,16 @@ func NewValue(v interface{}) *Value {
 		return &Value{&Value_Contexts{&Contexts{v}}}
 	case *gfxapi.Mesh:
 		return &Value{&Value_Mesh{v}}
+	case *gfxapi.ConstantTable:
+		return &Value{&Value_ConstantTable{v}}
 	case *gfxapi.Texture2D:
 		return &Value{&Value_Texture_2D{v}}
 	case *gfxapi.Cubemap:
 		return &Value{&Value_Cubemap{v}}
+	case *gfxapi.Texture2DArray:
+		return &Value{&Value_Texture_2DArray{v}}
+	case *gfxapi.Texture3D:
+		return &Value{&Value_Texture_3D{v}}
 	case *gfxapi.Shader:
 		return &Value{&Value_Shader{v}}
 	case *gfxapi.Program:
@@ -19,6 +19,9 @@
 
 set(files
     shadertools.go
+    shadertools_test.go
+    spirv.go
+    spirv_test.go
 )
 set(dirs
     cc
@@ -25,6 +25,7 @@
 #include <cstring>
 #include <iostream>
 #include <sstream>
+#include <stdexcept>
 #include <string>
 #include <vector>
 
@@ -130,6 +131,12 @@ void set_error_msg(code_with_debug_info_t* x, std::string msg) {
   strcpy(x->message, msg.c_str());
 }
 
+void set_error_msg(glsl_compile_result_t* x, std::string msg) {
+  x->ok = false;
+  x->message = new char[msg.length() + 1];
+  strcpy(x->message, msg.c_str());
+}
+
 std::vector<unsigned int> parseGlslang(const char* code, std::string* err_msg, bool is_fragment_shader,
                                        bool es_profile) {
   std::vector<unsigned int> spirv;
@@ -168,6 +175,73 @@ std::vector<unsigned int> parseGlslang(const char* code, std::string* err_msg, b
   return spirv;
 }
 
+/**
+ * Compiles Vulkan GLSL source code of the given stage to spirv using glslang.
+ **/
+glsl_compile_result_t* compileGlslToSpirv(const char* code, shader_stage_t stage) {
+  glsl_compile_result_t* result = new glsl_compile_result_t{};
+
+  EShLanguage lang;
+  switch (stage) {
+    case SHADER_STAGE_VERTEX: lang = EShLangVertex; break;
+    case SHADER_STAGE_TESS_CONTROL: lang = EShLangTessControl; break;
+    case SHADER_STAGE_TESS_EVALUATION: lang = EShLangTessEvaluation; break;
+    case SHADER_STAGE_GEOMETRY: lang = EShLangGeometry; break;
+    case SHADER_STAGE_FRAGMENT: lang = EShLangFragment; break;
+    case SHADER_STAGE_COMPUTE: lang = EShLangCompute; break;
+    default:
+      set_error_msg(result, "error: Unknown shader stage.");
+      return result;
+  }
+
+  EShMessages messages = static_cast<EShMessages>(EShMsgSpvRules | EShMsgVulkanRules);
+  std::string err_msg;
+  std::vector<unsigned int> spirv;
+
+  glslang::InitializeProcess();
+  {
+    glslang::TShader shader(lang);
+    shader.setStrings(&code, 1);
+    bool parsed = shader.parse(&DefaultTBuiltInResource, 450, ECoreProfile,
+                               false /* force version and profile */, false, /* forward compatible */
+                               messages);
+    if (!parsed) {
+      err_msg = "Compile failed\n";
+      err_msg += "InfoLog: " + std::string(shader.getInfoLog());
+    } else {
+      glslang::TProgram program;
+      program.addShader(&shader);
+      if (!program.link(messages)) {
+        err_msg = "link failed\n";
+        err_msg += "InfoLog:\n" + std::string(program.getInfoLog());
+      } else {
+        spv::SpvBuildLogger logger;
+        glslang::GlslangToSpv(*program.getIntermediate(lang), spirv, &logger);
+      }
+    }
+  }
+  glslang::FinalizeProcess();
+
+  if (!err_msg.empty()) {
+    set_error_msg(result, err_msg);
+    return result;
+  }
+
+  result->binary.words_num = spirv.size();
+  result->binary.words = new uint32_t[spirv.size()];
+  for (size_t i = 0; i < spirv.size(); i++) {
+    result->binary.words[i] = spirv[i];
+  }
+  result->ok = true;
+  return result;
+}
+
+void deleteGlslCompileResult(glsl_compile_result_t* result) {
+  if (result->message) delete[] result->message;
+  if (result->binary.words) delete[] result->binary.words;
+  delete result;
+}
+
 /**
  * Only Vertex and Fragment shaders are supported.
  * 1. Compiles source code to spirv using glslang,
@@ -299,6 +373,37 @@ void deleteDisassembleText(const char* text) {
     delete[] text;
 }
 
+const char* getDecompiledText(uint32_t* spirv_binary, size_t length,
+                              source_language_t language) {
+  std::vector<uint32_t> spirv_vec(spirv_binary, spirv_binary + length);
+
+  std::string source;
+  try {
+    switch (language) {
+      case SOURCE_LANGUAGE_GLSL:
+        source = spirv2vulkanglsl(std::move(spirv_vec));
+        break;
+      case SOURCE_LANGUAGE_HLSL:
+        source = spirv2hlsl(std::move(spirv_vec));
+        break;
+      default:
+        return nullptr;
+    }
+  } catch (const std::exception&) {
+    // SPIRV-Cross reports the shaders it cannot decompile with exceptions.
+    return nullptr;
+  }
+
+  char* chars = new char[source.size() + 1];
+  strcpy(chars, source.c_str());
+  return chars;
+}
+
+void deleteDecompiledText(const char* text) {
+  if (text)
+    delete[] text;
+}
+
 spirv_binary_t* assembleToBinary(const char* text) {
   if (!text) {
     return nullptr;
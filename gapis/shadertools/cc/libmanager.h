@@ -61,11 +61,31 @@ typedef struct options_t {
   bool disassemble;
 } options_t;
 
+typedef enum source_language_t {
+  SOURCE_LANGUAGE_GLSL,
+  SOURCE_LANGUAGE_HLSL,
+} source_language_t;
+
 typedef struct spirv_binary_t {
     uint32_t* words;
     size_t words_num;
 } spirv_binary_t;
 
+typedef enum shader_stage_t {
+  SHADER_STAGE_VERTEX,
+  SHADER_STAGE_TESS_CONTROL,
+  SHADER_STAGE_TESS_EVALUATION,
+  SHADER_STAGE_GEOMETRY,
+  SHADER_STAGE_FRAGMENT,
+  SHADER_STAGE_COMPUTE,
+} shader_stage_t;
+
+typedef struct glsl_compile_result_t {
+  bool ok;
+  char* message;
+  spirv_binary_t binary;
+} glsl_compile_result_t;
+
 code_with_debug_info_t* convertGlsl(const char*, size_t, const options_t*);
 
 void deleteGlslCodeWithDebug(code_with_debug_info_t*);
@@ -74,8 +94,16 @@ const char* getDisassembleText(uint32_t*, size_t);
 
 void deleteDisassembleText(const char*);
 
+const char* getDecompiledText(uint32_t*, size_t, source_language_t);
+
+void deleteDecompiledText(const char*);
+
 spirv_binary_t* assembleToBinary(const char*);
 
+glsl_compile_result_t* compileGlslToSpirv(const char*, shader_stage_t);
+
+void deleteGlslCompileResult(glsl_compile_result_t*);
+
 void deleteBinary(spirv_binary_t*);
 
 const char* opcodeToString(uint32_t);
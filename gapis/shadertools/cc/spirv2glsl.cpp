@@ -20,6 +20,7 @@
 // The version might not exactly match the one in SPRTV-Tools,
 // so it is important we never include both at the same time.
 #include "third_party/SPIRV-Cross/spirv_glsl.hpp"
+#include "third_party/SPIRV-Cross/spirv_hlsl.hpp"
 
 std::string spirv2glsl(std::vector<uint32_t> spirv) {
   spirv_cross::CompilerGLSL glsl(std::move(spirv));
@@ -31,3 +32,21 @@ std::string spirv2glsl(std::vector<uint32_t> spirv) {
   glsl.set_options(cross_options);
   return glsl.compile();
 }
+
+std::string spirv2vulkanglsl(std::vector<uint32_t> spirv) {
+  spirv_cross::CompilerGLSL glsl(std::move(spirv));
+  spirv_cross::CompilerGLSL::Options cross_options;
+  cross_options.version = 450;
+  cross_options.es = false;
+  cross_options.vulkan_semantics = true;
+  glsl.set_options(cross_options);
+  return glsl.compile();
+}
+
+std::string spirv2hlsl(std::vector<uint32_t> spirv) {
+  spirv_cross::CompilerHLSL hlsl(std::move(spirv));
+  spirv_cross::CompilerHLSL::Options cross_options;
+  cross_options.shader_model = 50;
+  hlsl.set_options(cross_options);
+  return hlsl.compile();
+}
@@ -18,3 +18,9 @@
 #include <vector>
 
 std::string spirv2glsl(std::vector<uint32_t> spirv);
+
+// Decompiles the SPIR-V to GLSL 450 with Vulkan semantics.
+std::string spirv2vulkanglsl(std::vector<uint32_t> spirv);
+
+// Decompiles the SPIR-V to HLSL shader model 5.0.
+std::string spirv2hlsl(std::vector<uint32_t> spirv);
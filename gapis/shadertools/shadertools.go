@@ -154,6 +154,40 @@ func DisassembleSpirvBinary(words []uint32) string {
 	return source
 }
 
+// SourceLanguage is a high level shading language SPIR-V can be decompiled to.
+type SourceLanguage int
+
+const (
+	// GLSL is GLSL 450 with Vulkan semantics.
+	GLSL SourceLanguage = iota
+	// HLSL is HLSL shader model 5.0.
+	HLSL
+)
+
+// DecompileSpirvBinary decompiles the given SPIR-V binary words to language by
+// calling SPIRV-Cross and returns the source code.
+func DecompileSpirvBinary(words []uint32, language SourceLanguage) (string, error) {
+	if len(words) == 0 {
+		return "", fmt.Errorf("No SPIR-V to decompile")
+	}
+	var lang C.source_language_t
+	switch language {
+	case GLSL:
+		lang = C.SOURCE_LANGUAGE_GLSL
+	case HLSL:
+		lang = C.SOURCE_LANGUAGE_HLSL
+	default:
+		return "", fmt.Errorf("Unknown source language %v", language)
+	}
+	text := C.getDecompiledText((*C.uint32_t)(&words[0]), C.size_t(len(words)), lang)
+	if text == nil {
+		return "", fmt.Errorf("Failed to decompile the SPIR-V shader")
+	}
+	source := C.GoString(text)
+	C.deleteDecompiledText(text)
+	return source, nil
+}
+
 // AssembleSpirvText assembles the given SPIR-V text chars by calling
 // SPIRV-Tools and returns the slice for the encoded binary. Returns nil
 // if assembling fails.
@@ -176,6 +210,56 @@ func AssembleSpirvText(chars string) []uint32 {
 	return words
 }
 
+// ShaderStage is a shader pipeline stage GLSL can be compiled for.
+type ShaderStage int
+
+const (
+	StageVertex ShaderStage = iota
+	StageTessControl
+	StageTessEvaluation
+	StageGeometry
+	StageFragment
+	StageCompute
+)
+
+// CompileGlsl compiles the given Vulkan GLSL source code of a shader of the
+// given stage to SPIR-V by calling glslang and returns the SPIR-V binary
+// words.
+func CompileGlsl(source string, stage ShaderStage) ([]uint32, error) {
+	var s C.shader_stage_t
+	switch stage {
+	case StageVertex:
+		s = C.SHADER_STAGE_VERTEX
+	case StageTessControl:
+		s = C.SHADER_STAGE_TESS_CONTROL
+	case StageTessEvaluation:
+		s = C.SHADER_STAGE_TESS_EVALUATION
+	case StageGeometry:
+		s = C.SHADER_STAGE_GEOMETRY
+	case StageFragment:
+		s = C.SHADER_STAGE_FRAGMENT
+	case StageCompute:
+		s = C.SHADER_STAGE_COMPUTE
+	default:
+		return nil, fmt.Errorf("Unknown shader stage %v", stage)
+	}
+	text := C.CString(source)
+	result := C.compileGlslToSpirv(text, s)
+	C.free(unsafe.Pointer(text))
+	defer C.deleteGlslCompileResult(result)
+
+	if !result.ok {
+		return nil, fmt.Errorf("Failed to compile the GLSL shader: %v", C.GoString(result.message))
+	}
+	count := uint64(result.binary.words_num)
+	words := make([]uint32, count)
+	if count > 0 {
+		data := (*[1 << 30]uint32)(unsafe.Pointer(result.binary.words))[:count:count]
+		copy(words, data)
+	}
+	return words, nil
+}
+
 // OpcodeToString converts opcode number to human readable string.
 func OpcodeToString(opcode uint32) string {
 	return C.GoString(C.opcodeToString(C.uint32_t(opcode)))
@@ -0,0 +1,54 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadertools_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+func TestCompileGlsl(t *testing.T) {
+	ctx := log.Testing(t)
+	words, err := shadertools.CompileGlsl(`
+		#version 450
+		layout(set = 0, binding = 1) uniform sampler2D tex;
+		layout(location = 0) in vec2 texcoord;
+		layout(location = 0) out vec4 color;
+		void main() {
+			color = texture(tex, texcoord);
+		}`, shadertools.StageFragment)
+	if !assert.For(ctx, "Valid shader").ThatError(err).Succeeded() {
+		return
+	}
+	assert.For(ctx, "Magic number").That(words[0]).Equals(uint32(0x07230203))
+	bindings, err := shadertools.DescriptorBindings(words)
+	if !assert.For(ctx, "Bindings").ThatError(err).Succeeded() {
+		return
+	}
+	assert.For(ctx, "Bindings").ThatSlice(bindings).Equals([]shadertools.DescriptorBinding{
+		{Name: "tex", Set: 0, Binding: 1, Type: shadertools.CombinedImageSampler, Count: 1, Used: true},
+	})
+
+	_, err = shadertools.CompileGlsl(`
+		#version 450
+		layout(location = 0) out vec4 color;
+		void main() {
+			color = undeclared;
+		}`, shadertools.StageFragment)
+	assert.For(ctx, "Invalid shader").ThatError(err).Failed()
+}
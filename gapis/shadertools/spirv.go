@@ -0,0 +1,679 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadertools
+
+import "fmt"
+
+// DescriptorType is the kind of resource accessed by a shader through a
+// descriptor binding.
+type DescriptorType int
+
+const (
+	UnknownDescriptor DescriptorType = iota
+	Sampler
+	CombinedImageSampler
+	SampledImage
+	StorageImage
+	UniformTexelBuffer
+	StorageTexelBuffer
+	UniformBuffer
+	StorageBuffer
+	InputAttachment
+)
+
+var descriptorTypeNames = map[DescriptorType]string{
+	UnknownDescriptor:    "unknown",
+	Sampler:              "sampler",
+	CombinedImageSampler: "combined image sampler",
+	SampledImage:         "sampled image",
+	StorageImage:         "storage image",
+	UniformTexelBuffer:   "uniform texel buffer",
+	StorageTexelBuffer:   "storage texel buffer",
+	UniformBuffer:        "uniform buffer",
+	StorageBuffer:        "storage buffer",
+	InputAttachment:      "input attachment",
+}
+
+func (t DescriptorType) String() string {
+	if name, ok := descriptorTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("DescriptorType(%d)", int(t))
+}
+
+// DescriptorBinding is a resource variable of a SPIR-V module that is bound
+// through a descriptor set.
+type DescriptorBinding struct {
+	Name    string         // Optional symbol name of the variable.
+	Set     uint32         // The DescriptorSet decoration.
+	Binding uint32         // The Binding decoration.
+	Type    DescriptorType // The kind of resource.
+	Count   uint32         // Number of array elements, 0 for runtime arrays.
+	Used    bool           // Whether the variable is referenced by any code.
+}
+
+const spirvMagic = 0x07230203
+
+// SPIR-V opcodes, decorations, storage classes and dimensions used to find
+// the descriptor bindings, uniform blocks and interface variables.
+const (
+	opName             = 5
+	opMemberName       = 6
+	opEntryPoint       = 15
+	opTypeBool         = 20
+	opTypeInt          = 21
+	opTypeFloat        = 22
+	opTypeVector       = 23
+	opTypeMatrix       = 24
+	opTypeImage        = 25
+	opTypeSampler      = 26
+	opTypeSampledImage = 27
+	opTypeArray        = 28
+	opTypeRuntimeArray = 29
+	opTypeStruct       = 30
+	opTypePointer      = 32
+	opConstant         = 43
+	opVariable         = 59
+	opDecorate         = 71
+	opMemberDecorate   = 72
+
+	decorationRelaxedPrecision = 0
+	decorationBlock            = 2
+	decorationBufferBlock      = 3
+	decorationRowMajor         = 4
+	decorationArrayStride      = 6
+	decorationMatrixStride     = 7
+	decorationLocation         = 30
+	decorationBinding          = 33
+	decorationDescriptorSet    = 34
+	decorationOffset           = 35
+
+	storageUniformConstant = 0
+	storageInput           = 1
+	storageUniform         = 2
+	storageOutput          = 3
+	storagePushConstant    = 9
+	storageStorageBuffer   = 12
+
+	dimBuffer      = 5
+	dimSubpassData = 6
+)
+
+// DescriptorBindings returns the descriptor bindings declared by the SPIR-V
+// module words, in declaration order.
+func DescriptorBindings(words []uint32) ([]DescriptorBinding, error) {
+	if len(words) < 5 || words[0] != spirvMagic {
+		return nil, fmt.Errorf("Not a SPIR-V module")
+	}
+
+	type variable struct {
+		id, ty, storage uint32
+	}
+	names := map[uint32]string{}
+	sets := map[uint32]uint32{}
+	bindings := map[uint32]uint32{}
+	blocks := map[uint32]uint32{}  // struct id -> Block or BufferBlock
+	types := map[uint32][]uint32{} // type id -> instruction words
+	constants := map[uint32]uint32{}
+	used := map[uint32]bool{}
+	variables := []variable{}
+	declared := map[uint32]bool{}
+
+	for i := 5; i < len(words); {
+		count, opcode := int(words[i]>>16), words[i]&0xffff
+		if count == 0 || i+count > len(words) {
+			return nil, fmt.Errorf("Invalid SPIR-V instruction at word %d", i)
+		}
+		inst := words[i : i+count]
+		i += count
+		switch opcode {
+		case opName:
+			if len(inst) > 2 {
+				names[inst[1]] = spirvString(inst[2:])
+			}
+		case opDecorate:
+			if len(inst) < 3 {
+				continue
+			}
+			switch inst[2] {
+			case decorationDescriptorSet:
+				if len(inst) > 3 {
+					sets[inst[1]] = inst[3]
+				}
+			case decorationBinding:
+				if len(inst) > 3 {
+					bindings[inst[1]] = inst[3]
+				}
+			case decorationBlock, decorationBufferBlock:
+				blocks[inst[1]] = inst[2]
+			}
+		case opEntryPoint, opMemberDecorate:
+		case opTypeImage, opTypeSampler, opTypeSampledImage, opTypeArray,
+			opTypeRuntimeArray, opTypeStruct, opTypePointer:
+			if len(inst) > 1 {
+				types[inst[1]] = inst
+			}
+		case opConstant:
+			if len(inst) > 3 {
+				constants[inst[2]] = inst[3]
+			}
+		case opVariable:
+			if len(inst) > 3 {
+				variables = append(variables, variable{id: inst[2], ty: inst[1], storage: inst[3]})
+				declared[inst[2]] = true
+			}
+		default:
+			// Any other instruction referencing a declared variable uses it.
+			for _, w := range inst[1:] {
+				if declared[w] {
+					used[w] = true
+				}
+			}
+		}
+	}
+
+	out := []DescriptorBinding{}
+	for _, v := range variables {
+		set, hasSet := sets[v.id]
+		binding, hasBinding := bindings[v.id]
+		if !hasSet && !hasBinding {
+			continue
+		}
+		switch v.storage {
+		case storageUniformConstant, storageUniform, storageStorageBuffer:
+		default:
+			continue
+		}
+		ty := types[v.ty]
+		if len(ty) < 4 || ty[0]&0xffff != opTypePointer {
+			return nil, fmt.Errorf("Variable %%%d does not have a pointer type", v.id)
+		}
+		// Unwrap the arrays of descriptors.
+		ty, count := types[ty[3]], uint32(1)
+		for len(ty) > 2 {
+			if op := ty[0] & 0xffff; op == opTypeArray && len(ty) > 3 {
+				count *= constants[ty[3]]
+			} else if op == opTypeRuntimeArray {
+				count = 0
+			} else {
+				break
+			}
+			ty = types[ty[2]]
+		}
+		out = append(out, DescriptorBinding{
+			Name:    names[v.id],
+			Set:     set,
+			Binding: binding,
+			Type:    descriptorType(ty, v.storage, blocks),
+			Count:   count,
+			Used:    used[v.id],
+		})
+	}
+	return out, nil
+}
+
+// descriptorType returns the kind of descriptor used by a variable of type ty
+// in the given storage class.
+func descriptorType(ty []uint32, storage uint32, blocks map[uint32]uint32) DescriptorType {
+	if len(ty) < 2 {
+		return UnknownDescriptor
+	}
+	switch ty[0] & 0xffff {
+	case opTypeSampler:
+		return Sampler
+	case opTypeSampledImage:
+		return CombinedImageSampler
+	case opTypeImage:
+		if len(ty) < 9 {
+			return UnknownDescriptor
+		}
+		dim, sampled := ty[3], ty[7]
+		switch {
+		case dim == dimSubpassData:
+			return InputAttachment
+		case dim == dimBuffer && sampled == 2:
+			return StorageTexelBuffer
+		case dim == dimBuffer:
+			return UniformTexelBuffer
+		case sampled == 2:
+			return StorageImage
+		default:
+			return SampledImage
+		}
+	case opTypeStruct:
+		switch {
+		case storage == storageStorageBuffer:
+			return StorageBuffer
+		case blocks[ty[1]] == decorationBufferBlock:
+			return StorageBuffer
+		case blocks[ty[1]] == decorationBlock:
+			return UniformBuffer
+		}
+	}
+	return UnknownDescriptor
+}
+
+// spirvString decodes the nul-terminated literal string packed in words.
+func spirvString(words []uint32) string {
+	bytes := make([]byte, 0, len(words)*4)
+	for _, w := range words {
+		for i := uint(0); i < 4; i++ {
+			c := byte(w >> (i * 8))
+			if c == 0 {
+				return string(bytes)
+			}
+			bytes = append(bytes, c)
+		}
+	}
+	return string(bytes)
+}
+
+// ScalarKind is the kind of the scalar components of a block member.
+type ScalarKind int
+
+const (
+	UnknownScalar ScalarKind = iota
+	Bool
+	Int
+	Uint
+	Float
+	Double
+)
+
+// BlockMember is a scalar, vector or matrix member of a uniform or push
+// constant block. Members of nested structures are flattened into the block.
+type BlockMember struct {
+	Name         string     // Dotted name of the member, if known.
+	Offset       uint32     // Byte offset of the member in the block.
+	Kind         ScalarKind // Kind of the scalar components.
+	Rows         uint32     // Number of vector components, 1 for scalars.
+	Columns      uint32     // Number of matrix columns, 1 for non-matrices.
+	Count        uint32     // Number of array elements, 1 for non-arrays.
+	ArrayStride  uint32     // Byte stride between the array elements.
+	MatrixStride uint32     // Byte stride between the matrix columns, or rows if RowMajor.
+	RowMajor     bool       // Whether the matrix rows are contiguous.
+}
+
+// ScalarSize returns the size in bytes of the scalar components of m.
+func (m BlockMember) ScalarSize() uint32 {
+	if m.Kind == Double {
+		return 8
+	}
+	return 4
+}
+
+// ScalarOffsets returns the byte offsets of each scalar component of m in the
+// block, in array element, column and row order.
+func (m BlockMember) ScalarOffsets() []uint32 {
+	out := make([]uint32, 0, m.Count*m.Columns*m.Rows)
+	for e := uint32(0); e < m.Count; e++ {
+		for c := uint32(0); c < m.Columns; c++ {
+			for r := uint32(0); r < m.Rows; r++ {
+				o := m.Offset + e*m.ArrayStride
+				if m.RowMajor {
+					o += r*m.MatrixStride + c*m.ScalarSize()
+				} else {
+					o += c*m.MatrixStride + r*m.ScalarSize()
+				}
+				out = append(out, o)
+			}
+		}
+	}
+	return out
+}
+
+// UniformBlock is a uniform buffer or push constant block declared by a
+// SPIR-V module.
+type UniformBlock struct {
+	Name         string        // Optional symbol name of the variable.
+	PushConstant bool          // Whether this is the push constant block.
+	Set          uint32        // The DescriptorSet decoration of a uniform buffer.
+	Binding      uint32        // The Binding decoration of a uniform buffer.
+	Members      []BlockMember // The flattened members of the block.
+}
+
+// UniformBlocks returns the uniform buffer and push constant blocks declared
+// by the SPIR-V module words, in declaration order. Arrays of uniform buffers
+// are not reported.
+func UniformBlocks(words []uint32) ([]UniformBlock, error) {
+	if len(words) < 5 || words[0] != spirvMagic {
+		return nil, fmt.Errorf("Not a SPIR-V module")
+	}
+
+	type variable struct {
+		id, ty, storage uint32
+	}
+	type member struct {
+		structure, index uint32
+	}
+	names := map[uint32]string{}
+	memberNames := map[member]string{}
+	sets := map[uint32]uint32{}
+	bindings := map[uint32]uint32{}
+	blocks := map[uint32]uint32{}
+	arrayStrides := map[uint32]uint32{}
+	offsets := map[member]uint32{}
+	matrixStrides := map[member]uint32{}
+	rowMajor := map[member]bool{}
+	types := map[uint32][]uint32{}
+	constants := map[uint32]uint32{}
+	variables := []variable{}
+
+	for i := 5; i < len(words); {
+		count, opcode := int(words[i]>>16), words[i]&0xffff
+		if count == 0 || i+count > len(words) {
+			return nil, fmt.Errorf("Invalid SPIR-V instruction at word %d", i)
+		}
+		inst := words[i : i+count]
+		i += count
+		switch opcode {
+		case opName:
+			if len(inst) > 2 {
+				names[inst[1]] = spirvString(inst[2:])
+			}
+		case opMemberName:
+			if len(inst) > 3 {
+				memberNames[member{inst[1], inst[2]}] = spirvString(inst[3:])
+			}
+		case opDecorate:
+			if len(inst) < 3 {
+				continue
+			}
+			switch inst[2] {
+			case decorationDescriptorSet:
+				if len(inst) > 3 {
+					sets[inst[1]] = inst[3]
+				}
+			case decorationBinding:
+				if len(inst) > 3 {
+					bindings[inst[1]] = inst[3]
+				}
+			case decorationArrayStride:
+				if len(inst) > 3 {
+					arrayStrides[inst[1]] = inst[3]
+				}
+			case decorationBlock, decorationBufferBlock:
+				blocks[inst[1]] = inst[2]
+			}
+		case opMemberDecorate:
+			if len(inst) < 4 {
+				continue
+			}
+			m := member{inst[1], inst[2]}
+			switch inst[3] {
+			case decorationOffset:
+				if len(inst) > 4 {
+					offsets[m] = inst[4]
+				}
+			case decorationMatrixStride:
+				if len(inst) > 4 {
+					matrixStrides[m] = inst[4]
+				}
+			case decorationRowMajor:
+				rowMajor[m] = true
+			}
+		case opTypeBool, opTypeInt, opTypeFloat, opTypeVector, opTypeMatrix,
+			opTypeArray, opTypeRuntimeArray, opTypeStruct, opTypePointer:
+			if len(inst) > 1 {
+				types[inst[1]] = inst
+			}
+		case opConstant:
+			if len(inst) > 3 {
+				constants[inst[2]] = inst[3]
+			}
+		case opVariable:
+			if len(inst) > 3 {
+				variables = append(variables, variable{id: inst[2], ty: inst[1], storage: inst[3]})
+			}
+		}
+	}
+
+	scalarKind := func(ty []uint32) ScalarKind {
+		switch {
+		case len(ty) < 2:
+			return UnknownScalar
+		case ty[0]&0xffff == opTypeBool:
+			return Bool
+		case ty[0]&0xffff == opTypeInt && len(ty) > 3 && ty[2] == 32:
+			if ty[3] != 0 {
+				return Int
+			}
+			return Uint
+		case ty[0]&0xffff == opTypeFloat && len(ty) > 2 && ty[2] == 32:
+			return Float
+		case ty[0]&0xffff == opTypeFloat && len(ty) > 2 && ty[2] == 64:
+			return Double
+		}
+		return UnknownScalar
+	}
+
+	// flatten appends the members of the structure with the type id to out,
+	// prefixing their names and offsetting them by base.
+	var flatten func(id uint32, prefix string, base uint32, out []BlockMember) []BlockMember
+	flatten = func(id uint32, prefix string, base uint32, out []BlockMember) []BlockMember {
+		structure := types[id]
+		for i := 2; i < len(structure); i++ {
+			key := member{id, uint32(i - 2)}
+			name := memberNames[key]
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			m := BlockMember{
+				Name:         name,
+				Offset:       base + offsets[key],
+				Rows:         1,
+				Columns:      1,
+				Count:        1,
+				MatrixStride: matrixStrides[key],
+				RowMajor:     rowMajor[key],
+			}
+			ty := types[structure[i]]
+			if len(ty) > 3 && ty[0]&0xffff == opTypeArray {
+				m.Count, m.ArrayStride = constants[ty[3]], arrayStrides[ty[1]]
+				ty = types[ty[2]]
+			}
+			if len(ty) < 2 {
+				continue
+			}
+			switch ty[0] & 0xffff {
+			case opTypeStruct:
+				if m.Count == 1 && m.ArrayStride == 0 {
+					out = flatten(ty[1], name, m.Offset, out)
+					continue
+				}
+				for e := uint32(0); e < m.Count; e++ {
+					out = flatten(ty[1], fmt.Sprintf("%s[%d]", name, e), m.Offset+e*m.ArrayStride, out)
+				}
+				continue
+			case opTypeMatrix:
+				if len(ty) < 4 {
+					continue
+				}
+				m.Columns, ty = ty[3], types[ty[2]]
+				if len(ty) < 4 || ty[0]&0xffff != opTypeVector {
+					continue
+				}
+				fallthrough
+			case opTypeVector:
+				if len(ty) < 4 {
+					continue
+				}
+				m.Rows, ty = ty[3], types[ty[2]]
+			}
+			if m.Kind = scalarKind(ty); m.Kind == UnknownScalar {
+				continue
+			}
+			out = append(out, m)
+		}
+		return out
+	}
+
+	out := []UniformBlock{}
+	for _, v := range variables {
+		ty := types[v.ty]
+		if len(ty) < 4 || ty[0]&0xffff != opTypePointer {
+			continue
+		}
+		ty = types[ty[3]]
+		if len(ty) < 2 || ty[0]&0xffff != opTypeStruct {
+			continue // Arrays of blocks and non-block variables.
+		}
+		block := UniformBlock{Name: names[v.id]}
+		switch {
+		case v.storage == storagePushConstant:
+			block.PushConstant = true
+		case v.storage == storageUniform && blocks[ty[1]] == decorationBlock:
+			block.Set, block.Binding = sets[v.id], bindings[v.id]
+		default:
+			continue
+		}
+		block.Members = flatten(ty[1], "", 0, nil)
+		out = append(out, block)
+	}
+	return out, nil
+}
+
+// Stage is the execution model of a SPIR-V entry point.
+type Stage int
+
+const (
+	VertexStage Stage = iota
+	TessControlStage
+	TessEvaluationStage
+	GeometryStage
+	FragmentStage
+	ComputeStage
+)
+
+// InterfaceVariable is an input or output variable of the entry points of a
+// SPIR-V module, with a Location decoration. Built-in variables are not
+// interface variables.
+type InterfaceVariable struct {
+	Name             string  // Optional symbol name of the variable.
+	Location         uint32  // The Location decoration.
+	Output           bool    // Whether this is an output rather than an input.
+	Float            bool    // Whether this is a 32-bit float scalar or vector.
+	RelaxedPrecision bool    // Whether the variable is decorated RelaxedPrecision.
+	Used             bool    // Whether the variable is referenced by any code.
+	Stages           []Stage // The stages of the entry points declaring it.
+}
+
+// InterfaceVariables returns the input and output variables of the entry
+// points of the SPIR-V module words, in declaration order.
+func InterfaceVariables(words []uint32) ([]InterfaceVariable, error) {
+	if len(words) < 5 || words[0] != spirvMagic {
+		return nil, fmt.Errorf("Not a SPIR-V module")
+	}
+
+	type variable struct {
+		id, ty, storage uint32
+	}
+	names := map[uint32]string{}
+	locations := map[uint32]uint32{}
+	relaxed := map[uint32]bool{}
+	stages := map[uint32][]Stage{}
+	types := map[uint32][]uint32{}
+	used := map[uint32]bool{}
+	variables := []variable{}
+	declared := map[uint32]bool{}
+
+	for i := 5; i < len(words); {
+		count, opcode := int(words[i]>>16), words[i]&0xffff
+		if count == 0 || i+count > len(words) {
+			return nil, fmt.Errorf("Invalid SPIR-V instruction at word %d", i)
+		}
+		inst := words[i : i+count]
+		i += count
+		switch opcode {
+		case opName:
+			if len(inst) > 2 {
+				names[inst[1]] = spirvString(inst[2:])
+			}
+		case opEntryPoint:
+			if len(inst) < 4 {
+				continue
+			}
+			// The interface ids follow the nul-terminated name.
+			j := 3
+			for ; j < len(inst); j++ {
+				if w := inst[j]; w&0xff == 0 || w&0xff00 == 0 || w&0xff0000 == 0 || w&0xff000000 == 0 {
+					j++
+					break
+				}
+			}
+			for _, id := range inst[j:] {
+				stages[id] = append(stages[id], Stage(inst[1]))
+			}
+		case opDecorate:
+			if len(inst) < 3 {
+				continue
+			}
+			switch inst[2] {
+			case decorationLocation:
+				if len(inst) > 3 {
+					locations[inst[1]] = inst[3]
+				}
+			case decorationRelaxedPrecision:
+				relaxed[inst[1]] = true
+			}
+		case opMemberDecorate:
+		case opTypeFloat, opTypeVector, opTypePointer:
+			if len(inst) > 1 {
+				types[inst[1]] = inst
+			}
+		case opVariable:
+			if len(inst) > 3 {
+				variables = append(variables, variable{id: inst[2], ty: inst[1], storage: inst[3]})
+				declared[inst[2]] = true
+			}
+		default:
+			// Any other instruction referencing a declared variable uses it.
+			for _, w := range inst[1:] {
+				if declared[w] {
+					used[w] = true
+				}
+			}
+		}
+	}
+
+	isFloat := func(ty []uint32) bool {
+		if len(ty) > 3 && ty[0]&0xffff == opTypeVector {
+			ty = types[ty[2]]
+		}
+		return len(ty) > 2 && ty[0]&0xffff == opTypeFloat && ty[2] == 32
+	}
+
+	out := []InterfaceVariable{}
+	for _, v := range variables {
+		location, ok := locations[v.id]
+		if !ok || (v.storage != storageInput && v.storage != storageOutput) {
+			continue
+		}
+		ty := types[v.ty]
+		if len(ty) < 4 || ty[0]&0xffff != opTypePointer {
+			return nil, fmt.Errorf("Variable %%%d does not have a pointer type", v.id)
+		}
+		out = append(out, InterfaceVariable{
+			Name:             names[v.id],
+			Location:         location,
+			Output:           v.storage == storageOutput,
+			Float:            isFloat(types[ty[3]]),
+			RelaxedPrecision: relaxed[v.id],
+			Used:             used[v.id],
+			Stages:           stages[v.id],
+		})
+	}
+	return out, nil
+}
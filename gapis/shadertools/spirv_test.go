@@ -0,0 +1,126 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadertools_test
+
+import (
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+func TestDescriptorBindings(t *testing.T) {
+	ctx := log.Testing(t)
+	words := []uint32{
+		0x07230203, 0x00010000, 0, 20, 0,
+		3<<16 | 5, 5, 'u' | 'b'<<8 | 'o'<<16, // OpName %5 "ubo"
+		4<<16 | 71, 5, 34, 1, // OpDecorate %5 DescriptorSet 1
+		4<<16 | 71, 5, 33, 2, // OpDecorate %5 Binding 2
+		3<<16 | 71, 3, 2, // OpDecorate %3 Block
+		4<<16 | 71, 11, 34, 0, // OpDecorate %11 DescriptorSet 0
+		4<<16 | 71, 11, 33, 0, // OpDecorate %11 Binding 0
+		2<<16 | 30, 3, // %3 = OpTypeStruct
+		4<<16 | 32, 4, 2, 3, // %4 = OpTypePointer Uniform %3
+		4<<16 | 59, 4, 5, 2, // %5 = OpVariable %4 Uniform
+		2<<16 | 26, 6, // %6 = OpTypeSampler
+		4<<16 | 43, 7, 8, 4, // %8 = OpConstant %7 4
+		4<<16 | 28, 9, 6, 8, // %9 = OpTypeArray %6 %8
+		4<<16 | 32, 10, 0, 9, // %10 = OpTypePointer UniformConstant %9
+		4<<16 | 59, 10, 11, 0, // %11 = OpVariable %10 UniformConstant
+		4<<16 | 61, 3, 12, 5, // %12 = OpLoad %3 %5
+	}
+	bindings, err := shadertools.DescriptorBindings(words)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	assert.With(ctx).ThatSlice(bindings).Equals([]shadertools.DescriptorBinding{
+		{Name: "ubo", Set: 1, Binding: 2, Type: shadertools.UniformBuffer, Count: 1, Used: true},
+		{Set: 0, Binding: 0, Type: shadertools.Sampler, Count: 4},
+	})
+
+	_, err = shadertools.DescriptorBindings([]uint32{1, 2, 3})
+	assert.With(ctx).ThatError(err).Failed()
+}
+
+func TestUniformBlocks(t *testing.T) {
+	ctx := log.Testing(t)
+	words := []uint32{
+		0x07230203, 0x00010000, 0, 20, 0,
+		3<<16 | 5, 10, 'p' | 'c'<<8, // OpName %10 "pc"
+		5<<16 | 6, 3, 0, 'c' | 'o'<<8 | 'l'<<16 | 'o'<<24, 'r', // OpMemberName %3 0 "color"
+		4<<16 | 6, 3, 1, 'm', // OpMemberName %3 1 "m"
+		4<<16 | 6, 3, 2, 'f', // OpMemberName %3 2 "f"
+		5<<16 | 72, 3, 0, 35, 0, // OpMemberDecorate %3 0 Offset 0
+		5<<16 | 72, 3, 1, 35, 16, // OpMemberDecorate %3 1 Offset 16
+		5<<16 | 72, 3, 1, 7, 16, // OpMemberDecorate %3 1 MatrixStride 16
+		5<<16 | 72, 3, 2, 35, 48, // OpMemberDecorate %3 2 Offset 48
+		4<<16 | 71, 8, 6, 16, // OpDecorate %8 ArrayStride 16
+		3<<16 | 71, 3, 2, // OpDecorate %3 Block
+		3<<16 | 22, 4, 32, // %4 = OpTypeFloat 32
+		4<<16 | 23, 5, 4, 4, // %5 = OpTypeVector %4 4
+		4<<16 | 23, 6, 4, 2, // %6 = OpTypeVector %4 2
+		4<<16 | 24, 7, 6, 2, // %7 = OpTypeMatrix %6 2
+		4<<16 | 21, 11, 32, 0, // %11 = OpTypeInt 32 0
+		4<<16 | 43, 11, 12, 2, // %12 = OpConstant %11 2
+		4<<16 | 28, 8, 4, 12, // %8 = OpTypeArray %4 %12
+		5<<16 | 30, 3, 5, 7, 8, // %3 = OpTypeStruct %5 %7 %8
+		4<<16 | 32, 9, 9, 3, // %9 = OpTypePointer PushConstant %3
+		4<<16 | 59, 9, 10, 9, // %10 = OpVariable %9 PushConstant
+	}
+	blocks, err := shadertools.UniformBlocks(words)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	members := []shadertools.BlockMember{
+		{Name: "color", Offset: 0, Kind: shadertools.Float, Rows: 4, Columns: 1, Count: 1},
+		{Name: "m", Offset: 16, Kind: shadertools.Float, Rows: 2, Columns: 2, Count: 1, MatrixStride: 16},
+		{Name: "f", Offset: 48, Kind: shadertools.Float, Rows: 1, Columns: 1, Count: 2, ArrayStride: 16},
+	}
+	assert.With(ctx).ThatSlice(blocks).Equals([]shadertools.UniformBlock{
+		{Name: "pc", PushConstant: true, Members: members},
+	})
+	assert.With(ctx).ThatSlice(members[1].ScalarOffsets()).Equals([]uint32{16, 20, 32, 36})
+	assert.With(ctx).ThatSlice(members[2].ScalarOffsets()).Equals([]uint32{48, 64})
+}
+
+func TestInterfaceVariables(t *testing.T) {
+	ctx := log.Testing(t)
+	words := []uint32{
+		0x07230203, 0x00010000, 0, 20, 0,
+		7<<16 | 15, 0, 1, 'm' | 'a'<<8 | 'i'<<16 | 'n'<<24, 0, 5, 8, // OpEntryPoint Vertex %1 "main" %5 %8
+		3<<16 | 5, 5, 'p' | 'o'<<8 | 's'<<16, // OpName %5 "pos"
+		4<<16 | 71, 5, 30, 0, // OpDecorate %5 Location 0
+		4<<16 | 71, 8, 30, 1, // OpDecorate %8 Location 1
+		3<<16 | 71, 8, 0, // OpDecorate %8 RelaxedPrecision
+		3<<16 | 22, 2, 32, // %2 = OpTypeFloat 32
+		4<<16 | 23, 3, 2, 4, // %3 = OpTypeVector %2 4
+		4<<16 | 32, 4, 1, 3, // %4 = OpTypePointer Input %3
+		4<<16 | 59, 4, 5, 1, // %5 = OpVariable %4 Input
+		4<<16 | 21, 6, 32, 1, // %6 = OpTypeInt 32 1
+		4<<16 | 32, 7, 3, 6, // %7 = OpTypePointer Output %6
+		4<<16 | 59, 7, 8, 3, // %8 = OpVariable %7 Output
+		4<<16 | 61, 3, 9, 5, // %9 = OpLoad %3 %5
+	}
+	variables, err := shadertools.InterfaceVariables(words)
+	if !assert.With(ctx).ThatError(err).Succeeded() {
+		return
+	}
+	vertex := []shadertools.Stage{shadertools.VertexStage}
+	assert.With(ctx).ThatSlice(variables).DeepEquals([]shadertools.InterfaceVariable{
+		{Name: "pos", Location: 0, Float: true, Used: true, Stages: vertex},
+		{Location: 1, Output: true, RelaxedPrecision: true, Stages: vertex},
+	})
+}
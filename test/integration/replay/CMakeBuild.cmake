@@ -13,5 +13,6 @@
 # limitations under the License.
 
 build_subdirectory(gles)
+build_subdirectory(vulkan)
 
 go_package()
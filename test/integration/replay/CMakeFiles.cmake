@@ -23,4 +23,5 @@ set(files
 )
 set(dirs
     gles
+    vulkan
 )
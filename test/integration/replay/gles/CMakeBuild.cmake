@@ -19,6 +19,7 @@ go_package()
 embed(
     OUTPUT "reference_embed.go"
     reference/one-depth.png
+    reference/scissored-square.png
     reference/solid-black.png
     reference/solid-blue.png
     reference/solid-green.png
@@ -27,5 +28,6 @@ embed(
     reference/triangle.png
     reference/triangle-180.png
     reference/triangle-depth.png
+    reference/triangle-overdraw.png
     reference/triangle_2.png
 )
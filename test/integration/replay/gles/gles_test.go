@@ -35,6 +35,7 @@ import (
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/os/device"
 	"github.com/google/gapid/core/os/device/bind"
+	gapir "github.com/google/gapid/gapir/client"
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/capture"
 	"github.com/google/gapid/gapis/database"
@@ -170,7 +171,7 @@ func (f *Fixture) p() memory.Pointer {
 func newFixture(ctx context.Context) (context.Context, *Fixture) {
 	r := bind.NewRegistry()
 	ctx = bind.PutRegistry(ctx, r)
-	m := replay.New(ctx)
+	m := replay.New(ctx, gapir.Options{})
 	ctx = replay.PutManager(ctx, m)
 	ctx = database.Put(ctx, database.NewInMemory(ctx))
 	bind.GetRegistry(ctx).AddDevice(ctx, bind.Host(ctx))
@@ -436,6 +437,28 @@ func generateDrawTexturedSquareCaptureWithSharedContext(f *Fixture) (*path.Captu
 	return storeCapture(ctx, atoms), verifyTrace
 }
 
+func generateBlitMultisampledFramebufferCapture(f *Fixture) (*path.Capture, traceVerifier) {
+	ctx := f.ctx
+	atoms, clear, blit, _ := samples.BlitMultisampledFramebuffer(ctx)
+
+	verifyTrace := func(ctx context.Context, cap *path.Capture, mgr *replay.Manager, dev bind.Device) {
+		intent := replay.Intent{
+			Capture: cap,
+			Device:  path.NewDevice(dev.Instance().Id.ID()),
+		}
+		defer checkReplay(ctx, intent, 1)() // expect a single replay batch.
+
+		done := &sync.WaitGroup{}
+		done.Add(2)
+		// Reading the multisampled framebuffer requires it to be resolved.
+		go checkColorBuffer(ctx, intent, mgr, 64, 64, 0.0, "scissored-square", clear, done)
+		go checkColorBuffer(ctx, intent, mgr, 64, 64, 0.0, "scissored-square", blit, done)
+		done.Wait()
+	}
+
+	return storeCapture(ctx, atoms), verifyTrace
+}
+
 func generateCaptureWithIssues(f *Fixture) (*path.Capture, traceVerifier) {
 	ctx := f.ctx
 	vs, fs, prog, pos := gles.ShaderId(f.newID()), gles.ShaderId(f.newID()), gles.ProgramId(f.newID()), gles.AttributeLocation(0)
@@ -603,6 +626,10 @@ func TestDrawTexturedSquareWithSharedContext(t *testing.T) {
 		generateDrawTexturedSquareCaptureWithSharedContext)
 }
 
+func TestBlitMultisampledFramebuffer(t *testing.T) {
+	testTrace(t, "blit_multisampled_framebuffer", generateBlitMultisampledFramebufferCapture)
+}
+
 func TestDrawTriangle(t *testing.T) {
 	testTrace(t, "draw_triangle", generateDrawTriangleCapture)
 }
@@ -673,6 +700,59 @@ func TestResizeRenderer(t *testing.T) {
 	maybeExportCapture(ctx, "resize_renderer", capture)
 }
 
+// TestOverdraw checks the overdraw heatmap of a backbuffer captured without a
+// stencil buffer, and that framebuffer objects without one report an error.
+func TestOverdraw(t *testing.T) {
+	ctx, f := newFixture(log.Testing(t))
+
+	triangleVerticesR := atom.Must(atom.AllocData(ctx, f.s, triangleVertices))
+	renderbuffer, framebuffer := gles.RenderbufferId(f.newID()), gles.FramebufferId(f.newID())
+	renderbuffersR := atom.Must(atom.AllocData(ctx, f.s, []gles.RenderbufferId{renderbuffer}))
+	framebuffersR := atom.Must(atom.AllocData(ctx, f.s, []gles.FramebufferId{framebuffer}))
+
+	vs, fs, prog, pos := gles.ShaderId(f.newID()), gles.ShaderId(f.newID()), gles.ProgramId(f.newID()), gles.AttributeLocation(0)
+	atoms, _, _ := initContext(f, 64, 64, false)
+	gles.FindDynamicContextState(atoms.Atoms[len(atoms.Atoms)-1].Extras()).BackbufferStencilFmt = gles.GLenum_GL_NONE
+	atoms.Add(gles.BuildProgram(ctx, f.s, vs, fs, prog, simpleVSSource, simpleFSSource(1.0, 0.0, 0.0))...)
+	triangle := atoms.Add(
+		gles.NewGlLinkProgram(prog),
+		gles.NewGlUseProgram(prog),
+		gles.NewGlGetAttribLocation(prog, "position", gles.GLint(pos)),
+		gles.NewGlEnableVertexAttribArray(pos),
+		gles.NewGlVertexAttribPointer(pos, 3, gles.GLenum_GL_FLOAT, gles.GLboolean(0), 0, triangleVerticesR.Ptr()),
+		gles.NewGlClearColor(0.0, 1.0, 0.0, 1.0),
+		gles.NewGlClear(gles.GLbitfield_GL_COLOR_BUFFER_BIT),
+		gles.NewGlDrawArrays(gles.GLenum_GL_TRIANGLES, 0, 3).AddRead(triangleVerticesR.Data()),
+	)
+	offscreen := atoms.Add(
+		gles.NewGlGenRenderbuffers(1, renderbuffersR.Ptr()).AddWrite(renderbuffersR.Data()),
+		gles.NewGlBindRenderbuffer(gles.GLenum_GL_RENDERBUFFER, renderbuffer),
+		gles.NewGlRenderbufferStorage(gles.GLenum_GL_RENDERBUFFER, gles.GLenum_GL_RGBA4, 64, 64),
+		gles.NewGlGenFramebuffers(1, framebuffersR.Ptr()).AddWrite(framebuffersR.Data()),
+		gles.NewGlBindFramebuffer(gles.GLenum_GL_FRAMEBUFFER, framebuffer),
+		gles.NewGlFramebufferRenderbuffer(gles.GLenum_GL_FRAMEBUFFER, gles.GLenum_GL_COLOR_ATTACHMENT0, gles.GLenum_GL_RENDERBUFFER, renderbuffer),
+		gles.NewGlClear(gles.GLbitfield_GL_COLOR_BUFFER_BIT),
+		gles.NewGlDrawArrays(gles.GLenum_GL_TRIANGLES, 0, 3).AddRead(triangleVerticesR.Data()),
+	)
+	intent := replay.Intent{
+		Capture: storeCapture(ctx, atoms),
+		Device:  path.NewDevice(f.device.Instance().Id.ID()),
+	}
+
+	ctx, _ = task.WithTimeout(ctx, replayTimeout)
+	query := gles.API().(replay.QueryFramebufferAttachment)
+	img, err := query.QueryFramebufferAttachment(
+		ctx, intent, f.mgr, triangle, 64, 64, gfxapi.FramebufferAttachment_Color0, replay.WireframeMode_Overdraw, nil)
+	if assert.For(ctx, "Backbuffer").ThatError(err).Succeeded() {
+		checkImage(ctx, "triangle-overdraw", img, 0.01)
+	}
+	_, err = query.QueryFramebufferAttachment(
+		ctx, intent, f.mgr, offscreen, 64, 64, gfxapi.FramebufferAttachment_Color0, replay.WireframeMode_Overdraw, nil)
+	assert.For(ctx, "Framebuffer without stencil").ThatError(err).Failed()
+
+	maybeExportCapture(ctx, "overdraw", intent.Capture)
+}
+
 // TestPreserveBuffersOnSwap checks that when the preserveBuffersOnSwap flag is
 // set, the backbuffer is preserved between calls to eglSwapBuffers().
 func TestPreserveBuffersOnSwap(t *testing.T) {
@@ -18,8 +18,11 @@
 # build and the file will be recreated, check in the new version.
 
 set(files
+    blit_multisampled_framebuffer.go
     builder.go
+    capture_transform_feedback.go
     clear_backbuffer.go
+    draw_instanced.go
     draw_textured_square.go
     samples.go
 )
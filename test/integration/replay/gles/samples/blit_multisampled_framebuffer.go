@@ -0,0 +1,71 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/gles"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// BlitMultisampledFramebuffer returns the atom list needed to create a context
+// then clear a 4x multisampled renderbuffer to blue with a red square in its
+// middle, and resolve it to the backbuffer with glBlitFramebuffer.
+// clear is the last atom drawing to the multisampled framebuffer, which is
+// still bound at that point.
+func BlitMultisampledFramebuffer(ctx context.Context) (atoms *atom.List, clear, blit, swap atom.ID) {
+	b := newBuilder(ctx)
+	_, eglSurface, eglDisplay := b.newEglContext(64, 64, memory.Nullptr, false)
+
+	framebufferNames := []gles.FramebufferId{1}
+	framebufferNamesPtr := b.data(ctx, framebufferNames)
+	renderbufferNames := []gles.RenderbufferId{1}
+	renderbufferNamesPtr := b.data(ctx, renderbufferNames)
+
+	// Build the multisampled framebuffer
+	b.Add(
+		gles.NewGlGenRenderbuffers(1, renderbufferNamesPtr.Ptr()).AddWrite(renderbufferNamesPtr.Data()),
+		gles.NewGlBindRenderbuffer(gles.GLenum_GL_RENDERBUFFER, renderbufferNames[0]),
+		gles.NewGlRenderbufferStorageMultisample(gles.GLenum_GL_RENDERBUFFER, 4, gles.GLenum_GL_RGBA8, 64, 64),
+		gles.NewGlGenFramebuffers(1, framebufferNamesPtr.Ptr()).AddWrite(framebufferNamesPtr.Data()),
+		gles.NewGlBindFramebuffer(gles.GLenum_GL_FRAMEBUFFER, framebufferNames[0]),
+		gles.NewGlFramebufferRenderbuffer(gles.GLenum_GL_FRAMEBUFFER, gles.GLenum_GL_COLOR_ATTACHMENT0, gles.GLenum_GL_RENDERBUFFER, renderbufferNames[0]),
+	)
+
+	// Draw to the multisampled framebuffer
+	clear = b.Add(
+		gles.NewGlClearColor(0.0, 0.0, 1.0, 1.0),
+		gles.NewGlClear(gles.GLbitfield_GL_COLOR_BUFFER_BIT),
+		gles.NewGlEnable(gles.GLenum_GL_SCISSOR_TEST),
+		gles.NewGlScissor(16, 16, 32, 32),
+		gles.NewGlClearColor(1.0, 0.0, 0.0, 1.0),
+		gles.NewGlClear(gles.GLbitfield_GL_COLOR_BUFFER_BIT),
+	)
+
+	// Resolve it to the backbuffer
+	blit = b.Add(
+		gles.NewGlDisable(gles.GLenum_GL_SCISSOR_TEST),
+		gles.NewGlBindFramebuffer(gles.GLenum_GL_READ_FRAMEBUFFER, framebufferNames[0]),
+		gles.NewGlBindFramebuffer(gles.GLenum_GL_DRAW_FRAMEBUFFER, 0),
+		gles.NewGlBlitFramebuffer(0, 0, 64, 64, 0, 0, 64, 64, gles.GLbitfield_GL_COLOR_BUFFER_BIT, gles.GLenum_GL_NEAREST),
+	)
+	swap = b.Add(
+		gles.NewEglSwapBuffers(eglDisplay, eglSurface, gles.EGLBoolean(1)),
+	)
+
+	return &b.List, clear, blit, swap
+}
@@ -42,6 +42,10 @@ func (b *builder) newID() uint {
 
 func (b *builder) newShaderID() gles.ShaderId   { return gles.ShaderId(b.newID()) }
 func (b *builder) newProgramID() gles.ProgramId { return gles.ProgramId(b.newID()) }
+func (b *builder) newBufferID() gles.BufferId   { return gles.BufferId(b.newID()) }
+func (b *builder) newTransformFeedbackID() gles.TransformFeedbackId {
+	return gles.TransformFeedbackId(b.newID())
+}
 
 // p returns a unique pointer. Meant to be used to generate
 // pointers representing driver-side data, so the allocation
@@ -93,3 +97,68 @@ func (b *builder) program(ctx context.Context,
 		programID,
 		vertexShaderSource, fragmentShaderSource)...)
 }
+
+// buffer generates a new buffer, binds it to target and fills it with the
+// encoded v.
+func (b *builder) buffer(ctx context.Context, target gles.GLenum, v interface{}, usage gles.GLenum) gles.BufferId {
+	id := b.newBufferID()
+	names := b.data(ctx, id)
+	data := b.data(ctx, v)
+	b.Add(
+		gles.NewGlGenBuffers(1, names.Ptr()).AddWrite(names.Data()),
+		gles.NewGlBindBuffer(target, id),
+		gles.NewGlBufferData(target, gles.GLsizeiptr(data.Range().Size), data.Ptr(), usage).AddRead(data.Data()),
+	)
+	return id
+}
+
+// transformFeedback generates a new transform feedback object and binds it.
+func (b *builder) transformFeedback(ctx context.Context) gles.TransformFeedbackId {
+	id := b.newTransformFeedbackID()
+	names := b.data(ctx, id)
+	b.Add(
+		gles.NewGlGenTransformFeedbacks(1, names.Ptr()).AddWrite(names.Data()),
+		gles.NewGlBindTransformFeedback(gles.GLenum_GL_TRANSFORM_FEEDBACK, id),
+	)
+	return id
+}
+
+// transformFeedbackVaryings sets the varyings of the program captured,
+// interleaved, by transform feedback. It must be called before linking.
+func (b *builder) transformFeedbackVaryings(ctx context.Context, programID gles.ProgramId, varyings ...string) {
+	names := make([]atom.AllocResult, len(varyings))
+	ptrs := make([]memory.Pointer, len(varyings))
+	for i, v := range varyings {
+		names[i] = b.data(ctx, v)
+		ptrs[i] = names[i].Ptr()
+	}
+	ptrsData := b.data(ctx, ptrs)
+	a := gles.NewGlTransformFeedbackVaryings(programID, gles.GLsizei(len(varyings)), ptrsData.Ptr(), gles.GLenum_GL_INTERLEAVED_ATTRIBS).
+		AddRead(ptrsData.Data())
+	for _, n := range names {
+		a.AddRead(n.Data())
+	}
+	b.Add(a)
+}
+
+// linkProgram links the program after binding the given vec2 attributes to
+// consecutive locations, starting at 0.
+func (b *builder) linkProgram(programID gles.ProgramId, attributes ...string) {
+	active := gles.AttributeIndexːActiveAttributeᵐ{}
+	for i, name := range attributes {
+		location := gles.AttributeLocation(i)
+		b.Add(gles.NewGlBindAttribLocation(programID, location, name))
+		active[gles.AttributeIndex(i)] = gles.ActiveAttribute{
+			Type:      gles.GLenum_GL_FLOAT_VEC2,
+			Name:      name,
+			ArraySize: 1,
+			Location:  location,
+		}
+	}
+	b.Add(atom.WithExtras(
+		gles.NewGlLinkProgram(programID),
+		&gles.ProgramInfo{
+			LinkStatus:       gles.GLboolean_GL_TRUE,
+			ActiveAttributes: active,
+		}))
+}
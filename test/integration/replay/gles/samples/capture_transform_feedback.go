@@ -0,0 +1,85 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/gles"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// CaptureTransformFeedback returns the atom list needed to create a context
+// then capture, with rasterization discarded, the doubled positions of four
+// points into a transform feedback buffer.
+func CaptureTransformFeedback(ctx context.Context) (atoms *atom.List, draw, end atom.ID) {
+	pointVertices := []float32{
+		-0.5, -0.5,
+		-0.5, +0.5,
+		+0.5, +0.5,
+		+0.5, -0.5,
+	}
+
+	feedbackVSSource := `
+		#version 300 es
+		precision mediump float;
+		in vec2 position;
+		out vec2 doubled;
+		void main() {
+			doubled = position * 2.0;
+			gl_Position = vec4(position, 0.5, 1.0);
+		}`
+
+	feedbackFSSource := `
+		#version 300 es
+		precision mediump float;
+		out vec4 color;
+		void main() {
+			color = vec4(1.0, 1.0, 1.0, 1.0);
+		}`
+
+	b := newBuilder(ctx)
+	vs, fs, prog, pos := b.newShaderID(), b.newShaderID(), b.newProgramID(), gles.AttributeLocation(0)
+	b.newEglContext(64, 64, memory.Nullptr, false)
+
+	// Build the program, declaring the captured varying before linking
+	b.program(ctx, vs, fs, prog, feedbackVSSource, feedbackFSSource)
+	b.transformFeedbackVaryings(ctx, prog, "doubled")
+	b.linkProgram(prog, "position")
+
+	// Build the input vertex stream and the buffer capturing the output
+	vertices := b.buffer(ctx, gles.GLenum_GL_ARRAY_BUFFER, pointVertices, gles.GLenum_GL_STATIC_DRAW)
+	captured := b.buffer(ctx, gles.GLenum_GL_TRANSFORM_FEEDBACK_BUFFER, make([]float32, len(pointVertices)), gles.GLenum_GL_DYNAMIC_READ)
+	b.transformFeedback(ctx)
+	b.Add(gles.NewGlBindBufferBase(gles.GLenum_GL_TRANSFORM_FEEDBACK_BUFFER, 0, captured))
+
+	// Capture the points
+	draw = b.Add(
+		gles.NewGlUseProgram(prog),
+		gles.NewGlBindBuffer(gles.GLenum_GL_ARRAY_BUFFER, vertices),
+		gles.NewGlEnableVertexAttribArray(pos),
+		gles.NewGlVertexAttribPointer(pos, 2, gles.GLenum_GL_FLOAT, gles.GLboolean(0), 0, memory.Nullptr),
+		gles.NewGlEnable(gles.GLenum_GL_RASTERIZER_DISCARD),
+		gles.NewGlBeginTransformFeedback(gles.GLenum_GL_POINTS),
+		gles.NewGlDrawArrays(gles.GLenum_GL_POINTS, 0, 4),
+	)
+	end = b.Add(
+		gles.NewGlEndTransformFeedback(),
+		gles.NewGlDisable(gles.GLenum_GL_RASTERIZER_DISCARD),
+	)
+
+	return &b.List, draw, end
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/gles"
+	"github.com/google/gapid/gapis/memory"
+)
+
+// DrawInstanced returns the atom list needed to create a context then draw
+// four instances of a triangle, each offset to a different quadrant by a
+// per-instance attribute.
+func DrawInstanced(ctx context.Context) (atoms *atom.List, draw, swap atom.ID) {
+	triangleVertices := []float32{
+		+0.0, -0.25,
+		-0.25, +0.25,
+		+0.25, +0.25,
+	}
+
+	instanceOffsets := []float32{
+		-0.5, -0.5,
+		-0.5, +0.5,
+		+0.5, +0.5,
+		+0.5, -0.5,
+	}
+
+	instancedVSSource := `
+		#version 300 es
+		precision mediump float;
+		in vec2 position;
+		in vec2 offset;
+		void main() {
+			gl_Position = vec4(position + offset, 0.5, 1.0);
+		}`
+
+	instancedFSSource := `
+		#version 300 es
+		precision mediump float;
+		out vec4 color;
+		void main() {
+			color = vec4(1.0, 0.0, 0.0, 1.0);
+		}`
+
+	b := newBuilder(ctx)
+	vs, fs, prog := b.newShaderID(), b.newShaderID(), b.newProgramID()
+	pos, offset := gles.AttributeLocation(0), gles.AttributeLocation(1)
+	_, eglSurface, eglDisplay := b.newEglContext(64, 64, memory.Nullptr, false)
+
+	// Build the program and the vertex streams
+	b.program(ctx, vs, fs, prog, instancedVSSource, instancedFSSource)
+	b.linkProgram(prog, "position", "offset")
+	vertices := b.buffer(ctx, gles.GLenum_GL_ARRAY_BUFFER, triangleVertices, gles.GLenum_GL_STATIC_DRAW)
+	offsets := b.buffer(ctx, gles.GLenum_GL_ARRAY_BUFFER, instanceOffsets, gles.GLenum_GL_STATIC_DRAW)
+
+	// Render the instances, advancing the offset once per instance
+	draw = b.Add(
+		gles.NewGlClearColor(0.0, 0.0, 0.0, 1.0),
+		gles.NewGlClear(gles.GLbitfield_GL_COLOR_BUFFER_BIT),
+		gles.NewGlUseProgram(prog),
+		gles.NewGlBindBuffer(gles.GLenum_GL_ARRAY_BUFFER, vertices),
+		gles.NewGlEnableVertexAttribArray(pos),
+		gles.NewGlVertexAttribPointer(pos, 2, gles.GLenum_GL_FLOAT, gles.GLboolean(0), 0, memory.Nullptr),
+		gles.NewGlBindBuffer(gles.GLenum_GL_ARRAY_BUFFER, offsets),
+		gles.NewGlEnableVertexAttribArray(offset),
+		gles.NewGlVertexAttribPointer(offset, 2, gles.GLenum_GL_FLOAT, gles.GLboolean(0), 0, memory.Nullptr),
+		gles.NewGlVertexAttribDivisor(offset, 1),
+		gles.NewGlDrawArraysInstanced(gles.GLenum_GL_TRIANGLES, 0, 3, 4),
+	)
+	swap = b.Add(
+		gles.NewEglSwapBuffers(eglDisplay, eglSurface, gles.EGLBoolean(1)),
+	)
+
+	return &b.List, draw, swap
+}
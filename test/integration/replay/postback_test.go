@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/google/gapid/core/app"
+	"github.com/google/gapid/core/data/id"
 	"github.com/google/gapid/core/data/pod"
 	"github.com/google/gapid/core/event/task"
 	"github.com/google/gapid/core/log"
@@ -49,9 +50,9 @@ func doReplay(t *testing.T, f func(*builder.Builder)) {
 	ctx = database.Put(ctx, database.NewInMemory(ctx))
 
 	device := bind.Host(ctx)
-	client := gapir.New(ctx)
+	client := gapir.New(ctx, gapir.Options{})
 	abi := device.Instance().GetConfiguration().PreferredABI(nil)
-	connection, err := client.Connect(ctx, device, abi)
+	connection, err := client.Connect(ctx, device, abi, id.ID{})
 	if err != nil {
 		t.Errorf("Failed to connect to '%v': %v", device, err)
 		return
@@ -66,7 +67,7 @@ func doReplay(t *testing.T, f func(*builder.Builder)) {
 		t.Errorf("Build failed with error: %v", err)
 	}
 
-	err = executor.Execute(ctx, payload, decoder, connection, abi.MemoryLayout)
+	err = executor.Execute(ctx, payload, decoder, connection, abi.MemoryLayout, false, nil)
 	if err != nil {
 		t.Errorf("Executor failed with error: %v", err)
 	}
@@ -0,0 +1,141 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/framework/binary"
+	"github.com/google/gapid/framework/binary/cyclic"
+	"github.com/google/gapid/framework/binary/vle"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/resolve"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+)
+
+// TestStateCheckpoints checks that the states after the commands of a capture
+// long enough to be checkpointed, which are mutated from the closest state
+// checkpoint, equal the states mutated from the first command.
+func TestStateCheckpoints(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	// Each frame updates the buffer with new data and presents.
+	b := samples.NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+	swapchain := b.Swapchain(ctx, device, 16, 16)
+	buffer := b.Buffer(ctx, device, 16, vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT)
+	for frame := uint32(0); len(b.Atoms) < 1200; frame++ {
+		commandBuffer := b.BeginCommandBuffer(ctx, device, pool)
+		b.UpdateBuffer(ctx, commandBuffer, buffer, [4]uint32{frame, frame + 1, frame + 2, frame + 3})
+		b.Submit(ctx, queue, commandBuffer)
+		b.Present(ctx, queue, swapchain)
+	}
+
+	c, err := capture.ImportAtomList(ctx, "checkpoints", &b.List)
+	if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+		return
+	}
+	ctx = capture.Put(ctx, c)
+	checkpoints, err := database.Build(ctx, &resolve.StateCheckpointsResolvable{Capture: c})
+	if !assert.For(ctx, "Checkpoints").ThatError(err).Succeeded() {
+		return
+	}
+	size := checkpoints.(*resolve.StateCheckpoints).DatabaseSize()
+	assert.For(ctx, "Checkpoints size").ThatInteger(int(size)).IsAtLeast(1)
+
+	captured, err := capture.Resolve(ctx)
+	if !assert.For(ctx, "Capture").ThatError(err).Succeeded() {
+		return
+	}
+	list, err := captured.Atoms(ctx)
+	if !assert.For(ctx, "Atoms").ThatError(err).Succeeded() {
+		return
+	}
+
+	want := capture.NewState(ctx)
+	for i, a := range list.Atoms {
+		a.Mutate(ctx, want, nil)
+		if i%100 != 99 {
+			continue
+		}
+		ctx := log.V{"id": i}.Bind(ctx)
+		got, err := resolve.GlobalState(ctx, c.Commands().Index(uint64(i)).StateAfter())
+		if assert.For(ctx, "GlobalState").ThatError(err).Succeeded() {
+			checkStatesEqual(ctx, got, want, list.Atoms[:i+1])
+		}
+	}
+}
+
+// checkStatesEqual checks that the API states and the memory of got and want
+// are equal. The application memory is compared in the ranges observed by the
+// atoms, and the other pools in their first deviceMemorySize bytes.
+func checkStatesEqual(ctx context.Context, got, want *gfxapi.State, atoms []atom.Atom) {
+	assert.For(ctx, "APIs").ThatInteger(len(got.APIs)).Equals(len(want.APIs))
+	for api, state := range want.APIs {
+		assert.For(ctx, "%v state", api.Name()).ThatSlice(encode(ctx, got.APIs[api])).Equals(encode(ctx, state))
+	}
+
+	assert.For(ctx, "NextPoolID").That(got.NextPoolID).Equals(want.NextPoolID)
+	assert.For(ctx, "Pools").ThatInteger(len(got.Memory)).Equals(len(want.Memory))
+	for _, a := range atoms {
+		o := a.Extras().Observations()
+		if o == nil {
+			continue
+		}
+		for _, obs := range append(o.Reads, o.Writes...) {
+			checkMemoryEqual(ctx, got, want, memory.ApplicationPool, obs.Range)
+		}
+	}
+	for id := range want.Memory {
+		if id != memory.ApplicationPool {
+			checkMemoryEqual(ctx, got, want, id, memory.Range{Size: deviceMemorySize})
+		}
+	}
+}
+
+// deviceMemorySize is the size of the device memories compared by
+// checkStatesEqual, which is larger than those of the capture.
+const deviceMemorySize = 4096
+
+func checkMemoryEqual(ctx context.Context, got, want *gfxapi.State, id memory.PoolID, rng memory.Range) {
+	ctx = log.V{"pool": id, "range": rng}.Bind(ctx)
+	if !assert.For(ctx, "Pool").That(got.Memory[id] != nil).Equals(true) {
+		return
+	}
+	gotData, wantData := make([]byte, rng.Size), make([]byte, rng.Size)
+	assert.For(ctx, "Get").ThatError(got.Memory[id].Slice(rng).Get(ctx, 0, gotData)).Succeeded()
+	assert.For(ctx, "Get").ThatError(want.Memory[id].Slice(rng).Get(ctx, 0, wantData)).Succeeded()
+	assert.For(ctx, "Memory").ThatSlice(gotData).Equals(wantData)
+}
+
+// encode returns the encoding of the API state, which is independent of the
+// iteration order of its maps.
+func encode(ctx context.Context, state binary.Object) []byte {
+	var b bytes.Buffer
+	e := cyclic.Encoder(vle.Writer(&b))
+	e.Variant(state)
+	assert.For(ctx, "Encode").ThatError(e.Error()).Succeeded()
+	return b.Bytes()
+}
@@ -0,0 +1,1174 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"bytes"
+	"context"
+	"math"
+
+	"github.com/google/gapid/core/data/endian"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/memory"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+const (
+	success     = vulkan.VkResult_VK_SUCCESS
+	colorFormat = vulkan.VkFormat_VK_FORMAT_R8G8B8A8_UNORM
+	colorAspect = vulkan.VkImageAspectFlags(vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_COLOR_BIT)
+	depthFormat = vulkan.VkFormat_VK_FORMAT_D24_UNORM_S8_UINT
+	depthAspect = vulkan.VkImageAspectFlags(vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_DEPTH_BIT |
+		vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_STENCIL_BIT)
+	allCommands = vulkan.VkPipelineStageFlags(vulkan.VkPipelineStageFlagBits_VK_PIPELINE_STAGE_ALL_COMMANDS_BIT)
+)
+
+// Builder builds in-memory Vulkan atom lists. The atoms are built for a single
+// queue of the first queue family of a single device, and are observed as if
+// captured.
+type Builder struct {
+	atom.List
+	state          *gfxapi.State
+	lastHandle     uint64
+	physicalDevice vulkan.VkPhysicalDevice // The physical device of the last device created.
+}
+
+// NewBuilder returns a new, empty Builder.
+func NewBuilder(ctx context.Context) *Builder {
+	return &Builder{
+		state: gfxapi.NewStateWithEmptyAllocator(),
+	}
+}
+
+// NewHandle returns a unique handle. Dispatchable and non-dispatchable
+// handles are taken from the same sequence.
+func (b *Builder) NewHandle() uint64 {
+	b.lastHandle++
+	return b.lastHandle
+}
+
+// Data allocates and writes the values v to the memory of the builder.
+func (b *Builder) Data(ctx context.Context, v ...interface{}) atom.AllocResult {
+	return atom.Must(atom.AllocData(ctx, b.state, v...))
+}
+
+// NewDevice creates an instance, a device with a single queue of the first
+// queue family and a command pool for that queue.
+func (b *Builder) NewDevice(ctx context.Context) (device vulkan.VkDevice, queue vulkan.VkQueue, pool vulkan.VkCommandPool) {
+	instance := vulkan.VkInstance(b.NewHandle())
+	physicalDevice := vulkan.VkPhysicalDevice(b.NewHandle())
+	b.physicalDevice = physicalDevice
+	device = vulkan.VkDevice(b.NewHandle())
+	queue = vulkan.VkQueue(b.NewHandle())
+	pool = vulkan.VkCommandPool(b.NewHandle())
+
+	instanceInfo := b.Data(ctx, vulkan.VkInstanceCreateInfo{
+		SType:                   vulkan.VkStructureType_VK_STRUCTURE_TYPE_INSTANCE_CREATE_INFO,
+		PNext:                   vulkan.NewVoidᶜᵖ(0),
+		PApplicationInfo:        vulkan.NewVkApplicationInfoᶜᵖ(0),
+		PpEnabledLayerNames:     vulkan.NewCharᶜᵖᶜᵖ(0),
+		PpEnabledExtensionNames: vulkan.NewCharᶜᵖᶜᵖ(0),
+	})
+	instanceData := b.Data(ctx, instance)
+	physicalDeviceCount := b.Data(ctx, uint32(1))
+	physicalDeviceData := b.Data(ctx, physicalDevice)
+	priority := b.Data(ctx, float32(1))
+	queueInfo := b.Data(ctx, vulkan.VkDeviceQueueCreateInfo{
+		SType:            vulkan.VkStructureType_VK_STRUCTURE_TYPE_DEVICE_QUEUE_CREATE_INFO,
+		PNext:            vulkan.NewVoidᶜᵖ(0),
+		QueueFamilyIndex: 0,
+		QueueCount:       1,
+		PQueuePriorities: vulkan.NewF32ᶜᵖ(priority.Address()),
+	})
+	deviceInfo := b.Data(ctx, vulkan.VkDeviceCreateInfo{
+		SType:                   vulkan.VkStructureType_VK_STRUCTURE_TYPE_DEVICE_CREATE_INFO,
+		PNext:                   vulkan.NewVoidᶜᵖ(0),
+		QueueCreateInfoCount:    1,
+		PQueueCreateInfos:       vulkan.NewVkDeviceQueueCreateInfoᶜᵖ(queueInfo.Address()),
+		PpEnabledLayerNames:     vulkan.NewCharᶜᵖᶜᵖ(0),
+		PpEnabledExtensionNames: vulkan.NewCharᶜᵖᶜᵖ(0),
+		PEnabledFeatures:        vulkan.NewVkPhysicalDeviceFeaturesᶜᵖ(0),
+	})
+	deviceData := b.Data(ctx, device)
+	queueData := b.Data(ctx, queue)
+	poolInfo := b.Data(ctx, vulkan.VkCommandPoolCreateInfo{
+		SType:            vulkan.VkStructureType_VK_STRUCTURE_TYPE_COMMAND_POOL_CREATE_INFO,
+		PNext:            vulkan.NewVoidᶜᵖ(0),
+		QueueFamilyIndex: 0,
+	})
+	poolData := b.Data(ctx, pool)
+
+	b.Add(
+		vulkan.NewVkCreateInstance(instanceInfo.Ptr(), memory.Nullptr, instanceData.Ptr(), success).
+			AddRead(instanceInfo.Data()).
+			AddWrite(instanceData.Data()),
+		vulkan.NewVkEnumeratePhysicalDevices(instance, physicalDeviceCount.Ptr(), physicalDeviceData.Ptr(), success).
+			AddRead(physicalDeviceCount.Data()).
+			AddWrite(physicalDeviceCount.Data()).
+			AddWrite(physicalDeviceData.Data()),
+		vulkan.NewVkCreateDevice(physicalDevice, deviceInfo.Ptr(), memory.Nullptr, deviceData.Ptr(), success).
+			AddRead(deviceInfo.Data()).
+			AddRead(queueInfo.Data()).
+			AddRead(priority.Data()).
+			AddWrite(deviceData.Data()),
+		vulkan.NewVkGetDeviceQueue(device, 0, 0, queueData.Ptr()).
+			AddWrite(queueData.Data()),
+		vulkan.NewVkCreateCommandPool(device, poolInfo.Ptr(), memory.Nullptr, poolData.Ptr(), success).
+			AddRead(poolInfo.Data()).
+			AddWrite(poolData.Data()),
+	)
+	return device, queue, pool
+}
+
+// QueueFamilyProperties gets the queue family properties of the physical
+// device of the last device created, which has a single family of graphics
+// queues.
+func (b *Builder) QueueFamilyProperties(ctx context.Context) {
+	count := b.Data(ctx, uint32(1))
+	properties := b.Data(ctx, vulkan.VkQueueFamilyProperties{
+		QueueFlags: vulkan.VkQueueFlags(vulkan.VkQueueFlagBits_VK_QUEUE_GRAPHICS_BIT |
+			vulkan.VkQueueFlagBits_VK_QUEUE_TRANSFER_BIT),
+		QueueCount: 1,
+	})
+	b.Add(vulkan.NewVkGetPhysicalDeviceQueueFamilyProperties(b.physicalDevice, count.Ptr(), properties.Ptr()).
+		AddRead(count.Data()).
+		AddWrite(count.Data()).
+		AddWrite(properties.Data()))
+}
+
+// MemoryProperties gets the memory properties of the physical device of the
+// last device created, which has a single memory type that is device local,
+// host visible and host coherent.
+func (b *Builder) MemoryProperties(ctx context.Context) {
+	properties := vulkan.VkPhysicalDeviceMemoryProperties{MemoryTypeCount: 1, MemoryHeapCount: 1}
+	properties.MemoryTypes.Elements[0] = vulkan.VkMemoryType{
+		PropertyFlags: vulkan.VkMemoryPropertyFlags(vulkan.VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_DEVICE_LOCAL_BIT |
+			vulkan.VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_HOST_VISIBLE_BIT |
+			vulkan.VkMemoryPropertyFlagBits_VK_MEMORY_PROPERTY_HOST_COHERENT_BIT),
+		HeapIndex: 0,
+	}
+	properties.MemoryHeaps.Elements[0] = vulkan.VkMemoryHeap{Size: 1 << 30}
+	data := b.Data(ctx, properties)
+	b.Add(vulkan.NewVkGetPhysicalDeviceMemoryProperties(b.physicalDevice, data.Ptr()).
+		AddWrite(data.Data()))
+}
+
+// DeviceMemory allocates size bytes of device memory from the first memory type.
+func (b *Builder) DeviceMemory(ctx context.Context, device vulkan.VkDevice, size vulkan.VkDeviceSize) vulkan.VkDeviceMemory {
+	mem := vulkan.VkDeviceMemory(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkMemoryAllocateInfo{
+		SType:           vulkan.VkStructureType_VK_STRUCTURE_TYPE_MEMORY_ALLOCATE_INFO,
+		PNext:           vulkan.NewVoidᶜᵖ(0),
+		AllocationSize:  size,
+		MemoryTypeIndex: 0,
+	})
+	memData := b.Data(ctx, mem)
+	b.Add(vulkan.NewVkAllocateMemory(device, info.Ptr(), memory.Nullptr, memData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(memData.Data()))
+	return mem
+}
+
+// Buffer creates a buffer of the given size and usage, bound to its own
+// device memory.
+func (b *Builder) Buffer(ctx context.Context, device vulkan.VkDevice, size vulkan.VkDeviceSize, usage vulkan.VkBufferUsageFlagBits) vulkan.VkBuffer {
+	return b.BufferIn(ctx, device, b.DeviceMemory(ctx, device, size), 0, size, usage)
+}
+
+// BufferIn creates a buffer of the given size and usage, bound at offset in
+// the device memory mem.
+func (b *Builder) BufferIn(ctx context.Context, device vulkan.VkDevice, mem vulkan.VkDeviceMemory,
+	offset, size vulkan.VkDeviceSize, usage vulkan.VkBufferUsageFlagBits) vulkan.VkBuffer {
+
+	buffer := vulkan.VkBuffer(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkBufferCreateInfo{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		Size:                size,
+		Usage:               vulkan.VkBufferUsageFlags(usage),
+		SharingMode:         vulkan.VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
+		PQueueFamilyIndices: vulkan.NewU32ᶜᵖ(0),
+	})
+	bufferData := b.Data(ctx, buffer)
+	b.Add(vulkan.NewVkCreateBuffer(device, info.Ptr(), memory.Nullptr, bufferData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(bufferData.Data()))
+	b.Add(vulkan.NewVkBindBufferMemory(device, buffer, mem, offset, success))
+	return buffer
+}
+
+// Image creates a single mip level 2D color image with the given usage,
+// bound to its own device memory.
+func (b *Builder) Image(ctx context.Context, device vulkan.VkDevice, width, height uint32, usage vulkan.VkImageUsageFlagBits) vulkan.VkImage {
+	return b.image(ctx, device, width, height, 1, colorFormat, vulkan.VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT, usage)
+}
+
+// MipmappedImage creates a 2D color image with the given number of mip
+// levels and usage, bound to its own device memory.
+func (b *Builder) MipmappedImage(ctx context.Context, device vulkan.VkDevice, width, height, levels uint32, usage vulkan.VkImageUsageFlagBits) vulkan.VkImage {
+	return b.image(ctx, device, width, height, levels, colorFormat, vulkan.VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT, usage)
+}
+
+// DepthImage creates a single mip level 2D depth and stencil image with the
+// given sample count and usage, bound to its own device memory.
+func (b *Builder) DepthImage(ctx context.Context, device vulkan.VkDevice, width, height uint32,
+	samples vulkan.VkSampleCountFlagBits, usage vulkan.VkImageUsageFlagBits) vulkan.VkImage {
+
+	return b.image(ctx, device, width, height, 1, depthFormat, samples, usage)
+}
+
+// PlanarImage creates a single mip level 2D image of the multi-planar format
+// with the given usage, bound to its own device memory. The format must have
+// at most 4 bytes per texel over all its planes.
+func (b *Builder) PlanarImage(ctx context.Context, device vulkan.VkDevice, width, height uint32,
+	format vulkan.VkFormat, usage vulkan.VkImageUsageFlagBits) vulkan.VkImage {
+
+	return b.image(ctx, device, width, height, 1, format, vulkan.VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT, usage)
+}
+
+// image creates a 2D image, with memory for 4 byte texels.
+func (b *Builder) image(ctx context.Context, device vulkan.VkDevice, width, height, levels uint32,
+	format vulkan.VkFormat, samples vulkan.VkSampleCountFlagBits, usage vulkan.VkImageUsageFlagBits) vulkan.VkImage {
+
+	image := vulkan.VkImage(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkImageCreateInfo{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_IMAGE_CREATE_INFO,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		ImageType:           vulkan.VkImageType_VK_IMAGE_TYPE_2D,
+		Format:              format,
+		Extent:              vulkan.VkExtent3D{Width: width, Height: height, Depth: 1},
+		MipLevels:           levels,
+		ArrayLayers:         1,
+		Samples:             samples,
+		Tiling:              vulkan.VkImageTiling_VK_IMAGE_TILING_OPTIMAL,
+		Usage:               vulkan.VkImageUsageFlags(usage),
+		SharingMode:         vulkan.VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
+		PQueueFamilyIndices: vulkan.NewU32ᶜᵖ(0),
+		InitialLayout:       vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED,
+	})
+	imageData := b.Data(ctx, image)
+	b.Add(vulkan.NewVkCreateImage(device, info.Ptr(), memory.Nullptr, imageData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(imageData.Data()))
+
+	size := uint32(0)
+	for level := uint32(0); level < levels; level++ {
+		size += levelSize(width, level) * levelSize(height, level) * 4 * uint32(samples)
+	}
+	mem := b.DeviceMemory(ctx, device, vulkan.VkDeviceSize(size))
+	b.Add(vulkan.NewVkBindImageMemory(device, image, mem, 0, success))
+	return image
+}
+
+// ImageView creates a view of the whole color image.
+func (b *Builder) ImageView(ctx context.Context, device vulkan.VkDevice, image vulkan.VkImage) vulkan.VkImageView {
+	return b.imageView(ctx, device, image, colorFormat, colorSubresourceRange())
+}
+
+// DepthImageView creates a view of the depth and stencil aspects of the whole
+// depth image.
+func (b *Builder) DepthImageView(ctx context.Context, device vulkan.VkDevice, image vulkan.VkImage) vulkan.VkImageView {
+	subresources := colorSubresourceRange()
+	subresources.AspectMask = depthAspect
+	return b.imageView(ctx, device, image, depthFormat, subresources)
+}
+
+// PlaneImageView creates a view of the given plane of the whole multi-planar
+// image, in the format of the plane.
+func (b *Builder) PlaneImageView(ctx context.Context, device vulkan.VkDevice, image vulkan.VkImage,
+	plane uint32, format vulkan.VkFormat) vulkan.VkImageView {
+
+	subresources := colorSubresourceRange()
+	subresources.AspectMask = vulkan.VkImageAspectFlags(vulkan.VkImageAspectFlagBits_VK_IMAGE_ASPECT_PLANE_0_BIT_KHR << plane)
+	return b.imageView(ctx, device, image, format, subresources)
+}
+
+func (b *Builder) imageView(ctx context.Context, device vulkan.VkDevice, image vulkan.VkImage,
+	format vulkan.VkFormat, subresources vulkan.VkImageSubresourceRange) vulkan.VkImageView {
+
+	view := vulkan.VkImageView(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkImageViewCreateInfo{
+		SType:    vulkan.VkStructureType_VK_STRUCTURE_TYPE_IMAGE_VIEW_CREATE_INFO,
+		PNext:    vulkan.NewVoidᶜᵖ(0),
+		Image:    image,
+		ViewType: vulkan.VkImageViewType_VK_IMAGE_VIEW_TYPE_2D,
+		Format:   format,
+		Components: vulkan.VkComponentMapping{
+			R: vulkan.VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+			G: vulkan.VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+			B: vulkan.VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+			A: vulkan.VkComponentSwizzle_VK_COMPONENT_SWIZZLE_IDENTITY,
+		},
+		SubresourceRange: subresources,
+	})
+	viewData := b.Data(ctx, view)
+	b.Add(vulkan.NewVkCreateImageView(device, info.Ptr(), memory.Nullptr, viewData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(viewData.Data()))
+	return view
+}
+
+// levelSize returns the size of the given mip level of an image dimension.
+func levelSize(size, level uint32) uint32 {
+	if size>>level == 0 {
+		return 1
+	}
+	return size >> level
+}
+
+func colorSubresourceRange() vulkan.VkImageSubresourceRange {
+	return vulkan.VkImageSubresourceRange{
+		AspectMask:     colorAspect,
+		BaseMipLevel:   0,
+		LevelCount:     1,
+		BaseArrayLayer: 0,
+		LayerCount:     1,
+	}
+}
+
+// Sampler creates a nearest-filtering, edge-clamping sampler.
+func (b *Builder) Sampler(ctx context.Context, device vulkan.VkDevice) vulkan.VkSampler {
+	sampler := vulkan.VkSampler(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkSamplerCreateInfo{
+		SType:         vulkan.VkStructureType_VK_STRUCTURE_TYPE_SAMPLER_CREATE_INFO,
+		PNext:         vulkan.NewVoidᶜᵖ(0),
+		MagFilter:     vulkan.VkFilter_VK_FILTER_NEAREST,
+		MinFilter:     vulkan.VkFilter_VK_FILTER_NEAREST,
+		MipmapMode:    vulkan.VkSamplerMipmapMode_VK_SAMPLER_MIPMAP_MODE_NEAREST,
+		AddressModeU:  vulkan.VkSamplerAddressMode_VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		AddressModeV:  vulkan.VkSamplerAddressMode_VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		AddressModeW:  vulkan.VkSamplerAddressMode_VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		MaxAnisotropy: 1,
+		CompareOp:     vulkan.VkCompareOp_VK_COMPARE_OP_NEVER,
+		BorderColor:   vulkan.VkBorderColor_VK_BORDER_COLOR_FLOAT_TRANSPARENT_BLACK,
+	})
+	samplerData := b.Data(ctx, sampler)
+	b.Add(vulkan.NewVkCreateSampler(device, info.Ptr(), memory.Nullptr, samplerData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(samplerData.Data()))
+	return sampler
+}
+
+// ShaderModule compiles the Vulkan GLSL source for the given stage and
+// creates a shader module holding the resulting SPIR-V.
+func (b *Builder) ShaderModule(ctx context.Context, device vulkan.VkDevice, source string, stage shadertools.ShaderStage) vulkan.VkShaderModule {
+	words, err := shadertools.CompileGlsl(source, stage)
+	if err != nil {
+		panic(err)
+	}
+	module := vulkan.VkShaderModule(b.NewHandle())
+	code := b.Data(ctx, words)
+	// VkShaderModuleCreateInfo holds a size field, which memory.Write cannot
+	// encode, so the structure is encoded by the API instead.
+	buf := &bytes.Buffer{}
+	vulkan.VkShaderModuleCreateInfoEncodeRaw(b.state, endian.Writer(buf, b.state.MemoryLayout.GetEndian()), &vulkan.VkShaderModuleCreateInfo{
+		SType:    vulkan.VkStructureType_VK_STRUCTURE_TYPE_SHADER_MODULE_CREATE_INFO,
+		PNext:    vulkan.NewVoidᶜᵖ(0),
+		CodeSize: uint64(len(words)) * 4,
+		PCode:    vulkan.NewU32ᶜᵖ(code.Address()),
+	})
+	info := b.Data(ctx, buf.Bytes())
+	moduleData := b.Data(ctx, module)
+	b.Add(vulkan.NewVkCreateShaderModule(device, info.Ptr(), memory.Nullptr, moduleData.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(code.Data()).
+		AddWrite(moduleData.Data()))
+	return module
+}
+
+// DescriptorSet creates a layout with a single binding of the given type at
+// binding 0, visible to the given stages, a pipeline layout using it, and a
+// descriptor set of that layout allocated from its own pool.
+func (b *Builder) DescriptorSet(ctx context.Context, device vulkan.VkDevice, ty vulkan.VkDescriptorType, stages vulkan.VkShaderStageFlagBits) (vulkan.VkDescriptorSet, vulkan.VkPipelineLayout) {
+	return b.descriptorSet(ctx, device, ty, stages, 1, 0)
+}
+
+// BindlessDescriptorSet is like DescriptorSet, but the layout has count
+// bindings of the given type, from binding 0, which can be updated after the
+// set is bound.
+func (b *Builder) BindlessDescriptorSet(ctx context.Context, device vulkan.VkDevice, ty vulkan.VkDescriptorType,
+	stages vulkan.VkShaderStageFlagBits, count uint32) (vulkan.VkDescriptorSet, vulkan.VkPipelineLayout) {
+
+	return b.descriptorSet(ctx, device, ty, stages, count,
+		vulkan.VkDescriptorSetLayoutCreateFlags(vulkan.VkDescriptorSetLayoutCreateFlagBits_VK_DESCRIPTOR_SET_LAYOUT_CREATE_UPDATE_AFTER_BIND_POOL_BIT_EXT))
+}
+
+func (b *Builder) descriptorSet(ctx context.Context, device vulkan.VkDevice, ty vulkan.VkDescriptorType,
+	stages vulkan.VkShaderStageFlagBits, count uint32, flags vulkan.VkDescriptorSetLayoutCreateFlags) (vulkan.VkDescriptorSet, vulkan.VkPipelineLayout) {
+
+	setLayout := vulkan.VkDescriptorSetLayout(b.NewHandle())
+	pipelineLayout := vulkan.VkPipelineLayout(b.NewHandle())
+	pool := vulkan.VkDescriptorPool(b.NewHandle())
+	set := vulkan.VkDescriptorSet(b.NewHandle())
+
+	bindings := make([]vulkan.VkDescriptorSetLayoutBinding, count)
+	for i := range bindings {
+		bindings[i] = vulkan.VkDescriptorSetLayoutBinding{
+			Binding:            uint32(i),
+			DescriptorType:     ty,
+			DescriptorCount:    1,
+			StageFlags:         vulkan.VkShaderStageFlags(stages),
+			PImmutableSamplers: vulkan.NewVkSamplerᶜᵖ(0),
+		}
+	}
+	binding := b.Data(ctx, bindings)
+	setLayoutInfo := b.Data(ctx, vulkan.VkDescriptorSetLayoutCreateInfo{
+		SType:        vulkan.VkStructureType_VK_STRUCTURE_TYPE_DESCRIPTOR_SET_LAYOUT_CREATE_INFO,
+		PNext:        vulkan.NewVoidᶜᵖ(0),
+		Flags:        flags,
+		BindingCount: count,
+		PBindings:    vulkan.NewVkDescriptorSetLayoutBindingᶜᵖ(binding.Address()),
+	})
+	setLayoutData := b.Data(ctx, setLayout)
+	pipelineLayoutInfo := b.Data(ctx, vulkan.VkPipelineLayoutCreateInfo{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_LAYOUT_CREATE_INFO,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		SetLayoutCount:      1,
+		PSetLayouts:         vulkan.NewVkDescriptorSetLayoutᶜᵖ(setLayoutData.Address()),
+		PPushConstantRanges: vulkan.NewVkPushConstantRangeᶜᵖ(0),
+	})
+	pipelineLayoutData := b.Data(ctx, pipelineLayout)
+	poolSize := b.Data(ctx, vulkan.VkDescriptorPoolSize{
+		Type:            ty,
+		DescriptorCount: count,
+	})
+	poolInfo := b.Data(ctx, vulkan.VkDescriptorPoolCreateInfo{
+		SType:         vulkan.VkStructureType_VK_STRUCTURE_TYPE_DESCRIPTOR_POOL_CREATE_INFO,
+		PNext:         vulkan.NewVoidᶜᵖ(0),
+		MaxSets:       1,
+		PoolSizeCount: 1,
+		PPoolSizes:    vulkan.NewVkDescriptorPoolSizeᶜᵖ(poolSize.Address()),
+	})
+	poolData := b.Data(ctx, pool)
+	setInfo := b.Data(ctx, vulkan.VkDescriptorSetAllocateInfo{
+		SType:              vulkan.VkStructureType_VK_STRUCTURE_TYPE_DESCRIPTOR_SET_ALLOCATE_INFO,
+		PNext:              vulkan.NewVoidᶜᵖ(0),
+		DescriptorPool:     pool,
+		DescriptorSetCount: 1,
+		PSetLayouts:        vulkan.NewVkDescriptorSetLayoutᶜᵖ(setLayoutData.Address()),
+	})
+	setData := b.Data(ctx, set)
+
+	b.Add(
+		vulkan.NewVkCreateDescriptorSetLayout(device, setLayoutInfo.Ptr(), memory.Nullptr, setLayoutData.Ptr(), success).
+			AddRead(setLayoutInfo.Data()).
+			AddRead(binding.Data()).
+			AddWrite(setLayoutData.Data()),
+		vulkan.NewVkCreatePipelineLayout(device, pipelineLayoutInfo.Ptr(), memory.Nullptr, pipelineLayoutData.Ptr(), success).
+			AddRead(pipelineLayoutInfo.Data()).
+			AddRead(setLayoutData.Data()).
+			AddWrite(pipelineLayoutData.Data()),
+		vulkan.NewVkCreateDescriptorPool(device, poolInfo.Ptr(), memory.Nullptr, poolData.Ptr(), success).
+			AddRead(poolInfo.Data()).
+			AddRead(poolSize.Data()).
+			AddWrite(poolData.Data()),
+		vulkan.NewVkAllocateDescriptorSets(device, setInfo.Ptr(), setData.Ptr(), success).
+			AddRead(setInfo.Data()).
+			AddRead(setLayoutData.Data()).
+			AddWrite(setData.Data()),
+	)
+	return set, pipelineLayout
+}
+
+// PipelineLayout creates a pipeline layout without descriptor sets nor push
+// constants.
+func (b *Builder) PipelineLayout(ctx context.Context, device vulkan.VkDevice) vulkan.VkPipelineLayout {
+	layout := vulkan.VkPipelineLayout(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkPipelineLayoutCreateInfo{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_LAYOUT_CREATE_INFO,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		PSetLayouts:         vulkan.NewVkDescriptorSetLayoutᶜᵖ(0),
+		PPushConstantRanges: vulkan.NewVkPushConstantRangeᶜᵖ(0),
+	})
+	layoutData := b.Data(ctx, layout)
+	b.Add(vulkan.NewVkCreatePipelineLayout(device, info.Ptr(), memory.Nullptr, layoutData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(layoutData.Data()))
+	return layout
+}
+
+// WriteImage writes the image view and sampler to binding 0 of the
+// descriptor set.
+func (b *Builder) WriteImage(ctx context.Context, device vulkan.VkDevice, set vulkan.VkDescriptorSet, view vulkan.VkImageView, sampler vulkan.VkSampler) {
+	imageInfo := b.Data(ctx, vulkan.VkDescriptorImageInfo{
+		Sampler:     sampler,
+		ImageView:   view,
+		ImageLayout: vulkan.VkImageLayout_VK_IMAGE_LAYOUT_SHADER_READ_ONLY_OPTIMAL,
+	})
+	b.writeDescriptor(ctx, device, vulkan.VkWriteDescriptorSet{
+		DstSet:         set,
+		DescriptorType: vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER,
+		PImageInfo:     vulkan.NewVkDescriptorImageInfoᶜᵖ(imageInfo.Address()),
+		PBufferInfo:    vulkan.NewVkDescriptorBufferInfoᶜᵖ(0),
+	}, imageInfo)
+}
+
+// WriteBuffer writes size bytes of the buffer from offset as the buffer
+// descriptor of the given type at binding 0 of the descriptor set.
+func (b *Builder) WriteBuffer(ctx context.Context, device vulkan.VkDevice, set vulkan.VkDescriptorSet,
+	ty vulkan.VkDescriptorType, buffer vulkan.VkBuffer, offset, size vulkan.VkDeviceSize) {
+
+	b.WriteBufferAt(ctx, device, set, 0, ty, buffer, offset, size)
+}
+
+// WriteBufferAt is like WriteBuffer, but writes the descriptor at the given
+// binding of the descriptor set.
+func (b *Builder) WriteBufferAt(ctx context.Context, device vulkan.VkDevice, set vulkan.VkDescriptorSet, binding uint32,
+	ty vulkan.VkDescriptorType, buffer vulkan.VkBuffer, offset, size vulkan.VkDeviceSize) {
+
+	bufferInfo := b.Data(ctx, vulkan.VkDescriptorBufferInfo{
+		Buffer: buffer,
+		Offset: offset,
+		Range:  size,
+	})
+	b.writeDescriptor(ctx, device, vulkan.VkWriteDescriptorSet{
+		DstSet:         set,
+		DstBinding:     binding,
+		DescriptorType: ty,
+		PImageInfo:     vulkan.NewVkDescriptorImageInfoᶜᵖ(0),
+		PBufferInfo:    vulkan.NewVkDescriptorBufferInfoᶜᵖ(bufferInfo.Address()),
+	}, bufferInfo)
+}
+
+func (b *Builder) writeDescriptor(ctx context.Context, device vulkan.VkDevice, write vulkan.VkWriteDescriptorSet, info atom.AllocResult) {
+	write.SType = vulkan.VkStructureType_VK_STRUCTURE_TYPE_WRITE_DESCRIPTOR_SET
+	write.PNext = vulkan.NewVoidᶜᵖ(0)
+	write.DescriptorCount = 1
+	write.PTexelBufferView = vulkan.NewVkBufferViewᶜᵖ(0)
+	writeData := b.Data(ctx, write)
+	b.Add(vulkan.NewVkUpdateDescriptorSets(device, 1, writeData.Ptr(), 0, memory.Nullptr).
+		AddRead(writeData.Data()).
+		AddRead(info.Data()))
+}
+
+// RenderPass creates a render pass with a single subpass drawing to a single
+// color attachment, which is cleared on load and transitioned to finalLayout
+// at the end of the pass.
+func (b *Builder) RenderPass(ctx context.Context, device vulkan.VkDevice, finalLayout vulkan.VkImageLayout) vulkan.VkRenderPass {
+	return b.renderPass(ctx, device, colorFormat, finalLayout)
+}
+
+// PlaneRenderPass creates a render pass like RenderPass, drawing to an
+// attachment of the given format, such as a plane of a multi-planar image.
+func (b *Builder) PlaneRenderPass(ctx context.Context, device vulkan.VkDevice,
+	format vulkan.VkFormat, finalLayout vulkan.VkImageLayout) vulkan.VkRenderPass {
+
+	return b.renderPass(ctx, device, format, finalLayout)
+}
+
+func (b *Builder) renderPass(ctx context.Context, device vulkan.VkDevice,
+	format vulkan.VkFormat, finalLayout vulkan.VkImageLayout) vulkan.VkRenderPass {
+
+	renderPass := vulkan.VkRenderPass(b.NewHandle())
+	attachment := b.Data(ctx, vulkan.VkAttachmentDescription{
+		Format:         format,
+		Samples:        vulkan.VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT,
+		LoadOp:         vulkan.VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_CLEAR,
+		StoreOp:        vulkan.VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_STORE,
+		StencilLoadOp:  vulkan.VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_DONT_CARE,
+		StencilStoreOp: vulkan.VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_DONT_CARE,
+		InitialLayout:  vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED,
+		FinalLayout:    finalLayout,
+	})
+	reference := b.Data(ctx, vulkan.VkAttachmentReference{
+		Attachment: 0,
+		Layout:     vulkan.VkImageLayout_VK_IMAGE_LAYOUT_COLOR_ATTACHMENT_OPTIMAL,
+	})
+	subpass := b.Data(ctx, vulkan.VkSubpassDescription{
+		PipelineBindPoint:       vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS,
+		PInputAttachments:       vulkan.NewVkAttachmentReferenceᶜᵖ(0),
+		ColorAttachmentCount:    1,
+		PColorAttachments:       vulkan.NewVkAttachmentReferenceᶜᵖ(reference.Address()),
+		PResolveAttachments:     vulkan.NewVkAttachmentReferenceᶜᵖ(0),
+		PDepthStencilAttachment: vulkan.NewVkAttachmentReferenceᶜᵖ(0),
+		PPreserveAttachments:    vulkan.NewU32ᶜᵖ(0),
+	})
+	info := b.Data(ctx, vulkan.VkRenderPassCreateInfo{
+		SType:           vulkan.VkStructureType_VK_STRUCTURE_TYPE_RENDER_PASS_CREATE_INFO,
+		PNext:           vulkan.NewVoidᶜᵖ(0),
+		AttachmentCount: 1,
+		PAttachments:    vulkan.NewVkAttachmentDescriptionᶜᵖ(attachment.Address()),
+		SubpassCount:    1,
+		PSubpasses:      vulkan.NewVkSubpassDescriptionᶜᵖ(subpass.Address()),
+		PDependencies:   vulkan.NewVkSubpassDependencyᶜᵖ(0),
+	})
+	renderPassData := b.Data(ctx, renderPass)
+	b.Add(vulkan.NewVkCreateRenderPass(device, info.Ptr(), memory.Nullptr, renderPassData.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(attachment.Data()).
+		AddRead(subpass.Data()).
+		AddRead(reference.Data()).
+		AddWrite(renderPassData.Data()))
+	return renderPass
+}
+
+// DepthRenderPass creates a render pass with a single subpass drawing to a
+// single depth and stencil attachment of the given sample count, which is
+// cleared on load and transitioned to finalLayout at the end of the pass.
+func (b *Builder) DepthRenderPass(ctx context.Context, device vulkan.VkDevice,
+	samples vulkan.VkSampleCountFlagBits, finalLayout vulkan.VkImageLayout) vulkan.VkRenderPass {
+
+	renderPass := vulkan.VkRenderPass(b.NewHandle())
+	attachment := b.Data(ctx, vulkan.VkAttachmentDescription{
+		Format:         depthFormat,
+		Samples:        samples,
+		LoadOp:         vulkan.VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_CLEAR,
+		StoreOp:        vulkan.VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_STORE,
+		StencilLoadOp:  vulkan.VkAttachmentLoadOp_VK_ATTACHMENT_LOAD_OP_CLEAR,
+		StencilStoreOp: vulkan.VkAttachmentStoreOp_VK_ATTACHMENT_STORE_OP_STORE,
+		InitialLayout:  vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED,
+		FinalLayout:    finalLayout,
+	})
+	reference := b.Data(ctx, vulkan.VkAttachmentReference{
+		Attachment: 0,
+		Layout:     vulkan.VkImageLayout_VK_IMAGE_LAYOUT_DEPTH_STENCIL_ATTACHMENT_OPTIMAL,
+	})
+	subpass := b.Data(ctx, vulkan.VkSubpassDescription{
+		PipelineBindPoint:       vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS,
+		PInputAttachments:       vulkan.NewVkAttachmentReferenceᶜᵖ(0),
+		PColorAttachments:       vulkan.NewVkAttachmentReferenceᶜᵖ(0),
+		PResolveAttachments:     vulkan.NewVkAttachmentReferenceᶜᵖ(0),
+		PDepthStencilAttachment: vulkan.NewVkAttachmentReferenceᶜᵖ(reference.Address()),
+		PPreserveAttachments:    vulkan.NewU32ᶜᵖ(0),
+	})
+	info := b.Data(ctx, vulkan.VkRenderPassCreateInfo{
+		SType:           vulkan.VkStructureType_VK_STRUCTURE_TYPE_RENDER_PASS_CREATE_INFO,
+		PNext:           vulkan.NewVoidᶜᵖ(0),
+		AttachmentCount: 1,
+		PAttachments:    vulkan.NewVkAttachmentDescriptionᶜᵖ(attachment.Address()),
+		SubpassCount:    1,
+		PSubpasses:      vulkan.NewVkSubpassDescriptionᶜᵖ(subpass.Address()),
+		PDependencies:   vulkan.NewVkSubpassDependencyᶜᵖ(0),
+	})
+	renderPassData := b.Data(ctx, renderPass)
+	b.Add(vulkan.NewVkCreateRenderPass(device, info.Ptr(), memory.Nullptr, renderPassData.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(attachment.Data()).
+		AddRead(subpass.Data()).
+		AddRead(reference.Data()).
+		AddWrite(renderPassData.Data()))
+	return renderPass
+}
+
+// Framebuffer creates a framebuffer of the render pass with the view as its
+// only attachment.
+func (b *Builder) Framebuffer(ctx context.Context, device vulkan.VkDevice, renderPass vulkan.VkRenderPass, view vulkan.VkImageView, width, height uint32) vulkan.VkFramebuffer {
+	framebuffer := vulkan.VkFramebuffer(b.NewHandle())
+	attachments := b.Data(ctx, view)
+	info := b.Data(ctx, vulkan.VkFramebufferCreateInfo{
+		SType:           vulkan.VkStructureType_VK_STRUCTURE_TYPE_FRAMEBUFFER_CREATE_INFO,
+		PNext:           vulkan.NewVoidᶜᵖ(0),
+		RenderPass:      renderPass,
+		AttachmentCount: 1,
+		PAttachments:    vulkan.NewVkImageViewᶜᵖ(attachments.Address()),
+		Width:           width,
+		Height:          height,
+		Layers:          1,
+	})
+	framebufferData := b.Data(ctx, framebuffer)
+	b.Add(vulkan.NewVkCreateFramebuffer(device, info.Ptr(), memory.Nullptr, framebufferData.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(attachments.Data()).
+		AddWrite(framebufferData.Data()))
+	return framebuffer
+}
+
+// GraphicsPipeline creates a pipeline drawing triangle lists with the given
+// shaders to the first subpass of the render pass. If vertexStride is not 0,
+// the vertices are read as vec2 positions at location 0 from binding 0.
+func (b *Builder) GraphicsPipeline(ctx context.Context, device vulkan.VkDevice,
+	renderPass vulkan.VkRenderPass, layout vulkan.VkPipelineLayout,
+	vs, fs vulkan.VkShaderModule, width, height, vertexStride uint32) vulkan.VkPipeline {
+
+	return b.graphicsPipeline(ctx, device, renderPass, layout, vs, fs, width, height, vertexStride,
+		vulkan.VkSampleCountFlagBits_VK_SAMPLE_COUNT_1_BIT)
+}
+
+// DepthPipeline creates a pipeline drawing triangle lists with the vertex
+// shader only to the first subpass of the render pass, which has a single
+// depth attachment of the given sample count. The depth test always passes.
+func (b *Builder) DepthPipeline(ctx context.Context, device vulkan.VkDevice,
+	renderPass vulkan.VkRenderPass, layout vulkan.VkPipelineLayout,
+	vs vulkan.VkShaderModule, width, height uint32, samples vulkan.VkSampleCountFlagBits) vulkan.VkPipeline {
+
+	return b.graphicsPipeline(ctx, device, renderPass, layout, vs, vulkan.VkShaderModule(0), width, height, 0, samples)
+}
+
+// graphicsPipeline creates a pipeline drawing to a single color attachment
+// with the fragment shader fs, or to a single depth attachment if fs is 0.
+func (b *Builder) graphicsPipeline(ctx context.Context, device vulkan.VkDevice,
+	renderPass vulkan.VkRenderPass, layout vulkan.VkPipelineLayout,
+	vs, fs vulkan.VkShaderModule, width, height, vertexStride uint32,
+	samples vulkan.VkSampleCountFlagBits) vulkan.VkPipeline {
+
+	depthOnly := fs == vulkan.VkShaderModule(0)
+	pipeline := vulkan.VkPipeline(b.NewHandle())
+	entry := b.Data(ctx, "main")
+	stageInfos := []vulkan.VkPipelineShaderStageCreateInfo{
+		{
+			SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_SHADER_STAGE_CREATE_INFO,
+			PNext:               vulkan.NewVoidᶜᵖ(0),
+			Stage:               vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_VERTEX_BIT,
+			Module:              vs,
+			PName:               vulkan.NewCharᶜᵖ(entry.Address()),
+			PSpecializationInfo: vulkan.NewVkSpecializationInfoᶜᵖ(0),
+		}, {
+			SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_SHADER_STAGE_CREATE_INFO,
+			PNext:               vulkan.NewVoidᶜᵖ(0),
+			Stage:               vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_FRAGMENT_BIT,
+			Module:              fs,
+			PName:               vulkan.NewCharᶜᵖ(entry.Address()),
+			PSpecializationInfo: vulkan.NewVkSpecializationInfoᶜᵖ(0),
+		},
+	}
+	if depthOnly {
+		stageInfos = stageInfos[:1]
+	}
+	stages := b.Data(ctx, stageInfos)
+
+	vertexBinding := b.Data(ctx, vulkan.VkVertexInputBindingDescription{
+		Binding:   0,
+		Stride:    vertexStride,
+		InputRate: vulkan.VkVertexInputRate_VK_VERTEX_INPUT_RATE_VERTEX,
+	})
+	vertexAttribute := b.Data(ctx, vulkan.VkVertexInputAttributeDescription{
+		Location: 0,
+		Binding:  0,
+		Format:   vulkan.VkFormat_VK_FORMAT_R32G32_SFLOAT,
+		Offset:   0,
+	})
+	vertexInput := vulkan.VkPipelineVertexInputStateCreateInfo{
+		SType:                        vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_VERTEX_INPUT_STATE_CREATE_INFO,
+		PNext:                        vulkan.NewVoidᶜᵖ(0),
+		PVertexBindingDescriptions:   vulkan.NewVkVertexInputBindingDescriptionᶜᵖ(0),
+		PVertexAttributeDescriptions: vulkan.NewVkVertexInputAttributeDescriptionᶜᵖ(0),
+	}
+	if vertexStride != 0 {
+		vertexInput.VertexBindingDescriptionCount = 1
+		vertexInput.PVertexBindingDescriptions = vulkan.NewVkVertexInputBindingDescriptionᶜᵖ(vertexBinding.Address())
+		vertexInput.VertexAttributeDescriptionCount = 1
+		vertexInput.PVertexAttributeDescriptions = vulkan.NewVkVertexInputAttributeDescriptionᶜᵖ(vertexAttribute.Address())
+	}
+	vertexInputState := b.Data(ctx, vertexInput)
+	inputAssemblyState := b.Data(ctx, vulkan.VkPipelineInputAssemblyStateCreateInfo{
+		SType:    vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_INPUT_ASSEMBLY_STATE_CREATE_INFO,
+		PNext:    vulkan.NewVoidᶜᵖ(0),
+		Topology: vulkan.VkPrimitiveTopology_VK_PRIMITIVE_TOPOLOGY_TRIANGLE_LIST,
+	})
+
+	viewport := b.Data(ctx, vulkan.VkViewport{
+		X:        0,
+		Y:        0,
+		Width:    float32(width),
+		Height:   float32(height),
+		MinDepth: 0,
+		MaxDepth: 1,
+	})
+	scissor := b.Data(ctx, vulkan.VkRect2D{
+		Offset: vulkan.VkOffset2D{X: 0, Y: 0},
+		Extent: vulkan.VkExtent2D{Width: width, Height: height},
+	})
+	viewportState := b.Data(ctx, vulkan.VkPipelineViewportStateCreateInfo{
+		SType:         vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_VIEWPORT_STATE_CREATE_INFO,
+		PNext:         vulkan.NewVoidᶜᵖ(0),
+		ViewportCount: 1,
+		PViewports:    vulkan.NewVkViewportᶜᵖ(viewport.Address()),
+		ScissorCount:  1,
+		PScissors:     vulkan.NewVkRect2Dᶜᵖ(scissor.Address()),
+	})
+	rasterizationState := b.Data(ctx, vulkan.VkPipelineRasterizationStateCreateInfo{
+		SType:       vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_RASTERIZATION_STATE_CREATE_INFO,
+		PNext:       vulkan.NewVoidᶜᵖ(0),
+		PolygonMode: vulkan.VkPolygonMode_VK_POLYGON_MODE_FILL,
+		CullMode:    vulkan.VkCullModeFlags(vulkan.VkCullModeFlagBits_VK_CULL_MODE_NONE),
+		FrontFace:   vulkan.VkFrontFace_VK_FRONT_FACE_COUNTER_CLOCKWISE,
+		LineWidth:   1,
+	})
+	multisampleState := b.Data(ctx, vulkan.VkPipelineMultisampleStateCreateInfo{
+		SType:                vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_MULTISAMPLE_STATE_CREATE_INFO,
+		PNext:                vulkan.NewVoidᶜᵖ(0),
+		RasterizationSamples: samples,
+		PSampleMask:          vulkan.NewVkSampleMaskᶜᵖ(0),
+	})
+	blendAttachment := b.Data(ctx, vulkan.VkPipelineColorBlendAttachmentState{
+		ColorWriteMask: vulkan.VkColorComponentFlags(
+			vulkan.VkColorComponentFlagBits_VK_COLOR_COMPONENT_R_BIT |
+				vulkan.VkColorComponentFlagBits_VK_COLOR_COMPONENT_G_BIT |
+				vulkan.VkColorComponentFlagBits_VK_COLOR_COMPONENT_B_BIT |
+				vulkan.VkColorComponentFlagBits_VK_COLOR_COMPONENT_A_BIT),
+	})
+	colorBlendState := b.Data(ctx, vulkan.VkPipelineColorBlendStateCreateInfo{
+		SType:           vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_COLOR_BLEND_STATE_CREATE_INFO,
+		PNext:           vulkan.NewVoidᶜᵖ(0),
+		LogicOp:         vulkan.VkLogicOp_VK_LOGIC_OP_COPY,
+		AttachmentCount: 1,
+		PAttachments:    vulkan.NewVkPipelineColorBlendAttachmentStateᶜᵖ(blendAttachment.Address()),
+	})
+
+	createInfo := vulkan.VkGraphicsPipelineCreateInfo{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		StageCount:          uint32(len(stageInfos)),
+		PStages:             vulkan.NewVkPipelineShaderStageCreateInfoᶜᵖ(stages.Address()),
+		PVertexInputState:   vulkan.NewVkPipelineVertexInputStateCreateInfoᶜᵖ(vertexInputState.Address()),
+		PInputAssemblyState: vulkan.NewVkPipelineInputAssemblyStateCreateInfoᶜᵖ(inputAssemblyState.Address()),
+		PTessellationState:  vulkan.NewVkPipelineTessellationStateCreateInfoᶜᵖ(0),
+		PViewportState:      vulkan.NewVkPipelineViewportStateCreateInfoᶜᵖ(viewportState.Address()),
+		PRasterizationState: vulkan.NewVkPipelineRasterizationStateCreateInfoᶜᵖ(rasterizationState.Address()),
+		PMultisampleState:   vulkan.NewVkPipelineMultisampleStateCreateInfoᶜᵖ(multisampleState.Address()),
+		PDepthStencilState:  vulkan.NewVkPipelineDepthStencilStateCreateInfoᶜᵖ(0),
+		PColorBlendState:    vulkan.NewVkPipelineColorBlendStateCreateInfoᶜᵖ(colorBlendState.Address()),
+		PDynamicState:       vulkan.NewVkPipelineDynamicStateCreateInfoᶜᵖ(0),
+		Layout:              layout,
+		RenderPass:          renderPass,
+		Subpass:             0,
+		BasePipelineIndex:   -1,
+	}
+	var depthStencilState atom.AllocResult
+	if depthOnly {
+		depthStencilState = b.Data(ctx, vulkan.VkPipelineDepthStencilStateCreateInfo{
+			SType:            vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_DEPTH_STENCIL_STATE_CREATE_INFO,
+			PNext:            vulkan.NewVoidᶜᵖ(0),
+			DepthTestEnable:  1,
+			DepthWriteEnable: 1,
+			DepthCompareOp:   vulkan.VkCompareOp_VK_COMPARE_OP_ALWAYS,
+			MaxDepthBounds:   1,
+		})
+		createInfo.PDepthStencilState = vulkan.NewVkPipelineDepthStencilStateCreateInfoᶜᵖ(depthStencilState.Address())
+		createInfo.PColorBlendState = vulkan.NewVkPipelineColorBlendStateCreateInfoᶜᵖ(0)
+	}
+	info := b.Data(ctx, createInfo)
+	pipelineData := b.Data(ctx, pipeline)
+
+	create := vulkan.NewVkCreateGraphicsPipelines(device, vulkan.VkPipelineCache(0), 1, info.Ptr(), memory.Nullptr, pipelineData.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(stages.Data()).
+		AddRead(entry.Data()).
+		AddRead(vertexInputState.Data()).
+		AddRead(inputAssemblyState.Data()).
+		AddRead(viewportState.Data()).
+		AddRead(viewport.Data()).
+		AddRead(scissor.Data()).
+		AddRead(rasterizationState.Data()).
+		AddRead(multisampleState.Data()).
+		AddWrite(pipelineData.Data())
+	if depthOnly {
+		create.AddRead(depthStencilState.Data())
+	} else {
+		create.AddRead(colorBlendState.Data()).AddRead(blendAttachment.Data())
+	}
+	if vertexStride != 0 {
+		create.AddRead(vertexBinding.Data()).AddRead(vertexAttribute.Data())
+	}
+	b.Add(create)
+	return pipeline
+}
+
+// ComputePipeline creates a pipeline running the main entry point of the
+// compute shader module.
+func (b *Builder) ComputePipeline(ctx context.Context, device vulkan.VkDevice, layout vulkan.VkPipelineLayout, cs vulkan.VkShaderModule) vulkan.VkPipeline {
+	pipeline := vulkan.VkPipeline(b.NewHandle())
+	entry := b.Data(ctx, "main")
+	info := b.Data(ctx, vulkan.VkComputePipelineCreateInfo{
+		SType: vulkan.VkStructureType_VK_STRUCTURE_TYPE_COMPUTE_PIPELINE_CREATE_INFO,
+		PNext: vulkan.NewVoidᶜᵖ(0),
+		Stage: vulkan.VkPipelineShaderStageCreateInfo{
+			SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_PIPELINE_SHADER_STAGE_CREATE_INFO,
+			PNext:               vulkan.NewVoidᶜᵖ(0),
+			Stage:               vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_COMPUTE_BIT,
+			Module:              cs,
+			PName:               vulkan.NewCharᶜᵖ(entry.Address()),
+			PSpecializationInfo: vulkan.NewVkSpecializationInfoᶜᵖ(0),
+		},
+		Layout:            layout,
+		BasePipelineIndex: -1,
+	})
+	pipelineData := b.Data(ctx, pipeline)
+	b.Add(vulkan.NewVkCreateComputePipelines(device, vulkan.VkPipelineCache(0), 1, info.Ptr(), memory.Nullptr, pipelineData.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(entry.Data()).
+		AddWrite(pipelineData.Data()))
+	return pipeline
+}
+
+// CommandBuffer allocates a primary command buffer from the pool.
+func (b *Builder) CommandBuffer(ctx context.Context, device vulkan.VkDevice, pool vulkan.VkCommandPool) vulkan.VkCommandBuffer {
+	commandBuffer := vulkan.VkCommandBuffer(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkCommandBufferAllocateInfo{
+		SType:              vulkan.VkStructureType_VK_STRUCTURE_TYPE_COMMAND_BUFFER_ALLOCATE_INFO,
+		PNext:              vulkan.NewVoidᶜᵖ(0),
+		CommandPool:        pool,
+		Level:              vulkan.VkCommandBufferLevel_VK_COMMAND_BUFFER_LEVEL_PRIMARY,
+		CommandBufferCount: 1,
+	})
+	commandBufferData := b.Data(ctx, commandBuffer)
+	b.Add(vulkan.NewVkAllocateCommandBuffers(device, info.Ptr(), commandBufferData.Ptr(), success).
+		AddRead(info.Data()).
+		AddWrite(commandBufferData.Data()))
+	return commandBuffer
+}
+
+// Begin begins recording the command buffer for a single submission.
+func (b *Builder) Begin(ctx context.Context, commandBuffer vulkan.VkCommandBuffer) {
+	info := b.Data(ctx, vulkan.VkCommandBufferBeginInfo{
+		SType:            vulkan.VkStructureType_VK_STRUCTURE_TYPE_COMMAND_BUFFER_BEGIN_INFO,
+		PNext:            vulkan.NewVoidᶜᵖ(0),
+		Flags:            vulkan.VkCommandBufferUsageFlags(vulkan.VkCommandBufferUsageFlagBits_VK_COMMAND_BUFFER_USAGE_ONE_TIME_SUBMIT_BIT),
+		PInheritanceInfo: vulkan.NewVkCommandBufferInheritanceInfoᶜᵖ(0),
+	})
+	b.Add(vulkan.NewVkBeginCommandBuffer(commandBuffer, info.Ptr(), success).
+		AddRead(info.Data()))
+}
+
+// BeginCommandBuffer allocates a primary command buffer from the pool and
+// begins recording it for a single submission.
+func (b *Builder) BeginCommandBuffer(ctx context.Context, device vulkan.VkDevice, pool vulkan.VkCommandPool) vulkan.VkCommandBuffer {
+	commandBuffer := b.CommandBuffer(ctx, device, pool)
+	b.Begin(ctx, commandBuffer)
+	return commandBuffer
+}
+
+// Submit ends the command buffer and submits it to the queue. It returns the
+// identifier of the submission.
+func (b *Builder) Submit(ctx context.Context, queue vulkan.VkQueue, commandBuffer vulkan.VkCommandBuffer) atom.ID {
+	commandBuffers := b.Data(ctx, commandBuffer)
+	info := b.Data(ctx, vulkan.VkSubmitInfo{
+		SType:              vulkan.VkStructureType_VK_STRUCTURE_TYPE_SUBMIT_INFO,
+		PNext:              vulkan.NewVoidᶜᵖ(0),
+		PWaitSemaphores:    vulkan.NewVkSemaphoreᶜᵖ(0),
+		PWaitDstStageMask:  vulkan.NewVkPipelineStageFlagsᶜᵖ(0),
+		CommandBufferCount: 1,
+		PCommandBuffers:    vulkan.NewVkCommandBufferᶜᵖ(commandBuffers.Address()),
+		PSignalSemaphores:  vulkan.NewVkSemaphoreᶜᵖ(0),
+	})
+	b.Add(vulkan.NewVkEndCommandBuffer(commandBuffer, success))
+	return b.Add(vulkan.NewVkQueueSubmit(queue, 1, info.Ptr(), vulkan.VkFence(0), success).
+		AddRead(info.Data()).
+		AddRead(commandBuffers.Data()))
+}
+
+// WaitIdle waits for the queue to be idle.
+func (b *Builder) WaitIdle(queue vulkan.VkQueue) {
+	b.Add(vulkan.NewVkQueueWaitIdle(queue, success))
+}
+
+// Swapchain creates a swapchain of a single image of the given size and gets
+// that image.
+func (b *Builder) Swapchain(ctx context.Context, device vulkan.VkDevice, width, height uint32) vulkan.VkSwapchainKHR {
+	swapchain := vulkan.VkSwapchainKHR(b.NewHandle())
+	image := vulkan.VkImage(b.NewHandle())
+	info := b.Data(ctx, vulkan.VkSwapchainCreateInfoKHR{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_SWAPCHAIN_CREATE_INFO_KHR,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		MinImageCount:       1,
+		ImageFormat:         colorFormat,
+		ImageColorSpace:     vulkan.VkColorSpaceKHR_VK_COLORSPACE_SRGB_NONLINEAR_KHR,
+		ImageExtent:         vulkan.VkExtent2D{Width: width, Height: height},
+		ImageArrayLayers:    1,
+		ImageUsage:          vulkan.VkImageUsageFlags(vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT),
+		ImageSharingMode:    vulkan.VkSharingMode_VK_SHARING_MODE_EXCLUSIVE,
+		PQueueFamilyIndices: vulkan.NewU32ᶜᵖ(0),
+		PreTransform:        vulkan.VkSurfaceTransformFlagBitsKHR_VK_SURFACE_TRANSFORM_IDENTITY_BIT_KHR,
+		CompositeAlpha:      vulkan.VkCompositeAlphaFlagBitsKHR_VK_COMPOSITE_ALPHA_OPAQUE_BIT_KHR,
+		PresentMode:         vulkan.VkPresentModeKHR_VK_PRESENT_MODE_FIFO_KHR,
+		Clipped:             1,
+	})
+	swapchainData := b.Data(ctx, swapchain)
+	count := b.Data(ctx, uint32(1))
+	images := b.Data(ctx, image)
+	b.Add(
+		vulkan.NewVkCreateSwapchainKHR(device, info.Ptr(), memory.Nullptr, swapchainData.Ptr(), success).
+			AddRead(info.Data()).
+			AddWrite(swapchainData.Data()),
+		vulkan.NewVkGetSwapchainImagesKHR(device, swapchain, count.Ptr(), images.Ptr(), success).
+			AddRead(count.Data()).
+			AddWrite(count.Data()).
+			AddWrite(images.Data()),
+	)
+	return swapchain
+}
+
+// Present presents the first image of the swapchain. It returns the
+// identifier of the present.
+func (b *Builder) Present(ctx context.Context, queue vulkan.VkQueue, swapchain vulkan.VkSwapchainKHR) atom.ID {
+	swapchains := b.Data(ctx, swapchain)
+	indices := b.Data(ctx, uint32(0))
+	info := b.Data(ctx, vulkan.VkPresentInfoKHR{
+		SType:           vulkan.VkStructureType_VK_STRUCTURE_TYPE_PRESENT_INFO_KHR,
+		PNext:           vulkan.NewVoidᶜᵖ(0),
+		PWaitSemaphores: vulkan.NewVkSemaphoreᶜᵖ(0),
+		SwapchainCount:  1,
+		PSwapchains:     vulkan.NewVkSwapchainKHRᶜᵖ(swapchains.Address()),
+		PImageIndices:   vulkan.NewU32ᶜᵖ(indices.Address()),
+		PResults:        vulkan.NewVkResultᵖ(0),
+	})
+	return b.Add(vulkan.NewVkQueuePresentKHR(queue, info.Ptr(), success).
+		AddRead(info.Data()).
+		AddRead(swapchains.Data()).
+		AddRead(indices.Data()))
+}
+
+// UpdateBuffer records the upload of v to the start of the buffer.
+// v must encode to at most 65536 bytes.
+func (b *Builder) UpdateBuffer(ctx context.Context, commandBuffer vulkan.VkCommandBuffer, buffer vulkan.VkBuffer, v interface{}) {
+	data := b.Data(ctx, v)
+	b.Add(vulkan.NewVkCmdUpdateBuffer(commandBuffer, buffer, 0, vulkan.VkDeviceSize(data.Range().Size), data.Ptr()).
+		AddRead(data.Data()))
+}
+
+// CopyBuffer records the copy of size bytes from the start of src to the
+// start of dst.
+func (b *Builder) CopyBuffer(ctx context.Context, commandBuffer vulkan.VkCommandBuffer, src, dst vulkan.VkBuffer, size vulkan.VkDeviceSize) {
+	region := b.Data(ctx, vulkan.VkBufferCopy{SrcOffset: 0, DstOffset: 0, Size: size})
+	b.Add(vulkan.NewVkCmdCopyBuffer(commandBuffer, src, dst, 1, region.Ptr()).
+		AddRead(region.Data()))
+}
+
+// CopyBufferToImage records the copy of the start of the buffer to the whole
+// mip level of the color image, in the general layout.
+func (b *Builder) CopyBufferToImage(ctx context.Context, commandBuffer vulkan.VkCommandBuffer,
+	buffer vulkan.VkBuffer, image vulkan.VkImage, width, height, level uint32) {
+
+	region := b.Data(ctx, vulkan.VkBufferImageCopy{
+		ImageSubresource: colorSubresourceLayers(level),
+		ImageOffset:      vulkan.VkOffset3D{X: 0, Y: 0, Z: 0},
+		ImageExtent:      vulkan.VkExtent3D{Width: levelSize(width, level), Height: levelSize(height, level), Depth: 1},
+	})
+	b.Add(vulkan.NewVkCmdCopyBufferToImage(commandBuffer, buffer, image,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL, 1, region.Ptr()).
+		AddRead(region.Data()))
+}
+
+// BlitImage records the blit of the first mip level of the color image src,
+// from the origin to srcCorner, to the box between the two corners of the
+// first mip level of the color image dst, both in the general layout.
+func (b *Builder) BlitImage(ctx context.Context, commandBuffer vulkan.VkCommandBuffer,
+	src vulkan.VkImage, srcCorner vulkan.VkOffset3D, dst vulkan.VkImage, dstCorners [2]vulkan.VkOffset3D) {
+
+	region := b.Data(ctx, vulkan.VkImageBlit{
+		SrcSubresource: colorSubresourceLayers(0),
+		SrcOffsets:     vulkan.VkOffset3Dː2ᵃ{Elements: [2]vulkan.VkOffset3D{{X: 0, Y: 0, Z: 0}, srcCorner}},
+		DstSubresource: colorSubresourceLayers(0),
+		DstOffsets:     vulkan.VkOffset3Dː2ᵃ{Elements: dstCorners},
+	})
+	b.Add(vulkan.NewVkCmdBlitImage(commandBuffer, src, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL,
+		dst, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL, 1, region.Ptr(), vulkan.VkFilter_VK_FILTER_NEAREST).
+		AddRead(region.Data()))
+}
+
+func colorSubresourceLayers(level uint32) vulkan.VkImageSubresourceLayers {
+	return vulkan.VkImageSubresourceLayers{
+		AspectMask:     colorAspect,
+		MipLevel:       level,
+		BaseArrayLayer: 0,
+		LayerCount:     1,
+	}
+}
+
+// ClearImage records the clear of the whole color image, in the general
+// layout, to black.
+func (b *Builder) ClearImage(ctx context.Context, commandBuffer vulkan.VkCommandBuffer, image vulkan.VkImage) {
+	color := b.Data(ctx, vulkan.VkClearColorValue{})
+	ranges := b.Data(ctx, colorSubresourceRange())
+	b.Add(vulkan.NewVkCmdClearColorImage(commandBuffer, image, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_GENERAL,
+		color.Ptr(), 1, ranges.Ptr()).
+		AddRead(color.Data()).
+		AddRead(ranges.Data()))
+}
+
+// MemoryBarrier records a barrier making all the previous memory writes
+// visible to all the following commands.
+func (b *Builder) MemoryBarrier(ctx context.Context, commandBuffer vulkan.VkCommandBuffer) {
+	barrier := b.Data(ctx, vulkan.VkMemoryBarrier{
+		SType:         vulkan.VkStructureType_VK_STRUCTURE_TYPE_MEMORY_BARRIER,
+		PNext:         vulkan.NewVoidᶜᵖ(0),
+		SrcAccessMask: vulkan.VkAccessFlags(vulkan.VkAccessFlagBits_VK_ACCESS_MEMORY_WRITE_BIT),
+		DstAccessMask: vulkan.VkAccessFlags(vulkan.VkAccessFlagBits_VK_ACCESS_MEMORY_READ_BIT),
+	})
+	b.Add(vulkan.NewVkCmdPipelineBarrier(commandBuffer, allCommands, allCommands, vulkan.VkDependencyFlags(0),
+		1, barrier.Ptr(), 0, memory.Nullptr, 0, memory.Nullptr).
+		AddRead(barrier.Data()))
+}
+
+// ImageBarrier records the transition of the whole image from oldLayout to
+// newLayout, ordered after all the previous memory writes.
+func (b *Builder) ImageBarrier(ctx context.Context, commandBuffer vulkan.VkCommandBuffer, image vulkan.VkImage, oldLayout, newLayout vulkan.VkImageLayout) {
+	barrier := b.Data(ctx, vulkan.VkImageMemoryBarrier{
+		SType:               vulkan.VkStructureType_VK_STRUCTURE_TYPE_IMAGE_MEMORY_BARRIER,
+		PNext:               vulkan.NewVoidᶜᵖ(0),
+		SrcAccessMask:       vulkan.VkAccessFlags(vulkan.VkAccessFlagBits_VK_ACCESS_MEMORY_WRITE_BIT),
+		DstAccessMask:       vulkan.VkAccessFlags(vulkan.VkAccessFlagBits_VK_ACCESS_MEMORY_READ_BIT | vulkan.VkAccessFlagBits_VK_ACCESS_MEMORY_WRITE_BIT),
+		OldLayout:           oldLayout,
+		NewLayout:           newLayout,
+		SrcQueueFamilyIndex: 0xFFFFFFFF,
+		DstQueueFamilyIndex: 0xFFFFFFFF,
+		Image:               image,
+		SubresourceRange:    colorSubresourceRange(),
+	})
+	b.Add(vulkan.NewVkCmdPipelineBarrier(commandBuffer, allCommands, allCommands, vulkan.VkDependencyFlags(0),
+		0, memory.Nullptr, 0, memory.Nullptr, 1, barrier.Ptr()).
+		AddRead(barrier.Data()))
+}
+
+// BeginRenderPass records the beginning of the render pass on the whole
+// framebuffer, clearing its attachment to the given color.
+func (b *Builder) BeginRenderPass(ctx context.Context, commandBuffer vulkan.VkCommandBuffer,
+	renderPass vulkan.VkRenderPass, framebuffer vulkan.VkFramebuffer,
+	width, height uint32, color [4]float32) {
+
+	bits := [4]uint32{}
+	for i, c := range color {
+		bits[i] = math.Float32bits(c)
+	}
+	clear := b.Data(ctx, vulkan.VkClearValue{
+		Color: vulkan.VkClearColorValue{Uint32: vulkan.U32ː4ᵃ{Elements: bits}},
+	})
+	info := b.Data(ctx, vulkan.VkRenderPassBeginInfo{
+		SType:       vulkan.VkStructureType_VK_STRUCTURE_TYPE_RENDER_PASS_BEGIN_INFO,
+		PNext:       vulkan.NewVoidᶜᵖ(0),
+		RenderPass:  renderPass,
+		Framebuffer: framebuffer,
+		RenderArea: vulkan.VkRect2D{
+			Offset: vulkan.VkOffset2D{X: 0, Y: 0},
+			Extent: vulkan.VkExtent2D{Width: width, Height: height},
+		},
+		ClearValueCount: 1,
+		PClearValues:    vulkan.NewVkClearValueᶜᵖ(clear.Address()),
+	})
+	b.Add(vulkan.NewVkCmdBeginRenderPass(commandBuffer, info.Ptr(), vulkan.VkSubpassContents_VK_SUBPASS_CONTENTS_INLINE).
+		AddRead(info.Data()).
+		AddRead(clear.Data()))
+}
+
+// BindDescriptorSet records the binding of the descriptor set as set 0, with
+// the given offsets for its dynamic descriptors.
+func (b *Builder) BindDescriptorSet(ctx context.Context, commandBuffer vulkan.VkCommandBuffer,
+	bindPoint vulkan.VkPipelineBindPoint, layout vulkan.VkPipelineLayout, set vulkan.VkDescriptorSet,
+	dynamicOffsets ...uint32) {
+
+	sets := b.Data(ctx, set)
+	bind := vulkan.NewVkCmdBindDescriptorSets(commandBuffer, bindPoint, layout, 0, 1, sets.Ptr(), 0, memory.Nullptr).
+		AddRead(sets.Data())
+	if len(dynamicOffsets) > 0 {
+		offsets := b.Data(ctx, dynamicOffsets)
+		bind = vulkan.NewVkCmdBindDescriptorSets(commandBuffer, bindPoint, layout, 0, 1, sets.Ptr(),
+			uint32(len(dynamicOffsets)), offsets.Ptr()).
+			AddRead(sets.Data()).
+			AddRead(offsets.Data())
+	}
+	b.Add(bind)
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// DispatchCompute returns the atom list needed to create a device then
+// dispatch a compute shader doubling each of the 256 values of a storage
+// buffer, which initially holds the values 0 to 255.
+func DispatchCompute(ctx context.Context) (atoms *atom.List, dispatch, submit atom.ID) {
+	const count = 256
+
+	doubleCSSource := `
+		#version 450
+		layout(local_size_x = 64) in;
+		layout(set = 0, binding = 0) buffer Values {
+			uint values[];
+		};
+		void main() {
+			values[gl_GlobalInvocationID.x] *= 2u;
+		}`
+
+	values := make([]uint32, count)
+	for i := range values {
+		values[i] = uint32(i)
+	}
+
+	b := NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+
+	// Build the storage buffer and the pipeline using it
+	size := vulkan.VkDeviceSize(count * 4)
+	buffer := b.Buffer(ctx, device, size,
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_STORAGE_BUFFER_BIT|
+			vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_SRC_BIT|
+			vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT)
+	set, layout := b.DescriptorSet(ctx, device,
+		vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER,
+		vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_COMPUTE_BIT)
+	b.WriteBuffer(ctx, device, set, vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_STORAGE_BUFFER, buffer, 0, size)
+	cs := b.ShaderModule(ctx, device, doubleCSSource, shadertools.StageCompute)
+	pipeline := b.ComputePipeline(ctx, device, layout, cs)
+
+	// Upload the values and double them
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	b.UpdateBuffer(ctx, cb, buffer, values)
+	b.MemoryBarrier(ctx, cb)
+	b.Add(vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE, pipeline))
+	b.BindDescriptorSet(ctx, cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_COMPUTE, layout, set)
+	dispatch = b.Add(vulkan.NewVkCmdDispatch(cb, count/64, 1, 1))
+	b.MemoryBarrier(ctx, cb)
+	submit = b.Submit(ctx, queue, cb)
+	b.WaitIdle(queue)
+
+	return &b.List, dispatch, submit
+}
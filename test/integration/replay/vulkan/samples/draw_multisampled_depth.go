@@ -0,0 +1,60 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// DrawMultisampledDepth returns the atom list needed to create a device then
+// draw a triangle with a horizontal depth gradient to a 64x64, 4 times
+// multisampled depth and stencil image, left in the attachment layout.
+func DrawMultisampledDepth(ctx context.Context) (atoms *atom.List, draw, submit atom.ID) {
+	gradientVSSource := `
+		#version 450
+		void main() {
+			vec2 position = vec2((gl_VertexIndex << 1) & 2, gl_VertexIndex & 2);
+			gl_Position = vec4(position * 2.0 - 1.0, position.x * 0.5, 1.0);
+		}`
+
+	const samples = vulkan.VkSampleCountFlagBits_VK_SAMPLE_COUNT_4_BIT
+	const attachmentLayout = vulkan.VkImageLayout_VK_IMAGE_LAYOUT_DEPTH_STENCIL_ATTACHMENT_OPTIMAL
+
+	b := NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+	b.QueueFamilyProperties(ctx)
+	depth := b.DepthImage(ctx, device, 64, 64, samples,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_DEPTH_STENCIL_ATTACHMENT_BIT)
+	view := b.DepthImageView(ctx, device, depth)
+	renderPass := b.DepthRenderPass(ctx, device, samples, attachmentLayout)
+	framebuffer := b.Framebuffer(ctx, device, renderPass, view, 64, 64)
+	vs := b.ShaderModule(ctx, device, gradientVSSource, shadertools.StageVertex)
+	pipeline := b.DepthPipeline(ctx, device, renderPass, b.PipelineLayout(ctx, device), vs, 64, 64, samples)
+
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	// The clear value of the first component is the depth.
+	b.BeginRenderPass(ctx, cb, renderPass, framebuffer, 64, 64, [4]float32{1.0, 0.0, 0.0, 0.0})
+	b.Add(vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, pipeline))
+	draw = b.Add(vulkan.NewVkCmdDraw(cb, 3, 1, 0, 0))
+	b.Add(vulkan.NewVkCmdEndRenderPass(cb))
+	submit = b.Submit(ctx, queue, cb)
+	b.WaitIdle(queue)
+
+	return &b.List, draw, submit
+}
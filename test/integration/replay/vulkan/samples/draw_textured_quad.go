@@ -0,0 +1,139 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+const texturedFSSource = `
+	#version 450
+	layout(set = 0, binding = 0) uniform sampler2D tex;
+	layout(location = 0) in vec2 texcoord;
+	layout(location = 0) out vec4 color;
+	void main() {
+		color = texture(tex, texcoord);
+	}`
+
+// DrawTexturedQuad returns the atom list needed to create a device then draw
+// a textured quad to a 128x128 color image.
+func DrawTexturedQuad(ctx context.Context) (atoms *atom.List, draw, submit atom.ID) {
+	quadVertices := []float32{
+		-0.5, -0.5,
+		-0.5, +0.5,
+		+0.5, +0.5,
+		+0.5, -0.5,
+	}
+
+	quadIndices := []uint16{
+		0, 1, 2, 0, 2, 3,
+	}
+
+	quadVSSource := `
+		#version 450
+		layout(location = 0) in vec2 position;
+		layout(location = 0) out vec2 texcoord;
+		void main() {
+			gl_Position = vec4(position, 0.5, 1.0);
+			texcoord = position + vec2(0.5, 0.5);
+		}`
+
+	texData := make([]uint8, 4*64*64)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			texData[y*64*4+x*4] = uint8(x * 4)
+			texData[y*64*4+x*4+1] = uint8(y * 4)
+			texData[y*64*4+x*4+2] = 255
+			texData[y*64*4+x*4+3] = 255
+		}
+	}
+
+	b := NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+
+	// Build the texture, geometry and target resources
+	texture := b.Image(ctx, device, 64, 64,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT|vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_DST_BIT)
+	textureView := b.ImageView(ctx, device, texture)
+	staging := b.Buffer(ctx, device, vulkan.VkDeviceSize(len(texData)),
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_SRC_BIT|vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT)
+	vertices := b.Buffer(ctx, device, vulkan.VkDeviceSize(len(quadVertices)*4),
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_VERTEX_BUFFER_BIT|vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT)
+	indices := b.Buffer(ctx, device, vulkan.VkDeviceSize(len(quadIndices)*2),
+		vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_INDEX_BUFFER_BIT|vulkan.VkBufferUsageFlagBits_VK_BUFFER_USAGE_TRANSFER_DST_BIT)
+	target := b.Image(ctx, device, 128, 128,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT|vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT)
+	targetView := b.ImageView(ctx, device, target)
+	sampler := b.Sampler(ctx, device)
+
+	// Build the pipeline and bind the texture to it
+	set, layout := b.DescriptorSet(ctx, device,
+		vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER,
+		vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_FRAGMENT_BIT)
+	b.WriteImage(ctx, device, set, textureView, sampler)
+	renderPass := b.RenderPass(ctx, device, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL)
+	framebuffer := b.Framebuffer(ctx, device, renderPass, targetView, 128, 128)
+	vs := b.ShaderModule(ctx, device, quadVSSource, shadertools.StageVertex)
+	fs := b.ShaderModule(ctx, device, texturedFSSource, shadertools.StageFragment)
+	pipeline := b.GraphicsPipeline(ctx, device, renderPass, layout, vs, fs, 128, 128, 2*4)
+
+	// Upload the texture and geometry
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	b.UpdateBuffer(ctx, cb, staging, texData)
+	b.UpdateBuffer(ctx, cb, vertices, quadVertices)
+	b.UpdateBuffer(ctx, cb, indices, quadIndices)
+	b.ImageBarrier(ctx, cb, texture,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_UNDEFINED,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL)
+	region := b.Data(ctx, vulkan.VkBufferImageCopy{
+		ImageSubresource: vulkan.VkImageSubresourceLayers{
+			AspectMask:     colorAspect,
+			MipLevel:       0,
+			BaseArrayLayer: 0,
+			LayerCount:     1,
+		},
+		ImageOffset: vulkan.VkOffset3D{X: 0, Y: 0, Z: 0},
+		ImageExtent: vulkan.VkExtent3D{Width: 64, Height: 64, Depth: 1},
+	})
+	b.Add(vulkan.NewVkCmdCopyBufferToImage(cb, staging, texture,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL, 1, region.Ptr()).
+		AddRead(region.Data()))
+	b.ImageBarrier(ctx, cb, texture,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_DST_OPTIMAL,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_SHADER_READ_ONLY_OPTIMAL)
+
+	// Render the quad using the built pipeline and texture
+	vertexBuffers := b.Data(ctx, vertices)
+	vertexOffsets := b.Data(ctx, vulkan.VkDeviceSize(0))
+	b.BeginRenderPass(ctx, cb, renderPass, framebuffer, 128, 128, [4]float32{0.0, 1.0, 0.0, 1.0})
+	b.Add(vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, pipeline))
+	b.BindDescriptorSet(ctx, cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, layout, set)
+	draw = b.Add(
+		vulkan.NewVkCmdBindVertexBuffers(cb, 0, 1, vertexBuffers.Ptr(), vertexOffsets.Ptr()).
+			AddRead(vertexBuffers.Data()).
+			AddRead(vertexOffsets.Data()),
+		vulkan.NewVkCmdBindIndexBuffer(cb, indices, 0, vulkan.VkIndexType_VK_INDEX_TYPE_UINT16),
+		vulkan.NewVkCmdDrawIndexed(cb, uint32(len(quadIndices)), 1, 0, 0, 0),
+	)
+	b.Add(vulkan.NewVkCmdEndRenderPass(cb))
+	submit = b.Submit(ctx, queue, cb)
+	b.WaitIdle(queue)
+
+	return &b.List, draw, submit
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// DrawToImagePlane returns the atom list needed to create a device then draw
+// a triangle covering the 32x32 chroma plane of a 64x64 two-plane 4:2:0 YCbCr
+// image, through a view of that plane.
+func DrawToImagePlane(ctx context.Context) (atoms *atom.List, draw, submit atom.ID) {
+	fullscreenVSSource := `
+		#version 450
+		void main() {
+			vec2 position = vec2((gl_VertexIndex << 1) & 2, gl_VertexIndex & 2);
+			gl_Position = vec4(position * 2.0 - 1.0, 0.5, 1.0);
+		}`
+	chromaFSSource := `
+		#version 450
+		layout(location = 0) out vec2 chroma;
+		void main() {
+			chroma = vec2(0.25, 0.75);
+		}`
+
+	const chromaFormat = vulkan.VkFormat_VK_FORMAT_R8G8_UNORM
+
+	b := NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+	b.QueueFamilyProperties(ctx)
+	image := b.PlanarImage(ctx, device, 64, 64, vulkan.VkFormat_VK_FORMAT_G8_B8R8_2PLANE_420_UNORM_KHR,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT|
+			vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT|
+			vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT)
+	view := b.PlaneImageView(ctx, device, image, 1, chromaFormat)
+	renderPass := b.PlaneRenderPass(ctx, device, chromaFormat, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL)
+	framebuffer := b.Framebuffer(ctx, device, renderPass, view, 32, 32)
+	vs := b.ShaderModule(ctx, device, fullscreenVSSource, shadertools.StageVertex)
+	fs := b.ShaderModule(ctx, device, chromaFSSource, shadertools.StageFragment)
+	pipeline := b.GraphicsPipeline(ctx, device, renderPass, b.PipelineLayout(ctx, device), vs, fs, 32, 32, 0)
+
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	b.BeginRenderPass(ctx, cb, renderPass, framebuffer, 32, 32, [4]float32{0.5, 0.5, 0.0, 0.0})
+	b.Add(vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, pipeline))
+	draw = b.Add(vulkan.NewVkCmdDraw(cb, 3, 1, 0, 0))
+	b.Add(vulkan.NewVkCmdEndRenderPass(cb))
+	submit = b.Submit(ctx, queue, cb)
+	b.WaitIdle(queue)
+
+	return &b.List, draw, submit
+}
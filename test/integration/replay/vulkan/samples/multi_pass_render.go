@@ -0,0 +1,94 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/gfxapi/vulkan"
+	"github.com/google/gapid/gapis/shadertools"
+)
+
+// MultiPassRender returns the atom list needed to create a device then render
+// a gradient to an offscreen 64x64 image in a first render pass, and sample
+// that image to fill a 128x128 color image in a second render pass.
+// Both passes draw a single full-screen triangle without vertex buffers.
+func MultiPassRender(ctx context.Context) (atoms *atom.List, first, second, submit atom.ID) {
+	fullscreenVSSource := `
+		#version 450
+		layout(location = 0) out vec2 texcoord;
+		void main() {
+			texcoord = vec2((gl_VertexIndex << 1) & 2, gl_VertexIndex & 2);
+			gl_Position = vec4(texcoord * 2.0 - 1.0, 0.5, 1.0);
+		}`
+
+	gradientFSSource := `
+		#version 450
+		layout(location = 0) in vec2 texcoord;
+		layout(location = 0) out vec4 color;
+		void main() {
+			color = vec4(texcoord, 1.0 - texcoord.x, 1.0);
+		}`
+
+	b := NewBuilder(ctx)
+	device, queue, pool := b.NewDevice(ctx)
+	vs := b.ShaderModule(ctx, device, fullscreenVSSource, shadertools.StageVertex)
+	set, layout := b.DescriptorSet(ctx, device,
+		vulkan.VkDescriptorType_VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER,
+		vulkan.VkShaderStageFlagBits_VK_SHADER_STAGE_FRAGMENT_BIT)
+
+	// Build the first pass, rendering to the offscreen image
+	offscreen := b.Image(ctx, device, 64, 64,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT|vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_SAMPLED_BIT)
+	offscreenView := b.ImageView(ctx, device, offscreen)
+	firstPass := b.RenderPass(ctx, device, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_SHADER_READ_ONLY_OPTIMAL)
+	firstFramebuffer := b.Framebuffer(ctx, device, firstPass, offscreenView, 64, 64)
+	gradientFS := b.ShaderModule(ctx, device, gradientFSSource, shadertools.StageFragment)
+	firstPipeline := b.GraphicsPipeline(ctx, device, firstPass, layout, vs, gradientFS, 64, 64, 0)
+
+	// Build the second pass, sampling the offscreen image
+	target := b.Image(ctx, device, 128, 128,
+		vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_COLOR_ATTACHMENT_BIT|vulkan.VkImageUsageFlagBits_VK_IMAGE_USAGE_TRANSFER_SRC_BIT)
+	targetView := b.ImageView(ctx, device, target)
+	secondPass := b.RenderPass(ctx, device, vulkan.VkImageLayout_VK_IMAGE_LAYOUT_TRANSFER_SRC_OPTIMAL)
+	secondFramebuffer := b.Framebuffer(ctx, device, secondPass, targetView, 128, 128)
+	b.WriteImage(ctx, device, set, offscreenView, b.Sampler(ctx, device))
+	texturedFS := b.ShaderModule(ctx, device, texturedFSSource, shadertools.StageFragment)
+	secondPipeline := b.GraphicsPipeline(ctx, device, secondPass, layout, vs, texturedFS, 128, 128, 0)
+
+	// Render both passes in a single command buffer
+	cb := b.BeginCommandBuffer(ctx, device, pool)
+	b.BeginRenderPass(ctx, cb, firstPass, firstFramebuffer, 64, 64, [4]float32{0.0, 0.0, 0.0, 1.0})
+	first = b.Add(
+		vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, firstPipeline),
+		vulkan.NewVkCmdDraw(cb, 3, 1, 0, 0),
+	)
+	b.Add(vulkan.NewVkCmdEndRenderPass(cb))
+	// The first pass already transitioned the offscreen image, this barrier
+	// only orders its writes before the reads of the second pass.
+	b.ImageBarrier(ctx, cb, offscreen,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_SHADER_READ_ONLY_OPTIMAL,
+		vulkan.VkImageLayout_VK_IMAGE_LAYOUT_SHADER_READ_ONLY_OPTIMAL)
+	b.BeginRenderPass(ctx, cb, secondPass, secondFramebuffer, 128, 128, [4]float32{0.0, 1.0, 0.0, 1.0})
+	b.Add(vulkan.NewVkCmdBindPipeline(cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, secondPipeline))
+	b.BindDescriptorSet(ctx, cb, vulkan.VkPipelineBindPoint_VK_PIPELINE_BIND_POINT_GRAPHICS, layout, set)
+	second = b.Add(vulkan.NewVkCmdDraw(cb, 3, 1, 0, 0))
+	b.Add(vulkan.NewVkCmdEndRenderPass(cb))
+	submit = b.Submit(ctx, queue, cb)
+	b.WaitIdle(queue)
+
+	return &b.List, first, second, submit
+}
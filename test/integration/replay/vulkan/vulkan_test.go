@@ -0,0 +1,94 @@
+// Copyright (C) 2017 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vulkan
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/gapid/core/assert"
+	"github.com/google/gapid/core/log"
+	"github.com/google/gapid/gapis/atom"
+	"github.com/google/gapid/gapis/capture"
+	"github.com/google/gapid/gapis/database"
+	"github.com/google/gapid/test/integration/replay/vulkan/samples"
+
+	_ "github.com/google/gapid/framework/binary/any"
+)
+
+// sample builds the atoms of a sample, returning the atoms which must be kept
+// to reproduce the output of its last atom.
+type sample func(ctx context.Context) (atoms *atom.List, keep []atom.ID)
+
+var testSamples = map[string]sample{
+	"DrawTexturedQuad": func(ctx context.Context) (*atom.List, []atom.ID) {
+		atoms, draw, submit := samples.DrawTexturedQuad(ctx)
+		return atoms, []atom.ID{draw, submit}
+	},
+	"DispatchCompute": func(ctx context.Context) (*atom.List, []atom.ID) {
+		atoms, dispatch, submit := samples.DispatchCompute(ctx)
+		return atoms, []atom.ID{dispatch, submit}
+	},
+	"MultiPassRender": func(ctx context.Context) (*atom.List, []atom.ID) {
+		atoms, first, second, submit := samples.MultiPassRender(ctx)
+		return atoms, []atom.ID{first, second, submit}
+	},
+	"DrawMultisampledDepth": func(ctx context.Context) (*atom.List, []atom.ID) {
+		atoms, draw, submit := samples.DrawMultisampledDepth(ctx)
+		return atoms, []atom.ID{draw, submit}
+	},
+}
+
+// TestSamples checks that each atom of the samples mutates the state without
+// error, and that dead code elimination keeps the draws, dispatches and
+// submissions of the samples.
+func TestSamples(t *testing.T) {
+	ctx := log.Testing(t)
+	ctx = database.Put(ctx, database.NewInMemory(ctx))
+
+	for name, build := range testSamples {
+		ctx := log.V{"sample": name}.Bind(ctx)
+		atoms, keep := build(ctx)
+		c, err := capture.ImportAtomList(ctx, name, atoms)
+		if !assert.For(ctx, "Import").ThatError(err).Succeeded() {
+			continue
+		}
+		ctx = capture.Put(ctx, c)
+
+		s := capture.NewState(ctx)
+		for i, a := range atoms.Atoms {
+			err := a.Mutate(ctx, s, nil)
+			ctx := log.V{"id": i, "atom": fmt.Sprintf("%T", a)}.Bind(ctx)
+			if !assert.For(ctx, "Mutate").ThatError(err).Succeeded() {
+				break
+			}
+		}
+
+		last := atom.ID(len(atoms.Atoms) - 1)
+		tracker := atoms.Atoms[last].API().(atom.DependencyTracker)
+		live, err := tracker.Dependencies(ctx, []atom.ID{last})
+		if !assert.For(ctx, "Dependencies").ThatError(err).Succeeded() {
+			continue
+		}
+		kept := atom.IDSet{}
+		for _, id := range live {
+			kept.Add(id)
+		}
+		for _, id := range keep {
+			assert.For(ctx, "Kept %v", id).That(kept.Contains(id)).Equals(true)
+		}
+	}
+}
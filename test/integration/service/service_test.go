@@ -26,6 +26,7 @@ import (
 	"github.com/google/gapid/core/log"
 	"github.com/google/gapid/core/net/grpcutil"
 	"github.com/google/gapid/core/os/device/bind"
+	gapir "github.com/google/gapid/gapir/client"
 	"github.com/google/gapid/gapis/atom"
 	"github.com/google/gapid/gapis/capture"
 	gapis "github.com/google/gapid/gapis/client"
@@ -67,7 +68,7 @@ func setup(t *testing.T) (context.Context, server.Server, func()) {
 	ctx := log.Testing(t)
 	r := bind.NewRegistry()
 	ctx = bind.PutRegistry(ctx, r)
-	m := replay.New(ctx)
+	m := replay.New(ctx, gapir.Options{})
 	ctx = replay.PutManager(ctx, m)
 	ctx = database.Put(ctx, database.NewInMemory(ctx))
 
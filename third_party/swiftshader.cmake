@@ -0,0 +1,50 @@
+# Copyright (C) 2017 Google Inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# SwiftShader is a software Vulkan driver, bundled with GAPIR so that captures
+# can be replayed without compatible graphics hardware. It is not built from
+# source: set SWIFTSHADER_PATH to the directory holding a SwiftShader Vulkan
+# build to include it in the package.
+set(SWIFTSHADER_PATH "" CACHE PATH "Directory holding a prebuilt SwiftShader Vulkan driver")
+
+if(SWIFTSHADER_PATH AND NOT ANDROID AND NOT GAPII_TARGET AND NOT DISABLED_CXX)
+    if(WIN32)
+        set(swiftshader_src "${SWIFTSHADER_PATH}/vk_swiftshader.dll")
+        set(swiftshader_lib "libvk_swiftshader.dll")
+    else()
+        set(swiftshader_src "${SWIFTSHADER_PATH}/libvk_swiftshader.so")
+        set(swiftshader_lib "libvk_swiftshader.so")
+    endif()
+    if(NOT EXISTS "${swiftshader_src}")
+        message(FATAL_ERROR "SwiftShader driver not found at ${swiftshader_src}")
+    endif()
+
+    set(swiftshader_dst "${CMAKE_RUNTIME_OUTPUT_DIRECTORY}/${swiftshader_lib}")
+    set(swiftshader_json "${CMAKE_RUNTIME_OUTPUT_DIRECTORY}/vk_swiftshader_icd.json")
+    add_custom_command(
+        OUTPUT ${swiftshader_dst}
+        COMMAND "${CMAKE_COMMAND}" -E copy_if_different ${swiftshader_src} ${swiftshader_dst}
+        DEPENDS ${swiftshader_src}
+    )
+    file(WRITE ${swiftshader_json} "{
+    \"file_format_version\": \"1.0.0\",
+    \"ICD\": {
+        \"library_path\": \"./${swiftshader_lib}\",
+        \"api_version\": \"1.0.5\"
+    }
+}
+")
+    add_custom_target(swiftshader ALL DEPENDS ${swiftshader_dst})
+    install(FILES ${swiftshader_dst} ${swiftshader_json} DESTINATION "${TARGET_INSTALL_PATH}/lib")
+endif()